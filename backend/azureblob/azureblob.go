@@ -69,6 +69,10 @@ const (
 	memoryPoolUseMmap    = false
 )
 
+// errNotWithVersionAt is returned by operations which modify the
+// remote in --azureblob-version-at mode
+var errNotWithVersionAt = errors.New("can't modify or delete files in --azureblob-version-at mode")
+
 // Register with Fs
 func init() {
 	fs.Register(&fs.RegInfo{
@@ -169,6 +173,26 @@ This option controls how often unused buffers will be removed from the pool.`,
 			Default:  memoryPoolUseMmap,
 			Advanced: true,
 			Help:     `Whether to use mmap buffers in internal memory pool.`,
+		}, {
+			Name: "version_at",
+			Help: `Show file versions as they were at the specified time.
+
+The parameter should be a date, "2006-01-02", datetime "2006-01-02
+15:04:05" or a duration for that long ago, eg "100d" or "1h".
+
+Each blob is shown as the newest version which is not later than
+this time. The container must have blob versioning enabled.
+
+Azure doesn't record when a blob was deleted, so a deleted blob is
+shown as its last version at any time after that was written.
+
+Note that when using this no file write operations are permitted,
+so you can't upload files or delete them.
+
+See [the options docs](/docs/#options) for valid formats.
+`,
+			Default:  fs.Time{},
+			Advanced: true,
 		}, {
 			Name:     config.ConfigEncoding,
 			Help:     config.ConfigEncodingHelp,
@@ -198,6 +222,7 @@ type Options struct {
 	DisableCheckSum      bool                 `config:"disable_checksum"`
 	MemoryPoolFlushTime  fs.Duration          `config:"memory_pool_flush_time"`
 	MemoryPoolUseMmap    bool                 `config:"memory_pool_use_mmap"`
+	VersionAt            fs.Time              `config:"version_at"`
 	Enc                  encoder.MultiEncoder `config:"encoding"`
 }
 
@@ -231,6 +256,7 @@ type Object struct {
 	mimeType   string                // Content-Type of the object
 	accessTier azblob.AccessTierType // Blob Access Tier
 	meta       map[string]string     // blob metadata
+	versionID  string                // version of the blob to read if set
 }
 
 // ------------------------------------------------------------
@@ -608,11 +634,21 @@ func (f *Fs) cntURL(container string) (containerURL *azblob.ContainerURL) {
 // Return an Object from a path
 //
 // If it can't be found it returns the error fs.ErrorObjectNotFound.
-func (f *Fs) newObjectWithInfo(remote string, info *azblob.BlobItemInternal) (fs.Object, error) {
+func (f *Fs) newObjectWithInfo(ctx context.Context, remote string, info *azblob.BlobItemInternal) (fs.Object, error) {
 	o := &Object{
 		fs:     f,
 		remote: remote,
 	}
+	if info == nil && f.opt.VersionAt.IsSet() {
+		var err error
+		info, err = f.findVersionAt(ctx, remote)
+		if err != nil {
+			return nil, err
+		}
+		if info == nil {
+			return nil, fs.ErrorObjectNotFound
+		}
+	}
 	if info != nil {
 		err := o.decodeMetaDataFromBlob(info)
 		if err != nil {
@@ -630,7 +666,7 @@ func (f *Fs) newObjectWithInfo(remote string, info *azblob.BlobItemInternal) (fs
 // NewObject finds the Object at remote.  If it can't be found
 // it returns the error fs.ErrorObjectNotFound.
 func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
-	return f.newObjectWithInfo(remote, nil)
+	return f.newObjectWithInfo(ctx, remote, nil)
 }
 
 // getBlobReference creates an empty blob reference with no metadata
@@ -667,6 +703,50 @@ func isDirectoryMarker(size int64, metadata azblob.Metadata, remote string) bool
 // listFn is called from list to handle an object
 type listFn func(remote string, object *azblob.BlobItemInternal, isDirectory bool) error
 
+// versionAtFilter chooses the version of each blob to show in
+// --azureblob-version-at mode from a listing including the versions.
+//
+// The versions of a blob are listed together but may be split over
+// more than one page of the listing.
+type versionAtFilter struct {
+	at       time.Time                // show the versions as they were at this time
+	name     string                   // name of the blob being considered
+	best     *azblob.BlobItemInternal // newest version of name not later than at
+	bestTime time.Time                // time of best
+}
+
+// versionTime returns the time the version of the blob was written
+func versionTime(item *azblob.BlobItemInternal) time.Time {
+	if item.VersionID != nil {
+		if t, err := time.Parse(time.RFC3339Nano, *item.VersionID); err == nil {
+			return t
+		}
+	}
+	// Blobs written before versioning was enabled have no version
+	return item.Properties.LastModified
+}
+
+// add considers item, returning the version chosen for the previous
+// blob, if any, when item is the first version of a new blob
+func (v *versionAtFilter) add(item *azblob.BlobItemInternal) (chosen *azblob.BlobItemInternal) {
+	if item.Name != v.name {
+		chosen = v.flush()
+		v.name = item.Name
+	}
+	t := versionTime(item)
+	if !t.After(v.at) && (v.best == nil || t.After(v.bestTime)) {
+		v.best, v.bestTime = item, t
+	}
+	return chosen
+}
+
+// flush returns the version chosen for the current blob, if any
+func (v *versionAtFilter) flush() (chosen *azblob.BlobItemInternal) {
+	chosen = v.best
+	v.best, v.bestTime = nil, time.Time{}
+	return chosen
+}
+
 // list lists the objects into the function supplied from
 // the container and root supplied
 //
@@ -696,10 +776,35 @@ func (f *Fs) list(ctx context.Context, container, directory, prefix string, addC
 			Snapshots:        false,
 			UncommittedBlobs: false,
 			Deleted:          false,
+			Versions:         f.opt.VersionAt.IsSet(),
 		},
 		Prefix:     directory,
 		MaxResults: int32(maxResults),
 	}
+	var versionAt *versionAtFilter
+	if f.opt.VersionAt.IsSet() {
+		versionAt = &versionAtFilter{at: time.Time(f.opt.VersionAt)}
+	}
+	sendFile := func(file *azblob.BlobItemInternal) error {
+		// Finish if file name no longer has prefix
+		// if prefix != "" && !strings.HasPrefix(file.Name, prefix) {
+		// 	return nil
+		// }
+		remote := f.opt.Enc.ToStandardPath(file.Name)
+		if !strings.HasPrefix(remote, prefix) {
+			fs.Debugf(f, "Odd name received %q", remote)
+			return nil
+		}
+		remote = remote[len(prefix):]
+		if isDirectoryMarker(*file.Properties.ContentLength, file.Metadata, remote) {
+			return nil // skip directory marker
+		}
+		if addContainer {
+			remote = path.Join(container, remote)
+		}
+		// Send object
+		return fn(remote, file, false)
+	}
 	for marker := (azblob.Marker{}); marker.NotDone(); {
 		var response *azblob.ListBlobsHierarchySegmentResponse
 		err := f.pacer.Call(func() (bool, error) {
@@ -719,24 +824,13 @@ func (f *Fs) list(ctx context.Context, container, directory, prefix string, addC
 		marker = response.NextMarker
 		for i := range response.Segment.BlobItems {
 			file := &response.Segment.BlobItems[i]
-			// Finish if file name no longer has prefix
-			// if prefix != "" && !strings.HasPrefix(file.Name, prefix) {
-			// 	return nil
-			// }
-			remote := f.opt.Enc.ToStandardPath(file.Name)
-			if !strings.HasPrefix(remote, prefix) {
-				fs.Debugf(f, "Odd name received %q", remote)
-				continue
-			}
-			remote = remote[len(prefix):]
-			if isDirectoryMarker(*file.Properties.ContentLength, file.Metadata, remote) {
-				continue // skip directory marker
-			}
-			if addContainer {
-				remote = path.Join(container, remote)
+			if versionAt != nil {
+				file = versionAt.add(file)
+				if file == nil {
+					continue
+				}
 			}
-			// Send object
-			err = fn(remote, file, false)
+			err = sendFile(file)
 			if err != nil {
 				return err
 			}
@@ -760,16 +854,64 @@ func (f *Fs) list(ctx context.Context, container, directory, prefix string, addC
 			}
 		}
 	}
+	if versionAt != nil {
+		if file := versionAt.flush(); file != nil {
+			return sendFile(file)
+		}
+	}
 	return nil
 }
 
+// findVersionAt finds the version of the blob at remote at the
+// --azureblob-version-at time
+func (f *Fs) findVersionAt(ctx context.Context, remote string) (info *azblob.BlobItemInternal, err error) {
+	container, containerPath := f.split(remote)
+	if container == "" || containerPath == "" {
+		return nil, fs.ErrorObjectNotFound
+	}
+	options := azblob.ListBlobsSegmentOptions{
+		Details: azblob.BlobListingDetails{
+			Metadata: true,
+			Versions: true,
+		},
+		Prefix:     f.opt.Enc.FromStandardPath(containerPath),
+		MaxResults: int32(f.opt.ListChunkSize),
+	}
+	versionAt := &versionAtFilter{at: time.Time(f.opt.VersionAt)}
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		var response *azblob.ListBlobsFlatSegmentResponse
+		err := f.pacer.Call(func() (bool, error) {
+			var err error
+			response, err = f.cntURL(container).ListBlobsFlatSegment(ctx, marker, options)
+			return f.shouldRetry(err)
+		})
+		if err != nil {
+			if storageErr, ok := err.(azblob.StorageError); ok && (storageErr.ServiceCode() == azblob.ServiceCodeContainerNotFound || storageErr.Response().StatusCode == http.StatusNotFound) {
+				return nil, fs.ErrorObjectNotFound
+			}
+			return nil, err
+		}
+		marker = response.NextMarker
+		for i := range response.Segment.BlobItems {
+			file := &response.Segment.BlobItems[i]
+			// The versions of the blob are listed before any
+			// other blobs with its name as a prefix
+			if file.Name != options.Prefix {
+				return versionAt.flush(), nil
+			}
+			versionAt.add(file)
+		}
+	}
+	return versionAt.flush(), nil
+}
+
 // Convert a list item into a DirEntry
-func (f *Fs) itemToDirEntry(remote string, object *azblob.BlobItemInternal, isDirectory bool) (fs.DirEntry, error) {
+func (f *Fs) itemToDirEntry(ctx context.Context, remote string, object *azblob.BlobItemInternal, isDirectory bool) (fs.DirEntry, error) {
 	if isDirectory {
 		d := fs.NewDir(remote, time.Time{})
 		return d, nil
 	}
-	o, err := f.newObjectWithInfo(remote, object)
+	o, err := f.newObjectWithInfo(ctx, remote, object)
 	if err != nil {
 		return nil, err
 	}
@@ -779,7 +921,7 @@ func (f *Fs) itemToDirEntry(remote string, object *azblob.BlobItemInternal, isDi
 // listDir lists a single directory
 func (f *Fs) listDir(ctx context.Context, container, directory, prefix string, addContainer bool) (entries fs.DirEntries, err error) {
 	err = f.list(ctx, container, directory, prefix, addContainer, false, f.opt.ListChunkSize, func(remote string, object *azblob.BlobItemInternal, isDirectory bool) error {
-		entry, err := f.itemToDirEntry(remote, object, isDirectory)
+		entry, err := f.itemToDirEntry(ctx, remote, object, isDirectory)
 		if err != nil {
 			return err
 		}
@@ -860,7 +1002,7 @@ func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (
 	list := walk.NewListRHelper(callback)
 	listR := func(container, directory, prefix string, addContainer bool) error {
 		return f.list(ctx, container, directory, prefix, addContainer, true, f.opt.ListChunkSize, func(remote string, object *azblob.BlobItemInternal, isDirectory bool) error {
-			entry, err := f.itemToDirEntry(remote, object, isDirectory)
+			entry, err := f.itemToDirEntry(ctx, remote, object, isDirectory)
 			if err != nil {
 				return err
 			}
@@ -949,6 +1091,9 @@ func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, opt
 
 // Mkdir creates the container if it doesn't exist
 func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	if f.opt.VersionAt.IsSet() {
+		return errNotWithVersionAt
+	}
 	container, _ := f.split(dir)
 	return f.makeContainer(ctx, container)
 }
@@ -1029,6 +1174,9 @@ func (f *Fs) deleteContainer(ctx context.Context, container string) error {
 //
 // Returns an error if it isn't empty
 func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	if f.opt.VersionAt.IsSet() {
+		return errNotWithVersionAt
+	}
 	container, directory := f.split(dir)
 	if container == "" || directory != "" {
 		return nil
@@ -1052,6 +1200,9 @@ func (f *Fs) Hashes() hash.Set {
 
 // Purge deletes all the files and directories including the old versions.
 func (f *Fs) Purge(ctx context.Context, dir string) error {
+	if f.opt.VersionAt.IsSet() {
+		return errNotWithVersionAt
+	}
 	container, directory := f.split(dir)
 	if container == "" || directory != "" {
 		// Delegate to caller if not root of a container
@@ -1070,6 +1221,9 @@ func (f *Fs) Purge(ctx context.Context, dir string) error {
 //
 // If it isn't possible then return fs.ErrorCantCopy
 func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	if f.opt.VersionAt.IsSet() {
+		return nil, errNotWithVersionAt
+	}
 	dstContainer, dstPath := f.split(remote)
 	err := f.makeContainer(ctx, dstContainer)
 	if err != nil {
@@ -1220,6 +1374,9 @@ func (o *Object) decodeMetaDataFromBlob(info *azblob.BlobItemInternal) (err erro
 	o.size = size
 	o.modTime = info.Properties.LastModified
 	o.accessTier = info.Properties.AccessTier
+	if o.fs.opt.VersionAt.IsSet() && info.VersionID != nil {
+		o.versionID = *info.VersionID
+	}
 	o.setMetadata(metadata)
 	return nil
 }
@@ -1227,7 +1384,11 @@ func (o *Object) decodeMetaDataFromBlob(info *azblob.BlobItemInternal) (err erro
 // getBlobReference creates an empty blob reference with no metadata
 func (o *Object) getBlobReference() azblob.BlobURL {
 	container, directory := o.split()
-	return o.fs.getBlobReference(container, directory)
+	blob := o.fs.getBlobReference(container, directory)
+	if o.versionID != "" {
+		blob = blob.WithVersionID(o.versionID)
+	}
+	return blob
 }
 
 // clearMetaData clears enough metadata so readMetaData will re-read it
@@ -1279,6 +1440,9 @@ func (o *Object) ModTime(ctx context.Context) (result time.Time) {
 
 // SetModTime sets the modification time of the local fs object
 func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	if o.fs.opt.VersionAt.IsSet() {
+		return errNotWithVersionAt
+	}
 	// Make sure o.meta is not nil
 	if o.meta == nil {
 		o.meta = make(map[string]string, 1)
@@ -1521,6 +1685,9 @@ func (o *Object) uploadMultipart(ctx context.Context, in io.Reader, size int64,
 //
 // The new object may have been created if an error is returned
 func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	if o.fs.opt.VersionAt.IsSet() {
+		return errNotWithVersionAt
+	}
 	container, _ := o.split()
 	err = o.fs.makeContainer(ctx, container)
 	if err != nil {
@@ -1600,6 +1767,9 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 
 // Remove an object
 func (o *Object) Remove(ctx context.Context) error {
+	if o.fs.opt.VersionAt.IsSet() {
+		return errNotWithVersionAt
+	}
 	blob := o.getBlobReference()
 	snapShotOptions := azblob.DeleteSnapshotsOptionNone
 	ac := azblob.BlobAccessConditions{}
@@ -1621,6 +1791,9 @@ func (o *Object) AccessTier() azblob.AccessTierType {
 
 // SetTier performs changing object tier
 func (o *Object) SetTier(tier string) error {
+	if o.fs.opt.VersionAt.IsSet() {
+		return errNotWithVersionAt
+	}
 	if !validateAccessTier(tier) {
 		return errors.Errorf("Tier %s not supported by Azure Blob Storage", tier)
 	}
@@ -4,7 +4,9 @@ package azureblob
 
 import (
 	"testing"
+	"time"
 
+	"github.com/Azure/azure-storage-blob-go/azblob"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -33,3 +35,38 @@ func TestIncrement(t *testing.T) {
 		assert.Equal(t, test.want, test.in)
 	}
 }
+
+func TestVersionAtFilter(t *testing.T) {
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	item := func(name string, offset time.Duration, versioned bool) *azblob.BlobItemInternal {
+		when := t0.Add(offset)
+		item := &azblob.BlobItemInternal{
+			Name:       name,
+			Properties: azblob.BlobProperties{LastModified: when},
+		}
+		if versioned {
+			versionID := when.Format(time.RFC3339Nano)
+			item.VersionID = &versionID
+		}
+		return item
+	}
+	items := []*azblob.BlobItemInternal{
+		item("a", -time.Hour, true),
+		item("a", time.Hour, true),
+		item("b", time.Hour, true),
+		item("c", -2*time.Hour, false),
+		item("c", -time.Hour, true),
+		item("c", 0, true),
+	}
+	v := versionAtFilter{at: t0}
+	var got []*azblob.BlobItemInternal
+	for _, item := range items {
+		if chosen := v.add(item); chosen != nil {
+			got = append(got, chosen)
+		}
+	}
+	if chosen := v.flush(); chosen != nil {
+		got = append(got, chosen)
+	}
+	assert.Equal(t, []*azblob.BlobItemInternal{items[0], items[5]}, got)
+}
@@ -302,6 +302,19 @@ type UploadPartResponse struct {
 	SHA1       string `json:"contentSha1"`   // The SHA1 of the bytes stored in the file.
 }
 
+// ListPartsRequest is passed to b2_list_parts
+type ListPartsRequest struct {
+	ID              string `json:"fileId"`                    // The ID returned by b2_start_large_file.
+	StartPartNumber int64  `json:"startPartNumber,omitempty"` // The first part to return.
+	MaxPartCount    int64  `json:"maxPartCount,omitempty"`    // The maximum number of parts to return from this call.
+}
+
+// ListPartsResponse is the response to b2_list_parts
+type ListPartsResponse struct {
+	Parts          []UploadPartResponse `json:"parts"`          // The parts which have been uploaded, in order.
+	NextPartNumber *int64               `json:"nextPartNumber"` // What to pass in to startPartNumber for the next search to continue where this one left off, or null if there are no more parts.
+}
+
 // FinishLargeFileRequest is passed to b2_finish_large_file
 //
 // The response is a FileInfo object (with extra AccountID and BucketID fields which we ignore).
@@ -64,7 +64,8 @@ const (
 
 // Globals
 var (
-	errNotWithVersions = errors.New("can't modify or delete files in --b2-versions mode")
+	errNotWithVersions  = errors.New("can't modify or delete files in --b2-versions mode")
+	errNotWithVersionAt = errors.New("can't modify or delete files in --b2-version-at mode")
 )
 
 // Register with Fs
@@ -106,6 +107,25 @@ in the [b2 integrations checklist](https://www.backblaze.com/b2/docs/integration
 			Help:     "Include old versions in directory listings.\nNote that when using this no file write operations are permitted,\nso you can't upload files or delete them.",
 			Default:  false,
 			Advanced: true,
+		}, {
+			Name: "version_at",
+			Help: `Show file versions as they were at the specified time.
+
+The parameter should be a date, "2006-01-02", datetime "2006-01-02
+15:04:05" or a duration for that long ago, eg "100d" or "1h".
+
+Each file is shown as the newest version which is not later than
+this time and files which were hidden or deleted by then are not
+shown. Files are always downloaded by ID from Backblaze in this
+mode, ignoring --b2-download-url.
+
+Note that when using this no file write operations are permitted,
+so you can't upload files or delete them.
+
+See [the options docs](/docs/#options) for valid formats.
+`,
+			Default:  fs.Time{},
+			Advanced: true,
 		}, {
 			Name:    "hard_delete",
 			Help:    "Permanently delete files on remote removal, otherwise hide files.",
@@ -199,6 +219,7 @@ type Options struct {
 	Endpoint                      string               `config:"endpoint"`
 	TestMode                      string               `config:"test_mode"`
 	Versions                      bool                 `config:"versions"`
+	VersionAt                     fs.Time              `config:"version_at"`
 	HardDelete                    bool                 `config:"hard_delete"`
 	UploadCutoff                  fs.SizeSuffix        `config:"upload_cutoff"`
 	CopyCutoff                    fs.SizeSuffix        `config:"copy_cutoff"`
@@ -645,6 +666,9 @@ var errEndList = errors.New("end list")
 //
 // If hidden is set then it will list the hidden (deleted) files too.
 //
+// If --b2-version-at is set then only the version of each file at
+// that time is listed.
+//
 // if findFile is set it will look for files called (bucket, directory)
 func (f *Fs) list(ctx context.Context, bucket, directory, prefix string, addBucket bool, recurse bool, limit int, hidden bool, findFile bool, fn listFn) error {
 	if !findFile {
@@ -680,9 +704,11 @@ func (f *Fs) list(ctx context.Context, bucket, directory, prefix string, addBuck
 		Method: "POST",
 		Path:   "/b2_list_file_names",
 	}
-	if hidden {
+	versionAt := f.opt.VersionAt.IsSet()
+	if hidden || versionAt {
 		opts.Path = "/b2_list_file_versions"
 	}
+	lastName := "" // name of the last file a version was chosen for
 	for {
 		var response api.ListFileNamesResponse
 		err := f.pacer.Call(func() (bool, error) {
@@ -703,6 +729,17 @@ func (f *Fs) list(ctx context.Context, bucket, directory, prefix string, addBuck
 				fs.Debugf(f, "Odd name received %q", file.Name)
 				continue
 			}
+			// Versions are listed newest first so choose the first
+			// one which is not too new
+			if versionAt && file.Action != "folder" {
+				if file.Action == "start" || file.Name == lastName || time.Time(file.UploadTimestamp).After(time.Time(f.opt.VersionAt)) {
+					continue
+				}
+				lastName = file.Name
+				if file.Action == "hide" {
+					continue
+				}
+			}
 			remote := file.Name[len(prefix):]
 			// Check for directory
 			isDirectory := remote == "" || strings.HasSuffix(remote, "/")
@@ -1004,6 +1041,9 @@ func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, opt
 
 // Mkdir creates the bucket if it doesn't exist
 func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	if f.opt.VersionAt.IsSet() {
+		return errNotWithVersionAt
+	}
 	bucket, _ := f.split(dir)
 	return f.makeBucket(ctx, bucket)
 }
@@ -1052,6 +1092,9 @@ func (f *Fs) makeBucket(ctx context.Context, bucket string) error {
 //
 // Returns an error if it isn't empty
 func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	if f.opt.VersionAt.IsSet() {
+		return errNotWithVersionAt
+	}
 	bucket, directory := f.split(dir)
 	if bucket == "" || directory != "" {
 		return nil
@@ -1230,11 +1273,17 @@ func (f *Fs) purge(ctx context.Context, dir string, oldOnly bool) error {
 
 // Purge deletes all the files and directories including the old versions.
 func (f *Fs) Purge(ctx context.Context, dir string) error {
+	if f.opt.VersionAt.IsSet() {
+		return errNotWithVersionAt
+	}
 	return f.purge(ctx, dir, false)
 }
 
 // CleanUp deletes all the hidden files.
 func (f *Fs) CleanUp(ctx context.Context) error {
+	if f.opt.VersionAt.IsSet() {
+		return errNotWithVersionAt
+	}
 	return f.purge(ctx, "", true)
 }
 
@@ -1305,6 +1354,9 @@ func (f *Fs) copy(ctx context.Context, dstObj *Object, srcObj *Object, newInfo *
 //
 // If it isn't possible then return fs.ErrorCantCopy
 func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	if f.opt.VersionAt.IsSet() {
+		return nil, errNotWithVersionAt
+	}
 	srcObj, ok := src.(*Object)
 	if !ok {
 		fs.Debugf(src, "Can't copy - not same remote type")
@@ -1509,6 +1561,8 @@ func (o *Object) getMetaDataListing(ctx context.Context) (info *api.File, err er
 	if o.fs.opt.Versions {
 		timestamp, bucketPath = api.RemoveVersion(bucketPath)
 		maxSearched = maxVersions
+	} else if o.fs.opt.VersionAt.IsSet() {
+		maxSearched = maxVersions
 	}
 
 	err = o.fs.list(ctx, bucket, bucketPath, "", false, true, maxSearched, o.fs.opt.Versions, true, func(remote string, object *api.File, isDirectory bool) error {
@@ -1537,6 +1591,10 @@ func (o *Object) getMetaDataListing(ctx context.Context) (info *api.File, err er
 
 // getMetaData gets the metadata from the object unconditionally
 func (o *Object) getMetaData(ctx context.Context) (info *api.File, err error) {
+	// If using version at, need to list the versions to find the correct one
+	if o.fs.opt.VersionAt.IsSet() {
+		return o.getMetaDataListing(ctx)
+	}
 	// If using versions and have a version suffix, need to list the directory to find the correct versions
 	if o.fs.opt.Versions {
 		timestamp, _ := api.RemoveVersion(o.remote)
@@ -1686,14 +1744,18 @@ func (o *Object) getOrHead(ctx context.Context, method string, options []fs.Open
 
 	// Use downloadUrl from backblaze if downloadUrl is not set
 	// otherwise use the custom downloadUrl
-	if o.fs.opt.DownloadURL == "" {
+	//
+	// In --b2-version-at mode always use backblaze so the file can
+	// be downloaded by id to get the right version
+	customURL := o.fs.opt.DownloadURL != "" && !o.fs.opt.VersionAt.IsSet()
+	if !customURL {
 		opts.RootURL = o.fs.info.DownloadURL
 	} else {
 		opts.RootURL = o.fs.opt.DownloadURL
 	}
 
 	// Download by id if set and not using DownloadURL otherwise by name
-	if o.id != "" && o.fs.opt.DownloadURL == "" {
+	if o.id != "" && !customURL {
 		opts.Path += "/b2api/v1/b2_download_file_by_id?fileId=" + urlEncode(o.id)
 	} else {
 		bucket, bucketPath := o.split()
@@ -1805,6 +1867,9 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 	if o.fs.opt.Versions {
 		return errNotWithVersions
 	}
+	if o.fs.opt.VersionAt.IsSet() {
+		return errNotWithVersionAt
+	}
 	size := src.Size()
 
 	bucket, bucketPath := o.split()
@@ -1960,6 +2025,9 @@ func (o *Object) Remove(ctx context.Context) error {
 	if o.fs.opt.Versions {
 		return errNotWithVersions
 	}
+	if o.fs.opt.VersionAt.IsSet() {
+		return errNotWithVersionAt
+	}
 	if o.fs.opt.HardDelete {
 		return o.fs.deleteByID(ctx, o.id, bucketPath)
 	}
@@ -1,10 +1,13 @@
 package b2
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
 )
 
 // Test b2 string encoding
@@ -168,3 +171,18 @@ func TestParseTimeString(t *testing.T) {
 	}
 
 }
+
+// Test that nothing can be modified in --b2-version-at mode
+func TestVersionAtReadOnly(t *testing.T) {
+	ctx := context.Background()
+	f := &Fs{}
+	f.opt.VersionAt = fs.Time(fstest.Time("2001-02-03T04:05:06.000000000Z"))
+	assert.Equal(t, errNotWithVersionAt, f.Mkdir(ctx, "bucket"))
+	assert.Equal(t, errNotWithVersionAt, f.Rmdir(ctx, "bucket"))
+	assert.Equal(t, errNotWithVersionAt, f.Purge(ctx, "bucket"))
+	assert.Equal(t, errNotWithVersionAt, f.CleanUp(ctx))
+	_, err := f.Copy(ctx, &Object{fs: f}, "file.txt")
+	assert.Equal(t, errNotWithVersionAt, err)
+	o := &Object{fs: f}
+	assert.Equal(t, errNotWithVersionAt, o.Remove(ctx))
+}
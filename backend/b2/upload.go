@@ -22,6 +22,7 @@ import (
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/lib/atexit"
 	"github.com/rclone/rclone/lib/rest"
+	"github.com/rclone/rclone/lib/resume"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -84,6 +85,70 @@ type largeUpload struct {
 	uploads   []*api.GetUploadPartURLResponse // result of get upload URL calls
 	chunkSize int64                           // chunk size to use
 	src       *Object                         // if copying, object we are reading from
+	resume    *resume.Upload                  // if set, save the state here so the upload can be resumed
+	done      int64                           // number of parts uploaded by a previous attempt
+}
+
+// largeFileState is the state of a large file upload which is saved
+// so it can be resumed with --resume-uploads
+type largeFileState struct {
+	ID        string // ID of the large file
+	ChunkSize int64  // size of the parts
+}
+
+// resumeLargeFile finds the large file upload saved in resumeUp and
+// the SHA1s of the parts of it which don't need uploading again.
+//
+// It returns an empty id if there is no upload to resume. As uploads
+// which can't be resumed are started again from the beginning errors
+// are logged rather than returned.
+func (f *Fs) resumeLargeFile(ctx context.Context, o *Object, resumeUp *resume.Upload, chunkSize int64) (id string, sha1s []string) {
+	var state largeFileState
+	found, err := resumeUp.Load(&state)
+	if err != nil {
+		fs.Errorf(o, "Can't resume large file upload: %v", err)
+		return "", nil
+	}
+	if !found || state.ID == "" {
+		return "", nil
+	}
+	if state.ChunkSize != chunkSize {
+		fs.Debugf(o, "Can't resume large file upload as the chunk size has changed")
+		resumeUp.Remove()
+		return "", nil
+	}
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/b2_list_parts",
+	}
+	var request = api.ListPartsRequest{
+		ID:           state.ID,
+		MaxPartCount: 1000,
+	}
+	for {
+		var response api.ListPartsResponse
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err := f.srv.CallJSON(ctx, &opts, &request, &response)
+			return f.shouldRetry(ctx, resp, err)
+		})
+		if err != nil {
+			fs.Debugf(o, "Can't resume large file upload %q: %v", state.ID, err)
+			resumeUp.Remove()
+			return "", nil
+		}
+		// Use the complete parts from the start of the file
+		for _, part := range response.Parts {
+			if part.PartNumber != int64(len(sha1s)+1) || part.Size != chunkSize {
+				return state.ID, sha1s
+			}
+			sha1s = append(sha1s, part.SHA1)
+		}
+		if response.NextPartNumber == nil {
+			break
+		}
+		request.StartPartNumber = *response.NextPartNumber
+	}
+	return state.ID, sha1s
 }
 
 // newLargeUpload starts an upload of object o from in with metadata in src
@@ -136,25 +201,53 @@ func (f *Fs) newLargeUpload(ctx context.Context, o *Object, in io.Reader, src fs
 		request.ContentType = newInfo.ContentType
 		request.Info = newInfo.Info
 	}
-	var response api.StartLargeFileResponse
-	err = f.pacer.Call(func() (bool, error) {
-		resp, err := f.srv.CallJSON(ctx, &opts, &request, &response)
-		return f.shouldRetry(ctx, resp, err)
-	})
-	if err != nil {
-		return nil, err
+	// Only uploads of known size can be resumed
+	resumeUp := resume.Get(ctx)
+	if doCopy || size < 0 {
+		resumeUp = nil
+	}
+	var (
+		id    string
+		sha1s []string
+	)
+	if resumeUp != nil {
+		id, sha1s = f.resumeLargeFile(ctx, o, resumeUp, int64(chunkSize))
+		if int64(len(sha1s)) > parts {
+			sha1s = sha1s[:parts]
+		}
+	}
+	if id == "" {
+		var response api.StartLargeFileResponse
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err := f.srv.CallJSON(ctx, &opts, &request, &response)
+			return f.shouldRetry(ctx, resp, err)
+		})
+		if err != nil {
+			return nil, err
+		}
+		id = response.ID
+		if resumeUp != nil {
+			err = resumeUp.Save(&largeFileState{ID: id, ChunkSize: int64(chunkSize)})
+			if err != nil {
+				fs.Errorf(o, "Upload won't be resumable: %v", err)
+				resumeUp = nil
+			}
+		}
 	}
 	up = &largeUpload{
 		f:         f,
 		o:         o,
 		doCopy:    doCopy,
 		what:      "upload",
-		id:        response.ID,
+		id:        id,
 		size:      size,
 		parts:     parts,
 		sha1s:     make([]string, sha1SliceSize),
 		chunkSize: int64(chunkSize),
+		resume:    resumeUp,
+		done:      int64(len(sha1s)),
 	}
+	copy(up.sha1s, sha1s)
 	// unwrap the accounting from the input, we use wrap to put it
 	// back on after the buffering
 	if doCopy {
@@ -427,14 +520,30 @@ func (up *largeUpload) Stream(ctx context.Context, initialUploadBlock []byte) (e
 
 // Upload uploads the chunks from the input
 func (up *largeUpload) Upload(ctx context.Context) (err error) {
-	defer atexit.OnError(&err, func() { _ = up.cancel(ctx) })()
+	defer atexit.OnError(&err, func() {
+		if up.resume != nil {
+			fs.Debugf(up.o, "Leaving large file %s to be resumed", up.what)
+			return
+		}
+		_ = up.cancel(ctx)
+	})()
 	fs.Debugf(up.o, "Starting %s of large file in %d chunks (id %q)", up.what, up.parts, up.id)
 	var (
 		g, gCtx   = errgroup.WithContext(ctx)
 		remaining = up.size
 	)
+	// Skip the parts uploaded already
+	if up.done > 0 {
+		skip := up.done * up.chunkSize
+		fs.Infof(up.o, "Resuming large file %s at %v after %d parts", up.what, fs.SizeSuffix(skip), up.done)
+		err = resume.Skip(up.in, skip)
+		if err != nil {
+			return err
+		}
+		remaining -= skip
+	}
 	g.Go(func() error {
-		for part := int64(1); part <= up.parts; part++ {
+		for part := up.done + 1; part <= up.parts; part++ {
 			// Get a block of memory from the pool and token which limits concurrency.
 			buf := up.f.getBuf(up.doCopy)
 
@@ -478,5 +587,9 @@ func (up *largeUpload) Upload(ctx context.Context) (err error) {
 	if err != nil {
 		return err
 	}
-	return up.finish(ctx)
+	err = up.finish(ctx)
+	if err == nil && up.resume != nil {
+		up.resume.Remove()
+	}
+	return err
 }
@@ -0,0 +1,150 @@
+package b2
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/b2/api"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/lib/resume"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// largeFileServer is a fake B2 server which only does the large file
+// upload of a single file
+type largeFileServer struct {
+	mu      sync.Mutex
+	url     string
+	parts   []api.UploadPartResponse // parts uploaded, by part number - 1
+	bodies  map[int64][]byte         // contents of the parts uploaded, by part number
+	data    []byte                   // the file once the upload is finished
+	started int                      // number of large files started
+}
+
+func (s *largeFileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var response interface{}
+	switch r.URL.Path {
+	case "/b2api/v1/b2_authorize_account":
+		response = &api.AuthorizeAccountResponse{APIURL: s.url, AuthorizationToken: "token"}
+	case "/b2api/v1/b2_create_bucket":
+		response = &api.Bucket{ID: "bucket-id", Name: "bucket"}
+	case "/b2api/v1/b2_list_buckets":
+		response = &api.ListBucketsResponse{Buckets: []api.Bucket{{ID: "bucket-id", Name: "bucket"}}}
+	case "/b2api/v1/b2_start_large_file":
+		s.started++
+		s.parts = nil
+		s.bodies = nil
+		response = &api.StartLargeFileResponse{ID: "large-file-id"}
+	case "/b2api/v1/b2_list_parts":
+		response = &api.ListPartsResponse{Parts: s.parts}
+	case "/b2api/v1/b2_get_upload_part_url":
+		response = &api.GetUploadPartURLResponse{ID: "large-file-id", UploadURL: s.url + "/upload", AuthorizationToken: "token"}
+	case "/upload":
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil || len(body) < 2*sha1.Size {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// The SHA1 of the part is sent at the end
+		body = body[:len(body)-2*sha1.Size]
+		partNumber, _ := strconv.ParseInt(r.Header.Get("X-Bz-Part-Number"), 10, 64)
+		for int64(len(s.parts)) < partNumber {
+			s.parts = append(s.parts, api.UploadPartResponse{})
+		}
+		sum := sha1.Sum(body)
+		part := api.UploadPartResponse{
+			ID:         "large-file-id",
+			PartNumber: partNumber,
+			Size:       int64(len(body)),
+			SHA1:       hex.EncodeToString(sum[:]),
+		}
+		s.parts[partNumber-1] = part
+		if s.bodies == nil {
+			s.bodies = map[int64][]byte{}
+		}
+		s.bodies[partNumber] = body
+		response = &part
+	case "/b2api/v1/b2_finish_large_file":
+		s.data = nil
+		for i := range s.parts {
+			s.data = append(s.data, s.bodies[int64(i+1)]...)
+		}
+		response = &api.FileInfo{ID: "large-file-id", Name: "file", Action: "upload", Size: int64(len(s.data))}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// Test a large file upload is resumed with --resume-uploads
+func TestUploadResume(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "rclone-b2-resume-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	oldDir := resume.Dir
+	resume.Dir = dir
+	defer func() {
+		resume.Dir = oldDir
+	}()
+
+	server := &largeFileServer{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	server.url = ts.URL
+
+	f, err := NewFs(ctx, "b2resume", "bucket", configmap.Simple{
+		"account":       "account",
+		"key":           "key",
+		"endpoint":      ts.URL,
+		"chunk_size":    "5M",
+		"upload_cutoff": "5M",
+	})
+	require.NoError(t, err)
+
+	const partSize = 5 * 1024 * 1024
+	data := bytes.Repeat([]byte("0123456789abcdef"), (2*partSize+1000)/16)
+	size := int64(len(data))
+	src := object.NewStaticObjectInfo("file", time.Now(), size, true, nil, nil)
+	uploadCtx := resume.WithUpload(ctx, f, "file", "fingerprint")
+
+	// Save the state as if a previous upload had been interrupted
+	// after the first part
+	require.NoError(t, resume.Get(uploadCtx).Save(&largeFileState{ID: "large-file-id", ChunkSize: partSize}))
+	sum := sha1.Sum(data[:partSize])
+	server.parts = []api.UploadPartResponse{{ID: "large-file-id", PartNumber: 1, Size: partSize, SHA1: hex.EncodeToString(sum[:])}}
+	server.bodies = map[int64][]byte{1: data[:partSize]}
+
+	// Now upload again with the first part corrupted - this should
+	// be skipped as that part has been uploaded already
+	corrupted := append([]byte(nil), data...)
+	copy(corrupted, bytes.Repeat([]byte("X"), partSize))
+	_, err = f.Put(uploadCtx, bytes.NewReader(corrupted), src)
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, server.started)
+	assert.True(t, bytes.Equal(data, server.data), "uploaded data differs")
+	states, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(states))
+}
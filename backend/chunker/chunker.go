@@ -30,6 +30,7 @@ import (
 	"github.com/rclone/rclone/fs/fspath"
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/lib/resume"
 )
 
 //
@@ -924,6 +925,9 @@ func (o *Object) readMetadata(ctx context.Context) error {
 
 // put implements Put, PutStream, PutUnchecked, Update
 func (f *Fs) put(ctx context.Context, in io.Reader, src fs.ObjectInfo, remote string, options []fs.OpenOption, basePut putFn) (obj fs.Object, err error) {
+	// The chunks are uploaded to different names so the wrapped
+	// remote can't resume them
+	ctx = resume.Without(ctx)
 	c := f.newChunkingReader(src)
 	wrapIn := c.wrapStream(ctx, in, src)
 
@@ -1320,7 +1324,7 @@ func (f *Fs) PutUnchecked(ctx context.Context, in io.Reader, src fs.ObjectInfo,
 		return nil, errors.New("can't PutUnchecked")
 	}
 	// TODO: handle range/limit options and really chunk stream here!
-	o, err := do(ctx, in, f.wrapInfo(src, "", -1))
+	o, err := do(resume.Without(ctx), in, f.wrapInfo(src, "", -1))
 	if err != nil {
 		return nil, err
 	}
@@ -1872,6 +1876,30 @@ func (o *Object) SetModTime(ctx context.Context, mtime time.Time) error {
 	return o.mainChunk().SetModTime(ctx, mtime)
 }
 
+// Metadata returns metadata for an object
+//
+// The metadata is read from the main chunk of the file.
+func (o *Object) Metadata(ctx context.Context) (fs.Metadata, error) {
+	if err := o.readMetadata(ctx); err != nil {
+		return nil, err // refuse to act on unsupported format
+	}
+	return fs.GetMetadata(ctx, o.mainChunk())
+}
+
+// SetMetadata sets metadata for an Object
+//
+// The metadata is written to the main chunk of the file.
+func (o *Object) SetMetadata(ctx context.Context, metadata fs.Metadata) error {
+	if err := o.readMetadata(ctx); err != nil {
+		return err // refuse to act on unsupported format
+	}
+	do, ok := o.mainChunk().(fs.SetMetadataer)
+	if !ok {
+		return fs.ErrorNotImplemented
+	}
+	return do.SetMetadata(ctx, metadata)
+}
+
 // Hash returns the selected checksum of the file.
 // If no checksum is available it returns "".
 //
@@ -2313,4 +2341,6 @@ var (
 	_ fs.Object          = (*Object)(nil)
 	_ fs.ObjectUnWrapper = (*Object)(nil)
 	_ fs.IDer            = (*Object)(nil)
+	_ fs.Metadataer      = (*Object)(nil)
+	_ fs.SetMetadataer   = (*Object)(nil)
 )
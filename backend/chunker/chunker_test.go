@@ -39,6 +39,7 @@ func TestIntegration(t *testing.T) {
 		UnimplementableFsMethods: []string{
 			"PublicLink",
 			"OpenWriterAt",
+			"Link",
 			"MergeDirs",
 			"DirCacheFlush",
 			"UserInfo",
@@ -0,0 +1,422 @@
+package compress
+
+import (
+	"encoding/binary"
+	"io"
+	"math/bits"
+
+	lz4 "github.com/bkaradzic/go-lz4"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+// Size of the uncompressed blocks used by the block based modes. Each
+// block is compressed independently so a read can start at any block.
+const blockSize = 1048576
+
+// BlockMetadata describes the independently compressed blocks of an
+// object so that it can be read from any offset.
+type BlockMetadata struct {
+	BlockSize int      // Uncompressed size of each block except the last
+	Offset    int64    // Offset of the first block in the compressed data
+	BlockData []uint32 // Compressed size of each block including its framing
+}
+
+// blockCodec compresses and decompresses independent blocks of data
+// and writes the framing which makes the result a valid file for the
+// standard tools.
+type blockCodec interface {
+	// header returns the data to write before the first block
+	header() []byte
+	// encode compresses src into a single framed block appending it to dst
+	encode(dst, src []byte) ([]byte, error)
+	// decode decompresses a block made by encode into dst which
+	// must be size bytes long. It may modify src.
+	decode(dst, src []byte, size int) error
+	// trailer returns the data to write after the last block
+	trailer(meta *BlockMetadata, size int64) []byte
+	// Close releases any resources held by the codec
+	Close() error
+}
+
+// newBlockCodec returns the codec for mode
+func newBlockCodec(mode int, level int) (blockCodec, error) {
+	switch mode {
+	case Zstd:
+		return newZstdCodec(level)
+	case Lz4:
+		return lz4Codec{}, nil
+	}
+	return nil, errors.Errorf("compression mode %d is not block based", mode)
+}
+
+// blockWriter is an io.WriteCloser which compresses the data written
+// to it in independent blocks
+type blockWriter struct {
+	w     io.Writer
+	codec blockCodec
+	buf   []byte // uncompressed data for the current block
+	out   []byte // compressed data for the current block
+	size  int64  // number of uncompressed bytes written
+	meta  BlockMetadata
+	wrote bool // set if the header has been written
+	err   error
+}
+
+// newBlockWriter makes a blockWriter writing to w with codec
+//
+// The header is written by the first write to w so creating the
+// writer does no I/O.
+func newBlockWriter(w io.Writer, codec blockCodec) *blockWriter {
+	return &blockWriter{
+		w:     w,
+		codec: codec,
+		buf:   make([]byte, 0, blockSize),
+		meta: BlockMetadata{
+			BlockSize: blockSize,
+			Offset:    int64(len(codec.header())),
+		},
+	}
+}
+
+// Write compresses p - see io.Writer
+func (bw *blockWriter) Write(p []byte) (n int, err error) {
+	if bw.err != nil {
+		return 0, bw.err
+	}
+	for len(p) > 0 {
+		chunk := len(p)
+		if free := blockSize - len(bw.buf); chunk > free {
+			chunk = free
+		}
+		bw.buf = append(bw.buf, p[:chunk]...)
+		p = p[chunk:]
+		n += chunk
+		if len(bw.buf) == blockSize {
+			if err = bw.flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// write writes p to the underlying writer remembering any error
+//
+// The header is written first if it hasn't been already.
+func (bw *blockWriter) write(p []byte) error {
+	if bw.err == nil && !bw.wrote {
+		_, bw.err = bw.w.Write(bw.codec.header())
+		bw.wrote = true
+	}
+	if bw.err == nil {
+		_, bw.err = bw.w.Write(p)
+	}
+	return bw.err
+}
+
+// flush compresses and writes the current block
+func (bw *blockWriter) flush() error {
+	var err error
+	bw.out, err = bw.codec.encode(bw.out[:0], bw.buf)
+	if err != nil {
+		bw.err = err
+		return err
+	}
+	if err := bw.write(bw.out); err != nil {
+		return err
+	}
+	bw.meta.BlockData = append(bw.meta.BlockData, uint32(len(bw.out)))
+	bw.size += int64(len(bw.buf))
+	bw.buf = bw.buf[:0]
+	return nil
+}
+
+// Close flushes the last block and writes the trailer
+func (bw *blockWriter) Close() error {
+	if len(bw.buf) > 0 {
+		if err := bw.flush(); err != nil {
+			return err
+		}
+	}
+	if err := bw.write(bw.codec.trailer(&bw.meta, bw.size)); err != nil {
+		return err
+	}
+	return bw.codec.Close()
+}
+
+// blockReader decompresses data written by a blockWriter starting at
+// any offset
+type blockReader struct {
+	in        io.ReadSeeker
+	codec     blockCodec
+	meta      *BlockMetadata
+	size      int64  // total uncompressed size
+	block     int    // index of the next block to read
+	buf       []byte // compressed data
+	out       []byte // uncompressed data not yet returned
+	outBuffer []byte
+}
+
+// newBlockReader makes a reader for the data in in which will return
+// data from offset onwards
+func newBlockReader(in io.ReadSeeker, codec blockCodec, meta *BlockMetadata, size int64, offset int64) (*blockReader, error) {
+	if meta == nil || meta.BlockSize <= 0 {
+		return nil, errors.New("missing block metadata")
+	}
+	if offset < 0 || offset > size {
+		return nil, errors.Errorf("offset %d out of range", offset)
+	}
+	br := &blockReader{
+		in:    in,
+		codec: codec,
+		meta:  meta,
+		size:  size,
+		block: int(offset / int64(meta.BlockSize)),
+	}
+	pos := meta.Offset
+	for i := 0; i < br.block && i < len(meta.BlockData); i++ {
+		pos += int64(meta.BlockData[i])
+	}
+	if _, err := in.Seek(pos, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if skip := int(offset % int64(meta.BlockSize)); skip > 0 {
+		if err := br.next(); err != nil {
+			return nil, err
+		}
+		br.out = br.out[skip:]
+	}
+	return br, nil
+}
+
+// next reads and decompresses the next block into out
+func (br *blockReader) next() error {
+	if br.block >= len(br.meta.BlockData) {
+		return io.EOF
+	}
+	uncompressed := int64(br.meta.BlockSize)
+	if remaining := br.size - int64(br.block)*uncompressed; remaining < uncompressed {
+		uncompressed = remaining
+	}
+	n := int(br.meta.BlockData[br.block])
+	if cap(br.buf) < n {
+		br.buf = make([]byte, n)
+	}
+	br.buf = br.buf[:n]
+	if _, err := io.ReadFull(br.in, br.buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return errors.Wrapf(err, "failed to read block %d", br.block)
+	}
+	if cap(br.outBuffer) < int(uncompressed) {
+		br.outBuffer = make([]byte, uncompressed)
+	}
+	br.out = br.outBuffer[:uncompressed]
+	if err := br.codec.decode(br.out, br.buf, len(br.out)); err != nil {
+		return errors.Wrapf(err, "failed to decompress block %d", br.block)
+	}
+	br.block++
+	return nil
+}
+
+// Read decompressed data - see io.Reader
+func (br *blockReader) Read(p []byte) (n int, err error) {
+	for len(br.out) == 0 {
+		if err = br.next(); err != nil {
+			return 0, err
+		}
+	}
+	n = copy(p, br.out)
+	br.out = br.out[n:]
+	return n, nil
+}
+
+// Close releases the codec
+func (br *blockReader) Close() error {
+	return br.codec.Close()
+}
+
+// zstdCodec writes each block as a zstd frame and finishes with a
+// seek table in the zstd seekable format.
+type zstdCodec struct {
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+}
+
+// Magic numbers for the zstd seekable format
+const (
+	zstdSkippableMagic = 0x184D2A5E
+	zstdSeekableMagic  = 0x8F92EAB1
+)
+
+// newZstdCodec makes a zstd codec compressing at level where -1
+// means the default level
+func newZstdCodec(level int) (*zstdCodec, error) {
+	encLevel := zstd.SpeedDefault
+	if level >= 0 {
+		encLevel = zstd.EncoderLevelFromZstd(level)
+	}
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(encLevel), zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	dec, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		_ = enc.Close()
+		return nil, err
+	}
+	return &zstdCodec{enc: enc, dec: dec}, nil
+}
+
+func (c *zstdCodec) header() []byte {
+	return nil
+}
+
+func (c *zstdCodec) encode(dst, src []byte) ([]byte, error) {
+	return c.enc.EncodeAll(src, dst), nil
+}
+
+func (c *zstdCodec) decode(dst, src []byte, size int) error {
+	out, err := c.dec.DecodeAll(src, dst[:0])
+	if err != nil {
+		return err
+	}
+	if len(out) != size {
+		return errors.Errorf("expecting %d bytes but got %d", size, len(out))
+	}
+	if size > 0 && &out[0] != &dst[0] {
+		copy(dst, out)
+	}
+	return nil
+}
+
+// trailer returns the seek table as a skippable frame which zstd
+// ignores when decompressing the whole file
+func (c *zstdCodec) trailer(meta *BlockMetadata, size int64) []byte {
+	n := len(meta.BlockData)
+	buf := make([]byte, 8, 8+8*n+9)
+	binary.LittleEndian.PutUint32(buf[0:], zstdSkippableMagic)
+	binary.LittleEndian.PutUint32(buf[4:], uint32(8*n+9))
+	for i, compressed := range meta.BlockData {
+		uncompressed := int64(meta.BlockSize)
+		if remaining := size - int64(i)*uncompressed; remaining < uncompressed {
+			uncompressed = remaining
+		}
+		buf = appendUint32(buf, compressed)
+		buf = appendUint32(buf, uint32(uncompressed))
+	}
+	buf = appendUint32(buf, uint32(n))
+	buf = append(buf, 0) // seek table descriptor - no checksums
+	buf = appendUint32(buf, zstdSeekableMagic)
+	return buf
+}
+
+func (c *zstdCodec) Close() error {
+	c.dec.Close()
+	return c.enc.Close()
+}
+
+// lz4Codec writes a single LZ4 frame made of independent blocks
+type lz4Codec struct{}
+
+// LZ4 frame format constants
+const (
+	lz4FrameMagic     = 0x184D2204
+	lz4FlagVersion    = 1 << 6 // version 01
+	lz4FlagBlockIndep = 1 << 5 // blocks are independent
+	lz4BlockMax1MB    = 6 << 4 // maximum block size 1 MiB
+	lz4Uncompressed   = 1 << 31
+)
+
+func (lz4Codec) header() []byte {
+	buf := make([]byte, 4, 7)
+	binary.LittleEndian.PutUint32(buf, lz4FrameMagic)
+	descriptor := []byte{lz4FlagVersion | lz4FlagBlockIndep, lz4BlockMax1MB}
+	buf = append(buf, descriptor...)
+	return append(buf, byte(xxh32(descriptor)>>8))
+}
+
+// encode writes the block with its size prefix, storing it
+// uncompressed if it doesn't compress
+func (lz4Codec) encode(dst, src []byte) ([]byte, error) {
+	// lz4.Encode prefixes the block with its uncompressed size which
+	// is exactly the space needed for the block size
+	out, err := lz4.Encode(dst[:cap(dst)], src)
+	if err != nil {
+		return nil, err
+	}
+	if n := len(out) - 4; n < len(src) {
+		binary.LittleEndian.PutUint32(out, uint32(n))
+		return out, nil
+	}
+	out = appendUint32(dst[:0], uint32(len(src))|lz4Uncompressed)
+	return append(out, src...), nil
+}
+
+func (lz4Codec) decode(dst, src []byte, size int) error {
+	if len(src) < 4 {
+		return io.ErrUnexpectedEOF
+	}
+	n := binary.LittleEndian.Uint32(src)
+	if n&lz4Uncompressed != 0 {
+		if int(n&^lz4Uncompressed) != size || len(src)-4 != size {
+			return errors.New("bad uncompressed block size")
+		}
+		copy(dst, src[4:])
+		return nil
+	}
+	// lz4.Decode wants the block prefixed with its uncompressed size
+	// so replace the block size with it
+	binary.LittleEndian.PutUint32(src, uint32(size))
+	out, err := lz4.Decode(dst, src)
+	if err != nil {
+		return err
+	}
+	if len(out) != size {
+		return errors.Errorf("expecting %d bytes but got %d", size, len(out))
+	}
+	return nil
+}
+
+// trailer returns the end mark of the frame
+func (lz4Codec) trailer(meta *BlockMetadata, size int64) []byte {
+	return make([]byte, 4)
+}
+
+func (lz4Codec) Close() error {
+	return nil
+}
+
+// appendUint32 appends x to buf in little endian order
+func appendUint32(buf []byte, x uint32) []byte {
+	return append(buf, byte(x), byte(x>>8), byte(x>>16), byte(x>>24))
+}
+
+// xxh32 returns the 32 bit xxHash of short inputs (less than 16
+// bytes) with seed 0 as used for the LZ4 frame header checksum
+func xxh32(p []byte) uint32 {
+	const (
+		prime1 = 2654435761
+		prime2 = 2246822519
+		prime3 = 3266489917
+		prime4 = 668265263
+		prime5 = 374761393
+	)
+	h := uint32(prime5) + uint32(len(p))
+	for ; len(p) >= 4; p = p[4:] {
+		h += binary.LittleEndian.Uint32(p) * prime3
+		h = bits.RotateLeft32(h, 17) * prime4
+	}
+	for _, b := range p {
+		h += uint32(b) * prime5
+		h = bits.RotateLeft32(h, 11) * prime1
+	}
+	h ^= h >> 15
+	h *= prime2
+	h ^= h >> 13
+	h *= prime3
+	h ^= h >> 16
+	return h
+}
@@ -12,10 +12,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"path"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/andybalholm/brotli"
 	"github.com/buengese/sgzip"
 	"github.com/gabriel-vasile/mimetype"
 
@@ -28,6 +31,7 @@ import (
 	"github.com/rclone/rclone/fs/fspath"
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/lib/resume"
 )
 
 // Globals
@@ -39,27 +43,63 @@ const (
 	heuristicBytes      = 1048576
 	minCompressionRatio = 1.1
 
-	gzFileExt           = ".gz"
 	metaFileExt         = ".json"
 	uncompressedFileExt = ".bin"
 )
 
 // Compression modes
+//
+// These are stored in the metadata of each object so must not change.
 const (
 	Uncompressed = 0
 	Gzip         = 2
+	Zstd         = 3
+	Lz4          = 4
+	Brotli       = 5
 )
 
+// compressionMode describes a compression mode
+type compressionMode struct {
+	mode int    // id of the mode stored in the metadata
+	name string // name of the mode in the config
+	ext  string // extension of the data files
+	help string
+}
+
+// compressionModes lists the supported compression modes
+var compressionModes = []compressionMode{{
+	mode: Gzip,
+	name: "gzip",
+	ext:  ".gz",
+	help: "Standard gzip compression with fastest parameters.",
+}, {
+	mode: Zstd,
+	name: "zstd",
+	ext:  ".zst",
+	help: "Zstandard compression in seekable frames.\nFaster and stronger than gzip.",
+}, {
+	mode: Lz4,
+	name: "lz4",
+	ext:  ".lz4",
+	help: "LZ4 compression in independent blocks.\nVery fast but compresses less than the others.",
+}, {
+	mode: Brotli,
+	name: "brotli",
+	ext:  ".br",
+	help: "Brotli compression.\nStrong but slow to compress. Ranged reads decompress from the start of the file.",
+}}
+
 var nameRegexp = regexp.MustCompile("^(.+?)\\.([A-Za-z0-9+_]{11})$")
 
 // Register with Fs
 func init() {
 	// Build compression mode options.
-	compressionModeOptions := []fs.OptionExample{
-		{ // Default compression mode options {
-			Value: "gzip",
-			Help:  "Standard gzip compression with fastest parameters.",
-		},
+	compressionModeOptions := make([]fs.OptionExample, len(compressionModes))
+	for i, cm := range compressionModes {
+		compressionModeOptions[i] = fs.OptionExample{
+			Value: cm.name,
+			Help:  cm.help,
+		}
 	}
 
 	// Register our remote
@@ -78,26 +118,59 @@ func init() {
 			Examples: compressionModeOptions,
 		}, {
 			Name: "level",
-			Help: `GZIP compression level (-2 to 9).
-
-			Generally -1 (default, equivalent to 5) is recommended.
-			Levels 1 to 9 increase compressiong at the cost of speed.. Going past 6 
-			generally offers very little return.
-			
-			Level -2 uses Huffmann encoding only. Only use if you now what you
-			are doing
-			Level 0 turns off compression.`,
+			Help: `Compression level.
+
+For gzip the level is -2 to 9. Generally -1 (default, equivalent to 5)
+is recommended. Levels 1 to 9 increase compression at the cost of
+speed. Going past 6 generally offers very little return. Level -2 uses
+Huffman encoding only. Only use if you know what you are doing.
+Level 0 turns off compression.
+
+For zstd the level is 1 to 22 as for the zstd command line tool,
+though the levels are mapped onto fewer internal settings.
+
+For brotli the level is 0 to 11.
+
+lz4 has no levels. -1 selects the default level for all the modes.`,
 			Default:  sgzip.DefaultCompression,
 			Advanced: true,
+		}, {
+			Name: "min_size",
+			Help: `Files smaller than this are stored uncompressed.
+
+Small files gain little from compression so this can be used to save
+the overhead of compressing them. Files of unknown size are always
+considered for compression.`,
+			Default:  fs.SizeSuffix(0),
+			Advanced: true,
+		}, {
+			Name: "include_ext",
+			Help: `Comma separated list of file extensions to compress.
+
+If set only files with one of these extensions (e.g. "log,txt,csv")
+are compressed and all other files are stored uncompressed.`,
+			Default:  fs.CommaSepList{},
+			Advanced: true,
+		}, {
+			Name: "exclude_ext",
+			Help: `Comma separated list of file extensions never to compress.
+
+Files with one of these extensions (e.g. "jpg,mp4,zip") are stored
+uncompressed without trying to compress them.`,
+			Default:  fs.CommaSepList{},
+			Advanced: true,
 		}},
 	})
 }
 
 // Options defines the configuration for this backend
 type Options struct {
-	Remote           string `config:"remote"`
-	CompressionMode  string `config:"mode"`
-	CompressionLevel int    `config:"level"`
+	Remote           string          `config:"remote"`
+	CompressionMode  string          `config:"mode"`
+	CompressionLevel int             `config:"level"`
+	MinSize          fs.SizeSuffix   `config:"min_size"`
+	IncludeExt       fs.CommaSepList `config:"include_ext"`
+	ExcludeExt       fs.CommaSepList `config:"exclude_ext"`
 }
 
 /*** FILESYSTEM FUNCTIONS ***/
@@ -105,12 +178,14 @@ type Options struct {
 // Fs represents a wrapped fs.Fs
 type Fs struct {
 	fs.Fs
-	wrapper  fs.Fs
-	name     string
-	root     string
-	opt      Options
-	mode     int          // compression mode id
-	features *fs.Features // optional features
+	wrapper    fs.Fs
+	name       string
+	root       string
+	opt        Options
+	mode       int                 // compression mode id
+	includeExt map[string]struct{} // only compress files with these extensions if set
+	excludeExt map[string]struct{} // never compress files with these extensions
+	features   *fs.Features        // optional features
 }
 
 // NewFs contstructs an Fs from the path, container:path
@@ -126,6 +201,10 @@ func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs,
 	if strings.HasPrefix(remote, name+":") {
 		return nil, errors.New("can't point press remote at itself - check the value of the remote setting")
 	}
+	mode := compressionModeFromName(opt.CompressionMode)
+	if mode == Uncompressed {
+		return nil, errors.Errorf("unknown compression mode %q", opt.CompressionMode)
+	}
 
 	wInfo, wName, wPath, wConfig, err := fs.ConfigFs(remote)
 	if err != nil {
@@ -149,11 +228,13 @@ func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs,
 
 	// Create the wrapping fs
 	f := &Fs{
-		Fs:   wrappedFs,
-		name: name,
-		root: rpath,
-		opt:  *opt,
-		mode: compressionModeFromName(opt.CompressionMode),
+		Fs:         wrappedFs,
+		name:       name,
+		root:       rpath,
+		opt:        *opt,
+		mode:       mode,
+		includeExt: makeExtSet(opt.IncludeExt),
+		excludeExt: makeExtSet(opt.ExcludeExt),
 	}
 	// the features here are ones we could support, and they are
 	// ANDed with the ones from wrappedFs
@@ -177,13 +258,65 @@ func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs,
 	return f, err
 }
 
+// compressionModeFromName returns the mode called name or
+// Uncompressed if not found
 func compressionModeFromName(name string) int {
-	switch name {
-	case "gzip":
-		return Gzip
-	default:
-		return Uncompressed
+	for _, cm := range compressionModes {
+		if strings.EqualFold(cm.name, name) {
+			return cm.mode
+		}
+	}
+	return Uncompressed
+}
+
+// compressionModeFromExt returns the mode whose data files have
+// extension ext or Uncompressed if not found
+func compressionModeFromExt(ext string) int {
+	for _, cm := range compressionModes {
+		if cm.ext == ext {
+			return cm.mode
+		}
+	}
+	return Uncompressed
+}
+
+// compressionModeExt returns the data file extension for mode
+func compressionModeExt(mode int) string {
+	for _, cm := range compressionModes {
+		if cm.mode == mode {
+			return cm.ext
+		}
+	}
+	return uncompressedFileExt
+}
+
+// makeExtSet makes a set of lower case file extensions without the
+// leading "." from exts
+func makeExtSet(exts fs.CommaSepList) map[string]struct{} {
+	set := make(map[string]struct{}, len(exts))
+	for _, ext := range exts {
+		ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+		if ext != "" {
+			set[ext] = struct{}{}
+		}
+	}
+	return set
+}
+
+// wantCompress returns whether the size and extension policy allows
+// src to be compressed
+func (f *Fs) wantCompress(src fs.ObjectInfo) bool {
+	if size := src.Size(); size >= 0 && size < int64(f.opt.MinSize) {
+		return false
+	}
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(src.Remote()), "."))
+	if len(f.includeExt) > 0 {
+		if _, found := f.includeExt[ext]; !found {
+			return false
+		}
 	}
+	_, excluded := f.excludeExt[ext]
+	return !excluded
 }
 
 // Converts an int64 to base64
@@ -215,6 +348,9 @@ func processFileName(compressedFileName string) (origFileName string, extension
 	if extension == uncompressedFileExt {
 		return nameWithSize, extension, -2, nil
 	}
+	if compressionModeFromExt(extension) == Uncompressed {
+		return "", "", 0, errors.New("Unknown file extension")
+	}
 	match := nameRegexp.FindStringSubmatch(nameWithSize)
 	if match == nil || len(match) != 3 {
 		return "", "", 0, errors.New("Invalid filename")
@@ -223,7 +359,7 @@ func processFileName(compressedFileName string) (origFileName string, extension
 	if err != nil {
 		return "", "", 0, errors.New("Could not decode size")
 	}
-	return match[1], gzFileExt, size, nil
+	return match[1], extension, size, nil
 }
 
 // Generates the file name for a metadata file
@@ -239,7 +375,7 @@ func isMetadataFile(filename string) bool {
 // makeDataName generates the file name for a data file with specified compression mode
 func makeDataName(remote string, size int64, mode int) (newRemote string) {
 	if mode != Uncompressed {
-		newRemote = remote + "." + int64ToBase64(size) + gzFileExt
+		newRemote = remote + "." + int64ToBase64(size) + compressionModeExt(mode)
 	} else {
 		newRemote = remote + uncompressedFileExt
 	}
@@ -352,13 +488,15 @@ func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
 		return nil, errors.New("error decoding metadata")
 	}
 	// Create our Object
-	o, err := f.Fs.NewObject(ctx, makeDataName(remote, meta.CompressionMetadata.Size, meta.Mode))
+	o, err := f.Fs.NewObject(ctx, makeDataName(remote, meta.Size, meta.Mode))
 	return f.newObject(o, mo, meta), err
 }
 
 // checkCompressAndType checks if an object is compressible and determines it's mime type
 // returns a multireader with the bytes that were read to determine mime type
-func checkCompressAndType(in io.Reader) (newReader io.Reader, compressible bool, mimeType string, err error) {
+//
+// Objects which the size and extension policy excludes are never compressible.
+func (f *Fs) checkCompressAndType(in io.Reader, src fs.ObjectInfo) (newReader io.Reader, compressible bool, mimeType string, err error) {
 	in, wrap := accounting.UnWrap(in)
 	buf := make([]byte, heuristicBytes)
 	n, err := in.Read(buf)
@@ -367,9 +505,11 @@ func checkCompressAndType(in io.Reader) (newReader io.Reader, compressible bool,
 		return nil, false, "", err
 	}
 	mime := mimetype.Detect(buf)
-	compressible, err = isCompressible(bytes.NewReader(buf))
-	if err != nil {
-		return nil, false, "", err
+	if f.wantCompress(src) {
+		compressible, err = isCompressible(bytes.NewReader(buf))
+		if err != nil {
+			return nil, false, "", err
+		}
 	}
 	in = io.MultiReader(bytes.NewReader(buf), in)
 	return wrap(in), compressible, mime.String(), nil
@@ -411,9 +551,89 @@ func (f *Fs) verifyObjectHash(ctx context.Context, o fs.Object, hasher *hash.Mul
 
 type putFn func(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error)
 
+// compressor is an io.WriteCloser which compresses data in one of
+// the compression modes
+type compressor interface {
+	io.WriteCloser
+	// Size returns the number of uncompressed bytes written
+	Size() int64
+	// setMetadata records the details needed to read the data back in meta
+	setMetadata(meta *ObjectMetadata)
+}
+
+// gzipCompressor compresses with sgzip
+type gzipCompressor struct {
+	*sgzip.Writer
+}
+
+func (c gzipCompressor) Size() int64 {
+	return c.MetaData().Size
+}
+
+func (c gzipCompressor) setMetadata(meta *ObjectMetadata) {
+	meta.CompressionMetadata = c.MetaData()
+}
+
+// blockCompressor compresses in independent blocks with a blockCodec
+type blockCompressor struct {
+	*blockWriter
+}
+
+func (c blockCompressor) Size() int64 {
+	return c.size
+}
+
+func (c blockCompressor) setMetadata(meta *ObjectMetadata) {
+	blockMeta := c.meta
+	meta.BlockMetadata = &blockMeta
+}
+
+// brotliCompressor compresses with brotli as a single stream
+type brotliCompressor struct {
+	*brotli.Writer
+	size int64
+}
+
+func (c *brotliCompressor) Write(p []byte) (n int, err error) {
+	n, err = c.Writer.Write(p)
+	c.size += int64(n)
+	return n, err
+}
+
+func (c *brotliCompressor) Size() int64 {
+	return c.size
+}
+
+func (c *brotliCompressor) setMetadata(meta *ObjectMetadata) {}
+
+// newCompressor makes a compressor for the configured mode writing to w
+func (f *Fs) newCompressor(w io.Writer) (compressor, error) {
+	level := f.opt.CompressionLevel
+	switch f.mode {
+	case Gzip:
+		gz, err := sgzip.NewWriterLevel(w, level)
+		if err != nil {
+			return nil, err
+		}
+		return gzipCompressor{gz}, nil
+	case Zstd, Lz4:
+		codec, err := newBlockCodec(f.mode, level)
+		if err != nil {
+			return nil, err
+		}
+		return blockCompressor{newBlockWriter(w, codec)}, nil
+	case Brotli:
+		if level < 0 {
+			level = brotli.DefaultCompression
+		}
+		return &brotliCompressor{Writer: brotli.NewWriterLevel(w, level)}, nil
+	}
+	return nil, errors.Errorf("unknown compression mode %d", f.mode)
+}
+
 type compressionResult struct {
 	err  error
-	meta sgzip.GzipMetadata
+	comp compressor
 }
 
 // Put a compressed version of a file. Returns a wrappable object and metadata.
@@ -429,17 +649,18 @@ func (f *Fs) putCompress(ctx context.Context, in io.Reader, src fs.ObjectInfo, o
 	pipeReader, pipeWriter := io.Pipe()
 	results := make(chan compressionResult)
 	go func() {
-		gz, err := sgzip.NewWriterLevel(pipeWriter, f.opt.CompressionLevel)
+		comp, err := f.newCompressor(pipeWriter)
 		if err != nil {
-			results <- compressionResult{err: err, meta: sgzip.GzipMetadata{}}
+			_ = pipeWriter.CloseWithError(err)
+			results <- compressionResult{err: err}
 			return
 		}
-		_, err = io.Copy(gz, in)
-		gzErr := gz.Close()
-		if gzErr != nil {
-			fs.Errorf(nil, "Failed to close compress: %v", gzErr)
+		_, err = io.Copy(comp, in)
+		compErr := comp.Close()
+		if compErr != nil {
+			fs.Errorf(nil, "Failed to close compress: %v", compErr)
 			if err == nil {
-				err = gzErr
+				err = compErr
 			}
 		}
 		closeErr := pipeWriter.Close()
@@ -449,7 +670,7 @@ func (f *Fs) putCompress(ctx context.Context, in io.Reader, src fs.ObjectInfo, o
 				err = closeErr
 			}
 		}
-		results <- compressionResult{err: err, meta: gz.MetaData()}
+		results <- compressionResult{err: err, comp: comp}
 	}()
 	wrappedIn := wrap(bufio.NewReaderSize(pipeReader, bufferSize)) // Probably no longer needed as sgzip has it's own buffering
 
@@ -496,7 +717,8 @@ func (f *Fs) putCompress(ctx context.Context, in io.Reader, src fs.ObjectInfo, o
 	}
 
 	// Generate metadata
-	meta := newMetadata(result.meta.Size, f.mode, result.meta, hex.EncodeToString(metaHasher.Sum(nil)), mimeType)
+	meta := newMetadata(result.comp.Size(), f.mode, sgzip.GzipMetadata{}, hex.EncodeToString(metaHasher.Sum(nil)), mimeType)
+	result.comp.setMetadata(meta)
 
 	// Check the hashes of the compressed data if we were comparing them
 	if ht != hash.None && hasher != nil {
@@ -579,6 +801,9 @@ func (f *Fs) putMetadata(ctx context.Context, meta *ObjectMetadata, src fs.Objec
 // putData is the function used for data, while putMeta is the function used for metadata.
 func (f *Fs) putWithCustomFunctions(ctx context.Context, in io.Reader, src fs.ObjectInfo, options []fs.OpenOption,
 	putData putFn, putMeta putFn, compressible bool, mimeType string) (*Object, error) {
+	// The wrapped remote sees the compressed data under a different
+	// name so it can't resume the upload
+	ctx = resume.Without(ctx)
 	// Put file then metadata
 	var dataObject fs.Object
 	var meta *ObjectMetadata
@@ -617,7 +842,7 @@ func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options .
 	o, err := f.NewObject(ctx, src.Remote())
 	if err == fs.ErrorObjectNotFound {
 		// Get our file compressibility
-		in, compressible, mimeType, err := checkCompressAndType(in)
+		in, compressible, mimeType, err := f.checkCompressAndType(in, src)
 		if err != nil {
 			return nil, err
 		}
@@ -637,7 +862,7 @@ func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, opt
 	}
 	found := err == nil
 
-	in, compressible, mimeType, err := checkCompressAndType(in)
+	in, compressible, mimeType, err := f.checkCompressAndType(in, src)
 	if err != nil {
 		return nil, err
 	}
@@ -898,7 +1123,6 @@ func (f *Fs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryT
 		return
 	}
 	wrappedNotifyFunc := func(path string, entryType fs.EntryType) {
-		fs.Logf(f, "path %q entryType %d", path, entryType)
 		var (
 			wrappedPath string
 		)
@@ -906,9 +1130,18 @@ func (f *Fs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryT
 		case fs.EntryDirectory:
 			wrappedPath = path
 		case fs.EntryObject:
-			// Note: All we really need to do to monitor the object is to check whether the metadata changed,
-			// as the metadata contains the hash. This will work unless there's a hash collision and the sizes stay the same.
-			wrappedPath = makeMetadataName(path)
+			// The change may be to the metadata file or the data file
+			// so translate either back to the name of the object
+			if isMetadataFile(path) {
+				wrappedPath = strings.TrimSuffix(path, metaFileExt)
+			} else {
+				origFileName, _, _, err := processFileName(path)
+				if err != nil {
+					fs.Debugf(path, "press ChangeNotify: ignoring change to unknown file: %v", err)
+					return
+				}
+				wrappedPath = origFileName
+			}
 		default:
 			fs.Errorf(path, "press ChangeNotify: ignoring unknown EntryType %d", entryType)
 			return
@@ -941,6 +1174,7 @@ type ObjectMetadata struct {
 	MD5                 string // MD5 hash of the file.
 	MimeType            string // Mime type of the file
 	CompressionMetadata sgzip.GzipMetadata
+	BlockMetadata       *BlockMetadata `json:",omitempty"` // Blocks of zstd and lz4 compressed files
 }
 
 // Object with external metadata
@@ -1016,7 +1250,7 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 		return o.mo, o.mo.Update(ctx, in, src, options...)
 	}
 
-	in, compressible, mimeType, err := checkCompressAndType(in)
+	in, compressible, mimeType, err := o.f.checkCompressAndType(in, src)
 	if err != nil {
 		return err
 	}
@@ -1176,6 +1410,24 @@ func (o *Object) GetTier() string {
 	return do.GetTier()
 }
 
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *Object) Metadata(ctx context.Context) (fs.Metadata, error) {
+	return fs.GetMetadata(ctx, o.Object)
+}
+
+// SetMetadata sets metadata for an Object
+//
+// It should return fs.ErrorNotImplemented if it can't set metadata
+func (o *Object) SetMetadata(ctx context.Context, metadata fs.Metadata) error {
+	do, ok := o.Object.(fs.SetMetadataer)
+	if !ok {
+		return fs.ErrorNotImplemented
+	}
+	return do.SetMetadata(ctx, metadata)
+}
+
 // UnWrap returns the wrapped Object
 func (o *Object) UnWrap() fs.Object {
 	return o.Object
@@ -1206,14 +1458,42 @@ func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (rc io.Read
 	}
 	// Get a chunkedreader for the wrapped object
 	chunkedReader := chunkedreader.New(ctx, o.Object, initialChunkSize, maxChunkSize)
+	var closer io.Closer = chunkedReader
 	// Get file handle
 	var file io.Reader
-	if offset != 0 {
-		file, err = sgzip.NewReaderAt(chunkedReader, &o.meta.CompressionMetadata, offset)
-	} else {
-		file, err = sgzip.NewReader(chunkedReader)
+	switch o.meta.Mode {
+	case Gzip:
+		if offset != 0 {
+			file, err = sgzip.NewReaderAt(chunkedReader, &o.meta.CompressionMetadata, offset)
+		} else {
+			file, err = sgzip.NewReader(chunkedReader)
+		}
+	case Zstd, Lz4:
+		var codec blockCodec
+		codec, err = newBlockCodec(o.meta.Mode, -1)
+		if err != nil {
+			break
+		}
+		var br *blockReader
+		br, err = newBlockReader(chunkedReader, codec, o.meta.BlockMetadata, o.meta.Size, offset)
+		if err != nil {
+			_ = codec.Close()
+			break
+		}
+		file = br
+		closer = multiCloser{br, chunkedReader}
+	case Brotli:
+		// brotli streams can't be read from the middle so
+		// decompress and discard up to the offset
+		file = brotli.NewReader(chunkedReader)
+		if offset != 0 {
+			_, err = io.CopyN(ioutil.Discard, file, offset)
+		}
+	default:
+		err = errors.Errorf("unknown compression mode %d", o.meta.Mode)
 	}
 	if err != nil {
+		_ = chunkedReader.Close()
 		return nil, err
 	}
 
@@ -1224,7 +1504,20 @@ func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (rc io.Read
 		fileReader = file
 	}
 	// Return a ReadCloser
-	return ReadCloserWrapper{Reader: fileReader, Closer: chunkedReader}, nil
+	return ReadCloserWrapper{Reader: fileReader, Closer: closer}, nil
+}
+
+// multiCloser closes all its Closers returning the first error
+type multiCloser []io.Closer
+
+// Close all the Closers
+func (mc multiCloser) Close() (err error) {
+	for _, c := range mc {
+		if closeErr := c.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
 }
 
 // ObjectInfo describes a wrapped fs.ObjectInfo for being the source
@@ -1354,5 +1647,7 @@ var (
 	_ fs.Object          = (*Object)(nil)
 	_ fs.ObjectUnWrapper = (*Object)(nil)
 	_ fs.IDer            = (*Object)(nil)
+	_ fs.Metadataer      = (*Object)(nil)
+	_ fs.SetMetadataer   = (*Object)(nil)
 	_ fs.MimeTyper       = (*Object)(nil)
 )
@@ -0,0 +1,117 @@
+package compress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXXH32(t *testing.T) {
+	assert.Equal(t, uint32(0x02CC5D05), xxh32(nil))
+	assert.Equal(t, uint32(0x550D7456), xxh32([]byte("a")))
+	assert.Equal(t, uint32(0x32D153FF), xxh32([]byte("abc")))
+}
+
+// makeTestData makes size bytes of partly compressible data
+func makeTestData(size int) []byte {
+	data := make([]byte, size)
+	r := rand.New(rand.NewSource(1))
+	for i := range data {
+		if i%1000 < 500 {
+			data[i] = byte(r.Intn(256))
+		} else {
+			data[i] = byte(i % 7)
+		}
+	}
+	return data
+}
+
+func TestBlockReadWrite(t *testing.T) {
+	for _, mode := range []int{Zstd, Lz4} {
+		for _, size := range []int{0, 1, 1000, blockSize, 2*blockSize + 12345} {
+			t.Run(fmt.Sprintf("mode=%d,size=%d", mode, size), func(t *testing.T) {
+				data := makeTestData(size)
+				codec, err := newBlockCodec(mode, -1)
+				require.NoError(t, err)
+				var buf bytes.Buffer
+				bw := newBlockWriter(&buf, codec)
+				_, err = bw.Write(data)
+				require.NoError(t, err)
+				require.NoError(t, bw.Close())
+				assert.Equal(t, int64(size), bw.size)
+
+				for _, offset := range []int{0, 1, size / 2, blockSize, blockSize + 1, size} {
+					if offset > size {
+						continue
+					}
+					codec, err := newBlockCodec(mode, -1)
+					require.NoError(t, err)
+					br, err := newBlockReader(bytes.NewReader(buf.Bytes()), codec, &bw.meta, int64(size), int64(offset))
+					require.NoError(t, err)
+					got, err := ioutil.ReadAll(br)
+					require.NoError(t, err)
+					require.NoError(t, br.Close())
+					assert.Equal(t, data[offset:], got, "offset %d", offset)
+				}
+			})
+		}
+	}
+}
+
+func TestBlockReaderErrors(t *testing.T) {
+	codec, err := newBlockCodec(Lz4, -1)
+	require.NoError(t, err)
+	_, err = newBlockReader(bytes.NewReader(nil), codec, nil, 10, 0)
+	assert.Error(t, err)
+	_, err = newBlockReader(bytes.NewReader(nil), codec, &BlockMetadata{BlockSize: blockSize}, 10, 11)
+	assert.Error(t, err)
+
+	// Truncated data
+	var buf bytes.Buffer
+	bw := newBlockWriter(&buf, codec)
+	_, err = bw.Write(makeTestData(1000))
+	require.NoError(t, err)
+	require.NoError(t, bw.Close())
+	br, err := newBlockReader(bytes.NewReader(buf.Bytes()[:100]), codec, &bw.meta, 1000, 0)
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(br)
+	assert.Equal(t, io.ErrUnexpectedEOF, errors.Cause(err))
+}
+
+func TestWantCompress(t *testing.T) {
+	f := &Fs{
+		opt:        Options{MinSize: 100},
+		excludeExt: makeExtSet(fs.CommaSepList{".JPG", "zip"}),
+	}
+	for _, test := range []struct {
+		remote string
+		size   int64
+		want   bool
+	}{
+		{"file.txt", 1000, true},
+		{"file.txt", 99, false},
+		{"file.txt", -1, true},
+		{"file.jpg", 1000, false},
+		{"dir/file.ZIP", 1000, false},
+		{"file", 1000, true},
+	} {
+		src := object.NewStaticObjectInfo(test.remote, time.Now(), test.size, true, nil, nil)
+		assert.Equal(t, test.want, f.wantCompress(src), test.remote)
+	}
+
+	f.includeExt = makeExtSet(fs.CommaSepList{"log", "txt"})
+	for remote, want := range map[string]bool{"a.log": true, "a.csv": false, "a": false} {
+		src := object.NewStaticObjectInfo(remote, time.Now(), 1000, true, nil, nil)
+		assert.Equal(t, want, f.wantCompress(src), remote)
+	}
+}
@@ -6,13 +6,20 @@ import (
 	"crypto/md5"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
 	"testing"
 	"time"
 
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/backend/memory"
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/obscure"
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/object"
 	"github.com/rclone/rclone/lib/random"
+	"github.com/rclone/rclone/lib/resume"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -141,3 +148,107 @@ func (f *Fs) InternalTest(t *testing.T) {
 	t.Run("ObjectInfoWrap", func(t *testing.T) { testObjectInfo(t, f, true) })
 	t.Run("ComputeHash", func(t *testing.T) { testComputeHash(t, f) })
 }
+
+// resumeTestFs wraps a memory remote and resumes uploads like the
+// backends which support --resume-uploads do. The first upload is
+// interrupted half way through.
+type resumeTestFs struct {
+	fs.Fs
+	interrupt bool
+}
+
+// Put uploads the rest of the data after anything saved by an
+// interrupted upload
+func (f *resumeTestFs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	var uploaded []byte
+	up := resume.Get(ctx)
+	if up != nil {
+		found, err := up.Load(&uploaded)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			if err := resume.Skip(in, int64(len(uploaded))); err != nil {
+				return nil, err
+			}
+		}
+	}
+	rest, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	data := append(uploaded, rest...)
+	if f.interrupt {
+		f.interrupt = false
+		if up != nil {
+			if err := up.Save(data[:len(data)/2]); err != nil {
+				return nil, err
+			}
+		}
+		return nil, errors.New("upload interrupted")
+	}
+	if up != nil {
+		up.Remove()
+	}
+	return f.Fs.Put(ctx, bytes.NewReader(data), src, options...)
+}
+
+func TestResumeUpload(t *testing.T) {
+	ctx := context.Background()
+	oldDir := resume.Dir
+	dir, err := ioutil.TempDir("", "rclone-crypt-resume-test")
+	require.NoError(t, err)
+	resume.Dir = dir
+	defer func() {
+		resume.Dir = oldDir
+		_ = os.RemoveAll(dir)
+	}()
+
+	wrapped := &resumeTestFs{interrupt: true}
+	fs.Register(&fs.RegInfo{
+		Name: "cryptresumetest",
+		NewFs: func(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+			var err error
+			wrapped.Fs, err = memory.NewFs(ctx, name, root, m)
+			return wrapped, err
+		},
+	})
+	f, err := NewFs(ctx, "TestCryptResume", "", configmap.Simple{
+		"remote":              ":cryptresumetest:bucket",
+		"password":            obscure.MustObscure("potato"),
+		"filename_encryption": "standard",
+	})
+	require.NoError(t, err)
+
+	localFs, cleanupLocalFs := makeTempLocalFs(t)
+	defer cleanupLocalFs()
+	contents := random.String(1000)
+	src, cleanupSrc := uploadFile(t, localFs, "file.txt", contents)
+	defer cleanupSrc()
+
+	// Upload as operations.Copy does with --resume-uploads
+	upload := func() (fs.Object, error) {
+		in, err := src.Open(ctx)
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, in.Close())
+		}()
+		uploadCtx := resume.WithUpload(ctx, f, "file.txt", fs.Fingerprint(ctx, src, true))
+		return f.Put(uploadCtx, in, src)
+	}
+	_, err = upload()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "upload interrupted")
+
+	// The retry uploads a new ciphertext so must not resume the
+	// upload of the old one
+	dst, err := upload()
+	require.NoError(t, err)
+	in, err := dst.Open(ctx)
+	require.NoError(t, err)
+	got, err := ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, contents, string(got))
+	require.NoError(t, dst.Remove(ctx))
+}
@@ -0,0 +1,171 @@
+package dedup
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/filter"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fs/walk"
+)
+
+// defaultMinAge is how old an unreferenced chunk must be before gc
+// removes it, so chunks of files still being uploaded are kept
+const defaultMinAge = fs.Duration(time.Hour)
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "gc",
+	Short: "Remove unreferenced chunks",
+	Long: `Count the references to each chunk from the manifests of all
+the files in the remote and remove the chunks which aren't referenced
+by any.  The whole remote is checked whatever the path given.
+
+Unreferenced chunks newer than the "min-age" option (default 1h) are
+kept as they may belong to files still being uploaded.  Don't run gc
+while other rclone processes are uploading to the same remote.
+
+Usage Example:
+
+    rclone backend gc dedup:
+    rclone backend gc -o min-age=0 dedup:
+    rclone backend gc --dry-run dedup:
+`,
+	Opts: map[string]string{
+		"min-age": "only remove unreferenced chunks older than this",
+	},
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "gc":
+		minAge := defaultMinAge
+		if s, ok := opt["min-age"]; ok {
+			err = minAge.Set(s)
+			if err != nil {
+				return nil, errors.Wrap(err, "bad min-age")
+			}
+		}
+		return f.gc(ctx, time.Duration(minAge))
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// gcStats is the result of the gc command
+type gcStats struct {
+	Manifests    int   `json:"manifests"`    // number of manifests read
+	Chunks       int   `json:"chunks"`       // number of chunks in the store
+	Referenced   int   `json:"referenced"`   // number of chunks referenced by manifests
+	References   int   `json:"references"`   // total number of references to chunks
+	Deleted      int   `json:"deleted"`      // number of chunks deleted
+	DeletedBytes int64 `json:"deletedBytes"` // size of the chunks deleted
+}
+
+// countRefs returns the number of references to each chunk from all
+// the manifests in the wrapped remote
+func (f *Fs) countRefs(ctx context.Context, stats *gcStats) (refs map[string]int, err error) {
+	top, err := cache.Get(ctx, f.opt.Remote)
+	if err != nil && err != fs.ErrorIsFile {
+		return nil, err
+	}
+	// Read every manifest, skipping the chunk store
+	filterOpt := filter.DefaultOpt
+	filterOpt.ExcludeRule = []string{"/" + storeDir + "/**"}
+	fi, err := filter.NewFilter(&filterOpt)
+	if err != nil {
+		return nil, err
+	}
+	ctx = filter.ReplaceConfig(ctx, fi)
+	refs = map[string]int{}
+	err = walk.ListR(ctx, top, "", true, -1, walk.ListObjects, func(entries fs.DirEntries) error {
+		for _, entry := range entries {
+			mo, ok := entry.(fs.Object)
+			if !ok || !strings.HasSuffix(mo.Remote(), manifestSuffix) || isStorePath(mo.Remote()) {
+				continue
+			}
+			// Any unreadable manifest stops the gc as its chunks
+			// would otherwise be removed
+			meta, err := readManifest(ctx, mo)
+			if err != nil {
+				return errors.Wrapf(err, "%s", mo.Remote())
+			}
+			stats.Manifests++
+			for _, ref := range meta.Chunks {
+				refs[ref.Hash]++
+				stats.References++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to count chunk references")
+	}
+	return refs, nil
+}
+
+// gc removes the chunks older than minAge which no manifest refers to
+func (f *Fs) gc(ctx context.Context, minAge time.Duration) (*gcStats, error) {
+	stats := new(gcStats)
+	// The chunks are found by the manifests so ignore the filters
+	fi, err := filter.NewFilter(nil)
+	if err != nil {
+		return nil, err
+	}
+	ctx = filter.ReplaceConfig(ctx, fi)
+	refs, err := f.countRefs(ctx, stats)
+	if err != nil {
+		return nil, err
+	}
+
+	var unreferenced []fs.Object
+	err = walk.ListR(ctx, f.store, "", true, -1, walk.ListObjects, func(entries fs.DirEntries) error {
+		for _, entry := range entries {
+			o, ok := entry.(fs.Object)
+			if !ok {
+				continue
+			}
+			stats.Chunks++
+			if refs[path.Base(o.Remote())] > 0 {
+				stats.Referenced++
+				continue
+			}
+			if age := time.Since(o.ModTime(ctx)); age < minAge {
+				fs.Debugf(o, "Keeping unreferenced chunk as it is only %v old", age.Truncate(time.Second))
+				continue
+			}
+			unreferenced = append(unreferenced, o)
+		}
+		return nil
+	})
+	if err == fs.ErrorDirNotFound {
+		return stats, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to list chunks")
+	}
+
+	for _, o := range unreferenced {
+		f.markChunk(path.Base(o.Remote()), false)
+		err = operations.DeleteFile(ctx, o)
+		if err != nil {
+			return stats, err
+		}
+		stats.Deleted++
+		stats.DeletedBytes += o.Size()
+	}
+	fs.Infof(f, "Removed %d unreferenced chunks (%v) of %d", stats.Deleted, fs.SizeSuffix(stats.DeletedBytes), stats.Chunks)
+	return stats, nil
+}
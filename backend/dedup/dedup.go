@@ -0,0 +1,573 @@
+// Package dedup implements a deduplicating overlay backend which
+// stores files as content defined chunks
+package dedup
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/lib/resume"
+)
+
+const (
+	// storeDir is the directory at the top of the wrapped remote
+	// which holds the chunks
+	storeDir = ".dedup"
+
+	// manifestSuffix is added to the name of each file to make the
+	// name of its manifest on the wrapped remote
+	manifestSuffix = ".dedup"
+
+	// manifestVersion is the version of the manifest format
+	manifestVersion = 1
+
+	// minChunkSize is the smallest allowed average chunk size
+	minChunkSize = 64
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "dedup",
+		Description: "Deduplicate files in other remotes with content defined chunks",
+		NewFs:       NewFs,
+		CommandHelp: commandHelp,
+		Options: []fs.Option{{
+			Name:     "remote",
+			Required: true,
+			Help: `Remote to store the deduplicated files in (e.g. myRemote:path).
+
+The chunks are stored in the "` + storeDir + `" directory at the top of
+this path.`,
+		}, {
+			Name:     "chunk_size",
+			Advanced: true,
+			Default:  fs.SizeSuffix(1024 * 1024),
+			Help: `Average size of the chunks files are split into.
+
+Chunks are between a quarter and four times this size.  Smaller chunks
+find more duplicated data but need more chunks to be stored.  Changing
+this stops new files sharing chunks with the files already stored.`,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Remote    string        `config:"remote"`
+	ChunkSize fs.SizeSuffix `config:"chunk_size"`
+}
+
+// Fs represents a wrapped fs.Fs
+type Fs struct {
+	fs.Fs
+	name     string
+	root     string
+	wrapper  fs.Fs
+	features *fs.Features
+	opt      *Options
+	store    fs.Fs // the chunk store on the wrapped remote
+
+	chunksMu sync.Mutex
+	chunks   map[string]struct{} // chunks known to be in the store
+}
+
+// NewFs constructs an Fs from the path, container:path
+func NewFs(ctx context.Context, fsname, rpath string, cmap configmap.Mapper) (fs.Fs, error) {
+	// Parse config into Options struct
+	opt := &Options{}
+	err := configstruct.Set(cmap, opt)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(opt.Remote, fsname+":") {
+		return nil, errors.New("can't point dedup remote at itself - check the value of the remote setting")
+	}
+	if opt.ChunkSize < minChunkSize {
+		return nil, errors.Errorf("chunk_size must be at least %v", fs.SizeSuffix(minChunkSize))
+	}
+	if isStorePath(path.Clean("/" + rpath)[1:]) {
+		return nil, errors.Errorf("can't use the chunk store %q as the root", storeDir)
+	}
+
+	remotePath := fspath.JoinRootPath(opt.Remote, rpath)
+	baseFs, err := cache.Get(ctx, remotePath)
+	if err != nil && err != fs.ErrorIsFile {
+		return nil, errors.Wrapf(err, "failed to make remote %q to wrap", remotePath)
+	}
+	storePath := fspath.JoinRootPath(opt.Remote, storeDir)
+	store, err := cache.Get(ctx, storePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to make chunk store %q", storePath)
+	}
+
+	f := &Fs{
+		Fs:     baseFs,
+		name:   fsname,
+		root:   rpath,
+		opt:    opt,
+		store:  store,
+		chunks: map[string]struct{}{},
+	}
+
+	// The files are stored as manifests so check to see if rpath
+	// points to one
+	isFile := false
+	if rpath != "" {
+		parent := path.Dir(rpath)
+		if parent == "." {
+			parent = ""
+		}
+		parentFs, err := cache.Get(ctx, fspath.JoinRootPath(opt.Remote, parent))
+		if err == nil {
+			_, err = parentFs.NewObject(ctx, path.Base(rpath)+manifestSuffix)
+			if err == nil {
+				f.Fs = parentFs
+				f.root = parent
+				isFile = true
+			}
+		}
+	}
+	cache.PinUntilFinalized(f.Fs, f)
+
+	// the features here are ones we could support, and they are
+	// ANDed with the ones from baseFs
+	f.features = (&fs.Features{
+		CaseInsensitive:         true,
+		DuplicateFiles:          false,
+		ReadMimeType:            false,
+		WriteMimeType:           false,
+		CanHaveEmptyDirectories: true,
+		BucketBased:             true,
+		BucketBasedRootOK:       true,
+		SetTier:                 false,
+		GetTier:                 false,
+		ServerSideAcrossConfigs: true,
+	}).Fill(ctx, f).Mask(ctx, f.Fs).WrapsFs(f, f.Fs)
+	// Files are copied by copying the manifest and streamed uploads
+	// are chunked so these don't need the wrapped remote to support
+	// them
+	f.features.Copy = f.Copy
+	f.features.PutStream = f.PutStream
+	// The size, modification time and hashes are read from the
+	// manifest
+	f.features.SlowModTime = true
+	f.features.SlowHash = true
+	// Metadata is stored in the manifest too
+	f.features.ReadMetadata = true
+	f.features.WriteMetadata = true
+	f.features.UserMetadata = true
+
+	if isFile {
+		return f, fs.ErrorIsFile
+	}
+	return f, nil
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("dedup::%s:%s", f.name, f.root)
+}
+
+// Precision of the ModTimes in this Fs
+//
+// The modification times are stored in the manifests.
+func (f *Fs) Precision() time.Duration {
+	return time.Nanosecond
+}
+
+// Hashes returns the supported hash sets.
+func (f *Fs) Hashes() hash.Set {
+	return hash.NewHashSet(hash.MD5, hash.SHA1)
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *Fs) UnWrap() fs.Fs {
+	return f.Fs
+}
+
+// WrapFs returns the Fs that is wrapping this Fs
+func (f *Fs) WrapFs() fs.Fs {
+	return f.wrapper
+}
+
+// SetWrapper sets the Fs that is wrapping this Fs
+func (f *Fs) SetWrapper(wrapper fs.Fs) {
+	f.wrapper = wrapper
+}
+
+// isStorePath returns true if p, relative to the top of the wrapped
+// remote, is in the chunk store
+func isStorePath(p string) bool {
+	return p == storeDir || strings.HasPrefix(p, storeDir+"/")
+}
+
+// inStore returns true if remote is in the chunk store
+func (f *Fs) inStore(remote string) bool {
+	return isStorePath(path.Join(f.root, remote))
+}
+
+// manifestName returns the name of the manifest for remote
+func manifestName(remote string) string {
+	return remote + manifestSuffix
+}
+
+// chunkPath returns the path of the chunk with hash in the store
+func chunkPath(hash string) string {
+	return path.Join(hash[:2], hash)
+}
+
+// processEntries turns the manifests in entries into Objects and
+// removes the chunk store
+func (f *Fs) processEntries(entries fs.DirEntries) (newEntries fs.DirEntries) {
+	newEntries = entries[:0] // in place filter
+	for _, entry := range entries {
+		if f.inStore(entry.Remote()) {
+			continue
+		}
+		switch x := entry.(type) {
+		case fs.Object:
+			remote := x.Remote()
+			if !strings.HasSuffix(remote, manifestSuffix) {
+				fs.Debugf(x, "Ignoring file which isn't a dedup manifest")
+				continue
+			}
+			newEntries = append(newEntries, f.newObject(strings.TrimSuffix(remote, manifestSuffix), x, nil))
+		default:
+			newEntries = append(newEntries, entry) // directory or unknown type
+		}
+	}
+	return newEntries
+}
+
+// List the objects and directories in dir into entries.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	if f.inStore(dir) {
+		return nil, fs.ErrorDirNotFound
+	}
+	if entries, err = f.Fs.List(ctx, dir); err != nil {
+		return nil, err
+	}
+	return f.processEntries(entries), nil
+}
+
+// ListR lists the objects and directories recursively into out.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	if f.inStore(dir) {
+		return fs.ErrorDirNotFound
+	}
+	return f.Fs.Features().ListR(ctx, dir, func(entries fs.DirEntries) error {
+		return callback(f.processEntries(entries))
+	})
+}
+
+// NewObject finds the Object at remote.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	if f.inStore(remote) {
+		return nil, fs.ErrorObjectNotFound
+	}
+	mo, err := f.Fs.NewObject(ctx, manifestName(remote))
+	if err != nil {
+		return nil, err
+	}
+	o := f.newObject(remote, mo, nil)
+	err = o.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Mkdir makes the directory (container, bucket)
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	if f.inStore(dir) {
+		return errors.Errorf("can't make directory in the chunk store %q", storeDir)
+	}
+	return f.Fs.Mkdir(ctx, dir)
+}
+
+// haveChunk returns true if the chunk is in the store
+func (f *Fs) haveChunk(ctx context.Context, ref chunkRef) bool {
+	f.chunksMu.Lock()
+	_, found := f.chunks[ref.Hash]
+	f.chunksMu.Unlock()
+	if found {
+		return true
+	}
+	o, err := f.store.NewObject(ctx, chunkPath(ref.Hash))
+	if err != nil {
+		return false
+	}
+	if o.Size() != ref.Size {
+		fs.Errorf(o, "Chunk has wrong size %d - expecting %d - uploading again", o.Size(), ref.Size)
+		return false
+	}
+	f.markChunk(ref.Hash, true)
+	return true
+}
+
+// markChunk records whether the chunk is in the store
+func (f *Fs) markChunk(hash string, present bool) {
+	f.chunksMu.Lock()
+	if present {
+		f.chunks[hash] = struct{}{}
+	} else {
+		delete(f.chunks, hash)
+	}
+	f.chunksMu.Unlock()
+}
+
+// putChunk uploads data to the store unless it is there already,
+// returning whether it was uploaded
+func (f *Fs) putChunk(ctx context.Context, data []byte) (ref chunkRef, uploaded bool, err error) {
+	sum := sha256.Sum256(data)
+	ref = chunkRef{
+		Hash: hex.EncodeToString(sum[:]),
+		Size: int64(len(data)),
+	}
+	if f.haveChunk(ctx, ref) {
+		return ref, false, nil
+	}
+	src := object.NewStaticObjectInfo(chunkPath(ref.Hash), time.Now(), ref.Size, true, nil, f.store)
+	_, err = f.store.Put(ctx, bytes.NewReader(data), src)
+	if err != nil {
+		return ref, false, errors.Wrapf(err, "failed to upload chunk %s", ref.Hash)
+	}
+	f.markChunk(ref.Hash, true)
+	return ref, true, nil
+}
+
+// upload splits in into chunks, uploads the ones which aren't in the
+// store and returns the manifest for the data
+func (f *Fs) upload(ctx context.Context, in io.Reader, src fs.ObjectInfo, options []fs.OpenOption) (*manifest, error) {
+	hasher, err := hash.NewMultiHasherTypes(f.Hashes())
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := fs.GetMetadataOptions(ctx, src, options)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read metadata from source object")
+	}
+	meta := &manifest{
+		Version:  manifestVersion,
+		ModTime:  src.ModTime(ctx),
+		Metadata: metadata,
+		Chunks:   []chunkRef{},
+	}
+	var newChunks int
+	splitter := newSplitter(io.TeeReader(in, hasher), int(f.opt.ChunkSize))
+	for {
+		data, err := splitter.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		ref, uploaded, err := f.putChunk(ctx, data)
+		if err != nil {
+			return nil, err
+		}
+		if uploaded {
+			newChunks++
+		}
+		meta.Chunks = append(meta.Chunks, ref)
+		meta.Size += ref.Size
+	}
+	if size := src.Size(); size >= 0 && size != meta.Size {
+		return nil, errors.Errorf("upload size mismatch: got %d expecting %d", meta.Size, size)
+	}
+	sums := hasher.Sums()
+	meta.MD5 = sums[hash.MD5]
+	meta.SHA1 = sums[hash.SHA1]
+	fs.Debugf(src, "Stored as %d chunks of which %d were new", len(meta.Chunks), newChunks)
+	return meta, nil
+}
+
+// putFn is the signature of the functions used to upload manifests
+type putFn func(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error)
+
+// putManifest writes meta as the manifest for remote with put
+func (f *Fs) putManifest(ctx context.Context, remote string, meta *manifest, put putFn) (fs.Object, error) {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode manifest")
+	}
+	src := object.NewStaticObjectInfo(manifestName(remote), meta.ModTime, int64(len(data)), true, nil, f.Fs)
+	mo, err := put(ctx, bytes.NewReader(data), src)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to upload manifest")
+	}
+	return mo, nil
+}
+
+// put uploads in as remote chunking it into the store
+func (f *Fs) put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options []fs.OpenOption) (fs.Object, error) {
+	remote := src.Remote()
+	if f.inStore(remote) {
+		return nil, errors.Errorf("can't upload into the chunk store %q", storeDir)
+	}
+	// The chunks and manifest are uploaded to different names so
+	// the wrapped remote can't resume them
+	ctx = resume.Without(ctx)
+	meta, err := f.upload(ctx, in, src, options)
+	if err != nil {
+		return nil, err
+	}
+	mo, err := f.putManifest(ctx, remote, meta, f.Fs.Put)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(remote, mo, meta), nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.put(ctx, in, src, options)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.put(ctx, in, src, options)
+}
+
+// sameStore returns true if src stores its chunks in the same place as f
+func (f *Fs) sameStore(src *Fs) bool {
+	return fs.ConfigString(f.store) == fs.ConfigString(src.store)
+}
+
+// Copy src to this remote using server-side copy operations.
+//
+// Only the manifest is copied as the chunks are shared.
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok || !f.sameStore(srcObj.f) {
+		fs.Debugf(src, "Can't copy - not same chunk store")
+		return nil, fs.ErrorCantCopy
+	}
+	err := srcObj.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	meta := *srcObj.meta
+	mo, err := f.putManifest(ctx, remote, &meta, f.Fs.Put)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(remote, mo, &meta), nil
+}
+
+// Move src to this remote using server-side move operations.
+//
+// Only the manifest is moved as the chunks are shared.
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Move
+	if do == nil {
+		return nil, fs.ErrorCantMove
+	}
+	srcObj, ok := src.(*Object)
+	if !ok || !f.sameStore(srcObj.f) {
+		fs.Debugf(src, "Can't move - not same chunk store")
+		return nil, fs.ErrorCantMove
+	}
+	mo, err := do(ctx, srcObj.mo, manifestName(remote))
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(remote, mo, srcObj.meta), nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	do := f.Fs.Features().DirMove
+	if do == nil {
+		return fs.ErrorCantDirMove
+	}
+	srcFs, ok := src.(*Fs)
+	if !ok || !f.sameStore(srcFs) {
+		fs.Debugf(srcFs, "Can't move directory - not same chunk store")
+		return fs.ErrorCantDirMove
+	}
+	return do(ctx, srcFs.Fs, srcRemote, dstRemote)
+}
+
+// CleanUp the trash in the Fs
+func (f *Fs) CleanUp(ctx context.Context) error {
+	do := f.Fs.Features().CleanUp
+	if do == nil {
+		return errors.New("can't CleanUp")
+	}
+	return do(ctx)
+}
+
+// About gets quota information from the Fs
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	do := f.Fs.Features().About
+	if do == nil {
+		return nil, errors.New("About not supported")
+	}
+	return do(ctx)
+}
+
+// DirCacheFlush resets the directory cache - used in testing
+// as an optional interface
+func (f *Fs) DirCacheFlush() {
+	if do := f.Fs.Features().DirCacheFlush; do != nil {
+		do()
+	}
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*Fs)(nil)
+	_ fs.Copier          = (*Fs)(nil)
+	_ fs.Mover           = (*Fs)(nil)
+	_ fs.DirMover        = (*Fs)(nil)
+	_ fs.Commander       = (*Fs)(nil)
+	_ fs.PutStreamer     = (*Fs)(nil)
+	_ fs.CleanUpper      = (*Fs)(nil)
+	_ fs.UnWrapper       = (*Fs)(nil)
+	_ fs.ListRer         = (*Fs)(nil)
+	_ fs.Abouter         = (*Fs)(nil)
+	_ fs.Wrapper         = (*Fs)(nil)
+	_ fs.DirCacheFlusher = (*Fs)(nil)
+	_ fs.Object          = (*Object)(nil)
+	_ fs.Metadataer      = (*Object)(nil)
+	_ fs.SetMetadataer   = (*Object)(nil)
+)
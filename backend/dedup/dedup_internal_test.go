@@ -0,0 +1,182 @@
+package dedup
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fs/walk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// randomData returns n bytes of repeatable random data
+func randomData(seed int64, n int) []byte {
+	data := make([]byte, n)
+	_, _ = rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
+
+// split returns the chunks of data made with an average size of avg
+func split(t *testing.T, data []byte, avg int) (chunks [][]byte) {
+	s := newSplitter(bytes.NewReader(data), avg)
+	for {
+		chunk, err := s.next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		chunks = append(chunks, append([]byte(nil), chunk...))
+	}
+	return chunks
+}
+
+func TestSplitter(t *testing.T) {
+	const avg = 1024
+	data := randomData(1, 256*1024)
+	chunks := split(t, data, avg)
+	assert.Equal(t, data, bytes.Join(chunks, nil))
+	for i, chunk := range chunks {
+		assert.True(t, len(chunk) <= 4*avg, "chunk %d too big", i)
+		if i < len(chunks)-1 {
+			assert.True(t, len(chunk) > avg/4, "chunk %d too small", i)
+		}
+	}
+	// The average should be roughly right
+	mean := len(data) / len(chunks)
+	assert.True(t, mean > avg/2 && mean < avg*2, "mean chunk size %d", mean)
+
+	// Inserting data only changes the chunks near the insertion
+	edited := append(append(append([]byte(nil), data[:100000]...), "inserted"...), data[100000:]...)
+	editedChunks := split(t, edited, avg)
+	seen := map[string]bool{}
+	for _, chunk := range chunks {
+		seen[string(chunk)] = true
+	}
+	changed := 0
+	for _, chunk := range editedChunks {
+		if !seen[string(chunk)] {
+			changed++
+		}
+	}
+	assert.True(t, changed <= 2, "%d chunks changed", changed)
+
+	// Empty input has no chunks
+	assert.Equal(t, 0, len(split(t, nil, avg)))
+}
+
+// newTestFs makes a dedup Fs on a temporary directory
+func newTestFs(t *testing.T) (f *Fs, dir string, cleanup func()) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "rclone-dedup-internal-test")
+	require.NoError(t, err)
+	fsi, err := NewFs(ctx, "TestDedupInternal", "", configmap.Simple{
+		"remote":     dir,
+		"chunk_size": "1k",
+	})
+	require.NoError(t, err)
+	return fsi.(*Fs), dir, func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+// put uploads data to remote on f
+func put(ctx context.Context, t *testing.T, f fs.Fs, remote string, data []byte) fs.Object {
+	src := object.NewStaticObjectInfo(remote, time.Now(), int64(len(data)), true, nil, nil)
+	o, err := f.Put(ctx, bytes.NewReader(data), src)
+	require.NoError(t, err)
+	return o
+}
+
+// read reads the contents of o in the range given
+func read(ctx context.Context, t *testing.T, o fs.Object, options ...fs.OpenOption) []byte {
+	in, err := o.Open(ctx, options...)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	return data
+}
+
+// countChunks returns the number of chunks in the store of f
+func countChunks(ctx context.Context, t *testing.T, f *Fs) int {
+	objs, _, err := walk.GetAll(ctx, f.store, "", true, -1)
+	require.NoError(t, err)
+	return len(objs)
+}
+
+func TestDedupAndGC(t *testing.T) {
+	ctx := context.Background()
+	f, dir, cleanup := newTestFs(t)
+	defer cleanup()
+
+	data := randomData(2, 64*1024)
+	o1 := put(ctx, t, f, "one", data)
+	n := countChunks(ctx, t, f)
+	assert.True(t, n > 10, "only %d chunks", n)
+
+	// The same data with a small edit only adds a few chunks
+	edited := append([]byte("prefix"), data...)
+	o2 := put(ctx, t, f, "dir/two", edited)
+	assert.True(t, countChunks(ctx, t, f) <= n+2)
+
+	// The store is hidden but the manifests are on the wrapped remote
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(entries))
+	_, err = os.Stat(filepath.Join(dir, "dir", "two"+manifestSuffix))
+	assert.NoError(t, err)
+
+	// Read back whole and in part
+	o, err := f.NewObject(ctx, "dir/two")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(edited)), o.Size())
+	assert.Equal(t, edited, read(ctx, t, o))
+	assert.Equal(t, edited[5000:20000], read(ctx, t, o, &fs.RangeOption{Start: 5000, End: 19999}))
+	assert.Equal(t, edited[60000:], read(ctx, t, o, &fs.SeekOption{Offset: 60000}))
+
+	// Copying is done by copying the manifest
+	chunks := countChunks(ctx, t, f)
+	o3, err := f.Copy(ctx, o1, "three")
+	require.NoError(t, err)
+	assert.Equal(t, data, read(ctx, t, o3))
+	assert.Equal(t, chunks, countChunks(ctx, t, f))
+
+	// gc keeps referenced chunks
+	stats, err := f.gc(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Manifests)
+	assert.Equal(t, chunks, stats.Chunks)
+	assert.Equal(t, chunks, stats.Referenced)
+	assert.Equal(t, 0, stats.Deleted)
+
+	// Removing files leaves chunks until gc which doesn't
+	// remove new chunks unless asked
+	require.NoError(t, o1.Remove(ctx))
+	require.NoError(t, o3.Remove(ctx))
+	stats, err = f.gc(ctx, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.Deleted)
+	assert.Equal(t, chunks, countChunks(ctx, t, f))
+
+	stats, err = f.gc(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Manifests)
+	assert.True(t, stats.Deleted > 0 && stats.Deleted <= 2, "deleted %d", stats.Deleted)
+	assert.Equal(t, chunks-stats.Deleted, countChunks(ctx, t, f))
+	assert.Equal(t, edited, read(ctx, t, o2))
+
+	// Uploading the removed data again restores the chunks
+	o1 = put(ctx, t, f, "one", data)
+	assert.Equal(t, data, read(ctx, t, o1))
+}
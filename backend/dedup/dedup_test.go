@@ -0,0 +1,68 @@
+// Test Dedup filesystem interface
+package dedup_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rclone/rclone/backend/dedup"
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+var (
+	unimplementableFsMethods = []string{
+		"OpenWriterAt",
+		"MergeDirs",
+		"PutUnchecked",
+		"ChangeNotify",
+		"PublicLink",
+		"Purge",
+		"UserInfo",
+		"Disconnect",
+		"Shutdown",
+		"Link",
+	}
+	unimplementableObjectMethods = []string{
+		"MimeType",
+		"GetTier",
+		"SetTier",
+		"ID",
+		"UnWrap",
+	}
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	if *fstest.RemoteName == "" {
+		t.Skip("Skipping as -remote not set")
+	}
+	fstests.Run(t, &fstests.Opt{
+		RemoteName:                   *fstest.RemoteName,
+		NilObject:                    (*dedup.Object)(nil),
+		UnimplementableFsMethods:     unimplementableFsMethods,
+		UnimplementableObjectMethods: unimplementableObjectMethods,
+	})
+}
+
+// TestLocal runs the integration tests against a local directory
+func TestLocal(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	tempdir := filepath.Join(os.TempDir(), "rclone-dedup-test")
+	name := "TestDedupLocal"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName:                   name + ":",
+		NilObject:                    (*dedup.Object)(nil),
+		UnimplementableFsMethods:     unimplementableFsMethods,
+		UnimplementableObjectMethods: unimplementableObjectMethods,
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "dedup"},
+			{Name: name, Key: "remote", Value: tempdir},
+			{Name: name, Key: "chunk_size", Value: "1k"},
+		},
+	})
+}
@@ -0,0 +1,336 @@
+package dedup
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/resume"
+)
+
+// manifest describes how a file is made from chunks
+type manifest struct {
+	Version  int         `json:"ver"`
+	Size     int64       `json:"size"`
+	ModTime  time.Time   `json:"modtime"`
+	MD5      string      `json:"md5,omitempty"`
+	SHA1     string      `json:"sha1,omitempty"`
+	Metadata fs.Metadata `json:"metadata,omitempty"`
+	Chunks   []chunkRef  `json:"chunks"`
+}
+
+// chunkRef is a reference to a chunk in the store
+type chunkRef struct {
+	Hash string `json:"hash"` // hex SHA-256 of the chunk
+	Size int64  `json:"size"`
+}
+
+// readManifest reads and decodes the manifest in mo
+func readManifest(ctx context.Context, mo fs.Object) (meta *manifest, err error) {
+	in, err := mo.Open(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open manifest")
+	}
+	defer fs.CheckClose(in, &err)
+	meta = new(manifest)
+	err = json.NewDecoder(in).Decode(meta)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode manifest")
+	}
+	if meta.Version != manifestVersion {
+		return nil, errors.Errorf("unsupported manifest version %d", meta.Version)
+	}
+	return meta, nil
+}
+
+// Object is a file stored as a manifest of chunks
+type Object struct {
+	f      *Fs
+	remote string
+	mo     fs.Object // the manifest on the wrapped remote
+
+	mu   sync.Mutex
+	meta *manifest // the decoded manifest - nil if not loaded yet
+}
+
+// newObject makes an Object for remote with the manifest mo. The
+// manifest is read on demand if meta is nil.
+func (f *Fs) newObject(remote string, mo fs.Object, meta *manifest) *Object {
+	return &Object{
+		f:      f,
+		remote: remote,
+		mo:     mo,
+		meta:   meta,
+	}
+}
+
+// load reads the manifest if it isn't loaded already
+func (o *Object) load(ctx context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.meta != nil {
+		return nil
+	}
+	meta, err := readManifest(ctx, o.mo)
+	if err != nil {
+		return errors.Wrapf(err, "%s", o.remote)
+	}
+	o.meta = meta
+	return nil
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Size returns the size of the file
+func (o *Object) Size() int64 {
+	err := o.load(context.Background())
+	if err != nil {
+		fs.Errorf(o, "Failed to read size: %v", err)
+		return -1
+	}
+	return o.meta.Size
+}
+
+// ModTime returns the modification time of the file
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	err := o.load(ctx)
+	if err != nil {
+		fs.Errorf(o, "Failed to read modification time: %v", err)
+		return o.mo.ModTime(ctx)
+	}
+	return o.meta.ModTime
+}
+
+// Hash returns the selected checksum of the file
+func (o *Object) Hash(ctx context.Context, ht hash.Type) (string, error) {
+	err := o.load(ctx)
+	if err != nil {
+		return "", err
+	}
+	switch ht {
+	case hash.MD5:
+		return o.meta.MD5, nil
+	case hash.SHA1:
+		return o.meta.SHA1, nil
+	}
+	return "", hash.ErrUnsupported
+}
+
+// Storable returns whether object is storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// SetModTime sets the modification time of the file
+//
+// The manifest is rewritten with the new time.
+func (o *Object) SetModTime(ctx context.Context, t time.Time) error {
+	err := o.load(ctx)
+	if err != nil {
+		return err
+	}
+	meta := *o.meta
+	meta.ModTime = t
+	return o.updateManifest(ctx, &meta)
+}
+
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *Object) Metadata(ctx context.Context) (fs.Metadata, error) {
+	err := o.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return o.meta.Metadata, nil
+}
+
+// SetMetadata sets metadata for an Object
+//
+// The metadata is merged into the manifest which is rewritten.
+func (o *Object) SetMetadata(ctx context.Context, metadata fs.Metadata) error {
+	err := o.load(ctx)
+	if err != nil {
+		return err
+	}
+	meta := *o.meta
+	meta.Metadata = nil
+	meta.Metadata.Merge(o.meta.Metadata)
+	meta.Metadata.Merge(metadata)
+	return o.updateManifest(ctx, &meta)
+}
+
+// updateManifest replaces the manifest of o with meta
+func (o *Object) updateManifest(ctx context.Context, meta *manifest) error {
+	update := func(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+		return o.mo, o.mo.Update(ctx, in, src, options...)
+	}
+	_, err := o.f.putManifest(ctx, o.remote, meta, update)
+	if err != nil {
+		return err
+	}
+	o.mu.Lock()
+	o.meta = meta
+	o.mu.Unlock()
+	return nil
+}
+
+// Update in to the object with the modTime given of the given size
+//
+// The chunks only used by the old contents are left in the store for
+// the gc command to remove.
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	ctx = resume.Without(ctx)
+	meta, err := o.f.upload(ctx, in, src, options)
+	if err != nil {
+		return err
+	}
+	return o.updateManifest(ctx, meta)
+}
+
+// Remove an object
+//
+// Only the manifest is removed - the chunks are left in the store for
+// the gc command to remove.
+func (o *Object) Remove(ctx context.Context) error {
+	return o.mo.Remove(ctx)
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	err := o.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	size := o.meta.Size
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.RangeOption:
+			offset, limit = x.Decode(size)
+		case *fs.SeekOption:
+			offset = x.Offset
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	if offset > size {
+		offset = size
+	}
+	if limit < 0 || offset+limit > size {
+		limit = size - offset
+	}
+	return &chunkReader{
+		ctx:       ctx,
+		o:         o,
+		chunks:    o.meta.Chunks,
+		offset:    offset,
+		remaining: limit,
+	}, nil
+}
+
+// chunkReader reads a range of a file from its chunks
+type chunkReader struct {
+	ctx       context.Context
+	o         *Object
+	chunks    []chunkRef    // chunks not opened yet
+	offset    int64         // offset to start reading from in chunks
+	remaining int64         // bytes left to read
+	in        io.ReadCloser // current chunk being read - nil if none
+	inLeft    int64         // bytes left to read from in
+}
+
+// openChunk opens the chunk containing the offset
+func (r *chunkReader) openChunk() error {
+	for len(r.chunks) > 0 && r.offset >= r.chunks[0].Size {
+		r.offset -= r.chunks[0].Size
+		r.chunks = r.chunks[1:]
+	}
+	if len(r.chunks) == 0 {
+		return errors.Wrap(io.ErrUnexpectedEOF, "manifest has too few chunks")
+	}
+	ref := r.chunks[0]
+	r.chunks = r.chunks[1:]
+	co, err := r.o.f.store.NewObject(r.ctx, chunkPath(ref.Hash))
+	if err != nil {
+		return errors.Wrapf(err, "failed to find chunk %s", ref.Hash)
+	}
+	r.inLeft = ref.Size - r.offset
+	if r.inLeft > r.remaining {
+		r.inLeft = r.remaining
+	}
+	var options []fs.OpenOption
+	if r.offset > 0 || r.inLeft < ref.Size {
+		options = append(options, &fs.RangeOption{Start: r.offset, End: r.offset + r.inLeft - 1})
+	}
+	r.offset = 0
+	r.in, err = co.Open(r.ctx, options...)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open chunk %s", ref.Hash)
+	}
+	return nil
+}
+
+// Read bytes from the chunks
+func (r *chunkReader) Read(p []byte) (n int, err error) {
+	for r.remaining > 0 {
+		if r.in == nil {
+			err = r.openChunk()
+			if err != nil {
+				return 0, err
+			}
+		}
+		if int64(len(p)) > r.inLeft {
+			p = p[:r.inLeft]
+		}
+		n, err = r.in.Read(p)
+		r.inLeft -= int64(n)
+		r.remaining -= int64(n)
+		if r.inLeft > 0 {
+			if err == io.EOF {
+				err = errors.Wrap(io.ErrUnexpectedEOF, "chunk too short")
+			}
+			return n, err
+		}
+		// Finished with this chunk
+		err = r.in.Close()
+		r.in = nil
+		if err != nil || n > 0 {
+			return n, err
+		}
+	}
+	return 0, io.EOF
+}
+
+// Close the reader
+func (r *chunkReader) Close() error {
+	if r.in == nil {
+		return nil
+	}
+	err := r.in.Close()
+	r.in = nil
+	return err
+}
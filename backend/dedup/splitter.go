@@ -0,0 +1,89 @@
+package dedup
+
+import (
+	"io"
+	"math/bits"
+)
+
+// gearTable is the table of random values used by the rolling hash.
+//
+// It is made with splitmix64 from a fixed seed and must never change
+// as it decides where files are split into chunks.
+var gearTable = func() (table [256]uint64) {
+	x := uint64(0x72636c6f6e656364)
+	for i := range table {
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
+
+// splitter splits a stream into content defined chunks.
+//
+// A gear hash is rolled over the data after the minimum chunk size and
+// a chunk is cut when the top bits of the hash are all zero, so the
+// chunk boundaries depend only on the nearby data.  This means that
+// inserting or removing data only changes the chunks around the edit.
+type splitter struct {
+	in   io.Reader
+	buf  []byte // buffer holding up to the maximum chunk size
+	n    int    // number of bytes of data in buf
+	used int    // number of bytes of buf returned in the last chunk
+	eof  bool   // set when in is exhausted
+	min  int    // minimum chunk size
+	mask uint64 // cut when the hash ANDed with this is zero
+}
+
+// newSplitter makes a splitter which reads from in and makes chunks
+// of about avg bytes which are between a quarter and four times avg
+func newSplitter(in io.Reader, avg int) *splitter {
+	min, max := avg/4, avg*4
+	maskBits := uint(bits.Len(uint(avg-min)) - 1)
+	return &splitter{
+		in:   in,
+		buf:  make([]byte, max),
+		min:  min,
+		mask: ((1 << maskBits) - 1) << (64 - maskBits),
+	}
+}
+
+// next returns the next chunk of the input which is only valid until
+// the next call.  It returns io.EOF when there are no more chunks.
+func (s *splitter) next() (chunk []byte, err error) {
+	// Move the unused data to the start of the buffer and fill it
+	copy(s.buf, s.buf[s.used:s.n])
+	s.n -= s.used
+	s.used = 0
+	if !s.eof && s.n < len(s.buf) {
+		n, err := io.ReadFull(s.in, s.buf[s.n:])
+		s.n += n
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			s.eof = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if s.n == 0 {
+		return nil, io.EOF
+	}
+	s.used = s.cut(s.buf[:s.n])
+	return s.buf[:s.used], nil
+}
+
+// cut returns the length of the chunk at the start of data
+func (s *splitter) cut(data []byte) int {
+	if len(data) <= s.min {
+		return len(data)
+	}
+	var h uint64
+	for i := s.min; i < len(data); i++ {
+		h = (h << 1) + gearTable[data[i]]
+		if h&s.mask == 0 {
+			return i + 1
+		}
+	}
+	return len(data)
+}
@@ -19,10 +19,13 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/lib/readers"
+	"github.com/rclone/rclone/lib/resume"
 	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/googleapi"
 )
@@ -49,6 +52,74 @@ type resumableUpload struct {
 	ret *drive.File
 }
 
+// uploadState is the state of a resumable upload which is saved so
+// it can be resumed with --resume-uploads
+type uploadState struct {
+	URI string // the resumable session URI
+}
+
+// resumeUpload finds the upload session saved in up and the position
+// to carry on uploading from.
+//
+// It returns an empty URI if there is no session to resume. As
+// uploads which can't be resumed are started again from the beginning
+// errors are logged rather than returned.
+func (f *Fs) resumeUpload(ctx context.Context, up *resume.Upload, remote string, size int64) (URI string, start int64) {
+	var state uploadState
+	found, err := up.Load(&state)
+	if err != nil {
+		fs.Errorf(remote, "Can't resume upload: %v", err)
+		return "", 0
+	}
+	if !found || state.URI == "" {
+		return "", 0
+	}
+	// Ask the server how much it has received - it replies with
+	// statusResumeIncomplete and a Range header if the session is
+	// still valid
+	rx := &resumableUpload{
+		f:             f,
+		remote:        remote,
+		URI:           state.URI,
+		ContentLength: size,
+	}
+	var res *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		res, err = f.client.Do(rx.makeRequest(ctx, 0, nil, 0))
+		if err == nil {
+			defer googleapi.CloseBody(res)
+			if res.StatusCode != statusResumeIncomplete {
+				err = googleapi.CheckResponse(res)
+				if err == nil {
+					err = errors.Errorf("unexpected status %d", res.StatusCode)
+				}
+			}
+		}
+		return f.shouldRetry(err)
+	})
+	if err == nil {
+		// Range is "bytes=0-N" or missing if nothing was received
+		if r := res.Header.Get("Range"); r != "" {
+			i := strings.LastIndexByte(r, '-')
+			if i < 0 {
+				err = errors.Errorf("bad Range %q", r)
+			} else {
+				start, err = strconv.ParseInt(r[i+1:], 10, 64)
+				start++
+			}
+		}
+	}
+	if err == nil && (start < 0 || start >= size) {
+		err = errors.Errorf("bad position %d", start)
+	}
+	if err != nil {
+		fs.Debugf(remote, "Can't resume upload: %v", err)
+		up.Remove()
+		return "", 0
+	}
+	return state.URI, start
+}
+
 // Upload the io.Reader in of size bytes with contentType and info
 func (f *Fs) Upload(ctx context.Context, in io.Reader, size int64, contentType, fileID, remote string, info *drive.File) (*drive.File, error) {
 	params := url.Values{
@@ -68,8 +139,58 @@ func (f *Fs) Upload(ctx context.Context, in io.Reader, size int64, contentType,
 		method = "PATCH"
 	}
 	urls += "?" + params.Encode()
+
+	// Resume the upload session if possible - only uploads of known
+	// size can be resumed
+	up := resume.Get(ctx)
+	if size < 0 {
+		up = nil
+	}
+	var (
+		loc   string
+		start int64
+	)
+	if up != nil {
+		loc, start = f.resumeUpload(ctx, up, remote, size)
+	}
+	if loc != "" {
+		fs.Infof(remote, "Resuming upload at %v", fs.SizeSuffix(start))
+		err := resume.Skip(in, start)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		loc, err = f.startUpload(ctx, method, urls, contentType, fileID, size, info)
+		if err != nil {
+			return nil, err
+		}
+		if up != nil {
+			err = up.Save(&uploadState{URI: loc})
+			if err != nil {
+				fs.Errorf(remote, "Upload won't be resumable: %v", err)
+				up = nil
+			}
+		}
+	}
+	rx := &resumableUpload{
+		f:             f,
+		remote:        remote,
+		URI:           loc,
+		Media:         in,
+		MediaType:     contentType,
+		ContentLength: size,
+	}
+	ret, err := rx.Upload(ctx, start)
+	if err == nil && up != nil {
+		up.Remove()
+	}
+	return ret, err
+}
+
+// startUpload starts a resumable upload session returning its URI
+func (f *Fs) startUpload(ctx context.Context, method, urls, contentType, fileID string, size int64, info *drive.File) (loc string, err error) {
 	var res *http.Response
-	var err error
 	err = f.pacer.Call(func() (bool, error) {
 		var body io.Reader
 		body, err = googleapi.WithoutDataWrapper.JSONReader(info)
@@ -98,18 +219,9 @@ func (f *Fs) Upload(ctx context.Context, in io.Reader, size int64, contentType,
 		return f.shouldRetry(err)
 	})
 	if err != nil {
-		return nil, err
-	}
-	loc := res.Header.Get("Location")
-	rx := &resumableUpload{
-		f:             f,
-		remote:        remote,
-		URI:           loc,
-		Media:         in,
-		MediaType:     contentType,
-		ContentLength: size,
+		return "", err
 	}
-	return rx.Upload(ctx)
+	return res.Header.Get("Location"), nil
 }
 
 // Make an http.Request for the range passed in
@@ -164,10 +276,9 @@ func (rx *resumableUpload) transferChunk(ctx context.Context, start int64, chunk
 	return res.StatusCode, nil
 }
 
-// Upload uploads the chunks from the input
+// Upload uploads the chunks from the input starting at offset start
 // It retries each chunk using the pacer and --low-level-retries
-func (rx *resumableUpload) Upload(ctx context.Context) (*drive.File, error) {
-	start := int64(0)
+func (rx *resumableUpload) Upload(ctx context.Context, start int64) (*drive.File, error) {
 	var StatusCode int
 	var err error
 	buf := make([]byte, int(rx.f.opt.ChunkSize))
@@ -0,0 +1,103 @@
+package drive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/resume"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/drive/v3"
+)
+
+// sessionServer is a fake resumable upload session which has
+// received the data in received so far
+type sessionServer struct {
+	mu       sync.Mutex
+	size     int64
+	received []byte
+}
+
+func (s *sessionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if len(body) > 0 {
+		var start, end, size int64
+		_, err = fmt.Sscanf(r.Header.Get("Content-Range"), "bytes %d-%d/%d", &start, &end, &size)
+		if err != nil || start != int64(len(s.received)) || size != s.size {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.received = append(s.received, body...)
+	}
+	if int64(len(s.received)) == s.size {
+		_, _ = fmt.Fprintf(w, `{"id":"file-id","name":"file"}`)
+		return
+	}
+	if len(s.received) > 0 {
+		w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", len(s.received)-1))
+	}
+	w.WriteHeader(statusResumeIncomplete)
+}
+
+// Test an upload session is resumed with --resume-uploads
+func TestUploadResume(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "rclone-drive-resume-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	oldDir := resume.Dir
+	resume.Dir = dir
+	defer func() {
+		resume.Dir = oldDir
+	}()
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1024)
+	size := int64(len(data))
+	server := &sessionServer{size: size, received: append([]byte(nil), data[:5000]...)}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	f := &Fs{
+		name:   "driveresume",
+		opt:    Options{ChunkSize: 4096},
+		client: ts.Client(),
+		pacer:  fs.NewPacer(ctx, pacer.NewGoogleDrive(pacer.MinSleep(time.Millisecond))),
+	}
+
+	// Save the session as if a previous upload had been interrupted
+	uploadCtx := resume.WithUpload(ctx, f, "file", "fingerprint")
+	require.NoError(t, resume.Get(uploadCtx).Save(&uploadState{URI: ts.URL + "/session"}))
+
+	// Upload with the start corrupted - this should be skipped
+	// as the server has it already
+	corrupted := append([]byte(nil), data...)
+	copy(corrupted, bytes.Repeat([]byte("X"), 5000))
+	info, err := f.Upload(uploadCtx, bytes.NewReader(corrupted), size, "text/plain", "", "file", &drive.File{})
+	require.NoError(t, err)
+	assert.Equal(t, "file-id", info.Id)
+	assert.True(t, bytes.Equal(data, server.received), "uploaded data differs")
+
+	// The saved session is removed once the upload is complete
+	states, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(states))
+}
@@ -562,6 +562,10 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 			d := fs.NewDir(newremote, object.Time)
 			entries = append(entries, d)
 		default:
+			if fs.IsPartialName(object.Name) {
+				// Skip uploads in progress or left by a crash
+				continue
+			}
 			o := &Object{
 				fs:     f,
 				remote: newremote,
@@ -704,11 +708,45 @@ func (f *Fs) Rmdir(ctx context.Context, dir string) error {
 	if err != nil {
 		return errors.Wrap(translateErrorFile(err), "Rmdir")
 	}
-	err = c.RemoveDir(f.dirFromStandardPath(path.Join(f.root, dir)))
+	root := path.Join(f.root, dir)
+	err = c.RemoveDir(f.dirFromStandardPath(root))
+	if err != nil && f.removePartials(c, root) {
+		// Try again now the partial files are gone
+		err = c.RemoveDir(f.dirFromStandardPath(root))
+	}
 	f.putFtpConnection(&c, err)
 	return translateErrorDir(err)
 }
 
+// removePartials removes the partial files left by interrupted
+// uploads in the directory root if there is nothing else in it,
+// returning true if any were removed
+func (f *Fs) removePartials(c *ftp.ServerConn, root string) bool {
+	files, err := c.List(f.dirFromStandardPath(root))
+	if err != nil {
+		return false
+	}
+	var partials []string
+	for _, file := range files {
+		if file.Name == "." || file.Name == ".." {
+			continue
+		}
+		f.entryToStandard(file)
+		if file.Type == ftp.EntryTypeFolder || !fs.IsPartialName(file.Name) {
+			return false
+		}
+		partials = append(partials, path.Join(root, file.Name))
+	}
+	for _, partialPath := range partials {
+		fs.Debugf(f, "Removing partial file %q left by an interrupted upload", partialPath)
+		if err := c.Delete(f.opt.Enc.FromStandardPath(partialPath)); err != nil {
+			fs.Errorf(f, "Failed to remove partial file %q: %v", partialPath, err)
+			return false
+		}
+	}
+	return len(partials) > 0
+}
+
 // Move renames a remote file object
 func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
 	srcObj, ok := src.(*Object)
@@ -926,7 +964,12 @@ func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (rc io.Read
 // The new object may have been created if an error is returned
 func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
 	// defer fs.Trace(o, "src=%v", src)("err=%v", &err)
-	path := path.Join(o.fs.root, o.remote)
+	dir, leaf := path.Split(path.Join(o.fs.root, o.remote))
+	path := dir + leaf
+	writePath := path
+	if !fs.GetConfig(ctx).Inplace {
+		writePath = dir + fs.PartialName(leaf)
+	}
 	// remove the file if upload failed
 	remove := func() {
 		// Give the FTP server a chance to get its internal state in order after the error.
@@ -934,7 +977,11 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 		// may still be dealing with it for a moment. A sleep isn't ideal but I haven't been
 		// able to think of a better method to find out if the server has finished - ncw
 		time.Sleep(1 * time.Second)
-		removeErr := o.Remove(ctx)
+		c, removeErr := o.fs.getFtpConnection(ctx)
+		if removeErr == nil {
+			removeErr = c.Delete(o.fs.opt.Enc.FromStandardPath(writePath))
+			o.fs.putFtpConnection(&c, removeErr)
+		}
 		if removeErr != nil {
 			fs.Debugf(o, "Failed to remove: %v", removeErr)
 		} else {
@@ -945,7 +992,7 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 	if err != nil {
 		return errors.Wrap(err, "Update")
 	}
-	err = c.Stor(o.fs.opt.Enc.FromStandardPath(path), in)
+	err = c.Stor(o.fs.opt.Enc.FromStandardPath(writePath), in)
 	if err != nil {
 		_ = c.Quit() // toss this connection to avoid sync errors
 		remove()
@@ -953,6 +1000,13 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 		return errors.Wrap(err, "update stor")
 	}
 	o.fs.putFtpConnection(&c, nil)
+	if writePath != path {
+		err = o.fs.renameInto(ctx, writePath, path)
+		if err != nil {
+			remove()
+			return errors.Wrap(err, "update rename")
+		}
+	}
 	o.info, err = o.fs.getInfo(ctx, path)
 	if err != nil {
 		return errors.Wrap(err, "update getinfo")
@@ -960,6 +1014,38 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 	return nil
 }
 
+// renameInto renames srcPath to dstPath replacing dstPath if it exists
+//
+// Some servers won't rename over an existing file so if the rename
+// fails dstPath is renamed out of the way and the rename tried again.
+// dstPath is put back if that fails too so it is never lost.
+func (f *Fs) renameInto(ctx context.Context, srcPath, dstPath string) error {
+	c, err := f.getFtpConnection(ctx)
+	if err != nil {
+		return err
+	}
+	from, to := f.opt.Enc.FromStandardPath(srcPath), f.opt.Enc.FromStandardPath(dstPath)
+	err = c.Rename(from, to)
+	if err != nil {
+		dir, leaf := path.Split(dstPath)
+		old := f.opt.Enc.FromStandardPath(dir + fs.PartialName(leaf+".old"))
+		fs.Debugf(f, "Rename failed so renaming %q out of the way before trying again: %v", dstPath, err)
+		movedOld := c.Rename(to, old) == nil
+		err = c.Rename(from, to)
+		if movedOld {
+			if err != nil {
+				if restoreErr := c.Rename(old, to); restoreErr != nil {
+					fs.Errorf(f, "Failed to restore %q: %v", dstPath, restoreErr)
+				}
+			} else if removeErr := c.Delete(old); removeErr != nil {
+				fs.Errorf(f, "Failed to remove old copy of %q: %v", dstPath, removeErr)
+			}
+		}
+	}
+	f.putFtpConnection(&c, err)
+	return err
+}
+
 // Remove an object
 func (o *Object) Remove(ctx context.Context) (err error) {
 	// defer fs.Trace(o, "")("err=%v", &err)
@@ -295,6 +295,7 @@ type Object struct {
 	remote   string    // The remote path
 	url      string    // download path
 	md5sum   string    // The MD5Sum of the object
+	crc32c   string    // The CRC32C of the object
 	bytes    int64     // Bytes in the object
 	modTime  time.Time // Modified time of the object
 	mimeType string
@@ -866,7 +867,7 @@ func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object,
 
 // Hashes returns the supported hash sets.
 func (f *Fs) Hashes() hash.Set {
-	return hash.Set(hash.MD5)
+	return hash.NewHashSet(hash.MD5, hash.CRC32C)
 }
 
 // ------------------------------------------------------------
@@ -889,12 +890,15 @@ func (o *Object) Remote() string {
 	return o.remote
 }
 
-// Hash returns the Md5sum of an object returning a lowercase hex string
+// Hash returns the Md5sum or CRC32C of an object returning a lowercase hex string
 func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
-	if t != hash.MD5 {
-		return "", hash.ErrUnsupported
+	switch t {
+	case hash.MD5:
+		return o.md5sum, nil
+	case hash.CRC32C:
+		return o.crc32c, nil
 	}
-	return o.md5sum, nil
+	return "", hash.ErrUnsupported
 }
 
 // Size returns the size of an object in bytes
@@ -916,6 +920,14 @@ func (o *Object) setMetaData(info *storage.Object) {
 		o.md5sum = hex.EncodeToString(md5sumData)
 	}
 
+	// Read crc32c - this is big endian base64 encoded
+	crc32cData, err := base64.StdEncoding.DecodeString(info.Crc32c)
+	if err != nil {
+		fs.Logf(o, "Bad CRC32C decode: %v", err)
+	} else {
+		o.crc32c = hex.EncodeToString(crc32cData)
+	}
+
 	// read mtime out of metadata if available
 	mtimeString, ok := info.Metadata[metaMtime]
 	if ok {
@@ -0,0 +1,186 @@
+package hasher
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/hash"
+)
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "drop",
+	Short: "Drop cache",
+	Long: `Completely drop checksum cache for the path.
+Usage Example:
+    rclone backend drop hasher:path
+`,
+}, {
+	Name:  "dump",
+	Short: "Dump the database",
+	Long: `Dump cached checksums for the path to the output.
+Only entries which are still valid are shown unless the "full"
+option is given.
+Usage Example:
+    rclone backend dump hasher:path
+    rclone backend dump -o full hasher:path
+`,
+	Opts: map[string]string{
+		"full": "show all entries including stale ones",
+	},
+}, {
+	Name:  "import",
+	Short: "Import a SUM file",
+	Long: `Amend hash cache from a SUM file and bind checksums to files by size/time.
+The SUM file should be in the format written by md5sum or sha1sum and
+can be on any remote. Paths in it are relative to the hasher path.
+Usage Example:
+    rclone backend import hasher:subdir md5 /path/to/sum.md5
+`,
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "drop":
+		n, err := f.db.drop(f.dbKey(""))
+		if err != nil {
+			return nil, err
+		}
+		fs.Infof(f, "Dropped %d cached checksums", n)
+		return nil, nil
+	case "dump":
+		_, full := opt["full"]
+		return f.dump(ctx, full)
+	case "import":
+		if len(arg) != 2 {
+			return nil, errors.New("please provide checksum type and path to sum file")
+		}
+		return nil, f.importSum(ctx, arg[0], arg[1])
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// dump returns the cached checksums for f as lines of text
+func (f *Fs) dump(ctx context.Context, full bool) ([]string, error) {
+	prefix := f.dbKey("")
+	var out []string
+	err := f.db.walk(prefix, func(key string, r *hashRecord) error {
+		remote := strings.TrimPrefix(strings.TrimPrefix(key, prefix), "/")
+		if !full {
+			o, err := f.NewObject(ctx, remote)
+			if err != nil {
+				return nil
+			}
+			if o.(*Object).cachedHashes(ctx) == nil {
+				return nil
+			}
+		}
+		var sums []string
+		for _, ht := range f.slowHashes.Array() {
+			sum := r.Hashes[ht.String()]
+			if sum == "" {
+				sum = "-"
+			}
+			sums = append(sums, sum)
+		}
+		out = append(out, fmt.Sprintf("%s  %d  %s  %s", strings.Join(sums, "  "), r.Size, r.ModTime.Format("2006-01-02 15:04:05.000000000"), remote))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// importSum reads the checksums of type hashName from the SUM file at
+// sumPath into the cache
+func (f *Fs) importSum(ctx context.Context, hashName, sumPath string) (err error) {
+	ht, err := parseHashType(hashName)
+	if err != nil {
+		return err
+	}
+	if !f.slowHashes.Contains(ht) {
+		return errors.Errorf("%v is not a cached hash type of this remote", ht)
+	}
+	parent, leaf, err := fspath.Split(sumPath)
+	if err != nil {
+		return err
+	}
+	if parent == "" {
+		parent = "."
+	}
+	sumFs, err := cache.Get(ctx, parent)
+	if err != nil && err != fs.ErrorIsFile {
+		return errors.Wrap(err, "failed to open sum file")
+	}
+	sumObj, err := sumFs.NewObject(ctx, leaf)
+	if err != nil {
+		return errors.Wrap(err, "failed to open sum file")
+	}
+	in, err := sumObj.Open(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to open sum file")
+	}
+	defer fs.CheckClose(in, &err)
+	return f.importSumFrom(ctx, ht, in)
+}
+
+// importSumFrom reads checksums of type ht in md5sum format from in
+// into the cache
+func (f *Fs) importSumFrom(ctx context.Context, ht hash.Type, in io.Reader) error {
+	var imported, skipped int
+	scanner := bufio.NewScanner(in)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 || len(fields[0]) != hash.Width(ht) {
+			return errors.Errorf("line %d: invalid %v sum line %q", lineNo, ht, line)
+		}
+		sum := strings.ToLower(fields[0])
+		remote := strings.TrimLeft(fields[1], " *")
+		remote = strings.TrimPrefix(remote, "./")
+		o, err := f.NewObject(ctx, remote)
+		if err != nil {
+			fs.Debugf(remote, "hasher: skipping import: %v", err)
+			skipped++
+			continue
+		}
+		obj := o.(*Object)
+		hashes := obj.cachedHashes(ctx)
+		if hashes == nil {
+			hashes = map[string]string{}
+		}
+		hashes[ht.String()] = sum
+		err = obj.putHashes(ctx, hashes)
+		if err != nil {
+			return err
+		}
+		imported++
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(err, "failed to read sum file")
+	}
+	fs.Infof(f, "Imported %d %v checksums, skipped %d", imported, ht, skipped)
+	return nil
+}
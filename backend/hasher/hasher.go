@@ -0,0 +1,511 @@
+// Package hasher implements a checksum handling overlay backend
+package hasher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/hash"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "hasher",
+		Description: "Better checksums for other remotes",
+		NewFs:       NewFs,
+		CommandHelp: commandHelp,
+		Options: []fs.Option{{
+			Name:     "remote",
+			Required: true,
+			Help:     "Remote to cache checksums for (e.g. myRemote:path).",
+		}, {
+			Name:    "hashes",
+			Default: fs.CommaSepList{"md5", "sha1"},
+			Help:    "Comma separated list of supported checksum types.",
+		}, {
+			Name:    "max_age",
+			Default: fs.DurationOff,
+			Help: `Maximum time to keep checksums in cache (0 = no cache, off = cache forever).
+
+Cached checksums are only used if the size and modification time of
+the file haven't changed since the checksum was stored.`,
+		}, {
+			Name:     "auto_size",
+			Advanced: true,
+			Default:  fs.SizeSuffix(0),
+			Help: `Auto-update checksum for files smaller than this size (disabled by default).
+
+Files up to this size will have their checksums calculated by reading
+the whole file when a checksum is requested and none is cached.`,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Remote   string          `config:"remote"`
+	Hashes   fs.CommaSepList `config:"hashes"`
+	AutoSize fs.SizeSuffix   `config:"auto_size"`
+	MaxAge   fs.Duration     `config:"max_age"`
+}
+
+// Fs represents a wrapped fs.Fs
+type Fs struct {
+	fs.Fs
+	name     string
+	root     string
+	wrapper  fs.Fs
+	features *fs.Features
+	opt      *Options
+	db       *hashDB
+	// fingerprinting
+	slowHashes hash.Set // hashes cached by this backend
+	passHashes hash.Set // hashes passed straight through to the wrapped remote
+	keepHashes hash.Set // all hashes advertised by this backend
+}
+
+// NewFs constructs an Fs from the path, container:path
+func NewFs(ctx context.Context, fsname, rpath string, cmap configmap.Mapper) (fs.Fs, error) {
+	// Parse config into Options struct
+	opt := &Options{}
+	err := configstruct.Set(cmap, opt)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(opt.Remote, fsname+":") {
+		return nil, errors.New("can't point hasher remote at itself - check the value of the remote setting")
+	}
+
+	remotePath := fspath.JoinRootPath(opt.Remote, rpath)
+	baseFs, err := cache.Get(ctx, remotePath)
+	if err != nil && err != fs.ErrorIsFile {
+		return nil, errors.Wrapf(err, "failed to make remote %q to wrap", remotePath)
+	}
+
+	f := &Fs{
+		Fs:   baseFs,
+		name: fsname,
+		root: rpath,
+		opt:  opt,
+	}
+	isFile := err == fs.ErrorIsFile
+	if isFile {
+		f.root = path.Dir(rpath)
+		if f.root == "." {
+			f.root = ""
+		}
+	}
+
+	// Hashes the wrapped remote supports are passed through unless
+	// they are slow to calculate in which case they are cached too
+	baseHashes := baseFs.Hashes()
+	slowBase := baseFs.Features().SlowHash
+	for _, hashName := range opt.Hashes {
+		hashType, parseErr := parseHashType(hashName)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		if slowBase || !baseHashes.Contains(hashType) {
+			f.slowHashes.Add(hashType)
+		}
+	}
+	for _, hashType := range baseHashes.Array() {
+		if !f.slowHashes.Contains(hashType) {
+			f.passHashes.Add(hashType)
+		}
+	}
+	f.keepHashes = hash.NewHashSet(append(f.passHashes.Array(), f.slowHashes.Array()...)...)
+
+	dbPath := filepath.Join(config.CacheDir, "hasher", fsname+dbFileSuffix)
+	f.db, err = openHashDB(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	cache.PinUntilFinalized(f.Fs, f)
+
+	// the features here are ones we could support, and they are
+	// ANDed with the ones from baseFs
+	f.features = (&fs.Features{
+		CaseInsensitive:         true,
+		DuplicateFiles:          false,
+		ReadMimeType:            false, // Object.MimeType not supported
+		WriteMimeType:           true,
+		CanHaveEmptyDirectories: true,
+		BucketBased:             true,
+		BucketBasedRootOK:       true,
+		SetTier:                 true,
+		GetTier:                 true,
+		ServerSideAcrossConfigs: true,
+		ReadMetadata:            true,
+		WriteMetadata:           true,
+		UserMetadata:            true,
+	}).Fill(ctx, f).Mask(ctx, baseFs).WrapsFs(f, baseFs)
+	// SlowHash is only true if the emulated hashes might need to be
+	// calculated
+	f.features.SlowHash = baseFs.Features().SlowHash || opt.AutoSize > 0
+
+	if isFile {
+		return f, fs.ErrorIsFile
+	}
+	return f, nil
+}
+
+// parseHashType parses a hash name leniently so that md5, MD5 and
+// sha1, SHA-1 are all accepted
+func parseHashType(name string) (hash.Type, error) {
+	normalise := func(s string) string {
+		return strings.Replace(strings.ToLower(strings.TrimSpace(s)), "-", "", -1)
+	}
+	want := normalise(name)
+	for _, ht := range hash.Supported().Array() {
+		if normalise(ht.String()) == want {
+			return ht, nil
+		}
+	}
+	return hash.None, errors.Errorf("unknown hash type %q", name)
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("hasher::%s:%s", f.name, f.root)
+}
+
+// Hashes returns the supported hash sets.
+func (f *Fs) Hashes() hash.Set {
+	return f.keepHashes
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *Fs) UnWrap() fs.Fs {
+	return f.Fs
+}
+
+// WrapFs returns the Fs that is wrapping this Fs
+func (f *Fs) WrapFs() fs.Fs {
+	return f.wrapper
+}
+
+// SetWrapper sets the Fs that is wrapping this Fs
+func (f *Fs) SetWrapper(wrapper fs.Fs) {
+	f.wrapper = wrapper
+}
+
+// dbKey returns the database key for remote
+//
+// This is the full path on the wrapped remote so that the cache can
+// be shared between hasher remotes with different roots.
+func (f *Fs) dbKey(remote string) string {
+	return path.Join(f.Fs.Root(), remote)
+}
+
+// wrapEntries wraps the objects in entries
+func (f *Fs) wrapEntries(baseEntries fs.DirEntries) (hashEntries fs.DirEntries, err error) {
+	for _, entry := range baseEntries {
+		switch x := entry.(type) {
+		case fs.Object:
+			hashEntries = append(hashEntries, f.wrapObject(x, nil))
+		default:
+			hashEntries = append(hashEntries, entry) // directory or unknown type
+		}
+	}
+	return hashEntries, nil
+}
+
+// List the objects and directories in dir into entries.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	if entries, err = f.Fs.List(ctx, dir); err != nil {
+		return nil, err
+	}
+	return f.wrapEntries(entries)
+}
+
+// ListR lists the objects and directories recursively into out.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	return f.Fs.Features().ListR(ctx, dir, func(entries fs.DirEntries) error {
+		newEntries, err := f.wrapEntries(entries)
+		if err != nil {
+			return err
+		}
+		return callback(newEntries)
+	})
+}
+
+// NewObject finds the Object at remote.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	o, err := f.Fs.NewObject(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.wrapObject(o, nil), nil
+}
+
+// Purge all files in the directory specified
+//
+// Any cached checksums for the directory are dropped.
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	do := f.Fs.Features().Purge
+	if do == nil {
+		return fs.ErrorCantPurge
+	}
+	err := do(ctx, dir)
+	if err != nil {
+		return err
+	}
+	_, err = f.db.drop(f.dbKey(dir))
+	return err
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	do := f.Fs.Features().PutStream
+	if do == nil {
+		return nil, errors.New("can't PutStream")
+	}
+	return f.put(ctx, in, src, options, do)
+}
+
+// PutUnchecked uploads the object
+//
+// This will create a duplicate if we upload a new file without
+// checking to see if there is one already - use Put() for that.
+func (f *Fs) PutUnchecked(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	do := f.Fs.Features().PutUnchecked
+	if do == nil {
+		return nil, errors.New("can't PutUnchecked")
+	}
+	return f.put(ctx, in, src, options, do)
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.put(ctx, in, src, options, f.Fs.Put)
+}
+
+// CleanUp the trash in the Fs
+func (f *Fs) CleanUp(ctx context.Context) error {
+	do := f.Fs.Features().CleanUp
+	if do == nil {
+		return errors.New("can't CleanUp")
+	}
+	return do(ctx)
+}
+
+// About gets quota information from the Fs
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	do := f.Fs.Features().About
+	if do == nil {
+		return nil, errors.New("About not supported")
+	}
+	return do(ctx)
+}
+
+// ChangeNotify calls the passed function with a path that has had changes.
+func (f *Fs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	if do := f.Fs.Features().ChangeNotify; do != nil {
+		do(ctx, notifyFunc, pollIntervalChan)
+	}
+}
+
+// UserInfo returns info about the connected user
+func (f *Fs) UserInfo(ctx context.Context) (map[string]string, error) {
+	do := f.Fs.Features().UserInfo
+	if do == nil {
+		return nil, fs.ErrorNotImplemented
+	}
+	return do(ctx)
+}
+
+// Disconnect the current user
+func (f *Fs) Disconnect(ctx context.Context) error {
+	do := f.Fs.Features().Disconnect
+	if do == nil {
+		return fs.ErrorNotImplemented
+	}
+	return do(ctx)
+}
+
+// MergeDirs merges the contents of all the directories passed
+// in into the first one and rmdirs the other directories.
+func (f *Fs) MergeDirs(ctx context.Context, dirs []fs.Directory) error {
+	do := f.Fs.Features().MergeDirs
+	if do == nil {
+		return errors.New("MergeDirs not supported")
+	}
+	return do(ctx, dirs)
+}
+
+// DirCacheFlush resets the directory cache - used in testing
+// as an optional interface
+func (f *Fs) DirCacheFlush() {
+	if do := f.Fs.Features().DirCacheFlush; do != nil {
+		do()
+	}
+}
+
+// PublicLink generates a public link to the remote path (usually readable by anyone)
+func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration, unlink bool) (string, error) {
+	do := f.Fs.Features().PublicLink
+	if do == nil {
+		return "", errors.New("PublicLink not supported")
+	}
+	return do(ctx, remote, expire, unlink)
+}
+
+// Copy src to this remote using server-side copy operations.
+//
+// Cached checksums are copied along with the object.
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Copy
+	if do == nil {
+		return nil, fs.ErrorCantCopy
+	}
+	o, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantCopy
+	}
+	oResult, err := do(ctx, o.Object, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.wrapObject(oResult, o.cachedHashes(ctx)), nil
+}
+
+// Move src to this remote using server-side move operations.
+//
+// Cached checksums are moved along with the object.
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Move
+	if do == nil {
+		return nil, fs.ErrorCantMove
+	}
+	o, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantMove
+	}
+	hashes := o.cachedHashes(ctx)
+	oResult, err := do(ctx, o.Object, remote)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.f.db.delete(o.f.dbKey(o.Remote())); err != nil {
+		fs.Errorf(o, "failed to drop cached checksums: %v", err)
+	}
+	return f.wrapObject(oResult, hashes), nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+//
+// Cached checksums are moved along with the directory.
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	do := f.Fs.Features().DirMove
+	if do == nil {
+		return fs.ErrorCantDirMove
+	}
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		fs.Debugf(srcFs, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	err := do(ctx, srcFs.Fs, srcRemote, dstRemote)
+	if err != nil {
+		return err
+	}
+	if srcFs.db != f.db {
+		_, err = srcFs.db.drop(srcFs.dbKey(srcRemote))
+		return err
+	}
+	srcPrefix, dstPrefix := srcFs.dbKey(srcRemote), f.dbKey(dstRemote)
+	var renames [][2]string
+	err = f.db.walk(srcPrefix, func(key string, r *hashRecord) error {
+		renames = append(renames, [2]string{key, dstPrefix + key[len(srcPrefix):]})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, rename := range renames {
+		if err := f.db.rename(rename[0], rename[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shutdown the backend, closing the hash database
+func (f *Fs) Shutdown(ctx context.Context) (err error) {
+	if do := f.Fs.Features().Shutdown; do != nil {
+		err = do(ctx)
+	}
+	if f.db != nil {
+		closeErr := f.db.close()
+		f.db = nil
+		if err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*Fs)(nil)
+	_ fs.Purger          = (*Fs)(nil)
+	_ fs.Copier          = (*Fs)(nil)
+	_ fs.Mover           = (*Fs)(nil)
+	_ fs.DirMover        = (*Fs)(nil)
+	_ fs.Commander       = (*Fs)(nil)
+	_ fs.PutUncheckeder  = (*Fs)(nil)
+	_ fs.PutStreamer     = (*Fs)(nil)
+	_ fs.CleanUpper      = (*Fs)(nil)
+	_ fs.UnWrapper       = (*Fs)(nil)
+	_ fs.ListRer         = (*Fs)(nil)
+	_ fs.Abouter         = (*Fs)(nil)
+	_ fs.Wrapper         = (*Fs)(nil)
+	_ fs.MergeDirser     = (*Fs)(nil)
+	_ fs.DirCacheFlusher = (*Fs)(nil)
+	_ fs.ChangeNotifier  = (*Fs)(nil)
+	_ fs.PublicLinker    = (*Fs)(nil)
+	_ fs.UserInfoer      = (*Fs)(nil)
+	_ fs.Disconnecter    = (*Fs)(nil)
+	_ fs.Shutdowner      = (*Fs)(nil)
+	_ fs.Object          = (*Object)(nil)
+	_ fs.ObjectUnWrapper = (*Object)(nil)
+	_ fs.IDer            = (*Object)(nil)
+	_ fs.SetTierer       = (*Object)(nil)
+	_ fs.GetTierer       = (*Object)(nil)
+	_ fs.Metadataer      = (*Object)(nil)
+	_ fs.SetMetadataer   = (*Object)(nil)
+)
@@ -0,0 +1,235 @@
+package hasher
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	testContents = "hello world"
+	// whirlpool sum of testContents
+	testWhirlpool = "8d8309ca6af848095bcabaf9a53b1b6ce7f594c1434fd6e5177e7e5c20e76cd30936d8606e7f36acbef8978fea008e6400a975d51abe6ba4923178c7cf90c802"
+)
+
+// newTestFs makes a hasher Fs on a temporary directory with its
+// database in a temporary cache directory
+func newTestFs(t *testing.T, m configmap.Simple) (f *Fs, dir string, cleanup func()) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "rclone-hasher-internal-test")
+	require.NoError(t, err)
+	oldCacheDir := config.CacheDir
+	config.CacheDir = filepath.Join(dir, "cache")
+	if _, ok := m["max_age"]; !ok {
+		m["max_age"] = "off"
+	}
+	m["remote"] = filepath.Join(dir, "data")
+	require.NoError(t, os.MkdirAll(m["remote"], 0777))
+	fsi, err := NewFs(ctx, "TestHasherInternal", "", m)
+	require.NoError(t, err)
+	f = fsi.(*Fs)
+	return f, dir, func() {
+		assert.NoError(t, f.Shutdown(ctx))
+		config.CacheDir = oldCacheDir
+		_ = os.RemoveAll(dir)
+	}
+}
+
+// put uploads contents to remote on f
+func put(ctx context.Context, t *testing.T, f fs.Fs, remote, contents string, modTime time.Time) fs.Object {
+	src := object.NewStaticObjectInfo(remote, modTime, int64(len(contents)), true, nil, nil)
+	o, err := f.Put(ctx, bytes.NewBufferString(contents), src)
+	require.NoError(t, err)
+	return o
+}
+
+func TestHasherCache(t *testing.T) {
+	ctx := context.Background()
+	f, dir, cleanup := newTestFs(t, configmap.Simple{"hashes": "whirlpool"})
+	defer cleanup()
+	assert.True(t, f.Hashes().Contains(hash.Whirlpool))
+	assert.True(t, f.slowHashes.Contains(hash.Whirlpool))
+	assert.False(t, f.slowHashes.Contains(hash.MD5))
+
+	modTime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	o := put(ctx, t, f, "file.txt", testContents, modTime)
+
+	// Hash is cached on upload
+	sum, err := o.Hash(ctx, hash.Whirlpool)
+	require.NoError(t, err)
+	assert.Equal(t, testWhirlpool, sum)
+
+	// and survives finding the object again
+	o, err = f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	sum, err = o.Hash(ctx, hash.Whirlpool)
+	require.NoError(t, err)
+	assert.Equal(t, testWhirlpool, sum)
+
+	// and SetModTime
+	require.NoError(t, o.SetModTime(ctx, modTime.Add(time.Hour)))
+	sum, err = o.Hash(ctx, hash.Whirlpool)
+	require.NoError(t, err)
+	assert.Equal(t, testWhirlpool, sum)
+
+	// Changing the file underneath invalidates the cache so the
+	// hash is read from the wrapped remote and cached again
+	path := filepath.Join(dir, "data", "file.txt")
+	require.NoError(t, ioutil.WriteFile(path, []byte("hello potato"), 0666))
+	o, err = f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	sum, err = o.Hash(ctx, hash.Whirlpool)
+	require.NoError(t, err)
+	assert.NotEqual(t, testWhirlpool, sum)
+	r, err := f.db.get(f.dbKey("file.txt"))
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, sum, r.Hashes[hash.Whirlpool.String()])
+
+	// Remove drops the cache entry
+	require.NoError(t, o.Remove(ctx))
+	r, err = f.db.get(f.dbKey("file.txt"))
+	require.NoError(t, err)
+	assert.Nil(t, r)
+}
+
+func TestHasherAutoSize(t *testing.T) {
+	ctx := context.Background()
+	f, dir, cleanup := newTestFs(t, configmap.Simple{"hashes": "whirlpool", "auto_size": "1k"})
+	defer cleanup()
+
+	path := filepath.Join(dir, "data", "file.txt")
+	require.NoError(t, ioutil.WriteFile(path, []byte(testContents), 0666))
+	o, err := f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	sum, err := o.Hash(ctx, hash.Whirlpool)
+	require.NoError(t, err)
+	assert.Equal(t, testWhirlpool, sum)
+
+	r, err := f.db.get(f.dbKey("file.txt"))
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, testWhirlpool, r.Hashes[hash.Whirlpool.String()])
+}
+
+func TestHasherNoCache(t *testing.T) {
+	ctx := context.Background()
+	f, _, cleanup := newTestFs(t, configmap.Simple{"hashes": "whirlpool", "max_age": "0"})
+	defer cleanup()
+
+	o := put(ctx, t, f, "file.txt", testContents, time.Now())
+	sum, err := o.Hash(ctx, hash.Whirlpool)
+	require.NoError(t, err)
+	assert.Equal(t, testWhirlpool, sum)
+	r, err := f.db.get(f.dbKey("file.txt"))
+	require.NoError(t, err)
+	assert.Nil(t, r)
+}
+
+func TestHasherCommands(t *testing.T) {
+	ctx := context.Background()
+	f, dir, cleanup := newTestFs(t, configmap.Simple{"hashes": "whirlpool"})
+	defer cleanup()
+
+	path := filepath.Join(dir, "data", "dir", "file.txt")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0777))
+	require.NoError(t, ioutil.WriteFile(path, []byte(testContents), 0666))
+
+	// Import a sum file
+	sumPath := filepath.Join(dir, "sums.txt")
+	require.NoError(t, ioutil.WriteFile(sumPath, []byte(testWhirlpool+"  dir/file.txt\n"+testWhirlpool+" *missing.txt\n"), 0666))
+	_, err := f.Command(ctx, "import", []string{"whirlpool", sumPath}, nil)
+	require.NoError(t, err)
+
+	o, err := f.NewObject(ctx, "dir/file.txt")
+	require.NoError(t, err)
+	sum, err := o.Hash(ctx, hash.Whirlpool)
+	require.NoError(t, err)
+	assert.Equal(t, testWhirlpool, sum)
+
+	// Dump it
+	out, err := f.Command(ctx, "dump", nil, nil)
+	require.NoError(t, err)
+	lines := out.([]string)
+	require.Equal(t, 1, len(lines))
+	assert.True(t, strings.HasPrefix(lines[0], testWhirlpool+"  11  "))
+	assert.True(t, strings.HasSuffix(lines[0], "  dir/file.txt"))
+
+	// Bad import
+	_, err = f.Command(ctx, "import", []string{"potato", sumPath}, nil)
+	assert.Error(t, err)
+	_, err = f.Command(ctx, "import", []string{"whirlpool"}, nil)
+	assert.Error(t, err)
+
+	// Drop it
+	_, err = f.Command(ctx, "drop", nil, nil)
+	require.NoError(t, err)
+	r, err := f.db.get(f.dbKey("dir/file.txt"))
+	require.NoError(t, err)
+	assert.Nil(t, r)
+
+	_, err = f.Command(ctx, "potato", nil, nil)
+	assert.Equal(t, fs.ErrorCommandNotFound, err)
+}
+
+func TestHasherDirMove(t *testing.T) {
+	ctx := context.Background()
+	f, _, cleanup := newTestFs(t, configmap.Simple{"hashes": "whirlpool"})
+	defer cleanup()
+	if f.Features().DirMove == nil {
+		t.Skip("DirMove not supported")
+	}
+
+	put(ctx, t, f, "a/file.txt", testContents, time.Now())
+	require.NoError(t, f.Features().DirMove(ctx, f, "a", "b"))
+	o, err := f.NewObject(ctx, "b/file.txt")
+	require.NoError(t, err)
+	sum, err := o.Hash(ctx, hash.Whirlpool)
+	require.NoError(t, err)
+	assert.Equal(t, testWhirlpool, sum)
+}
+
+func TestHasherDBReleasedWhenIdle(t *testing.T) {
+	ctx := context.Background()
+	oldIdleTimeout := dbIdleTimeout
+	dbIdleTimeout = 10 * time.Millisecond
+	defer func() {
+		dbIdleTimeout = oldIdleTimeout
+	}()
+	f, _, cleanup := newTestFs(t, configmap.Simple{"hashes": "whirlpool"})
+	defer cleanup()
+
+	o := put(ctx, t, f, "file.txt", testContents, time.Now())
+	_, err := o.Hash(ctx, hash.Whirlpool)
+	require.NoError(t, err)
+
+	// Once idle another process can open the database
+	time.Sleep(100 * time.Millisecond)
+	db, err := bolt.Open(f.db.path, dbOpenFileMode, &bolt.Options{Timeout: 100 * time.Millisecond})
+	require.NoError(t, err)
+
+	// and we wait for it to be released before using it again
+	done := make(chan error)
+	go func() {
+		_, err := f.db.get(f.dbKey("file.txt"))
+		done <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, db.Close())
+	require.NoError(t, <-done)
+}
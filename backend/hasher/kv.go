@@ -0,0 +1,301 @@
+package hasher
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	dbFileSuffix   = ".bolt"
+	dbBucket       = "hashes"
+	dbOpenTimeout  = 10 * time.Second
+	dbOpenFileMode = 0600
+)
+
+// dbIdleTimeout is how long the database is kept open after the last
+// operation so other rclone processes can use it
+var dbIdleTimeout = time.Second
+
+// hashRecord is the data stored in the database for each file
+type hashRecord struct {
+	Size    int64             `json:"size"`
+	ModTime time.Time         `json:"modTime"`
+	Created time.Time         `json:"created"`
+	Hashes  map[string]string `json:"hashes"`
+}
+
+// hashDB is a bolt database of hashRecords keyed by path
+//
+// The same database may be shared between several Fs so it is
+// reference counted.
+//
+// bolt locks the database file exclusively while it is open so it is
+// only opened while it is being used and closed again after
+// dbIdleTimeout so other processes can share it.
+type hashDB struct {
+	path string
+	refs int // protected by dbMu
+
+	mu     sync.Mutex
+	db     *bolt.DB    // open database or nil
+	active int         // number of operations in progress
+	idle   *time.Timer // closes the database when idle
+}
+
+var (
+	dbMu sync.Mutex
+	dbs  = map[string]*hashDB{}
+)
+
+// openHashDB opens (or reuses) the database at path
+func openHashDB(path string) (*hashDB, error) {
+	dbMu.Lock()
+	defer dbMu.Unlock()
+	if d, ok := dbs[path]; ok {
+		d.refs++
+		return d, nil
+	}
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create hash database directory")
+	}
+	d := &hashDB{
+		path: path,
+		refs: 1,
+	}
+	// Open it now to check it works and to create the bucket
+	err = d.update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(dbBucket))
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to initialise hash database")
+	}
+	dbs[path] = d
+	return d, nil
+}
+
+// close drops a reference to the database closing it if it was the last
+func (d *hashDB) close() error {
+	dbMu.Lock()
+	defer dbMu.Unlock()
+	d.refs--
+	if d.refs > 0 {
+		return nil
+	}
+	delete(dbs, d.path)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.idle != nil {
+		d.idle.Stop()
+		d.idle = nil
+	}
+	return d.closeDB()
+}
+
+// closeDB closes the bolt database if it is open - call with mu held
+func (d *hashDB) closeDB() error {
+	if d.db == nil {
+		return nil
+	}
+	err := d.db.Close()
+	d.db = nil
+	return err
+}
+
+// closeIdle closes the database if it hasn't been used since the
+// timer was set
+func (d *hashDB) closeIdle() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.active > 0 {
+		return
+	}
+	if err := d.closeDB(); err != nil {
+		fs.Errorf(nil, "hasher: failed to close hash database %q: %v", d.path, err)
+	}
+}
+
+// use opens the database if necessary and calls fn with it
+func (d *hashDB) use(fn func(db *bolt.DB) error) error {
+	d.mu.Lock()
+	if d.idle != nil {
+		d.idle.Stop()
+		d.idle = nil
+	}
+	if d.db == nil {
+		db, err := bolt.Open(d.path, dbOpenFileMode, &bolt.Options{Timeout: dbOpenTimeout})
+		if err != nil {
+			d.mu.Unlock()
+			return errors.Wrapf(err, "failed to open hash database %q", d.path)
+		}
+		d.db = db
+	}
+	d.active++
+	db := d.db
+	d.mu.Unlock()
+
+	err := fn(db)
+
+	d.mu.Lock()
+	d.active--
+	if d.active == 0 && d.db != nil {
+		d.idle = time.AfterFunc(dbIdleTimeout, d.closeIdle)
+	}
+	d.mu.Unlock()
+	return err
+}
+
+// view runs fn in a read only transaction
+func (d *hashDB) view(fn func(tx *bolt.Tx) error) error {
+	return d.use(func(db *bolt.DB) error {
+		return db.View(fn)
+	})
+}
+
+// update runs fn in a read-write transaction
+func (d *hashDB) update(fn func(tx *bolt.Tx) error) error {
+	return d.use(func(db *bolt.DB) error {
+		return db.Update(fn)
+	})
+}
+
+// get reads the record for key returning nil if not found
+func (d *hashDB) get(key string) (r *hashRecord, err error) {
+	err = d.view(func(tx *bolt.Tx) error {
+		data := tx.Bucket([]byte(dbBucket)).Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+		r = new(hashRecord)
+		return json.Unmarshal(data, r)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read hash record for %q", key)
+	}
+	return r, nil
+}
+
+// put stores the record for key
+func (d *hashDB) put(key string, r *hashRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	err = d.update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(dbBucket)).Put([]byte(key), data)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to write hash record for %q", key)
+	}
+	return nil
+}
+
+// delete removes the record for key if it exists
+func (d *hashDB) delete(key string) error {
+	err := d.update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(dbBucket)).Delete([]byte(key))
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete hash record for %q", key)
+	}
+	return nil
+}
+
+// rename moves the record at oldKey to newKey if it exists
+func (d *hashDB) rename(oldKey, newKey string) error {
+	err := d.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(dbBucket))
+		data := b.Get([]byte(oldKey))
+		if data == nil {
+			return nil
+		}
+		// data is only valid for the transaction so copy it
+		data = append([]byte(nil), data...)
+		if err := b.Put([]byte(newKey), data); err != nil {
+			return err
+		}
+		return b.Delete([]byte(oldKey))
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to rename hash record %q to %q", oldKey, newKey)
+	}
+	return nil
+}
+
+// walk calls fn for each record whose key starts with prefix
+//
+// fn must not modify the database.
+func (d *hashDB) walk(prefix string, fn func(key string, r *hashRecord) error) error {
+	return d.view(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(dbBucket)).Cursor()
+		for k, v := c.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, v = c.Next() {
+			if !hasPrefix(string(k), prefix) {
+				continue
+			}
+			var r hashRecord
+			if err := json.Unmarshal(v, &r); err != nil {
+				fs.Debugf(nil, "hasher: skipping corrupt record %q: %v", k, err)
+				continue
+			}
+			if err := fn(string(k), &r); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// drop removes all the records whose key starts with prefix
+func (d *hashDB) drop(prefix string) (n int, err error) {
+	err = d.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(dbBucket))
+		if prefix == "" {
+			n = b.Stats().KeyN
+			if err := tx.DeleteBucket([]byte(dbBucket)); err != nil {
+				return err
+			}
+			_, err := tx.CreateBucketIfNotExists([]byte(dbBucket))
+			return err
+		}
+		// keys are only valid for the transaction so copy them
+		var keys [][]byte
+		c := b.Cursor()
+		for k, _ := c.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, _ = c.Next() {
+			if hasPrefix(string(k), prefix) {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+		}
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	if err != nil {
+		return n, errors.Wrap(err, "failed to drop hash records")
+	}
+	return n, nil
+}
+
+// hasPrefix returns whether key is prefix or is inside the
+// directory prefix
+func hasPrefix(key, prefix string) bool {
+	if prefix == "" {
+		return true
+	}
+	if !strings.HasPrefix(key, prefix) {
+		return false
+	}
+	return len(key) == len(prefix) || prefix[len(prefix)-1] == '/' || key[len(prefix)] == '/'
+}
@@ -0,0 +1,356 @@
+package hasher
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/resume"
+)
+
+// Object represents an object on the wrapped remote with cached checksums
+type Object struct {
+	fs.Object
+	f *Fs
+}
+
+// wrapObject wraps o, storing hashes in the cache if set
+func (f *Fs) wrapObject(o fs.Object, hashes map[string]string) *Object {
+	obj := &Object{
+		Object: o,
+		f:      f,
+	}
+	if len(hashes) > 0 {
+		if err := obj.putHashes(context.Background(), hashes); err != nil {
+			fs.Errorf(obj, "failed to cache checksums: %v", err)
+		}
+	}
+	return obj
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// UnWrap returns the wrapped Object
+func (o *Object) UnWrap() fs.Object {
+	return o.Object
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.Object.String()
+}
+
+// ID returns the ID of the Object if known, or "" if not
+func (o *Object) ID() string {
+	do, ok := o.Object.(fs.IDer)
+	if !ok {
+		return ""
+	}
+	return do.ID()
+}
+
+// SetTier performs changing storage tier of the Object if
+// multiple storage classes supported
+func (o *Object) SetTier(tier string) error {
+	do, ok := o.Object.(fs.SetTierer)
+	if !ok {
+		return errors.New("hasher: wrapped remote does not support SetTier")
+	}
+	return do.SetTier(tier)
+}
+
+// GetTier returns the Tier of the Object
+func (o *Object) GetTier() string {
+	do, ok := o.Object.(fs.GetTierer)
+	if !ok {
+		return ""
+	}
+	return do.GetTier()
+}
+
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *Object) Metadata(ctx context.Context) (fs.Metadata, error) {
+	return fs.GetMetadata(ctx, o.Object)
+}
+
+// SetMetadata sets metadata for an Object
+//
+// It should return fs.ErrorNotImplemented if it can't set metadata
+func (o *Object) SetMetadata(ctx context.Context, metadata fs.Metadata) error {
+	do, ok := o.Object.(fs.SetMetadataer)
+	if !ok {
+		return fs.ErrorNotImplemented
+	}
+	return do.SetMetadata(ctx, metadata)
+}
+
+// Hash returns the selected checksum of the file
+//
+// Hashes supported quickly by the wrapped remote are passed straight
+// through. Other hashes are returned from the cache if the size and
+// modification time of the object haven't changed since they were
+// stored. Failing that they are read from the wrapped remote if it
+// supports them or calculated if the object is smaller than
+// auto_size, and cached. If no checksum is available "" is returned.
+func (o *Object) Hash(ctx context.Context, hashType hash.Type) (string, error) {
+	f := o.f
+	if f.passHashes.Contains(hashType) {
+		return o.Object.Hash(ctx, hashType)
+	}
+	if !f.slowHashes.Contains(hashType) {
+		return "", hash.ErrUnsupported
+	}
+	hashes := o.cachedHashes(ctx)
+	if value, found := hashes[hashType.String()]; found {
+		return value, nil
+	}
+	if f.Fs.Hashes().Contains(hashType) {
+		value, err := o.Object.Hash(ctx, hashType)
+		if err != nil || value == "" {
+			return value, err
+		}
+		if hashes == nil {
+			hashes = map[string]string{}
+		}
+		hashes[hashType.String()] = value
+		if err := o.putHashes(ctx, hashes); err != nil {
+			fs.Errorf(o, "failed to cache checksums: %v", err)
+		}
+		return value, nil
+	}
+	if o.Size() < 0 || o.Size() > int64(f.opt.AutoSize) {
+		return "", nil
+	}
+	hashes, err := o.updateHashes(ctx)
+	if err != nil {
+		return "", err
+	}
+	return hashes[hashType.String()], nil
+}
+
+// cachedHashes returns the valid hashes from the cache or nil if
+// there aren't any
+func (o *Object) cachedHashes(ctx context.Context) map[string]string {
+	f := o.f
+	if f.opt.MaxAge == 0 {
+		return nil
+	}
+	r, err := f.db.get(f.dbKey(o.Remote()))
+	if err != nil {
+		fs.Debugf(o, "failed to read cached checksums: %v", err)
+		return nil
+	}
+	if r == nil {
+		return nil
+	}
+	if r.Size != o.Size() {
+		fs.Debugf(o, "ignoring cached checksums: size changed")
+		return nil
+	}
+	if f.Fs.Precision() != fs.ModTimeNotSupported {
+		dt := o.ModTime(ctx).Sub(r.ModTime)
+		if dt >= f.Fs.Precision() || dt <= -f.Fs.Precision() {
+			fs.Debugf(o, "ignoring cached checksums: modification time changed")
+			return nil
+		}
+	}
+	if f.opt.MaxAge.IsSet() && time.Since(r.Created) > time.Duration(f.opt.MaxAge) {
+		fs.Debugf(o, "ignoring cached checksums: expired")
+		return nil
+	}
+	return r.Hashes
+}
+
+// putHashes stores hashes for the object in the cache
+func (o *Object) putHashes(ctx context.Context, hashes map[string]string) error {
+	f := o.f
+	if f.opt.MaxAge == 0 {
+		return nil
+	}
+	return f.db.put(f.dbKey(o.Remote()), &hashRecord{
+		Size:    o.Size(),
+		ModTime: o.ModTime(ctx),
+		Created: time.Now(),
+		Hashes:  hashes,
+	})
+}
+
+// updateHashes reads the whole object calculating and caching all
+// the emulated hashes
+func (o *Object) updateHashes(ctx context.Context) (hashes map[string]string, err error) {
+	hasher, err := hash.NewMultiHasherTypes(o.f.slowHashes)
+	if err != nil {
+		return nil, err
+	}
+	in, err := o.Object.Open(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open object to calculate checksums")
+	}
+	_, err = io.Copy(hasher, in)
+	closeErr := in.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read object to calculate checksums")
+	}
+	hashes = hashSumsToStrings(hasher.Sums())
+	if err := o.putHashes(ctx, hashes); err != nil {
+		fs.Errorf(o, "failed to cache checksums: %v", err)
+	}
+	return hashes, nil
+}
+
+// Open an object for read
+//
+// If the whole object is read then its checksums are cached as a side
+// effect.
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	in, err := o.Object.Open(ctx, options...)
+	if err != nil || o.f.slowHashes.Count() == 0 || o.f.opt.MaxAge == 0 {
+		return in, err
+	}
+	for _, option := range options {
+		if _, isRange := option.(*fs.RangeOption); isRange {
+			return in, nil
+		}
+		if _, isSeek := option.(*fs.SeekOption); isSeek {
+			return in, nil
+		}
+	}
+	hasher, err := hash.NewMultiHasherTypes(o.f.slowHashes)
+	if err != nil {
+		_ = in.Close()
+		return nil, err
+	}
+	return &hashingReader{
+		o:      o,
+		ctx:    ctx,
+		in:     in,
+		hasher: hasher,
+	}, nil
+}
+
+// hashingReader calculates the checksums of the data read through it
+// and caches them if the whole object was read
+type hashingReader struct {
+	o      *Object
+	ctx    context.Context
+	in     io.ReadCloser
+	hasher *hash.MultiHasher
+	eof    bool
+}
+
+// Read bytes from the object - see io.Reader
+func (r *hashingReader) Read(p []byte) (n int, err error) {
+	n, err = r.in.Read(p)
+	_, _ = r.hasher.Write(p[:n])
+	if err == io.EOF {
+		r.eof = true
+	}
+	return n, err
+}
+
+// Close the object and cache the checksums if it was read fully
+func (r *hashingReader) Close() error {
+	err := r.in.Close()
+	if err == nil && r.eof && r.hasher.Size() == r.o.Size() {
+		if putErr := r.o.putHashes(r.ctx, hashSumsToStrings(r.hasher.Sums())); putErr != nil {
+			fs.Errorf(r.o, "failed to cache checksums: %v", putErr)
+		}
+	}
+	return err
+}
+
+// putFn is the signature of Put, PutStream and PutUnchecked
+type putFn func(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error)
+
+// put uploads in with the put function calculating the emulated
+// hashes on the way through
+func (f *Fs) put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options []fs.OpenOption, put putFn) (fs.Object, error) {
+	// The checksums are calculated from all the data so the wrapped
+	// remote mustn't skip any of it by resuming the upload
+	ctx = resume.Without(ctx)
+	var hasher *hash.MultiHasher
+	if f.slowHashes.Count() > 0 && f.opt.MaxAge != 0 {
+		var err error
+		hasher, err = hash.NewMultiHasherTypes(f.slowHashes)
+		if err != nil {
+			return nil, err
+		}
+		// Unwrap the accounting so it stays on the outside
+		wrappedIn, wrap := accounting.UnWrap(in)
+		in = wrap(io.TeeReader(wrappedIn, hasher))
+	}
+	o, err := put(ctx, in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	if hasher == nil {
+		return f.wrapObject(o, nil), nil
+	}
+	if hasher.Size() != o.Size() {
+		fs.Debugf(o, "not caching checksums: read %d bytes but object is %d bytes", hasher.Size(), o.Size())
+		return f.wrapObject(o, nil), nil
+	}
+	return f.wrapObject(o, hashSumsToStrings(hasher.Sums())), nil
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	_, err := o.f.put(ctx, in, src, options, func(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+		return o.Object, o.Object.Update(ctx, in, src, options...)
+	})
+	return err
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	err := o.Object.Remove(ctx)
+	if err != nil {
+		return err
+	}
+	if err := o.f.db.delete(o.f.dbKey(o.Remote())); err != nil {
+		fs.Errorf(o, "failed to drop cached checksums: %v", err)
+	}
+	return nil
+}
+
+// SetModTime sets the modification time of the file
+//
+// The cached checksums are kept valid as the data hasn't changed.
+func (o *Object) SetModTime(ctx context.Context, mtime time.Time) error {
+	hashes := o.cachedHashes(ctx)
+	err := o.Object.SetModTime(ctx, mtime)
+	if err != nil {
+		return err
+	}
+	if hashes != nil {
+		if err := o.putHashes(ctx, hashes); err != nil {
+			fs.Errorf(o, "failed to cache checksums: %v", err)
+		}
+	}
+	return nil
+}
+
+// hashSumsToStrings converts the output of MultiHasher.Sums into the
+// form stored in the cache
+func hashSumsToStrings(sums map[hash.Type]string) map[string]string {
+	hashes := make(map[string]string, len(sums))
+	for ht, value := range sums {
+		hashes[ht.String()] = value
+	}
+	return hashes
+}
@@ -0,0 +1,288 @@
+// +build linux
+
+package local
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"golang.org/x/sys/unix"
+)
+
+// watchMask is the inotify events which are watched for on each
+// directory
+const watchMask = unix.IN_ATTRIB | unix.IN_CLOSE_WRITE | unix.IN_CREATE |
+	unix.IN_DELETE | unix.IN_MODIFY | unix.IN_MOVED_FROM | unix.IN_MOVED_TO |
+	unix.IN_DONT_FOLLOW | unix.IN_ONLYDIR
+
+// ChangeNotify calls the passed function with a path that has had
+// changes.
+//
+// Changes are found with inotify which watches every directory under
+// the root, so the poll interval is only used to stop watching (zero)
+// or start again (non zero), and to check whether the root has been
+// created if it didn't exist. Watching starts straight away so
+// changes made before the first poll interval is received aren't
+// missed.
+func (f *Fs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	missed := false // set if changes were missed as the root couldn't be watched
+	start := func() (w *watcher) {
+		w, err := f.newWatcher(notifyFunc, missed)
+		if err != nil {
+			if os.IsNotExist(errors.Cause(err)) {
+				fs.Debugf(f, "Waiting for root to be created to watch for changes")
+			} else {
+				fs.Errorf(f, "Failed to watch for changes: %v", err)
+			}
+			missed = true
+			return nil
+		}
+		missed = false
+		return w
+	}
+	w := start()
+	go func() {
+		var ticker *time.Ticker
+		var tickerC <-chan time.Time
+		setTicker := func(pollInterval time.Duration) {
+			if ticker != nil {
+				ticker.Stop()
+				ticker, tickerC = nil, nil
+			}
+			if pollInterval > 0 {
+				ticker = time.NewTicker(pollInterval)
+				tickerC = ticker.C
+			}
+		}
+		stop := func() {
+			if w != nil {
+				w.close()
+				w = nil
+			}
+		}
+		defer func() {
+			setTicker(0)
+			stop()
+		}()
+		for {
+			select {
+			case pollInterval, ok := <-pollIntervalChan:
+				if !ok {
+					return
+				}
+				setTicker(pollInterval)
+				if pollInterval == 0 {
+					stop()
+				} else if w == nil {
+					w = start()
+				}
+			case <-tickerC:
+				if w == nil {
+					w = start()
+				}
+			}
+		}
+	}()
+}
+
+// watcher watches a directory tree with inotify
+type watcher struct {
+	f          *Fs
+	notifyFunc func(string, fs.EntryType)
+	file       *os.File       // inotify instance
+	dirs       map[int]string // directory remote for each watch descriptor
+	warned     bool           // set if warned about running out of watches
+	done       chan struct{}  // closed when run has finished
+}
+
+// newWatcher starts watching every directory under the root of f
+// calling notifyFunc with the changes. If notify is set everything
+// already under the root is notified too.
+func (f *Fs) newWatcher(notifyFunc func(string, fs.EntryType), notify bool) (*watcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start inotify")
+	}
+	w := &watcher{
+		f:          f,
+		notifyFunc: notifyFunc,
+		file:       os.NewFile(uintptr(fd), "inotify"),
+		dirs:       make(map[int]string),
+		done:       make(chan struct{}),
+	}
+	_, err = os.Stat(f.root)
+	if err == nil {
+		w.addDir("", notify)
+		if len(w.dirs) == 0 {
+			err = errors.New("failed to watch root")
+		}
+	}
+	if err != nil {
+		_ = w.file.Close()
+		return nil, err
+	}
+	go w.run()
+	return w, nil
+}
+
+// control calls fn with the inotify file descriptor which can't be
+// closed while fn is running
+func (w *watcher) control(fn func(fd int)) error {
+	rawConn, err := w.file.SyscallConn()
+	if err != nil {
+		return err
+	}
+	return rawConn.Control(func(fd uintptr) {
+		fn(int(fd))
+	})
+}
+
+// addDir watches dir and all the directories under it.
+//
+// If notify is set the entries found are notified as they may have
+// been made before dir was watched.
+func (w *watcher) addDir(dir string, notify bool) {
+	localPath := w.f.localPath(dir)
+	var wd int
+	var err error
+	controlErr := w.control(func(fd int) {
+		wd, err = unix.InotifyAddWatch(fd, localPath, watchMask)
+	})
+	if controlErr != nil {
+		err = controlErr
+	}
+	if err != nil {
+		if err == unix.ENOSPC {
+			if !w.warned {
+				fs.Errorf(w.f, "Not watching all directories for changes as inotify has run out of watches - increase fs.inotify.max_user_watches")
+				w.warned = true
+			}
+		} else if err != unix.ENOENT && !errors.Is(err, os.ErrClosed) {
+			fs.Errorf(w.f, "Failed to watch %q for changes: %v", dir, err)
+		}
+		return
+	}
+	w.dirs[wd] = dir
+	fd, err := os.Open(localPath)
+	if err != nil {
+		return
+	}
+	fis, err := fd.Readdir(-1)
+	_ = fd.Close()
+	if err != nil {
+		fs.Errorf(w.f, "Failed to read %q to watch for changes: %v", dir, err)
+	}
+	for _, fi := range fis {
+		name := fi.Name()
+		if fs.IsPartialName(name) {
+			continue
+		}
+		remote := w.f.cleanRemote(dir, name)
+		if fi.IsDir() {
+			if w.f.dev == readDevice(fi, w.f.opt.OneFileSystem) {
+				w.addDir(remote, notify)
+			}
+			if notify {
+				w.notifyFunc(remote, fs.EntryDirectory)
+			}
+		} else if notify {
+			if w.f.opt.TranslateSymlinks && fi.Mode()&os.ModeSymlink != 0 {
+				remote += linkSuffix
+			}
+			w.notifyFunc(remote, fs.EntryObject)
+		}
+	}
+}
+
+// removeDir stops watching dir and all the directories under it
+func (w *watcher) removeDir(dir string) {
+	for wd, watched := range w.dirs {
+		if watched == dir || strings.HasPrefix(watched, dir+"/") {
+			_ = w.control(func(fd int) {
+				_, _ = unix.InotifyRmWatch(fd, uint32(wd))
+			})
+			delete(w.dirs, wd)
+		}
+	}
+}
+
+// run reads the events until the watcher is closed
+func (w *watcher) run() {
+	defer close(w.done)
+	var buf [unix.SizeofInotifyEvent * 4096]byte
+	for {
+		n, err := w.file.Read(buf[:])
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				fs.Errorf(w.f, "Failed to read changes: %v", err)
+			}
+			return
+		}
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			offset += unix.SizeofInotifyEvent
+			name := strings.TrimRight(string(buf[offset:offset+int(event.Len)]), "\x00")
+			offset += int(event.Len)
+			w.handle(int(event.Wd), event.Mask, name)
+		}
+	}
+}
+
+// handle an inotify event for name in the directory watched by wd
+func (w *watcher) handle(wd int, mask uint32, name string) {
+	if mask&unix.IN_Q_OVERFLOW != 0 {
+		fs.Logf(w.f, "Too many changes to keep track of - notifying a change to the root")
+		w.notifyFunc("", fs.EntryDirectory)
+		return
+	}
+	dir, ok := w.dirs[wd]
+	if !ok {
+		return
+	}
+	if mask&unix.IN_IGNORED != 0 {
+		delete(w.dirs, wd)
+		return
+	}
+	if name == "" {
+		return
+	}
+	if fs.IsPartialName(name) {
+		// Uploads in progress are renamed into place when done
+		return
+	}
+	remote := w.f.cleanRemote(dir, name)
+	if mask&unix.IN_ISDIR != 0 {
+		if mask&(unix.IN_MOVED_FROM|unix.IN_DELETE) != 0 {
+			w.removeDir(remote)
+		}
+		if mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+			w.addDir(remote, true)
+		}
+		w.notifyFunc(remote, fs.EntryDirectory)
+		return
+	}
+	if w.f.opt.TranslateSymlinks {
+		fi, err := os.Lstat(w.f.localPath(remote))
+		if err != nil {
+			// Gone so notify both the names it could have had
+			w.notifyFunc(remote+linkSuffix, fs.EntryObject)
+		} else if fi.Mode()&os.ModeSymlink != 0 {
+			remote += linkSuffix
+		}
+	}
+	w.notifyFunc(remote, fs.EntryObject)
+}
+
+// close stops watching and waits for run to finish
+func (w *watcher) close() {
+	_ = w.file.Close()
+	<-w.done
+}
+
+// Check the interfaces are satisfied
+var _ fs.ChangeNotifier = &Fs{}
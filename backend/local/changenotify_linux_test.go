@@ -0,0 +1,74 @@
+// +build linux
+
+package local
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeNotifyRenamedDir(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	f := r.Flocal.(*Fs)
+	require.NoError(t, os.MkdirAll(filepath.Join(f.root, "old", "sub"), 0777))
+
+	var mu sync.Mutex
+	changes := map[string]fs.EntryType{}
+	pollInterval := make(chan time.Duration)
+	f.ChangeNotify(ctx, func(remote string, entryType fs.EntryType) {
+		mu.Lock()
+		changes[remote] = entryType
+		mu.Unlock()
+	}, pollInterval)
+	defer close(pollInterval)
+	pollInterval <- time.Minute
+
+	// Wait for a change to be notified
+	waitFor := func(remote string, want fs.EntryType) {
+		for tries := 0; tries < 100; tries++ {
+			mu.Lock()
+			entryType, ok := changes[remote]
+			mu.Unlock()
+			if ok {
+				assert.Equal(t, want, entryType, remote)
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		t.Errorf("change to %q not notified", remote)
+	}
+
+	// Changes deep in the tree are seen
+	require.NoError(t, ioutil.WriteFile(filepath.Join(f.root, "old", "sub", "file1"), []byte("hello"), 0666))
+	waitFor("old/sub/file1", fs.EntryObject)
+
+	// After a rename changes are reported with the new path
+	require.NoError(t, os.Rename(filepath.Join(f.root, "old"), filepath.Join(f.root, "new")))
+	waitFor("old", fs.EntryDirectory)
+	waitFor("new", fs.EntryDirectory)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(f.root, "new", "sub", "file2"), []byte("hello"), 0666))
+	waitFor("new/sub/file2", fs.EntryObject)
+
+	// New directories are watched
+	require.NoError(t, os.MkdirAll(filepath.Join(f.root, "new", "made"), 0777))
+	waitFor("new/made", fs.EntryDirectory)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(f.root, "new", "made", "file3"), []byte("hello"), 0666))
+	waitFor("new/made/file3", fs.EntryObject)
+
+	mu.Lock()
+	_, ok := changes["old/sub/file2"]
+	mu.Unlock()
+	assert.False(t, ok, "change notified with old path")
+}
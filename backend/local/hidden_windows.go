@@ -0,0 +1,29 @@
+//+build windows
+
+package local
+
+import "syscall"
+
+// copyHidden makes the file at dst hidden if the file at src is
+func copyHidden(dst, src string) error {
+	srcPtr, err := syscall.UTF16PtrFromString(src)
+	if err != nil {
+		return err
+	}
+	srcAttrs, err := syscall.GetFileAttributes(srcPtr)
+	if err != nil {
+		return err
+	}
+	if srcAttrs&syscall.FILE_ATTRIBUTE_HIDDEN == 0 {
+		return nil
+	}
+	dstPtr, err := syscall.UTF16PtrFromString(dst)
+	if err != nil {
+		return err
+	}
+	dstAttrs, err := syscall.GetFileAttributes(dstPtr)
+	if err != nil {
+		return err
+	}
+	return syscall.SetFileAttributes(dstPtr, dstAttrs|syscall.FILE_ATTRIBUTE_HIDDEN)
+}
@@ -13,6 +13,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode/utf8"
 
@@ -41,6 +42,10 @@ func init() {
 		Description: "Local Disk",
 		NewFs:       NewFs,
 		CommandHelp: commandHelp,
+		MetadataInfo: &fs.MetadataInfo{
+			System: systemMetadataInfo,
+			Help:   "Depending on which OS is in use the local backend may return only some of the system metadata. Setting system metadata is supported on all OSes but setting user metadata is not supported.",
+		},
 		Options: []fs.Option{{
 			Name: "nounc",
 			Help: "Disable UNC (long path names) conversion on Windows",
@@ -155,6 +160,19 @@ when copying to a CIFS mount owned by another user. If this option is
 enabled, rclone will no longer update the modtime after copying a file.`,
 			Default:  false,
 			Advanced: true,
+		}, {
+			Name: "change_notify",
+			Help: `Watch for changes to the files (Linux only)
+
+If this is set then rclone watches every directory under the root
+with inotify so that "rclone mount", "rclone serve" and "rclone
+changes" see changes made outside rclone straight away. It walks the
+whole directory tree when it starts to do this, and each directory
+uses an inotify watch.
+
+This is only supported on Linux and is ignored on other systems.`,
+			Default:  false,
+			Advanced: true,
 		}, {
 			Name:     config.ConfigEncoding,
 			Help:     config.ConfigEncodingHelp,
@@ -178,6 +196,7 @@ type Options struct {
 	CaseInsensitive   bool                 `config:"case_insensitive"`
 	NoSparse          bool                 `config:"no_sparse"`
 	NoSetModTime      bool                 `config:"no_set_modtime"`
+	ChangeNotify      bool                 `config:"change_notify"`
 	Enc               encoder.MultiEncoder `config:"encoding"`
 }
 
@@ -245,7 +264,12 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 		CanHaveEmptyDirectories: true,
 		IsLocal:                 true,
 		SlowHash:                true,
+		ReadMetadata:            true,
+		WriteMetadata:           true,
 	}).Fill(ctx, f)
+	if !opt.ChangeNotify {
+		f.features.ChangeNotify = nil
+	}
 	if opt.FollowSymlinks {
 		f.lstat = os.Stat
 	}
@@ -451,6 +475,10 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 		for _, fi := range fis {
 			name := fi.Name()
 			mode := fi.Mode()
+			if !fi.IsDir() && fs.IsPartialName(name) {
+				// Skip uploads in progress or left by a crash
+				continue
+			}
 			newRemote := f.cleanRemote(dir, name)
 			// Follow symlinks if required
 			if f.opt.FollowSymlinks && (mode&os.ModeSymlink) != 0 {
@@ -549,7 +577,45 @@ func (f *Fs) Mkdir(ctx context.Context, dir string) error {
 //
 // If it isn't empty it will return an error
 func (f *Fs) Rmdir(ctx context.Context, dir string) error {
-	return os.Remove(f.localPath(dir))
+	localPath := f.localPath(dir)
+	err := os.Remove(localPath)
+	if err == nil || os.IsNotExist(err) {
+		return err
+	}
+	// Remove any partial files left by interrupted uploads if
+	// they are all that is in the directory and try again
+	if !removePartials(localPath) {
+		return err
+	}
+	return os.Remove(localPath)
+}
+
+// removePartials removes the partial files in dirPath if there is
+// nothing else in it, returning true if any were removed
+func removePartials(dirPath string) bool {
+	fd, err := os.Open(dirPath)
+	if err != nil {
+		return false
+	}
+	names, err := fd.Readdirnames(-1)
+	_ = fd.Close()
+	if err != nil || len(names) == 0 {
+		return false
+	}
+	for _, name := range names {
+		if !fs.IsPartialName(name) {
+			return false
+		}
+	}
+	for _, name := range names {
+		partialPath := filepath.Join(dirPath, name)
+		fs.Debugf(partialPath, "Removing partial file left by an interrupted upload")
+		if err := remove(partialPath); err != nil {
+			fs.Errorf(partialPath, "Failed to remove partial file: %v", err)
+			return false
+		}
+	}
+	return true
 }
 
 // Precision of the file system
@@ -700,6 +766,78 @@ func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object,
 	return dstObj, nil
 }
 
+// Link src to this remote as a hard link.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantLink
+func (f *Fs) Link(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't link - not same remote type")
+		return nil, fs.ErrorCantLink
+	}
+	if srcObj.translatedLink {
+		fs.Debugf(src, "Can't link - is a translated symlink")
+		return nil, fs.ErrorCantLink
+	}
+
+	// Temporary Object under construction
+	dstObj := f.newObject(remote)
+	dstObj.fs.objectMetaMu.RLock()
+	dstObjMode := dstObj.mode
+	dstObj.fs.objectMetaMu.RUnlock()
+
+	// Check it is a file if it exists
+	err := dstObj.lstat()
+	exists := err == nil
+	if os.IsNotExist(err) {
+		// OK
+	} else if err != nil {
+		return nil, err
+	} else if !dstObj.fs.isRegular(dstObjMode) {
+		// It isn't a file
+		return nil, errors.New("can't link onto non-file")
+	}
+
+	// Create destination
+	err = dstObj.mkdirAll()
+	if err != nil {
+		return nil, err
+	}
+
+	// Replace any existing file
+	if exists {
+		err = os.Remove(dstObj.path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Do the link
+	err = os.Link(srcObj.path, dstObj.path)
+	if os.IsNotExist(err) || os.IsPermission(err) {
+		return nil, err
+	} else if err != nil {
+		// probably trying to link across file system boundaries or
+		// on a file system without hard links
+		fs.Debugf(src, "Can't link: %v", err)
+		return nil, fs.ErrorCantLink
+	}
+
+	// Update the info
+	err = dstObj.lstat()
+	if err != nil {
+		return nil, err
+	}
+
+	return dstObj, nil
+}
+
 // DirMove moves src, srcRemote to this remote at dstRemote
 // using server-side move operations.
 //
@@ -1093,18 +1231,25 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 		return err
 	}
 
+	// Symlinks are made in place, see fs.PartialPrefix for the rest
+	writePath := o.path
+	if !o.translatedLink && !fs.GetConfig(ctx).Inplace {
+		writePath = partialPath(o.path)
+	}
+
 	var symlinkData bytes.Buffer
+	var outFile *os.File
 	// If the object is a regular file, create it.
 	// If it is a translated link, just read in the contents, and
 	// then create a symlink
 	if !o.translatedLink {
-		f, err := file.OpenFile(o.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+		f, err := file.OpenFile(writePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
 		if err != nil {
-			if runtime.GOOS == "windows" && os.IsPermission(err) {
+			if runtime.GOOS == "windows" && os.IsPermission(err) && writePath == o.path {
 				// If permission denied on Windows might be trying to update a
 				// hidden file, in which case try opening without CREATE
 				// See: https://stackoverflow.com/questions/13215716/ioerror-errno-13-permission-denied-when-trying-to-open-hidden-file-in-w-mod
-				f, err = file.OpenFile(o.path, os.O_WRONLY|os.O_TRUNC, 0666)
+				f, err = file.OpenFile(writePath, os.O_WRONLY|os.O_TRUNC, 0666)
 				if err != nil {
 					return err
 				}
@@ -1117,6 +1262,7 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 		if err != nil {
 			fs.Debugf(o, "Failed to pre-allocate: %v", err)
 		}
+		outFile = f
 		out = f
 	} else {
 		out = nopWriterCloser{&symlinkData}
@@ -1128,10 +1274,17 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 	}
 
 	_, err = io.Copy(out, in)
+	if err == nil && writePath != o.path {
+		// Make sure the data is on disk before renaming it into place
+		err = outFile.Sync()
+	}
 	closeErr := out.Close()
 	if err == nil {
 		err = closeErr
 	}
+	if err == nil && writePath != o.path {
+		err = renamePartial(writePath, o.path)
+	}
 
 	if o.translatedLink {
 		if err == nil {
@@ -1154,7 +1307,7 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 
 	if err != nil {
 		fs.Logf(o, "Removing partially written file on error: %v", err)
-		if removeErr := os.Remove(o.path); removeErr != nil {
+		if removeErr := os.Remove(writePath); removeErr != nil {
 			fs.Errorf(o, "Failed to remove partially written file: %v", removeErr)
 		}
 		return err
@@ -1173,6 +1326,16 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 		return err
 	}
 
+	// Set the metadata if --metadata is in use
+	metadata, err := fs.GetMetadataOptions(ctx, src, options)
+	if err != nil {
+		return errors.Wrap(err, "failed to read metadata from source object")
+	}
+	err = o.writeMetadata(metadata)
+	if err != nil {
+		return err
+	}
+
 	// ReRead info now that we have finished
 	return o.lstat()
 }
@@ -1197,7 +1360,12 @@ func (f *Fs) OpenWriterAt(ctx context.Context, remote string, size int64) (fs.Wr
 		return nil, errors.New("can't open a symlink for random writing")
 	}
 
-	out, err := file.OpenFile(o.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	inplace := fs.GetConfig(ctx).Inplace
+	writePath := o.path
+	if !inplace {
+		writePath = partialPath(o.path)
+	}
+	out, err := file.OpenFile(writePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
 	if err != nil {
 		return nil, err
 	}
@@ -1217,7 +1385,95 @@ func (f *Fs) OpenWriterAt(ctx context.Context, remote string, size int64) (fs.Wr
 		}
 	}
 
-	return out, nil
+	if inplace {
+		return out, nil
+	}
+	return &partialWriterAt{File: out, path: o.path, size: size}, nil
+}
+
+// partialWriterAt writes to a partial file which is renamed into
+// place when it is closed if all of it has been written. If the size
+// isn't known it is renamed into place whatever was written.
+type partialWriterAt struct {
+	*os.File
+	path    string // where the file should end up
+	size    int64  // size of the complete file or -1 if not known
+	written int64  // bytes written so far - use atomic
+}
+
+// WriteAt writes p at offset off counting the bytes written
+func (w *partialWriterAt) WriteAt(p []byte, off int64) (n int, err error) {
+	n, err = w.File.WriteAt(p, off)
+	atomic.AddInt64(&w.written, int64(n))
+	return n, err
+}
+
+// Close the file and rename it into place if it is complete,
+// otherwise remove it
+func (w *partialWriterAt) Close() error {
+	err := w.File.Sync()
+	closeErr := w.File.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if written := atomic.LoadInt64(&w.written); err == nil && w.size >= 0 && written != w.size {
+		err = errors.Errorf("incomplete write: wrote %d of %d bytes", written, w.size)
+	}
+	if err != nil {
+		if removeErr := os.Remove(w.Name()); removeErr != nil {
+			fs.Errorf(w.path, "Failed to remove partially written file: %v", removeErr)
+		}
+		return err
+	}
+	return renamePartial(w.Name(), w.path)
+}
+
+// partialPath returns a new path in the same directory as path to
+// upload to before renaming it into place
+func partialPath(path string) string {
+	dir, leaf := filepath.Split(path)
+	return filepath.Join(dir, fs.PartialName(leaf))
+}
+
+// renamePartial renames the complete partial file at partialPath
+// into place at path giving it the permissions and, on Windows, the
+// hidden attribute of the file it replaces
+func renamePartial(partialPath, path string) error {
+	if fi, err := os.Stat(path); err == nil {
+		if err := os.Chmod(partialPath, fi.Mode().Perm()); err != nil {
+			fs.Debugf(path, "Failed to copy permissions to partial file: %v", err)
+		}
+		if err := copyHidden(partialPath, path); err != nil {
+			fs.Debugf(path, "Failed to copy hidden attribute to partial file: %v", err)
+		}
+	}
+	err := os.Rename(partialPath, path)
+	if err == nil || runtime.GOOS != "windows" || !os.IsPermission(err) {
+		return err
+	}
+	// If permission denied on Windows might be trying to replace a
+	// hidden file, which can't be renamed over but can be opened
+	// without CREATE, so copy the data into it instead
+	// See: https://stackoverflow.com/questions/13215716/ioerror-errno-13-permission-denied-when-trying-to-open-hidden-file-in-w-mod
+	out, openErr := file.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0666)
+	if openErr != nil {
+		return err
+	}
+	in, err := os.Open(partialPath)
+	if err != nil {
+		_ = out.Close()
+		return err
+	}
+	_, err = io.Copy(out, in)
+	_ = in.Close()
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to copy partial file into place")
+	}
+	return os.Remove(partialPath)
 }
 
 // setMetadata sets the file info from the os.FileInfo passed in
@@ -1291,8 +1547,11 @@ var (
 	_ fs.Purger         = &Fs{}
 	_ fs.PutStreamer    = &Fs{}
 	_ fs.Mover          = &Fs{}
+	_ fs.Linker         = &Fs{}
 	_ fs.DirMover       = &Fs{}
 	_ fs.Commander      = &Fs{}
 	_ fs.OpenWriterAter = &Fs{}
 	_ fs.Object         = &Object{}
+	_ fs.Metadataer     = &Object{}
+	_ fs.SetMetadataer  = &Object{}
 )
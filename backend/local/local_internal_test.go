@@ -1,17 +1,23 @@
 package local
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config/configmap"
 	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/lib/file"
 	"github.com/rclone/rclone/lib/readers"
@@ -158,6 +164,171 @@ func TestSymlink(t *testing.T) {
 	require.NoError(t, in.Close())
 }
 
+func TestLink(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	f := r.Flocal.(*Fs)
+
+	file1 := r.WriteFile("file.txt", "hello", fstest.Time("2001-02-03T04:05:10.123123123Z"))
+	src, err := f.NewObject(ctx, file1.Path)
+	require.NoError(t, err)
+
+	// Link into a new directory and over an existing file
+	for _, remote := range []string{"dir/link.txt", "dir/link.txt"} {
+		dst, err := f.Link(ctx, src, remote)
+		require.NoError(t, err)
+		assert.Equal(t, remote, dst.Remote())
+		fi1, err := os.Stat(filepath.Join(f.root, "file.txt"))
+		require.NoError(t, err)
+		fi2, err := os.Stat(filepath.Join(f.root, "dir", "link.txt"))
+		require.NoError(t, err)
+		assert.True(t, os.SameFile(fi1, fi2))
+	}
+	file2 := file1
+	file2.Path = "dir/link.txt"
+	fstest.CheckItems(t, r.Flocal, file1, file2)
+
+	// Can't link onto a directory
+	_, err = f.Link(ctx, src, "dir")
+	assert.Error(t, err)
+}
+
+// errorReader returns some data then an error
+type errorReader struct {
+	data []byte
+}
+
+func (r *errorReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, errors.New("read failed")
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// partialFiles returns the names of the partial files in dir
+func partialFiles(t *testing.T, dir string) (names []string) {
+	matches, err := filepath.Glob(filepath.Join(dir, fs.PartialPrefix+"*"))
+	require.NoError(t, err)
+	for _, match := range matches {
+		names = append(names, filepath.Base(match))
+	}
+	return names
+}
+
+func TestListSkipsPartial(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	f := r.Flocal.(*Fs)
+
+	// A real file ending in .partial is listed but one left by an
+	// interrupted upload isn't
+	file1 := r.WriteFile("log.20210101.partial", "real", fstest.Time("2001-02-03T04:05:10.123123123Z"))
+	leftOver := fs.PartialName("file.txt")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(f.root, leftOver), []byte("left over"), 0666))
+	fstest.CheckItems(t, r.Flocal, file1)
+}
+
+func TestRmdirRemovesPartial(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	f := r.Flocal.(*Fs)
+
+	dir := filepath.Join(f.root, "dir")
+	require.NoError(t, os.MkdirAll(dir, 0777))
+	leftOver := filepath.Join(dir, fs.PartialName("file.txt"))
+	require.NoError(t, ioutil.WriteFile(leftOver, []byte("left over"), 0666))
+	realFile := filepath.Join(dir, "file.txt")
+	require.NoError(t, ioutil.WriteFile(realFile, []byte("real"), 0666))
+
+	// The partial file is kept while the directory isn't empty
+	assert.Error(t, f.Rmdir(ctx, "dir"))
+	_, err := os.Stat(leftOver)
+	assert.NoError(t, err)
+
+	// and removed with the directory once it is
+	require.NoError(t, os.Remove(realFile))
+	require.NoError(t, f.Rmdir(ctx, "dir"))
+	_, err = os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestUpdatePartial(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	f := r.Flocal.(*Fs)
+
+	file1 := r.WriteFile("file.txt", "original", fstest.Time("2001-02-03T04:05:10.123123123Z"))
+	o, err := f.NewObject(ctx, file1.Path)
+	require.NoError(t, err)
+	filePath := filepath.Join(f.root, file1.Path)
+	src := object.NewStaticObjectInfo(file1.Path, time.Now(), 100, true, nil, nil)
+
+	// A failed update leaves the original file alone
+	err = o.Update(ctx, &errorReader{data: []byte("new")}, src)
+	require.Error(t, err)
+	fstest.CheckItems(t, r.Flocal, file1)
+	assert.Equal(t, []string(nil), partialFiles(t, f.root))
+
+	// A successful update replaces it keeping its permissions
+	require.NoError(t, os.Chmod(filePath, 0750))
+	err = o.Update(ctx, strings.NewReader("updated"), src)
+	require.NoError(t, err)
+	data, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, "updated", string(data))
+	assert.Equal(t, []string(nil), partialFiles(t, f.root))
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(filePath)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0750), fi.Mode().Perm())
+	}
+
+	// With --inplace a failed update removes the file
+	ctx, ci := fs.AddConfig(ctx)
+	ci.Inplace = true
+	err = o.Update(ctx, &errorReader{data: []byte("new")}, src)
+	require.Error(t, err)
+	_, err = os.Stat(filePath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestOpenWriterAtPartial(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	f := r.Flocal.(*Fs)
+	filePath := filepath.Join(f.root, "file.txt")
+
+	// An incomplete write is removed
+	out, err := f.OpenWriterAt(ctx, "file.txt", 10)
+	require.NoError(t, err)
+	_, err = out.WriteAt([]byte("hello"), 5)
+	require.NoError(t, err)
+	assert.Error(t, out.Close())
+	_, err = os.Stat(filePath)
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, []string(nil), partialFiles(t, f.root))
+
+	// A complete write is renamed into place
+	out, err = f.OpenWriterAt(ctx, "file.txt", 10)
+	require.NoError(t, err)
+	_, err = os.Stat(filePath)
+	assert.True(t, os.IsNotExist(err))
+	_, err = out.WriteAt([]byte("world"), 5)
+	require.NoError(t, err)
+	_, err = out.WriteAt([]byte("hello"), 0)
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+	data, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, "helloworld", string(data))
+}
+
 func TestSymlinkError(t *testing.T) {
 	m := configmap.Simple{
 		"links":      "true",
@@ -166,3 +337,59 @@ func TestSymlinkError(t *testing.T) {
 	_, err := NewFs(context.Background(), "local", "/", m)
 	assert.Equal(t, errLinksAndCopyLinks, err)
 }
+
+func TestMetadata(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	f := r.Flocal.(*Fs)
+
+	modTime1 := fstest.Time("2001-02-03T04:05:10.123123123Z")
+	file1 := r.WriteFile("file.txt", "hello", modTime1)
+	require.NoError(t, os.Chmod(filepath.Join(f.root, "file.txt"), 0640))
+	obj, err := f.NewObject(ctx, file1.Path)
+	require.NoError(t, err)
+	o := obj.(*Object)
+
+	// Read the metadata
+	m, err := o.Metadata(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "0100640", m["mode"])
+	assert.Equal(t, modTime1.Format(time.RFC3339Nano), m["mtime"])
+
+	// Set the metadata
+	modTime2 := fstest.Time("2011-12-25T12:59:59.123456789Z")
+	err = o.SetMetadata(ctx, fs.Metadata{
+		"mode":  "0100600",
+		"mtime": modTime2.Format(time.RFC3339Nano),
+	})
+	require.NoError(t, err)
+	fi, err := os.Stat(o.path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+	fstest.AssertTimeEqualWithPrecision(t, o.remote, modTime2, o.ModTime(ctx), time.Nanosecond)
+
+	// Check bad metadata is rejected
+	err = o.SetMetadata(ctx, fs.Metadata{"mode": "potato"})
+	assert.Error(t, err)
+	err = o.SetMetadata(ctx, fs.Metadata{"uid": "potato"})
+	assert.Error(t, err)
+
+	// Check an owner we can't set isn't an error
+	if runtime.GOOS == "linux" && os.Geteuid() != 0 {
+		err = o.SetMetadata(ctx, fs.Metadata{"uid": "0", "gid": "0"})
+		assert.NoError(t, err)
+	}
+
+	// Check metadata is copied on upload with --metadata
+	ctx, ci := fs.AddConfig(ctx)
+	ci.Metadata = true
+	ci.MetadataSet = fs.Metadata{"mode": "0100604"}
+	src := operations.NewOverrideRemote(o, "file2.txt")
+	dst, err := f.Put(ctx, bytes.NewBufferString("hello"), src, fs.MetadataOption{"mtime": modTime1.Format(time.RFC3339Nano)})
+	require.NoError(t, err)
+	m, err = dst.(*Object).Metadata(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "0100604", m["mode"])
+	assert.Equal(t, modTime1.Format(time.RFC3339Nano), m["mtime"])
+}
@@ -0,0 +1,169 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+)
+
+const metadataTimeFormat = time.RFC3339Nano
+
+// system metadata keys which this backend uses
+var systemMetadataInfo = map[string]fs.MetadataHelp{
+	"mode": {
+		Help:    "File type and mode",
+		Type:    "octal, unix style",
+		Example: "0100664",
+	},
+	"uid": {
+		Help:    "User ID of owner",
+		Type:    "decimal number",
+		Example: "500",
+	},
+	"gid": {
+		Help:    "Group ID of owner",
+		Type:    "decimal number",
+		Example: "500",
+	},
+	"atime": {
+		Help:    "Time of last access",
+		Type:    "RFC 3339",
+		Example: "2006-01-02T15:04:05.999999999Z07:00",
+	},
+	"mtime": {
+		Help:    "Time of last modification",
+		Type:    "RFC 3339",
+		Example: "2006-01-02T15:04:05.999999999Z07:00",
+	},
+}
+
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *Object) Metadata(ctx context.Context) (metadata fs.Metadata, err error) {
+	info, err := o.fs.lstat(o.path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read metadata")
+	}
+	metadata = make(fs.Metadata, len(systemMetadataInfo))
+	metadata["mode"] = fmt.Sprintf("0%o", fileModeToUnix(info.Mode()))
+	metadata["mtime"] = info.ModTime().Format(metadataTimeFormat)
+	readMetadataFromStat(info, metadata)
+	return metadata, nil
+}
+
+// SetMetadata sets metadata for an Object
+//
+// Unknown keys are ignored.
+func (o *Object) SetMetadata(ctx context.Context, metadata fs.Metadata) error {
+	return o.writeMetadata(metadata)
+}
+
+// writeMetadata applies the system metadata passed in to the file
+//
+// It does nothing if metadata is empty.
+func (o *Object) writeMetadata(metadata fs.Metadata) (err error) {
+	if len(metadata) == 0 {
+		return nil
+	}
+	if mode, ok := metadata["mode"]; ok && !o.translatedLink {
+		m, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse metadata mode %q", mode)
+		}
+		err = os.Chmod(o.path, os.FileMode(m)&os.ModePerm)
+		if err != nil {
+			return errors.Wrap(err, "failed to set permissions from metadata")
+		}
+	}
+	uid, gid := -1, -1
+	if s, ok := metadata["uid"]; ok {
+		uid, err = strconv.Atoi(s)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse metadata uid %q", s)
+		}
+	}
+	if s, ok := metadata["gid"]; ok {
+		gid, err = strconv.Atoi(s)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse metadata gid %q", s)
+		}
+	}
+	if uid >= 0 || gid >= 0 {
+		err = lChown(o.path, uid, gid)
+		if os.IsPermission(err) {
+			// Only root can give files away so don't fail the
+			// upload if we aren't allowed to
+			fs.Logf(o, "Failed to set owner from metadata: %v", err)
+		} else if err != nil {
+			return errors.Wrap(err, "failed to set owner from metadata")
+		}
+	}
+	atimeString, haveAtime := metadata["atime"]
+	mtimeString, haveMtime := metadata["mtime"]
+	if (haveAtime || haveMtime) && !o.fs.opt.NoSetModTime {
+		var atime, mtime time.Time
+		if haveMtime {
+			mtime, err = time.Parse(metadataTimeFormat, mtimeString)
+			if err != nil {
+				return errors.Wrapf(err, "failed to parse metadata mtime %q", mtimeString)
+			}
+		} else {
+			mtime = o.ModTime(context.Background())
+		}
+		atime = mtime
+		if haveAtime {
+			atime, err = time.Parse(metadataTimeFormat, atimeString)
+			if err != nil {
+				return errors.Wrapf(err, "failed to parse metadata atime %q", atimeString)
+			}
+		}
+		if o.translatedLink {
+			err = lChtimes(o.path, atime, mtime)
+		} else {
+			err = os.Chtimes(o.path, atime, mtime)
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to set times from metadata")
+		}
+	}
+	// Re-read metadata
+	return o.lstat()
+}
+
+// fileModeToUnix converts a Go file mode into a unix style mode
+// including the file type bits
+func fileModeToUnix(mode os.FileMode) uint32 {
+	m := uint32(mode.Perm())
+	switch {
+	case mode&os.ModeDir != 0:
+		m |= 0040000
+	case mode&os.ModeSymlink != 0:
+		m |= 0120000
+	case mode&os.ModeNamedPipe != 0:
+		m |= 0010000
+	case mode&os.ModeSocket != 0:
+		m |= 0140000
+	case mode&os.ModeCharDevice != 0:
+		m |= 0020000
+	case mode&os.ModeDevice != 0:
+		m |= 0060000
+	default:
+		m |= 0100000
+	}
+	if mode&os.ModeSetuid != 0 {
+		m |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		m |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		m |= 01000
+	}
+	return m
+}
@@ -0,0 +1,31 @@
+// +build linux
+
+package local
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// readMetadataFromStat reads the owner and access time from info into
+// metadata
+func readMetadataFromStat(info os.FileInfo, metadata fs.Metadata) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		fs.Debugf(info.Name(), "Type assertion info.Sys().(*syscall.Stat_t) failed from: %#v", info.Sys())
+		return
+	}
+	metadata["uid"] = strconv.FormatUint(uint64(stat.Uid), 10)
+	metadata["gid"] = strconv.FormatUint(uint64(stat.Gid), 10)
+	metadata["atime"] = time.Unix(stat.Atim.Unix()).Format(metadataTimeFormat)
+}
+
+// lChown changes the owner of the named file without following
+// symlinks - -1 means leave unchanged
+func lChown(name string, uid, gid int) error {
+	return os.Lchown(name, uid, gid)
+}
@@ -0,0 +1,24 @@
+// +build !linux
+
+package local
+
+import (
+	"os"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// readMetadataFromStat reads the owner and access time from info into
+// metadata
+//
+// These aren't supported on this OS so it does nothing.
+func readMetadataFromStat(info os.FileInfo, metadata fs.Metadata) {
+}
+
+// lChown changes the owner of the named file without following
+// symlinks
+//
+// This isn't supported on this OS so it does nothing.
+func lChown(name string, uid, gid int) error {
+	return nil
+}
@@ -36,6 +36,7 @@ import (
 	"github.com/rclone/rclone/lib/pacer"
 	"github.com/rclone/rclone/lib/readers"
 	"github.com/rclone/rclone/lib/rest"
+	"github.com/rclone/rclone/lib/resume"
 	"golang.org/x/oauth2"
 )
 
@@ -1722,22 +1723,79 @@ func (o *Object) cancelUploadSession(ctx context.Context, url string) (err error
 	return
 }
 
+// uploadSessionState is the state of an upload session which is saved
+// so it can be resumed with --resume-uploads
+type uploadSessionState struct {
+	UploadURL string // URL of the upload session
+}
+
+// resumeUploadSession finds the upload session saved in up and the
+// position to carry on uploading from.
+//
+// It returns an empty uploadURL if there is no session to resume. As
+// uploads which can't be resumed are started again from the beginning
+// errors are logged rather than returned.
+func (o *Object) resumeUploadSession(ctx context.Context, up *resume.Upload, size int64) (uploadURL string, position int64) {
+	var state uploadSessionState
+	found, err := up.Load(&state)
+	if err != nil {
+		fs.Errorf(o, "Can't resume multipart upload: %v", err)
+		return "", 0
+	}
+	if !found || state.UploadURL == "" {
+		return "", 0
+	}
+	position, err = o.getPosition(ctx, state.UploadURL)
+	if err == nil && (position < 0 || position >= size) {
+		err = errors.Errorf("bad position %d", position)
+	}
+	if err != nil {
+		fs.Debugf(o, "Can't resume multipart upload: %v", err)
+		up.Remove()
+		return "", 0
+	}
+	return state.UploadURL, position
+}
+
 // uploadMultipart uploads a file using multipart upload
 func (o *Object) uploadMultipart(ctx context.Context, in io.Reader, size int64, modTime time.Time, options ...fs.OpenOption) (info *api.Item, err error) {
 	if size <= 0 {
 		return nil, errors.New("unknown-sized upload not supported")
 	}
 
+	// Resume the upload session if possible
+	var (
+		up        = resume.Get(ctx)
+		uploadURL string
+		position  int64
+	)
+	if up != nil {
+		uploadURL, position = o.resumeUploadSession(ctx, up, size)
+	}
+
 	// Create upload session
-	fs.Debugf(o, "Starting multipart upload")
-	session, err := o.createUploadSession(ctx, modTime)
-	if err != nil {
-		return nil, err
+	if uploadURL == "" {
+		fs.Debugf(o, "Starting multipart upload")
+		session, err := o.createUploadSession(ctx, modTime)
+		if err != nil {
+			return nil, err
+		}
+		uploadURL = session.UploadURL
+		if up != nil {
+			err = up.Save(&uploadSessionState{UploadURL: uploadURL})
+			if err != nil {
+				fs.Errorf(o, "Upload won't be resumable: %v", err)
+				up = nil
+			}
+		}
 	}
-	uploadURL := session.UploadURL
 
 	// Cancel the session if something went wrong
 	defer atexit.OnError(&err, func() {
+		if up != nil {
+			fs.Debugf(o, "Leaving multipart upload to be resumed: %v", err)
+			return
+		}
 		fs.Debugf(o, "Cancelling multipart upload: %v", err)
 		cancelErr := o.cancelUploadSession(ctx, uploadURL)
 		if cancelErr != nil {
@@ -1745,9 +1803,17 @@ func (o *Object) uploadMultipart(ctx context.Context, in io.Reader, size int64,
 		}
 	})()
 
+	// Skip the data uploaded already
+	if position > 0 {
+		fs.Infof(o, "Resuming multipart upload at %v", fs.SizeSuffix(position))
+		err = resume.Skip(in, position)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Upload the chunks
-	remaining := size
-	position := int64(0)
+	remaining := size - position
 	for remaining > 0 {
 		n := int64(o.fs.opt.ChunkSize)
 		if remaining < n {
@@ -1763,6 +1829,9 @@ func (o *Object) uploadMultipart(ctx context.Context, in io.Reader, size int64,
 		position += n
 	}
 
+	if up != nil {
+		up.Remove()
+	}
 	return info, nil
 }
 
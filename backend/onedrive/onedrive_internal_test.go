@@ -0,0 +1,104 @@
+package onedrive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/rest"
+	"github.com/rclone/rclone/lib/resume"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sessionServer is a fake upload session which has received the data
+// in received so far
+type sessionServer struct {
+	mu       sync.Mutex
+	size     int64
+	received []byte
+}
+
+func (s *sessionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == "PUT" {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var start, end, size int64
+		_, err = fmt.Sscanf(r.Header.Get("Content-Range"), "bytes %d-%d/%d", &start, &end, &size)
+		if err != nil || start != int64(len(s.received)) || size != s.size {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		s.received = append(s.received, body...)
+		if int64(len(s.received)) == s.size {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprintf(w, `{"id":"file-id","name":"file","size":%d}`, s.size)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+	_, _ = fmt.Fprintf(w, `{"nextExpectedRanges":["%d-"]}`, len(s.received))
+}
+
+// Test an upload session is resumed with --resume-uploads
+func TestUploadResume(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "rclone-onedrive-resume-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	oldDir := resume.Dir
+	resume.Dir = dir
+	defer func() {
+		resume.Dir = oldDir
+	}()
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1024)
+	size := int64(len(data))
+	server := &sessionServer{size: size, received: append([]byte(nil), data[:5000]...)}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	f := &Fs{
+		name:  "onedriveresume",
+		opt:   Options{ChunkSize: 4096},
+		srv:   rest.NewClient(ts.Client()),
+		pacer: fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(time.Millisecond))),
+	}
+	o := &Object{fs: f, remote: "file"}
+
+	// Save the session as if a previous upload had been interrupted
+	uploadCtx := resume.WithUpload(ctx, f, "file", "fingerprint")
+	require.NoError(t, resume.Get(uploadCtx).Save(&uploadSessionState{UploadURL: ts.URL + "/session"}))
+
+	// Upload with the start corrupted - this should be skipped
+	// as the server has it already
+	corrupted := append([]byte(nil), data...)
+	copy(corrupted, bytes.Repeat([]byte("X"), 5000))
+	info, err := o.uploadMultipart(uploadCtx, bytes.NewReader(corrupted), size, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "file-id", info.GetID())
+	assert.True(t, bytes.Equal(data, server.received), "uploaded data differs")
+
+	// The saved session is removed once the upload is complete
+	states, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(states))
+}
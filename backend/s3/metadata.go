@@ -0,0 +1,135 @@
+package s3
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/ncw/swift"
+	"github.com/rclone/rclone/fs"
+)
+
+// system metadata keys which this backend uses
+var systemMetadataInfo = map[string]fs.MetadataHelp{
+	"cache-control": {
+		Help:    "Cache-Control header",
+		Type:    "string",
+		Example: "no-cache",
+	},
+	"content-disposition": {
+		Help:    "Content-Disposition header",
+		Type:    "string",
+		Example: "inline",
+	},
+	"content-encoding": {
+		Help:    "Content-Encoding header",
+		Type:    "string",
+		Example: "gzip",
+	},
+	"content-language": {
+		Help:    "Content-Language header",
+		Type:    "string",
+		Example: "en-US",
+	},
+	"content-type": {
+		Help:    "Content-Type header",
+		Type:    "string",
+		Example: "text/plain",
+	},
+	"mtime": {
+		Help:    "Time of last modification, read from rclone metadata",
+		Type:    "RFC 3339",
+		Example: "2006-01-02T15:04:05.999999999Z07:00",
+	},
+	"object-lock-mode": {
+		Help:     "Object Lock retention mode",
+		Type:     "string",
+		Example:  "GOVERNANCE",
+		ReadOnly: true,
+	},
+	"object-lock-retain-until-date": {
+		Help:     "Date the Object Lock retention expires",
+		Type:     "RFC 3339",
+		Example:  "2006-01-02T15:04:05Z",
+		ReadOnly: true,
+	},
+	"object-lock-legal-hold-status": {
+		Help:     "Object Lock legal hold status",
+		Type:     "string",
+		Example:  "ON",
+		ReadOnly: true,
+	},
+}
+
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *Object) Metadata(ctx context.Context) (metadata fs.Metadata, err error) {
+	err = o.readMetaData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metadata = make(fs.Metadata, len(o.meta)+5)
+	for k, v := range o.meta {
+		switch k {
+		case metaMtime:
+			if modTime, err := swift.FloatStringToTime(*v); err == nil {
+				metadata["mtime"] = modTime.Format(time.RFC3339Nano)
+			}
+		case metaMD5Hash:
+			// don't write hash metadata
+		default:
+			metadata[strings.ToLower(k)] = *v
+		}
+	}
+	if o.mimeType != "" {
+		metadata["content-type"] = o.mimeType
+	}
+	setMetadata := func(k string, v *string) {
+		if v == nil || *v == "" {
+			return
+		}
+		metadata[k] = *v
+	}
+	setMetadata("cache-control", o.cacheControl)
+	setMetadata("content-disposition", o.contentDisposition)
+	setMetadata("content-encoding", o.contentEncoding)
+	setMetadata("content-language", o.contentLanguage)
+	setMetadata("object-lock-mode", &o.lockMode)
+	if o.lockRetainUntil != nil {
+		metadata["object-lock-retain-until-date"] = o.lockRetainUntil.Format(time.RFC3339)
+	}
+	setMetadata("object-lock-legal-hold-status", &o.legalHold)
+	return metadata, nil
+}
+
+// applyMetadata sets the system and user metadata in meta on req
+func (o *Object) applyMetadata(req *s3.PutObjectInput, meta fs.Metadata) {
+	for k, v := range meta {
+		switch k {
+		case "cache-control":
+			req.CacheControl = aws.String(v)
+		case "content-disposition":
+			req.ContentDisposition = aws.String(v)
+		case "content-encoding":
+			req.ContentEncoding = aws.String(v)
+		case "content-language":
+			req.ContentLanguage = aws.String(v)
+		case "content-type":
+			req.ContentType = aws.String(v)
+		case "mtime":
+			modTime, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				fs.Debugf(o, "failed to parse metadata %s: %q: %v", k, v, err)
+				continue
+			}
+			req.Metadata[metaMtime] = aws.String(swift.TimeToFloatString(modTime))
+		case "object-lock-mode", "object-lock-retain-until-date", "object-lock-legal-hold-status":
+			// read only - use the object_lock options to set these
+		default:
+			req.Metadata[k] = aws.String(v)
+		}
+	}
+}
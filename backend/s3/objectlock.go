@@ -0,0 +1,245 @@
+package s3
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/operations"
+)
+
+// parseLockMode checks mode is a valid Object Lock mode, returning
+// it in the upper case form S3 uses
+func parseLockMode(mode string) (string, error) {
+	mode = strings.ToUpper(strings.TrimSpace(mode))
+	switch mode {
+	case "", s3.ObjectLockModeGovernance, s3.ObjectLockModeCompliance:
+		return mode, nil
+	}
+	return "", errors.Errorf("unknown object lock mode %q - must be %s or %s", mode, s3.ObjectLockModeGovernance, s3.ObjectLockModeCompliance)
+}
+
+// parseRetainUntil parses a retain until date which is either an
+// absolute date or a duration relative to now
+func parseRetainUntil(retainUntil string, now time.Time) (time.Time, error) {
+	retainUntil = strings.TrimSpace(retainUntil)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+		if t, err := time.Parse(layout, retainUntil); err == nil {
+			return t, nil
+		}
+	}
+	d, err := fs.ParseDuration(retainUntil)
+	if err != nil {
+		return time.Time{}, errors.Errorf("can't parse %q as a date or a duration", retainUntil)
+	}
+	if d <= 0 {
+		return time.Time{}, errors.Errorf("retain until duration %q must be positive", retainUntil)
+	}
+	return now.Add(d), nil
+}
+
+// checkObjectLock checks the object lock options are consistent
+func checkObjectLock(opt *Options) (err error) {
+	opt.ObjectLockMode, err = parseLockMode(opt.ObjectLockMode)
+	if err != nil {
+		return err
+	}
+	if (opt.ObjectLockMode == "") != (opt.ObjectLockRetainUntil == "") {
+		return errors.New("object_lock_mode and object_lock_retain_until must be set together")
+	}
+	if opt.ObjectLockRetainUntil != "" {
+		if _, err = parseRetainUntil(opt.ObjectLockRetainUntil, time.Now()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// objectLocking returns true if uploads should set an Object Lock
+func (f *Fs) objectLocking() bool {
+	return f.opt.ObjectLockMode != "" || f.opt.ObjectLockLegalHold
+}
+
+// objectLock returns the Object Lock settings to use for a new
+// object, with nil values for those which shouldn't be set
+func (f *Fs) objectLock() (mode *string, retainUntil *time.Time, legalHold *string) {
+	if f.opt.ObjectLockMode != "" {
+		mode = aws.String(f.opt.ObjectLockMode)
+		// This was checked in NewFs so can't fail
+		t, _ := parseRetainUntil(f.opt.ObjectLockRetainUntil, time.Now())
+		retainUntil = &t
+	}
+	if f.opt.ObjectLockLegalHold {
+		legalHold = aws.String(s3.ObjectLockLegalHoldStatusOn)
+	}
+	return mode, retainUntil, legalHold
+}
+
+// objectLockStatus is the result of an object lock backend command
+// on a single object
+type objectLockStatus struct {
+	Remote          string
+	Status          string
+	Mode            string `json:",omitempty"`
+	RetainUntilDate string `json:",omitempty"`
+	LegalHold       string `json:",omitempty"`
+}
+
+// setLockStatus reads the Object Lock settings of o into st
+func (o *Object) setLockStatus(st *objectLockStatus) {
+	st.Mode = o.lockMode
+	if o.lockRetainUntil != nil {
+		st.RetainUntilDate = o.lockRetainUntil.Format(time.RFC3339)
+	}
+	st.LegalHold = o.legalHold
+}
+
+// objectLockCommand calls fn on all the objects in f, returning a
+// status for each one.
+//
+// If update is set the command changes the objects so obeys
+// --dry-run and the object lock settings are read again afterwards.
+func (f *Fs) objectLockCommand(ctx context.Context, name string, update bool, fn func(ctx context.Context, o *Object) error) (out []objectLockStatus, err error) {
+	var outMu sync.Mutex
+	out = []objectLockStatus{}
+	err = operations.ListFn(ctx, f, func(obj fs.Object) {
+		// Remember this is run --checkers times concurrently
+		st := objectLockStatus{Status: "OK", Remote: obj.Remote()}
+		defer func() {
+			outMu.Lock()
+			out = append(out, st)
+			outMu.Unlock()
+		}()
+		o, ok := obj.(*Object)
+		if !ok {
+			st.Status = "Not an S3 object"
+			return
+		}
+		if update && operations.SkipDestructive(ctx, obj, name) {
+			return
+		}
+		err := o.readMetaData(ctx)
+		if err == nil {
+			err = fn(ctx, o)
+		}
+		if err == nil && update {
+			o.meta = nil // read the new settings
+			err = o.readMetaData(ctx)
+		}
+		if err != nil {
+			st.Status = err.Error()
+			return
+		}
+		o.setLockStatus(&st)
+	})
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Remote < out[j].Remote
+	})
+	return out, err
+}
+
+// putRetention sets the Object Lock retention of o
+func (o *Object) putRetention(ctx context.Context, mode string, retainUntil time.Time, bypassGovernance bool) error {
+	bucket, bucketPath := o.split()
+	req := s3.PutObjectRetentionInput{
+		Bucket: &bucket,
+		Key:    &bucketPath,
+		Retention: &s3.ObjectLockRetention{
+			Mode:            &mode,
+			RetainUntilDate: &retainUntil,
+		},
+	}
+	if bypassGovernance {
+		req.BypassGovernanceRetention = aws.Bool(true)
+	}
+	return o.fs.pacer.Call(func() (bool, error) {
+		_, err := o.fs.c.PutObjectRetentionWithContext(ctx, &req)
+		return o.fs.shouldRetry(err)
+	})
+}
+
+// putLegalHold sets the Object Lock legal hold of o to status
+func (o *Object) putLegalHold(ctx context.Context, status string) error {
+	bucket, bucketPath := o.split()
+	req := s3.PutObjectLegalHoldInput{
+		Bucket: &bucket,
+		Key:    &bucketPath,
+		LegalHold: &s3.ObjectLockLegalHold{
+			Status: &status,
+		},
+	}
+	return o.fs.pacer.Call(func() (bool, error) {
+		_, err := o.fs.c.PutObjectLegalHoldWithContext(ctx, &req)
+		return o.fs.shouldRetry(err)
+	})
+}
+
+// retentionCommand runs the retention backend commands
+func (f *Fs) retentionCommand(ctx context.Context, name string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "retention":
+		return f.objectLockCommand(ctx, name, false, func(ctx context.Context, o *Object) error {
+			return nil
+		})
+	case "legal-hold":
+		status := strings.ToUpper(opt["status"])
+		if status != s3.ObjectLockLegalHoldStatusOn && status != s3.ObjectLockLegalHoldStatusOff {
+			return nil, errors.Errorf("need -o status=%s or -o status=%s", s3.ObjectLockLegalHoldStatusOn, s3.ObjectLockLegalHoldStatusOff)
+		}
+		return f.objectLockCommand(ctx, name, true, func(ctx context.Context, o *Object) error {
+			return o.putLegalHold(ctx, status)
+		})
+	}
+	mode, err := parseLockMode(opt["mode"])
+	if err != nil {
+		return nil, err
+	}
+	if opt["retain-until"] == "" {
+		return nil, errors.New("need -o retain-until=DATE or DURATION")
+	}
+	retainUntil, err := parseRetainUntil(opt["retain-until"], time.Now())
+	if err != nil {
+		return nil, errors.Wrap(err, "bad retain-until")
+	}
+	bypassGovernance := false
+	if value, ok := opt["bypass-governance"]; ok && value != "" {
+		bypassGovernance, err = strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.Wrap(err, "bad bypass-governance")
+		}
+	} else if ok {
+		bypassGovernance = true
+	}
+	switch name {
+	case "set-retention":
+		if mode == "" {
+			return nil, errors.New("need -o mode=GOVERNANCE or -o mode=COMPLIANCE")
+		}
+		return f.objectLockCommand(ctx, name, true, func(ctx context.Context, o *Object) error {
+			return o.putRetention(ctx, mode, retainUntil, bypassGovernance)
+		})
+	case "extend-retention":
+		return f.objectLockCommand(ctx, name, true, func(ctx context.Context, o *Object) error {
+			newMode := o.lockMode
+			if mode != "" {
+				newMode = mode
+			}
+			if newMode == "" {
+				return errors.New("no retention set - use -o mode to set one")
+			}
+			if o.lockRetainUntil != nil && !retainUntil.After(*o.lockRetainUntil) {
+				fs.Debugf(o, "Already retained until %v", o.lockRetainUntil)
+				return nil
+			}
+			return o.putRetention(ctx, newMode, retainUntil, bypassGovernance)
+		})
+	}
+	return nil, fs.ErrorCommandNotFound
+}
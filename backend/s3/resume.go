@@ -0,0 +1,77 @@
+package s3
+
+import (
+	"context"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/resume"
+)
+
+// multipartState is the state of a multipart upload which is saved
+// so it can be resumed with --resume-uploads
+type multipartState struct {
+	UploadID string // ID of the multipart upload
+	PartSize int    // size of the parts
+}
+
+// resumeMultipart finds the parts of the multipart upload saved in up
+// which don't need uploading again.
+//
+// It returns a nil uploadID if there is no upload to resume. As
+// uploads which can't be resumed are started again from the
+// beginning errors are logged rather than returned.
+func (o *Object) resumeMultipart(ctx context.Context, up *resume.Upload, req *s3.PutObjectInput) (uploadID *string, partSize int, parts []*s3.CompletedPart) {
+	f := o.fs
+	var state multipartState
+	found, err := up.Load(&state)
+	if err != nil {
+		fs.Errorf(o, "Can't resume multipart upload: %v", err)
+		return nil, 0, nil
+	}
+	if !found || state.UploadID == "" || state.PartSize <= 0 {
+		return nil, 0, nil
+	}
+
+	// Find the parts the server has
+	var uploaded []*s3.Part
+	listReq := &s3.ListPartsInput{
+		Bucket:       req.Bucket,
+		Key:          req.Key,
+		UploadId:     &state.UploadID,
+		RequestPayer: req.RequestPayer,
+	}
+	for {
+		var resp *s3.ListPartsOutput
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err = f.c.ListPartsWithContext(ctx, listReq)
+			return f.shouldRetry(err)
+		})
+		if err != nil {
+			fs.Debugf(o, "Can't resume multipart upload %q: %v", state.UploadID, err)
+			up.Remove()
+			return nil, 0, nil
+		}
+		uploaded = append(uploaded, resp.Parts...)
+		if resp.IsTruncated == nil || !*resp.IsTruncated || resp.NextPartNumberMarker == nil {
+			break
+		}
+		listReq.PartNumberMarker = resp.NextPartNumberMarker
+	}
+
+	// Use the complete parts from the start of the file
+	sort.Slice(uploaded, func(i, j int) bool {
+		return *uploaded[i].PartNumber < *uploaded[j].PartNumber
+	})
+	for i, part := range uploaded {
+		if part.PartNumber == nil || *part.PartNumber != int64(i+1) || part.Size == nil || *part.Size != int64(state.PartSize) {
+			break
+		}
+		parts = append(parts, &s3.CompletedPart{
+			PartNumber: part.PartNumber,
+			ETag:       part.ETag,
+		})
+	}
+	return &state.UploadID, state.PartSize, parts
+}
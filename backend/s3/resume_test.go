@@ -0,0 +1,179 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/lib/resume"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingReader returns an error after reading n bytes of in
+type failingReader struct {
+	in io.Reader
+	n  int
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		return 0, errors.New("read failed")
+	}
+	if len(p) > r.n {
+		p = p[:r.n]
+	}
+	n, err := r.in.Read(p)
+	r.n -= n
+	return n, err
+}
+
+// multipartServer is a fake S3 server which only does the multipart
+// upload of a single object
+type multipartServer struct {
+	mu      sync.Mutex
+	parts   map[int][]byte // parts uploaded by part number
+	data    []byte         // the object once the upload is complete
+	created int            // number of multipart uploads created
+}
+
+const testUploadID = "upload-id"
+
+func (s *multipartServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := r.URL.Query()
+	_, uploads := q["uploads"]
+	switch {
+	case r.Method == "POST" && uploads:
+		s.created++
+		s.parts = map[int][]byte{}
+		_, _ = fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", testUploadID)
+	case r.Method == "PUT" && q.Get("uploadId") == testUploadID:
+		partNumber, _ := strconv.Atoi(q.Get("partNumber"))
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.parts[partNumber] = data
+		sum := md5.Sum(data)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	case r.Method == "GET" && q.Get("uploadId") == testUploadID:
+		_, _ = fmt.Fprintf(w, "<ListPartsResult><IsTruncated>false</IsTruncated>")
+		for _, n := range s.partNumbers() {
+			_, _ = fmt.Fprintf(w, `<Part><PartNumber>%d</PartNumber><ETag>"%d"</ETag><Size>%d</Size></Part>`, n, n, len(s.parts[n]))
+		}
+		_, _ = fmt.Fprintf(w, "</ListPartsResult>")
+	case r.Method == "POST" && q.Get("uploadId") == testUploadID:
+		s.data = nil
+		for _, n := range s.partNumbers() {
+			s.data = append(s.data, s.parts[n]...)
+		}
+		s.parts = nil
+		_, _ = fmt.Fprintf(w, `<CompleteMultipartUploadResult><ETag>"etag-%d"</ETag></CompleteMultipartUploadResult>`, len(s.partNumbers()))
+	case r.Method == "HEAD" && s.data != nil:
+		w.Header().Set("Content-Length", strconv.Itoa(len(s.data)))
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("ETag", `"etag"`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// partNumbers returns the numbers of the parts uploaded in order -
+// call with the mutex held
+func (s *multipartServer) partNumbers() (ns []int) {
+	for n := range s.parts {
+		ns = append(ns, n)
+	}
+	sort.Ints(ns)
+	return ns
+}
+
+// Test a multipart upload is resumed with --resume-uploads
+func TestResumeMultipartUpload(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "rclone-s3-resume-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	oldDir := resume.Dir
+	resume.Dir = dir
+	defer func() {
+		resume.Dir = oldDir
+	}()
+
+	server := &multipartServer{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	// Make a remote for the server which uploads one part at a time
+	f, err := NewFs(ctx, "s3resume", "bucket", configmap.Simple{
+		"provider":           "Other",
+		"endpoint":           ts.URL,
+		"access_key_id":      "key",
+		"secret_access_key":  "secret",
+		"chunk_size":         "5M",
+		"upload_cutoff":      "5M",
+		"upload_concurrency": "1",
+		"max_upload_parts":   "10000",
+		"no_check_bucket":    "true",
+		"force_path_style":   "true",
+	})
+	require.NoError(t, err)
+
+	const partSize = 5 * 1024 * 1024
+	data := bytes.Repeat([]byte("0123456789abcdef"), (2*partSize+1000)/16)
+	size := int64(len(data))
+	src := object.NewStaticObjectInfo("file", time.Now(), size, true, nil, nil)
+	uploadCtx := resume.WithUpload(ctx, f, "file", "fingerprint")
+
+	// Fail part way through the second part
+	_, err = f.Put(uploadCtx, &failingReader{in: bytes.NewReader(data), n: partSize + 1000}, src)
+	require.Error(t, err)
+	states, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(states))
+
+	// The first part may still be uploading
+	for i := 0; i < 100; i++ {
+		server.mu.Lock()
+		n := len(server.parts)
+		server.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Now upload again with the first part corrupted - this should
+	// be skipped as that part has been uploaded already
+	corrupted := append([]byte(nil), data...)
+	copy(corrupted, bytes.Repeat([]byte("X"), partSize))
+	_, err = f.Put(uploadCtx, bytes.NewReader(corrupted), src)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, server.created)
+	assert.True(t, bytes.Equal(data, server.data), "uploaded data differs")
+	states, err = filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(states))
+}
@@ -51,6 +51,7 @@ import (
 	"github.com/rclone/rclone/lib/pool"
 	"github.com/rclone/rclone/lib/readers"
 	"github.com/rclone/rclone/lib/rest"
+	"github.com/rclone/rclone/lib/resume"
 	"github.com/rclone/rclone/lib/structs"
 	"golang.org/x/sync/errgroup"
 )
@@ -61,6 +62,10 @@ func init() {
 		Name:        "s3",
 		Description: "Amazon S3 Compliant Storage Providers including AWS, Alibaba, Ceph, Digital Ocean, Dreamhost, IBM COS, Minio, and Tencent COS",
 		NewFs:       NewFs,
+		MetadataInfo: &fs.MetadataInfo{
+			System: systemMetadataInfo,
+			Help:   `User metadata is stored as x-amz-meta- keys. S3 metadata keys are case insensitive and are always returned in lower case.`,
+		},
 		CommandHelp: commandHelp,
 		Options: []fs.Option{{
 			Name: fs.ConfigProvider,
@@ -89,6 +94,9 @@ func init() {
 			}, {
 				Value: "Netease",
 				Help:  "Netease Object Storage (NOS)",
+			}, {
+				Value: "Rclone",
+				Help:  "Rclone S3 Server (rclone serve s3)",
 			}, {
 				Value: "Scaleway",
 				Help:  "Scaleway Object Storage",
@@ -1169,6 +1177,24 @@ In Ceph, this can be increased with the "rgw list buckets max chunk" option.
 `,
 			Default:  1000,
 			Advanced: true,
+		}, {
+			Name: "version_at",
+			Help: `Show file versions as they were at the specified time.
+
+The parameter should be a date, "2006-01-02", datetime "2006-01-02
+15:04:05" or a duration for that long ago, eg "100d" or "1h".
+
+Each object is shown as the newest version which is not later than
+this time and objects which were deleted by then are hidden. The
+bucket must have versioning enabled.
+
+Note that when using this no file write operations are permitted,
+so you can't upload files or delete them.
+
+See [the options docs](/docs/#options) for valid formats.
+`,
+			Default:  fs.Time{},
+			Advanced: true,
 		}, {
 			Name: "no_check_bucket",
 			Help: `If set, don't attempt to check the bucket exists or create it
@@ -1207,6 +1233,39 @@ This option controls how often unused buffers will be removed from the pool.`,
 			Default:  memoryPoolUseMmap,
 			Advanced: true,
 			Help:     `Whether to use mmap buffers in internal memory pool.`,
+		}, {
+			Name: "object_lock_mode",
+			Help: `The Object Lock mode to set on uploaded and copied objects.
+
+The bucket must have Object Lock enabled. This needs
+object_lock_retain_until to be set too.`,
+			Examples: []fs.OptionExample{{
+				Value: "",
+				Help:  "None",
+			}, {
+				Value: "GOVERNANCE",
+				Help:  "Governance mode - users with special permissions can remove the lock",
+			}, {
+				Value: "COMPLIANCE",
+				Help:  "Compliance mode - no-one can remove the lock until it expires",
+			}},
+			Advanced: true,
+		}, {
+			Name: "object_lock_retain_until",
+			Help: `How long to lock uploaded and copied objects for.
+
+This is either a date, as 2006-01-02 or in RFC 3339 format, or a
+duration from the time of upload such as 30d or 1y.`,
+			Advanced: true,
+		}, {
+			Name: "object_lock_legal_hold",
+			Help: `If set, put a legal hold on uploaded and copied objects.
+
+A legal hold stops the object being deleted or overwritten until it
+is removed, independently of the retention period. The bucket must
+have Object Lock enabled.`,
+			Default:  false,
+			Advanced: true,
 		}, {
 			Name:     "disable_http2",
 			Default:  false,
@@ -1228,6 +1287,10 @@ See: https://github.com/rclone/rclone/issues/4673, https://github.com/rclone/rcl
 const (
 	metaMtime   = "Mtime"     // the meta key to store mtime in - e.g. X-Amz-Meta-Mtime
 	metaMD5Hash = "Md5chksum" // the meta key to store md5hash in
+	// Headers used for the additional checksums AWS can return
+	checksumModeHeader   = "X-Amz-Checksum-Mode"
+	checksumSHA256Header = "X-Amz-Checksum-Sha256"
+	checksumCRC32CHeader = "X-Amz-Checksum-Crc32c"
 	// The maximum size of object we can COPY - this should be 5GiB but is < 5GB for b2 compatibility
 	// See https://forum.rclone.org/t/copying-files-within-a-b2-bucket/16680/76
 	maxSizeForCopy      = 4768 * 1024 * 1024
@@ -1242,6 +1305,10 @@ const (
 	maxExpireDuration   = fs.Duration(7 * 24 * time.Hour) // max expiry is 1 week
 )
 
+// errNotWithVersionAt is returned by operations which modify the
+// remote in --s3-version-at mode
+var errNotWithVersionAt = errors.New("can't modify or delete files in --s3-version-at mode")
+
 // Options defines the configuration for this backend
 type Options struct {
 	Provider              string               `config:"provider"`
@@ -1273,11 +1340,15 @@ type Options struct {
 	UseAccelerateEndpoint bool                 `config:"use_accelerate_endpoint"`
 	LeavePartsOnError     bool                 `config:"leave_parts_on_error"`
 	ListChunk             int64                `config:"list_chunk"`
+	VersionAt             fs.Time              `config:"version_at"`
 	NoCheckBucket         bool                 `config:"no_check_bucket"`
 	Enc                   encoder.MultiEncoder `config:"encoding"`
 	MemoryPoolFlushTime   fs.Duration          `config:"memory_pool_flush_time"`
 	MemoryPoolUseMmap     bool                 `config:"memory_pool_use_mmap"`
 	DisableHTTP2          bool                 `config:"disable_http2"`
+	ObjectLockMode        string               `config:"object_lock_mode"`
+	ObjectLockRetainUntil string               `config:"object_lock_retain_until"`
+	ObjectLockLegalHold   bool                 `config:"object_lock_legal_hold"`
 }
 
 // Fs represents a remote s3 server
@@ -1313,6 +1384,21 @@ type Object struct {
 	meta         map[string]*string // The object metadata if known - may be nil
 	mimeType     string             // MimeType of object - may be ""
 	storageClass string             // e.g. GLACIER
+	sha256       string             // SHA-256 of the object if returned by the provider
+	crc32c       string             // CRC-32C of the object if returned by the provider
+
+	// Object Lock settings - only read by readMetaData
+	lockMode        string     // GOVERNANCE or COMPLIANCE if retention is set
+	lockRetainUntil *time.Time // date the retention expires
+	legalHold       string     // ON or OFF if known
+
+	// Metadata as pointers to strings as they often won't be present
+	cacheControl       *string // Cache-Control: header
+	contentDisposition *string // Content-Disposition: header
+	contentEncoding    *string // Content-Encoding: header
+	contentLanguage    *string // Content-Language: header
+
+	versionID *string // version of the object to read if set
 }
 
 // ------------------------------------------------------------
@@ -1492,6 +1578,9 @@ func s3Connection(ctx context.Context, opt *Options) (*s3.S3, *session.Session,
 	if opt.Provider == "Scaleway" && opt.MaxUploadParts > 1000 {
 		opt.MaxUploadParts = 1000
 	}
+	if opt.Provider == "Rclone" {
+		opt.ForcePathStyle = true
+	}
 	awsConfig := aws.NewConfig().
 		WithMaxRetries(0). // Rely on rclone's retry logic
 		WithCredentials(cred).
@@ -1602,6 +1691,10 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 		md5sumBinary := md5.Sum([]byte(opt.SSECustomerKey))
 		opt.SSECustomerKeyMD5 = base64.StdEncoding.EncodeToString(md5sumBinary[:])
 	}
+	err = checkObjectLock(opt)
+	if err != nil {
+		return nil, errors.Wrap(err, "s3: object lock")
+	}
 	c, ses, err := s3Connection(ctx, opt)
 	if err != nil {
 		return nil, err
@@ -1644,7 +1737,19 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 		SetTier:           true,
 		GetTier:           true,
 		SlowModTime:       true,
+		ReadMetadata:      true,
+		WriteMetadata:     true,
+		UserMetadata:      true,
 	}).Fill(ctx, f)
+	if opt.Provider == "Rclone" {
+		// rclone serve s3 stores the data and modification time
+		// only, so can't keep mime types, tiers or other metadata
+		f.features.WriteMimeType = false
+		f.features.SetTier = false
+		f.features.GetTier = false
+		f.features.WriteMetadata = false
+		f.features.UserMetadata = false
+	}
 	if f.rootBucket != "" && f.rootDirectory != "" {
 		// Check to see if the (bucket,directory) is actually an existing file
 		oldRoot := f.root
@@ -1669,10 +1774,18 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 // Return an Object from a path
 //
 //If it can't be found it returns the error ErrorObjectNotFound.
-func (f *Fs) newObjectWithInfo(ctx context.Context, remote string, info *s3.Object) (fs.Object, error) {
+func (f *Fs) newObjectWithInfo(ctx context.Context, remote string, info *s3.Object, versionID *string) (fs.Object, error) {
 	o := &Object{
-		fs:     f,
-		remote: remote,
+		fs:        f,
+		remote:    remote,
+		versionID: versionID,
+	}
+	if info == nil && f.opt.VersionAt.IsSet() {
+		var err error
+		info, o.versionID, err = f.findVersionAt(ctx, remote)
+		if err != nil {
+			return nil, err
+		}
 	}
 	if info != nil {
 		// Set info but not meta
@@ -1697,7 +1810,48 @@ func (f *Fs) newObjectWithInfo(ctx context.Context, remote string, info *s3.Obje
 // NewObject finds the Object at remote.  If it can't be found
 // it returns the error fs.ErrorObjectNotFound.
 func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
-	return f.newObjectWithInfo(ctx, remote, nil)
+	return f.newObjectWithInfo(ctx, remote, nil, nil)
+}
+
+// findVersionAt finds the version of the object at remote at the
+// --s3-version-at time
+func (f *Fs) findVersionAt(ctx context.Context, remote string) (info *s3.Object, versionID *string, err error) {
+	bucket, bucketPath := f.split(remote)
+	if bucket == "" || bucketPath == "" {
+		return nil, nil, fs.ErrorObjectNotFound
+	}
+	req := s3.ListObjectsInput{
+		Bucket:  &bucket,
+		Prefix:  &bucketPath,
+		MaxKeys: &f.opt.ListChunk,
+	}
+	var versionIDMarker *string
+	versionAt := &versionAtFilter{at: time.Time(f.opt.VersionAt)}
+	noDecode := func(key string) string { return key }
+	for {
+		var resp *s3.ListObjectsOutput
+		var versionIDs []*string
+		err = f.pacer.Call(func() (bool, error) {
+			resp, versionIDs, err = f.listObjectVersionsAt(ctx, &req, &versionIDMarker, versionAt, noDecode)
+			return f.shouldRetry(err)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		// The versions of bucketPath are listed before any other
+		// keys with it as a prefix
+		for i, object := range resp.Contents {
+			if aws.StringValue(object.Key) == bucketPath {
+				return object, versionIDs[i], nil
+			}
+		}
+		// Carry on only if there may be more versions of bucketPath
+		if !aws.BoolValue(resp.IsTruncated) || aws.StringValue(resp.NextMarker) != bucketPath {
+			break
+		}
+		req.Marker = resp.NextMarker
+	}
+	return nil, nil, fs.ErrorObjectNotFound
 }
 
 // Gets the bucket location
@@ -1746,7 +1900,113 @@ func (f *Fs) updateRegionForBucket(bucket string) error {
 }
 
 // listFn is called from list to handle an object.
-type listFn func(remote string, object *s3.Object, isDirectory bool) error
+//
+// versionID is only set in --s3-version-at mode.
+type listFn func(remote string, object *s3.Object, versionID *string, isDirectory bool) error
+
+// versionAtFilter chooses the version of each key to show in
+// --s3-version-at mode from a listing of the object versions.
+//
+// It remembers the last key chosen so the versions of a key can be
+// split over more than one page of the listing.
+type versionAtFilter struct {
+	at      time.Time // show the versions as they were at this time
+	lastKey *string   // the last key a version was chosen for
+}
+
+// objectVersion is an object version or delete marker
+type objectVersion struct {
+	key          string     // the decoded key
+	object       *s3.Object // the object - nil for a delete marker
+	versionID    *string
+	lastModified time.Time
+}
+
+// filter returns the objects which existed at v.at from a page of
+// object versions, with the version IDs to read them with.
+//
+// S3 lists versions in key order and newest first for each key, but
+// in separate lists for objects and delete markers. decode is used to
+// get the real key from the listed key for sorting.
+func (v *versionAtFilter) filter(resp *s3.ListObjectVersionsOutput, decode func(string) string) (objects []*s3.Object, versionIDs []*string) {
+	versions := make([]objectVersion, 0, len(resp.Versions)+len(resp.DeleteMarkers))
+	for _, version := range resp.Versions {
+		versions = append(versions, objectVersion{
+			key: decode(aws.StringValue(version.Key)),
+			object: &s3.Object{
+				Key:          version.Key,
+				ETag:         version.ETag,
+				LastModified: version.LastModified,
+				Owner:        version.Owner,
+				Size:         version.Size,
+				StorageClass: version.StorageClass,
+			},
+			versionID:    version.VersionId,
+			lastModified: aws.TimeValue(version.LastModified),
+		})
+	}
+	for _, marker := range resp.DeleteMarkers {
+		versions = append(versions, objectVersion{
+			key:          decode(aws.StringValue(marker.Key)),
+			versionID:    marker.VersionId,
+			lastModified: aws.TimeValue(marker.LastModified),
+		})
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		if versions[i].key != versions[j].key {
+			return versions[i].key < versions[j].key
+		}
+		return versions[i].lastModified.After(versions[j].lastModified)
+	})
+	for _, version := range versions {
+		if v.lastKey != nil && *v.lastKey == version.key {
+			continue // already chosen a version for this key
+		}
+		if version.lastModified.After(v.at) {
+			continue // too new
+		}
+		key := version.key
+		v.lastKey = &key
+		if version.object == nil {
+			continue // deleted at v.at
+		}
+		objects = append(objects, version.object)
+		versionIDs = append(versionIDs, version.versionID)
+	}
+	return objects, versionIDs
+}
+
+// listObjectVersionsAt lists a page of the objects as they were at
+// the --s3-version-at time.
+//
+// It takes the same request and returns the same response as
+// ListObjects along with the version IDs of the objects returned.
+// versionIDMarker is used to continue the listing from the previous
+// page.
+func (f *Fs) listObjectVersionsAt(ctx context.Context, req *s3.ListObjectsInput, versionIDMarker **string, versionAt *versionAtFilter, decode func(string) string) (*s3.ListObjectsOutput, []*string, error) {
+	vReq := s3.ListObjectVersionsInput{
+		Bucket:          req.Bucket,
+		Delimiter:       req.Delimiter,
+		EncodingType:    req.EncodingType,
+		KeyMarker:       req.Marker,
+		MaxKeys:         req.MaxKeys,
+		Prefix:          req.Prefix,
+		VersionIdMarker: *versionIDMarker,
+	}
+	vResp, err := f.c.ListObjectVersionsWithContext(ctx, &vReq)
+	if err != nil {
+		return nil, nil, err
+	}
+	objects, versionIDs := versionAt.filter(vResp, decode)
+	*versionIDMarker = vResp.NextVersionIdMarker
+	resp := &s3.ListObjectsOutput{
+		CommonPrefixes: vResp.CommonPrefixes,
+		Contents:       objects,
+		IsTruncated:    vResp.IsTruncated,
+		NextMarker:     vResp.NextKeyMarker,
+	}
+	return resp, versionIDs, nil
+}
 
 // list lists the objects into the function supplied from
 // the bucket and directory supplied.  The remote has prefix
@@ -1765,7 +2025,14 @@ func (f *Fs) list(ctx context.Context, bucket, directory, prefix string, addBuck
 	if !recurse {
 		delimiter = "/"
 	}
-	var marker *string
+	var (
+		marker          *string
+		versionIDMarker *string
+		versionAt       *versionAtFilter
+	)
+	if f.opt.VersionAt.IsSet() {
+		versionAt = &versionAtFilter{at: time.Time(f.opt.VersionAt)}
+	}
 	// URL encode the listings so we can use control characters in object names
 	// See: https://github.com/aws/aws-sdk-go/issues/1914
 	//
@@ -1781,7 +2048,7 @@ func (f *Fs) list(ctx context.Context, bucket, directory, prefix string, addBuck
 	//
 	// So we enable only on providers we know supports it properly, all others can retry when a
 	// XML Syntax error is detected.
-	var urlEncodeListings = (f.opt.Provider == "AWS" || f.opt.Provider == "Wasabi" || f.opt.Provider == "Alibaba" || f.opt.Provider == "Minio" || f.opt.Provider == "TencentCOS")
+	var urlEncodeListings = (f.opt.Provider == "AWS" || f.opt.Provider == "Wasabi" || f.opt.Provider == "Alibaba" || f.opt.Provider == "Minio" || f.opt.Provider == "TencentCOS" || f.opt.Provider == "Rclone")
 	for {
 		// FIXME need to implement ALL loop
 		req := s3.ListObjectsInput{
@@ -1795,9 +2062,21 @@ func (f *Fs) list(ctx context.Context, bucket, directory, prefix string, addBuck
 			req.EncodingType = aws.String(s3.EncodingTypeUrl)
 		}
 		var resp *s3.ListObjectsOutput
+		var versionIDs []*string
 		var err error
 		err = f.pacer.Call(func() (bool, error) {
-			resp, err = f.c.ListObjectsWithContext(ctx, &req)
+			if versionAt != nil {
+				resp, versionIDs, err = f.listObjectVersionsAt(ctx, &req, &versionIDMarker, versionAt, func(key string) string {
+					if urlEncodeListings {
+						if decoded, err := url.QueryUnescape(key); err == nil {
+							return decoded
+						}
+					}
+					return key
+				})
+			} else {
+				resp, err = f.c.ListObjectsWithContext(ctx, &req)
+			}
 			if err != nil && !urlEncodeListings {
 				if awsErr, ok := err.(awserr.RequestFailure); ok {
 					if origErr := awsErr.OrigErr(); origErr != nil {
@@ -1858,13 +2137,17 @@ func (f *Fs) list(ctx context.Context, bucket, directory, prefix string, addBuck
 				if strings.HasSuffix(remote, "/") {
 					remote = remote[:len(remote)-1]
 				}
-				err = fn(remote, &s3.Object{Key: &remote}, true)
+				err = fn(remote, &s3.Object{Key: &remote}, nil, true)
 				if err != nil {
 					return err
 				}
 			}
 		}
-		for _, object := range resp.Contents {
+		for i, object := range resp.Contents {
+			var versionID *string
+			if versionIDs != nil {
+				versionID = versionIDs[i]
+			}
 			remote := aws.StringValue(object.Key)
 			if urlEncodeListings {
 				remote, err = url.QueryUnescape(remote)
@@ -1887,7 +2170,7 @@ func (f *Fs) list(ctx context.Context, bucket, directory, prefix string, addBuck
 			if isDirectory && object.Size != nil && *object.Size == 0 {
 				continue // skip directory marker
 			}
-			err = fn(remote, object, false)
+			err = fn(remote, object, versionID, false)
 			if err != nil {
 				return err
 			}
@@ -1896,7 +2179,12 @@ func (f *Fs) list(ctx context.Context, bucket, directory, prefix string, addBuck
 			break
 		}
 		// Use NextMarker if set, otherwise use last Key
-		if resp.NextMarker == nil || *resp.NextMarker == "" {
+		if versionAt != nil {
+			if resp.NextMarker == nil || *resp.NextMarker == "" {
+				return errors.New("s3 protocol error: received versions listing with IsTruncated set and no NextKeyMarker")
+			}
+			marker = resp.NextMarker
+		} else if resp.NextMarker == nil || *resp.NextMarker == "" {
 			if len(resp.Contents) == 0 {
 				return errors.New("s3 protocol error: received listing with IsTruncated set, no NextMarker and no Contents")
 			}
@@ -1915,7 +2203,7 @@ func (f *Fs) list(ctx context.Context, bucket, directory, prefix string, addBuck
 }
 
 // Convert a list item into a DirEntry
-func (f *Fs) itemToDirEntry(ctx context.Context, remote string, object *s3.Object, isDirectory bool) (fs.DirEntry, error) {
+func (f *Fs) itemToDirEntry(ctx context.Context, remote string, object *s3.Object, versionID *string, isDirectory bool) (fs.DirEntry, error) {
 	if isDirectory {
 		size := int64(0)
 		if object.Size != nil {
@@ -1924,7 +2212,7 @@ func (f *Fs) itemToDirEntry(ctx context.Context, remote string, object *s3.Objec
 		d := fs.NewDir(remote, time.Time{}).SetSize(size)
 		return d, nil
 	}
-	o, err := f.newObjectWithInfo(ctx, remote, object)
+	o, err := f.newObjectWithInfo(ctx, remote, object, versionID)
 	if err != nil {
 		return nil, err
 	}
@@ -1934,8 +2222,8 @@ func (f *Fs) itemToDirEntry(ctx context.Context, remote string, object *s3.Objec
 // listDir lists files and directories to out
 func (f *Fs) listDir(ctx context.Context, bucket, directory, prefix string, addBucket bool) (entries fs.DirEntries, err error) {
 	// List the objects and directories
-	err = f.list(ctx, bucket, directory, prefix, addBucket, false, func(remote string, object *s3.Object, isDirectory bool) error {
-		entry, err := f.itemToDirEntry(ctx, remote, object, isDirectory)
+	err = f.list(ctx, bucket, directory, prefix, addBucket, false, func(remote string, object *s3.Object, versionID *string, isDirectory bool) error {
+		entry, err := f.itemToDirEntry(ctx, remote, object, versionID, isDirectory)
 		if err != nil {
 			return err
 		}
@@ -2012,8 +2300,8 @@ func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (
 	bucket, directory := f.split(dir)
 	list := walk.NewListRHelper(callback)
 	listR := func(bucket, directory, prefix string, addBucket bool) error {
-		return f.list(ctx, bucket, directory, prefix, addBucket, true, func(remote string, object *s3.Object, isDirectory bool) error {
-			entry, err := f.itemToDirEntry(ctx, remote, object, isDirectory)
+		return f.list(ctx, bucket, directory, prefix, addBucket, true, func(remote string, object *s3.Object, versionID *string, isDirectory bool) error {
+			entry, err := f.itemToDirEntry(ctx, remote, object, versionID, isDirectory)
 			if err != nil {
 				return err
 			}
@@ -2088,6 +2376,9 @@ func (f *Fs) bucketExists(ctx context.Context, bucket string) (bool, error) {
 
 // Mkdir creates the bucket if it doesn't exist
 func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	if f.opt.VersionAt.IsSet() {
+		return errNotWithVersionAt
+	}
 	bucket, _ := f.split(dir)
 	return f.makeBucket(ctx, bucket)
 }
@@ -2129,6 +2420,9 @@ func (f *Fs) makeBucket(ctx context.Context, bucket string) error {
 //
 // Returns an error if it isn't empty
 func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	if f.opt.VersionAt.IsSet() {
+		return errNotWithVersionAt
+	}
 	bucket, directory := f.split(dir)
 	if bucket == "" || directory != "" {
 		return nil
@@ -2168,6 +2462,9 @@ func (f *Fs) copy(ctx context.Context, req *s3.CopyObjectInput, dstBucket, dstPa
 	req.ACL = &f.opt.ACL
 	req.Key = &dstPath
 	source := pathEscape(path.Join(srcBucket, srcPath))
+	if src.versionID != nil {
+		source += fmt.Sprintf("?versionId=%s", *src.versionID)
+	}
 	req.CopySource = &source
 	if f.opt.ServerSideEncryption != "" {
 		req.ServerSideEncryption = &f.opt.ServerSideEncryption
@@ -2190,6 +2487,9 @@ func (f *Fs) copy(ctx context.Context, req *s3.CopyObjectInput, dstBucket, dstPa
 	if req.StorageClass == nil && f.opt.StorageClass != "" {
 		req.StorageClass = &f.opt.StorageClass
 	}
+	if f.objectLocking() {
+		req.ObjectLockMode, req.ObjectLockRetainUntilDate, req.ObjectLockLegalHoldStatus = f.objectLock()
+	}
 
 	if src.bytes >= int64(f.opt.CopyCutoff) {
 		return f.copyMultipart(ctx, req, dstBucket, dstPath, srcBucket, srcPath, src)
@@ -2315,6 +2615,9 @@ func (f *Fs) copyMultipart(ctx context.Context, copyReq *s3.CopyObjectInput, dst
 //
 // If it isn't possible then return fs.ErrorCantCopy
 func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	if f.opt.VersionAt.IsSet() {
+		return nil, errNotWithVersionAt
+	}
 	dstBucket, dstPath := f.split(remote)
 	err := f.makeBucket(ctx, dstBucket)
 	if err != nil {
@@ -2337,7 +2640,13 @@ func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object,
 }
 
 // Hashes returns the supported hash sets.
+//
+// SHA-256 and CRC-32C are only available for objects uploaded with
+// them by other tools, see Object.Hash.
 func (f *Fs) Hashes() hash.Set {
+	if f.opt.Provider == "AWS" {
+		return hash.NewHashSet(hash.MD5, hash.SHA256, hash.CRC32C)
+	}
 	return hash.Set(hash.MD5)
 }
 
@@ -2468,6 +2777,89 @@ Durations are parsed as per the rest of rclone, 2h, 7d, 7w etc.
 	Opts: map[string]string{
 		"max-age": "Max age of upload to delete",
 	},
+}, {
+	Name:  "retention",
+	Short: "Show the Object Lock retention and legal hold of objects",
+	Long: `This command shows the Object Lock settings of one or more objects.
+
+    rclone backend retention s3:bucket/path/to/object
+    rclone backend retention s3:bucket/path/to/directory
+
+It obeys the filters and returns a list of dictionaries. Mode,
+RetainUntilDate and LegalHold are only present if set on the object.
+The Status will be OK if the settings could be read or an error
+message if not.
+
+    [
+        {
+            "Remote": "test.txt",
+            "Status": "OK",
+            "Mode": "GOVERNANCE",
+            "RetainUntilDate": "2022-01-01T00:00:00Z",
+            "LegalHold": "OFF"
+        }
+    ]
+
+The same settings are shown by "rclone lsjson --metadata".
+`,
+}, {
+	Name:  "set-retention",
+	Short: "Set the Object Lock retention of objects",
+	Long: `This command sets the Object Lock retention of one or more objects.
+
+    rclone backend set-retention s3:bucket/path/to/object -o mode=GOVERNANCE -o retain-until=30d
+    rclone backend set-retention s3:bucket/path -o mode=COMPLIANCE -o retain-until=2030-01-01
+
+The retain-until is either a date or a duration from now. Retention
+in COMPLIANCE mode can't be shortened and retention in GOVERNANCE
+mode can only be shortened or removed with -o bypass-governance.
+
+This obeys the filters. Test first with -i/--interactive or --dry-run.
+
+It returns a list of the objects as for the retention command showing
+the new settings.
+`,
+	Opts: map[string]string{
+		"mode":              "Object Lock mode: GOVERNANCE|COMPLIANCE",
+		"retain-until":      "Date or duration from now to retain the objects until",
+		"bypass-governance": "Allow GOVERNANCE retention to be shortened",
+	},
+}, {
+	Name:  "extend-retention",
+	Short: "Extend the Object Lock retention of objects",
+	Long: `This command extends the Object Lock retention of one or more objects.
+
+    rclone backend extend-retention s3:bucket/path -o retain-until=1y
+
+Objects retained beyond retain-until already are left alone. The
+mode of each object is kept unless -o mode is given, which is needed
+for objects with no retention.
+
+This obeys the filters. Test first with -i/--interactive or --dry-run.
+
+It returns a list of the objects as for the retention command showing
+the new settings.
+`,
+	Opts: map[string]string{
+		"mode":         "Object Lock mode to use: GOVERNANCE|COMPLIANCE",
+		"retain-until": "Date or duration from now to retain the objects until",
+	},
+}, {
+	Name:  "legal-hold",
+	Short: "Set or remove the Object Lock legal hold on objects",
+	Long: `This command sets or removes the legal hold on one or more objects.
+
+    rclone backend legal-hold s3:bucket/path/to/object -o status=ON
+    rclone backend legal-hold s3:bucket/path -o status=OFF
+
+This obeys the filters. Test first with -i/--interactive or --dry-run.
+
+It returns a list of the objects as for the retention command showing
+the new settings.
+`,
+	Opts: map[string]string{
+		"status": "Legal hold status: ON|OFF",
+	},
 }}
 
 // Command the backend to run a named command
@@ -2544,6 +2936,8 @@ func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[str
 		return out, nil
 	case "list-multipart-uploads":
 		return f.listMultipartUploadsAll(ctx)
+	case "retention", "set-retention", "extend-retention", "legal-hold":
+		return f.retentionCommand(ctx, name, opt)
 	case "cleanup":
 		maxAge := 24 * time.Hour
 		if opt["max-age"] != "" {
@@ -2715,7 +3109,26 @@ func (o *Object) setMD5FromEtag(etag string) {
 }
 
 // Hash returns the Md5sum of an object returning a lowercase hex string
+//
+// On AWS the SHA-256 and CRC-32C are returned too if the object was
+// uploaded with them. rclone doesn't upload with them so these are
+// slow, optional hashes - each needs a HEAD request and is usually
+// empty.
 func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
+	if t == hash.SHA256 || t == hash.CRC32C {
+		if !o.fs.Hashes().Contains(t) {
+			return "", hash.ErrUnsupported
+		}
+		// These are only returned by HEAD
+		err := o.readMetaData(ctx)
+		if err != nil {
+			return "", err
+		}
+		if t == hash.SHA256 {
+			return o.sha256, nil
+		}
+		return o.crc32c, nil
+	}
 	if t != hash.MD5 {
 		return "", hash.ErrUnsupported
 	}
@@ -2737,8 +3150,9 @@ func (o *Object) Size() int64 {
 func (o *Object) headObject(ctx context.Context) (resp *s3.HeadObjectOutput, err error) {
 	bucket, bucketPath := o.split()
 	req := s3.HeadObjectInput{
-		Bucket: &bucket,
-		Key:    &bucketPath,
+		Bucket:    &bucket,
+		Key:       &bucketPath,
+		VersionId: o.versionID,
 	}
 	if o.fs.opt.SSECustomerAlgorithm != "" {
 		req.SSECustomerAlgorithm = &o.fs.opt.SSECustomerAlgorithm
@@ -2751,7 +3165,17 @@ func (o *Object) headObject(ctx context.Context) (resp *s3.HeadObjectOutput, err
 	}
 	err = o.fs.pacer.Call(func() (bool, error) {
 		var err error
-		resp, err = o.fs.c.HeadObjectWithContext(ctx, &req)
+		r, out := o.fs.c.HeadObjectRequest(&req)
+		r.SetContext(ctx)
+		if o.fs.opt.Provider == "AWS" {
+			// Ask for the additional checksums to be returned
+			r.HTTPRequest.Header.Set(checksumModeHeader, "ENABLED")
+		}
+		err = r.Send()
+		if err == nil {
+			resp = out
+			o.setChecksumsFromHeaders(r.HTTPResponse.Header)
+		}
 		return o.fs.shouldRetry(err)
 	})
 	if err != nil {
@@ -2766,6 +3190,29 @@ func (o *Object) headObject(ctx context.Context) (resp *s3.HeadObjectOutput, err
 	return resp, nil
 }
 
+// setChecksumsFromHeaders reads the additional checksums from the
+// headers of a HEAD or GET response
+//
+// Only checksums of the whole object are used - checksums of
+// multipart uploads are checksums of the part checksums and are
+// ignored.
+func (o *Object) setChecksumsFromHeaders(header http.Header) {
+	decode := func(name string) string {
+		value := header.Get(name)
+		if value == "" || strings.Contains(value, "-") {
+			return ""
+		}
+		data, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			fs.Debugf(o, "Failed to decode %s %q: %v", name, value, err)
+			return ""
+		}
+		return hex.EncodeToString(data)
+	}
+	o.sha256 = decode(checksumSHA256Header)
+	o.crc32c = decode(checksumCRC32CHeader)
+}
+
 // readMetaData gets the metadata if it hasn't already been fetched
 //
 // it also sets the info
@@ -2808,6 +3255,17 @@ func (o *Object) readMetaData(ctx context.Context) (err error) {
 		o.lastModified = *resp.LastModified
 	}
 	o.mimeType = aws.StringValue(resp.ContentType)
+
+	// Set system metadata
+	o.cacheControl = resp.CacheControl
+	o.contentDisposition = resp.ContentDisposition
+	o.contentEncoding = resp.ContentEncoding
+	o.contentLanguage = resp.ContentLanguage
+
+	// Set Object Lock settings
+	o.lockMode = aws.StringValue(resp.ObjectLockMode)
+	o.lockRetainUntil = resp.ObjectLockRetainUntilDate
+	o.legalHold = aws.StringValue(resp.ObjectLockLegalHoldStatus)
 	return nil
 }
 
@@ -2840,6 +3298,9 @@ func (o *Object) ModTime(ctx context.Context) time.Time {
 
 // SetModTime sets the modification time of the local fs object
 func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	if o.fs.opt.VersionAt.IsSet() {
+		return errNotWithVersionAt
+	}
 	err := o.readMetaData(ctx)
 	if err != nil {
 		return err
@@ -2870,8 +3331,9 @@ func (o *Object) Storable() bool {
 func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
 	bucket, bucketPath := o.split()
 	req := s3.GetObjectInput{
-		Bucket: &bucket,
-		Key:    &bucketPath,
+		Bucket:    &bucket,
+		Key:       &bucketPath,
+		VersionId: o.versionID,
 	}
 	if o.fs.opt.SSECustomerAlgorithm != "" {
 		req.SSECustomerAlgorithm = &o.fs.opt.SSECustomerAlgorithm
@@ -2953,25 +3415,55 @@ func (o *Object) uploadMultipart(ctx context.Context, req *s3.PutObjectInput, si
 		}
 	}
 
+	// Only uploads of known size can be resumed
+	up := resume.Get(ctx)
+	if size < 0 {
+		up = nil
+	}
+	var (
+		uid   *string
+		parts []*s3.CompletedPart
+	)
+	if up != nil {
+		var resumePartSize int
+		uid, resumePartSize, parts = o.resumeMultipart(ctx, up, req)
+		if uid != nil {
+			partSize = resumePartSize
+		}
+	}
+
 	memPool := f.getMemoryPool(int64(partSize))
 
-	var mReq s3.CreateMultipartUploadInput
-	structs.SetFrom(&mReq, req)
-	var cout *s3.CreateMultipartUploadOutput
-	err = f.pacer.Call(func() (bool, error) {
-		var err error
-		cout, err = f.c.CreateMultipartUploadWithContext(ctx, &mReq)
-		return f.shouldRetry(err)
-	})
-	if err != nil {
-		return errors.Wrap(err, "multipart upload failed to initialise")
+	if uid == nil {
+		var mReq s3.CreateMultipartUploadInput
+		structs.SetFrom(&mReq, req)
+		var cout *s3.CreateMultipartUploadOutput
+		err = f.pacer.Call(func() (bool, error) {
+			var err error
+			cout, err = f.c.CreateMultipartUploadWithContext(ctx, &mReq)
+			return f.shouldRetry(err)
+		})
+		if err != nil {
+			return errors.Wrap(err, "multipart upload failed to initialise")
+		}
+		uid = cout.UploadId
+		if up != nil {
+			err = up.Save(&multipartState{UploadID: *uid, PartSize: partSize})
+			if err != nil {
+				fs.Errorf(o, "Upload won't be resumable: %v", err)
+				up = nil
+			}
+		}
 	}
-	uid := cout.UploadId
 
 	defer atexit.OnError(&err, func() {
 		if o.fs.opt.LeavePartsOnError {
 			return
 		}
+		if up != nil {
+			fs.Debugf(o, "Leaving multipart upload to be resumed")
+			return
+		}
 		fs.Debugf(o, "Cancelling multipart upload")
 		errCancel := f.pacer.Call(func() (bool, error) {
 			_, err := f.c.AbortMultipartUploadWithContext(context.Background(), &s3.AbortMultipartUploadInput{
@@ -2991,11 +3483,20 @@ func (o *Object) uploadMultipart(ctx context.Context, req *s3.PutObjectInput, si
 		g, gCtx  = errgroup.WithContext(ctx)
 		finished = false
 		partsMu  sync.Mutex // to protect parts
-		parts    []*s3.CompletedPart
 		off      int64
 	)
 
-	for partNum := int64(1); !finished; partNum++ {
+	// Skip the parts uploaded already
+	if len(parts) > 0 {
+		off = int64(len(parts)) * int64(partSize)
+		fs.Infof(o, "Resuming multipart upload at %v after %d parts", fs.SizeSuffix(off), len(parts))
+		err = resume.Skip(in, off)
+		if err != nil {
+			return err
+		}
+	}
+
+	for partNum := int64(len(parts)) + 1; !finished; partNum++ {
 		// Get a block of memory from the pool and token which limits concurrency.
 		tokens.Get()
 		buf := memPool.Get()
@@ -3101,11 +3602,17 @@ func (o *Object) uploadMultipart(ctx context.Context, req *s3.PutObjectInput, si
 	if err != nil {
 		return errors.Wrap(err, "multipart upload failed to finalise")
 	}
+	if up != nil {
+		up.Remove()
+	}
 	return nil
 }
 
 // Update the Object from in with modTime and size
 func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	if o.fs.opt.VersionAt.IsSet() {
+		return errNotWithVersionAt
+	}
 	bucket, bucketPath := o.split()
 	err := o.fs.makeBucket(ctx, bucket)
 	if err != nil {
@@ -3175,6 +3682,21 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 	if o.fs.opt.StorageClass != "" {
 		req.StorageClass = &o.fs.opt.StorageClass
 	}
+	if o.fs.objectLocking() {
+		req.ObjectLockMode, req.ObjectLockRetainUntilDate, req.ObjectLockLegalHoldStatus = o.fs.objectLock()
+		// Object Lock needs a Content-MD5 which multipart uploads
+		// calculate for each part if it isn't known
+		if md5sum == "" {
+			multipart = true
+		}
+	}
+	// Fetch metadata if --metadata is in use
+	meta, err := fs.GetMetadataOptions(ctx, src, options)
+	if err != nil {
+		return errors.Wrap(err, "failed to read metadata from source object")
+	}
+	o.applyMetadata(&req, meta)
+
 	// Apply upload options
 	for _, option := range options {
 		key, value := option.Header()
@@ -3272,6 +3794,9 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 
 // Remove an object
 func (o *Object) Remove(ctx context.Context) error {
+	if o.fs.opt.VersionAt.IsSet() {
+		return errNotWithVersionAt
+	}
 	bucket, bucketPath := o.split()
 	req := s3.DeleteObjectInput{
 		Bucket: &bucket,
@@ -3296,6 +3821,9 @@ func (o *Object) MimeType(ctx context.Context) string {
 
 // SetTier performs changing storage class
 func (o *Object) SetTier(tier string) (err error) {
+	if o.fs.opt.VersionAt.IsSet() {
+		return errNotWithVersionAt
+	}
 	ctx := context.TODO()
 	tier = strings.ToUpper(tier)
 	bucket, bucketPath := o.split()
@@ -3331,4 +3859,5 @@ var (
 	_ fs.MimeTyper   = &Object{}
 	_ fs.GetTierer   = &Object{}
 	_ fs.SetTierer   = &Object{}
+	_ fs.Metadataer  = &Object{}
 )
@@ -0,0 +1,142 @@
+package s3
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLockMode(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"governance", "GOVERNANCE", false},
+		{" COMPLIANCE ", "COMPLIANCE", false},
+		{"potato", "", true},
+	} {
+		got, err := parseLockMode(test.in)
+		assert.Equal(t, test.wantErr, err != nil, test.in)
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
+
+func TestParseRetainUntil(t *testing.T) {
+	now := time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+	for _, test := range []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"2030-01-02", time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC), false},
+		{"2030-01-02T03:04:05Z", time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC), false},
+		{"1h", now.Add(time.Hour), false},
+		{"30d", now.Add(30 * 24 * time.Hour), false},
+		{"-1h", time.Time{}, true},
+		{"potato", time.Time{}, true},
+	} {
+		got, err := parseRetainUntil(test.in, now)
+		assert.Equal(t, test.wantErr, err != nil, test.in)
+		assert.True(t, test.want.Equal(got), "%s: want %v got %v", test.in, test.want, got)
+	}
+}
+
+func TestCheckObjectLock(t *testing.T) {
+	opt := Options{}
+	require.NoError(t, checkObjectLock(&opt))
+
+	opt = Options{ObjectLockMode: "governance", ObjectLockRetainUntil: "1d"}
+	require.NoError(t, checkObjectLock(&opt))
+	assert.Equal(t, "GOVERNANCE", opt.ObjectLockMode)
+
+	for _, opt := range []Options{
+		{ObjectLockMode: "GOVERNANCE"},
+		{ObjectLockRetainUntil: "1d"},
+		{ObjectLockMode: "potato", ObjectLockRetainUntil: "1d"},
+		{ObjectLockMode: "COMPLIANCE", ObjectLockRetainUntil: "potato"},
+	} {
+		opt := opt
+		assert.Error(t, checkObjectLock(&opt), opt)
+	}
+}
+
+func TestObjectLock(t *testing.T) {
+	f := &Fs{}
+	mode, retainUntil, legalHold := f.objectLock()
+	assert.False(t, f.objectLocking())
+	assert.Nil(t, mode)
+	assert.Nil(t, retainUntil)
+	assert.Nil(t, legalHold)
+
+	f.opt = Options{ObjectLockMode: "COMPLIANCE", ObjectLockRetainUntil: "2030-01-02", ObjectLockLegalHold: true}
+	assert.True(t, f.objectLocking())
+	mode, retainUntil, legalHold = f.objectLock()
+	assert.Equal(t, "COMPLIANCE", *mode)
+	assert.Equal(t, time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC), *retainUntil)
+	assert.Equal(t, "ON", *legalHold)
+}
+
+func TestVersionAtFilter(t *testing.T) {
+	t0 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(hours int) *time.Time {
+		t := t0.Add(time.Duration(hours) * time.Hour)
+		return &t
+	}
+	version := func(key, id string, hours int) *s3.ObjectVersion {
+		return &s3.ObjectVersion{Key: aws.String(key), VersionId: aws.String(id), LastModified: at(hours), Size: aws.Int64(int64(hours))}
+	}
+	marker := func(key, id string, hours int) *s3.DeleteMarkerEntry {
+		return &s3.DeleteMarkerEntry{Key: aws.String(key), VersionId: aws.String(id), LastModified: at(hours)}
+	}
+	noDecode := func(key string) string { return key }
+	check := func(objects []*s3.Object, versionIDs []*string, want ...string) {
+		var got []string
+		for i, object := range objects {
+			got = append(got, aws.StringValue(object.Key)+"@"+aws.StringValue(versionIDs[i]))
+		}
+		assert.Equal(t, want, got)
+	}
+
+	v := &versionAtFilter{at: *at(10)}
+	objects, versionIDs := v.filter(&s3.ListObjectVersionsOutput{
+		Versions: []*s3.ObjectVersion{
+			version("a", "a3", 12), // too new
+			version("a", "a2", 9),  // chosen
+			version("a", "a1", 1),
+			version("b", "b2", 5),  // deleted by b3
+			version("c", "c1", 11), // created after
+			version("d", "d2", 8),  // recreated after delete
+			version("d", "d1", 2),
+			version("e", "e2", 10), // exactly at the time
+		},
+		DeleteMarkers: []*s3.DeleteMarkerEntry{
+			marker("b", "b3", 6),
+			marker("d", "d1x", 4),
+			marker("e", "e3", 11), // deleted after
+		},
+	}, noDecode)
+	check(objects, versionIDs, "a@a2", "d@d2", "e@e2")
+
+	// versions of a key split over two pages
+	v = &versionAtFilter{at: *at(10)}
+	objects, versionIDs = v.filter(&s3.ListObjectVersionsOutput{
+		Versions: []*s3.ObjectVersion{
+			version("a", "a1", 1),
+			version("f", "f3", 9),
+		},
+	}, noDecode)
+	check(objects, versionIDs, "a@a1", "f@f3")
+	objects, versionIDs = v.filter(&s3.ListObjectVersionsOutput{
+		Versions: []*s3.ObjectVersion{
+			version("f", "f2", 7),
+			version("g", "g1", 3),
+		},
+	}, noDecode)
+	check(objects, versionIDs, "g@g1")
+}
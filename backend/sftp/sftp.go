@@ -762,6 +762,10 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 		return nil, errors.Wrapf(err, "error listing %q", dir)
 	}
 	for _, info := range infos {
+		if info.Mode().IsRegular() && fs.IsPartialName(info.Name()) {
+			// Skip uploads in progress or left by a crash
+			continue
+		}
 		remote := path.Join(dir, info.Name())
 		// If file is a symlink (not a regular file is the best cross platform test we can do), do a stat to
 		// pick up the size and type of the destination, instead of the size and type of the symlink.
@@ -872,17 +876,46 @@ func (f *Fs) Rmdir(ctx context.Context, dir string) error {
 	if len(entries) != 0 {
 		return fs.ErrorDirectoryNotEmpty
 	}
-	// Remove the directory
 	root := path.Join(f.absRoot, dir)
 	c, err := f.getSftpConnection(ctx)
 	if err != nil {
 		return errors.Wrap(err, "Rmdir")
 	}
-	err = c.sftpClient.RemoveDirectory(root)
+	// Remove any partial files left by interrupted uploads as
+	// they are skipped by List
+	err = f.removePartials(c, root)
+	if err == nil {
+		// Remove the directory
+		err = c.sftpClient.RemoveDirectory(root)
+	}
 	f.putSftpConnection(&c, err)
 	return err
 }
 
+// removePartials removes the partial files in the directory root
+func (f *Fs) removePartials(c *conn, root string) error {
+	sftpDir := root
+	if sftpDir == "" {
+		sftpDir = "."
+	}
+	infos, err := c.sftpClient.ReadDir(sftpDir)
+	if err != nil {
+		return errors.Wrap(err, "Rmdir")
+	}
+	for _, info := range infos {
+		if !info.Mode().IsRegular() || !fs.IsPartialName(info.Name()) {
+			continue
+		}
+		partialPath := path.Join(root, info.Name())
+		fs.Debugf(f, "Removing partial file %q left by an interrupted upload", partialPath)
+		err = c.sftpClient.Remove(partialPath)
+		if err != nil {
+			return errors.Wrap(err, "Rmdir: failed to remove partial file")
+		}
+	}
+	return nil
+}
+
 // Move renames a remote sftp file object
 func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
 	srcObj, ok := src.(*Object)
@@ -1371,11 +1404,16 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 	// Clear the hash cache since we are about to update the object
 	o.md5sum = nil
 	o.sha1sum = nil
+	writePath := o.path()
+	if !fs.GetConfig(ctx).Inplace {
+		dir, leaf := path.Split(writePath)
+		writePath = dir + fs.PartialName(leaf)
+	}
 	c, err := o.fs.getSftpConnection(ctx)
 	if err != nil {
 		return errors.Wrap(err, "Update")
 	}
-	file, err := c.sftpClient.OpenFile(o.path(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	file, err := c.sftpClient.OpenFile(writePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
 	o.fs.putSftpConnection(&c, err)
 	if err != nil {
 		return errors.Wrap(err, "Update Create failed")
@@ -1387,7 +1425,7 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 			fs.Debugf(src, "Failed to open new SSH connection for delete: %v", removeErr)
 			return
 		}
-		removeErr = c.sftpClient.Remove(o.path())
+		removeErr = c.sftpClient.Remove(writePath)
 		o.fs.putSftpConnection(&c, removeErr)
 		if removeErr != nil {
 			fs.Debugf(src, "Failed to remove: %v", removeErr)
@@ -1405,6 +1443,13 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 		remove()
 		return errors.Wrap(err, "Update Close failed")
 	}
+	if writePath != o.path() {
+		err = o.fs.renameInto(ctx, writePath, o.path())
+		if err != nil {
+			remove()
+			return errors.Wrap(err, "Update Rename failed")
+		}
+	}
 	err = o.SetModTime(ctx, src.ModTime(ctx))
 	if err != nil {
 		return errors.Wrap(err, "Update SetModTime failed")
@@ -1412,6 +1457,48 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 	return nil
 }
 
+// renameInto renames srcPath to dstPath replacing dstPath if it exists
+//
+// This uses the posix-rename extension which replaces atomically if
+// the server supports it. Otherwise dstPath is renamed out of the way
+// first and put back if the rename fails so it is never lost.
+func (f *Fs) renameInto(ctx context.Context, srcPath, dstPath string) (err error) {
+	c, err := f.getSftpConnection(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		f.putSftpConnection(&c, err)
+	}()
+	err = c.sftpClient.PosixRename(srcPath, dstPath)
+	if err == nil {
+		return nil
+	}
+	dir, leaf := path.Split(dstPath)
+	oldPath := dir + fs.PartialName(leaf+".old")
+	fs.Debugf(f, "posix-rename failed so renaming %q to %q before rename: %v", dstPath, oldPath, err)
+	err = c.sftpClient.Rename(dstPath, oldPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	movedOld := err == nil
+	err = c.sftpClient.Rename(srcPath, dstPath)
+	if err != nil {
+		if movedOld {
+			if restoreErr := c.sftpClient.Rename(oldPath, dstPath); restoreErr != nil {
+				fs.Errorf(f, "Failed to restore %q from %q: %v", dstPath, oldPath, restoreErr)
+			}
+		}
+		return err
+	}
+	if movedOld {
+		if removeErr := c.sftpClient.Remove(oldPath); removeErr != nil {
+			fs.Errorf(f, "Failed to remove %q: %v", oldPath, removeErr)
+		}
+	}
+	return nil
+}
+
 // Remove a remote sftp file object
 func (o *Object) Remove(ctx context.Context) error {
 	c, err := o.fs.getSftpConnection(ctx)
@@ -36,12 +36,14 @@ docs = [
     "sharefile.md",
     "crypt.md",
     "compress.md",
+    "dedup.md",
     "dropbox.md",
     "filefabric.md",
     "ftp.md",
     "googlecloudstorage.md",
     "drive.md",
     "googlephotos.md",
+    "hasher.md",
     "http.md",
     "hubic.md",
     "jottacloud.md",
@@ -0,0 +1,91 @@
+// Package bisync implements the bisync command which synchronises
+// two paths in both directions
+package bisync
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/spf13/cobra"
+)
+
+// Globals
+var (
+	opt = DefaultOptions()
+)
+
+func init() {
+	opt.WorkDir = filepath.Join(config.CacheDir, "bisync")
+	cmd.Root.AddCommand(commandDefinition)
+	cmdFlags := commandDefinition.Flags()
+	flags.BoolVarP(cmdFlags, &opt.Resync, "resync", "", opt.Resync, "Make path1 and path2 contain the same files and start new listings.")
+	flags.FVarP(cmdFlags, &opt.ConflictResolve, "conflict-resolve", "", "How to resolve files changed on both sides: both|newer|rename.")
+	flags.StringVarP(cmdFlags, &opt.ConflictSuffix, "conflict-suffix", "", opt.ConflictSuffix, "Suffix to use when renaming conflicting files.")
+	flags.IntVarP(cmdFlags, &opt.MaxDeletePercent, "max-delete-percent", "", opt.MaxDeletePercent, "Abort if more than this percentage of files were deleted on either side.")
+	flags.BoolVarP(cmdFlags, &opt.Force, "force", "", opt.Force, "Bypass the --max-delete-percent safety check.")
+	flags.StringVarP(cmdFlags, &opt.WorkDir, "workdir", "", opt.WorkDir, "Directory to store the listings in.")
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "bisync remote1:path1 remote2:path2",
+	Short: `Bidirectional synchronization between two paths.`,
+	Long: `
+Synchronise path1 and path2 in both directions, so that new, changed
+and deleted files on either side are propagated to the other.
+
+bisync keeps a listing of each side in the work directory (set with
+` + "`--workdir`" + `) from the end of the previous run.  On each run
+both paths are listed and compared against these listings to find
+which files are new, changed (by size or modification time) or
+deleted on each side.  These changes are then made on the other side
+and new listings are saved.
+
+The first time a pair of paths is used (or after the listings have
+been lost) you must run with ` + "`--resync`" + `.  This copies files
+which are only on one side to the other and overwrites path2 with
+path1 where files differ.  Nothing is deleted by a resync.
+
+If a file is changed on one side and deleted on the other the change
+wins and the file is restored.  If a file is changed on both sides
+then ` + "`--conflict-resolve`" + ` controls what happens
+
+  * ` + "`--conflict-resolve both`" + ` - (default) keep both versions
+    on both sides, renamed to ` + "`file.conflict1.ext`" + ` and
+    ` + "`file.conflict2.ext`" + ` for the path1 and path2 versions.
+  * ` + "`--conflict-resolve newer`" + ` - the newer version
+    overwrites the older one.
+  * ` + "`--conflict-resolve rename`" + ` - the newer version keeps the
+    name and the older version is renamed with its conflict suffix.
+
+The suffix can be changed with ` + "`--conflict-suffix`" + `.
+
+As a safety check bisync will abort without changing anything if
+more than ` + "`--max-delete-percent`" + ` (default 50%) of the files
+on either side have been deleted since the last run, which usually
+means a path was unmounted or emptied by mistake.  Use ` + "`--force`" + `
+to continue anyway.  If ` + "`--max-delete`" + ` is set then bisync
+will also abort if more than that many deletes were detected.
+
+Filters are applied to both sides.  ` + "`--backup-dir`" + ` is used
+for the side which it is on the same remote as and ` + "`--suffix`" + `
+on its own applies to both sides.  Use ` + "`--dry-run`" + ` to see
+what would happen - the listings aren't updated in this case.
+
+Only one bisync of a given pair of paths can run at once.  Empty
+directories are not synchronised.
+
+If bisync fails part way through then the listings aren't updated so
+the next run will pick up the changes again.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(2, 2, command, args)
+		f1 := cmd.NewFsDir(args[:1])
+		f2 := cmd.NewFsDir(args[1:])
+		cmd.Run(true, true, command, func() error {
+			return Bisync(context.Background(), f1, f2, opt)
+		})
+	},
+}
@@ -0,0 +1,206 @@
+package bisync
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/all" // import all backends
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Some times used in the tests
+var (
+	t1 = fstest.Time("2001-02-03T04:05:06.499999999Z")
+	t2 = fstest.Time("2011-12-25T12:59:59.123456789Z")
+	t3 = fstest.Time("2011-12-30T12:59:59.000000000Z")
+)
+
+// TestMain drives the tests
+func TestMain(m *testing.M) {
+	fstest.TestMain(m)
+}
+
+// newOpt makes options using a temporary work directory
+func newOpt(t *testing.T) (opt *Options, cleanup func()) {
+	workDir, err := ioutil.TempDir("", "rclone-bisync-test")
+	require.NoError(t, err)
+	opt = DefaultOptions()
+	opt.WorkDir = workDir
+	return opt, func() {
+		_ = os.RemoveAll(workDir)
+	}
+}
+
+// remove deletes item from f
+func remove(ctx context.Context, t *testing.T, f fs.Fs, item fstest.Item) {
+	o, err := f.NewObject(ctx, item.Path)
+	require.NoError(t, err)
+	require.NoError(t, o.Remove(ctx))
+}
+
+func TestBisyncNeedsResync(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	opt, cleanup := newOpt(t)
+	defer cleanup()
+	r.Mkdir(ctx, r.Fremote)
+
+	err := Bisync(ctx, r.Flocal, r.Fremote, opt)
+	require.Error(t, err)
+	assert.True(t, fserrors.IsFatalError(err))
+	assert.Contains(t, err.Error(), "--resync")
+}
+
+func TestBisync(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	opt, cleanup := newOpt(t)
+	defer cleanup()
+
+	file1 := r.WriteFile("one", "one", t1)
+	file2 := r.WriteObject(ctx, "two", "two", t1)
+	file3 := r.WriteBoth(ctx, "dir/three", "three", t1)
+	file4 := r.WriteBoth(ctx, "four", "four", t1)
+
+	// Resync makes both sides the same
+	opt.Resync = true
+	require.NoError(t, Bisync(ctx, r.Flocal, r.Fremote, opt))
+	fstest.CheckItems(t, r.Flocal, file1, file2, file3, file4)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3, file4)
+
+	// Nothing changed so nothing to do
+	opt.Resync = false
+	require.NoError(t, Bisync(ctx, r.Flocal, r.Fremote, opt))
+	fstest.CheckItems(t, r.Flocal, file1, file2, file3, file4)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3, file4)
+
+	// Make changes on both sides
+	file1 = r.WriteFile("one", "one changed", t2)
+	remove(ctx, t, r.Fremote, file2)
+	remove(ctx, t, r.Flocal, file3)
+	file5 := r.WriteObject(ctx, "five", "five", t2)
+	require.NoError(t, Bisync(ctx, r.Flocal, r.Fremote, opt))
+	fstest.CheckItems(t, r.Flocal, file1, file4, file5)
+	fstest.CheckItems(t, r.Fremote, file1, file4, file5)
+
+	// Changed on one side and deleted on the other - change wins
+	remove(ctx, t, r.Flocal, file4)
+	file4 = r.WriteObject(ctx, "four", "four changed", t2)
+	require.NoError(t, Bisync(ctx, r.Flocal, r.Fremote, opt))
+	fstest.CheckItems(t, r.Flocal, file1, file4, file5)
+	fstest.CheckItems(t, r.Fremote, file1, file4, file5)
+}
+
+func TestBisyncDryRun(t *testing.T) {
+	ctx := context.Background()
+	ci := fs.GetConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	opt, cleanup := newOpt(t)
+	defer cleanup()
+
+	file1 := r.WriteBoth(ctx, "one", "one", t1)
+	opt.Resync = true
+	require.NoError(t, Bisync(ctx, r.Flocal, r.Fremote, opt))
+	opt.Resync = false
+
+	file2 := r.WriteFile("two", "two", t1)
+	ci.DryRun = true
+	err := Bisync(ctx, r.Flocal, r.Fremote, opt)
+	ci.DryRun = false
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Flocal, file1, file2)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	// the listings weren't updated so the change is still found
+	require.NoError(t, Bisync(ctx, r.Flocal, r.Fremote, opt))
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+}
+
+func TestBisyncConflict(t *testing.T) {
+	for _, test := range []struct {
+		resolve ConflictResolve
+		want    []fstest.Item
+	}{
+		{
+			resolve: ConflictNewer,
+			want: []fstest.Item{
+				fstest.NewItem("file.txt", "remote", t3),
+			},
+		},
+		{
+			resolve: ConflictRename,
+			want: []fstest.Item{
+				fstest.NewItem("file.txt", "remote", t3),
+				fstest.NewItem("file.conflict1.txt", "local", t2),
+			},
+		},
+		{
+			resolve: ConflictKeepBoth,
+			want: []fstest.Item{
+				fstest.NewItem("file.conflict1.txt", "local", t2),
+				fstest.NewItem("file.conflict2.txt", "remote", t3),
+			},
+		},
+	} {
+		t.Run(test.resolve.String(), func(t *testing.T) {
+			ctx := context.Background()
+			r := fstest.NewRun(t)
+			defer r.Finalise()
+			opt, cleanup := newOpt(t)
+			defer cleanup()
+
+			r.WriteBoth(ctx, "file.txt", "original", t1)
+			opt.Resync = true
+			require.NoError(t, Bisync(ctx, r.Flocal, r.Fremote, opt))
+			opt.Resync = false
+
+			r.WriteFile("file.txt", "local", t2)
+			r.WriteObject(ctx, "file.txt", "remote", t3)
+			opt.ConflictResolve = test.resolve
+			require.NoError(t, Bisync(ctx, r.Flocal, r.Fremote, opt))
+			fstest.CheckItems(t, r.Flocal, test.want...)
+			fstest.CheckItems(t, r.Fremote, test.want...)
+
+			// Next run should find nothing to do
+			require.NoError(t, Bisync(ctx, r.Flocal, r.Fremote, opt))
+			fstest.CheckItems(t, r.Flocal, test.want...)
+			fstest.CheckItems(t, r.Fremote, test.want...)
+		})
+	}
+}
+
+func TestBisyncTooManyDeletes(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	opt, cleanup := newOpt(t)
+	defer cleanup()
+
+	file1 := r.WriteBoth(ctx, "one", "one", t1)
+	file2 := r.WriteBoth(ctx, "two", "two", t1)
+	file3 := r.WriteBoth(ctx, "three", "three", t1)
+	opt.Resync = true
+	require.NoError(t, Bisync(ctx, r.Flocal, r.Fremote, opt))
+	opt.Resync = false
+
+	remove(ctx, t, r.Flocal, file1)
+	remove(ctx, t, r.Flocal, file2)
+	err := Bisync(ctx, r.Flocal, r.Fremote, opt)
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), ErrorTooManyDelete.Error()))
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+
+	opt.Force = true
+	require.NoError(t, Bisync(ctx, r.Flocal, r.Fremote, opt))
+	fstest.CheckItems(t, r.Fremote, file3)
+}
@@ -0,0 +1,73 @@
+package bisync
+
+import (
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// delta describes how a file has changed since the last run
+type delta uint8
+
+// Types of delta
+const (
+	deltaNone    delta = iota // file unchanged
+	deltaNew                  // file not in previous listing
+	deltaChanged              // size or modification time changed
+	deltaDeleted              // file in previous listing but now gone
+)
+
+func (d delta) String() string {
+	switch d {
+	case deltaNone:
+		return "unchanged"
+	case deltaNew:
+		return "new"
+	case deltaChanged:
+		return "changed"
+	case deltaDeleted:
+		return "deleted"
+	}
+	return "unknown"
+}
+
+// deltaSet is the deltas found on one side keyed by remote
+type deltaSet map[string]delta
+
+// count returns the number of deltas of type d in the set
+func (ds deltaSet) count(d delta) (n int) {
+	for _, x := range ds {
+		if x == d {
+			n++
+		}
+	}
+	return n
+}
+
+// findDeltas compares the previous listing with the current one
+//
+// Modification times are compared to within modifyWindow - if that is
+// fs.ModTimeNotSupported then only sizes are compared.
+func findDeltas(prev, cur fileList, modifyWindow time.Duration) deltaSet {
+	ds := deltaSet{}
+	for remote, now := range cur {
+		old, found := prev[remote]
+		switch {
+		case !found:
+			ds[remote] = deltaNew
+		case old.size != now.size:
+			ds[remote] = deltaChanged
+		case modifyWindow != fs.ModTimeNotSupported:
+			dt := now.modTime.Sub(old.modTime)
+			if dt != 0 && (dt >= modifyWindow || dt <= -modifyWindow) {
+				ds[remote] = deltaChanged
+			}
+		}
+	}
+	for remote := range prev {
+		if _, found := cur[remote]; !found {
+			ds[remote] = deltaDeleted
+		}
+	}
+	return ds
+}
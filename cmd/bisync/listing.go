@@ -0,0 +1,214 @@
+package bisync
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/march"
+)
+
+// listingHeader is written as the first line of every listing file
+const listingHeader = "# rclone bisync listing v1"
+
+// fileInfo is the state of a single file recorded in a listing
+type fileInfo struct {
+	size    int64
+	modTime time.Time
+}
+
+// fileList is a snapshot of the files on one side of a bisync
+type fileList map[string]fileInfo
+
+// newFileInfo makes a fileInfo from an object
+func newFileInfo(ctx context.Context, o fs.Object) fileInfo {
+	return fileInfo{
+		size:    o.Size(),
+		modTime: o.ModTime(ctx),
+	}
+}
+
+// sorted returns the remotes in the list in sorted order
+func (ls fileList) sorted() []string {
+	remotes := make([]string, 0, len(ls))
+	for remote := range ls {
+		remotes = append(remotes, remote)
+	}
+	sort.Strings(remotes)
+	return remotes
+}
+
+// save writes the listing atomically to the file name given
+func (ls fileList) save(name string) (err error) {
+	tmpName := name + ".tmp"
+	out, err := os.Create(tmpName)
+	if err != nil {
+		return errors.Wrap(err, "failed to create listing")
+	}
+	w := bufio.NewWriter(out)
+	_, _ = fmt.Fprintln(w, listingHeader)
+	for _, remote := range ls.sorted() {
+		info := ls[remote]
+		_, _ = fmt.Fprintf(w, "%d %s %s\n", info.size, info.modTime.UTC().Format(time.RFC3339Nano), strconv.Quote(remote))
+	}
+	err = w.Flush()
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpName)
+		return errors.Wrap(err, "failed to write listing")
+	}
+	return os.Rename(tmpName, name)
+}
+
+// loadFileList reads a listing written by save
+//
+// It returns an error satisfying os.IsNotExist if the listing isn't
+// found.
+func loadFileList(name string) (ls fileList, err error) {
+	in, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CheckClose(in, &err)
+	return readFileList(in)
+}
+
+// readFileList parses a listing from in
+func readFileList(in io.Reader) (ls fileList, err error) {
+	ls = fileList{}
+	scanner := bufio.NewScanner(in)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if lineNo == 1 {
+			if line != listingHeader {
+				return nil, errors.Errorf("bad listing header %q", line)
+			}
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) != 3 {
+			return nil, errors.Errorf("line %d: bad listing line %q", lineNo, line)
+		}
+		size, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d: bad size", lineNo)
+		}
+		modTime, err := time.Parse(time.RFC3339Nano, fields[1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d: bad modification time", lineNo)
+		}
+		remote, err := strconv.Unquote(fields[2])
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d: bad path", lineNo)
+		}
+		ls[remote] = fileInfo{size: size, modTime: modTime}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if lineNo == 0 {
+		return nil, errors.New("empty listing")
+	}
+	return ls, nil
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// listingBase returns the base file name for the listings of the pair
+// f1, f2 in workDir
+func listingBase(workDir string, f1, f2 fs.Fs) string {
+	name := unsafeChars.ReplaceAllString(fs.ConfigString(f1), "_") + ".." + unsafeChars.ReplaceAllString(fs.ConfigString(f2), "_")
+	return filepath.Join(workDir, name)
+}
+
+// lister collects the objects found on both sides by a march
+type lister struct {
+	ctx  context.Context
+	mu   sync.Mutex
+	objs [2]map[string]fs.Object
+	ls   [2]fileList
+}
+
+// add records o as present on side i
+func (l *lister) add(i int, o fs.Object) {
+	info := newFileInfo(l.ctx, o)
+	l.mu.Lock()
+	l.objs[i][o.Remote()] = o
+	l.ls[i][o.Remote()] = info
+	l.mu.Unlock()
+}
+
+// SrcOnly is called for a DirEntry found only on path1
+func (l *lister) SrcOnly(src fs.DirEntry) (recurse bool) {
+	switch x := src.(type) {
+	case fs.Object:
+		l.add(0, x)
+	case fs.Directory:
+		return true
+	}
+	return false
+}
+
+// DstOnly is called for a DirEntry found only on path2
+func (l *lister) DstOnly(dst fs.DirEntry) (recurse bool) {
+	switch x := dst.(type) {
+	case fs.Object:
+		l.add(1, x)
+	case fs.Directory:
+		return true
+	}
+	return false
+}
+
+// Match is called for a DirEntry found on both path1 and path2
+func (l *lister) Match(ctx context.Context, dst, src fs.DirEntry) (recurse bool) {
+	srcObj, srcIsObj := src.(fs.Object)
+	dstObj, dstIsObj := dst.(fs.Object)
+	switch {
+	case srcIsObj && dstIsObj:
+		l.add(0, srcObj)
+		l.add(1, dstObj)
+	case !srcIsObj && !dstIsObj:
+		return true
+	default:
+		fs.Errorf(src, "Can't bisync a file with a directory of the same name - ignoring")
+	}
+	return false
+}
+
+// listBoth marches f1 and f2 in lock step returning the objects
+// found on each side and the corresponding listings
+func listBoth(ctx context.Context, f1, f2 fs.Fs) (objs [2]map[string]fs.Object, ls [2]fileList, err error) {
+	l := &lister{ctx: ctx}
+	for i := range l.objs {
+		l.objs[i] = map[string]fs.Object{}
+		l.ls[i] = fileList{}
+	}
+	m := &march.March{
+		Ctx:      ctx,
+		Fsrc:     f1,
+		Fdst:     f2,
+		Callback: l,
+	}
+	err = m.Run(ctx)
+	return l.objs, l.ls, err
+}
@@ -0,0 +1,494 @@
+package bisync
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/operations"
+)
+
+// ConflictResolve is how bisync resolves a file changed on both sides
+type ConflictResolve int
+
+// Conflict resolution policies
+const (
+	ConflictKeepBoth ConflictResolve = iota // rename both versions and keep them on both sides
+	ConflictNewer                           // the newer version overwrites the older one
+	ConflictRename                          // the newer version wins, the older one is renamed
+)
+
+func (x ConflictResolve) String() string {
+	switch x {
+	case ConflictKeepBoth:
+		return "both"
+	case ConflictNewer:
+		return "newer"
+	case ConflictRename:
+		return "rename"
+	}
+	return "unknown"
+}
+
+// Set a ConflictResolve from a string
+func (x *ConflictResolve) Set(s string) error {
+	switch s {
+	case "both":
+		*x = ConflictKeepBoth
+	case "newer":
+		*x = ConflictNewer
+	case "rename":
+		*x = ConflictRename
+	default:
+		return errors.Errorf("unknown conflict resolution %q - must be both, newer or rename", s)
+	}
+	return nil
+}
+
+// Type of the value
+func (x *ConflictResolve) Type() string {
+	return "string"
+}
+
+// Options configure a bisync run
+type Options struct {
+	Resync           bool            // copy path1 to path2 and vice versa to make new listings
+	ConflictResolve  ConflictResolve // how to resolve files changed on both sides
+	ConflictSuffix   string          // suffix added to conflicting files which are renamed
+	MaxDeletePercent int             // abort if more than this percentage of files are deleted on one side
+	Force            bool            // bypass the MaxDeletePercent safety check
+	WorkDir          string          // directory to store the listings in
+}
+
+// DefaultOptions returns the default bisync options
+func DefaultOptions() *Options {
+	return &Options{
+		ConflictResolve:  ConflictKeepBoth,
+		ConflictSuffix:   "conflict",
+		MaxDeletePercent: 50,
+	}
+}
+
+// errors returned by Bisync
+var (
+	ErrorNoListings    = errors.New("no prior listings found for this pair of paths - run with --resync first")
+	ErrorTooManyDelete = errors.New("too many deletes detected - aborting")
+	ErrorLocked        = errors.New("another bisync of this pair of paths is running")
+)
+
+// bisyncRun holds the state for a single run of Bisync
+type bisyncRun struct {
+	opt       *Options
+	fs        [2]fs.Fs
+	backupDir [2]fs.Fs
+	window    [2]time.Duration
+	objs      [2]map[string]fs.Object
+	ls        [2]fileList
+	touchedMu sync.Mutex
+	touched   map[string]struct{}
+}
+
+// Bisync synchronises f1 and f2 in both directions
+//
+// It compares each side against the listing saved at the end of the
+// previous run to work out which files were created, changed or
+// deleted on each side and propagates those changes to the other
+// side.
+func Bisync(ctx context.Context, f1, f2 fs.Fs, opt *Options) (err error) {
+	ci := fs.GetConfig(ctx)
+	if operations.Overlapping(f1, f2) {
+		return fserrors.FatalError(errors.New("path1 and path2 mustn't overlap"))
+	}
+	b := &bisyncRun{
+		opt:     opt,
+		fs:      [2]fs.Fs{f1, f2},
+		touched: map[string]struct{}{},
+	}
+	for i, f := range b.fs {
+		b.window[i] = fs.GetModifyWindow(ctx, f)
+		b.backupDir[i], err = getBackupDir(ctx, f, b.fs[1-i])
+		if err != nil {
+			return err
+		}
+	}
+
+	err = os.MkdirAll(opt.WorkDir, 0700)
+	if err != nil {
+		return errors.Wrap(err, "failed to make work directory")
+	}
+	base := listingBase(opt.WorkDir, f1, f2)
+	lockFile := base + ".lck"
+	lock, err := os.OpenFile(lockFile, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if os.IsExist(err) {
+		return errors.Wrapf(ErrorLocked, "remove %q if this is not the case", lockFile)
+	} else if err != nil {
+		return errors.Wrap(err, "failed to make lock file")
+	}
+	_, _ = fmt.Fprintf(lock, "%d\n", os.Getpid())
+	_ = lock.Close()
+	defer func() {
+		_ = os.Remove(lockFile)
+	}()
+	listingNames := [2]string{base + ".path1.lst", base + ".path2.lst"}
+
+	fs.Infof(nil, "Listing path1 %q and path2 %q", fs.ConfigString(f1), fs.ConfigString(f2))
+	b.objs, b.ls, err = listBoth(ctx, f1, f2)
+	if err != nil {
+		return errors.Wrap(err, "failed to list paths")
+	}
+
+	if opt.Resync {
+		err = b.resync(ctx)
+	} else {
+		var prev [2]fileList
+		for i := range prev {
+			prev[i], err = loadFileList(listingNames[i])
+			if os.IsNotExist(err) {
+				return fserrors.FatalError(ErrorNoListings)
+			} else if err != nil {
+				return errors.Wrapf(err, "failed to read listing %q", listingNames[i])
+			}
+		}
+		var ds [2]deltaSet
+		for i := range ds {
+			ds[i] = findDeltas(prev[i], b.ls[i], b.window[i])
+			fs.Infof(nil, "Path%d: %d new, %d changed, %d deleted", i+1, ds[i].count(deltaNew), ds[i].count(deltaChanged), ds[i].count(deltaDeleted))
+		}
+		for i := range ds {
+			err = b.checkDeletes(ctx, i, prev[i], ds[i])
+			if err != nil {
+				return err
+			}
+		}
+		err = b.run(ctx, ds)
+	}
+	if err != nil {
+		return err
+	}
+
+	if ci.DryRun {
+		fs.Logf(nil, "Not saving listings as --dry-run is set")
+		return nil
+	}
+	b.refreshListings(ctx)
+	for i, name := range listingNames {
+		err = b.ls[i].save(name)
+		if err != nil {
+			return err
+		}
+	}
+	fs.Infof(nil, "Bisync successful")
+	return nil
+}
+
+// getBackupDir returns the --backup-dir to use for f which is
+// synced with other, or nil if none is configured for f
+//
+// As --backup-dir must be on the same remote as the destination it
+// is only used for the side it has a config in common with.
+// --suffix on its own applies to both sides.
+func getBackupDir(ctx context.Context, f, other fs.Fs) (fs.Fs, error) {
+	ci := fs.GetConfig(ctx)
+	if ci.BackupDir != "" {
+		backupDir, err := cache.Get(ctx, ci.BackupDir)
+		if err != nil {
+			return nil, fserrors.FatalError(errors.Errorf("Failed to make fs for --backup-dir %q: %v", ci.BackupDir, err))
+		}
+		if !operations.SameConfig(f, backupDir) {
+			return nil, nil
+		}
+	} else if ci.Suffix == "" {
+		return nil, nil
+	}
+	return operations.BackupDir(ctx, f, other, "")
+}
+
+// checkDeletes aborts the run if too many deletes were found on side i
+func (b *bisyncRun) checkDeletes(ctx context.Context, i int, prev fileList, ds deltaSet) error {
+	ci := fs.GetConfig(ctx)
+	deletes := ds.count(deltaDeleted)
+	if deletes == 0 {
+		return nil
+	}
+	if ci.MaxDelete >= 0 && int64(deletes) > ci.MaxDelete {
+		fs.Errorf(b.fs[i], "%d deletes found which exceeds --max-delete %d", deletes, ci.MaxDelete)
+		return fserrors.FatalError(ErrorTooManyDelete)
+	}
+	percent := 100 * deletes / len(prev)
+	if percent > b.opt.MaxDeletePercent {
+		if b.opt.Force {
+			fs.Logf(b.fs[i], "%d%% of files deleted exceeds --max-delete-percent %d%% - continuing as --force is set", percent, b.opt.MaxDeletePercent)
+			return nil
+		}
+		fs.Errorf(b.fs[i], "%d%% of files deleted exceeds --max-delete-percent %d%% - use --force to continue anyway", percent, b.opt.MaxDeletePercent)
+		return fserrors.FatalError(ErrorTooManyDelete)
+	}
+	return nil
+}
+
+// touch records that remote may have been altered by the run
+func (b *bisyncRun) touch(remotes ...string) {
+	b.touchedMu.Lock()
+	for _, remote := range remotes {
+		b.touched[remote] = struct{}{}
+	}
+	b.touchedMu.Unlock()
+}
+
+// refreshListings reads back the state of all the touched files so
+// the saved listings reflect what is really on each side
+func (b *bisyncRun) refreshListings(ctx context.Context) {
+	for remote := range b.touched {
+		for i, f := range b.fs {
+			o, err := f.NewObject(ctx, remote)
+			if err == nil {
+				b.ls[i][remote] = newFileInfo(ctx, o)
+			} else {
+				delete(b.ls[i], remote)
+			}
+		}
+	}
+}
+
+// copyTo copies src to side i as remote, overwriting dst if set
+func (b *bisyncRun) copyTo(ctx context.Context, i int, dst fs.Object, remote string, src fs.Object) error {
+	b.touch(remote)
+	if dst != nil && b.backupDir[i] != nil {
+		err := operations.MoveBackupDir(ctx, b.backupDir[i], dst)
+		if err != nil {
+			return err
+		}
+		dst = nil
+	}
+	_, err := operations.Copy(ctx, b.fs[i], dst, remote, src)
+	return err
+}
+
+// deleteFrom deletes o from side i
+func (b *bisyncRun) deleteFrom(ctx context.Context, i int, o fs.Object) error {
+	b.touch(o.Remote())
+	return operations.DeleteFileWithBackupDir(ctx, o, b.backupDir[i])
+}
+
+// renameOn renames o on side i to newRemote
+func (b *bisyncRun) renameOn(ctx context.Context, i int, o fs.Object, newRemote string) error {
+	b.touch(o.Remote(), newRemote)
+	_, err := operations.Move(ctx, b.fs[i], nil, newRemote, o)
+	return err
+}
+
+// conflictName returns the name for the version of remote from side i
+func (b *bisyncRun) conflictName(remote string, i int) string {
+	ext := path.Ext(remote)
+	return fmt.Sprintf("%s.%s%d%s", remote[:len(remote)-len(ext)], b.opt.ConflictSuffix, i+1, ext)
+}
+
+// resolveConflict deals with remote which was changed on both sides
+func (b *bisyncRun) resolveConflict(ctx context.Context, remote string) error {
+	o := [2]fs.Object{b.objs[0][remote], b.objs[1][remote]}
+	// winner is the newer of the two, path1 if they are the same age
+	winner := 0
+	if o[1].ModTime(ctx).After(o[0].ModTime(ctx)) {
+		winner = 1
+	}
+	loser := 1 - winner
+	switch b.opt.ConflictResolve {
+	case ConflictNewer:
+		fs.Logf(o[loser], "Conflict: overwriting with newer version from path%d", winner+1)
+		return b.copyTo(ctx, loser, o[loser], remote, o[winner])
+	case ConflictRename:
+		newName := b.conflictName(remote, loser)
+		fs.Logf(o[loser], "Conflict: renaming older version to %q", newName)
+		err := b.copyTo(ctx, winner, nil, newName, o[loser])
+		if err != nil {
+			return err
+		}
+		err = b.renameOn(ctx, loser, o[loser], newName)
+		if err != nil {
+			return err
+		}
+		return b.copyTo(ctx, loser, nil, remote, o[winner])
+	case ConflictKeepBoth:
+		var newNames [2]string
+		for i := range o {
+			newNames[i] = b.conflictName(remote, i)
+		}
+		fs.Logf(o[0], "Conflict: keeping both versions as %q and %q", newNames[0], newNames[1])
+		for i := range o {
+			err := b.copyTo(ctx, 1-i, nil, newNames[i], o[i])
+			if err != nil {
+				return err
+			}
+		}
+		for i := range o {
+			err := b.renameOn(ctx, i, o[i], newNames[i])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return errors.Errorf("unknown conflict resolution %v", b.opt.ConflictResolve)
+}
+
+// plan works out the actions needed to propagate the deltas
+func (b *bisyncRun) plan(ctx context.Context, ds [2]deltaSet) (actions []func(context.Context) error) {
+	remotes := map[string]struct{}{}
+	for i := range ds {
+		for remote := range ds[i] {
+			remotes[remote] = struct{}{}
+		}
+	}
+	sorted := make([]string, 0, len(remotes))
+	for remote := range remotes {
+		sorted = append(sorted, remote)
+	}
+	sort.Strings(sorted)
+	for _, remote := range sorted {
+		remote := remote
+		d := [2]delta{ds[0][remote], ds[1][remote]}
+		o := [2]fs.Object{b.objs[0][remote], b.objs[1][remote]}
+		switch {
+		case d[0] == deltaDeleted && d[1] == deltaDeleted:
+			// deleted on both sides - nothing to do
+		case d[0] != deltaNone && d[1] != deltaNone && d[0] != deltaDeleted && d[1] != deltaDeleted:
+			// new or changed on both sides
+			if operations.Equal(ctx, o[0], o[1]) {
+				fs.Debugf(o[0], "Changed identically on both sides")
+				continue
+			}
+			actions = append(actions, func(ctx context.Context) error {
+				return b.resolveConflict(ctx, remote)
+			})
+		default:
+			// changed on one side only, or changed on one side and
+			// deleted on the other in which case the change wins
+			from := 0
+			if d[0] == deltaNone || (d[1] != deltaNone && d[1] != deltaDeleted) {
+				from = 1
+			}
+			to := 1 - from
+			if d[from] == deltaDeleted {
+				if o[to] != nil {
+					actions = append(actions, func(ctx context.Context) error {
+						return b.deleteFrom(ctx, to, o[to])
+					})
+				}
+				continue
+			}
+			if o[to] != nil && operations.Equal(ctx, o[from], o[to]) {
+				fs.Debugf(o[from], "Already identical on path%d", to+1)
+				continue
+			}
+			if d[to] == deltaDeleted {
+				fs.Logf(o[from], "Changed on path%d but deleted on path%d - restoring", from+1, to+1)
+			}
+			actions = append(actions, func(ctx context.Context) error {
+				return b.copyTo(ctx, to, o[to], remote, o[from])
+			})
+		}
+	}
+	return actions
+}
+
+// resync makes the two sides identical with path1 winning where
+// files differ and no deletions
+func (b *bisyncRun) resync(ctx context.Context) error {
+	fs.Infof(nil, "Resyncing path1 to path2 and path2 to path1")
+	remotes := map[string]struct{}{}
+	for i := range b.objs {
+		for remote := range b.objs[i] {
+			remotes[remote] = struct{}{}
+		}
+	}
+	var actions []func(context.Context) error
+	for remote := range remotes {
+		o := [2]fs.Object{b.objs[0][remote], b.objs[1][remote]}
+		remote := remote
+		switch {
+		case o[1] == nil:
+			actions = append(actions, func(ctx context.Context) error {
+				return b.copyTo(ctx, 1, nil, remote, o[0])
+			})
+		case o[0] == nil:
+			actions = append(actions, func(ctx context.Context) error {
+				return b.copyTo(ctx, 0, nil, remote, o[1])
+			})
+		case !operations.Equal(ctx, o[0], o[1]):
+			actions = append(actions, func(ctx context.Context) error {
+				return b.copyTo(ctx, 1, o[1], remote, o[0])
+			})
+		}
+	}
+	return b.runActions(ctx, actions)
+}
+
+// run plans and carries out the actions needed for the deltas
+func (b *bisyncRun) run(ctx context.Context, ds [2]deltaSet) error {
+	actions := b.plan(ctx, ds)
+	if len(actions) == 0 {
+		fs.Infof(nil, "No changes to propagate")
+		return nil
+	}
+	return b.runActions(ctx, actions)
+}
+
+// runActions runs the actions in parallel using --transfers workers
+//
+// It returns the first error encountered, stopping early if it is
+// fatal.
+func (b *bisyncRun) runActions(ctx context.Context, actions []func(context.Context) error) error {
+	ci := fs.GetConfig(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		errCount int
+		in       = make(chan func(context.Context) error)
+	)
+	wg.Add(ci.Transfers)
+	for i := 0; i < ci.Transfers; i++ {
+		go func() {
+			defer wg.Done()
+			for action := range in {
+				err := action(ctx)
+				if err == nil {
+					continue
+				}
+				mu.Lock()
+				if firstErr == nil || fserrors.IsFatalError(err) && !fserrors.IsFatalError(firstErr) {
+					firstErr = err
+				}
+				errCount++
+				mu.Unlock()
+				if fserrors.IsFatalError(err) {
+					cancel()
+				}
+			}
+		}()
+	}
+outer:
+	for _, action := range actions {
+		select {
+		case <-ctx.Done():
+			break outer
+		case in <- action:
+		}
+	}
+	close(in)
+	wg.Wait()
+	if errCount > 1 {
+		return errors.Wrapf(firstErr, "bisync failed with %d errors: first error", errCount)
+	}
+	return firstErr
+}
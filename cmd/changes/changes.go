@@ -0,0 +1,154 @@
+// Package changes implements the changes command which records the
+// paths changed in a remote for sync --files-from-changes
+package changes
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/changes"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/lib/atexit"
+	"github.com/spf13/cobra"
+)
+
+// Globals
+var (
+	pollInterval = time.Minute
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefinition)
+	cmdFlags := commandDefinition.Flags()
+	flags.DurationVarP(cmdFlags, &pollInterval, "poll-interval", "", pollInterval, "Time to wait between polling for changes on remotes which poll.")
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "changes remote:path [journal]",
+	Short: `Record the paths which change in remote:path.`,
+	Long: `
+Watch remote:path for changes and append the paths of the files and
+directories which change to the journal, one per line, relative to
+remote:path.  If no journal is given the paths are printed instead.
+
+This runs until it is stopped.  Use the journal with
+` + "`--files-from-changes`" + ` to sync just the paths which have changed,
+for example
+
+    rclone changes --local-change-notify /data /var/lib/rclone/data.changes &
+    rclone sync --files-from-changes /var/lib/rclone/data.changes /data remote:data
+
+The changes are found by watching the local filesystem with inotify
+on Linux if ` + "`--local-change-notify`" + ` is set, or using the change notifications of remotes which have
+them, such as Google Drive, OneDrive and Box, which are polled every
+` + "`--poll-interval`" + `.
+
+Changes made while this isn't running can't be seen, so when it
+starts it writes a change to the root, "/", to the journal which
+makes the next sync a full sync.  If the local filesystem changes too
+fast for the changes to be kept up with the same happens.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 2, command, args)
+		fsrc := cmd.NewFsSrc(args)
+		write := writeStdout
+		if len(args) > 1 {
+			journal := changes.NewJournal(args[1])
+			write = journal.Append
+		}
+		cmd.Run(false, false, command, func() error {
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			atexit.Register(func() {
+				cancel()
+				<-done
+			})
+			defer close(done)
+			if len(args) > 1 {
+				// Anything could have changed while not recording
+				set := make(changes.Set)
+				set.Add(changes.Root)
+				err := write(set)
+				if err != nil {
+					return err
+				}
+			}
+			return Record(ctx, fsrc, write, pollInterval)
+		})
+	},
+}
+
+// writeStdout prints the paths in set
+func writeStdout(set changes.Set) error {
+	var remotes []string
+	for remote := range set {
+		remotes = append(remotes, remote)
+	}
+	sort.Strings(remotes)
+	out := bufio.NewWriter(os.Stdout)
+	for _, remote := range remotes {
+		if remote == "" {
+			remote = changes.Root
+		}
+		_, _ = out.WriteString(remote)
+		_ = out.WriteByte('\n')
+	}
+	return out.Flush()
+}
+
+// Record watches f for changes calling write with the paths which
+// have changed at most once a second until ctx is cancelled.
+//
+// pollInterval is passed on to remotes which poll for changes.
+func Record(ctx context.Context, f fs.Fs, write func(changes.Set) error, pollInterval time.Duration) error {
+	doChangeNotify := f.Features().ChangeNotify
+	if doChangeNotify == nil {
+		if f.Features().IsLocal && runtime.GOOS == "linux" {
+			return errors.Errorf("%v doesn't support change notifications unless --local-change-notify is set", f)
+		}
+		return errors.Errorf("%v doesn't support change notifications", f)
+	}
+	var (
+		mu  sync.Mutex
+		set = make(changes.Set)
+	)
+	flush := func() error {
+		mu.Lock()
+		changed := set
+		set = make(changes.Set)
+		mu.Unlock()
+		return write(changed)
+	}
+	pollIntervalChan := make(chan time.Duration)
+	doChangeNotify(ctx, func(remote string, entryType fs.EntryType) {
+		fs.Debugf(f, "Change to %q", remote)
+		mu.Lock()
+		set.Add(remote)
+		mu.Unlock()
+	}, pollIntervalChan)
+	pollIntervalChan <- pollInterval
+	defer close(pollIntervalChan)
+	fs.Infof(f, "Recording changes")
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			err := flush()
+			if err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return flush()
+		}
+	}
+}
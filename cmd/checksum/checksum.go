@@ -0,0 +1,68 @@
+package checksum
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/cmd/check"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/spf13/cobra"
+)
+
+var download = false
+
+func init() {
+	cmd.Root.AddCommand(commandDefinition)
+	cmdFlags := commandDefinition.Flags()
+	flags.BoolVarP(cmdFlags, &download, "download", "", download, "Check by hashing the contents.")
+	check.AddFlags(cmdFlags)
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "checksum <hash> sumfile src:path",
+	Short: `Checks the files in the source against a SUM file.`,
+	Long: strings.Replace(`
+Checks that hashsums of source files match the SUM file.
+It compares hashes (MD5, SHA1, etc) and logs a report of files which
+don't match.  It doesn't alter the file system.
+
+The sumfile is a text file in the format written by md5sum, sha1sum
+or |rclone hashsum|, which can be on any remote. For example
+
+    rclone hashsum MD5 --output-file MD5SUMS remote:path
+    rclone checksum MD5 MD5SUMS remote:path
+
+If you supply the |--download| flag, it will download the data from
+the source and calculate the hashes on the fly, which works with
+remotes which don't support the hash.
+
+The SUM file plays the part of the destination in the reports below,
+so files listed in the SUM file but not found in the source are
+missing on the source and files in the source not listed in the SUM
+file are missing on the destination.
+`, "|", "`", -1) + check.FlagsHelp,
+	RunE: func(command *cobra.Command, args []string) error {
+		cmd.CheckArgs(3, 3, command, args)
+		var hashType hash.Type
+		if err := hashType.Set(args[0]); err != nil {
+			return err
+		}
+		fsum, sumFile, fsrc := cmd.NewFsSrcFileDst(args[1:])
+		if sumFile == "" {
+			return errors.Errorf("%q must be a file", args[1])
+		}
+		cmd.Run(false, true, command, func() error {
+			opt, close, err := check.GetCheckOpt(fsrc, fsum)
+			if err != nil {
+				return err
+			}
+			defer close()
+			return operations.CheckSum(context.Background(), fsrc, fsum, sumFile, hashType, opt, download)
+		})
+		return nil
+	},
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/spf13/cobra"
+)
+
+var (
+	encryptionRecipients     []string
+	encryptionRecipientFiles []string
+	encryptionPassphrase     bool
+)
+
+func init() {
+	configCommand.AddCommand(configEncryptionCommand)
+	configEncryptionCommand.AddCommand(configEncryptionKeygenCommand)
+	configEncryptionCommand.AddCommand(configEncryptionSetCommand)
+	configEncryptionCommand.AddCommand(configEncryptionRotateCommand)
+	configEncryptionCommand.AddCommand(configEncryptionRemoveCommand)
+	cmdFlags := configEncryptionSetCommand.Flags()
+	flags.StringArrayVarP(cmdFlags, &encryptionRecipients, "recipient", "r", nil, "Public key to encrypt the config secrets to (may be repeated).")
+	flags.StringArrayVarP(cmdFlags, &encryptionRecipientFiles, "recipients-file", "R", nil, "File of public or secret keys to encrypt the config secrets to (may be repeated).")
+	flags.BoolVarP(cmdFlags, &encryptionPassphrase, "passphrase", "p", false, "Ask for a passphrase which can also decrypt the config secrets.")
+}
+
+var configEncryptionCommand = &cobra.Command{
+	Use:   "encryption",
+	Short: `Show and change how the secrets in the config are encrypted.`,
+	Long: `
+With no subcommands this shows the public keys and whether a
+passphrase can decrypt the secrets in the config.
+
+See the [Configuration Encryption](/docs/#configuration-encryption)
+section for more info.
+`,
+	RunE: func(command *cobra.Command, args []string) error {
+		cmd.CheckArgs(0, 0, command, args)
+		recipients, passphrase, ok := config.ConfigRecipients()
+		if !ok {
+			fmt.Println("The secrets in the config aren't encrypted with keys or a passphrase.")
+			return nil
+		}
+		for _, recipient := range recipients {
+			fmt.Println(recipient)
+		}
+		if passphrase {
+			fmt.Println("passphrase")
+		}
+		return nil
+	},
+}
+
+var configEncryptionKeygenCommand = &cobra.Command{
+	Use:   "keygen [file]",
+	Short: `Make a new key file to encrypt the config with.`,
+	Long: `
+This makes a new secret key and writes it to the file given, or to
+standard output if none is given. The file must not already exist.
+
+The public key is printed on standard error. Pass it to "rclone config
+encryption set --recipient" to encrypt the config so the key file can
+decrypt it.
+
+    rclone config encryption keygen ~/.config/rclone/key.txt
+`,
+	RunE: func(command *cobra.Command, args []string) error {
+		cmd.CheckArgs(0, 1, command, args)
+		secretKey, publicKey, err := config.GenerateKey()
+		if err != nil {
+			return err
+		}
+		contents := fmt.Sprintf("# public key: %s\n%s\n", publicKey, secretKey)
+		if len(args) == 0 {
+			fmt.Print(contents)
+		} else {
+			out, err := os.OpenFile(args[0], os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+			if err != nil {
+				return errors.Wrap(err, "failed to create key file")
+			}
+			_, err = out.WriteString(contents)
+			closeErr := out.Close()
+			if err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return errors.Wrap(err, "failed to write key file")
+			}
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "Public key: %s\n", publicKey)
+		return nil
+	},
+}
+
+var configEncryptionSetCommand = &cobra.Command{
+	Use:   "set",
+	Short: `Encrypt the secrets in the config with keys or a passphrase.`,
+	Long: `
+This encrypts the passwords, tokens and other secrets in the config so
+they can be decrypted with the secret key of any of the recipients or
+the passphrase. The rest of the config is left readable.
+
+This replaces any existing encryption of the config, including the
+password protection set with "rclone config".
+
+    rclone config encryption set -r RCLONE-PUBLIC-KEY-... -R team-keys.txt --passphrase
+`,
+	RunE: func(command *cobra.Command, args []string) error {
+		cmd.CheckArgs(0, 0, command, args)
+		recipients := append([]string{}, encryptionRecipients...)
+		for _, recipientFile := range encryptionRecipientFiles {
+			in, err := os.Open(recipientFile)
+			if err != nil {
+				return errors.Wrap(err, "failed to open recipients file")
+			}
+			keys, err := config.ParseKeys(in)
+			_ = in.Close()
+			if err != nil {
+				return errors.Wrapf(err, "failed to read recipients file %q", recipientFile)
+			}
+			recipients = append(recipients, keys...)
+		}
+		passphrase := ""
+		if encryptionPassphrase {
+			passphrase = config.ChangePassword("configuration")
+		}
+		if len(recipients) == 0 && passphrase == "" {
+			return errors.New("need --recipient, --recipients-file or --passphrase")
+		}
+		return config.EncryptConfig(recipients, passphrase)
+	},
+}
+
+var configEncryptionRotateCommand = &cobra.Command{
+	Use:   "rotate",
+	Short: `Replace the key the secrets in the config are encrypted with.`,
+	Long: `
+This re-encrypts the secrets in the config with a new key which is
+wrapped for the same public keys and passphrase as before, without
+having to enter them again.
+
+Use this if a copy of the config or one of the key files may have
+leaked. To change the keys or passphrase which can decrypt the config
+use "rclone config encryption set" which makes a new key too.
+`,
+	RunE: func(command *cobra.Command, args []string) error {
+		cmd.CheckArgs(0, 0, command, args)
+		return config.RotateConfigKey()
+	},
+}
+
+var configEncryptionRemoveCommand = &cobra.Command{
+	Use:   "remove",
+	Short: `Remove the encryption from the config.`,
+	Long: `
+This decrypts the config and saves it without any encryption.
+`,
+	RunE: func(command *cobra.Command, args []string) error {
+		cmd.CheckArgs(0, 0, command, args)
+		config.DecryptConfig()
+		return nil
+	},
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/rclone/rclone/cmd"
+	synccmd "github.com/rclone/rclone/cmd/sync"
 	"github.com/rclone/rclone/fs/config/flags"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fs/sync"
@@ -18,6 +19,8 @@ func init() {
 	cmd.Root.AddCommand(commandDefinition)
 	cmdFlags := commandDefinition.Flags()
 	flags.BoolVarP(cmdFlags, &createEmptySrcDirs, "create-empty-src-dirs", "", createEmptySrcDirs, "Create empty source dirs on destination after copy")
+	synccmd.AddReportFlags(cmdFlags)
+	synccmd.AddPlanFlags(cmdFlags)
 }
 
 var commandDefinition = &cobra.Command{
@@ -74,13 +77,21 @@ recently very efficiently like this:
 **Note**: Use the ` + "`-P`" + `/` + "`--progress`" + ` flag to view real-time transfer statistics.
 
 **Note**: Use the ` + "`--dry-run` or the `--interactive`/`-i`" + ` flag to test without copying anything.
-`,
+` + synccmd.ReportHelp + synccmd.PlanHelp,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(2, 2, command, args)
 		fsrc, srcFileName, fdst := cmd.NewFsSrcFileDst(args)
+		synccmd.CheckPlanFlags(srcFileName)
 		cmd.Run(true, true, command, func() error {
 			if srcFileName == "" {
-				return sync.CopyDir(context.Background(), fdst, fsrc, createEmptySrcDirs)
+				ctx, close, err := synccmd.Context(context.Background())
+				if err != nil {
+					return err
+				}
+				defer close()
+				return synccmd.RunPlan(ctx, "copy", fdst, fsrc, func(ctx context.Context) error {
+					return sync.CopyDir(ctx, fdst, fsrc, createEmptySrcDirs)
+				})
 			}
 			return operations.CopyFile(context.Background(), fdst, fsrc, srcFileName, srcFileName)
 		})
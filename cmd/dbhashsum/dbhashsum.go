@@ -30,7 +30,7 @@ The output is in the same format as md5sum and sha1sum.
 		fsrc := cmd.NewFsSrc(args)
 		fs.Logf(nil, `"rclone dbhashsum" is deprecated, use "rclone hashsum %v %s" instead`, dropbox.DbHashType, args[0])
 		cmd.Run(false, false, command, func() error {
-			return operations.HashLister(context.Background(), dropbox.DbHashType, fsrc, os.Stdout)
+			return operations.HashLister(context.Background(), dropbox.DbHashType, false, false, fsrc, os.Stdout)
 		})
 	},
 }
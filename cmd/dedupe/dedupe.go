@@ -6,6 +6,7 @@ import (
 
 	"github.com/rclone/rclone/cmd"
 	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/spf13/cobra"
 )
@@ -13,6 +14,7 @@ import (
 var (
 	dedupeMode = operations.DeduplicateInteractive
 	byHash     = false
+	hashType   = hash.None
 )
 
 func init() {
@@ -20,6 +22,7 @@ func init() {
 	cmdFlag := commandDefinition.Flags()
 	flags.FVarP(cmdFlag, &dedupeMode, "dedupe-mode", "", "Dedupe mode interactive|skip|first|newest|oldest|largest|smallest|rename.")
 	flags.BoolVarP(cmdFlag, &byHash, "by-hash", "", false, "Find indentical hashes rather than names")
+	flags.FVarP(cmdFlag, &hashType, "hash", "", "Hash to compare files with, e.g. XXH64 (default the first the remote supports)")
 }
 
 var commandDefinition = &cobra.Command{
@@ -41,6 +44,11 @@ duplicate hashes instead which will work on any backend which supports
 at least one hash. This can be used to find files with duplicate
 content. This is known as deduping by hash.
 
+Files are compared using the first hash the backend supports unless
+another is chosen with the ` + "`--hash`" + ` flag, e.g. ` + "`--hash XXH64`" + `
+to use a fast non-cryptographic hash where the backend supports it,
+as the local backend does.
+
 If deduping by name, first rclone will merge directories with the same
 name.  It will do this iteratively until all the identically named
 directories have been merged.
@@ -144,7 +152,7 @@ Or
 		}
 		fdst := cmd.NewFsSrc(args)
 		cmd.Run(false, false, command, func() error {
-			return operations.Deduplicate(context.Background(), fdst, dedupeMode, byHash)
+			return operations.Deduplicate(context.Background(), fdst, dedupeMode, byHash, hashType)
 		})
 	},
 }
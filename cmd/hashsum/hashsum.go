@@ -2,11 +2,14 @@ package hashsum
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config/flags"
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/operations"
@@ -15,18 +18,42 @@ import (
 
 var (
 	outputBase64 = false
+	download     = false
+	outputFile   = ""
+	checkFile    = ""
 )
 
 func init() {
 	cmd.Root.AddCommand(commandDefinition)
 	cmdFlags := commandDefinition.Flags()
 	flags.BoolVarP(cmdFlags, &outputBase64, "base64", "", outputBase64, "Output base64 encoded hashsum")
+	flags.BoolVarP(cmdFlags, &download, "download", "", download, "Download the file and hash it locally; if this flag is not specified, the hash is requested from the remote")
+	flags.StringVarP(cmdFlags, &outputFile, "output-file", "", outputFile, "Output hashsums to a file rather than the terminal")
+	flags.StringVarP(cmdFlags, &checkFile, "checkfile", "C", checkFile, "Validate hashes against a given SUM file instead of printing them")
+}
+
+// GetHashOutput opens the file to write the hashes to, or returns
+// stdout if outputFile is empty
+func GetHashOutput(outputFile string) (out io.Writer, close func(), err error) {
+	if outputFile == "" {
+		return os.Stdout, func() {}, nil
+	}
+	file, err := os.Create(outputFile)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create output file")
+	}
+	close = func() {
+		if err := file.Close(); err != nil {
+			fs.Errorf(nil, "Failed to close output file: %v", err)
+		}
+	}
+	return file, close, nil
 }
 
 var commandDefinition = &cobra.Command{
 	Use:   "hashsum <hash> remote:path",
 	Short: `Produces a hashsum file for all the objects in the path.`,
-	Long: `
+	Long: strings.Replace(`
 Produces a hash file for all the objects in the path using the hash
 named.  The output is in the same format as the standard
 md5sum/sha1sum tool.
@@ -37,13 +64,35 @@ Run without a hash to see the list of supported hashes, e.g.
     Supported hashes are:
       * MD5
       * SHA-1
+      * Whirlpool
+      * CRC-32
+      * SHA-256
+      * BLAKE3
+      * XXH64
+      * CRC-32C
       * DropboxHash
+      * MailruHash
       * QuickXorHash
 
 Then
 
     $ rclone hashsum MD5 remote:path
-`,
+
+Use |--output-file| to write the hashes to a file which can be
+checked later with |rclone checksum| or |rclone check --checkfile|,
+or with |--checkfile| on this command. The hashes are written base64
+encoded with |--base64| and are calculated by reading the files with
+|--download|, which works with any remote, even one that doesn't
+support the hash.
+
+Use |--checkfile| to verify the files against a SUM file, for
+example
+
+    $ rclone hashsum MD5 --checkfile remote:MD5SUMS remote:path
+
+This is the same as |rclone checksum MD5 remote:MD5SUMS remote:path|
+without its reporting flags.
+`, "|", "`", -1),
 	RunE: func(command *cobra.Command, args []string) error {
 		cmd.CheckArgs(0, 2, command, args)
 		if len(args) == 0 {
@@ -61,11 +110,23 @@ Then
 			return err
 		}
 		fsrc := cmd.NewFsSrc(args[1:])
+		if checkFile != "" {
+			fsum, sumFile := cmd.NewFsFile(checkFile)
+			if sumFile == "" {
+				return errors.New("--checkfile must point to a file")
+			}
+			cmd.Run(false, true, command, func() error {
+				return operations.CheckSum(context.Background(), fsrc, fsum, sumFile, ht, &operations.CheckOpt{}, download)
+			})
+			return nil
+		}
 		cmd.Run(false, false, command, func() error {
-			if outputBase64 {
-				return operations.HashListerBase64(context.Background(), ht, fsrc, os.Stdout)
+			output, close, err := GetHashOutput(outputFile)
+			if err != nil {
+				return err
 			}
-			return operations.HashLister(context.Background(), ht, fsrc, os.Stdout)
+			defer close()
+			return operations.HashLister(context.Background(), ht, outputBase64, download, fsrc, output)
 		})
 		return nil
 	},
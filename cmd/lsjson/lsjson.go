@@ -25,11 +25,12 @@ func init() {
 	flags.BoolVarP(cmdFlags, &opt.ShowHash, "hash", "", false, "Include hashes in the output (may take longer).")
 	flags.BoolVarP(cmdFlags, &opt.NoModTime, "no-modtime", "", false, "Don't read the modification time (can speed things up).")
 	flags.BoolVarP(cmdFlags, &opt.NoMimeType, "no-mimetype", "", false, "Don't read the mime type (can speed things up).")
-	flags.BoolVarP(cmdFlags, &opt.ShowEncrypted, "encrypted", "M", false, "Show the encrypted names.")
+	flags.BoolVarP(cmdFlags, &opt.ShowEncrypted, "encrypted", "", false, "Show the encrypted names.")
 	flags.BoolVarP(cmdFlags, &opt.ShowOrigIDs, "original", "", false, "Show the ID of the underlying Object.")
 	flags.BoolVarP(cmdFlags, &opt.FilesOnly, "files-only", "", false, "Show only files in the listing.")
 	flags.BoolVarP(cmdFlags, &opt.DirsOnly, "dirs-only", "", false, "Show only directories in the listing.")
 	flags.StringArrayVarP(cmdFlags, &opt.HashTypes, "hash-type", "", nil, "Show only this hash type (may be repeated).")
+	flags.BoolVarP(cmdFlags, &opt.Metadata, "metadata", "M", false, "Add metadata to the listing.")
 }
 
 var commandDefinition = &cobra.Command{
@@ -57,6 +58,10 @@ The output is an array of Items, where each Item looks like this
       "Path" : "full/path/goes/here/file.txt",
       "Size" : 6,
       "Tier" : "hot",
+      "Metadata" : {
+         "content-type" : "text/plain",
+         "mtime" : "2017-05-31T16:15:57.034468261+01:00"
+      }
    }
 
 If --hash is not specified the Hashes property won't be emitted. The
@@ -71,7 +76,12 @@ If --no-mimetype is specified then MimeType will be blank. This can
 speed things up on remotes where reading the MimeType takes an extra
 request (e.g. s3, swift).
 
-If --encrypted is not specified the Encrypted won't be emitted.
+If --encrypted is not specified the Encrypted won't be emitted. Note
+that -M is now short for --metadata rather than --encrypted.
+
+If --metadata (or -M) is specified then the Metadata property will be
+emitted for objects whose backend supports reading metadata.  This
+may take an extra request per object.
 
 If --dirs-only is not specified files in addition to directories are
 returned
@@ -42,6 +42,32 @@ func AddRc(mountUtilName string, mountFunction MountFn) {
 	mountFns[mountUtilName] = mountFunction
 }
 
+// resolveMountMethod returns the mount function for mountType, or
+// the preferred mount function if mountType is empty. It returns a
+// nil MountFn if no suitable mount function is registered.
+//
+// Call with mountMu held
+func resolveMountMethod(mountType string) (string, MountFn) {
+	if mountType != "" {
+		return mountType, mountFns[mountType]
+	}
+	for _, mountType := range []string{"mount", "cmount", "mount2"} {
+		if mountFn := mountFns[mountType]; mountFn != nil {
+			return mountType, mountFn
+		}
+	}
+	return "", nil
+}
+
+// ResolveMountMethod returns the mount function for mountType, or
+// the preferred mount function if mountType is empty. It returns a
+// nil MountFn if no suitable mount function is registered.
+func ResolveMountMethod(mountType string) (string, MountFn) {
+	mountMu.Lock()
+	defer mountMu.Unlock()
+	return resolveMountMethod(mountType)
+}
+
 func init() {
 	rc.Add(rc.Call{
 		Path:         "mount/mount",
@@ -95,19 +121,14 @@ func mountRc(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 	}
 
 	mountType, err := in.GetString("mountType")
+	if err != nil {
+		mountType = ""
+	}
 
 	mountMu.Lock()
 	defer mountMu.Unlock()
 
-	if err != nil || mountType == "" {
-		if mountFns["mount"] != nil {
-			mountType = "mount"
-		} else if mountFns["cmount"] != nil {
-			mountType = "cmount"
-		} else if mountFns["mount2"] != nil {
-			mountType = "mount2"
-		}
-	}
+	mountType, mountFn := resolveMountMethod(mountType)
 
 	// Get Fs.fs to be mounted from fs parameter in the params
 	fdst, err := rc.GetFs(ctx, in)
@@ -115,9 +136,9 @@ func mountRc(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 		return nil, err
 	}
 
-	if mountFns[mountType] != nil {
+	if mountFn != nil {
 		VFS := vfs.New(fdst, &vfsOpt)
-		_, unmountFn, err := mountFns[mountType](VFS, mountPoint, &mountOpt)
+		_, unmountFn, err := mountFn(VFS, mountPoint, &mountOpt)
 
 		if err != nil {
 			log.Printf("mount FAILED: %v", err)
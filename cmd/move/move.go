@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/rclone/rclone/cmd"
+	synccmd "github.com/rclone/rclone/cmd/sync"
 	"github.com/rclone/rclone/fs/config/flags"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fs/sync"
@@ -21,6 +22,8 @@ func init() {
 	cmdFlags := commandDefinition.Flags()
 	flags.BoolVarP(cmdFlags, &deleteEmptySrcDirs, "delete-empty-src-dirs", "", deleteEmptySrcDirs, "Delete empty source dirs after move")
 	flags.BoolVarP(cmdFlags, &createEmptySrcDirs, "create-empty-src-dirs", "", createEmptySrcDirs, "Create empty source dirs on destination after move")
+	synccmd.AddReportFlags(cmdFlags)
+	synccmd.AddPlanFlags(cmdFlags)
 }
 
 var commandDefinition = &cobra.Command{
@@ -52,13 +55,21 @@ can speed transfers up greatly.
 ` + "`--dry-run` or the `--interactive`/`-i`" + ` flag.
 
 **Note**: Use the ` + "`-P`" + `/` + "`--progress`" + ` flag to view real-time transfer statistics.
-`,
+` + synccmd.ReportHelp + synccmd.PlanHelp,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(2, 2, command, args)
 		fsrc, srcFileName, fdst := cmd.NewFsSrcFileDst(args)
+		synccmd.CheckPlanFlags(srcFileName)
 		cmd.Run(true, true, command, func() error {
 			if srcFileName == "" {
-				return sync.MoveDir(context.Background(), fdst, fsrc, deleteEmptySrcDirs, createEmptySrcDirs)
+				ctx, close, err := synccmd.Context(context.Background())
+				if err != nil {
+					return err
+				}
+				defer close()
+				return synccmd.RunPlan(ctx, "move", fdst, fsrc, func(ctx context.Context) error {
+					return sync.MoveDir(ctx, fdst, fsrc, deleteEmptySrcDirs, createEmptySrcDirs)
+				})
 			}
 			return operations.MoveFile(context.Background(), fdst, fsrc, srcFileName, srcFileName)
 		})
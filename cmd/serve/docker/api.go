@@ -0,0 +1,228 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+)
+
+// contentType is the content type of the docker plugin protocol
+const contentType = "application/vnd.docker.plugins.v1.1+json"
+
+// VolInfo describes a volume in the responses to docker
+type VolInfo struct {
+	Name       string
+	Mountpoint string                 `json:",omitempty"`
+	CreatedAt  string                 `json:",omitempty"`
+	Status     map[string]interface{} `json:",omitempty"`
+}
+
+// Requests and responses of the volume plugin protocol
+type (
+	activateResponse struct {
+		Implements []string
+	}
+	createRequest struct {
+		Name string
+		Opts map[string]string `json:",omitempty"`
+	}
+	nameRequest struct {
+		Name string
+	}
+	mountRequest struct {
+		Name string
+		ID   string
+	}
+	mountResponse struct {
+		Mountpoint string
+	}
+	getResponse struct {
+		Volume *VolInfo
+	}
+	listResponse struct {
+		Volumes []*VolInfo
+	}
+	capabilitiesResponse struct {
+		Capabilities struct {
+			Scope string
+		}
+	}
+	errorResponse struct {
+		Err string
+	}
+)
+
+// Server serves the docker volume plugin protocol for a Driver
+type Server struct {
+	drv *Driver
+	srv *http.Server
+}
+
+// NewServer makes a server for drv
+func NewServer(drv *Driver) *Server {
+	s := &Server{drv: drv}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/Plugin.Activate", s.activate)
+	mux.HandleFunc("/VolumeDriver.Create", s.create)
+	mux.HandleFunc("/VolumeDriver.Remove", s.remove)
+	mux.HandleFunc("/VolumeDriver.Mount", s.mount)
+	mux.HandleFunc("/VolumeDriver.Unmount", s.unmount)
+	mux.HandleFunc("/VolumeDriver.Path", s.path)
+	mux.HandleFunc("/VolumeDriver.Get", s.get)
+	mux.HandleFunc("/VolumeDriver.List", s.list)
+	mux.HandleFunc("/VolumeDriver.Capabilities", s.capabilities)
+	s.srv = &http.Server{Handler: mux}
+	return s
+}
+
+// Serve serves requests on listener until the server is shut down
+func (s *Server) Serve(listener net.Listener) error {
+	err := s.srv.Serve(listener)
+	if err == http.ErrServerClosed {
+		err = nil
+	}
+	return err
+}
+
+// ServeTCP serves requests on the TCP address addr.
+//
+// Unless specDir is empty a spec file is written there so docker can
+// find the plugin. It is removed when the server is shut down.
+func (s *Server) ServeTCP(addr, specDir string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	if specDir != "" {
+		specPath := filepath.Join(specDir, pluginName+".spec")
+		if err = writeSpecFile(specPath, "tcp://"+listener.Addr().String()); err != nil {
+			_ = listener.Close()
+			return err
+		}
+		defer func() {
+			_ = os.Remove(specPath)
+		}()
+	}
+	fs.Logf(nil, "Serving docker volume plugin on tcp://%s", listener.Addr())
+	return s.Serve(listener)
+}
+
+// writeSpecFile writes a spec file at specPath pointing at url
+func writeSpecFile(specPath, url string) error {
+	if err := os.MkdirAll(filepath.Dir(specPath), 0755); err != nil {
+		return errors.Wrap(err, "failed to make spec directory")
+	}
+	if err := ioutil.WriteFile(specPath, []byte(url+"\n"), 0644); err != nil {
+		return errors.Wrap(err, "failed to write spec file")
+	}
+	return nil
+}
+
+// Shutdown stops the server
+func (s *Server) Shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return s.srv.Shutdown(ctx)
+}
+
+// decode reads the JSON request into in, writing an error response
+// and returning false on failure
+func decode(w http.ResponseWriter, r *http.Request, in interface{}) bool {
+	err := json.NewDecoder(r.Body).Decode(in)
+	if err != nil {
+		writeError(w, errors.Wrap(err, "failed to decode request"))
+		return false
+	}
+	return true
+}
+
+// writeResponse writes out as the JSON response
+func writeResponse(w http.ResponseWriter, status int, out interface{}) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		fs.Errorf(nil, "docker plugin: failed to write response: %v", err)
+	}
+}
+
+// writeError writes err as the response
+func writeError(w http.ResponseWriter, err error) {
+	fs.Errorf(nil, "docker plugin: %v", err)
+	writeResponse(w, http.StatusInternalServerError, &errorResponse{Err: err.Error()})
+}
+
+// writeResult writes out as the response or err if it is set
+func writeResult(w http.ResponseWriter, out interface{}, err error) {
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, out)
+}
+
+func (s *Server) activate(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, http.StatusOK, &activateResponse{Implements: []string{"VolumeDriver"}})
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	var in createRequest
+	if decode(w, r, &in) {
+		writeResult(w, &errorResponse{}, s.drv.Create(in.Name, in.Opts))
+	}
+}
+
+func (s *Server) remove(w http.ResponseWriter, r *http.Request) {
+	var in nameRequest
+	if decode(w, r, &in) {
+		writeResult(w, &errorResponse{}, s.drv.Remove(in.Name))
+	}
+}
+
+func (s *Server) mount(w http.ResponseWriter, r *http.Request) {
+	var in mountRequest
+	if decode(w, r, &in) {
+		mountPoint, err := s.drv.Mount(in.Name, in.ID)
+		writeResult(w, &mountResponse{Mountpoint: mountPoint}, err)
+	}
+}
+
+func (s *Server) unmount(w http.ResponseWriter, r *http.Request) {
+	var in mountRequest
+	if decode(w, r, &in) {
+		writeResult(w, &errorResponse{}, s.drv.Unmount(in.Name, in.ID))
+	}
+}
+
+func (s *Server) path(w http.ResponseWriter, r *http.Request) {
+	var in nameRequest
+	if decode(w, r, &in) {
+		mountPoint, err := s.drv.Path(in.Name)
+		writeResult(w, &mountResponse{Mountpoint: mountPoint}, err)
+	}
+}
+
+func (s *Server) get(w http.ResponseWriter, r *http.Request) {
+	var in nameRequest
+	if decode(w, r, &in) {
+		info, err := s.drv.Get(in.Name)
+		writeResult(w, &getResponse{Volume: info}, err)
+	}
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, http.StatusOK, &listResponse{Volumes: s.drv.List()})
+}
+
+func (s *Server) capabilities(w http.ResponseWriter, r *http.Request) {
+	var out capabilitiesResponse
+	out.Capabilities.Scope = "local"
+	writeResponse(w, http.StatusOK, &out)
+}
@@ -0,0 +1,99 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-docker-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	drv := newTestDriver(t, dir, true)
+	defer drv.Exit()
+	ts := httptest.NewServer(NewServer(drv).srv.Handler)
+	defer ts.Close()
+
+	// call does a request returning the status and decoded response
+	call := func(method string, in interface{}) (int, map[string]interface{}) {
+		body, err := json.Marshal(in)
+		require.NoError(t, err)
+		resp, err := http.Post(ts.URL+"/"+method, contentType, bytes.NewReader(body))
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, resp.Body.Close())
+		}()
+		assert.Equal(t, contentType, resp.Header.Get("Content-Type"))
+		var out map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		return resp.StatusCode, out
+	}
+	mountPoint := filepath.Join(dir, "mnt", "vol")
+
+	status, out := call("Plugin.Activate", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []interface{}{"VolumeDriver"}, out["Implements"])
+
+	status, out = call("VolumeDriver.Capabilities", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]interface{}{"Scope": "local"}, out["Capabilities"])
+
+	status, out = call("VolumeDriver.Create", map[string]interface{}{
+		"Name": "vol",
+		"Opts": map[string]string{"remote": dir, "mount-type": testMountType},
+	})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "", out["Err"])
+
+	status, out = call("VolumeDriver.Create", map[string]interface{}{"Name": "bad"})
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Contains(t, out["Err"], `needs a "remote" or "type" option`)
+
+	status, out = call("VolumeDriver.List", nil)
+	assert.Equal(t, http.StatusOK, status)
+	volumes := out["Volumes"].([]interface{})
+	require.Equal(t, 1, len(volumes))
+	assert.Equal(t, "vol", volumes[0].(map[string]interface{})["Name"])
+
+	status, out = call("VolumeDriver.Mount", map[string]string{"Name": "vol", "ID": "id1"})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, mountPoint, out["Mountpoint"])
+	assert.True(t, isMounted(mountPoint))
+
+	status, out = call("VolumeDriver.Path", map[string]string{"Name": "vol"})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, mountPoint, out["Mountpoint"])
+
+	status, out = call("VolumeDriver.Get", map[string]string{"Name": "vol"})
+	assert.Equal(t, http.StatusOK, status)
+	volume := out["Volume"].(map[string]interface{})
+	assert.Equal(t, "vol", volume["Name"])
+	assert.Equal(t, mountPoint, volume["Mountpoint"])
+	assert.Equal(t, dir, volume["Status"].(map[string]interface{})["Fs"])
+
+	status, out = call("VolumeDriver.Remove", map[string]string{"Name": "vol"})
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Contains(t, out["Err"], "in use")
+
+	status, _ = call("VolumeDriver.Unmount", map[string]string{"Name": "vol", "ID": "id1"})
+	assert.Equal(t, http.StatusOK, status)
+	assert.False(t, isMounted(mountPoint))
+
+	status, _ = call("VolumeDriver.Remove", map[string]string{"Name": "vol"})
+	assert.Equal(t, http.StatusOK, status)
+
+	status, out = call("VolumeDriver.Get", map[string]string{"Name": "vol"})
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Contains(t, out["Err"], "not found")
+}
@@ -0,0 +1,137 @@
+// Package docker serves remotes as docker volumes using the docker
+// volume plugin protocol
+package docker
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/cmd/mountlib"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/lib/atexit"
+	"github.com/rclone/rclone/vfs"
+	"github.com/rclone/rclone/vfs/vfsflags"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	pluginName = "rclone"
+	sockDir    = "/run/docker/plugins" // where docker looks for plugin sockets
+	specDir    = "/etc/docker/plugins" // where docker looks for plugin spec files
+)
+
+// Options contains options for the docker volume plugin
+type Options struct {
+	BaseDir     string
+	SocketAddr  string
+	SocketGID   int
+	ForgetState bool
+	NoSpec      bool
+}
+
+// DefaultOpt is the default values used for Options
+var DefaultOpt = Options{
+	BaseDir:   "/var/lib/docker-volumes/rclone",
+	SocketGID: -1,
+}
+
+// Opt is options set by command line flags
+var Opt = DefaultOpt
+
+// AddFlags adds flags for the docker volume plugin
+func AddFlags(flagSet *pflag.FlagSet) {
+	flags.StringVarP(flagSet, &Opt.BaseDir, "base-dir", "", Opt.BaseDir, "Base directory for the volume mount points.")
+	flags.StringVarP(flagSet, &Opt.SocketAddr, "socket-addr", "", Opt.SocketAddr, "Address <host:port> to serve on instead of the unix socket.")
+	flags.IntVarP(flagSet, &Opt.SocketGID, "socket-gid", "", Opt.SocketGID, "Group ID to give access to the unix socket (-1 to leave unchanged).")
+	flags.BoolVarP(flagSet, &Opt.ForgetState, "forget-state", "", Opt.ForgetState, "Forget the volumes saved by a previous run on start.")
+	flags.BoolVarP(flagSet, &Opt.NoSpec, "no-spec", "", Opt.NoSpec, "Don't write a spec file when serving on --socket-addr.")
+}
+
+func init() {
+	flagSet := Command.Flags()
+	mountlib.AddFlags(flagSet)
+	vfsflags.AddFlags(flagSet)
+	AddFlags(flagSet)
+}
+
+// Command definition for cobra
+var Command = &cobra.Command{
+	Use:   "docker",
+	Short: `Serve any remote on docker's volume plugin API.`,
+	Long: `
+rclone serve docker implements the docker volume plugin API, allowing
+docker to use any remote rclone supports as a volume. Each volume is
+an rclone mount, so one of the rclone mount commands must be
+supported on the host.
+
+By default the plugin listens on the unix socket
+` + "`" + sockDir + "/" + pluginName + ".sock`" + ` where docker finds it
+automatically. Use ` + "`--socket-gid`" + ` to allow a group other than the
+one running rclone to use the socket. Alternatively use
+` + "`--socket-addr host:port`" + ` to serve over TCP, in which case rclone
+writes a spec file to ` + "`" + specDir + "`" + ` so docker can find the
+plugin, unless ` + "`--no-spec`" + ` is given.
+
+The plugin needs to run as root (or with enough privileges to use
+FUSE and write the sockets above), for example
+
+    sudo rclone serve docker --base-dir /var/lib/docker-volumes/rclone
+
+Volumes are created with the driver options saying what to mount
+
+    docker volume create my_vol -d rclone -o remote=gdrive:backups -o vfs-cache-mode=full
+    docker run --rm -it -v my_vol:/data alpine ls /data
+
+The driver options are
+
+- ` + "`remote`" + ` (or ` + "`fs`" + `) - the remote to mount, e.g. ` + "`gdrive:backups`" + `
+- ` + "`type`" + ` - instead of ` + "`remote`" + `, the type of backend to make an on the fly remote with
+- ` + "`path`" + ` - a path within the remote to mount
+- ` + "`mount-type`" + ` - the mount implementation to use, ` + "`mount`" + `, ` + "`cmount`" + ` or ` + "`mount2`" + `
+- any of the mount and VFS flags below without the leading ` + "`--`" + `,
+  e.g. ` + "`allow-other`" + `, ` + "`dir-cache-time=1h`" + ` or ` + "`vfs-cache-mode=writes`" + `
+- anything else is passed to the backend as a config option using
+  the name from the config file, e.g. ` + "`-o type=sftp -o host=example.com -o user=me`" + `
+
+The mount and VFS flags given to ` + "`rclone serve docker`" + ` are the
+defaults for all volumes.
+
+A volume is mounted in a directory named after it in ` + "`--base-dir`" + `
+when the first container using it starts, and unmounted when the last
+one stops. The volumes and the containers using them are saved in
+the rclone cache directory, so when the plugin restarts it recreates
+the volumes and remounts those which are in use. Use
+` + "`--forget-state`" + ` to start with no volumes instead.
+
+Removing a volume with ` + "`docker volume rm`" + ` unmounts it and removes
+its mount point. A volume can't be removed while a container is
+using it.
+
+` + vfs.Help,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(0, 0, command, args)
+		cmd.Run(false, false, command, func() error {
+			drv, err := NewDriver(context.Background(), Opt.BaseDir, config.CacheDir, &mountlib.Opt, &vfsflags.Opt, Opt.ForgetState)
+			if err != nil {
+				return err
+			}
+			defer drv.Exit()
+			srv := NewServer(drv)
+			atexit.Register(func() {
+				_ = srv.Shutdown()
+				drv.Exit()
+			})
+			if Opt.SocketAddr == "" {
+				return srv.ServeUnix(filepath.Join(sockDir, pluginName+".sock"), Opt.SocketGID)
+			}
+			spec := specDir
+			if Opt.NoSpec {
+				spec = ""
+			}
+			return srv.ServeTCP(Opt.SocketAddr, spec)
+		})
+	},
+}
@@ -0,0 +1,268 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/cmd/mountlib"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs/vfscommon"
+)
+
+// stateFile is the name of the file in the cache directory which
+// persists the volumes across restarts
+const stateFile = "docker-plugin.state"
+
+// Driver implements the docker volume plugin API using rclone mounts
+type Driver struct {
+	ctx       context.Context
+	root      string             // directory holding the mount points
+	statePath string             // file holding the state of the volumes
+	mntOpt    *mountlib.Options  // default mount options
+	vfsOpt    *vfscommon.Options // default VFS options
+	mu        sync.Mutex         // protects the following
+	volumes   map[string]*Volume
+}
+
+// NewDriver makes a driver which mounts volumes in root and keeps its
+// state in stateDir.
+//
+// The volumes and their mounts are restored from the state file
+// unless forgetState is set.
+func NewDriver(ctx context.Context, root, stateDir string, mntOpt *mountlib.Options, vfsOpt *vfscommon.Options, forgetState bool) (*Driver, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to make base directory")
+	}
+	if err := os.MkdirAll(stateDir, 0700); err != nil {
+		return nil, errors.Wrap(err, "failed to make state directory")
+	}
+	drv := &Driver{
+		ctx:       ctx,
+		root:      root,
+		statePath: filepath.Join(stateDir, stateFile),
+		mntOpt:    mntOpt,
+		vfsOpt:    vfsOpt,
+		volumes:   map[string]*Volume{},
+	}
+	if forgetState {
+		if err := os.Remove(drv.statePath); err != nil && !os.IsNotExist(err) {
+			return nil, errors.Wrap(err, "failed to remove state file")
+		}
+		return drv, nil
+	}
+	if err := drv.restoreState(); err != nil {
+		return nil, err
+	}
+	return drv, nil
+}
+
+// mountPath returns the mount point for the volume called name
+func (drv *Driver) mountPath(name string) string {
+	return filepath.Join(drv.root, name)
+}
+
+// checkName checks the volume name is usable as a directory name
+func checkName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return errors.Errorf("invalid volume name %q", name)
+	}
+	return nil
+}
+
+// getVolume finds the volume called name
+//
+// Call with drv.mu held
+func (drv *Driver) getVolume(name string) (*Volume, error) {
+	vol, ok := drv.volumes[name]
+	if !ok {
+		return nil, errors.Errorf("volume %q not found", name)
+	}
+	return vol, nil
+}
+
+// Create makes a new volume called name with the driver options opts
+func (drv *Driver) Create(name string, opts map[string]string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	drv.mu.Lock()
+	defer drv.mu.Unlock()
+	if _, ok := drv.volumes[name]; ok {
+		return errors.Errorf("volume %q already exists", name)
+	}
+	vol, err := newVolume(drv, name, opts)
+	if err != nil {
+		return err
+	}
+	drv.volumes[name] = vol
+	fs.Infof(nil, "Volume %q: created for %q", name, vol.opt.fsString)
+	return drv.saveState()
+}
+
+// Remove removes the volume called name which must not be in use
+func (drv *Driver) Remove(name string) error {
+	drv.mu.Lock()
+	defer drv.mu.Unlock()
+	vol, err := drv.getVolume(name)
+	if err != nil {
+		return err
+	}
+	if len(vol.Mounts) != 0 {
+		return errors.Errorf("volume %q is in use", name)
+	}
+	if err = vol.unmount(); err != nil {
+		return err
+	}
+	if err = os.Remove(vol.MountPoint); err != nil && !os.IsNotExist(err) {
+		fs.Errorf(nil, "Volume %q: failed to remove mount point: %v", name, err)
+	}
+	delete(drv.volumes, name)
+	fs.Infof(nil, "Volume %q: removed", name)
+	return drv.saveState()
+}
+
+// Mount mounts the volume called name for the mount request id,
+// returning the mount point
+func (drv *Driver) Mount(name, id string) (string, error) {
+	drv.mu.Lock()
+	defer drv.mu.Unlock()
+	vol, err := drv.getVolume(name)
+	if err != nil {
+		return "", err
+	}
+	if err = vol.addMount(drv.ctx, id); err != nil {
+		return "", err
+	}
+	return vol.MountPoint, drv.saveState()
+}
+
+// Unmount releases the volume called name for the mount request id,
+// unmounting it if it is no longer in use
+func (drv *Driver) Unmount(name, id string) error {
+	drv.mu.Lock()
+	defer drv.mu.Unlock()
+	vol, err := drv.getVolume(name)
+	if err != nil {
+		return err
+	}
+	if err = vol.removeMount(id); err != nil {
+		return err
+	}
+	return drv.saveState()
+}
+
+// Path returns the mount point of the volume called name
+func (drv *Driver) Path(name string) (string, error) {
+	drv.mu.Lock()
+	defer drv.mu.Unlock()
+	vol, err := drv.getVolume(name)
+	if err != nil {
+		return "", err
+	}
+	return vol.MountPoint, nil
+}
+
+// Get returns information about the volume called name
+func (drv *Driver) Get(name string) (*VolInfo, error) {
+	drv.mu.Lock()
+	defer drv.mu.Unlock()
+	vol, err := drv.getVolume(name)
+	if err != nil {
+		return nil, err
+	}
+	return vol.info(true), nil
+}
+
+// List returns information about all the volumes
+func (drv *Driver) List() []*VolInfo {
+	drv.mu.Lock()
+	defer drv.mu.Unlock()
+	infos := make([]*VolInfo, 0, len(drv.volumes))
+	for _, vol := range drv.volumes {
+		infos = append(infos, vol.info(false))
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// Exit unmounts all the volumes.
+//
+// The state file still records the mount requests so the volumes
+// will be mounted again when the driver restarts.
+func (drv *Driver) Exit() {
+	drv.mu.Lock()
+	defer drv.mu.Unlock()
+	for _, vol := range drv.volumes {
+		if err := vol.unmount(); err != nil {
+			fs.Errorf(nil, "%v", err)
+		}
+	}
+}
+
+// saveState writes the volumes to the state file
+//
+// Call with drv.mu held
+func (drv *Driver) saveState() error {
+	vols := make([]*Volume, 0, len(drv.volumes))
+	for _, vol := range drv.volumes {
+		vols = append(vols, vol)
+	}
+	sort.Slice(vols, func(i, j int) bool {
+		return vols[i].Name < vols[j].Name
+	})
+	data, err := json.MarshalIndent(vols, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode state")
+	}
+	// The options may contain credentials so keep them private
+	tmpPath := drv.statePath + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, data, 0600); err != nil {
+		return errors.Wrap(err, "failed to write state")
+	}
+	if err = os.Rename(tmpPath, drv.statePath); err != nil {
+		return errors.Wrap(err, "failed to write state")
+	}
+	return nil
+}
+
+// restoreState reads the volumes from the state file, mounting those
+// which were in use
+func (drv *Driver) restoreState() error {
+	data, err := ioutil.ReadFile(drv.statePath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "failed to read state")
+	}
+	var vols []*Volume
+	if err = json.Unmarshal(data, &vols); err != nil {
+		return errors.Wrapf(err, "failed to decode state file %q", drv.statePath)
+	}
+	drv.mu.Lock()
+	defer drv.mu.Unlock()
+	for _, vol := range vols {
+		if err := vol.init(drv); err != nil {
+			fs.Errorf(nil, "Volume %q: dropping as failed to restore: %v", vol.Name, err)
+			continue
+		}
+		vol.MountPoint = drv.mountPath(vol.Name)
+		drv.volumes[vol.Name] = vol
+		if len(vol.Mounts) == 0 {
+			continue
+		}
+		if err := vol.mount(drv.ctx); err != nil {
+			fs.Errorf(nil, "Volume %q: failed to remount: %v", vol.Name, err)
+			vol.Mounts = nil
+		}
+	}
+	return drv.saveState()
+}
@@ -0,0 +1,218 @@
+package docker
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/cmd/mountlib"
+	"github.com/rclone/rclone/vfs"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMountType = "docker-test"
+
+// testMounts records the mounts made by testMount
+var testMounts = struct {
+	mu     sync.Mutex
+	mounts map[string]chan error // mount point to error channel
+}{
+	mounts: map[string]chan error{},
+}
+
+// testMount pretends to mount VFS on mountpoint
+func testMount(VFS *vfs.VFS, mountpoint string, opt *mountlib.Options) (<-chan error, func() error, error) {
+	testMounts.mu.Lock()
+	defer testMounts.mu.Unlock()
+	if _, found := testMounts.mounts[mountpoint]; found {
+		return nil, nil, os.ErrExist
+	}
+	errChan := make(chan error, 1)
+	testMounts.mounts[mountpoint] = errChan
+	unmount := func() error {
+		testMounts.mu.Lock()
+		defer testMounts.mu.Unlock()
+		delete(testMounts.mounts, mountpoint)
+		VFS.Shutdown()
+		errChan <- nil
+		return nil
+	}
+	return errChan, unmount, nil
+}
+
+func init() {
+	mountlib.AddRc(testMountType, testMount)
+}
+
+// isMounted returns true if mountpoint is mounted by testMount
+func isMounted(mountpoint string) bool {
+	testMounts.mu.Lock()
+	defer testMounts.mu.Unlock()
+	_, found := testMounts.mounts[mountpoint]
+	return found
+}
+
+// newTestDriver makes a driver with its directories in dir
+func newTestDriver(t *testing.T, dir string, forgetState bool) *Driver {
+	mntOpt := mountlib.DefaultOpt
+	vfsOpt := vfscommon.DefaultOpt
+	drv, err := NewDriver(context.Background(), filepath.Join(dir, "mnt"), filepath.Join(dir, "state"), &mntOpt, &vfsOpt, forgetState)
+	require.NoError(t, err)
+	return drv
+}
+
+func TestParseOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-docker-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	drv := newTestDriver(t, dir, true)
+
+	vo, err := parseOptions(drv, map[string]string{
+		"remote":         dir,
+		"path":           "sub/dir",
+		"allow-other":    "",
+		"attr_timeout":   "5s",
+		"option":         "foo,bar=baz",
+		"vfs-cache-mode": "writes",
+		"read-only":      "true",
+		"dir-perms":      "755",
+		"uid":            "1000",
+		"links":          "true",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, dir+"/sub/dir", vo.fsString)
+	assert.Equal(t, "local", vo.fsInfo.Name)
+	assert.True(t, vo.mntOpt.AllowOther)
+	assert.Equal(t, 5*time.Second, vo.mntOpt.AttrTimeout)
+	assert.Equal(t, []string{"foo", "bar=baz"}, vo.mntOpt.ExtraOptions)
+	assert.Equal(t, vfscommon.CacheModeWrites, vo.vfsOpt.CacheMode)
+	assert.True(t, vo.vfsOpt.ReadOnly)
+	assert.Equal(t, os.FileMode(0755), vo.vfsOpt.DirPerms)
+	assert.Equal(t, uint32(1000), vo.vfsOpt.UID)
+	assert.Equal(t, "true", vo.fsOpt["links"])
+
+	// Defaults come from the driver and aren't changed
+	assert.False(t, drv.mntOpt.AllowOther)
+	assert.Equal(t, vfscommon.CacheModeOff, drv.vfsOpt.CacheMode)
+
+	vo, err = parseOptions(drv, map[string]string{"type": "local", "path": dir})
+	require.NoError(t, err)
+	assert.Equal(t, ":local:"+dir, vo.fsString)
+
+	for _, opts := range []map[string]string{
+		{},
+		{"remote": dir, "type": "local"},
+		{"remote": dir, "allow-other": "potato"},
+		{"remote": dir, "vfs-cache-mode": "potato"},
+		{"remote": dir, "potato": "true"},
+		{"remote": "notfoundremote:"},
+	} {
+		_, err = parseOptions(drv, opts)
+		assert.Error(t, err, opts)
+	}
+}
+
+func TestDriver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-docker-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	remote := filepath.Join(dir, "remote")
+	require.NoError(t, os.Mkdir(remote, 0777))
+	drv := newTestDriver(t, dir, false)
+	opts := map[string]string{"remote": remote, "mount-type": testMountType}
+
+	// Create
+	require.NoError(t, drv.Create("vol", opts))
+	assert.Error(t, drv.Create("vol", opts))
+	assert.Error(t, drv.Create("../vol", opts))
+	assert.Error(t, drv.Create("bad", map[string]string{"remote": remote, "potato": "1"}))
+	mountPoint, err := drv.Path("vol")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "mnt", "vol"), mountPoint)
+	_, err = drv.Path("missing")
+	assert.Error(t, err)
+
+	// Mount twice then unmount twice
+	got, err := drv.Mount("vol", "id1")
+	require.NoError(t, err)
+	assert.Equal(t, mountPoint, got)
+	assert.True(t, isMounted(mountPoint))
+	_, err = drv.Mount("vol", "id2")
+	require.NoError(t, err)
+	info, err := drv.Get("vol")
+	require.NoError(t, err)
+	assert.Equal(t, "vol", info.Name)
+	assert.Equal(t, true, info.Status["Mounted"])
+	assert.Equal(t, 2, info.Status["Mounts"])
+	assert.Error(t, drv.Remove("vol"))
+	require.NoError(t, drv.Unmount("vol", "id1"))
+	assert.True(t, isMounted(mountPoint))
+	assert.Error(t, drv.Unmount("vol", "id1"))
+
+	// Restart the driver - the volume should be remounted
+	drv.Exit()
+	assert.False(t, isMounted(mountPoint))
+	drv = newTestDriver(t, dir, false)
+	assert.True(t, isMounted(mountPoint))
+	infos := drv.List()
+	require.Equal(t, 1, len(infos))
+	assert.Equal(t, "vol", infos[0].Name)
+
+	require.NoError(t, drv.Unmount("vol", "id2"))
+	assert.False(t, isMounted(mountPoint))
+	require.NoError(t, drv.Remove("vol"))
+	_, err = os.Stat(mountPoint)
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, 0, len(drv.List()))
+
+	// Forgetting the state removes the volumes
+	require.NoError(t, drv.Create("vol2", opts))
+	drv = newTestDriver(t, dir, false)
+	assert.Equal(t, 1, len(drv.List()))
+	drv = newTestDriver(t, dir, true)
+	assert.Equal(t, 0, len(drv.List()))
+}
+
+func TestDriverExternalUnmount(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-docker-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	drv := newTestDriver(t, dir, true)
+	require.NoError(t, drv.Create("vol", map[string]string{"remote": dir, "mount-type": testMountType}))
+	mountPoint, err := drv.Mount("vol", "id1")
+	require.NoError(t, err)
+
+	// Unmount behind the driver's back
+	testMounts.mu.Lock()
+	errChan := testMounts.mounts[mountPoint]
+	delete(testMounts.mounts, mountPoint)
+	testMounts.mu.Unlock()
+	errChan <- nil
+
+	// The driver should notice and mount again when asked
+	for i := 0; i < 100; i++ {
+		info, err := drv.Get("vol")
+		require.NoError(t, err)
+		if info.Status["Mounted"] == false {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_, err = drv.Mount("vol", "id2")
+	require.NoError(t, err)
+	assert.True(t, isMounted(mountPoint))
+	drv.Exit()
+}
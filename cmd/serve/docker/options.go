@@ -0,0 +1,233 @@
+package docker
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/cmd/mountlib"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/rclone/rclone/vfs/vfsflags"
+)
+
+// volumeOptions are the parsed driver options of a volume
+type volumeOptions struct {
+	fsString  string            // the remote to mount
+	mountType string            // mount implementation to use, "" for the default
+	mntOpt    mountlib.Options  // mount options
+	vfsOpt    vfscommon.Options // VFS options
+	fsOpt     configmap.Simple  // backend options
+	fsInfo    *fs.RegInfo       // the backend
+	fsConfig  string            // name of the config section
+	fsPath    string            // path within the remote
+}
+
+// normalName converts an option name as typed by the user into the
+// form used for the mount and VFS flags
+func normalName(name string) string {
+	return strings.Replace(strings.ToLower(strings.TrimSpace(name)), "_", "-", -1)
+}
+
+// parseOptions parses the driver options passed to a volume,
+// starting from the defaults in drv.
+//
+// The remote to mount is given by the "remote" option (or its alias
+// "fs") and can be extended with the "path" option. Alternatively
+// "type" can be used to make an on the fly remote of that backend
+// type. Mount and VFS options use the names of the command line
+// flags without the leading "--" and any other options are passed to
+// the backend.
+func parseOptions(drv *Driver, opts map[string]string) (vo *volumeOptions, err error) {
+	vo = &volumeOptions{
+		mntOpt: *drv.mntOpt,
+		vfsOpt: *drv.vfsOpt,
+		fsOpt:  configmap.Simple{},
+	}
+	var remote, fsType, fsPath string
+
+	// Parse in a fixed order so the errors are repeatable
+	names := make([]string, 0, len(opts))
+	for name := range opts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := opts[name]
+		switch normalName(name) {
+		case "remote", "fs":
+			remote = value
+		case "type":
+			fsType = value
+		case "path":
+			fsPath = value
+		case "mount-type":
+			vo.mountType = value
+		default:
+			found, err := setMountOption(&vo.mntOpt, normalName(name), value)
+			if !found && err == nil {
+				found, err = setVFSOption(&vo.vfsOpt, normalName(name), value)
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, "bad value for option %q", name)
+			}
+			if !found {
+				// Backend options use the config file names
+				vo.fsOpt[strings.Replace(strings.ToLower(name), "-", "_", -1)] = value
+			}
+		}
+	}
+
+	switch {
+	case remote != "" && fsType != "":
+		return nil, errors.New(`only one of "remote" and "type" may be set`)
+	case remote != "":
+		vo.fsString = fspathJoin(remote, fsPath)
+	case fsType != "":
+		vo.fsString = ":" + fsType + ":" + fsPath
+	default:
+		return nil, errors.New(`volume needs a "remote" or "type" option`)
+	}
+
+	vo.fsInfo, vo.fsConfig, vo.fsPath, err = fs.ParseRemote(vo.fsString)
+	if err != nil {
+		return nil, errors.Wrapf(err, "bad remote %q", vo.fsString)
+	}
+	for name := range vo.fsOpt {
+		if vo.fsInfo.Options.Get(name) == nil {
+			return nil, errors.Errorf("unknown option %q for backend %q", name, vo.fsInfo.Name)
+		}
+	}
+	return vo, nil
+}
+
+// fspathJoin joins path onto the end of remote
+func fspathJoin(remote, path string) string {
+	if path == "" {
+		return remote
+	}
+	path = strings.TrimLeft(path, "/")
+	if strings.HasSuffix(remote, ":") || strings.HasSuffix(remote, "/") {
+		return remote + path
+	}
+	return remote + "/" + path
+}
+
+// parseBool parses a boolean option where an empty value means true
+func parseBool(value string) (bool, error) {
+	if value == "" {
+		return true, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// parseList parses a comma separated list of values
+func parseList(value string) []string {
+	var out []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// setMountOption sets the mount option called name to value,
+// returning false if there is no such option
+func setMountOption(opt *mountlib.Options, name, value string) (found bool, err error) {
+	found = true
+	switch name {
+	case "debug-fuse":
+		opt.DebugFUSE, err = parseBool(value)
+	case "allow-non-empty":
+		opt.AllowNonEmpty, err = parseBool(value)
+	case "allow-root":
+		opt.AllowRoot, err = parseBool(value)
+	case "allow-other":
+		opt.AllowOther, err = parseBool(value)
+	case "default-permissions":
+		opt.DefaultPermissions, err = parseBool(value)
+	case "write-back-cache":
+		opt.WritebackCache, err = parseBool(value)
+	case "async-read":
+		opt.AsyncRead, err = parseBool(value)
+	case "max-read-ahead":
+		err = opt.MaxReadAhead.Set(value)
+	case "attr-timeout":
+		opt.AttrTimeout, err = fs.ParseDuration(value)
+	case "daemon-timeout":
+		opt.DaemonTimeout, err = fs.ParseDuration(value)
+	case "option", "o":
+		opt.ExtraOptions = parseList(value)
+	case "fuse-flag":
+		opt.ExtraFlags = parseList(value)
+	case "volname":
+		opt.VolumeName = value
+	default:
+		found = false
+	}
+	return found, err
+}
+
+// setVFSOption sets the VFS option called name to value, returning
+// false if there is no such option
+func setVFSOption(opt *vfscommon.Options, name, value string) (found bool, err error) {
+	found = true
+	switch name {
+	case "no-modtime":
+		opt.NoModTime, err = parseBool(value)
+	case "no-checksum":
+		opt.NoChecksum, err = parseBool(value)
+	case "no-seek":
+		opt.NoSeek, err = parseBool(value)
+	case "read-only":
+		opt.ReadOnly, err = parseBool(value)
+	case "vfs-case-insensitive":
+		opt.CaseInsensitive, err = parseBool(value)
+	case "dir-cache-time":
+		opt.DirCacheTime, err = fs.ParseDuration(value)
+	case "poll-interval":
+		opt.PollInterval, err = fs.ParseDuration(value)
+	case "vfs-cache-mode":
+		err = opt.CacheMode.Set(value)
+	case "vfs-cache-poll-interval":
+		opt.CachePollInterval, err = fs.ParseDuration(value)
+	case "vfs-cache-max-age":
+		opt.CacheMaxAge, err = fs.ParseDuration(value)
+	case "vfs-cache-max-size":
+		err = opt.CacheMaxSize.Set(value)
+	case "vfs-read-chunk-size":
+		err = opt.ChunkSize.Set(value)
+	case "vfs-read-chunk-size-limit":
+		err = opt.ChunkSizeLimit.Set(value)
+	case "vfs-write-wait":
+		opt.WriteWait, err = fs.ParseDuration(value)
+	case "vfs-read-wait":
+		opt.ReadWait, err = fs.ParseDuration(value)
+	case "vfs-write-back":
+		opt.WriteBack, err = fs.ParseDuration(value)
+	case "vfs-read-ahead":
+		err = opt.ReadAhead.Set(value)
+	case "dir-perms":
+		err = (&vfsflags.FileMode{Mode: &opt.DirPerms}).Set(value)
+	case "file-perms":
+		err = (&vfsflags.FileMode{Mode: &opt.FilePerms}).Set(value)
+	case "umask":
+		var umask uint64
+		umask, err = strconv.ParseUint(value, 8, 32)
+		opt.Umask = int(umask)
+	case "uid":
+		var uid uint64
+		uid, err = strconv.ParseUint(value, 10, 32)
+		opt.UID = uint32(uid)
+	case "gid":
+		var gid uint64
+		gid, err = strconv.ParseUint(value, 10, 32)
+		opt.GID = uint32(gid)
+	default:
+		found = false
+	}
+	return found, err
+}
@@ -0,0 +1,40 @@
+// +build linux freebsd
+
+package docker
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+)
+
+// ServeUnix serves requests on the unix socket at path.
+//
+// If gid is not negative the socket is made accessible to that group.
+func (s *Server) ServeUnix(path string, gid int) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrap(err, "failed to make socket directory")
+	}
+	// Remove a socket left over from a previous run
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed to remove old socket")
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	if gid >= 0 {
+		if err = os.Chown(path, -1, gid); err == nil {
+			err = os.Chmod(path, 0660)
+		}
+		if err != nil {
+			_ = listener.Close()
+			return errors.Wrap(err, "failed to set socket permissions")
+		}
+	}
+	fs.Logf(nil, "Serving docker volume plugin on unix://%s", path)
+	return s.Serve(listener)
+}
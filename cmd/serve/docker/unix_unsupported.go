@@ -0,0 +1,12 @@
+// +build !linux,!freebsd
+
+package docker
+
+import (
+	"errors"
+)
+
+// ServeUnix is not supported on this platform
+func (s *Server) ServeUnix(path string, gid int) error {
+	return errors.New("unix sockets are not supported on this platform - use --socket-addr")
+}
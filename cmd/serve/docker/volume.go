@@ -0,0 +1,200 @@
+package docker
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/cmd/mountlib"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/vfs"
+)
+
+// Volume is a docker volume backed by an rclone mount.
+//
+// The exported fields are saved in the state file.
+type Volume struct {
+	Name       string            `json:"name"`
+	MountPoint string            `json:"mountpoint"`
+	CreatedAt  time.Time         `json:"created"`
+	Options    map[string]string `json:"options"`
+	Mounts     []string          `json:"mounts"` // IDs of the mount requests using the volume
+
+	drv       *Driver
+	opt       *volumeOptions
+	mounted   bool
+	mountGen  int // incremented on each mount
+	unmountFn mountlib.UnmountFn
+}
+
+// newVolume makes a volume called name with the driver options opts
+func newVolume(drv *Driver, name string, opts map[string]string) (*Volume, error) {
+	vol := &Volume{
+		Name:       name,
+		MountPoint: drv.mountPath(name),
+		CreatedAt:  time.Now(),
+		Options:    opts,
+	}
+	if err := vol.init(drv); err != nil {
+		return nil, err
+	}
+	return vol, nil
+}
+
+// init parses the options of the volume for use with drv
+func (vol *Volume) init(drv *Driver) (err error) {
+	vol.drv = drv
+	if vol.Options == nil {
+		vol.Options = map[string]string{}
+	}
+	vol.opt, err = parseOptions(drv, vol.Options)
+	return err
+}
+
+// newFs makes the Fs to mount, adding the backend options from the
+// volume to the config for the remote
+func (vol *Volume) newFs(ctx context.Context) (fs.Fs, error) {
+	opt := vol.opt
+	fsConfig := fs.ConfigMap(opt.fsInfo, opt.fsConfig)
+	config := configmap.New()
+	config.AddGetter(opt.fsOpt)
+	config.AddGetter(fsConfig)
+	config.AddSetter(fsConfig)
+	f, err := opt.fsInfo.NewFs(ctx, opt.fsConfig, opt.fsPath, config)
+	if err == fs.ErrorIsFile {
+		return nil, errors.Errorf("can't mount %q as it is a file", opt.fsString)
+	}
+	return f, err
+}
+
+// mount mounts the volume on its mount point if it isn't mounted
+// already
+func (vol *Volume) mount(ctx context.Context) error {
+	if vol.mounted {
+		return nil
+	}
+	mountType, mountFn := mountlib.ResolveMountMethod(vol.opt.mountType)
+	if mountFn == nil {
+		if mountType == "" {
+			return errors.New("no mount implementations are available")
+		}
+		return errors.Errorf("mount type %q is not available", mountType)
+	}
+	f, err := vol.newFs(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create remote %q", vol.opt.fsString)
+	}
+	if err = os.MkdirAll(vol.MountPoint, 0755); err != nil {
+		return errors.Wrap(err, "failed to make mount point")
+	}
+	VFS := vfs.New(f, &vol.opt.vfsOpt)
+	errChan, unmountFn, err := mountFn(VFS, vol.MountPoint, &vol.opt.mntOpt)
+	if err != nil {
+		VFS.Shutdown()
+		return errors.Wrapf(err, "failed to mount %q", vol.opt.fsString)
+	}
+	fs.Infof(nil, "Volume %q: mounted %q on %q using %s", vol.Name, vol.opt.fsString, vol.MountPoint, mountType)
+	vol.mounted = true
+	vol.mountGen++
+	vol.unmountFn = unmountFn
+	go vol.wait(errChan, vol.mountGen)
+	return nil
+}
+
+// wait for the mount to finish, marking the volume unmounted if it
+// was unmounted outside rclone
+func (vol *Volume) wait(errChan <-chan error, mountGen int) {
+	err := <-errChan
+	vol.drv.mu.Lock()
+	defer vol.drv.mu.Unlock()
+	if !vol.mounted || vol.mountGen != mountGen {
+		return
+	}
+	if err != nil {
+		fs.Errorf(nil, "Volume %q: mount finished with error: %v", vol.Name, err)
+	} else {
+		fs.Logf(nil, "Volume %q: unmounted externally", vol.Name)
+	}
+	vol.mounted = false
+	vol.unmountFn = nil
+}
+
+// unmount unmounts the volume if it is mounted
+func (vol *Volume) unmount() error {
+	if !vol.mounted {
+		return nil
+	}
+	// Mark as unmounted first so wait doesn't log the unmount
+	vol.mounted = false
+	unmountFn := vol.unmountFn
+	vol.unmountFn = nil
+	if err := unmountFn(); err != nil {
+		vol.mounted = true
+		vol.unmountFn = unmountFn
+		return errors.Wrapf(err, "failed to unmount volume %q", vol.Name)
+	}
+	fs.Infof(nil, "Volume %q: unmounted %q", vol.Name, vol.MountPoint)
+	return nil
+}
+
+// hasMount returns true if the volume is in use by mount request id
+func (vol *Volume) hasMount(id string) bool {
+	for _, mountID := range vol.Mounts {
+		if mountID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// addMount mounts the volume for the mount request id
+func (vol *Volume) addMount(ctx context.Context, id string) error {
+	if err := vol.mount(ctx); err != nil {
+		return err
+	}
+	if !vol.hasMount(id) {
+		vol.Mounts = append(vol.Mounts, id)
+	}
+	return nil
+}
+
+// removeMount releases the volume for mount request id, unmounting
+// it when it is no longer in use
+func (vol *Volume) removeMount(id string) error {
+	if !vol.hasMount(id) {
+		return errors.Errorf("volume %q is not mounted by %q", vol.Name, id)
+	}
+	if len(vol.Mounts) == 1 {
+		if err := vol.unmount(); err != nil {
+			return err
+		}
+	}
+	mounts := vol.Mounts[:0]
+	for _, mountID := range vol.Mounts {
+		if mountID != id {
+			mounts = append(mounts, mountID)
+		}
+	}
+	vol.Mounts = mounts
+	return nil
+}
+
+// info returns the information about the volume for docker,
+// including the status if withStatus is set
+func (vol *Volume) info(withStatus bool) *VolInfo {
+	info := &VolInfo{
+		Name:       vol.Name,
+		Mountpoint: vol.MountPoint,
+		CreatedAt:  vol.CreatedAt.Format(time.RFC3339),
+	}
+	if withStatus {
+		info.Status = map[string]interface{}{
+			"Fs":      vol.opt.fsString,
+			"Mounted": vol.mounted,
+			"Mounts":  len(vol.Mounts),
+		}
+	}
+	return info
+}
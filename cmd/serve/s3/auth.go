@@ -0,0 +1,493 @@
+package s3
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	gohash "hash"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Constants used by AWS signature version 4
+const (
+	signV4Algorithm    = "AWS4-HMAC-SHA256"
+	amzDateFormat      = "20060102T150405Z"
+	yyyymmdd           = "20060102"
+	unsignedPayload    = "UNSIGNED-PAYLOAD"
+	streamingPayload   = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	streamingTrailer   = "STREAMING-UNSIGNED-PAYLOAD-TRAILER"
+	maxClockSkew       = 15 * time.Minute
+	maxPresignedExpiry = 7 * 24 * time.Hour
+	maxChunkSize       = 16 * 1024 * 1024
+)
+
+// emptySHA256 is the hex SHA-256 of no data
+var emptySHA256 = hex.EncodeToString(sha256.New().Sum(nil))
+
+// signatureInfo holds the parts of a signature V4 parsed from the
+// Authorization header or the query of a presigned URL
+type signatureInfo struct {
+	accessKeyID   string
+	scope         string // date/region/service/aws4_request
+	date          string // yyyymmdd from the scope
+	signedHeaders []string
+	signature     string
+	presigned     bool
+	expires       time.Duration // presigned only
+}
+
+// parseCredential parses "AKID/yyyymmdd/region/s3/aws4_request" into info
+func (info *signatureInfo) parseCredential(credential string) error {
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[4] != "aws4_request" {
+		return errors.Errorf("malformed credential %q", credential)
+	}
+	info.accessKeyID = parts[0]
+	info.date = parts[1]
+	info.scope = strings.Join(parts[1:], "/")
+	return nil
+}
+
+// parseAuthHeader parses a signature V4 Authorization header of the form
+//
+//     AWS4-HMAC-SHA256 Credential=AKID/20130524/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-date, Signature=xxx
+func parseAuthHeader(header string) (*signatureInfo, error) {
+	if !strings.HasPrefix(header, signV4Algorithm+" ") {
+		return nil, errors.New("only AWS4-HMAC-SHA256 signatures are supported")
+	}
+	info := &signatureInfo{}
+	for _, field := range strings.Split(header[len(signV4Algorithm)+1:], ",") {
+		field = strings.TrimSpace(field)
+		equals := strings.IndexByte(field, '=')
+		if equals < 0 {
+			return nil, errors.Errorf("malformed authorization field %q", field)
+		}
+		key, value := field[:equals], field[equals+1:]
+		switch key {
+		case "Credential":
+			if err := info.parseCredential(value); err != nil {
+				return nil, err
+			}
+		case "SignedHeaders":
+			info.signedHeaders = strings.Split(value, ";")
+		case "Signature":
+			info.signature = value
+		}
+	}
+	if info.accessKeyID == "" || len(info.signedHeaders) == 0 || info.signature == "" {
+		return nil, errors.New("incomplete authorization header")
+	}
+	return info, nil
+}
+
+// parsePresigned parses the signature V4 parameters from the query of
+// a presigned URL
+func parsePresigned(query url.Values) (*signatureInfo, error) {
+	if query.Get("X-Amz-Algorithm") != signV4Algorithm {
+		return nil, errors.New("only AWS4-HMAC-SHA256 signatures are supported")
+	}
+	info := &signatureInfo{
+		presigned:     true,
+		signedHeaders: strings.Split(query.Get("X-Amz-SignedHeaders"), ";"),
+		signature:     query.Get("X-Amz-Signature"),
+	}
+	if err := info.parseCredential(query.Get("X-Amz-Credential")); err != nil {
+		return nil, err
+	}
+	expires, err := strconv.ParseInt(query.Get("X-Amz-Expires"), 10, 64)
+	if err != nil || expires < 0 {
+		return nil, errors.New("malformed X-Amz-Expires")
+	}
+	info.expires = time.Duration(expires) * time.Second
+	if info.expires > maxPresignedExpiry {
+		return nil, errors.New("X-Amz-Expires must be less than a week")
+	}
+	if info.signature == "" {
+		return nil, errors.New("missing X-Amz-Signature")
+	}
+	return info, nil
+}
+
+// requestTime returns the time the request was signed
+func requestTime(r *http.Request, info *signatureInfo) (time.Time, error) {
+	if info.presigned {
+		return time.Parse(amzDateFormat, r.URL.Query().Get("X-Amz-Date"))
+	}
+	if amzDate := r.Header.Get("X-Amz-Date"); amzDate != "" {
+		return time.Parse(amzDateFormat, amzDate)
+	}
+	return http.ParseTime(r.Header.Get("Date"))
+}
+
+// uriEncode encodes s as AWS requires - everything except the
+// unreserved characters is percent encoded, and "/" too if
+// encodeSlash is set
+func uriEncode(s string, encodeSlash bool) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			buf.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			buf.WriteByte(c)
+		default:
+			buf.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+		}
+	}
+	return buf.String()
+}
+
+// canonicalQuery returns the sorted and encoded query string of r
+// without the signature
+func canonicalQuery(query url.Values) string {
+	var params []string
+	for key, values := range query {
+		if key == "X-Amz-Signature" {
+			continue
+		}
+		for _, value := range values {
+			params = append(params, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// headerValue returns the canonical value of the header called name
+func headerValue(r *http.Request, name string) string {
+	var values []string
+	switch name {
+	case "host":
+		values = []string{r.Host}
+	case "content-length":
+		values = r.Header.Values(name)
+		if len(values) == 0 && r.ContentLength >= 0 {
+			values = []string{strconv.FormatInt(r.ContentLength, 10)}
+		}
+	case "transfer-encoding":
+		values = r.TransferEncoding
+	default:
+		values = r.Header.Values(name)
+	}
+	trimmed := make([]string, len(values))
+	for i, value := range values {
+		trimmed[i] = strings.Join(strings.Fields(value), " ")
+	}
+	return strings.Join(trimmed, ",")
+}
+
+// canonicalRequest builds the canonical request which is signed
+func canonicalRequest(r *http.Request, info *signatureInfo, payloadHash string) string {
+	var headers strings.Builder
+	for _, name := range info.signedHeaders {
+		headers.WriteString(name)
+		headers.WriteByte(':')
+		headers.WriteString(headerValue(r, name))
+		headers.WriteByte('\n')
+	}
+	return strings.Join([]string{
+		r.Method,
+		uriEncode(r.URL.Path, false),
+		canonicalQuery(r.URL.Query()),
+		headers.String(),
+		strings.Join(info.signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// sha256Hex returns the hex SHA-256 of data
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// signingKey derives the key used to sign requests for the scope
+func signingKey(secretKey, scope string) []byte {
+	key := []byte("AWS4" + secretKey)
+	for _, part := range strings.Split(scope, "/") {
+		key = hmacSHA256(key, part)
+	}
+	return key
+}
+
+// signer calculates signatures for one request
+type signer struct {
+	key     []byte
+	amzDate string
+	scope   string
+}
+
+// sign returns the hex signature of the string to sign made from
+// algorithm and lines
+func (s *signer) sign(algorithm string, lines ...string) string {
+	stringToSign := strings.Join(append([]string{algorithm, s.amzDate, s.scope}, lines...), "\n")
+	return hex.EncodeToString(hmacSHA256(s.key, stringToSign))
+}
+
+// signaturesEqual compares two hex signatures in constant time
+func signaturesEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// checkAuth authenticates r, returning the access key ID and secret
+// key it was signed with.
+//
+// If no keys are configured then all requests are allowed and the
+// returned keys are blank.
+//
+// The body of r is replaced with one which decodes aws-chunked
+// uploads and which returns an error at the end if the payload
+// doesn't match the signed checksum.
+func (s *Server) checkAuth(r *http.Request) (accessKeyID, secretKey string, err error) {
+	var info *signatureInfo
+	if header := r.Header.Get("Authorization"); header != "" {
+		info, err = parseAuthHeader(header)
+	} else if r.URL.Query().Get("X-Amz-Algorithm") != "" {
+		info, err = parsePresigned(r.URL.Query())
+	}
+	if err != nil {
+		return "", "", &apiError{http.StatusBadRequest, "AuthorizationHeaderMalformed", err.Error()}
+	}
+
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if info != nil && info.presigned {
+		payloadHash = r.URL.Query().Get("X-Amz-Content-Sha256")
+		if payloadHash == "" {
+			payloadHash = unsignedPayload
+		}
+	}
+
+	var sig *signer
+	if len(s.keys) > 0 {
+		if info == nil {
+			return "", "", errAccessDenied
+		}
+		var ok bool
+		secretKey, ok = s.keys[info.accessKeyID]
+		if !ok {
+			return "", "", errInvalidAccessKeyID
+		}
+		signed, err := requestTime(r, info)
+		if err != nil {
+			return "", "", errMissingSecurityHeader
+		}
+		if signed.UTC().Format(yyyymmdd) != info.date {
+			return "", "", errSignatureDoesNotMatch
+		}
+		now := time.Now()
+		if info.presigned {
+			if now.After(signed.Add(info.expires)) {
+				return "", "", errExpiredToken
+			}
+		} else if d := now.Sub(signed); d > maxClockSkew || d < -maxClockSkew {
+			return "", "", errRequestTimeTooSkewed
+		}
+		if payloadHash == "" && !info.presigned {
+			return "", "", errMissingSecurityHeader
+		}
+		sig = &signer{
+			key:     signingKey(secretKey, info.scope),
+			amzDate: signed.UTC().Format(amzDateFormat),
+			scope:   info.scope,
+		}
+		expected := sig.sign(signV4Algorithm, sha256Hex([]byte(canonicalRequest(r, info, payloadHash))))
+		if !signaturesEqual(expected, info.signature) {
+			return "", "", errSignatureDoesNotMatch
+		}
+		accessKeyID = info.accessKeyID
+	}
+
+	err = wrapBody(r, payloadHash, sig, info)
+	if err != nil {
+		return "", "", err
+	}
+	return accessKeyID, secretKey, nil
+}
+
+// wrapBody replaces the body of r with one which decodes and checks
+// the payload as described by payloadHash
+//
+// sig and info are set if the request signature was verified
+func wrapBody(r *http.Request, payloadHash string, sig *signer, info *signatureInfo) error {
+	switch payloadHash {
+	case "", unsignedPayload:
+		return nil
+	case streamingPayload, streamingTrailer:
+	default:
+		want, err := hex.DecodeString(payloadHash)
+		if err != nil || len(want) != sha256.Size {
+			return errContentSHA256Mismatch
+		}
+		r.Body = &sha256Reader{
+			in:   r.Body,
+			hash: sha256.New(),
+			want: want,
+		}
+		return nil
+	}
+	decodedLength, err := strconv.ParseInt(r.Header.Get("X-Amz-Decoded-Content-Length"), 10, 64)
+	if err != nil {
+		return errMissingSecurityHeader
+	}
+	c := &chunkedReader{
+		in:      bufio.NewReader(r.Body),
+		closer:  r.Body,
+		trailer: payloadHash == streamingTrailer,
+	}
+	if sig != nil && payloadHash == streamingPayload {
+		c.sig = sig
+		c.prevSignature = info.signature
+	}
+	r.Body = c
+	r.ContentLength = decodedLength
+	return nil
+}
+
+// sha256Reader checks the SHA-256 of the data read through it
+type sha256Reader struct {
+	in   io.ReadCloser
+	hash gohash.Hash
+	want []byte
+}
+
+// Read bytes returning an error instead of io.EOF if the checksum
+// didn't match
+func (s *sha256Reader) Read(p []byte) (n int, err error) {
+	n, err = s.in.Read(p)
+	_, _ = s.hash.Write(p[:n])
+	if err == io.EOF && !bytes.Equal(s.hash.Sum(nil), s.want) {
+		err = errContentSHA256Mismatch
+	}
+	return n, err
+}
+
+// Close the underlying body
+func (s *sha256Reader) Close() error {
+	return s.in.Close()
+}
+
+// chunkedReader decodes an aws-chunked body, verifying the chunk
+// signatures if sig is set
+//
+// Each chunk is read and verified in full before any of it is
+// returned so unsigned data is never passed on.
+type chunkedReader struct {
+	in            *bufio.Reader
+	closer        io.Closer
+	sig           *signer
+	prevSignature string
+	trailer       bool
+	buf           []byte
+	chunk         []byte
+	err           error
+}
+
+// Read decoded bytes
+func (c *chunkedReader) Read(p []byte) (n int, err error) {
+	for len(c.buf) == 0 {
+		if c.err != nil {
+			return 0, c.err
+		}
+		c.err = c.readChunk()
+	}
+	n = copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
+
+// Close the underlying body
+func (c *chunkedReader) Close() error {
+	return c.closer.Close()
+}
+
+// readLine reads a CRLF terminated line
+func (c *chunkedReader) readLine() (string, error) {
+	line, err := c.in.ReadString('\n')
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(line) > 4096 {
+		return "", errors.New("aws-chunked line too long")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readChunk reads the next chunk into c.buf returning io.EOF after
+// the last one
+func (c *chunkedReader) readChunk() error {
+	header, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	sizeHex, signature := header, ""
+	if semicolon := strings.IndexByte(header, ';'); semicolon >= 0 {
+		sizeHex = header[:semicolon]
+		signature = strings.TrimPrefix(header[semicolon+1:], "chunk-signature=")
+	}
+	size, err := strconv.ParseInt(sizeHex, 16, 64)
+	if err != nil || size < 0 || size > maxChunkSize {
+		return errors.Errorf("malformed aws-chunked chunk header %q", header)
+	}
+	if int64(cap(c.chunk)) < size {
+		c.chunk = make([]byte, size)
+	}
+	c.buf = c.chunk[:size]
+	if _, err = io.ReadFull(c.in, c.buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	if c.sig != nil {
+		expected := c.sig.sign(signV4Algorithm+"-PAYLOAD", c.prevSignature, emptySHA256, sha256Hex(c.buf))
+		if !signaturesEqual(expected, signature) {
+			c.buf = nil
+			return errSignatureDoesNotMatch
+		}
+		c.prevSignature = signature
+	}
+	if size > 0 {
+		if line, err := c.readLine(); err != nil || line != "" {
+			c.buf = nil
+			return errors.New("malformed aws-chunked chunk terminator")
+		}
+		return nil
+	}
+	// Skip any trailing headers up to the blank line
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			if !c.trailer && err == io.ErrUnexpectedEOF {
+				// some clients don't send the final CRLF
+				return io.EOF
+			}
+			return err
+		}
+		if line == "" {
+			return io.EOF
+		}
+	}
+}
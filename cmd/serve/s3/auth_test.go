@@ -0,0 +1,256 @@
+package s3
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURIEncode(t *testing.T) {
+	assert.Equal(t, "a/b%20c/%E4%B8%96~-_.", uriEncode("a/b c/世~-_.", false))
+	assert.Equal(t, "a%2Fb%2Bc%3D", uriEncode("a/b+c=", true))
+}
+
+func TestParseAuthHeader(t *testing.T) {
+	info, err := parseAuthHeader("AWS4-HMAC-SHA256 Credential=AKID/20130524/us-east-1/s3/aws4_request, SignedHeaders=host;range;x-amz-date, Signature=abcdef")
+	require.NoError(t, err)
+	assert.Equal(t, "AKID", info.accessKeyID)
+	assert.Equal(t, "20130524", info.date)
+	assert.Equal(t, "20130524/us-east-1/s3/aws4_request", info.scope)
+	assert.Equal(t, []string{"host", "range", "x-amz-date"}, info.signedHeaders)
+	assert.Equal(t, "abcdef", info.signature)
+
+	for _, bad := range []string{
+		"AWS AKID:signature",
+		"AWS4-HMAC-SHA256 Credential=AKID/20130524/us-east-1/s3, SignedHeaders=host, Signature=abcdef",
+		"AWS4-HMAC-SHA256 Credential=AKID/20130524/us-east-1/s3/aws4_request, Signature=abcdef",
+		"AWS4-HMAC-SHA256 Credential",
+	} {
+		_, err := parseAuthHeader(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+// authTestServer starts a server which checks the auth of each
+// request and returns the access key and the body read or the error
+func authTestServer(t *testing.T) (*httptest.Server, func()) {
+	s := &Server{
+		keys: map[string]string{testAccessKey: testSecretKey},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessKeyID, _, err := s.checkAuth(r)
+		var body []byte
+		if err == nil {
+			body, err = ioutil.ReadAll(r.Body)
+		}
+		if err != nil {
+			apiErr, ok := errors.Cause(err).(*apiError)
+			if !ok {
+				apiErr = &apiError{Code: err.Error()}
+			}
+			_, _ = fmt.Fprintf(w, "error %s", apiErr.Code)
+			return
+		}
+		_, _ = fmt.Fprintf(w, "ok %s %s", accessKeyID, body)
+	}))
+	return ts, ts.Close
+}
+
+// doRequest does req returning the body of the response
+func doRequest(t *testing.T, req *http.Request) string {
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	return string(body)
+}
+
+func TestCheckAuth(t *testing.T) {
+	ts, cleanup := authTestServer(t)
+	defer cleanup()
+	// S3 doesn't double escape the path when signing
+	s3Path := func(s *v4.Signer) { s.DisableURIPathEscaping = true }
+	signer := v4.NewSigner(credentials.NewStaticCredentials(testAccessKey, testSecretKey, ""), s3Path)
+	badSigner := v4.NewSigner(credentials.NewStaticCredentials(testAccessKey, "wrong", ""), s3Path)
+	unknownSigner := v4.NewSigner(credentials.NewStaticCredentials("unknown", testSecretKey, ""), s3Path)
+	now := time.Now()
+
+	newRequest := func(method, path, body string) *http.Request {
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		return req
+	}
+
+	for _, test := range []struct {
+		name string
+		sign func(req *http.Request, body string)
+		body string
+		want string
+	}{{
+		name: "Signed",
+		sign: func(req *http.Request, body string) {
+			_, err := signer.Sign(req, strings.NewReader(body), "s3", "us-east-1", now)
+			require.NoError(t, err)
+		},
+		body: "potato",
+		want: "ok " + testAccessKey + " potato",
+	}, {
+		name: "UnsignedPayload",
+		sign: func(req *http.Request, body string) {
+			req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+			_, err := signer.Sign(req, strings.NewReader(body), "s3", "us-east-1", now)
+			require.NoError(t, err)
+		},
+		body: "potato",
+		want: "ok " + testAccessKey + " potato",
+	}, {
+		name: "BodyChanged",
+		sign: func(req *http.Request, body string) {
+			_, err := signer.Sign(req, strings.NewReader("tomato"), "s3", "us-east-1", now)
+			require.NoError(t, err)
+			req.Body = ioutil.NopCloser(strings.NewReader(body))
+		},
+		body: "potato",
+		want: "error XAmzContentSHA256Mismatch",
+	}, {
+		name: "WrongSecret",
+		sign: func(req *http.Request, body string) {
+			_, err := badSigner.Sign(req, strings.NewReader(body), "s3", "us-east-1", now)
+			require.NoError(t, err)
+		},
+		want: "error SignatureDoesNotMatch",
+	}, {
+		name: "UnknownKey",
+		sign: func(req *http.Request, body string) {
+			_, err := unknownSigner.Sign(req, strings.NewReader(body), "s3", "us-east-1", now)
+			require.NoError(t, err)
+		},
+		want: "error InvalidAccessKeyId",
+	}, {
+		name: "TooSkewed",
+		sign: func(req *http.Request, body string) {
+			_, err := signer.Sign(req, strings.NewReader(body), "s3", "us-east-1", now.Add(-time.Hour))
+			require.NoError(t, err)
+		},
+		want: "error RequestTimeTooSkewed",
+	}, {
+		name: "Anonymous",
+		sign: func(req *http.Request, body string) {},
+		want: "error AccessDenied",
+	}, {
+		name: "Presigned",
+		sign: func(req *http.Request, body string) {
+			_, err := signer.Presign(req, strings.NewReader(body), "s3", "us-east-1", time.Hour, now)
+			require.NoError(t, err)
+		},
+		body: "potato",
+		want: "ok " + testAccessKey + " potato",
+	}, {
+		name: "PresignedExpired",
+		sign: func(req *http.Request, body string) {
+			_, err := signer.Presign(req, strings.NewReader(body), "s3", "us-east-1", time.Hour, now.Add(-2*time.Hour))
+			require.NoError(t, err)
+		},
+		want: "error AccessDenied",
+	}} {
+		t.Run(test.name, func(t *testing.T) {
+			req := newRequest("PUT", "/bucket/dir/file%20name%2B.txt?x-id=PutObject", test.body)
+			test.sign(req, test.body)
+			assert.Equal(t, test.want, doRequest(t, req))
+		})
+	}
+}
+
+func TestCheckAuthNoKeys(t *testing.T) {
+	s := &Server{}
+	req := httptest.NewRequest("GET", "/bucket", nil)
+	accessKeyID, secretKey, err := s.checkAuth(req)
+	require.NoError(t, err)
+	assert.Equal(t, "", accessKeyID)
+	assert.Equal(t, "", secretKey)
+}
+
+// This is the example from the AWS documentation for a chunked upload
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-streaming.html
+func TestChunkedReaderSigned(t *testing.T) {
+	sig := &signer{
+		key:     signingKey(testSecretKey, "20130524/us-east-1/s3/aws4_request"),
+		amzDate: "20130524T000000Z",
+		scope:   "20130524/us-east-1/s3/aws4_request",
+	}
+	chunk1 := bytes.Repeat([]byte{'a'}, 65536)
+	chunk2 := bytes.Repeat([]byte{'a'}, 1024)
+	var body bytes.Buffer
+	body.WriteString("10000;chunk-signature=ad80c730a21e5b8d04586a2213dd63b9a0e99e0e2307b0ade35a65485a288648\r\n")
+	body.Write(chunk1)
+	body.WriteString("\r\n400;chunk-signature=0055627c9e194cb4542bae2aa5492e3c1575bbb81b612b7d234b86a503ef5497\r\n")
+	body.Write(chunk2)
+	body.WriteString("\r\n0;chunk-signature=b6c6ea8a5354eaf15b3cb7646744f4275b71ea724fed81ceb9323e279d449df9\r\n\r\n")
+	seed := "4f232c4386841ef735655705268965c44a0e4690baa4adea153f7db9fa80a0a9"
+
+	newRequest := func(body []byte) *http.Request {
+		r := httptest.NewRequest("PUT", "/examplebucket/chunkObject.txt", bytes.NewReader(body))
+		r.Header.Set("X-Amz-Decoded-Content-Length", "66560")
+		return r
+	}
+
+	r := newRequest(body.Bytes())
+	require.NoError(t, wrapBody(r, streamingPayload, sig, &signatureInfo{signature: seed}))
+	assert.Equal(t, int64(66560), r.ContentLength)
+	got, err := ioutil.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.Equal(t, append(chunk1, chunk2...), got)
+
+	// Corrupt the second chunk - none of it should be returned
+	corrupt := append([]byte(nil), body.Bytes()...)
+	corrupt[len(corrupt)-200] = 'b'
+	r = newRequest(corrupt)
+	require.NoError(t, wrapBody(r, streamingPayload, sig, &signatureInfo{signature: seed}))
+	got, err = ioutil.ReadAll(r.Body)
+	assert.Equal(t, errSignatureDoesNotMatch, err)
+	assert.Equal(t, chunk1, got)
+
+	// Without a signer the signatures aren't checked
+	r = newRequest(corrupt)
+	require.NoError(t, wrapBody(r, streamingPayload, nil, nil))
+	got, err = ioutil.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.Equal(t, len(chunk1)+len(chunk2), len(got))
+
+	// Truncated
+	r = newRequest(body.Bytes()[:1000])
+	require.NoError(t, wrapBody(r, streamingPayload, sig, &signatureInfo{signature: seed}))
+	_, err = ioutil.ReadAll(r.Body)
+	assert.Error(t, err)
+}
+
+func TestChunkedReaderTrailer(t *testing.T) {
+	body := "5\r\nhello\r\n6\r\n world\r\n0\r\nx-amz-checksum-crc32:DUoRhQ==\r\n\r\n"
+	r := httptest.NewRequest("PUT", "/bucket/file.txt", strings.NewReader(body))
+	r.Header.Set("X-Amz-Decoded-Content-Length", "11")
+	require.NoError(t, wrapBody(r, streamingTrailer, nil, nil))
+	got, err := ioutil.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(got))
+
+	r = httptest.NewRequest("PUT", "/bucket/file.txt", strings.NewReader("zz\r\nhello"))
+	r.Header.Set("X-Amz-Decoded-Content-Length", "5")
+	require.NoError(t, wrapBody(r, streamingTrailer, nil, nil))
+	_, err = ioutil.ReadAll(r.Body)
+	assert.Error(t, err)
+
+	r = httptest.NewRequest("PUT", "/bucket/file.txt", strings.NewReader(body))
+	assert.Equal(t, errMissingSecurityHeader, wrapBody(r, streamingTrailer, nil, nil))
+}
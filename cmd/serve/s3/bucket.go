@@ -0,0 +1,382 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs"
+)
+
+// Limits on listings
+const (
+	defaultMaxKeys = 1000
+	maxMaxKeys     = 1000
+)
+
+// locationConstraint is the response to GetBucketLocation
+type locationConstraint struct {
+	XMLName  xml.Name `xml:"LocationConstraint"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Location string   `xml:",chardata"`
+}
+
+// listBuckets lists the directories in the root as buckets
+func (req *request) listBuckets() error {
+	root, err := req.vfs.Root()
+	if err != nil {
+		return err
+	}
+	nodes, err := root.ReadDirAll()
+	if err != nil {
+		return err
+	}
+	result := &listAllMyBucketsResult{
+		Xmlns:   s3Namespace,
+		Owner:   serverOwner,
+		Buckets: []bucketInfo{},
+	}
+	for _, node := range nodes {
+		if !node.IsDir() {
+			continue
+		}
+		result.Buckets = append(result.Buckets, bucketInfo{
+			Name:         keyName(node.Name()),
+			CreationDate: formatTime(node.ModTime()),
+		})
+	}
+	writeXML(req.w, http.StatusOK, result)
+	return nil
+}
+
+// headBucket checks the bucket exists
+func (req *request) headBucket() error {
+	if _, err := req.bucketDir(); err != nil {
+		return err
+	}
+	req.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// getBucketLocation returns the default region for any bucket
+func (req *request) getBucketLocation() error {
+	if _, err := req.bucketDir(); err != nil {
+		return err
+	}
+	writeXML(req.w, http.StatusOK, &locationConstraint{Xmlns: s3Namespace})
+	return nil
+}
+
+// createBucket makes the directory for the bucket
+func (req *request) createBucket() error {
+	if _, err := req.bucketDir(); err == nil {
+		return errBucketAlreadyExists
+	} else if err != errNoSuchBucket {
+		return err
+	}
+	if _, err := req.vfs.Stat(vfsPath(req.bucket, "")); err == nil {
+		return &apiError{http.StatusConflict, "BucketAlreadyExists", "A file exists with the bucket name."}
+	}
+	if err := req.vfs.Mkdir(vfsPath(req.bucket, ""), 0777); err != nil {
+		return err
+	}
+	req.w.Header().Set("Location", "/"+req.bucket)
+	req.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// deleteBucket removes the directory for the bucket if it is empty
+func (req *request) deleteBucket() error {
+	dir, err := req.bucketDir()
+	if err != nil {
+		return err
+	}
+	nodes, err := dir.ReadDirAll()
+	if err != nil {
+		return err
+	}
+	if len(nodes) != 0 {
+		return errBucketNotEmpty
+	}
+	if err = dir.Remove(); err != nil {
+		return err
+	}
+	req.w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// listEntry is a key or common prefix found while listing
+type listEntry struct {
+	key    string
+	file   *vfs.File // nil for a common prefix
+	prefix bool
+}
+
+// listDir appends the entries in dir, whose keys start with dirKey,
+// to entries. If recurse is set then subdirectories are listed too,
+// otherwise they are returned as common prefixes.
+func listDir(dir *vfs.Dir, dirKey, prefix string, recurse bool, entries []listEntry) ([]listEntry, error) {
+	nodes, err := dir.ReadDirAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range nodes {
+		if strings.HasPrefix(node.Name(), tempPrefix) {
+			continue
+		}
+		key := dirKey + keyName(node.Name())
+		if node.IsDir() {
+			key += "/"
+			// Skip directories which can't contain the prefix
+			if !strings.HasPrefix(key, prefix) && !strings.HasPrefix(prefix, key) {
+				continue
+			}
+			if recurse {
+				entries, err = listDir(node.(*vfs.Dir), key, prefix, recurse, entries)
+				if err != nil {
+					return nil, err
+				}
+			} else if strings.HasPrefix(key, prefix) {
+				entries = append(entries, listEntry{key: key, prefix: true})
+			}
+			continue
+		}
+		if file, ok := node.(*vfs.File); ok && strings.HasPrefix(key, prefix) {
+			entries = append(entries, listEntry{key: key, file: file})
+		}
+	}
+	return entries, nil
+}
+
+// listEntries returns the sorted keys and common prefixes in the
+// bucket matching prefix and delimiter
+func (req *request) listEntries(bucket *vfs.Dir, prefix, delimiter string) ([]listEntry, error) {
+	// Find the deepest directory which contains all the matches
+	dirKey := ""
+	if slash := strings.LastIndexByte(prefix, '/'); slash >= 0 {
+		dirKey = prefix[:slash+1]
+	}
+	var dir = bucket
+	if dirKey != "" {
+		node, err := req.vfs.Stat(vfsPath(req.bucket, dirKey))
+		if err == vfs.ENOENT || (err == nil && !node.IsDir()) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		dir = node.(*vfs.Dir)
+	}
+
+	// Only directories can be rolled up quickly
+	recurse := delimiter != "/"
+	entries, err := listDir(dir, dirKey, prefix, recurse, nil)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+	if !recurse || delimiter == "" {
+		return entries, nil
+	}
+
+	// Roll up keys containing the delimiter after the prefix
+	out := entries[:0]
+	for _, entry := range entries {
+		if i := strings.Index(entry.key[len(prefix):], delimiter); i >= 0 {
+			commonPrefix := entry.key[:len(prefix)+i+len(delimiter)]
+			if n := len(out); n > 0 && out[n-1].prefix && out[n-1].key == commonPrefix {
+				continue
+			}
+			entry = listEntry{key: commonPrefix, prefix: true}
+		}
+		out = append(out, entry)
+	}
+	return out, nil
+}
+
+// listObjects lists the objects in a bucket for ListObjects or
+// ListObjectsV2 if v2 is set
+func (req *request) listObjects(v2 bool) error {
+	query := req.r.URL.Query()
+	bucket, err := req.bucketDir()
+	if err != nil {
+		return err
+	}
+	prefix := query.Get("prefix")
+	delimiter := query.Get("delimiter")
+	encodingType := query.Get("encoding-type")
+	if encodingType != "" && encodingType != "url" {
+		return errInvalidArgument
+	}
+	maxKeys := defaultMaxKeys
+	if value := query.Get("max-keys"); value != "" {
+		maxKeys, err = strconv.Atoi(value)
+		if err != nil || maxKeys < 0 {
+			return errInvalidArgument
+		}
+		if maxKeys > maxMaxKeys {
+			maxKeys = maxMaxKeys
+		}
+	}
+
+	// Find where to start the listing
+	result := &listBucketResult{
+		Xmlns:        s3Namespace,
+		Name:         req.bucket,
+		Prefix:       prefix,
+		Delimiter:    delimiter,
+		MaxKeys:      maxKeys,
+		EncodingType: encodingType,
+	}
+	var marker string
+	if v2 {
+		result.ContinuationToken = query.Get("continuation-token")
+		result.StartAfter = query.Get("start-after")
+		marker = result.StartAfter
+		if result.ContinuationToken != "" {
+			marker = result.ContinuationToken
+		}
+	} else {
+		marker = query.Get("marker")
+		result.Marker = &marker
+	}
+
+	entries, err := req.listEntries(bucket, prefix, delimiter)
+	if err != nil {
+		return err
+	}
+	// Skip entries up to the marker, keeping any common prefix
+	// which may contain keys after it
+	if marker != "" {
+		out := entries[:0]
+		for _, entry := range entries {
+			if entry.key > marker || (entry.prefix && entry.key != marker && strings.HasPrefix(marker, entry.key)) {
+				out = append(out, entry)
+			}
+		}
+		entries = out
+	}
+	if len(entries) > maxKeys {
+		entries = entries[:maxKeys]
+		result.IsTruncated = true
+	}
+
+	fetchOwner := !v2 || query.Get("fetch-owner") == "true"
+	for _, entry := range entries {
+		if entry.prefix {
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: entry.key})
+			continue
+		}
+		obj := object{
+			Key:          entry.key,
+			LastModified: formatTime(entry.file.ModTime()),
+			ETag:         fileETag(req.ctx, entry.file, true),
+			Size:         entry.file.Size(),
+			StorageClass: "STANDARD",
+		}
+		if fetchOwner {
+			obj.Owner = &serverOwner
+		}
+		result.Contents = append(result.Contents, obj)
+	}
+	if result.IsTruncated {
+		last := entries[len(entries)-1].key
+		if v2 {
+			result.NextContinuationToken = last
+		} else {
+			result.NextMarker = last
+		}
+	}
+	if v2 {
+		keyCount := len(entries)
+		result.KeyCount = &keyCount
+	}
+
+	if encodingType == "url" {
+		encode := func(s string) string {
+			return uriEncode(s, false)
+		}
+		result.Prefix = encode(result.Prefix)
+		result.Delimiter = encode(result.Delimiter)
+		result.StartAfter = encode(result.StartAfter)
+		result.NextMarker = encode(result.NextMarker)
+		if result.Marker != nil {
+			encoded := encode(*result.Marker)
+			result.Marker = &encoded
+		}
+		for i := range result.Contents {
+			result.Contents[i].Key = encode(result.Contents[i].Key)
+		}
+		for i := range result.CommonPrefixes {
+			result.CommonPrefixes[i].Prefix = encode(result.CommonPrefixes[i].Prefix)
+		}
+		writeXML(req.w, http.StatusOK, result)
+		return nil
+	}
+	writeListXML(req.w, result)
+	return nil
+}
+
+// isXMLChar returns true if r can be represented in XML 1.0
+func isXMLChar(r rune) bool {
+	return r == 0x09 || r == 0x0A || r == 0x0D ||
+		(r >= 0x20 && r <= 0xD7FF) ||
+		(r >= 0xE000 && r <= 0xFFFD) ||
+		(r >= 0x10000 && r <= utf8.MaxRune)
+}
+
+// writeListXML writes a listing which isn't URL encoded.
+//
+// Go's XML encoder silently replaces characters which can't be
+// represented in XML, so these are written as character references
+// instead. Like AWS this makes the response invalid XML, which
+// clients such as rclone detect and retry the listing with URL
+// encoding.
+func writeListXML(w http.ResponseWriter, result *listBucketResult) {
+	placeholder := "rclone" + strings.ToLower(newID()) + "x"
+	found := false
+	escape := func(s string) string {
+		if strings.IndexFunc(s, func(r rune) bool { return !isXMLChar(r) }) < 0 {
+			return s
+		}
+		found = true
+		var out strings.Builder
+		for _, r := range s {
+			if isXMLChar(r) {
+				out.WriteRune(r)
+			} else {
+				out.WriteString(placeholder + strconv.FormatInt(int64(r), 16) + ";")
+			}
+		}
+		return out.String()
+	}
+	for i := range result.Contents {
+		result.Contents[i].Key = escape(result.Contents[i].Key)
+	}
+	for i := range result.CommonPrefixes {
+		result.CommonPrefixes[i].Prefix = escape(result.CommonPrefixes[i].Prefix)
+	}
+	result.NextMarker = escape(result.NextMarker)
+	result.NextContinuationToken = escape(result.NextContinuationToken)
+	if !found {
+		writeXML(w, http.StatusOK, result)
+		return
+	}
+	data, err := xml.Marshal(result)
+	if err != nil {
+		fs.Errorf(nil, "serve s3: failed to marshal response: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	data = bytes.Replace(data, []byte(placeholder), []byte("&#x"), -1)
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,168 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/cmd/serve/httplib"
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newListServer makes a server with no auth keys serving a bucket
+// containing files
+func newListServer(t *testing.T, files ...string) (s *Server, cleanup func()) {
+	dir, err := ioutil.TempDir("", "rclone-serve-s3-test")
+	require.NoError(t, err)
+	for _, file := range files {
+		p := filepath.Join(dir, "bucket", filepath.FromSlash(file))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0777))
+		require.NoError(t, ioutil.WriteFile(p, []byte(file), 0666))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "empty"), 0777))
+	f, err := fs.NewFs(context.Background(), dir)
+	require.NoError(t, err)
+	opt := httplib.DefaultOpt
+	s, err = newServer(context.Background(), f, &opt, &DefaultOpt)
+	require.NoError(t, err)
+	return s, func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}
+}
+
+// list does a listing of the bucket with the query given
+func list(t *testing.T, s *Server, query string) (result listBucketResult) {
+	w := httptest.NewRecorder()
+	s.handler(w, httptest.NewRequest("GET", "/bucket?"+query, nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &result))
+	return result
+}
+
+func keys(result listBucketResult) (keys []string) {
+	for _, object := range result.Contents {
+		keys = append(keys, object.Key)
+	}
+	for _, prefix := range result.CommonPrefixes {
+		keys = append(keys, prefix.Prefix+"*")
+	}
+	return keys
+}
+
+func TestListObjects(t *testing.T) {
+	s, cleanup := newListServer(t,
+		"a.txt",
+		"b/one.txt",
+		"b/two.txt",
+		"b/c/three.txt",
+		"b-c.txt",
+		"d/e-f/four.txt",
+		tempPrefix+"ignored",
+	)
+	defer cleanup()
+
+	for _, test := range []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"a.txt", "b-c.txt", "b/c/three.txt", "b/one.txt", "b/two.txt", "d/e-f/four.txt"}},
+		{"delimiter=/", []string{"a.txt", "b-c.txt", "b/*", "d/*"}},
+		{"prefix=b&delimiter=/", []string{"b-c.txt", "b/*"}},
+		{"prefix=b/&delimiter=/", []string{"b/one.txt", "b/two.txt", "b/c/*"}},
+		{"prefix=b/t", []string{"b/two.txt"}},
+		{"prefix=z/", nil},
+		{"delimiter=-", []string{"a.txt", "b/c/three.txt", "b/one.txt", "b/two.txt", "b-*", "d/e-*"}},
+		{"prefix=d/&delimiter=-", []string{"d/e-*"}},
+		{"marker=b/one.txt", []string{"b/two.txt", "d/e-f/four.txt"}},
+		{"list-type=2&start-after=b/c/three.txt&delimiter=/", []string{"b/*", "d/*"}},
+		{"list-type=2&start-after=b/&delimiter=/", []string{"d/*"}},
+	} {
+		t.Run(test.query, func(t *testing.T) {
+			assert.Equal(t, test.want, keys(list(t, s, test.query)))
+		})
+	}
+}
+
+func TestListObjectsPaging(t *testing.T) {
+	s, cleanup := newListServer(t, "1", "2", "3", "4/5")
+	defer cleanup()
+
+	result := list(t, s, "max-keys=2")
+	assert.Equal(t, []string{"1", "2"}, keys(result))
+	assert.True(t, result.IsTruncated)
+	assert.Equal(t, "2", result.NextMarker)
+
+	result = list(t, s, "max-keys=2&marker=2")
+	assert.Equal(t, []string{"3", "4/5"}, keys(result))
+	assert.False(t, result.IsTruncated)
+
+	result = list(t, s, "list-type=2&max-keys=2&delimiter=/&continuation-token=2")
+	assert.Equal(t, []string{"3", "4/*"}, keys(result))
+	assert.False(t, result.IsTruncated)
+	require.NotNil(t, result.KeyCount)
+	assert.Equal(t, 2, *result.KeyCount)
+}
+
+func TestListObjectsEncoding(t *testing.T) {
+	s, cleanup := newListServer(t, "odd\uffff.txt", "plain.txt")
+	defer cleanup()
+
+	result := list(t, s, "encoding-type=url")
+	assert.Equal(t, []string{"odd%EF%BF%BF.txt", "plain.txt"}, keys(result))
+
+	// Without URL encoding the response is invalid XML
+	w := httptest.NewRecorder()
+	s.handler(w, httptest.NewRequest("GET", "/bucket", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.Contains(w.Body.String(), "<Key>odd&#xffff;.txt</Key>"), w.Body.String())
+	assert.Error(t, xml.Unmarshal(w.Body.Bytes(), &result))
+}
+
+func TestListObjectsControlChars(t *testing.T) {
+	s, cleanup := newListServer(t, "\nleading LF/file.txt")
+	defer cleanup()
+
+	// The VFS lists "␊leading LF" which must be listed as the key
+	result := list(t, s, "encoding-type=url")
+	assert.Equal(t, []string{"%0Aleading%20LF/file.txt"}, keys(result))
+	result = list(t, s, "encoding-type=url&prefix=%0Aleading%20LF/&delimiter=/")
+	assert.Equal(t, []string{"%0Aleading%20LF/file.txt"}, keys(result))
+}
+
+func TestListBuckets(t *testing.T) {
+	s, cleanup := newListServer(t, "file.txt")
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	s.handler(w, httptest.NewRequest("GET", "/", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var result listAllMyBucketsResult
+	require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &result))
+	var names []string
+	for _, bucket := range result.Buckets {
+		names = append(names, bucket.Name)
+	}
+	assert.Equal(t, []string{"bucket", "empty"}, names)
+
+	w = httptest.NewRecorder()
+	s.handler(w, httptest.NewRequest("GET", "/missing", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.True(t, strings.Contains(w.Body.String(), "<Code>NoSuchBucket</Code>"))
+
+	w = httptest.NewRecorder()
+	s.handler(w, httptest.NewRequest("DELETE", "/bucket", nil))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = httptest.NewRecorder()
+	s.handler(w, httptest.NewRequest("DELETE", "/empty", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
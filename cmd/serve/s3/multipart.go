@@ -0,0 +1,383 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// Limits on multipart uploads
+const (
+	maxPartNumber  = 10000
+	defaultMaxList = 1000
+)
+
+// uploadedPart is a part of a multipart upload stored on disk
+type uploadedPart struct {
+	md5     []byte
+	size    int64
+	modTime time.Time
+}
+
+// multipartUpload is a multipart upload in progress.
+//
+// The parts are stored in a local temporary directory until the
+// upload is completed.
+type multipartUpload struct {
+	mu          sync.Mutex
+	id          string
+	accessKeyID string // the user who started the upload
+	bucket      string
+	key         string
+	initiated   time.Time
+	modTime     time.Time // from the metadata, zero if not set
+	dir         string    // local directory holding the parts
+	parts       map[int]*uploadedPart
+	completing  bool
+}
+
+// partPath returns the local path of the part
+func (u *multipartUpload) partPath(partNumber int) string {
+	return filepath.Join(u.dir, strconv.Itoa(partNumber))
+}
+
+// remove deletes the parts from disk
+func (u *multipartUpload) remove() {
+	if err := os.RemoveAll(u.dir); err != nil {
+		fs.Errorf(nil, "serve s3: failed to remove multipart upload %s: %v", u.id, err)
+	}
+}
+
+// getUpload finds the upload from the uploadId parameter
+func (req *request) getUpload() (*multipartUpload, error) {
+	id := req.r.URL.Query().Get("uploadId")
+	req.s.mu.Lock()
+	upload, ok := req.s.uploads[id]
+	req.s.mu.Unlock()
+	if !ok || upload.bucket != req.bucket || upload.key != req.key || upload.accessKeyID != req.accessKeyID {
+		return nil, errNoSuchUpload
+	}
+	return upload, nil
+}
+
+// createMultipartUpload serves CreateMultipartUpload
+func (req *request) createMultipartUpload() error {
+	if _, err := req.bucketDir(); err != nil {
+		return err
+	}
+	dir, err := ioutil.TempDir("", "rclone-serve-s3-")
+	if err != nil {
+		return err
+	}
+	upload := &multipartUpload{
+		id:          newID(),
+		accessKeyID: req.accessKeyID,
+		bucket:      req.bucket,
+		key:         req.key,
+		initiated:   time.Now(),
+		modTime:     requestModTime(req.r),
+		dir:         dir,
+		parts:       map[int]*uploadedPart{},
+	}
+	req.s.mu.Lock()
+	req.s.uploads[upload.id] = upload
+	req.s.mu.Unlock()
+	writeXML(req.w, http.StatusOK, &initiateMultipartUploadResult{
+		Xmlns:    s3Namespace,
+		Bucket:   req.bucket,
+		Key:      req.key,
+		UploadID: upload.id,
+	})
+	return nil
+}
+
+// uploadPart serves UploadPart
+func (req *request) uploadPart() error {
+	upload, err := req.getUpload()
+	if err != nil {
+		return err
+	}
+	partNumber, err := strconv.Atoi(req.r.URL.Query().Get("partNumber"))
+	if err != nil || partNumber < 1 || partNumber > maxPartNumber {
+		return errInvalidArgument
+	}
+	wantMD5, err := contentMD5(req.r)
+	if err != nil {
+		return err
+	}
+
+	// Write the part to a temporary file then rename it into place
+	// so an overwritten part is only replaced when complete
+	out, err := ioutil.TempFile(upload.dir, "part-")
+	if err != nil {
+		return err
+	}
+	hasher := md5.New()
+	size, err := io.Copy(io.MultiWriter(out, hasher), req.r.Body)
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	sum := hasher.Sum(nil)
+	if err == nil && wantMD5 != nil && string(sum) != string(wantMD5) {
+		err = errBadDigest
+	}
+	if err != nil {
+		_ = os.Remove(out.Name())
+		return err
+	}
+
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+	if upload.completing {
+		_ = os.Remove(out.Name())
+		return errNoSuchUpload
+	}
+	if err = os.Rename(out.Name(), upload.partPath(partNumber)); err != nil {
+		_ = os.Remove(out.Name())
+		return err
+	}
+	upload.parts[partNumber] = &uploadedPart{
+		md5:     sum,
+		size:    size,
+		modTime: time.Now(),
+	}
+	req.w.Header().Set("ETag", `"`+hex.EncodeToString(sum)+`"`)
+	req.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// partReader reads the parts of an upload one after another
+type partReader struct {
+	upload  *multipartUpload
+	numbers []int
+	current *os.File
+}
+
+// Read bytes from the parts
+func (p *partReader) Read(buf []byte) (n int, err error) {
+	for {
+		if p.current == nil {
+			if len(p.numbers) == 0 {
+				return 0, io.EOF
+			}
+			p.current, err = os.Open(p.upload.partPath(p.numbers[0]))
+			if err != nil {
+				return 0, err
+			}
+			p.numbers = p.numbers[1:]
+		}
+		n, err = p.current.Read(buf)
+		if err == io.EOF {
+			_ = p.current.Close()
+			p.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+// Close any open part
+func (p *partReader) Close() {
+	if p.current != nil {
+		_ = p.current.Close()
+	}
+}
+
+// completeMultipartUpload serves CompleteMultipartUpload
+func (req *request) completeMultipartUpload() error {
+	upload, err := req.getUpload()
+	if err != nil {
+		return err
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(req.r.Body, 4*1024*1024))
+	if err != nil {
+		return err
+	}
+	var in completeMultipartUploadRequest
+	if err = xml.Unmarshal(body, &in); err != nil || len(in.Parts) == 0 {
+		return errMalformedXML
+	}
+
+	upload.mu.Lock()
+	if upload.completing {
+		upload.mu.Unlock()
+		return errNoSuchUpload
+	}
+	// Check the parts and calculate the ETag
+	numbers := make([]int, 0, len(in.Parts))
+	etagHasher := md5.New()
+	for i, p := range in.Parts {
+		if i > 0 && p.PartNumber <= in.Parts[i-1].PartNumber {
+			upload.mu.Unlock()
+			return errInvalidPartOrder
+		}
+		stored, ok := upload.parts[p.PartNumber]
+		if !ok || strings.Trim(p.ETag, `"`) != hex.EncodeToString(stored.md5) {
+			upload.mu.Unlock()
+			return errInvalidPart
+		}
+		numbers = append(numbers, p.PartNumber)
+		_, _ = etagHasher.Write(stored.md5)
+	}
+	upload.completing = true
+	upload.mu.Unlock()
+
+	// Concatenate the parts into the object
+	remote := req.objectPath()
+	parts := &partReader{upload: upload, numbers: numbers}
+	_, err = writeFile(req.vfs, remote, parts, nil)
+	parts.Close()
+	if err != nil {
+		upload.mu.Lock()
+		upload.completing = false
+		upload.mu.Unlock()
+		return err
+	}
+	setModTime(req.vfs, remote, upload.modTime)
+
+	req.s.mu.Lock()
+	delete(req.s.uploads, upload.id)
+	req.s.mu.Unlock()
+	upload.remove()
+
+	etag := fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(etagHasher.Sum(nil)), len(numbers))
+	writeXML(req.w, http.StatusOK, &completeMultipartUploadResult{
+		Xmlns:    s3Namespace,
+		Location: "/" + req.bucket + "/" + req.key,
+		Bucket:   req.bucket,
+		Key:      req.key,
+		ETag:     etag,
+	})
+	return nil
+}
+
+// abortMultipartUpload serves AbortMultipartUpload
+func (req *request) abortMultipartUpload() error {
+	upload, err := req.getUpload()
+	if err != nil {
+		return err
+	}
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+	if upload.completing {
+		return errNoSuchUpload
+	}
+	upload.completing = true
+	req.s.mu.Lock()
+	delete(req.s.uploads, upload.id)
+	req.s.mu.Unlock()
+	upload.remove()
+	req.w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// listParts serves ListParts
+func (req *request) listParts() error {
+	upload, err := req.getUpload()
+	if err != nil {
+		return err
+	}
+	query := req.r.URL.Query()
+	maxParts := defaultMaxList
+	if value := query.Get("max-parts"); value != "" {
+		maxParts, err = strconv.Atoi(value)
+		if err != nil || maxParts < 0 {
+			return errInvalidArgument
+		}
+	}
+	marker := 0
+	if value := query.Get("part-number-marker"); value != "" {
+		marker, err = strconv.Atoi(value)
+		if err != nil {
+			return errInvalidArgument
+		}
+	}
+	result := &listPartsResult{
+		Xmlns:            s3Namespace,
+		Bucket:           req.bucket,
+		Key:              req.key,
+		UploadID:         upload.id,
+		PartNumberMarker: marker,
+		MaxParts:         maxParts,
+	}
+	upload.mu.Lock()
+	numbers := make([]int, 0, len(upload.parts))
+	for n := range upload.parts {
+		if n > marker {
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Ints(numbers)
+	if len(numbers) > maxParts {
+		numbers = numbers[:maxParts]
+		result.IsTruncated = true
+	}
+	for _, n := range numbers {
+		p := upload.parts[n]
+		result.Parts = append(result.Parts, part{
+			PartNumber:   n,
+			LastModified: formatTime(p.modTime),
+			ETag:         `"` + hex.EncodeToString(p.md5) + `"`,
+			Size:         p.size,
+		})
+		result.NextPartNumberMarker = n
+	}
+	upload.mu.Unlock()
+	writeXML(req.w, http.StatusOK, result)
+	return nil
+}
+
+// listMultipartUploads serves ListMultipartUploads
+func (req *request) listMultipartUploads() error {
+	if _, err := req.bucketDir(); err != nil {
+		return err
+	}
+	prefix := req.r.URL.Query().Get("prefix")
+	result := &listMultipartUploadsResult{
+		Xmlns:      s3Namespace,
+		Bucket:     req.bucket,
+		Prefix:     prefix,
+		MaxUploads: defaultMaxList,
+	}
+	req.s.mu.Lock()
+	for _, upload := range req.s.uploads {
+		if upload.bucket == req.bucket && upload.accessKeyID == req.accessKeyID && strings.HasPrefix(upload.key, prefix) {
+			result.Uploads = append(result.Uploads, uploadInfo{
+				Key:       upload.key,
+				UploadID:  upload.id,
+				Initiated: formatTime(upload.initiated),
+			})
+		}
+	}
+	req.s.mu.Unlock()
+	sort.Slice(result.Uploads, func(i, j int) bool {
+		a, b := result.Uploads[i], result.Uploads[j]
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.Initiated < b.Initiated
+	})
+	if len(result.Uploads) > defaultMaxList {
+		result.Uploads = result.Uploads[:defaultMaxList]
+		result.IsTruncated = true
+	}
+	writeXML(req.w, http.StatusOK, result)
+	return nil
+}
@@ -0,0 +1,441 @@
+package s3
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ncw/swift"
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/vfs"
+)
+
+// metaMtime is the header used by rclone's s3 backend for the
+// modification time
+const metaMtime = "X-Amz-Meta-Mtime"
+
+// maxDeleteObjects is the most keys DeleteObjects will take
+const maxDeleteObjects = 1000
+
+// tempPrefix is the prefix of the names that uploads are written to
+// before being renamed into place. These are hidden from S3 clients.
+const tempPrefix = ".rclone-s3-upload-"
+
+// fileETag returns the quoted ETag for file.
+//
+// This is the MD5 if available, otherwise one made from the
+// modification time and size. If listing is set then the MD5 is only
+// used if it doesn't need the object to be read.
+func fileETag(ctx context.Context, file *vfs.File, listing bool) string {
+	if o, ok := file.DirEntry().(fs.Object); ok {
+		f := o.Fs()
+		if f.Hashes().Contains(hash.MD5) && !(listing && f.Features().SlowHash) {
+			sum, err := o.Hash(ctx, hash.MD5)
+			if err == nil && sum != "" {
+				return `"` + sum + `"`
+			}
+		}
+	}
+	return fmt.Sprintf(`"%x-%x"`, file.ModTime().UnixNano(), file.Size())
+}
+
+// requestModTime returns the modification time from the metadata of
+// the request or the zero time if not set
+func requestModTime(r *http.Request) time.Time {
+	value := r.Header.Get(metaMtime)
+	if value == "" {
+		return time.Time{}
+	}
+	modTime, err := swift.FloatStringToTime(value)
+	if err != nil {
+		fs.Debugf(r.URL.Path, "serve s3: ignoring bad %s %q: %v", metaMtime, value, err)
+		return time.Time{}
+	}
+	return modTime
+}
+
+// statFile returns the file for the object
+func (req *request) statFile() (*vfs.File, error) {
+	if _, err := req.bucketDir(); err != nil {
+		return nil, err
+	}
+	node, err := req.vfs.Stat(req.objectPath())
+	if err == vfs.ENOENT || (err == nil && !node.IsFile()) {
+		return nil, errNoSuchKey
+	} else if err != nil {
+		return nil, err
+	}
+	return node.(*vfs.File), nil
+}
+
+// getObject serves GetObject and HeadObject
+func (req *request) getObject() error {
+	file, err := req.statFile()
+	if err != nil {
+		return err
+	}
+	w, r := req.w, req.r
+	header := w.Header()
+	header.Set("ETag", fileETag(req.ctx, file, false))
+	header.Set(metaMtime, swift.TimeToFloatString(file.ModTime()))
+	header.Set("Last-Modified", file.ModTime().UTC().Format(http.TimeFormat))
+	header.Set("Accept-Ranges", "bytes")
+	if o, ok := file.DirEntry().(fs.Object); ok {
+		header.Set("Content-Type", fs.MimeType(req.ctx, o))
+	} else {
+		header.Set("Content-Type", fs.MimeTypeFromName(file.Name()))
+	}
+	query := r.URL.Query()
+	for _, override := range []string{"Content-Type", "Content-Language", "Expires", "Cache-Control", "Content-Disposition", "Content-Encoding"} {
+		if value := query.Get("response-" + strings.ToLower(override)); value != "" {
+			header.Set(override, value)
+		}
+	}
+	if r.Method == "HEAD" {
+		header.Set("Content-Length", strconv.FormatInt(file.Size(), 10))
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+
+	in, err := file.Open(os.O_RDONLY)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := in.Close()
+		if err != nil {
+			fs.Errorf(req.objectPath(), "Failed to close file: %v", err)
+		}
+	}()
+
+	// Account the transfer
+	if o, ok := file.DirEntry().(fs.Object); ok {
+		tr := accounting.Stats(req.ctx).NewTransfer(o)
+		defer tr.Done(req.ctx, nil)
+	}
+
+	// ServeContent deals with ranges and conditional requests
+	http.ServeContent(w, r, file.Name(), file.ModTime(), in)
+	return nil
+}
+
+// contentMD5 returns the expected MD5 from the Content-MD5 header or
+// nil if not set
+func contentMD5(r *http.Request) ([]byte, error) {
+	value := r.Header.Get("Content-MD5")
+	if value == "" {
+		return nil, nil
+	}
+	sum, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(sum) != md5.Size {
+		return nil, errInvalidDigest
+	}
+	return sum, nil
+}
+
+// writeFile writes in to the file at remote in the VFS returning the
+// MD5 of the data written.
+//
+// If the remote can rename files then the data is written to a
+// temporary name first so that incomplete uploads are never seen,
+// otherwise the file is removed if the write fails.
+func writeFile(VFS *vfs.VFS, remote string, in io.Reader, wantMD5 []byte) (sum []byte, err error) {
+	if err = mkdirAll(VFS, path.Dir(remote)); err != nil {
+		return nil, err
+	}
+	target := remote
+	if features := VFS.Fs().Features(); features.Move != nil || features.Copy != nil {
+		target = path.Join(path.Dir(remote), tempPrefix+strings.ToLower(newID()))
+	}
+	handle, err := VFS.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, err
+	}
+	hasher := md5.New()
+	_, err = io.Copy(io.MultiWriter(handle, hasher), in)
+	if err == nil {
+		sum = hasher.Sum(nil)
+		if wantMD5 != nil && string(sum) != string(wantMD5) {
+			err = errBadDigest
+		}
+	}
+	closeErr := handle.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil && target != remote {
+		err = VFS.Rename(target, remote)
+	}
+	if err != nil {
+		if removeErr := VFS.Remove(target); removeErr != nil && removeErr != vfs.ENOENT {
+			fs.Errorf(target, "serve s3: failed to remove incomplete upload: %v", removeErr)
+		}
+		return nil, err
+	}
+	return sum, nil
+}
+
+// setModTime sets the modification time of remote if modTime is set
+func setModTime(VFS *vfs.VFS, remote string, modTime time.Time) {
+	if modTime.IsZero() {
+		return
+	}
+	if err := VFS.Chtimes(remote, modTime, modTime); err != nil {
+		fs.Errorf(remote, "serve s3: failed to set modification time: %v", err)
+	}
+}
+
+// putObject serves PutObject
+func (req *request) putObject() error {
+	if _, err := req.bucketDir(); err != nil {
+		return err
+	}
+	r := req.r
+	remote := req.objectPath()
+
+	// A zero length key ending in / makes a directory
+	if strings.HasSuffix(req.key, "/") {
+		if r.ContentLength > 0 {
+			return &apiError{http.StatusBadRequest, "InvalidArgument", "Keys ending in / must be empty."}
+		}
+		if err := mkdirAll(req.vfs, remote); err != nil {
+			return err
+		}
+		req.w.Header().Set("ETag", `"`+hex.EncodeToString(md5.New().Sum(nil))+`"`)
+		req.w.WriteHeader(http.StatusOK)
+		return nil
+	}
+
+	wantMD5, err := contentMD5(r)
+	if err != nil {
+		return err
+	}
+	sum, err := writeFile(req.vfs, remote, r.Body, wantMD5)
+	if err != nil {
+		return err
+	}
+	setModTime(req.vfs, remote, requestModTime(r))
+	req.w.Header().Set("ETag", `"`+hex.EncodeToString(sum)+`"`)
+	req.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// parseCopySource parses the X-Amz-Copy-Source header into a bucket
+// and key
+func parseCopySource(value string) (bucket, key string, err error) {
+	if i := strings.Index(value, "?"); i >= 0 {
+		value = value[:i]
+	}
+	value, err = url.PathUnescape(value)
+	if err != nil {
+		return "", "", errInvalidArgument
+	}
+	bucket, key = splitPath(value)
+	if bucket == "" || key == "" {
+		return "", "", errInvalidArgument
+	}
+	if err = checkBucketName(bucket); err != nil {
+		return "", "", err
+	}
+	if err = checkKey(key); err != nil {
+		return "", "", err
+	}
+	return bucket, key, nil
+}
+
+// copyObject serves CopyObject
+func (req *request) copyObject() error {
+	r := req.r
+	if _, err := req.bucketDir(); err != nil {
+		return err
+	}
+	srcBucket, srcKey, err := parseCopySource(r.Header.Get("X-Amz-Copy-Source"))
+	if err != nil {
+		return err
+	}
+	srcReq := *req
+	srcReq.bucket, srcReq.key = srcBucket, srcKey
+	srcFile, err := srcReq.statFile()
+	if err != nil {
+		return err
+	}
+	srcRemote, dstRemote := srcReq.objectPath(), req.objectPath()
+
+	modTime := time.Time{}
+	switch directive := r.Header.Get("X-Amz-Metadata-Directive"); directive {
+	case "", "COPY":
+		if srcRemote == dstRemote {
+			return &apiError{http.StatusBadRequest, "InvalidRequest", "This copy request is illegal because it is trying to copy an object to itself without changing the object's metadata."}
+		}
+	case "REPLACE":
+		modTime = requestModTime(r)
+	default:
+		return errInvalidArgument
+	}
+
+	if srcRemote != dstRemote {
+		err = req.copyFile(srcFile, dstRemote)
+		if err != nil {
+			return err
+		}
+	}
+	setModTime(req.vfs, dstRemote, modTime)
+
+	node, err := req.vfs.Stat(dstRemote)
+	if err != nil {
+		return err
+	}
+	dstFile, ok := node.(*vfs.File)
+	if !ok {
+		return errNoSuchKey
+	}
+	writeXML(req.w, http.StatusOK, &copyObjectResult{
+		Xmlns:        s3Namespace,
+		LastModified: formatTime(dstFile.ModTime()),
+		ETag:         fileETag(req.ctx, dstFile, false),
+	})
+	return nil
+}
+
+// copyFile copies srcFile to dstRemote, server side if possible
+func (req *request) copyFile(srcFile *vfs.File, dstRemote string) error {
+	if err := mkdirAll(req.vfs, path.Dir(dstRemote)); err != nil {
+		return err
+	}
+	if srcObj, ok := srcFile.DirEntry().(fs.Object); ok {
+		_, err := operations.Copy(req.ctx, req.vfs.Fs(), nil, dstRemote, srcObj)
+		if err != nil {
+			return err
+		}
+		// Make the VFS read the new object
+		root, err := req.vfs.Root()
+		if err != nil {
+			return err
+		}
+		root.ForgetPath(dstRemote, fs.EntryObject)
+		return nil
+	}
+
+	// The source is being written so copy it through the VFS
+	in, err := srcFile.Open(os.O_RDONLY)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+	if _, err = writeFile(req.vfs, dstRemote, in, nil); err != nil {
+		return err
+	}
+	setModTime(req.vfs, dstRemote, srcFile.ModTime())
+	return nil
+}
+
+// removeFile removes the file at remote and any directories which
+// are left empty, stopping at the bucket
+func removeFile(VFS *vfs.VFS, remote string) error {
+	node, err := VFS.Stat(remote)
+	if err == vfs.ENOENT || (err == nil && node.IsDir()) {
+		// Deleting a key which doesn't exist is not an error
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err = node.Remove(); err != nil {
+		return err
+	}
+	for dir := path.Dir(remote); strings.Contains(dir, "/"); dir = path.Dir(dir) {
+		node, err := VFS.Stat(dir)
+		if err != nil || !node.IsDir() {
+			break
+		}
+		nodes, err := node.(*vfs.Dir).ReadDirAll()
+		if err != nil || len(nodes) != 0 {
+			break
+		}
+		if err = node.Remove(); err != nil {
+			fs.Debugf(dir, "serve s3: failed to remove empty directory: %v", err)
+			break
+		}
+	}
+	return nil
+}
+
+// deleteObject serves DeleteObject
+func (req *request) deleteObject() error {
+	if _, err := req.bucketDir(); err != nil {
+		return err
+	}
+	remote := req.objectPath()
+	if strings.HasSuffix(req.key, "/") {
+		// Remove a directory marker if it is empty
+		if node, err := req.vfs.Stat(remote); err == nil && node.IsDir() {
+			if nodes, err := node.(*vfs.Dir).ReadDirAll(); err == nil && len(nodes) == 0 {
+				_ = node.Remove()
+			}
+		}
+	} else if err := removeFile(req.vfs, remote); err != nil {
+		return err
+	}
+	req.w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// deleteObjects serves DeleteObjects
+func (req *request) deleteObjects() error {
+	if _, err := req.bucketDir(); err != nil {
+		return err
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(req.r.Body, 4*1024*1024))
+	if err != nil {
+		return err
+	}
+	var in deleteRequest
+	if err = xml.Unmarshal(body, &in); err != nil {
+		return errMalformedXML
+	}
+	if len(in.Objects) > maxDeleteObjects {
+		return errMalformedXML
+	}
+	result := &deleteResult{Xmlns: s3Namespace}
+	for _, object := range in.Objects {
+		err := checkKey(object.Key)
+		if err == nil {
+			err = removeFile(req.vfs, vfsPath(req.bucket, object.Key))
+		}
+		if err != nil {
+			apiErr, ok := errors.Cause(err).(*apiError)
+			if !ok {
+				fs.Errorf(object.Key, "serve s3: failed to delete: %v", err)
+				apiErr = vfsError(err)
+			}
+			result.Errors = append(result.Errors, deleteError{
+				Key:     object.Key,
+				Code:    apiErr.Code,
+				Message: apiErr.Message,
+			})
+			continue
+		}
+		if !in.Quiet {
+			result.Deleted = append(result.Deleted, deletedObject{Key: object.Key})
+		}
+	}
+	writeXML(req.w, http.StatusOK, result)
+	return nil
+}
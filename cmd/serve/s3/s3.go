@@ -0,0 +1,496 @@
+// Package s3 implements an S3 compatible server backed by rclone VFS
+package s3
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/cmd/serve/httplib"
+	"github.com/rclone/rclone/cmd/serve/httplib/httpflags"
+	"github.com/rclone/rclone/cmd/serve/proxy"
+	"github.com/rclone/rclone/cmd/serve/proxy/proxyflags"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/lib/encoder"
+	"github.com/rclone/rclone/vfs"
+	"github.com/rclone/rclone/vfs/vfsflags"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Options contains options for the s3 server
+type Options struct {
+	AuthKeys []string // access_key_id,secret_access_key pairs
+}
+
+// DefaultOpt is the default values used for Options
+var DefaultOpt = Options{}
+
+// Opt is options set by command line flags
+var Opt = DefaultOpt
+
+// AddFlags adds flags for the s3 server
+func AddFlags(flagSet *pflag.FlagSet) {
+	flags.StringArrayVarP(flagSet, &Opt.AuthKeys, "auth-key", "", Opt.AuthKeys, "Set key pair for v4 authorization, split by comma (access_key_id,secret_access_key)")
+}
+
+func init() {
+	flagSet := Command.Flags()
+	httpflags.AddFlags(flagSet)
+	vfsflags.AddFlags(flagSet)
+	proxyflags.AddFlags(flagSet)
+	AddFlags(flagSet)
+}
+
+// Command definition for cobra
+var Command = &cobra.Command{
+	Use:   "s3 remote:path",
+	Short: `Serve remote:path over s3.`,
+	Long: `
+rclone serve s3 implements a basic S3 server that serves the remote
+via the S3 API. It can be used by any S3 client, including rclone's
+own s3 backend, to read and write the remote.
+
+The directories at the root of remote:path are served as buckets and
+the files within them as objects. Only path style addressing is
+supported, so S3 clients should be configured with
+` + "`force_path_style = true`" + ` or equivalent.
+
+For example, to serve a remote over S3 with authentication
+
+    rclone serve s3 --auth-key ACCESS_KEY_ID,SECRET_ACCESS_KEY remote:path
+
+and use it with rclone with a remote configured like this. The
+` + "`Rclone`" + ` provider sets path style addressing and turns off the S3
+features which the server doesn't support.
+
+    [serves3]
+    type = s3
+    provider = Rclone
+    access_key_id = ACCESS_KEY_ID
+    secret_access_key = SECRET_ACCESS_KEY
+    endpoint = http://127.0.0.1:8080/
+
+### S3 options
+
+#### --auth-key
+
+Requests are authenticated with AWS signature version 4, using the
+key pairs supplied with ` + "`--auth-key access_key_id,secret_access_key`" + `.
+This flag can be repeated to supply more than one key pair. Both
+signed headers and presigned URLs are accepted.
+
+If no ` + "`--auth-key`" + ` is supplied then all requests are allowed
+without checking their signatures. The ` + "`--user`, `--pass` and `--htpasswd`" + `
+flags can't be used with serve s3.
+
+#### Supported operations
+
+The following S3 operations are supported:
+
+- ListBuckets, CreateBucket, DeleteBucket, HeadBucket, GetBucketLocation
+- ListObjects and ListObjectsV2
+- GetObject (including range requests), HeadObject
+- PutObject, CopyObject
+- DeleteObject, DeleteObjects
+- CreateMultipartUpload, UploadPart, CompleteMultipartUpload,
+  AbortMultipartUpload, ListParts, ListMultipartUploads
+
+Other operations, such as ACLs, versioning, tagging and lifecycle
+rules, are not supported and return an error.
+
+#### Object metadata
+
+The modification time of an object is set from the
+` + "`X-Amz-Meta-Mtime`" + ` header as used by rclone's s3 backend, and
+returned in the same header. Other metadata is not stored.
+
+The ETag of an object is its MD5 checksum if the remote supports MD5,
+otherwise it is made from the modification time and size. In
+listings the MD5 is only used if the remote can read it without
+reading the object, as the local backend needs to.
+
+Multipart uploads are stored in a temporary directory until they are
+completed, and are lost if the server is restarted.
+
+#### Authenticating with --auth-proxy
+
+If ` + "`--auth-proxy`" + ` is in use then the proxy program is called with the
+access key ID as the ` + "`user`" + ` and the secret access key as the
+` + "`pass`" + ` once the signature of a request has been verified against
+` + "`--auth-key`" + `, which must be set.
+
+` + httplib.Help + vfs.Help + proxy.Help,
+	RunE: func(command *cobra.Command, args []string) error {
+		var f fs.Fs
+		if proxyflags.Opt.AuthProxy == "" {
+			cmd.CheckArgs(1, 1, command, args)
+			f = cmd.NewFsSrc(args)
+		} else {
+			cmd.CheckArgs(0, 0, command, args)
+		}
+		cmd.Run(false, false, command, func() error {
+			s, err := newServer(context.Background(), f, &httpflags.Opt, &Opt)
+			if err != nil {
+				return err
+			}
+			err = s.serve()
+			if err != nil {
+				return err
+			}
+			s.Wait()
+			return nil
+		})
+		return nil
+	},
+}
+
+// Server is an S3 server serving a VFS
+type Server struct {
+	*httplib.Server
+	f       fs.Fs
+	_vfs    *vfs.VFS // don't use directly, use getVFS
+	proxy   *proxy.Proxy
+	ctx     context.Context   // for global config
+	keys    map[string]string // access key ID to secret access key
+	mu      sync.Mutex        // protects the following
+	uploads map[string]*multipartUpload
+}
+
+// Make a new S3 server to serve the remote
+func newServer(ctx context.Context, f fs.Fs, opt *httplib.Options, s3Opt *Options) (*Server, error) {
+	if opt.BasicUser != "" || opt.HtPasswd != "" {
+		return nil, errors.New("serve s3 authenticates with --auth-key not --user, --pass or --htpasswd")
+	}
+	s := &Server{
+		f:       f,
+		ctx:     ctx,
+		keys:    map[string]string{},
+		uploads: map[string]*multipartUpload{},
+	}
+	for _, pair := range s3Opt.AuthKeys {
+		comma := strings.IndexByte(pair, ',')
+		if comma <= 0 || comma == len(pair)-1 {
+			return nil, errors.Errorf("auth key %q must be in the form access_key_id,secret_access_key", pair)
+		}
+		s.keys[pair[:comma]] = pair[comma+1:]
+	}
+	if proxyflags.Opt.AuthProxy != "" {
+		if len(s.keys) == 0 {
+			return nil, errors.New("serve s3 needs --auth-key to use --auth-proxy")
+		}
+		s.proxy = proxy.New(ctx, &proxyflags.Opt)
+	} else {
+		s._vfs = vfs.New(f, &vfsflags.Opt)
+	}
+	s.Server = httplib.NewServer(http.HandlerFunc(s.handler), opt)
+	return s, nil
+}
+
+// serve runs the http server in the background.
+//
+// Use s.Close() and s.Wait() to shutdown server
+func (s *Server) serve() error {
+	err := s.Serve()
+	if err != nil {
+		return err
+	}
+	fs.Logf(s.f, "S3 Server started on %s", s.URL())
+	return nil
+}
+
+// Close shuts the server down and removes any incomplete uploads
+func (s *Server) Close() {
+	s.Server.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, upload := range s.uploads {
+		upload.remove()
+		delete(s.uploads, id)
+	}
+}
+
+// getVFS returns the VFS to use for the access key ID
+func (s *Server) getVFS(accessKeyID, secretKey string) (*vfs.VFS, error) {
+	if s._vfs != nil {
+		return s._vfs, nil
+	}
+	VFS, _, err := s.proxy.Call(accessKeyID, secretKey, false)
+	if err != nil {
+		fs.Infof(nil, "serve s3: proxy auth failed for %q: %v", accessKeyID, err)
+		return nil, errAccessDenied
+	}
+	return VFS, nil
+}
+
+// newID returns a random hex ID
+func newID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return strings.ToUpper(hex.EncodeToString(id[:]))
+}
+
+// request holds the state for a single request
+type request struct {
+	s           *Server
+	w           http.ResponseWriter
+	r           *http.Request
+	ctx         context.Context
+	vfs         *vfs.VFS
+	accessKeyID string
+	bucket      string
+	key         string
+}
+
+// handler serves all the S3 requests
+func (s *Server) handler(w http.ResponseWriter, r *http.Request) {
+	urlPath, ok := s.Path(w, r)
+	if !ok {
+		return
+	}
+	requestID := newID()
+	w.Header().Set("x-amz-request-id", requestID)
+	w.Header().Set("Server", "rclone/"+fs.Version)
+	fs.Infof(urlPath, "%s from %s", r.Method, r.RemoteAddr)
+
+	req := &request{
+		s:   s,
+		w:   w,
+		r:   r,
+		ctx: r.Context(),
+	}
+	req.bucket, req.key = splitPath(urlPath)
+	err := req.serve()
+	if err != nil {
+		req.writeError(err, requestID)
+	}
+}
+
+// splitPath splits urlPath into a bucket and a key
+func splitPath(urlPath string) (bucket, key string) {
+	urlPath = strings.TrimPrefix(urlPath, "/")
+	slash := strings.IndexByte(urlPath, '/')
+	if slash < 0 {
+		return urlPath, ""
+	}
+	return urlPath[:slash], urlPath[slash+1:]
+}
+
+// checkBucketName returns an error if the bucket can't be a directory
+func checkBucketName(bucket string) error {
+	if bucket == "." || bucket == ".." {
+		return errInvalidBucketName
+	}
+	return nil
+}
+
+// checkKey returns an error if the key can't be stored as a path in
+// the VFS. Keys ending in "/" are allowed as directory markers.
+func checkKey(key string) error {
+	if key == "" || len(key) > 1024 {
+		return errInvalidArgument
+	}
+	for _, segment := range strings.Split(strings.TrimSuffix(key, "/"), "/") {
+		if segment == "" || segment == "." || segment == ".." || strings.HasPrefix(segment, tempPrefix) {
+			return &apiError{http.StatusBadRequest, "InvalidArgument", "Key can't be stored: " + key}
+		}
+	}
+	return nil
+}
+
+// serve dispatches the request to the S3 operation
+func (req *request) serve() (err error) {
+	r := req.r
+	query := r.URL.Query()
+	var secretKey string
+	req.accessKeyID, secretKey, err = req.s.checkAuth(r)
+	if err != nil {
+		return err
+	}
+	req.vfs, err = req.s.getVFS(req.accessKeyID, secretKey)
+	if err != nil {
+		return err
+	}
+	if req.bucket == "" {
+		if r.Method == "GET" {
+			return req.listBuckets()
+		}
+		return errMethodNotAllowed
+	}
+	if err = checkBucketName(req.bucket); err != nil {
+		return err
+	}
+	if req.key == "" {
+		switch r.Method {
+		case "HEAD":
+			return req.headBucket()
+		case "GET":
+			switch {
+			case has(query, "uploads"):
+				return req.listMultipartUploads()
+			case has(query, "location"):
+				return req.getBucketLocation()
+			case has(query, "acl"), has(query, "versioning"), has(query, "policy"),
+				has(query, "tagging"), has(query, "lifecycle"), has(query, "cors"):
+				return errNotImplemented
+			case query.Get("list-type") == "2":
+				return req.listObjects(true)
+			default:
+				return req.listObjects(false)
+			}
+		case "PUT":
+			if len(query) > 0 {
+				return errNotImplemented
+			}
+			return req.createBucket()
+		case "DELETE":
+			return req.deleteBucket()
+		case "POST":
+			if has(query, "delete") {
+				return req.deleteObjects()
+			}
+		}
+		return errMethodNotAllowed
+	}
+	if err = checkKey(req.key); err != nil {
+		return err
+	}
+	switch r.Method {
+	case "HEAD", "GET":
+		if has(query, "uploadId") {
+			return req.listParts()
+		}
+		return req.getObject()
+	case "PUT":
+		switch {
+		case has(query, "uploadId") && has(query, "partNumber"):
+			if r.Header.Get("X-Amz-Copy-Source") != "" {
+				return errNotImplemented
+			}
+			return req.uploadPart()
+		case len(query) > 0 && !has(query, "X-Amz-Signature"):
+			return errNotImplemented
+		case r.Header.Get("X-Amz-Copy-Source") != "":
+			return req.copyObject()
+		default:
+			return req.putObject()
+		}
+	case "POST":
+		switch {
+		case has(query, "uploads"):
+			return req.createMultipartUpload()
+		case has(query, "uploadId"):
+			return req.completeMultipartUpload()
+		}
+	case "DELETE":
+		if has(query, "uploadId") {
+			return req.abortMultipartUpload()
+		}
+		return req.deleteObject()
+	}
+	return errMethodNotAllowed
+}
+
+// has returns true if the query has the parameter name
+func has(query map[string][]string, name string) bool {
+	_, ok := query[name]
+	return ok
+}
+
+// writeError writes err to the client as an S3 error
+func (req *request) writeError(err error, requestID string) {
+	apiErr, ok := errors.Cause(err).(*apiError)
+	if !ok {
+		apiErr = vfsError(err)
+		if apiErr == errInternal {
+			fs.Errorf(req.r.URL.Path, "serve s3: %s failed: %v", req.r.Method, err)
+		}
+	}
+	fs.Debugf(req.r.URL.Path, "serve s3: %s returned %s", req.r.Method, apiErr.Code)
+	if req.r.Method == "HEAD" {
+		req.w.WriteHeader(apiErr.Status)
+		return
+	}
+	writeXML(req.w, apiErr.Status, &errorResponse{
+		Code:      apiErr.Code,
+		Message:   apiErr.Message,
+		Resource:  req.r.URL.Path,
+		RequestID: requestID,
+	})
+}
+
+// vfsError converts an error from the VFS into an apiError
+func vfsError(err error) *apiError {
+	switch errors.Cause(err) {
+	case vfs.ENOENT, fs.ErrorObjectNotFound, fs.ErrorDirNotFound:
+		return errNoSuchKey
+	case vfs.EPERM, vfs.EROFS:
+		return errAccessDenied
+	case vfs.ENOTEMPTY, fs.ErrorDirectoryNotEmpty:
+		return errBucketNotEmpty
+	}
+	return errInternal
+}
+
+// bucketDir returns the directory for the bucket
+func (req *request) bucketDir() (*vfs.Dir, error) {
+	node, err := req.vfs.Stat(vfsPath(req.bucket, ""))
+	if err == vfs.ENOENT || (err == nil && !node.IsDir()) {
+		return nil, errNoSuchBucket
+	} else if err != nil {
+		return nil, err
+	}
+	return node.(*vfs.Dir), nil
+}
+
+// objectPath returns the VFS path of the object
+func (req *request) objectPath() string {
+	return vfsPath(req.bucket, req.key)
+}
+
+// vfsPath returns the VFS path of key in bucket.
+//
+// Keys can contain any characters so they are converted to the
+// standard encoding which the VFS names are in, eg a leading "\n"
+// is stored as "␊", otherwise they won't match the names listed.
+func vfsPath(bucket, key string) string {
+	return encoder.Identity().ToStandardPath(path.Join(bucket, key))
+}
+
+// keyName returns the key for the VFS name, undoing vfsPath
+func keyName(name string) string {
+	return encoder.Identity().FromStandardName(name)
+}
+
+// mkdirAll makes the directory dir and all its parents in the VFS
+func mkdirAll(VFS *vfs.VFS, dir string) error {
+	if dir == "" || dir == "." {
+		return nil
+	}
+	node, err := VFS.Stat(dir)
+	if err == nil {
+		if !node.IsDir() {
+			return &apiError{http.StatusConflict, "InvalidArgument", "A file exists where a directory is needed: " + dir}
+		}
+		return nil
+	}
+	if err != vfs.ENOENT {
+		return err
+	}
+	if err = mkdirAll(VFS, path.Dir(dir)); err != nil {
+		return err
+	}
+	err = VFS.Mkdir(dir, 0777)
+	if os.IsExist(err) {
+		err = nil
+	}
+	return err
+}
@@ -0,0 +1,160 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/filter"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/spf13/cobra"
+)
+
+var pruneCommand = &cobra.Command{
+	Use:   "prune dest:path",
+	Short: `Remove old snapshots from dest:path.`,
+	Long: `
+Remove the snapshots made by ` + "`rclone snapshot`" + ` in dest:path
+which aren't kept by any of the retention rules.
+
+  * ` + "`--keep-last N`" + ` - keep the latest N snapshots
+  * ` + "`--keep-daily N`" + ` - keep the latest snapshot of each of the last N days which have one
+  * ` + "`--keep-weekly N`" + ` - keep the latest snapshot of each of the last N weeks which have one
+  * ` + "`--keep-monthly N`" + ` - keep the latest snapshot of each of the last N months which have one
+  * ` + "`--keep-yearly N`" + ` - keep the latest snapshot of each of the last N years which have one
+
+A snapshot is kept if any of the rules keeps it.  Days, weeks and
+months are in UTC like the snapshot names and weeks are ISO 8601
+weeks.  At least one rule must be given.
+
+Incomplete snapshots (those without a manifest) older than the latest
+complete snapshot are always removed.  The latest complete snapshot
+is never removed.
+
+Filters are ignored when removing snapshots.  Use ` + "`--dry-run`" + `
+or ` + "`--interactive`" + ` to see what would be removed.
+
+For example
+
+    rclone snapshot prune remote:backup --keep-daily 7 --keep-weekly 4 --keep-monthly 12
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		fdst := cmd.NewFsDir(args)
+		cmd.Run(true, false, command, func() error {
+			return Prune(context.Background(), fdst, opt)
+		})
+	},
+}
+
+// hasKeep returns true if any retention rules are set
+func (opt *Options) hasKeep() bool {
+	return opt.KeepLast > 0 || opt.KeepDaily > 0 || opt.KeepWeekly > 0 || opt.KeepMonthly > 0 || opt.KeepYearly > 0
+}
+
+// keepRule keeps the latest snapshot in each of n periods returned
+// by period
+type keepRule struct {
+	n      int
+	period func(t time.Time) string
+}
+
+// retain returns the names of the complete snapshots in snaps, which
+// should be sorted oldest first, kept by the rules in opt
+func retain(snaps []Snap, opt Options) map[string]bool {
+	rules := []keepRule{
+		{opt.KeepLast, func(t time.Time) string { return t.String() }},
+		{opt.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{opt.KeepWeekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-%02d", year, week)
+		}},
+		{opt.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") }},
+		{opt.KeepYearly, func(t time.Time) string { return t.Format("2006") }},
+	}
+	keep := map[string]bool{}
+	for _, rule := range rules {
+		n, last := rule.n, ""
+		for i := len(snaps) - 1; i >= 0 && n > 0; i-- {
+			snap := snaps[i]
+			if !snap.Complete {
+				continue
+			}
+			period := rule.period(snap.Time.UTC())
+			if period == last {
+				continue
+			}
+			last = period
+			keep[snap.Name] = true
+			n--
+		}
+	}
+	return keep
+}
+
+// Prune removes the snapshots in fdst which aren't kept by the
+// retention rules in opt
+func Prune(ctx context.Context, fdst fs.Fs, opt Options) error {
+	if !opt.hasKeep() {
+		return errors.New("need at least one --keep-* flag to prune snapshots")
+	}
+	// Snapshots are removed whole so ignore the filters
+	fi, err := filter.NewFilter(nil)
+	if err != nil {
+		return err
+	}
+	ctx = filter.ReplaceConfig(ctx, fi)
+
+	snaps, err := List(ctx, fdst)
+	if err != nil {
+		return err
+	}
+	keep := retain(snaps, opt)
+	latest := -1
+	for i, snap := range snaps {
+		if snap.Complete {
+			latest = i
+		}
+	}
+	if latest >= 0 {
+		keep[snaps[latest].Name] = true
+	}
+	var errCount int
+	for i, snap := range snaps {
+		// Incomplete snapshots newer than the latest may be in progress
+		if keep[snap.Name] || (!snap.Complete && i > latest) {
+			fs.Debugf(fdst, "Keeping snapshot %q", snap.Name)
+			continue
+		}
+		fs.Infof(fdst, "Removing snapshot %q", snap.Name)
+		err = removeSnapshot(ctx, fdst, snap)
+		if err != nil {
+			fs.Errorf(fdst, "Failed to remove snapshot %q: %v", snap.Name, err)
+			errCount++
+		}
+	}
+	if errCount > 0 {
+		return errors.Errorf("failed to remove %d snapshots", errCount)
+	}
+	return nil
+}
+
+// removeSnapshot removes snap and its manifest from fdst
+func removeSnapshot(ctx context.Context, fdst fs.Fs, snap Snap) error {
+	// Remove the manifest first so a partly removed snapshot is
+	// seen as incomplete
+	if snap.Complete {
+		o, err := fdst.NewObject(ctx, snap.Name+manifestSuffix)
+		if err != nil {
+			return err
+		}
+		err = operations.DeleteFile(ctx, o)
+		if err != nil {
+			return err
+		}
+	}
+	return operations.Purge(ctx, fdst, snap.Name)
+}
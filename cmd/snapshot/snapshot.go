@@ -0,0 +1,313 @@
+// Package snapshot implements the snapshot command which makes
+// incremental backups into dated directories
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fs/sync"
+	"github.com/rclone/rclone/fs/walk"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// TimeFormat is the format of the snapshot directory names which
+	// are the time of the snapshot in UTC
+	TimeFormat = "2006-01-02-150405"
+
+	// manifestSuffix is added to the snapshot name to make the name
+	// of its manifest
+	manifestSuffix = ".manifest"
+)
+
+// Options for the snapshot and prune commands
+type Options struct {
+	ManifestHash bool // put the hashes of the files in the manifest
+	KeepLast     int  // keep this many of the latest snapshots
+	KeepDaily    int  // keep the last snapshot of this many days
+	KeepWeekly   int  // keep the last snapshot of this many weeks
+	KeepMonthly  int  // keep the last snapshot of this many months
+	KeepYearly   int  // keep the last snapshot of this many years
+}
+
+// DefaultOptions returns the default options for the snapshot commands
+func DefaultOptions() Options {
+	return Options{}
+}
+
+// Globals
+var (
+	opt = DefaultOptions()
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefinition)
+	cmdFlags := commandDefinition.Flags()
+	flags.BoolVarP(cmdFlags, &opt.ManifestHash, "manifest-hash", "", opt.ManifestHash, "Include the hashes of the files in the manifest.")
+	addKeepFlags(cmdFlags)
+	commandDefinition.AddCommand(pruneCommand)
+	addKeepFlags(pruneCommand.Flags())
+}
+
+// addKeepFlags adds the retention flags to cmdFlags
+func addKeepFlags(cmdFlags *pflag.FlagSet) {
+	flags.IntVarP(cmdFlags, &opt.KeepLast, "keep-last", "", opt.KeepLast, "Keep this many of the latest snapshots.")
+	flags.IntVarP(cmdFlags, &opt.KeepDaily, "keep-daily", "", opt.KeepDaily, "Keep the last snapshot of each of this many days.")
+	flags.IntVarP(cmdFlags, &opt.KeepWeekly, "keep-weekly", "", opt.KeepWeekly, "Keep the last snapshot of each of this many weeks.")
+	flags.IntVarP(cmdFlags, &opt.KeepMonthly, "keep-monthly", "", opt.KeepMonthly, "Keep the last snapshot of each of this many months.")
+	flags.IntVarP(cmdFlags, &opt.KeepYearly, "keep-yearly", "", opt.KeepYearly, "Keep the last snapshot of each of this many years.")
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "snapshot source:path dest:path",
+	Short: `Make an incremental snapshot of source:path in dest:path.`,
+	Long: `
+Copy source:path into a new directory in dest:path named after the
+current time in UTC, e.g. ` + "`dest:path/2021-02-03-101500`" + `.
+
+Files which are unchanged since the previous snapshot are not copied
+from the source.  Instead they are server-side copied from the
+previous snapshot, or hard linked to it on the local backend, so each
+snapshot is a complete copy of the source but only the changed files
+are transferred.  If the destination supports neither server-side
+copy nor hard links then every file is copied from the source.
+
+Filters apply to the source as they do for ` + "`rclone copy`" + `.
+
+When the snapshot is complete a manifest listing its files is written
+to ` + "`dest:path/NAME.manifest`" + ` with one JSON object per line
+in the format used by ` + "`rclone lsjson`" + `.  Use
+` + "`--manifest-hash`" + ` to include the hashes of the files.  A
+snapshot without a manifest is incomplete - it isn't used as the
+previous snapshot and it is removed by a later prune.  If the snapshot
+fails it is carried on with when rclone retries.
+
+If any of the ` + "`--keep-*`" + ` flags are given then the snapshots
+are pruned after a successful snapshot as described in
+` + "`rclone snapshot prune`" + `.
+
+For example to make a snapshot every day and keep the last 7 days,
+the last 4 weeks and the last 12 months
+
+    rclone snapshot /home remote:backup --keep-daily 7 --keep-weekly 4 --keep-monthly 12
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(2, 2, command, args)
+		fsrc := cmd.NewFsDir(args[:1])
+		fdst := cmd.NewFsDir(args[1:])
+		// Work out the name here so retries carry on with the same snapshot
+		name := time.Now().UTC().Format(TimeFormat)
+		cmd.Run(true, true, command, func() error {
+			ctx := context.Background()
+			err := Snapshot(ctx, fsrc, fdst, name, opt)
+			if err != nil || !opt.hasKeep() {
+				return err
+			}
+			return Prune(ctx, fdst, opt)
+		})
+	},
+}
+
+// Snap is a snapshot in the destination
+type Snap struct {
+	Name     string    // name of the directory
+	Time     time.Time // when the snapshot was made
+	Complete bool      // set if the snapshot has a manifest
+}
+
+// List returns the snapshots in fdst sorted oldest first
+func List(ctx context.Context, fdst fs.Fs) (snaps []Snap, err error) {
+	entries, err := fdst.List(ctx, "")
+	if err == fs.ErrorDirNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to list snapshots")
+	}
+	manifests := map[string]bool{}
+	for _, entry := range entries {
+		if o, ok := entry.(fs.Object); ok {
+			manifests[o.Remote()] = true
+		}
+	}
+	for _, entry := range entries {
+		dir, ok := entry.(fs.Directory)
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(TimeFormat, dir.Remote())
+		if err != nil {
+			continue
+		}
+		snaps = append(snaps, Snap{
+			Name:     dir.Remote(),
+			Time:     t,
+			Complete: manifests[dir.Remote()+manifestSuffix],
+		})
+	}
+	sort.Slice(snaps, func(i, j int) bool {
+		return snaps[i].Time.Before(snaps[j].Time)
+	})
+	return snaps, nil
+}
+
+// Snapshot copies fsrc into the snapshot called name in fdst, reusing
+// the unchanged files from the latest complete snapshot.
+//
+// If the snapshot exists but is incomplete it is brought up to date
+// with fsrc.
+func Snapshot(ctx context.Context, fsrc, fdst fs.Fs, name string, opt Options) error {
+	snaps, err := List(ctx, fdst)
+	if err != nil {
+		return err
+	}
+	var prev *Snap
+	for i := range snaps {
+		snap := &snaps[i]
+		if snap.Name == name && snap.Complete {
+			return errors.Errorf("snapshot %q already exists", name)
+		}
+		if snap.Complete && snap.Name < name {
+			prev = snap
+		}
+	}
+	fsnap, err := cache.Get(ctx, fspath.JoinRootPath(fs.ConfigString(fdst), name))
+	if err != nil {
+		return errors.Wrap(err, "failed to make snapshot")
+	}
+
+	if prev == nil {
+		fs.Infof(fsnap, "Making first snapshot")
+	} else {
+		prevPath := fspath.JoinRootPath(fs.ConfigString(fdst), prev.Name)
+		fs.Infof(fsnap, "Making snapshot using previous snapshot %q", prev.Name)
+		switch {
+		case fsnap.Features().Copy != nil:
+			var ci *fs.ConfigInfo
+			ctx, ci = fs.AddConfig(ctx)
+			ci.CompareDest = nil
+			ci.CopyDest = []string{prevPath}
+		case fsnap.Features().Link != nil:
+			fprev, err := cache.Get(ctx, prevPath)
+			if err != nil {
+				return errors.Wrap(err, "failed to open previous snapshot")
+			}
+			err = linkUnchanged(ctx, fsrc, fsnap, fprev)
+			if err != nil {
+				return err
+			}
+		default:
+			fs.Logf(fsnap, "Copying all files as the destination doesn't support server-side copy or hard links")
+		}
+	}
+
+	// Sync rather than copy to remove any files left by an earlier
+	// attempt at the snapshot which have gone from the source
+	err = sync.Sync(ctx, fsnap, fsrc, true)
+	if err != nil {
+		return err
+	}
+	return writeManifest(ctx, fdst, fsnap, name, opt)
+}
+
+// linkUnchanged hard links the files in fsrc which are unchanged
+// since the previous snapshot fprev into fsnap
+func linkUnchanged(ctx context.Context, fsrc, fsnap, fprev fs.Fs) error {
+	location := fs.ConfigString(fprev)
+	return walk.ListR(ctx, fsrc, "", false, -1, walk.ListObjects, func(entries fs.DirEntries) error {
+		for _, entry := range entries {
+			src, ok := entry.(fs.Object)
+			if !ok {
+				continue
+			}
+			remote := src.Remote()
+			prevObj, err := fprev.NewObject(ctx, remote)
+			if err == fs.ErrorObjectNotFound {
+				continue
+			} else if err != nil {
+				return err
+			}
+			if !unchanged(ctx, src, prevObj) {
+				continue
+			}
+			// Already linked by an earlier attempt
+			if _, err := fsnap.NewObject(ctx, remote); err == nil {
+				continue
+			}
+			if operations.SkipDestructive(ctx, src, "hard link") {
+				continue
+			}
+			_, err = fsnap.Features().Link(ctx, prevObj, remote)
+			if err == fs.ErrorCantLink {
+				fs.Debugf(src, "Can't hard link from previous snapshot so will copy")
+				continue
+			} else if err != nil {
+				return errors.Wrapf(err, "failed to link %q", remote)
+			}
+			fs.Debugf(src, "Unchanged - hard linked from %q", location)
+			accounting.Stats(ctx).DestMatch(location)
+		}
+		return nil
+	})
+}
+
+// writeManifest lists fsnap into the manifest for snapshot name in fdst
+func writeManifest(ctx context.Context, fdst, fsnap fs.Fs, name string, opt Options) error {
+	var buf bytes.Buffer
+	lopt := operations.ListJSONOpt{
+		Recurse:    true,
+		FilesOnly:  true,
+		NoMimeType: true,
+		ShowHash:   opt.ManifestHash,
+	}
+	enc := json.NewEncoder(&buf)
+	err := operations.ListJSON(ctx, fsnap, "", &lopt, func(item *operations.ListJSONItem) error {
+		return enc.Encode(item)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to list snapshot for manifest")
+	}
+	_, err = operations.Rcat(ctx, fdst, name+manifestSuffix, ioutil.NopCloser(&buf), time.Now())
+	if err != nil {
+		return errors.Wrap(err, "failed to write manifest")
+	}
+	return nil
+}
+
+// unchanged returns whether src is the same as prev in the previous
+// snapshot.
+//
+// Unlike operations.Equal this never sets the modification time of
+// prev as the previous snapshot mustn't be changed. For the same
+// reason a file whose modification time has changed isn't counted as
+// unchanged, otherwise the sync would set the modification time of
+// the hard link it shares with the previous snapshot.
+func unchanged(ctx context.Context, src, prev fs.Object) bool {
+	ctx, ci := fs.AddConfig(ctx)
+	ci.NoUpdateModTime = true
+	if !operations.Equal(ctx, src, prev) {
+		return false
+	}
+	if ci.SizeOnly || ci.CheckSum {
+		return true
+	}
+	modifyWindow := fs.GetModifyWindow(ctx, src.Fs(), prev.Fs())
+	if modifyWindow == fs.ModTimeNotSupported {
+		return true
+	}
+	dt := prev.ModTime(ctx).Sub(src.ModTime(ctx))
+	return dt < modifyWindow && dt > -modifyWindow
+}
@@ -0,0 +1,171 @@
+package snapshot
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/all" // import all backends
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Some times used in the tests
+var (
+	t1 = fstest.Time("2001-02-03T04:05:06.499999999Z")
+	t2 = fstest.Time("2011-12-25T12:59:59.123456789Z")
+)
+
+// TestMain drives the tests
+func TestMain(m *testing.M) {
+	fstest.TestMain(m)
+}
+
+// makeSnaps makes complete snapshots at the times given
+func makeSnaps(times ...string) (snaps []Snap) {
+	for _, s := range times {
+		t := fstest.Time(s)
+		snaps = append(snaps, Snap{
+			Name:     t.UTC().Format(TimeFormat),
+			Time:     t,
+			Complete: true,
+		})
+	}
+	return snaps
+}
+
+// names returns the sorted names of the snapshots kept
+func names(snaps []Snap, keep map[string]bool) (out []string) {
+	for _, snap := range snaps {
+		if keep[snap.Name] {
+			out = append(out, snap.Name)
+		}
+	}
+	return out
+}
+
+func TestRetain(t *testing.T) {
+	snaps := makeSnaps(
+		"2020-11-30T10:00:00Z",
+		"2020-12-31T10:00:00Z",
+		"2021-01-01T10:00:00Z",
+		"2021-01-27T10:00:00Z",
+		"2021-02-01T10:00:00Z",
+		"2021-02-02T10:00:00Z",
+		"2021-02-02T20:00:00Z",
+		"2021-02-03T10:00:00Z",
+	)
+	for _, test := range []struct {
+		opt  Options
+		want []string
+	}{
+		{Options{}, nil},
+		{Options{KeepLast: 2}, []string{"2021-02-02-200000", "2021-02-03-100000"}},
+		{Options{KeepDaily: 3}, []string{"2021-02-01-100000", "2021-02-02-200000", "2021-02-03-100000"}},
+		{Options{KeepWeekly: 2}, []string{"2021-01-27-100000", "2021-02-03-100000"}},
+		{Options{KeepMonthly: 3}, []string{"2020-12-31-100000", "2021-01-27-100000", "2021-02-03-100000"}},
+		{Options{KeepYearly: 5}, []string{"2020-12-31-100000", "2021-02-03-100000"}},
+		{Options{KeepLast: 1, KeepMonthly: 4}, []string{"2020-11-30-100000", "2020-12-31-100000", "2021-01-27-100000", "2021-02-03-100000"}},
+	} {
+		assert.Equal(t, test.want, names(snaps, retain(snaps, test.opt)), test.opt)
+	}
+
+	// Incomplete snapshots aren't counted
+	snaps[len(snaps)-1].Complete = false
+	assert.Equal(t, []string{"2021-02-02-200000"}, names(snaps, retain(snaps, Options{KeepLast: 1})))
+}
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	opt := DefaultOptions()
+
+	file1 := r.WriteFile("one", "one", t1)
+	file2 := r.WriteFile("dir/two", "two", t1)
+
+	// First snapshot copies everything
+	first := time.Date(2021, 2, 1, 10, 0, 0, 0, time.UTC).Format(TimeFormat)
+	require.NoError(t, Snapshot(ctx, r.Flocal, r.Fremote, first, opt))
+
+	// Second snapshot with one file changed and one new
+	file2b := r.WriteFile("dir/two", "two changed", t2)
+	file3 := r.WriteFile("three", "three", t2)
+	second := time.Date(2021, 2, 2, 10, 0, 0, 0, time.UTC).Format(TimeFormat)
+	require.NoError(t, Snapshot(ctx, r.Flocal, r.Fremote, second, opt))
+
+	// The same snapshot can't be made twice
+	assert.Error(t, Snapshot(ctx, r.Flocal, r.Fremote, second, opt))
+
+	snaps, err := List(ctx, r.Fremote)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(snaps))
+	assert.Equal(t, first, snaps[0].Name)
+	assert.Equal(t, second, snaps[1].Name)
+	assert.True(t, snaps[0].Complete)
+	assert.True(t, snaps[1].Complete)
+
+	checkSnap := func(name string, items ...fstest.Item) {
+		fsnap, err := fs.NewFs(ctx, r.FremoteName+"/"+name)
+		require.NoError(t, err)
+		fstest.CheckItems(t, fsnap, items...)
+		_, err = r.Fremote.NewObject(ctx, name+manifestSuffix)
+		assert.NoError(t, err)
+	}
+	checkSnap(first, file1, file2)
+	checkSnap(second, file1, file2b, file3)
+
+	// Unchanged files should be hard linked on the local backend
+	if r.Fremote.Features().IsLocal {
+		fi1, err := os.Stat(filepath.Join(r.FremoteName, first, "one"))
+		require.NoError(t, err)
+		fi2, err := os.Stat(filepath.Join(r.FremoteName, second, "one"))
+		require.NoError(t, err)
+		assert.True(t, os.SameFile(fi1, fi2), "expecting unchanged file to be hard linked")
+	}
+
+	// Prune keeping only the latest
+	assert.Error(t, Prune(ctx, r.Fremote, opt))
+	opt.KeepLast = 1
+	require.NoError(t, Prune(ctx, r.Fremote, opt))
+	snaps, err = List(ctx, r.Fremote)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(snaps))
+	assert.Equal(t, second, snaps[0].Name)
+	checkSnap(second, file1, file2b, file3)
+	_, err = r.Fremote.NewObject(ctx, first+manifestSuffix)
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+}
+
+func TestSnapshotModTimeChanged(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	opt := DefaultOptions()
+
+	file1 := r.WriteFile("one", "one", t1)
+	first := time.Date(2021, 2, 1, 10, 0, 0, 0, time.UTC).Format(TimeFormat)
+	require.NoError(t, Snapshot(ctx, r.Flocal, r.Fremote, first, opt))
+
+	// Only the modification time changes
+	file1b := r.WriteFile("one", "one", t2)
+	second := time.Date(2021, 2, 2, 10, 0, 0, 0, time.UTC).Format(TimeFormat)
+	require.NoError(t, Snapshot(ctx, r.Flocal, r.Fremote, second, opt))
+
+	// The previous snapshot must be left alone
+	for _, test := range []struct {
+		name string
+		item fstest.Item
+	}{
+		{first, file1},
+		{second, file1b},
+	} {
+		fsnap, err := fs.NewFs(ctx, r.FremoteName+"/"+test.name)
+		require.NoError(t, err)
+		fstest.CheckItems(t, fsnap, test.item)
+	}
+}
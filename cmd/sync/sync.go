@@ -2,22 +2,270 @@ package sync
 
 import (
 	"context"
+	"io"
+	"log"
+	"os"
+	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fs/sync"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
 	createEmptySrcDirs = false
+	combined           = ""
+	newFile            = ""
+	updated            = ""
+	deleted            = ""
+	identical          = ""
+	renamed            = ""
+	errFile            = ""
+	jsonReport         = ""
+	writePlan          = ""
+	applyPlan          = ""
+	watch              = false
+	watchOpt           = sync.DefaultWatchOpt
 )
 
 func init() {
 	cmd.Root.AddCommand(commandDefinition)
 	cmdFlags := commandDefinition.Flags()
 	flags.BoolVarP(cmdFlags, &createEmptySrcDirs, "create-empty-src-dirs", "", createEmptySrcDirs, "Create empty source dirs on destination after sync")
+	flags.BoolVarP(cmdFlags, &watch, "watch", "", watch, "Keep running, syncing the changes made to the source")
+	flags.DurationVarP(cmdFlags, &watchOpt.Delay, "watch-delay", "", watchOpt.Delay, "With --watch sync once there have been no changes for this long")
+	flags.DurationVarP(cmdFlags, &watchOpt.PollInterval, "poll-interval", "", watchOpt.PollInterval, "With --watch time to wait between polling for changes on remotes which poll.")
+	AddReportFlags(cmdFlags)
+	AddPlanFlags(cmdFlags)
+}
+
+// AddReportFlags adds the flags to report what happened to each file
+// to cmdFlags
+func AddReportFlags(cmdFlags *pflag.FlagSet) {
+	flags.StringVarP(cmdFlags, &combined, "combined", "", combined, "Make a combined report of changes to this file")
+	flags.StringVarP(cmdFlags, &newFile, "new", "", newFile, "Report all files copied which weren't in the destination to this file")
+	flags.StringVarP(cmdFlags, &updated, "updated", "", updated, "Report all files which replaced a different file in the destination to this file")
+	flags.StringVarP(cmdFlags, &deleted, "deleted", "", deleted, "Report all files deleted from the destination to this file")
+	flags.StringVarP(cmdFlags, &identical, "identical", "", identical, "Report all files skipped as identical to this file")
+	flags.StringVarP(cmdFlags, &renamed, "renamed", "", renamed, "Report all files renamed in the destination to this file")
+	flags.StringVarP(cmdFlags, &errFile, "error", "", errFile, "Report all files with errors to this file")
+	flags.StringVarP(cmdFlags, &jsonReport, "json-report", "", jsonReport, "Report all files as JSON lines with details to this file")
+}
+
+// ReportHelp describes the report flags for the help
+var ReportHelp = strings.Replace(`
+### Reports
+
+The |--new|, |--updated|, |--deleted|, |--identical|, |--renamed|
+and |--error| flags write paths, one per line, to the file name (or
+stdout if it is |-|) supplied, saying what happened to each file.
+The paths are relative to the root of the source or destination.
+
+The |--combined| flag will write a file (or stdout) which contains all
+file paths with a symbol and then a space and then the path to tell
+you what happened to it.
+
+- |+ path| means path was copied and wasn't in the destination
+- |* path| means path was copied over a different file in the destination
+- |- path| means path was deleted from the destination
+- |= path| means path was skipped as it was already in the destination
+- |> path| means path was renamed in the destination with |--track-renames|
+- |! path| means there was an error transferring or deleting path
+
+The |--json-report| flag writes a JSON object per line for each file
+with the |Action| (|new|, |updated|, |deleted|, |identical|,
+|renamed| or |error|), the |Path|, the old path as |From| for renamed
+files, the |Size|, the |Hashes|, the |Reason| for the action and any
+|Error|, for example
+
+    {"Action":"updated","Path":"dir/file.txt","Size":6,"Hashes":{"MD5":"b1946ac92492d2347c6235b4d2611184"},"Reason":"size differs"}
+
+The |Hashes| are only included for files which were transferred, or
+for all files with |--checksum| or |--track-renames|, so that
+unchanged files aren't read just to write the report. They are left
+out if they can't be read, which may happen for deleted files.
+
+The reports are written when the source is a directory. When
+moving with a report the files are always moved one by one, rather than
+with a server-side directory move, so each can be reported.
+`, "|", "`", -1)
+
+// AddPlanFlags adds the flags to write and apply plans to cmdFlags
+func AddPlanFlags(cmdFlags *pflag.FlagSet) {
+	flags.StringVarP(cmdFlags, &writePlan, "write-plan", "", writePlan, "Write what would be done to this file instead of doing it")
+	flags.StringVarP(cmdFlags, &applyPlan, "apply-plan", "", applyPlan, "Do what the plan in this file says instead of comparing source and dest")
+}
+
+// PlanHelp describes the plan flags for the help
+var PlanHelp = strings.Replace(`
+### Plans
+
+The |--write-plan| flag writes what would be done to the file name (or
+stdout if it is |-|) supplied instead of doing it, as if |--dry-run|
+was set.  The plan can be reviewed, or edited to remove things which
+shouldn't be done, and then applied later with the same command,
+source and destination and |--apply-plan|, which does exactly what
+is in the plan (or stdin if it is |-|) without comparing the source
+and destination again.
+
+    rclone sync --write-plan plan.json /data remote:data
+    rclone sync --apply-plan plan.json /data remote:data
+
+The plan is a JSON object with the |Operation|, the |Src| and |Dst|
+and the |Items| to do, each with the |Action| (|copy|, |update|,
+|move|, |delete|, |rename|, |mkdir| or |rmdir|), the |Path|, the old
+path as |From| for renamed files, |Source| if the action is on the
+source and the |SrcFingerprint| and |DstFingerprint| of the size,
+modification time and hash of the files when the plan was made, for
+example
+
+    {"Action":"update","Path":"file.txt","SrcFingerprint":"6,2021-01-02 03:04:05 +0000 UTC,b1946ac92492d2347c6235b4d2611184","DstFingerprint":"3,2020-01-02 03:04:05 +0000 UTC,acbd18db4cc2f85cedef654fccc4a4d8"}
+
+When the plan is applied the files are checked against the
+fingerprints first and any which have changed since are left alone
+with an error, so nothing is deleted.  Files which the plan has
+already been applied to are skipped, so a plan can be applied again
+if it fails part way through.  Renames are done first, then copies
+and moves, then deletions, then directories are made and removed.
+
+Flags such as |--backup-dir| and |--dry-run| take effect when the plan
+is applied.  |--fix-case| can't be used when making a plan.
+`, "|", "`", -1)
+
+// CheckPlanFlags stops rclone if the plan flags are used with a file
+// as the source
+func CheckPlanFlags(srcFileName string) {
+	if srcFileName != "" && (writePlan != "" || applyPlan != "") {
+		log.Fatalf("Can't use --write-plan or --apply-plan with a file as the source")
+	}
+}
+
+// RunPlan runs fn to do operation from fsrc to fdst with ctx, or
+// writes what it would do to the file given by --write-plan or does
+// the plan in the file given by --apply-plan instead.
+func RunPlan(ctx context.Context, operation string, fdst, fsrc fs.Fs, fn func(ctx context.Context) error) error {
+	switch {
+	case writePlan != "" && applyPlan != "":
+		return fserrors.FatalError(errors.New("can't use --write-plan and --apply-plan together"))
+	case writePlan != "":
+		plan := sync.NewPlan()
+		err := fn(sync.WithPlan(ctx, plan))
+		if err != nil {
+			return err
+		}
+		if writePlan == "-" {
+			return plan.Write(os.Stdout)
+		}
+		out, err := os.Create(writePlan)
+		if err != nil {
+			return err
+		}
+		err = plan.Write(out)
+		closeErr := out.Close()
+		if err != nil {
+			return err
+		}
+		return closeErr
+	case applyPlan != "":
+		plan, err := readPlan()
+		if err != nil {
+			return err
+		}
+		if plan.Operation != operation {
+			return fserrors.FatalError(errors.Errorf("can't apply a %s plan with %s", plan.Operation, operation))
+		}
+		return sync.ApplyPlan(ctx, fdst, fsrc, plan)
+	}
+	return fn(ctx)
+}
+
+// readPlan reads the plan in the file given by --apply-plan
+func readPlan() (plan *sync.Plan, err error) {
+	if applyPlan == "-" {
+		return sync.ReadPlan(os.Stdin)
+	}
+	in, err := os.Open(applyPlan)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CheckClose(in, &err)
+	return sync.ReadPlan(in)
+}
+
+// GetReport gets the report corresponding to the report flags or nil
+// if none of them are set
+func GetReport() (report *sync.Report, close func(), err error) {
+	closers := []io.Closer{}
+	close = func() {
+		for _, closer := range closers {
+			err := closer.Close()
+			if err != nil {
+				fs.Errorf(nil, "Failed to close report output: %v", err)
+			}
+		}
+	}
+
+	var opt sync.ReportOpt
+	used := false
+	open := func(name string, pout *io.Writer) error {
+		if name == "" {
+			return nil
+		}
+		used = true
+		if name == "-" {
+			*pout = os.Stdout
+			return nil
+		}
+		out, err := os.Create(name)
+		if err != nil {
+			return err
+		}
+		*pout = out
+		closers = append(closers, out)
+		return nil
+	}
+
+	for _, output := range []struct {
+		name string
+		pout *io.Writer
+	}{
+		{combined, &opt.Combined},
+		{newFile, &opt.New},
+		{updated, &opt.Updated},
+		{deleted, &opt.Deleted},
+		{identical, &opt.Identical},
+		{renamed, &opt.Renamed},
+		{errFile, &opt.Error},
+		{jsonReport, &opt.JSON},
+	} {
+		if err = open(output.name, output.pout); err != nil {
+			close()
+			return nil, nil, err
+		}
+	}
+	if !used {
+		return nil, close, nil
+	}
+	return sync.NewReport(opt), close, nil
+}
+
+// Context returns a context which makes sync, copy and move report to
+// the outputs given by the report flags
+func Context(ctx context.Context) (_ context.Context, close func(), err error) {
+	report, close, err := GetReport()
+	if err != nil {
+		return nil, nil, err
+	}
+	if report != nil {
+		ctx = sync.WithReport(ctx, report)
+	}
+	return ctx, close, nil
 }
 
 var commandDefinition = &cobra.Command{
@@ -46,13 +294,54 @@ If dest:path doesn't exist, it is created and the source:path contents
 go there.
 
 **Note**: Use the ` + "`-P`" + `/` + "`--progress`" + ` flag to view real-time transfer statistics
-`,
+
+### Watching for changes
+
+With ` + "`--watch`" + ` rclone does the sync then keeps running, listening
+for changes to the source and syncing just the paths which changed,
+copying and deleting files as needed.  This needs a source which can
+notify changes - the local filesystem on Linux with
+` + "`--local-change-notify`" + `, which uses inotify, or a remote such as
+Google Drive, OneDrive or Box which is polled for changes every
+` + "`--poll-interval`" + `.
+
+    rclone sync --watch --local-change-notify /data remote:data
+
+Changes are synced once there have been none for ` + "`--watch-delay`" + `
+(default 5s), or at most 10 times that if they keep coming.  If a sync
+fails the changed paths are kept and tried again after a delay which
+grows up to 5 minutes.  If the source says anything may have changed,
+and every ` + "`--full-sync-interval`" + `, everything is synced.
+
+The paths waiting to be synced, those being synced and the last error
+can be read with the [sync/watchstatus](/rc/#sync-watchstatus) remote
+control command when ` + "`--rc`" + ` is in use.
+` + ReportHelp + PlanHelp,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(2, 2, command, args)
 		fsrc, srcFileName, fdst := cmd.NewFsSrcFileDst(args)
+		if watch && srcFileName != "" {
+			log.Fatalf("Can't use --watch with a file as the source")
+		}
+		if watch && (writePlan != "" || applyPlan != "") {
+			log.Fatalf("Can't use --watch with --write-plan or --apply-plan")
+		}
+		CheckPlanFlags(srcFileName)
 		cmd.Run(true, true, command, func() error {
 			if srcFileName == "" {
-				return sync.Sync(context.Background(), fdst, fsrc, createEmptySrcDirs)
+				ctx, close, err := Context(context.Background())
+				if err != nil {
+					return err
+				}
+				defer close()
+				if watch {
+					opt := watchOpt
+					opt.CopyEmptySrcDirs = createEmptySrcDirs
+					return sync.Watch(ctx, fdst, fsrc, opt)
+				}
+				return RunPlan(ctx, "sync", fdst, fsrc, func(ctx context.Context) error {
+					return sync.Sync(ctx, fdst, fsrc, createEmptySrcDirs)
+				})
 			}
 			return operations.CopyFile(context.Background(), fdst, fsrc, srcFileName, srcFileName)
 		})
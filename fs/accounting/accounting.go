@@ -50,7 +50,8 @@ type Account struct {
 	exit    chan struct{} // channel that will be closed when transfer is finished
 	withBuf bool          // is using a buffered in
 
-	tokenBucket *rate.Limiter // per file bandwidth limiter (may be nil)
+	tokenBucket        *rate.Limiter   // per file bandwidth limiter (may be nil)
+	remoteTokenBuckets []*rate.Limiter // bandwidth limiters of the remotes (may be empty)
 
 	values accountValues
 }
@@ -59,6 +60,7 @@ type Account struct {
 type accountValues struct {
 	mu      sync.Mutex // Mutex for stat values.
 	bytes   int64      // Total number of bytes read
+	skipped int64      // Number of bytes skipped as they were done already
 	max     int64      // if >=0 the max number of bytes to transfer
 	start   time.Time  // Start time of first read
 	lpTime  time.Time  // Time of last average measurement
@@ -189,6 +191,7 @@ func (acc *Account) UpdateReader(ctx context.Context, in io.ReadCloser) {
 	acc.values.mu.Lock()
 	acc.values.lpBytes = 0
 	acc.values.bytes = 0
+	acc.values.skipped = 0
 	acc.values.mu.Unlock()
 }
 
@@ -280,10 +283,28 @@ func (acc *Account) ServerSideCopyEnd(n int64) {
 	acc.stats.Bytes(n)
 }
 
+// WithRemoteBwLimit limits the bandwidth of the transfer to that set
+// by bwlimit in the config of any of the remotes passed in. This is
+// as well as any global --bwlimit.
+func (acc *Account) WithRemoteBwLimit(ctx context.Context, remotes ...fs.Info) *Account {
+	var tokenBuckets []*rate.Limiter
+	for _, f := range remotes {
+		if tokenBucket := getRemoteTokenBucket(ctx, f); tokenBucket != nil {
+			tokenBuckets = append(tokenBuckets, tokenBucket)
+		}
+	}
+	acc.values.mu.Lock()
+	acc.remoteTokenBuckets = tokenBuckets
+	acc.values.mu.Unlock()
+	return acc
+}
+
 // Account for n bytes from the current file bandwidth limit (if any)
+// and the limits of the remotes
 func (acc *Account) limitPerFileBandwidth(n int) {
 	acc.values.mu.Lock()
 	tokenBucket := acc.tokenBucket
+	remoteTokenBuckets := acc.remoteTokenBuckets
 	acc.values.mu.Unlock()
 
 	if tokenBucket != nil {
@@ -292,6 +313,12 @@ func (acc *Account) limitPerFileBandwidth(n int) {
 			fs.Errorf(nil, "Token bucket error: %v", err)
 		}
 	}
+	for _, tokenBucket := range remoteTokenBuckets {
+		err := tokenBucket.WaitN(context.Background(), n)
+		if err != nil {
+			fs.Errorf(nil, "Token bucket error: %v", err)
+		}
+	}
 }
 
 // Account the read and limit bandwidth
@@ -377,6 +404,16 @@ func (acc *Account) AccountRead(n int) (err error) {
 	return err
 }
 
+// AccountSkip marks n bytes of the transfer as done without them
+// being read, for example when resuming an upload which sent them
+// already. They aren't counted as transferred or limited by
+// --bwlimit.
+func (acc *Account) AccountSkip(n int64) {
+	acc.values.mu.Lock()
+	acc.values.skipped += n
+	acc.values.mu.Unlock()
+}
+
 // Close the object
 func (acc *Account) Close() error {
 	acc.mu.Lock()
@@ -406,7 +443,7 @@ func (acc *Account) progress() (bytes, size int64) {
 		return 0, 0
 	}
 	acc.values.mu.Lock()
-	bytes, size = acc.values.bytes, acc.size
+	bytes, size = acc.values.bytes+acc.values.skipped, acc.size
 	acc.values.mu.Unlock()
 	return bytes, size
 }
@@ -439,7 +476,7 @@ func (acc *Account) eta() (etaDuration time.Duration, ok bool) {
 	}
 	acc.values.mu.Lock()
 	defer acc.values.mu.Unlock()
-	return eta(acc.values.bytes, acc.size, acc.values.avg)
+	return eta(acc.values.bytes+acc.values.skipped, acc.size, acc.values.avg)
 }
 
 // shortenName shortens in to size runes long
@@ -573,6 +610,12 @@ func (a *accountStream) Read(p []byte) (n int, err error) {
 	return a.acc.read(a.in, p)
 }
 
+// AccountSkip marks n bytes as done in the parent *Account - see
+// Account.AccountSkip
+func (a *accountStream) AccountSkip(n int64) {
+	a.acc.AccountSkip(n)
+}
+
 // Accounter accounts a stream allowing the accounting to be removed and re-added
 type Accounter interface {
 	io.Reader
@@ -44,6 +44,7 @@ type StatsInfo struct {
 	renameQueueSize   int64
 	deletes           int64
 	deletedDirs       int64
+	destMatches       map[string]int64 // files found in each --compare-dest or --copy-dest
 	inProgress        *inProgress
 	startedTransfers  []*Transfer   // currently active transfers
 	oldTimeRanges     timeRanges    // a merged list of time ranges for the transfers
@@ -77,6 +78,13 @@ func (s *StatsInfo) RemoteStats() (out rc.Params, err error) {
 	out["deletes"] = s.deletes
 	out["deletedDirs"] = s.deletedDirs
 	out["renames"] = s.renames
+	if len(s.destMatches) > 0 {
+		destMatches := make(map[string]int64, len(s.destMatches))
+		for location, n := range s.destMatches {
+			destMatches[location] = n
+		}
+		out["destMatches"] = destMatches
+	}
 	out["transferTime"] = s.totalDuration().Seconds()
 	out["elapsedTime"] = time.Since(startTime).Seconds()
 	s.mu.RUnlock()
@@ -324,6 +332,16 @@ func (s *StatsInfo) String() string {
 		if s.renames != 0 {
 			_, _ = fmt.Fprintf(buf, "Renamed:       %10d\n", s.renames)
 		}
+		if len(s.destMatches) != 0 {
+			locations := make([]string, 0, len(s.destMatches))
+			for location := range s.destMatches {
+				locations = append(locations, location)
+			}
+			sort.Strings(locations)
+			for _, location := range locations {
+				_, _ = fmt.Fprintf(buf, "Dest matched:  %10d in %s\n", s.destMatches[location], location)
+			}
+		}
 		if s.transfers != 0 || totalTransfer != 0 {
 			_, _ = fmt.Fprintf(buf, "Transferred:   %10d / %d, %s\n",
 				s.transfers, totalTransfer, percent(s.transfers, totalTransfer))
@@ -477,6 +495,17 @@ func (s *StatsInfo) DeletedDirs(deletedDirs int64) int64 {
 	return s.deletedDirs
 }
 
+// DestMatch records that a file was found in the --compare-dest or
+// --copy-dest at location so didn't need transferring
+func (s *StatsInfo) DestMatch(location string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.destMatches == nil {
+		s.destMatches = make(map[string]int64)
+	}
+	s.destMatches[location]++
+}
+
 // Renames updates the stats for renames
 func (s *StatsInfo) Renames(renames int64) int64 {
 	s.mu.Lock()
@@ -499,6 +528,7 @@ func (s *StatsInfo) ResetCounters() {
 	s.transfers = 0
 	s.deletes = 0
 	s.deletedDirs = 0
+	s.destMatches = nil
 	s.renames = 0
 	s.startedTransfers = nil
 	s.oldDuration = 0
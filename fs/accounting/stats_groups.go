@@ -95,6 +95,7 @@ Returns the following values:
 	"transfers": number of transferred files,
 	"deletes" : number of deleted files,
 	"renames" : number of renamed files,
+	"destMatches" : number of files found in each --compare-dest or --copy-dest,
 	"transferTime" : total time spent on running jobs,
 	"elapsedTime": time in seconds since the start of the process,
 	"lastError": last occurred error,
@@ -360,6 +361,12 @@ func (sg *statsGroups) sum(ctx context.Context) *StatsInfo {
 			sum.transfers += stats.transfers
 			sum.deletes += stats.deletes
 			sum.deletedDirs += stats.deletedDirs
+			for location, n := range stats.destMatches {
+				if sum.destMatches == nil {
+					sum.destMatches = make(map[string]int64)
+				}
+				sum.destMatches[location] += n
+			}
 			sum.renames += stats.renames
 			sum.checking.merge(stats.checking)
 			sum.transferring.merge(stats.transferring)
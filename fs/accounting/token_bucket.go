@@ -21,6 +21,18 @@ var (
 	currLimit         fs.BwTimeSlot
 )
 
+// Bandwidth limiters for the remotes with bwlimit set in their config
+var (
+	remoteTokenBucketsMu sync.Mutex
+	remoteTokenBuckets   = map[string]*remoteTokenBucket{}
+)
+
+// remoteTokenBucket is the bandwidth limiter for one remote
+type remoteTokenBucket struct {
+	bandwidth   fs.SizeSuffix
+	tokenBucket *rate.Limiter
+}
+
 const maxBurstSize = 4 * 1024 * 1024 // must be bigger than the biggest request
 
 // make a new empty token bucket with the bandwidth given
@@ -101,6 +113,42 @@ func StartTokenTicker(ctx context.Context) {
 	}()
 }
 
+// getRemoteTokenBucket returns the bandwidth limiter for f if bwlimit
+// is set in its config or nil if not.
+//
+// The limiter is shared by all the transfers to and from the remote
+// and is remade if the bandwidth in its timetable changes.
+func getRemoteTokenBucket(ctx context.Context, f fs.Info) *rate.Limiter {
+	ci, overridden := fs.RemoteConfig(ctx, f)
+	isSet := false
+	for _, name := range overridden {
+		if name == "bwlimit" {
+			isSet = true
+			break
+		}
+	}
+	if !isSet {
+		return nil
+	}
+	bandwidth := ci.BwLimit.LimitAt(time.Now()).Bandwidth
+	remoteTokenBucketsMu.Lock()
+	defer remoteTokenBucketsMu.Unlock()
+	if bandwidth <= 0 {
+		delete(remoteTokenBuckets, f.Name())
+		return nil
+	}
+	bucket := remoteTokenBuckets[f.Name()]
+	if bucket == nil || bucket.bandwidth != bandwidth {
+		fs.Debugf(f, "Limiting bandwidth of the remote to %vBytes/s", &bandwidth)
+		bucket = &remoteTokenBucket{
+			bandwidth:   bandwidth,
+			tokenBucket: newTokenBucket(bandwidth),
+		}
+		remoteTokenBuckets[f.Name()] = bucket
+	}
+	return bucket.tokenBucket
+}
+
 // limitBandwidth sleeps for the correct amount of time for the passage
 // of n bytes according to the current bandwidth limit
 func limitBandwidth(n int) {
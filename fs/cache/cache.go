@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fspath"
 	"github.com/rclone/rclone/lib/cache"
 )
 
@@ -19,6 +20,7 @@ var (
 // Canonicalize looks up fsString in the mapping from user supplied
 // names to canonical names and return the canonical form
 func Canonicalize(fsString string) string {
+	fsString = canonicalParams(fsString)
 	mu.Lock()
 	canonicalName, ok := remap[fsString]
 	mu.Unlock()
@@ -29,6 +31,24 @@ func Canonicalize(fsString string) string {
 	return canonicalName
 }
 
+// canonicalParams puts the parameters overriding the config in the
+// remote name of fsString, if any, into canonical order so remotes
+// with the same parameters written differently are the same Fs.
+//
+// Remotes with different parameters stay different as the
+// parameters are part of the name of the Fs.
+func canonicalParams(fsString string) string {
+	configName, fsPath, err := fspath.Parse(fsString)
+	if err != nil || configName == "" {
+		return fsString
+	}
+	name, params, err := fspath.SplitConfigName(configName)
+	if err != nil || len(params) == 0 {
+		return fsString
+	}
+	return fspath.JoinConfigName(name, params) + ":" + fsPath
+}
+
 // Put in a mapping from fsString => canonicalName if they are different
 func addMapping(fsString, canonicalName string) {
 	if canonicalName == fsString {
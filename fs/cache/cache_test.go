@@ -150,6 +150,48 @@ func TestPut(t *testing.T) {
 
 }
 
+func TestGetParams(t *testing.T) {
+	defer c.Clear()
+	created := 0
+	create := func(ctx context.Context, path string) (fs.Fs, error) {
+		created++
+		switch path {
+		case "mock:/":
+			return mockfs.NewFs(ctx, "mock", "/"), nil
+		case "mock,a=1,b=2:/":
+			return mockfs.NewFs(ctx, "mock,a=1,b=2", "/"), nil
+		case "mock,a=2:/":
+			return mockfs.NewFs(ctx, "mock,a=2", "/"), nil
+		}
+		t.Fatalf("Unknown path %q", path)
+		panic("unreachable")
+	}
+
+	f, err := GetFn(context.Background(), "mock:/", create)
+	require.NoError(t, err)
+
+	// Different parameters make different Fs
+	f1, err := GetFn(context.Background(), "mock,b=2,a=1:/", create)
+	require.NoError(t, err)
+	assert.NotEqual(t, f, f1)
+	assert.Equal(t, "mock,a=1,b=2:/", fs.ConfigString(f1))
+
+	f2, err := GetFn(context.Background(), "mock,a=2:/", create)
+	require.NoError(t, err)
+	assert.NotEqual(t, f1, f2)
+	assert.Equal(t, 3, c.Entries())
+
+	// The same parameters in any order find the same Fs
+	f3, err := GetFn(context.Background(), "mock,a=1,b=2:/", create)
+	require.NoError(t, err)
+	assert.Equal(t, f1, f3)
+	f3, err = GetFn(context.Background(), "mock,b=2,a=1:/", create)
+	require.NoError(t, err)
+	assert.Equal(t, f1, f3)
+	assert.Equal(t, 3, created)
+	assert.Equal(t, 3, c.Entries())
+}
+
 func TestPin(t *testing.T) {
 	cleanup, create := mockNewFs(t)
 	defer cleanup()
@@ -0,0 +1,338 @@
+// Package changes reads and writes journals of changed paths which
+// are used to sync only the paths which have changed.
+package changes
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/lib/file"
+)
+
+// Root is how a change to everything is written in a journal
+const Root = "/"
+
+// Clean returns remote in the form used in journals - with no leading
+// or trailing "/" and "" for the root.
+func Clean(remote string) string {
+	return path.Clean("/" + remote)[1:]
+}
+
+// Set is a set of changed paths
+type Set map[string]struct{}
+
+// Add remote to the set
+func (s Set) Add(remote string) {
+	s[Clean(remote)] = struct{}{}
+}
+
+// HasRoot returns true if the root is in the set, meaning everything
+// needs checking
+func (s Set) HasRoot() bool {
+	_, ok := s[""]
+	return ok
+}
+
+// Paths returns the sorted paths in the set leaving out any path in a
+// directory which is also in the set
+func (s Set) Paths() (paths []string) {
+	for remote := range s {
+		paths = append(paths, remote)
+	}
+	sort.Strings(paths)
+	out := paths[:0]
+	for _, remote := range paths {
+		if !s.hasParent(remote) {
+			out = append(out, remote)
+		}
+	}
+	return out
+}
+
+// hasParent returns true if any of the parents of remote are in the
+// set
+func (s Set) hasParent(remote string) bool {
+	if remote == "" {
+		return false
+	}
+	for {
+		remote = path.Dir(remote)
+		if remote == "." {
+			remote = ""
+		}
+		if _, ok := s[remote]; ok {
+			return true
+		}
+		if remote == "" {
+			return false
+		}
+	}
+}
+
+// Journal is a file of changed paths, one per line, relative to the
+// root of the remote being watched. A line of "/" means that anything
+// could have changed.
+//
+// Paths are appended with Append. Take reads the paths and moves them
+// aside so new changes can be appended while they are dealt with, and
+// Done removes them once they have been.
+type Journal struct {
+	path string
+}
+
+// NewJournal returns a Journal in the file at path. If path is "-"
+// the paths are read from stdin and not removed. Every Take of such a
+// Journal returns the same paths as stdin can only be read once.
+func NewJournal(path string) *Journal {
+	return &Journal{path: path}
+}
+
+// String returns the path of the journal
+func (j *Journal) String() string {
+	return j.path
+}
+
+// pending is where the paths are kept while they are being dealt with
+func (j *Journal) pending() string {
+	return j.path + ".syncing"
+}
+
+// full is the file whose modification time is the time of the last
+// full sync
+func (j *Journal) full() string {
+	return j.path + ".full"
+}
+
+// lock takes an exclusive lock on the journal so that Append and
+// Take, which may be in different processes, don't run at the same
+// time. Otherwise Take could move the journal aside between Append
+// opening it and writing to it, losing the change.
+func (j *Journal) lock() (unlock func(), err error) {
+	f, err := os.OpenFile(j.path+".lock", os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open journal lock")
+	}
+	err = file.Lock(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, errors.Wrap(err, "failed to lock journal")
+	}
+	return func() {
+		_ = file.Unlock(f)
+		_ = f.Close()
+	}, nil
+}
+
+// Append adds the paths in set to the journal
+func (j *Journal) Append(set Set) (err error) {
+	if len(set) == 0 {
+		return nil
+	}
+	unlock, err := j.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return appendSet(j.path, set)
+}
+
+// appendHook is called by appendSet between opening the file and
+// writing to it - it is used in the tests
+var appendHook = func() {}
+
+// appendSet adds the paths in set to the file at name
+func appendSet(name string, set Set) (err error) {
+	out, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return errors.Wrap(err, "failed to open journal")
+	}
+	defer func() {
+		closeErr := out.Close()
+		if err == nil && closeErr != nil {
+			err = errors.Wrap(closeErr, "failed to close journal")
+		}
+	}()
+	appendHook()
+	var lines strings.Builder
+	for remote := range set {
+		if remote == "" {
+			remote = Root
+		}
+		lines.WriteString(remote)
+		lines.WriteByte('\n')
+	}
+	_, err = out.WriteString(lines.String())
+	if err != nil {
+		return errors.Wrap(err, "failed to write journal")
+	}
+	return out.Sync()
+}
+
+// stdin is read by the journal "-"
+var stdin io.Reader = os.Stdin
+
+var (
+	stdinMu  sync.Mutex
+	stdinSet Set // the paths read from stdin or nil if not read yet
+)
+
+// readStdin adds the paths on stdin to set
+//
+// stdin can only be read once so the paths are kept for the next
+// time, for example when the sync is retried.
+func readStdin(set Set) error {
+	stdinMu.Lock()
+	defer stdinMu.Unlock()
+	if stdinSet == nil {
+		paths := make(Set)
+		if err := read("-", paths); err != nil {
+			return err
+		}
+		stdinSet = paths
+	}
+	for remote := range stdinSet {
+		set[remote] = struct{}{}
+	}
+	return nil
+}
+
+// read adds the paths in the file at name to set
+func read(name string, set Set) error {
+	in := stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return errors.Wrap(err, "failed to open journal")
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		in = f
+	}
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line != "" {
+			set.Add(line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(err, "failed to read journal")
+	}
+	return nil
+}
+
+// Take returns the paths in the journal, including any not dealt with
+// by an earlier Take, moving them aside so new changes start a new
+// journal.
+func (j *Journal) Take() (set Set, err error) {
+	set = make(Set)
+	if j.path == "-" {
+		return set, readStdin(set)
+	}
+	unlock, err := j.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	// Move the journal aside so new changes aren't lost, adding
+	// it to any paths left over from last time
+	pending := j.pending()
+	if _, err = os.Stat(pending); os.IsNotExist(err) {
+		err = os.Rename(j.path, pending)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, errors.Wrap(err, "failed to move journal aside")
+		}
+	} else if err != nil {
+		return nil, err
+	} else {
+		taken := j.path + ".taken"
+		err = os.Rename(j.path, taken)
+		if err == nil {
+			err = read(taken, set)
+			if err != nil {
+				return nil, err
+			}
+			old := make(Set)
+			err = read(pending, old)
+			if err != nil {
+				return nil, err
+			}
+			for remote := range old {
+				set.Add(remote)
+			}
+			err = writeSet(pending, set)
+			if err != nil {
+				return nil, err
+			}
+			_ = os.Remove(taken)
+			return set, nil
+		} else if !os.IsNotExist(err) {
+			return nil, errors.Wrap(err, "failed to move journal aside")
+		}
+	}
+	return set, read(pending, set)
+}
+
+// writeSet replaces the file at name with the paths in set
+func writeSet(name string, set Set) error {
+	tmp := name + ".tmp"
+	_ = os.Remove(tmp)
+	err := appendSet(tmp, set)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+// Done removes the paths returned by Take as they have been dealt
+// with
+func (j *Journal) Done() error {
+	if j.path == "-" {
+		return nil
+	}
+	err := os.Remove(j.pending())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// LastFullSync returns the time of the last full sync or the zero
+// time if there hasn't been one
+func (j *Journal) LastFullSync() time.Time {
+	if j.path == "-" {
+		return time.Time{}
+	}
+	fi, err := os.Stat(j.full())
+	if err != nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
+
+// FullSyncDone records the time of a full sync
+func (j *Journal) FullSyncDone() error {
+	if j.path == "-" {
+		return nil
+	}
+	f, err := os.Create(j.full())
+	if err != nil {
+		return errors.Wrap(err, "failed to record full sync")
+	}
+	err = f.Close()
+	if err != nil {
+		return errors.Wrap(err, "failed to record full sync")
+	}
+	now := time.Now()
+	return os.Chtimes(j.full(), now, now)
+}
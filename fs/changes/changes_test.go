@@ -0,0 +1,132 @@
+package changes
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSet(t *testing.T) {
+	set := make(Set)
+	for _, remote := range []string{"a/b/c", "/a/b/c/", "d", "a/b", "e/f", "e/f2", "g//h/"} {
+		set.Add(remote)
+	}
+	assert.False(t, set.HasRoot())
+	assert.Equal(t, []string{"a/b", "d", "e/f", "e/f2", "g/h"}, set.Paths())
+
+	set.Add(Root)
+	assert.True(t, set.HasRoot())
+	assert.Equal(t, []string{""}, set.Paths())
+}
+
+// newSet makes a Set from remotes
+func newSet(remotes ...string) Set {
+	set := make(Set)
+	for _, remote := range remotes {
+		set.Add(remote)
+	}
+	return set
+}
+
+func TestJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-changes-test")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	path := filepath.Join(dir, "journal")
+	j := NewJournal(path)
+
+	// Nothing to take
+	set, err := j.Take()
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(set))
+
+	// Take what was appended
+	require.NoError(t, j.Append(newSet("a", "b/c")))
+	require.NoError(t, j.Append(newSet("a", "d")))
+	set, err = j.Take()
+	require.NoError(t, err)
+	assert.Equal(t, newSet("a", "b/c", "d"), set)
+
+	// If not done the paths are taken again with the new ones
+	require.NoError(t, j.Append(newSet("e", Root)))
+	set, err = j.Take()
+	require.NoError(t, err)
+	assert.Equal(t, newSet("a", "b/c", "d", "e", ""), set)
+	set, err = j.Take()
+	require.NoError(t, err)
+	assert.Equal(t, newSet("a", "b/c", "d", "e", ""), set)
+
+	// Once done they are gone
+	require.NoError(t, j.Done())
+	set, err = j.Take()
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(set))
+
+	// Full sync time is recorded
+	assert.True(t, j.LastFullSync().IsZero())
+	require.NoError(t, j.FullSyncDone())
+	assert.WithinDuration(t, time.Now(), j.LastFullSync(), time.Minute)
+}
+
+// Test no change is lost when Take runs while Append is writing
+func TestJournalAppendTake(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-changes-test")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	path := filepath.Join(dir, "journal")
+	j := NewJournal(path)
+
+	// Take and finish with the journal after Append has opened it
+	// but before it has written to it
+	taken := make(chan Set, 1)
+	appendHook = func() {
+		appendHook = func() {}
+		go func() {
+			set, err := NewJournal(path).Take()
+			assert.NoError(t, err)
+			assert.NoError(t, j.Done())
+			taken <- set
+		}()
+		// Give Take the chance to run if it isn't held off
+		time.Sleep(100 * time.Millisecond)
+	}
+	defer func() {
+		appendHook = func() {}
+	}()
+	require.NoError(t, j.Append(newSet("a")))
+
+	set := <-taken
+	rest, err := j.Take()
+	require.NoError(t, err)
+	for remote := range rest {
+		set[remote] = struct{}{}
+	}
+	assert.Equal(t, newSet("a"), set)
+}
+
+func TestJournalStdin(t *testing.T) {
+	oldStdin := stdin
+	defer func() {
+		stdin = oldStdin
+		stdinSet = nil
+	}()
+	stdin = strings.NewReader("a\nb/c\n")
+
+	// stdin can be taken more than once, e.g. when retrying
+	for i := 0; i < 2; i++ {
+		set, err := NewJournal("-").Take()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b/c"}, set.Paths())
+		require.NoError(t, NewJournal("-").Done())
+	}
+}
@@ -70,14 +70,18 @@ type ConfigInfo struct {
 	IgnoreSize             bool
 	IgnoreChecksum         bool
 	IgnoreCaseSync         bool
+	FixCase                bool
 	NoTraverse             bool
+	FilesFromChanges       string        // journal of changed paths to sync instead of the whole tree
+	FullSyncInterval       time.Duration // time between full syncs when syncing changed paths
 	CheckFirst             bool
 	NoCheckDest            bool
 	NoUnicodeNormalization bool
 	NoUpdateModTime        bool
+	Inplace                bool
 	DataRateUnit           string
-	CompareDest            string
-	CopyDest               string
+	CompareDest            []string
+	CopyDest               []string
 	BackupDir              string
 	Suffix                 string
 	SuffixKeepExtension    bool
@@ -96,6 +100,8 @@ type ConfigInfo struct {
 	StatsFileNameLength    int
 	AskPassword            bool
 	PasswordCommand        SpaceSepList
+	ConfigKeyFile          []string
+	ConfigKeyCommand       SpaceSepList
 	UseServerModTime       bool
 	MaxTransfer            SizeSuffix
 	MaxDuration            time.Duration
@@ -121,6 +127,10 @@ type ConfigInfo struct {
 	DownloadHeaders        []*HTTPOption
 	Headers                []*HTTPOption
 	RefreshTimes           bool
+	Metadata               bool              // preserve metadata when copying objects
+	MetadataSet            Metadata          // extra metadata to write when uploading
+	MetadataMap            map[string]string // rename metadata keys when copying objects
+	ResumeUploads          bool              // save the state of uploads so they can be resumed
 }
 
 // NewConfig creates a new config with everything set to the default
@@ -152,6 +162,7 @@ func NewConfig() *ConfigInfo {
 	c.TPSLimitBurst = 1
 	c.MaxTransfer = -1
 	c.MaxBacklog = 10000
+	c.FullSyncInterval = 7 * 24 * time.Hour
 	// We do not want to set the default here. We use this variable being empty as part of the fall-through of options.
 	//	c.StatsOneLineDateFormat = "2006/01/02 15:04:05 - "
 	c.MultiThreadCutoff = SizeSuffix(250 * 1024 * 1024)
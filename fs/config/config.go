@@ -15,7 +15,6 @@ import (
 	"log"
 	mathrand "math/rand"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"runtime"
@@ -80,7 +79,8 @@ var (
 	// configFile is the global config data structure. Don't read it directly, use getConfigData()
 	configFile *goconfig.ConfigFile
 
-	// ConfigPath points to the config file
+	// ConfigPath points to the config file, or where the config is
+	// stored if StorageType isn't "file"
 	ConfigPath = makeConfigPath()
 
 	// CacheDir points to the cache directory.  Users of this
@@ -215,18 +215,31 @@ func makeConfigPath() string {
 // LoadConfig loads the config file
 func LoadConfig(ctx context.Context) {
 	// Set RCLONE_CONFIG_DIR for backend config and subprocesses
-	_ = os.Setenv("RCLONE_CONFIG_DIR", filepath.Dir(ConfigPath))
+	switch StorageType {
+	case "file":
+		_ = os.Setenv("RCLONE_CONFIG_DIR", filepath.Dir(ConfigPath))
+	case "dir":
+		_ = os.Setenv("RCLONE_CONFIG_DIR", ConfigPath)
+	}
+
+	// Start with an empty config so any remote needed to load the
+	// config doesn't try to read it
+	configFile, _ = goconfig.LoadFromReader(&bytes.Buffer{})
 
 	// Load configuration file.
-	var err error
-	configFile, err = loadConfigFile()
+	storage, err := getStorage()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	newConfigFile, err := storage.Load()
 	if err == errorConfigFileNotFound {
-		fs.Logf(nil, "Config file %q not found - using defaults", ConfigPath)
-		configFile, _ = goconfig.LoadFromReader(&bytes.Buffer{})
+		where := storage.String()
+		fs.Logf(nil, "%s%s not found - using defaults", strings.ToUpper(where[:1]), where[1:])
 	} else if err != nil {
-		log.Fatalf("Failed to load config file %q: %v", ConfigPath, err)
+		log.Fatalf("Failed to load %v: %v", storage, err)
 	} else {
-		fs.Debugf(nil, "Using config file from %q", ConfigPath)
+		configFile = newConfigFile
+		fs.Debugf(nil, "Using %v", storage)
 	}
 
 	// Start the token bucket limiter
@@ -241,20 +254,23 @@ func LoadConfig(ctx context.Context) {
 
 var errorConfigFileNotFound = errors.New("config file not found")
 
-// loadConfigFile will load a config file, and
+// loadConfigFile will load the config from its storage, and
 // automatically decrypt it.
 func loadConfigFile() (*goconfig.ConfigFile, error) {
+	storage, err := getStorage()
+	if err != nil {
+		return nil, err
+	}
+	return storage.Load()
+}
+
+// parseConfig parses the contents of a config file, decrypting it
+// if necessary.
+func parseConfig(b []byte) (*goconfig.ConfigFile, error) {
 	ctx := context.Background()
 	ci := fs.GetConfig(ctx)
 	var usingPasswordCommand bool
 
-	b, err := ioutil.ReadFile(ConfigPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, errorConfigFileNotFound
-		}
-		return nil, err
-	}
 	// Find first non-empty line
 	r := bufio.NewReader(bytes.NewBuffer(b))
 	for {
@@ -273,6 +289,9 @@ func loadConfigFile() (*goconfig.ConfigFile, error) {
 		if l == "RCLONE_ENCRYPT_V0:" {
 			break
 		}
+		if l == encryptV1Header {
+			return parseConfigV1(ci, r)
+		}
 		if strings.HasPrefix(l, "RCLONE_ENCRYPT_V") {
 			return nil, errors.New("unsupported configuration encryption - update rclone for support")
 		}
@@ -281,30 +300,13 @@ func loadConfigFile() (*goconfig.ConfigFile, error) {
 
 	if len(configKey) == 0 {
 		if len(ci.PasswordCommand) != 0 {
-			var stdout bytes.Buffer
-			var stderr bytes.Buffer
-
-			cmd := exec.Command(ci.PasswordCommand[0], ci.PasswordCommand[1:]...)
-
-			cmd.Stdout = &stdout
-			cmd.Stderr = &stderr
-			cmd.Stdin = os.Stdin
-
-			if err := cmd.Run(); err != nil {
-				// One does not always get the stderr returned in the wrapped error.
-				fs.Errorf(nil, "Using --password-command returned: %v", err)
-				if ers := strings.TrimSpace(stderr.String()); ers != "" {
-					fs.Errorf(nil, "--password-command stderr: %s", ers)
-				}
-				return nil, errors.Wrap(err, "password command failed")
+			pass, err := runKeyCommand("--password-command", ci.PasswordCommand)
+			if err != nil {
+				return nil, err
 			}
-			if pass := strings.Trim(stdout.String(), "\r\n"); pass != "" {
-				err := setConfigPassword(pass)
-				if err != nil {
-					return nil, errors.Wrap(err, "incorrect password")
-				}
-			} else {
-				return nil, errors.New("password-command returned empty string")
+			err = setConfigPassword(pass)
+			if err != nil {
+				return nil, errors.Wrap(err, "incorrect password")
 			}
 
 			if len(configKey) == 0 {
@@ -384,6 +386,7 @@ func loadConfigFile() (*goconfig.ConfigFile, error) {
 		fs.Errorf(nil, "Couldn't decrypt configuration, most likely wrong password.")
 		configKey = nil
 	}
+	configEncryption = nil
 	return goconfig.LoadFromReader(bytes.NewBuffer(out))
 }
 
@@ -509,99 +512,73 @@ func changeConfigPassword() {
 	}
 }
 
-// saveConfig saves configuration file.
-// if configKey has been set, the file will be encrypted.
-func saveConfig() error {
-	dir, name := filepath.Split(ConfigPath)
-	err := os.MkdirAll(dir, os.ModePerm)
-	if err != nil {
-		return errors.Wrap(err, "failed to create config directory")
-	}
-	f, err := ioutil.TempFile(dir, name)
-	if err != nil {
-		return errors.Errorf("Failed to create temp file for new config: %v", err)
-	}
-	defer func() {
-		if err := os.Remove(f.Name()); err != nil && !os.IsNotExist(err) {
-			fs.Errorf(nil, "Failed to remove temp config file: %v", err)
-		}
-	}()
-
+// marshalConfig returns the contents of the config file for c.
+// If configKey has been set, the contents will be encrypted. If
+// configEncryption has been set, the secrets in it will be encrypted.
+func marshalConfig(c *goconfig.ConfigFile) ([]byte, error) {
 	var buf bytes.Buffer
-	err = goconfig.SaveConfigData(getConfigData(), &buf)
+	err := goconfig.SaveConfigData(c, &buf)
 	if err != nil {
-		return errors.Errorf("Failed to save config file: %v", err)
+		return nil, errors.Errorf("Failed to save config file: %v", err)
 	}
 
+	if configEncryption != nil {
+		return configEncryption.marshal(buf.Bytes())
+	}
 	if len(configKey) == 0 {
-		if _, err := buf.WriteTo(f); err != nil {
-			return errors.Errorf("Failed to write temp config file: %v", err)
-		}
-	} else {
-		_, _ = fmt.Fprintln(f, "# Encrypted rclone configuration File")
-		_, _ = fmt.Fprintln(f, "")
-		_, _ = fmt.Fprintln(f, "RCLONE_ENCRYPT_V0:")
-
-		// Generate new nonce and write it to the start of the ciphertext
-		var nonce [24]byte
-		n, _ := rand.Read(nonce[:])
-		if n != 24 {
-			return errors.Errorf("nonce short read: %d", n)
-		}
-		enc := base64.NewEncoder(base64.StdEncoding, f)
-		_, err = enc.Write(nonce[:])
-		if err != nil {
-			return errors.Errorf("Failed to write temp config file: %v", err)
-		}
-
-		var key [32]byte
-		copy(key[:], configKey[:32])
-
-		b := secretbox.Seal(nil, buf.Bytes(), &nonce, &key)
-		_, err = enc.Write(b)
-		if err != nil {
-			return errors.Errorf("Failed to write temp config file: %v", err)
-		}
-		_ = enc.Close()
+		return buf.Bytes(), nil
 	}
 
-	_ = f.Sync()
-	err = f.Close()
-	if err != nil {
-		return errors.Errorf("Failed to close config file: %v", err)
-	}
+	var out bytes.Buffer
+	_, _ = fmt.Fprintln(&out, "# Encrypted rclone configuration File")
+	_, _ = fmt.Fprintln(&out, "")
+	_, _ = fmt.Fprintln(&out, "RCLONE_ENCRYPT_V0:")
 
-	var fileMode os.FileMode = 0600
-	info, err := os.Stat(ConfigPath)
+	// Generate new nonce and write it to the start of the ciphertext
+	var nonce [24]byte
+	n, _ := rand.Read(nonce[:])
+	if n != 24 {
+		return nil, errors.Errorf("nonce short read: %d", n)
+	}
+	enc := base64.NewEncoder(base64.StdEncoding, &out)
+	_, err = enc.Write(nonce[:])
 	if err != nil {
-		fs.Debugf(nil, "Using default permissions for config file: %v", fileMode)
-	} else if info.Mode() != fileMode {
-		fs.Debugf(nil, "Keeping previous permissions for config file: %v", info.Mode())
-		fileMode = info.Mode()
+		return nil, errors.Errorf("Failed to write temp config file: %v", err)
 	}
 
-	attemptCopyGroup(ConfigPath, f.Name())
+	var key [32]byte
+	copy(key[:], configKey[:32])
 
-	err = os.Chmod(f.Name(), fileMode)
+	b := secretbox.Seal(nil, buf.Bytes(), &nonce, &key)
+	_, err = enc.Write(b)
 	if err != nil {
-		fs.Errorf(nil, "Failed to set permissions on config file: %v", err)
+		return nil, errors.Errorf("Failed to write temp config file: %v", err)
 	}
+	_ = enc.Close()
+	return out.Bytes(), nil
+}
 
-	if err = os.Rename(ConfigPath, ConfigPath+".old"); err != nil && !os.IsNotExist(err) {
-		return errors.Errorf("Failed to move previous config to backup location: %v", err)
-	}
-	if err = os.Rename(f.Name(), ConfigPath); err != nil {
-		return errors.Errorf("Failed to move newly written config from %s to final location: %v", f.Name(), err)
-	}
-	if err := os.Remove(ConfigPath + ".old"); err != nil && !os.IsNotExist(err) {
-		fs.Errorf(nil, "Failed to remove backup config file: %v", err)
+// saveConfig saves the config to its storage.
+// if configKey has been set, the file will be encrypted.
+func saveConfig() error {
+	storage, err := getStorage()
+	if err != nil {
+		return err
 	}
-	return nil
+	return storage.Save(getConfigData())
 }
 
 // SaveConfig calling function which saves configuration file.
 // if saveConfig returns error trying again after sleep.
 func SaveConfig() {
+	unlock := lockConfig()
+	defer unlock()
+	saveConfigWithRetries()
+}
+
+// saveConfigWithRetries saves the config, trying again after a sleep
+// if it fails. The config should be locked with lockConfig.
+func saveConfigWithRetries() {
 	ctx := context.Background()
 	ci := fs.GetConfig(ctx)
 	var err error
@@ -609,6 +586,10 @@ func SaveConfig() {
 		if err = saveConfig(); err == nil {
 			return
 		}
+		if err == errorConfigReadOnly {
+			fs.Errorf(nil, "Failed to save config - changes will be lost when rclone exits: %v", err)
+			return
+		}
 		waitingTimeMs := mathrand.Intn(1000)
 		time.Sleep(time.Duration(waitingTimeMs) * time.Millisecond)
 	}
@@ -620,9 +601,15 @@ func SaveConfig() {
 // SetValueAndSave sets the key to the value and saves just that
 // value in the config file.  It loads the old config file in from
 // disk first and overwrites the given value only.
+//
+// The config is locked while this is done so rclone processes
+// refreshing tokens at the same time don't lose each other's changes.
 func SetValueAndSave(name, key, value string) (err error) {
 	// Set the value in config in case we fail to reload it
 	getConfigData().SetValue(name, key, value)
+	// Stop other rclone processes saving the config until we are done
+	unlock := lockConfig()
+	defer unlock()
 	// Reload the config file
 	reloadedConfigFile, err := loadConfigFile()
 	if err == errorConfigFileNotFound {
@@ -641,7 +628,7 @@ func SetValueAndSave(name, key, value string) (err error) {
 	// Set the value in the reloaded version
 	reloadedConfigFile.SetValue(name, key, value)
 	// Save it again
-	SaveConfig()
+	saveConfigWithRetries()
 	return nil
 }
 
@@ -1288,6 +1275,14 @@ func CopyRemote(name string) {
 
 // ShowConfigLocation prints the location of the config file in use
 func ShowConfigLocation() {
+	if StorageType != "file" {
+		storage, err := getStorage()
+		if err != nil {
+			log.Fatalf("Failed to find config: %v", err)
+		}
+		fmt.Printf("Configuration is stored in %v\n", storage)
+		return
+	}
 	if _, err := os.Stat(ConfigPath); os.IsNotExist(err) {
 		fmt.Println("Configuration file doesn't exist, but rclone will use this path:")
 	} else {
@@ -1350,7 +1345,30 @@ func EditConfig(ctx context.Context) {
 // configuration encryption settings.
 func SetPassword() {
 	for {
-		if len(configKey) > 0 {
+		if configEncryption != nil {
+			fmt.Println("The secrets in your configuration are encrypted with keys or a passphrase.")
+			for _, recipient := range configEncryption.recipients() {
+				fmt.Printf("  - %s\n", recipient)
+			}
+			if configEncryption.hasPassphrase() {
+				fmt.Println("  - passphrase")
+			}
+			what := []string{"rRotate key", "uUnencrypt configuration", "qQuit to main menu"}
+			switch i := Command(what); i {
+			case 'r':
+				if err := RotateConfigKey(); err != nil {
+					fmt.Printf("Failed to rotate key: %v\n", err)
+				} else {
+					fmt.Println("Key rotated")
+				}
+				continue
+			case 'u':
+				DecryptConfig()
+				continue
+			case 'q':
+				return
+			}
+		} else if len(configKey) > 0 {
 			fmt.Println("Your configuration is encrypted.")
 			what := []string{"cChange Password", "uUnencrypt configuration", "qQuit to main menu"}
 			switch i := Command(what); i {
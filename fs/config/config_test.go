@@ -19,6 +19,7 @@ func testConfigFile(t *testing.T, configFileName string) func() {
 	ctx := context.Background()
 	ci := fs.GetConfig(ctx)
 	configKey = nil // reset password
+	configEncryption = nil
 	_ = os.Unsetenv("_RCLONE_CONFIG_KEY_FILE")
 	_ = os.Unsetenv("RCLONE_CONFIG_PASS")
 	// create temp config file
@@ -63,6 +64,7 @@ func testConfigFile(t *testing.T, configFileName string) func() {
 	return func() {
 		err := os.Remove(path)
 		assert.NoError(t, err)
+		_ = os.Remove(path + ".lock")
 
 		os.Stdout = oldOsStdout
 		ConfigPath = oldConfigPath
@@ -70,6 +72,8 @@ func testConfigFile(t *testing.T, configFileName string) func() {
 		Password = oldPassword
 		*ci = oldConfig
 		configFile = oldConfigFile
+		configEncryption = nil
+		configKey = nil
 
 		_ = os.Unsetenv("_RCLONE_CONFIG_KEY_FILE")
 		_ = os.Unsetenv("RCLONE_CONFIG_PASS")
@@ -0,0 +1,808 @@
+// Encryption of the secrets in the config with key files and passphrases
+
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/Unknwon/goconfig"
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/scrypt"
+)
+
+// The config file encrypted with the V1 scheme looks like this
+//
+//   RCLONE_ENCRYPT_V1:
+//   -> X25519 <recipient public key> <ephemeral public key> <wrapped data key>
+//   -> scrypt <salt> <log2 of the work factor> <wrapped data key>
+//   --- <MAC of the lines above>
+//
+//   [remote]
+//   type = drive
+//   token = ENC[<nonce and encrypted value>]
+//
+// The secret values are encrypted with a random data key. Each of
+// the "->" lines holds a copy of the data key wrapped for one
+// recipient: the holder of an X25519 key file or a passphrase. The
+// data key can be replaced without re-entering the secrets, and the
+// rest of the config stays readable so it can be diffed.
+const (
+	encryptV1Header = "RCLONE_ENCRYPT_V1:"
+	stanzaPrefix    = "-> "
+	macPrefix       = "--- "
+	encryptedPrefix = "ENC["
+	encryptedSuffix = "]"
+
+	// SecretKeyPrefix starts the secret key in a key file
+	SecretKeyPrefix = "RCLONE-SECRET-KEY-"
+
+	// PublicKeyPrefix starts a public key
+	PublicKeyPrefix = "RCLONE-PUBLIC-KEY-"
+
+	stanzaX25519  = "X25519"
+	stanzaScrypt  = "scrypt"
+	scryptLogN    = 16 // work factor used for new passphrases
+	maxScryptLogN = 22 // biggest work factor accepted
+
+	dataKeySize = 32
+)
+
+// Labels to derive the different keys with
+const (
+	labelX25519 = "rclone-config-v1/X25519"
+	labelScrypt = "rclone-config-v1/scrypt"
+	labelMAC    = "rclone-config-v1/header"
+	labelValues = "rclone-config-v1/values"
+)
+
+// b64 is the encoding used for binary data in the config
+var b64 = base64.RawURLEncoding
+
+// encryptionV1 holds the state of a config encrypted with the V1
+// scheme.
+type encryptionV1 struct {
+	dataKey    []byte
+	stanzas    []stanza
+	passphrase string            // passphrase used, if known, for rewrapping
+	encrypted  map[string]string // encrypted values read, indexed by section, key and value
+}
+
+// stanza is the data key wrapped for one recipient
+type stanza struct {
+	kind string   // stanzaX25519 or stanzaScrypt
+	args []string // arguments before the wrapped key
+	body []byte   // the wrapped data key
+}
+
+// configEncryption is set if the config uses the V1 scheme
+var configEncryption *encryptionV1
+
+// String makes the line for the stanza
+func (s stanza) String() string {
+	return stanzaPrefix + strings.Join(append(append([]string{s.kind}, s.args...), b64.EncodeToString(s.body)), " ")
+}
+
+// parseStanza parses a stanza line without the prefix
+func parseStanza(line string) (s stanza, err error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return s, errors.Errorf("bad key line %q", line)
+	}
+	s.kind = fields[0]
+	s.args = fields[1 : len(fields)-1]
+	s.body, err = b64.DecodeString(fields[len(fields)-1])
+	if err != nil {
+		return s, errors.Wrapf(err, "bad wrapped key in %q", line)
+	}
+	return s, nil
+}
+
+// deriveKey derives a 32 byte key for label from secret and salt
+func deriveKey(secret, salt []byte, label string) []byte {
+	key := make([]byte, chacha20poly1305.KeySize)
+	_, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, []byte(label)), key)
+	if err != nil {
+		panic(err) // only fails if asked for too many bytes
+	}
+	return key
+}
+
+// wrapKey encrypts the data key with wrapKey. The wrapping keys are
+// only used once so the nonce can be zero.
+func wrapKey(wrappingKey, dataKey []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.New(wrappingKey)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nil, make([]byte, aead.NonceSize()), dataKey, nil), nil
+}
+
+// unwrapKey decrypts the data key with wrapKey
+func unwrapKey(wrappingKey, wrapped []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.New(wrappingKey)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := aead.Open(nil, make([]byte, aead.NonceSize()), wrapped, nil)
+	if err != nil || len(dataKey) != dataKeySize {
+		return nil, errors.New("wrong key")
+	}
+	return dataKey, nil
+}
+
+// GenerateKey makes a new X25519 key pair, returning the secret key
+// for the key file and the public key to encrypt the config to.
+func GenerateKey() (secretKey, publicKey string, err error) {
+	secret := make([]byte, curve25519.ScalarSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", errors.Wrap(err, "failed to make key")
+	}
+	public, err := curve25519.X25519(secret, curve25519.Basepoint)
+	if err != nil {
+		return "", "", err
+	}
+	return SecretKeyPrefix + b64.EncodeToString(secret), PublicKeyPrefix + b64.EncodeToString(public), nil
+}
+
+// parseKey parses a key with the prefix given
+func parseKey(key, prefix string) ([]byte, error) {
+	if !strings.HasPrefix(key, prefix) {
+		return nil, errors.Errorf("key must start with %q", prefix)
+	}
+	b, err := b64.DecodeString(key[len(prefix):])
+	if err == nil && len(b) != curve25519.PointSize {
+		err = errors.New("wrong length")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "bad key")
+	}
+	return b, nil
+}
+
+// PublicKey returns the public key for the secret key, or the public
+// key itself if passed one.
+func PublicKey(key string) (string, error) {
+	key = strings.TrimSpace(key)
+	if strings.HasPrefix(key, PublicKeyPrefix) {
+		_, err := parseKey(key, PublicKeyPrefix)
+		return key, err
+	}
+	secret, err := parseKey(key, SecretKeyPrefix)
+	if err != nil {
+		return "", err
+	}
+	public, err := curve25519.X25519(secret, curve25519.Basepoint)
+	if err != nil {
+		return "", err
+	}
+	return PublicKeyPrefix + b64.EncodeToString(public), nil
+}
+
+// ParseKeys reads the keys, one per line, ignoring blank lines and
+// comments starting with #.
+func ParseKeys(in io.Reader) (keys []string, err error) {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keys = append(keys, line)
+	}
+	return keys, scanner.Err()
+}
+
+// wrapX25519 wraps the data key for the recipient's public key
+func wrapX25519(dataKey []byte, recipient string) (s stanza, err error) {
+	public, err := parseKey(recipient, PublicKeyPrefix)
+	if err != nil {
+		return s, err
+	}
+	ephemeralSecret := make([]byte, curve25519.ScalarSize)
+	if _, err := rand.Read(ephemeralSecret); err != nil {
+		return s, err
+	}
+	ephemeral, err := curve25519.X25519(ephemeralSecret, curve25519.Basepoint)
+	if err != nil {
+		return s, err
+	}
+	shared, err := curve25519.X25519(ephemeralSecret, public)
+	if err != nil {
+		return s, err
+	}
+	body, err := wrapKey(deriveKey(shared, append(ephemeral, public...), labelX25519), dataKey)
+	if err != nil {
+		return s, err
+	}
+	return stanza{
+		kind: stanzaX25519,
+		args: []string{recipient, b64.EncodeToString(ephemeral)},
+		body: body,
+	}, nil
+}
+
+// unwrapX25519 unwraps the data key with the secret key, returning
+// nil if the stanza is for a different key
+func unwrapX25519(s stanza, secretKey string) ([]byte, error) {
+	if len(s.args) != 2 {
+		return nil, errors.New("bad X25519 key line")
+	}
+	recipient, err := PublicKey(secretKey)
+	if err != nil {
+		return nil, err
+	}
+	if recipient != s.args[0] {
+		return nil, nil
+	}
+	secret, err := parseKey(secretKey, SecretKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	ephemeral, err := b64.DecodeString(s.args[1])
+	if err != nil {
+		return nil, errors.Wrap(err, "bad X25519 key line")
+	}
+	shared, err := curve25519.X25519(secret, ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	public, _ := parseKey(recipient, PublicKeyPrefix)
+	return unwrapKey(deriveKey(shared, append(ephemeral, public...), labelX25519), s.body)
+}
+
+// wrapScrypt wraps the data key with the passphrase
+func wrapScrypt(dataKey []byte, passphrase string) (s stanza, err error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return s, err
+	}
+	key, err := scrypt.Key([]byte(passphrase), append([]byte(labelScrypt), salt...), 1<<scryptLogN, 8, 1, chacha20poly1305.KeySize)
+	if err != nil {
+		return s, err
+	}
+	body, err := wrapKey(key, dataKey)
+	if err != nil {
+		return s, err
+	}
+	return stanza{
+		kind: stanzaScrypt,
+		args: []string{b64.EncodeToString(salt), strconv.Itoa(scryptLogN)},
+		body: body,
+	}, nil
+}
+
+// unwrapScrypt unwraps the data key with the passphrase
+func unwrapScrypt(s stanza, passphrase string) ([]byte, error) {
+	if len(s.args) != 2 {
+		return nil, errors.New("bad scrypt key line")
+	}
+	salt, err := b64.DecodeString(s.args[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "bad scrypt key line")
+	}
+	logN, err := strconv.Atoi(s.args[1])
+	if err != nil || logN < 1 || logN > maxScryptLogN {
+		return nil, errors.Errorf("bad scrypt work factor %q", s.args[1])
+	}
+	key, err := scrypt.Key([]byte(passphrase), append([]byte(labelScrypt), salt...), 1<<logN, 8, 1, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	return unwrapKey(key, s.body)
+}
+
+// newEncryptionV1 makes a new random data key wrapped for each of the
+// recipients and the passphrase if set.
+func newEncryptionV1(recipients []string, passphrase string) (*encryptionV1, error) {
+	if len(recipients) == 0 && passphrase == "" {
+		return nil, errors.New("need at least one key or a passphrase to encrypt the config")
+	}
+	e := &encryptionV1{
+		dataKey:    make([]byte, dataKeySize),
+		passphrase: passphrase,
+	}
+	if _, err := rand.Read(e.dataKey); err != nil {
+		return nil, errors.Wrap(err, "failed to make key")
+	}
+	seen := map[string]bool{}
+	for _, recipient := range recipients {
+		recipient, err := PublicKey(recipient)
+		if err != nil {
+			return nil, err
+		}
+		if seen[recipient] {
+			continue
+		}
+		seen[recipient] = true
+		s, err := wrapX25519(e.dataKey, recipient)
+		if err != nil {
+			return nil, err
+		}
+		e.stanzas = append(e.stanzas, s)
+	}
+	if passphrase != "" {
+		s, err := wrapScrypt(e.dataKey, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		e.stanzas = append(e.stanzas, s)
+	}
+	return e, nil
+}
+
+// recipients returns the public keys the data key is wrapped for
+func (e *encryptionV1) recipients() (recipients []string) {
+	for _, s := range e.stanzas {
+		if s.kind == stanzaX25519 && len(s.args) > 0 {
+			recipients = append(recipients, s.args[0])
+		}
+	}
+	return recipients
+}
+
+// hasPassphrase returns true if the data key is wrapped with a passphrase
+func (e *encryptionV1) hasPassphrase() bool {
+	for _, s := range e.stanzas {
+		if s.kind == stanzaScrypt {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPassphrase returns true if the passphrase unwraps the data key
+func (e *encryptionV1) checkPassphrase(passphrase string) bool {
+	for _, s := range e.stanzas {
+		if s.kind != stanzaScrypt {
+			continue
+		}
+		dataKey, err := unwrapScrypt(s, passphrase)
+		if err == nil && bytes.Equal(dataKey, e.dataKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// header returns the header lines which the MAC is over
+func (e *encryptionV1) header() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(encryptV1Header + "\n")
+	for _, s := range e.stanzas {
+		buf.WriteString(s.String() + "\n")
+	}
+	return buf.Bytes()
+}
+
+// mac returns the MAC of the header with the data key
+func (e *encryptionV1) mac(dataKey []byte) []byte {
+	h := hmac.New(sha256.New, deriveKey(dataKey, nil, labelMAC))
+	_, _ = h.Write(e.header())
+	return h.Sum(nil)
+}
+
+// readHeaderV1 reads the key lines and MAC after the encryptV1Header
+// line.
+func readHeaderV1(r *bufio.Reader) (e *encryptionV1, mac []byte, err error) {
+	e = &encryptionV1{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to read encrypted config header")
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, stanzaPrefix):
+			s, err := parseStanza(line[len(stanzaPrefix):])
+			if err != nil {
+				return nil, nil, err
+			}
+			e.stanzas = append(e.stanzas, s)
+		case strings.HasPrefix(line, macPrefix):
+			mac, err = b64.DecodeString(strings.TrimSpace(line[len(macPrefix):]))
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "bad MAC in encrypted config header")
+			}
+			return e, mac, nil
+		default:
+			return nil, nil, errors.Errorf("unexpected line %q in encrypted config header", line)
+		}
+	}
+}
+
+// runKeyCommand runs the command named by flag returning the first
+// line of its output
+func runKeyCommand(flag string, command []string) (string, error) {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	cmd := exec.Command(command[0], command[1:]...)
+
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Stdin = os.Stdin
+
+	if err := cmd.Run(); err != nil {
+		// One does not always get the stderr returned in the wrapped error.
+		fs.Errorf(nil, "Using %s returned: %v", flag, err)
+		if ers := strings.TrimSpace(stderr.String()); ers != "" {
+			fs.Errorf(nil, "%s stderr: %s", flag, ers)
+		}
+		return "", errors.Wrapf(err, "%s failed", strings.TrimLeft(flag, "-"))
+	}
+	out := strings.Trim(stdout.String(), "\r\n")
+	if out == "" {
+		return "", errors.Errorf("%s returned empty string", strings.TrimLeft(flag, "-"))
+	}
+	return out, nil
+}
+
+// secretKeys returns the secret keys from --config-key-file and
+// --config-key-command
+func secretKeys(ci *fs.ConfigInfo) (keys []string, err error) {
+	for _, keyFile := range ci.ConfigKeyFile {
+		b, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read --config-key-file")
+		}
+		fileKeys, err := ParseKeys(bytes.NewReader(b))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read --config-key-file %q", keyFile)
+		}
+		keys = append(keys, fileKeys...)
+	}
+	if len(ci.ConfigKeyCommand) != 0 {
+		out, err := runKeyCommand("--config-key-command", ci.ConfigKeyCommand)
+		if err != nil {
+			return nil, err
+		}
+		commandKeys, err := ParseKeys(strings.NewReader(out))
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, commandKeys...)
+	}
+	return keys, nil
+}
+
+// unlock finds the data key using the key passed from the parent
+// process, the key files or the passphrase, checking it against mac.
+func (e *encryptionV1) unlock(ci *fs.ConfigInfo, mac []byte) error {
+	try := func(dataKey []byte) bool {
+		if dataKey != nil && hmac.Equal(e.mac(dataKey), mac) {
+			e.dataKey = dataKey
+			return true
+		}
+		return false
+	}
+
+	// Key passed on by the parent process - see PassConfigKeyForDaemonization
+	if envKeyFile := os.Getenv("_RCLONE_CONFIG_KEY_FILE"); len(envKeyFile) > 0 {
+		obscuredKey, err := ioutil.ReadFile(envKeyFile)
+		_ = os.Remove(envKeyFile)
+		if err == nil && try([]byte(obscure.MustReveal(string(obscuredKey)))) {
+			fs.Debugf(nil, "using _RCLONE_CONFIG_KEY_FILE for config key")
+			return nil
+		}
+	}
+
+	// Key files
+	keys, err := secretKeys(ci)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		for _, s := range e.stanzas {
+			if s.kind != stanzaX25519 {
+				continue
+			}
+			dataKey, err := unwrapX25519(s, key)
+			if err != nil {
+				fs.Debugf(nil, "Failed to decrypt config key: %v", err)
+			}
+			if try(dataKey) {
+				return e.passOn()
+			}
+		}
+	}
+
+	// Passphrase
+	if !e.hasPassphrase() {
+		return errors.New("unable to decrypt configuration: no matching key - use --config-key-file or --config-key-command")
+	}
+	passphrase := func() (string, error) {
+		return GetPassword("Enter configuration passphrase:"), nil
+	}
+	switch {
+	case len(ci.PasswordCommand) != 0:
+		passphrase = func() (string, error) {
+			return runKeyCommand("--password-command", ci.PasswordCommand)
+		}
+	case os.Getenv("RCLONE_CONFIG_PASS") != "":
+		passphrase = func() (string, error) {
+			return os.Getenv("RCLONE_CONFIG_PASS"), nil
+		}
+	case !ci.AskPassword:
+		return errors.New("unable to decrypt configuration and not allowed to ask for password - set RCLONE_CONFIG_PASS or use --config-key-file")
+	}
+	for {
+		pass, err := passphrase()
+		if err != nil {
+			return err
+		}
+		pass, err = checkPassword(pass)
+		if err != nil {
+			return err
+		}
+		for _, s := range e.stanzas {
+			if s.kind != stanzaScrypt {
+				continue
+			}
+			dataKey, err := unwrapScrypt(s, pass)
+			if err != nil {
+				fs.Debugf(nil, "Failed to decrypt config key: %v", err)
+			}
+			if try(dataKey) {
+				e.passphrase = pass
+				return e.passOn()
+			}
+		}
+		if len(ci.PasswordCommand) != 0 || os.Getenv("RCLONE_CONFIG_PASS") != "" {
+			return errors.New("unable to decrypt configuration: incorrect passphrase")
+		}
+		fs.Errorf(nil, "Couldn't decrypt configuration, most likely wrong passphrase.")
+	}
+}
+
+// passOn saves the data key for a child process if required - see
+// PassConfigKeyForDaemonization
+func (e *encryptionV1) passOn() error {
+	if !PassConfigKeyForDaemonization {
+		return nil
+	}
+	tempFile, err := ioutil.TempFile("", "rclone")
+	if err != nil {
+		return errors.Wrap(err, "cannot create temp file to store config key")
+	}
+	_, err = tempFile.WriteString(obscure.MustObscure(string(e.dataKey)))
+	closeErr := tempFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Setenv("_RCLONE_CONFIG_KEY_FILE", tempFile.Name())
+	}
+	if err != nil {
+		_ = os.Remove(tempFile.Name())
+		return errors.Wrap(err, "failed to pass config key on")
+	}
+	fs.Debugf(nil, "saving config key to temp file")
+	return nil
+}
+
+// valueAEAD returns the cipher for the values in the config
+func (e *encryptionV1) valueAEAD() (aeadCipher, error) {
+	return chacha20poly1305.NewX(deriveKey(e.dataKey, nil, labelValues))
+}
+
+// aeadCipher is the interface of the value cipher
+type aeadCipher interface {
+	NonceSize() int
+	Overhead() int
+	Seal(dst, nonce, plaintext, additionalData []byte) []byte
+	Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error)
+}
+
+// valueID identifies a value so the encrypted version can be reused
+func valueID(section, key, value string) string {
+	return section + "\x00" + key + "\x00" + value
+}
+
+// decryptValues decrypts all the encrypted values in c, binding each
+// to its section and key so they can't be swapped around.
+func (e *encryptionV1) decryptValues(c *goconfig.ConfigFile) error {
+	aead, err := e.valueAEAD()
+	if err != nil {
+		return err
+	}
+	e.encrypted = map[string]string{}
+	for _, section := range c.GetSectionList() {
+		for _, key := range c.GetKeyList(section) {
+			value, _ := c.GetValue(section, key)
+			if !strings.HasPrefix(value, encryptedPrefix) || !strings.HasSuffix(value, encryptedSuffix) {
+				continue
+			}
+			b, err := b64.DecodeString(value[len(encryptedPrefix) : len(value)-len(encryptedSuffix)])
+			if err != nil || len(b) < aead.NonceSize() {
+				return errors.Errorf("bad encrypted value for %q in [%s]", key, section)
+			}
+			plain, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], []byte(section+"\x00"+key))
+			if err != nil {
+				return errors.Errorf("failed to decrypt value for %q in [%s]", key, section)
+			}
+			c.SetValue(section, key, string(plain))
+			e.encrypted[valueID(section, key, string(plain))] = value
+		}
+	}
+	return nil
+}
+
+// isSecret returns true if the key in section of c should be
+// encrypted: passwords of the backend and anything which looks like
+// a password, secret or token.
+func isSecret(c *goconfig.ConfigFile, section, key string) bool {
+	if key == ConfigToken || key == ConfigClientSecret {
+		return true
+	}
+	for _, word := range []string{"pass", "secret", "token"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	if key == "key" || strings.HasSuffix(key, "_key") {
+		return true
+	}
+	if backend, err := c.GetValue(section, "type"); err == nil {
+		if ri, err := fs.Find(backend); err == nil {
+			if o := ri.Options.Get(key); o != nil && o.IsPassword {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// marshal returns the config file for the plain text config in
+// plain with the secret values encrypted.
+func (e *encryptionV1) marshal(plain []byte) ([]byte, error) {
+	c, err := goconfig.LoadFromReader(bytes.NewReader(plain))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encrypt config")
+	}
+	aead, err := e.valueAEAD()
+	if err != nil {
+		return nil, err
+	}
+	encryptedValues := map[string]string{}
+	for _, section := range c.GetSectionList() {
+		for _, key := range c.GetKeyList(section) {
+			value, _ := c.GetValue(section, key)
+			if value == "" || !isSecret(c, section, key) {
+				continue
+			}
+			// Reuse the encrypted value if unchanged to keep diffs small
+			id := valueID(section, key, value)
+			encrypted, ok := e.encrypted[id]
+			if !ok {
+				nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(value)+aead.Overhead())
+				if _, err := rand.Read(nonce); err != nil {
+					return nil, err
+				}
+				b := aead.Seal(nonce, nonce, []byte(value), []byte(section+"\x00"+key))
+				encrypted = encryptedPrefix + b64.EncodeToString(b) + encryptedSuffix
+			}
+			encryptedValues[id] = encrypted
+			c.SetValue(section, key, encrypted)
+		}
+	}
+	e.encrypted = encryptedValues
+	var buf bytes.Buffer
+	_, _ = fmt.Fprintln(&buf, "# rclone configuration File with encrypted secrets")
+	_, _ = fmt.Fprintln(&buf, "")
+	buf.Write(e.header())
+	_, _ = fmt.Fprintln(&buf, macPrefix+b64.EncodeToString(e.mac(e.dataKey)))
+	_, _ = fmt.Fprintln(&buf, "")
+	err = goconfig.SaveConfigData(c, &buf)
+	if err != nil {
+		return nil, errors.Errorf("Failed to save config file: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// parseConfigV1 reads the rest of a config encrypted with the V1
+// scheme after the encryptV1Header line, decrypting the values.
+func parseConfigV1(ci *fs.ConfigInfo, r *bufio.Reader) (*goconfig.ConfigFile, error) {
+	e, mac, err := readHeaderV1(r)
+	if err != nil {
+		return nil, err
+	}
+	rest, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	c, err := goconfig.LoadFromReader(bytes.NewBuffer(rest))
+	if err != nil {
+		return nil, err
+	}
+	if err = e.unlock(ci, mac); err != nil {
+		return nil, err
+	}
+	if err = e.decryptValues(c); err != nil {
+		return nil, err
+	}
+	configEncryption = e
+	configKey = nil
+	return c, nil
+}
+
+// EncryptConfig encrypts the secrets in the config so they can be
+// decrypted with the secret key of any of the recipients or the
+// passphrase if set, then saves it.
+//
+// This replaces any existing encryption, including the password
+// based encryption of the whole config file.
+func EncryptConfig(recipients []string, passphrase string) error {
+	if passphrase != "" {
+		var err error
+		passphrase, err = checkPassword(passphrase)
+		if err != nil {
+			return err
+		}
+	}
+	// Make sure the config is loaded before changing the encryption
+	_ = getConfigData()
+	e, err := newEncryptionV1(recipients, passphrase)
+	if err != nil {
+		return err
+	}
+	configEncryption = e
+	configKey = nil
+	SaveConfig()
+	return nil
+}
+
+// RotateConfigKey replaces the key the secrets in the config are
+// encrypted with and saves the config. The new key is wrapped for
+// the same recipients and passphrase as before.
+func RotateConfigKey() error {
+	_ = getConfigData()
+	old := configEncryption
+	if old == nil {
+		return errors.New("config secrets aren't encrypted with keys or a passphrase")
+	}
+	passphrase := old.passphrase
+	if old.hasPassphrase() && passphrase == "" {
+		// Unlocked with a key file so check the passphrase to keep
+		passphrase = GetPassword("Enter configuration passphrase:")
+		if !old.checkPassphrase(passphrase) {
+			return errors.New("incorrect passphrase")
+		}
+	}
+	return EncryptConfig(old.recipients(), passphrase)
+}
+
+// ConfigRecipients returns the public keys the config secrets are
+// encrypted to and whether a passphrase can decrypt them. It returns
+// ok false if the config doesn't use this encryption.
+func ConfigRecipients() (recipients []string, passphrase bool, ok bool) {
+	_ = getConfigData()
+	if configEncryption == nil {
+		return nil, false, false
+	}
+	return configEncryption.recipients(), configEncryption.hasPassphrase(), true
+}
+
+// DecryptConfig removes any encryption from the config and saves it
+func DecryptConfig() {
+	_ = getConfigData()
+	configEncryption = nil
+	configKey = nil
+	SaveConfig()
+}
@@ -0,0 +1,310 @@
+package config
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateKey(t *testing.T) {
+	secretKey, publicKey, err := GenerateKey()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secretKey, SecretKeyPrefix))
+	assert.True(t, strings.HasPrefix(publicKey, PublicKeyPrefix))
+
+	got, err := PublicKey(secretKey)
+	require.NoError(t, err)
+	assert.Equal(t, publicKey, got)
+	got, err = PublicKey(publicKey)
+	require.NoError(t, err)
+	assert.Equal(t, publicKey, got)
+
+	for _, bad := range []string{"", "potato", PublicKeyPrefix + "AAAA", SecretKeyPrefix + "!!"} {
+		_, err = PublicKey(bad)
+		assert.Error(t, err, bad)
+	}
+
+	keys, err := ParseKeys(strings.NewReader("# comment\n\n  " + secretKey + "  \n" + publicKey + "\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{secretKey, publicKey}, keys)
+}
+
+// writeKeyFile writes a new key file into dir returning its path and
+// public key
+func writeKeyFile(t *testing.T, dir, name string) (path, publicKey string) {
+	secretKey, publicKey, err := GenerateKey()
+	require.NoError(t, err)
+	path = filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte("# key\n"+secretKey+"\n"), 0600))
+	return path, publicKey
+}
+
+// reloadConfig forgets the config and its keys and loads it again
+func reloadConfig(t *testing.T) error {
+	configFile = nil
+	configEncryption = nil
+	configKey = nil
+	c, err := loadConfigFile()
+	if err == nil {
+		configFile = c
+	}
+	return err
+}
+
+func TestEncryptConfig(t *testing.T) {
+	defer testConfigFile(t, "encrypt.conf")()
+	ci := fs.GetConfig(context.Background())
+	oldCi := *ci
+	defer func() {
+		*ci = oldCi
+	}()
+	ci.AskPassword = false
+
+	dir, err := ioutil.TempDir("", "rclone-encrypt-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	keyFile1, publicKey1 := writeKeyFile(t, dir, "key1.txt")
+	keyFile2, publicKey2 := writeKeyFile(t, dir, "key2.txt")
+	keyFile3, _ := writeKeyFile(t, dir, "key3.txt")
+
+	getConfigData().SetValue("one", "type", "config_test_remote")
+	getConfigData().SetValue("one", "bool", "true")
+	getConfigData().SetValue("one", "pass", "potato")
+	getConfigData().SetValue("one", "token", `{"access_token":"sausage"}`)
+	getConfigData().SetValue("two", "type", "local")
+	getConfigData().SetValue("two", "secret_access_key", "beans")
+
+	require.NoError(t, EncryptConfig([]string{publicKey1, publicKey2, publicKey1}, ""))
+	b, err := ioutil.ReadFile(ConfigPath)
+	require.NoError(t, err)
+	contents := string(b)
+	assert.Contains(t, contents, encryptV1Header)
+	assert.Equal(t, 2, strings.Count(contents, stanzaPrefix+stanzaX25519))
+	assert.Contains(t, contents, "bool = true")
+	assert.Contains(t, contents, "type = config_test_remote")
+	assert.Equal(t, 3, strings.Count(contents, encryptedPrefix))
+	for _, secret := range []string{"potato", "sausage", "beans"} {
+		assert.NotContains(t, contents, secret)
+	}
+
+	// Saving again without changes leaves the config alone
+	SaveConfig()
+	b, err = ioutil.ReadFile(ConfigPath)
+	require.NoError(t, err)
+	assert.Equal(t, contents, string(b))
+
+	// No key
+	err = reloadConfig(t)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no matching key")
+
+	// Wrong key
+	ci.ConfigKeyFile = []string{keyFile3}
+	assert.Error(t, reloadConfig(t))
+
+	// Either key works
+	for _, keyFile := range []string{keyFile1, keyFile2} {
+		ci.ConfigKeyFile = []string{keyFile3, keyFile}
+		require.NoError(t, reloadConfig(t), keyFile)
+		assert.Equal(t, "potato", FileGet("one", "pass"))
+		assert.Equal(t, `{"access_token":"sausage"}`, FileGet("one", "token"))
+		assert.Equal(t, "beans", FileGet("two", "secret_access_key"))
+		assert.Equal(t, "true", FileGet("one", "bool"))
+	}
+
+	// Key from a command
+	ci.ConfigKeyFile = nil
+	ci.ConfigKeyCommand = fs.SpaceSepList{"cat", keyFile2}
+	require.NoError(t, reloadConfig(t))
+	assert.Equal(t, "potato", FileGet("one", "pass"))
+	ci.ConfigKeyCommand = nil
+
+	// Changing one value only changes its line
+	ci.ConfigKeyFile = []string{keyFile1}
+	getConfigData().SetValue("one", "pass", "carrot")
+	SaveConfig()
+	b, err = ioutil.ReadFile(ConfigPath)
+	require.NoError(t, err)
+	assert.Equal(t, 1, countChangedLines(contents, string(b)))
+	require.NoError(t, reloadConfig(t))
+	assert.Equal(t, "carrot", FileGet("one", "pass"))
+
+	// Rotating changes all the secrets but keeps the recipients
+	require.NoError(t, RotateConfigKey())
+	b, err = ioutil.ReadFile(ConfigPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "carrot")
+	recipients, passphrase, ok := ConfigRecipients()
+	assert.True(t, ok)
+	assert.False(t, passphrase)
+	assert.Equal(t, []string{publicKey1, publicKey2}, recipients)
+	ci.ConfigKeyFile = []string{keyFile2}
+	require.NoError(t, reloadConfig(t))
+	assert.Equal(t, "carrot", FileGet("one", "pass"))
+
+	// Decrypting leaves a plain config
+	DecryptConfig()
+	b, err = ioutil.ReadFile(ConfigPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), encryptV1Header)
+	assert.Contains(t, string(b), "pass = carrot")
+	_, _, ok = ConfigRecipients()
+	assert.False(t, ok)
+}
+
+// countChangedLines counts the lines which differ between a and b
+func countChangedLines(a, b string) (n int) {
+	aLines, bLines := strings.Split(a, "\n"), strings.Split(b, "\n")
+	if len(aLines) != len(bLines) {
+		return -1
+	}
+	for i := range aLines {
+		if aLines[i] != bLines[i] {
+			n++
+		}
+	}
+	return n
+}
+
+func TestEncryptConfigPassphrase(t *testing.T) {
+	defer testConfigFile(t, "encrypt-pass.conf")()
+	ci := fs.GetConfig(context.Background())
+	oldCi := *ci
+	defer func() {
+		*ci = oldCi
+	}()
+	ci.AskPassword = false
+
+	getConfigData().SetValue("one", "type", "config_test_remote")
+	getConfigData().SetValue("one", "pass", "potato")
+	require.NoError(t, EncryptConfig(nil, "asdf"))
+
+	err := reloadConfig(t)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not allowed to ask for password")
+
+	require.NoError(t, os.Setenv("RCLONE_CONFIG_PASS", "wrong"))
+	err = reloadConfig(t)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "incorrect passphrase")
+
+	require.NoError(t, os.Setenv("RCLONE_CONFIG_PASS", "asdf"))
+	require.NoError(t, reloadConfig(t))
+	assert.Equal(t, "potato", FileGet("one", "pass"))
+	require.NoError(t, os.Unsetenv("RCLONE_CONFIG_PASS"))
+
+	// The passphrase is kept when rotating
+	require.NoError(t, RotateConfigKey())
+	ci.PasswordCommand = fs.SpaceSepList{"echo", "asdf"}
+	require.NoError(t, reloadConfig(t))
+	assert.Equal(t, "potato", FileGet("one", "pass"))
+	_, passphrase, _ := ConfigRecipients()
+	assert.True(t, passphrase)
+}
+
+func TestEncryptConfigTampered(t *testing.T) {
+	defer testConfigFile(t, "encrypt-tamper.conf")()
+	ci := fs.GetConfig(context.Background())
+	oldCi := *ci
+	defer func() {
+		*ci = oldCi
+	}()
+	ci.AskPassword = false
+
+	dir, err := ioutil.TempDir("", "rclone-encrypt-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	keyFile1, publicKey1 := writeKeyFile(t, dir, "key1.txt")
+	_, publicKey2 := writeKeyFile(t, dir, "key2.txt")
+	ci.ConfigKeyFile = []string{keyFile1}
+
+	getConfigData().SetValue("one", "type", "config_test_remote")
+	getConfigData().SetValue("one", "pass", "potato")
+	getConfigData().SetValue("two", "type", "config_test_remote")
+	getConfigData().SetValue("two", "pass", "carrot")
+	require.NoError(t, EncryptConfig([]string{publicKey1, publicKey2}, ""))
+	b, err := ioutil.ReadFile(ConfigPath)
+	require.NoError(t, err)
+	contents := string(b)
+	require.NoError(t, reloadConfig(t))
+
+	write := func(contents string) {
+		require.NoError(t, ioutil.WriteFile(ConfigPath, []byte(contents), 0600))
+	}
+
+	// Removing a recipient breaks the MAC
+	lines := strings.Split(contents, "\n")
+	var removed []string
+	for _, line := range lines {
+		if !strings.Contains(line, publicKey2) {
+			removed = append(removed, line)
+		}
+	}
+	write(strings.Join(removed, "\n"))
+	assert.Error(t, reloadConfig(t))
+
+	// Swapping encrypted values between remotes is detected
+	var swapped []string
+	var passLines []int
+	for i, line := range lines {
+		if strings.HasPrefix(line, "pass = ") {
+			passLines = append(passLines, i)
+		}
+		swapped = append(swapped, line)
+	}
+	require.Equal(t, 2, len(passLines))
+	swapped[passLines[0]], swapped[passLines[1]] = swapped[passLines[1]], swapped[passLines[0]]
+	write(strings.Join(swapped, "\n"))
+	err = reloadConfig(t)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to decrypt value")
+
+	// The original still loads
+	write(contents)
+	require.NoError(t, reloadConfig(t))
+	assert.Equal(t, "carrot", FileGet("two", "pass"))
+}
+
+func TestEncryptConfigMigrate(t *testing.T) {
+	defer testConfigFile(t, "encrypt-migrate.conf")()
+	ci := fs.GetConfig(context.Background())
+	oldCi := *ci
+	defer func() {
+		*ci = oldCi
+	}()
+	ci.AskPassword = false
+
+	// Encrypt the whole file the old way
+	getConfigData().SetValue("one", "type", "config_test_remote")
+	getConfigData().SetValue("one", "pass", "potato")
+	require.NoError(t, setConfigPassword("asdf"))
+	SaveConfig()
+	b, err := ioutil.ReadFile(ConfigPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "RCLONE_ENCRYPT_V0:")
+
+	require.NoError(t, os.Setenv("RCLONE_CONFIG_PASS", "asdf"))
+	require.NoError(t, reloadConfig(t))
+	assert.Equal(t, "potato", FileGet("one", "pass"))
+
+	// Switching to the new encryption replaces the old
+	require.NoError(t, EncryptConfig(nil, "asdf"))
+	assert.Nil(t, configKey)
+	b, err = ioutil.ReadFile(ConfigPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "RCLONE_ENCRYPT_V0:")
+	assert.Contains(t, string(b), encryptV1Header)
+	require.NoError(t, reloadConfig(t))
+	assert.Equal(t, "potato", FileGet("one", "pass"))
+}
@@ -0,0 +1,380 @@
+// Storage for the config
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Unknwon/goconfig"
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/lib/file"
+)
+
+// StorageType is the type of storage used for the config, as set
+// by --config-storage. ConfigPath is where it is stored.
+var StorageType = "file"
+
+// Storage is where the config is loaded from and saved to
+type Storage interface {
+	// Load reads the config, returning errorConfigFileNotFound
+	// if there isn't any
+	Load() (*goconfig.ConfigFile, error)
+
+	// Save writes the config
+	Save(c *goconfig.ConfigFile) error
+
+	// Lock stops other rclone processes changing the config until
+	// unlock is called
+	Lock() (unlock func(), err error)
+
+	// String describes where the config is stored
+	String() string
+}
+
+// errorConfigReadOnly is returned by storage which can't be saved
+var errorConfigReadOnly = errors.New("config storage is read only")
+
+// storageTypes are the types of storage which can be used for the
+// config and how to make them from ConfigPath
+var storageTypes = map[string]func(path string) Storage{
+	// an INI file on disk, possibly encrypted
+	"file": func(path string) Storage { return fileStorage(path) },
+	// nothing stored - remotes come from the environment or the rc
+	"memory": func(path string) Storage { return memoryStorage{} },
+	// a read only directory with a file for each remote
+	"dir": func(path string) Storage { return dirStorage(path) },
+	// an INI file, possibly encrypted, on another remote
+	"remote": func(path string) Storage { return remoteStorage(path) },
+}
+
+// StorageTypes returns the names of the types of config storage
+func StorageTypes() (names []string) {
+	for name := range storageTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getStorage returns the Storage for the config
+func getStorage() (Storage, error) {
+	newStorage, ok := storageTypes[StorageType]
+	if !ok {
+		return nil, errors.Errorf("unknown --config-storage %q - must be one of %s", StorageType, strings.Join(StorageTypes(), ", "))
+	}
+	return newStorage(ConfigPath), nil
+}
+
+// IsLocalStorage returns true if ConfigPath is a path on the local
+// disk rather than a remote.
+func IsLocalStorage() bool {
+	return StorageType == "file" || StorageType == "dir"
+}
+
+// lockConfig locks the storage of the config returning a function
+// to unlock it. Failure to lock is logged but otherwise ignored.
+func lockConfig() (unlock func()) {
+	s, err := getStorage()
+	if err != nil {
+		// Loading or saving will report this
+		return func() {}
+	}
+	unlock, err = s.Lock()
+	if err != nil {
+		fs.Errorf(nil, "Failed to lock %v - continuing without: %v", s, err)
+		return func() {}
+	}
+	return unlock
+}
+
+// lockFile takes an exclusive lock on the file at path, creating it
+// and its directory if necessary.
+func lockFile(path string) (unlock func(), err error) {
+	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create lock directory")
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open lock file")
+	}
+	err = file.Lock(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, errors.Wrap(err, "failed to lock")
+	}
+	return func() {
+		if err := file.Unlock(f); err != nil {
+			fs.Errorf(nil, "Failed to unlock config: %v", err)
+		}
+		_ = f.Close()
+	}, nil
+}
+
+// fileStorage is the config stored in an INI file on disk
+type fileStorage string
+
+// Load the config file
+func (s fileStorage) Load() (*goconfig.ConfigFile, error) {
+	b, err := ioutil.ReadFile(string(s))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errorConfigFileNotFound
+		}
+		return nil, err
+	}
+	return parseConfig(b)
+}
+
+// Save the config file, writing it to a temporary file first then
+// renaming it into place.
+func (s fileStorage) Save(c *goconfig.ConfigFile) error {
+	configPath := string(s)
+	dir, name := filepath.Split(configPath)
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return errors.Wrap(err, "failed to create config directory")
+	}
+	f, err := ioutil.TempFile(dir, name)
+	if err != nil {
+		return errors.Errorf("Failed to create temp file for new config: %v", err)
+	}
+	defer func() {
+		if err := os.Remove(f.Name()); err != nil && !os.IsNotExist(err) {
+			fs.Errorf(nil, "Failed to remove temp config file: %v", err)
+		}
+	}()
+
+	b, err := marshalConfig(c)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		return errors.Errorf("Failed to write temp config file: %v", err)
+	}
+
+	_ = f.Sync()
+	err = f.Close()
+	if err != nil {
+		return errors.Errorf("Failed to close config file: %v", err)
+	}
+
+	var fileMode os.FileMode = 0600
+	info, err := os.Stat(configPath)
+	if err != nil {
+		fs.Debugf(nil, "Using default permissions for config file: %v", fileMode)
+	} else if info.Mode() != fileMode {
+		fs.Debugf(nil, "Keeping previous permissions for config file: %v", info.Mode())
+		fileMode = info.Mode()
+	}
+
+	attemptCopyGroup(configPath, f.Name())
+
+	err = os.Chmod(f.Name(), fileMode)
+	if err != nil {
+		fs.Errorf(nil, "Failed to set permissions on config file: %v", err)
+	}
+
+	if err = os.Rename(configPath, configPath+".old"); err != nil && !os.IsNotExist(err) {
+		return errors.Errorf("Failed to move previous config to backup location: %v", err)
+	}
+	if err = os.Rename(f.Name(), configPath); err != nil {
+		return errors.Errorf("Failed to move newly written config from %s to final location: %v", f.Name(), err)
+	}
+	if err := os.Remove(configPath + ".old"); err != nil && !os.IsNotExist(err) {
+		fs.Errorf(nil, "Failed to remove backup config file: %v", err)
+	}
+	return nil
+}
+
+// Lock the config file using a lock file next to it. The config file
+// itself can't be used as it is replaced when saved.
+func (s fileStorage) Lock() (unlock func(), err error) {
+	return lockFile(string(s) + ".lock")
+}
+
+// String describes the storage
+func (s fileStorage) String() string {
+	return fmt.Sprintf("config file %q", string(s))
+}
+
+// memoryStorage is config which is never stored
+type memoryStorage struct{}
+
+// Load returns an empty config
+func (memoryStorage) Load() (*goconfig.ConfigFile, error) {
+	return goconfig.LoadFromReader(&bytes.Buffer{})
+}
+
+// Save does nothing as the config is kept in memory
+func (memoryStorage) Save(c *goconfig.ConfigFile) error {
+	return nil
+}
+
+// Lock does nothing as no other process can see the config
+func (memoryStorage) Lock() (unlock func(), err error) {
+	return func() {}, nil
+}
+
+// String describes the storage
+func (memoryStorage) String() string {
+	return "config in memory"
+}
+
+// dirStorage is a read only directory of config files, one for each
+// remote. Each file is named after the remote with a ".conf" suffix
+// and holds "key = value" lines for that remote only.
+type dirStorage string
+
+// dirStorageSuffix is the suffix of the files read from the directory
+const dirStorageSuffix = ".conf"
+
+// Load the config from the files in the directory
+func (s dirStorage) Load() (*goconfig.ConfigFile, error) {
+	entries, err := ioutil.ReadDir(string(s))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errorConfigFileNotFound
+		}
+		return nil, err
+	}
+	c, _ := goconfig.LoadFromReader(&bytes.Buffer{})
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, dirStorageSuffix) {
+			continue
+		}
+		section := strings.TrimSuffix(name, dirStorageSuffix)
+		if err := fspath.CheckConfigName(section); err != nil {
+			fs.Errorf(nil, "Ignoring config file %q: %v", name, err)
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(string(s), name))
+		if err != nil {
+			return nil, err
+		}
+		remote, err := goconfig.LoadFromReader(bytes.NewBuffer(b))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read config file %q", name)
+		}
+		// Keys outside of any section are in the default section
+		for _, key := range remote.GetKeyList(goconfig.DEFAULT_SECTION) {
+			value, _ := remote.GetValue(goconfig.DEFAULT_SECTION, key)
+			c.SetValue(section, key, value)
+		}
+		if len(c.GetKeyList(section)) == 0 {
+			fs.Errorf(nil, "Ignoring config file %q: no keys found", name)
+		}
+	}
+	return c, nil
+}
+
+// Save returns an error as the directory is read only
+func (s dirStorage) Save(c *goconfig.ConfigFile) error {
+	return errorConfigReadOnly
+}
+
+// Lock does nothing as the config can't be changed
+func (s dirStorage) Lock() (unlock func(), err error) {
+	return func() {}, nil
+}
+
+// String describes the storage
+func (s dirStorage) String() string {
+	return fmt.Sprintf("config directory %q", string(s))
+}
+
+// remoteStorage is the config stored in an INI file on a remote.
+//
+// The remote can't be configured in the config itself, so it needs
+// to be set up with a connection string or environment variables.
+type remoteStorage string
+
+// object returns the Fs and the config file in it. The object is nil
+// if the file doesn't exist.
+func (s remoteStorage) object(ctx context.Context) (f fs.Fs, leaf string, o fs.Object, err error) {
+	parent, leaf, err := fspath.Split(string(s))
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if leaf == "" {
+		return nil, "", nil, errors.Errorf("config path %q must be a file on the remote", string(s))
+	}
+	f, err = fs.NewFs(ctx, parent)
+	if err != nil {
+		return nil, "", nil, errors.Wrap(err, "failed to make remote for config")
+	}
+	o, err = f.NewObject(ctx, leaf)
+	if err == fs.ErrorObjectNotFound {
+		return f, leaf, nil, nil
+	}
+	return f, leaf, o, err
+}
+
+// Load the config file from the remote
+func (s remoteStorage) Load() (*goconfig.ConfigFile, error) {
+	ctx := context.Background()
+	_, _, o, err := s.object(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if o == nil {
+		return nil, errorConfigFileNotFound
+	}
+	in, err := o.Open(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open config")
+	}
+	b, err := ioutil.ReadAll(in)
+	fs.CheckClose(in, &err)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read config")
+	}
+	return parseConfig(b)
+}
+
+// Save the config file to the remote
+func (s remoteStorage) Save(c *goconfig.ConfigFile) error {
+	ctx := context.Background()
+	b, err := marshalConfig(c)
+	if err != nil {
+		return err
+	}
+	f, leaf, o, err := s.object(ctx)
+	if err != nil {
+		return err
+	}
+	src := object.NewStaticObjectInfo(leaf, time.Now(), int64(len(b)), true, nil, f)
+	if o == nil {
+		_, err = f.Put(ctx, bytes.NewReader(b), src)
+	} else {
+		err = o.Update(ctx, bytes.NewReader(b), src)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to write config")
+	}
+	return nil
+}
+
+// Lock the config with a lock file in the cache directory. This only
+// stops rclone processes on this machine clobbering each other.
+func (s remoteStorage) Lock() (unlock func(), err error) {
+	return lockFile(filepath.Join(CacheDir, "config-locks", fmt.Sprintf("%x.lock", md5.Sum([]byte(s)))))
+}
+
+// String describes the storage
+func (s remoteStorage) String() string {
+	return fmt.Sprintf("config file %q on remote", string(s))
+}
@@ -0,0 +1,60 @@
+package config_test
+
+import (
+	"crypto/md5"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const remoteStorageTestName = "configTestNameForRemoteStorage"
+
+// Test the config can be saved to and loaded from a remote. This
+// lives outside the config package as it needs the local backend.
+func TestRemoteStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-storage-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	configPath := filepath.Join(dir, "sub", "rclone.conf")
+	oldStorageType, oldConfigPath, oldCacheDir := config.StorageType, config.ConfigPath, config.CacheDir
+	defer func() {
+		config.StorageType, config.ConfigPath, config.CacheDir = oldStorageType, oldConfigPath, oldCacheDir
+	}()
+	config.StorageType = "remote"
+	config.ConfigPath = ":local:" + configPath
+	config.CacheDir = filepath.Join(dir, "cache")
+
+	_, err = config.FileGetFresh(remoteStorageTestName, "type")
+	assert.EqualError(t, err, "config file not found")
+
+	// Save creates the config file on the remote
+	config.FileSet(remoteStorageTestName, "type", "local")
+	config.SaveConfig()
+	assert.FileExists(t, configPath)
+	lockPath := filepath.Join(config.CacheDir, "config-locks", fmt.Sprintf("%x.lock", md5.Sum([]byte(config.ConfigPath))))
+	assert.FileExists(t, lockPath)
+
+	value, err := config.FileGetFresh(remoteStorageTestName, "type")
+	require.NoError(t, err)
+	assert.Equal(t, "local", value)
+
+	// Save again updates the existing config file
+	config.FileSet(remoteStorageTestName, "test_key", "sausage")
+	config.SaveConfig()
+	value, err = config.FileGetFresh(remoteStorageTestName, "test_key")
+	require.NoError(t, err)
+	assert.Equal(t, "sausage", value)
+
+	config.DeleteRemote(remoteStorageTestName)
+	_, err = config.FileGetFresh(remoteStorageTestName, "type")
+	assert.Error(t, err)
+}
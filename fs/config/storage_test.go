@@ -0,0 +1,102 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Unknwon/goconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStorage(t *testing.T) {
+	oldStorageType := StorageType
+	defer func() {
+		StorageType = oldStorageType
+	}()
+	for _, name := range StorageTypes() {
+		StorageType = name
+		s, err := getStorage()
+		require.NoError(t, err, name)
+		assert.NotNil(t, s, name)
+	}
+	StorageType = "potato"
+	_, err := getStorage()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown --config-storage "potato"`)
+}
+
+func TestFileStorage(t *testing.T) {
+	configKey = nil
+	dir, err := ioutil.TempDir("", "rclone-storage-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	s := fileStorage(filepath.Join(dir, "sub", "rclone.conf"))
+
+	_, err = s.Load()
+	assert.Equal(t, errorConfigFileNotFound, err)
+
+	c, _ := goconfig.LoadFromReader(&bytes.Buffer{})
+	c.SetValue("remote", "type", "local")
+	unlock, err := s.Lock()
+	require.NoError(t, err)
+	require.NoError(t, s.Save(c))
+	unlock()
+	assert.FileExists(t, string(s)+".lock")
+
+	c, err = s.Load()
+	require.NoError(t, err)
+	value, err := c.GetValue("remote", "type")
+	require.NoError(t, err)
+	assert.Equal(t, "local", value)
+}
+
+func TestMemoryStorage(t *testing.T) {
+	s := memoryStorage{}
+	c, err := s.Load()
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, c.GetSectionList())
+	c.SetValue("remote", "type", "local")
+	require.NoError(t, s.Save(c))
+	c, err = s.Load()
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, c.GetSectionList())
+}
+
+func TestDirStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-storage-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	s := dirStorage(dir)
+	for name, contents := range map[string]string{
+		"one.conf":     "type = local\ncopy_links = true\n",
+		"two.conf":     "# comment\ntype = s3\nprovider = AWS\n",
+		"ignored.txt":  "type = local\n",
+		".hidden.conf": "type = local\n",
+		"bad:.conf":    "type = local\n",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0600))
+	}
+
+	c, err := s.Load()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"one", "two"}, c.GetSectionList())
+	value, err := c.GetValue("one", "copy_links")
+	require.NoError(t, err)
+	assert.Equal(t, "true", value)
+	value, err = c.GetValue("two", "provider")
+	require.NoError(t, err)
+	assert.Equal(t, "AWS", value)
+
+	assert.Equal(t, errorConfigReadOnly, s.Save(c))
+
+	_, err = dirStorage(filepath.Join(dir, "notfound")).Load()
+	assert.Equal(t, errorConfigFileNotFound, err)
+}
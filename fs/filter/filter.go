@@ -466,6 +466,19 @@ func (f *Filter) Include(remote string, size int64, modTime time.Time) bool {
 	return f.includeRemote(remote)
 }
 
+// IncludeRemote returns whether remote passes the name based filters
+// and --files-from.
+//
+// Unlike Include it doesn't check the size or age filters, so can be
+// used when the object itself isn't available.
+func (f *Filter) IncludeRemote(remote string) bool {
+	if f.files != nil {
+		_, include := f.files[remote]
+		return include
+	}
+	return f.includeRemote(remote)
+}
+
 // IncludeObject returns whether this object should be included into
 // the sync or not. This is a convenience function to avoid calling
 // o.ModTime(), which is an expensive operation.
@@ -49,6 +49,7 @@ var (
 	ErrorNotFoundInConfigFile        = errors.New("didn't find section in config file")
 	ErrorCantPurge                   = errors.New("can't purge directory")
 	ErrorCantCopy                    = errors.New("can't copy object - incompatible remotes")
+	ErrorCantLink                    = errors.New("can't link object - incompatible remotes")
 	ErrorCantMove                    = errors.New("can't move object - incompatible remotes")
 	ErrorCantDirMove                 = errors.New("can't move directory - incompatible remotes")
 	ErrorCantUploadEmptyFiles        = errors.New("can't upload empty files to this remote")
@@ -92,6 +93,8 @@ type RegInfo struct {
 	Options Options
 	// The command help, if any
 	CommandHelp []CommandHelp
+	// Help about the metadata this backend supports, if any
+	MetadataInfo *MetadataInfo
 }
 
 // FileName returns the on disk file name for this backend
@@ -425,6 +428,7 @@ type FullObjectInfo interface {
 	IDer
 	ObjectUnWrapper
 	GetTierer
+	Metadataer
 }
 
 // FullObject contains all the optional interfaces for Object
@@ -437,6 +441,7 @@ type FullObject interface {
 	ObjectUnWrapper
 	GetTierer
 	SetTierer
+	Metadataer
 }
 
 // ObjectOptionalInterfaces returns the names of supported and
@@ -465,6 +470,12 @@ func ObjectOptionalInterfaces(o Object) (supported, unsupported []string) {
 	_, ok = o.(GetTierer)
 	store(ok, "GetTier")
 
+	_, ok = o.(Metadataer)
+	store(ok, "Metadata")
+
+	_, ok = o.(SetMetadataer)
+	store(ok, "SetMetadata")
+
 	return supported, unsupported
 }
 
@@ -518,6 +529,9 @@ type Features struct {
 	IsLocal                 bool // is the local backend
 	SlowModTime             bool // if calling ModTime() generally takes an extra transaction
 	SlowHash                bool // if calling Hash() generally takes an extra transaction
+	ReadMetadata            bool // can read metadata from objects
+	WriteMetadata           bool // can write metadata to objects
+	UserMetadata            bool // can read/write general purpose metadata
 
 	// Purge all files in the directory specified
 	//
@@ -538,6 +552,17 @@ type Features struct {
 	// If it isn't possible then return fs.ErrorCantCopy
 	Copy func(ctx context.Context, src Object, remote string) (Object, error)
 
+	// Link src to this remote as a hard link.
+	//
+	// This is stored with the remote path given
+	//
+	// It returns the destination Object and a possible error
+	//
+	// Will only be called if src.Fs().Name() == f.Name()
+	//
+	// If it isn't possible then return fs.ErrorCantLink
+	Link func(ctx context.Context, src Object, remote string) (Object, error)
+
 	// Move src to this remote using server-side move operations.
 	//
 	// This is stored with the remote path given
@@ -727,6 +752,9 @@ func (ft *Features) Fill(ctx context.Context, f Fs) *Features {
 	if do, ok := f.(Copier); ok {
 		ft.Copy = do.Copy
 	}
+	if do, ok := f.(Linker); ok {
+		ft.Link = do.Link
+	}
 	if do, ok := f.(Mover); ok {
 		ft.Move = do.Move
 	}
@@ -806,6 +834,9 @@ func (ft *Features) Mask(ctx context.Context, f Fs) *Features {
 	// ft.IsLocal = ft.IsLocal && mask.IsLocal Don't propagate IsLocal
 	ft.SlowModTime = ft.SlowModTime && mask.SlowModTime
 	ft.SlowHash = ft.SlowHash && mask.SlowHash
+	ft.ReadMetadata = ft.ReadMetadata && mask.ReadMetadata
+	ft.WriteMetadata = ft.WriteMetadata && mask.WriteMetadata
+	ft.UserMetadata = ft.UserMetadata && mask.UserMetadata
 
 	if mask.Purge == nil {
 		ft.Purge = nil
@@ -813,6 +844,9 @@ func (ft *Features) Mask(ctx context.Context, f Fs) *Features {
 	if mask.Copy == nil {
 		ft.Copy = nil
 	}
+	if mask.Link == nil {
+		ft.Link = nil
+	}
 	if mask.Move == nil {
 		ft.Move = nil
 	}
@@ -917,6 +951,20 @@ type Copier interface {
 	Copy(ctx context.Context, src Object, remote string) (Object, error)
 }
 
+// Linker is an optional interface for Fs
+type Linker interface {
+	// Link src to this remote as a hard link.
+	//
+	// This is stored with the remote path given
+	//
+	// It returns the destination Object and a possible error
+	//
+	// Will only be called if src.Fs().Name() == f.Name()
+	//
+	// If it isn't possible then return fs.ErrorCantLink
+	Link(ctx context.Context, src Object, remote string) (Object, error)
+}
+
 // Mover is an optional interface for Fs
 type Mover interface {
 	// Move src to this remote using server-side move operations.
@@ -1199,18 +1247,26 @@ func MustFind(name string) *RegInfo {
 
 // ParseRemote deconstructs a path into configName, fsPath, looking up
 // the fsName in the config file (returning NotFoundInConfigFile if not found)
+//
+// Any parameters overriding the config of the remote are left on
+// configName in canonical form for ConfigMap to read, so
+// "remote,b=2,a=1:path" returns "remote,a=1,b=2" as the configName.
 func ParseRemote(path string) (fsInfo *RegInfo, configName, fsPath string, err error) {
 	configName, fsPath, err = fspath.Parse(path)
 	if err != nil {
 		return nil, "", "", err
 	}
+	name, params, err := fspath.SplitConfigName(configName)
+	if err != nil {
+		return nil, "", "", err
+	}
 	var fsName string
 	var ok bool
-	if configName != "" {
-		if strings.HasPrefix(configName, ":") {
-			fsName = configName[1:]
+	if name != "" {
+		if strings.HasPrefix(name, ":") {
+			fsName = name[1:]
 		} else {
-			m := ConfigMap(nil, configName)
+			m := ConfigMap(nil, name)
 			fsName, ok = m.Get("type")
 			if !ok {
 				return nil, "", "", ErrorNotFoundInConfigFile
@@ -1218,10 +1274,10 @@ func ParseRemote(path string) (fsInfo *RegInfo, configName, fsPath string, err e
 		}
 	} else {
 		fsName = "local"
-		configName = "local"
+		name = "local"
 	}
 	fsInfo, err = Find(fsName)
-	return fsInfo, configName, fsPath, err
+	return fsInfo, fspath.JoinConfigName(name, params), fsPath, err
 }
 
 // A configmap.Getter to read from the environment RCLONE_CONFIG_backend_option_name
@@ -1300,19 +1356,33 @@ func (section getConfigFile) Get(key string) (value string, ok bool) {
 //
 // If fsInfo is nil then the returned configmap.Map should only be
 // used for reading non backend specific parameters, such as "type".
+//
+// If configName has parameters, eg "remote,chunk_size=64M", these
+// override the config for remote.
 func ConfigMap(fsInfo *RegInfo, configName string) (config *configmap.Map) {
+	// Split off the parameters overriding the config
+	name, params, err := fspath.SplitConfigName(configName)
+	if err != nil {
+		name, params = configName, nil
+	}
+
 	// Create the config
 	config = configmap.New()
 
 	// Read the config, more specific to least specific
 
+	// parameters in the config name
+	if len(params) > 0 {
+		config.AddGetter(params)
+	}
+
 	// flag values
 	if fsInfo != nil {
 		config.AddGetter(&regInfoValues{fsInfo, false})
 	}
 
 	// remote specific environment vars
-	config.AddGetter(configEnvVars(configName))
+	config.AddGetter(configEnvVars(name))
 
 	// backend specific environment vars
 	if fsInfo != nil {
@@ -1320,7 +1390,7 @@ func ConfigMap(fsInfo *RegInfo, configName string) (config *configmap.Map) {
 	}
 
 	// config file
-	config.AddGetter(getConfigFile(configName))
+	config.AddGetter(getConfigFile(name))
 
 	// default values
 	if fsInfo != nil {
@@ -1328,7 +1398,11 @@ func ConfigMap(fsInfo *RegInfo, configName string) (config *configmap.Map) {
 	}
 
 	// Set Config
-	config.AddSetter(setConfigFile(configName))
+	if len(params) > 0 {
+		// so the new value overrides the parameter
+		config.AddSetter(params)
+	}
+	config.AddSetter(setConfigFile(name))
 	return config
 }
 
@@ -1355,6 +1429,9 @@ func ConfigFs(path string) (fsInfo *RegInfo, configName, fsPath string, config *
 // Remotes are looked up in the config file.  If the remote isn't
 // found then NotFoundInConfigFile will be returned.
 //
+// The backend is passed a context with any global options set in the
+// config of the remote applied - see AddRemoteConfig.
+//
 // On Windows avoid single character remote names as they can be mixed
 // up with drive letters.
 func NewFs(ctx context.Context, path string) (Fs, error) {
@@ -1363,6 +1440,12 @@ func NewFs(ctx context.Context, path string) (Fs, error) {
 	if err != nil {
 		return nil, err
 	}
+	rc, err := parseRemoteConfig(fsInfo, config)
+	if err != nil {
+		return nil, err
+	}
+	setRemoteConfig(configName, rc)
+	ctx = addRemoteConfig(ctx, configName, rc)
 	return fsInfo.NewFs(ctx, configName, fsPath, config)
 }
 
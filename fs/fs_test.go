@@ -342,3 +342,43 @@ func TestOptionGetters(t *testing.T) {
 	}
 
 }
+
+func TestConfigMapParams(t *testing.T) {
+	fsInfo := &RegInfo{
+		Name:    "local",
+		Prefix:  "local",
+		Options: testOptions,
+	}
+
+	oldConfigFileGet := ConfigFileGet
+	ConfigFileGet = func(section, key string) (string, bool) {
+		if section == "sausage" && (key == "key1" || key == "nounc") {
+			return "value1", true
+		}
+		return "", false
+	}
+	defer func() {
+		ConfigFileGet = oldConfigFileGet
+	}()
+
+	for i, test := range []struct {
+		configName string
+		key        string
+		wantValue  string
+		wantOk     bool
+	}{
+		{"sausage", "key1", "value1", true},
+		{"sausage", "nounc", "value1", true},
+		{"sausage,nounc=param", "key1", "value1", true},
+		{"sausage,nounc=param", "nounc", "param", true},
+		{"sausage,copy_links", "copy_links", "true", true},
+		{"sausage,copy_links", "not_found", "", false},
+		{"potato,nounc=param", "key1", "", false},
+		{"potato,nounc=param", "nounc", "param", true},
+	} {
+		what := fmt.Sprintf("%d: %q: %q", i, test.configName, test.key)
+		gotValue, gotOk := ConfigMap(fsInfo, test.configName).Get(test.key)
+		assert.Equal(t, test.wantValue, gotValue, what)
+		assert.Equal(t, test.wantOk, gotOk, what)
+	}
+}
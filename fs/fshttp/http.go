@@ -33,16 +33,38 @@ var (
 	cookieJar, _ = cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
 )
 
+// Transports and limiters for the remotes which override the
+// remoteTransportOptions by the name of the remote, so all the
+// clients a remote makes share them
+var (
+	remoteTransportsMu sync.Mutex
+	remoteTransports   = map[string]http.RoundTripper{}
+	remoteTPSBuckets   = map[string]*rate.Limiter{}
+)
+
+// remoteTransportOptions are the options which a remote can override
+// in its config which need a transport of its own
+var remoteTransportOptions = []string{"timeout", "contimeout", "header", "tpslimit", "tpslimit_burst"}
+
+// newTPSBucket makes a limiter for the transactions per second set in
+// ci or returns nil if there is no limit
+func newTPSBucket(ci *fs.ConfigInfo) *rate.Limiter {
+	if ci.TPSLimit <= 0 {
+		return nil
+	}
+	tpsBurst := ci.TPSLimitBurst
+	if tpsBurst < 1 {
+		tpsBurst = 1
+	}
+	return rate.NewLimiter(rate.Limit(ci.TPSLimit), tpsBurst)
+}
+
 // StartHTTPTokenBucket starts the token bucket if necessary
 func StartHTTPTokenBucket(ctx context.Context) {
 	ci := fs.GetConfig(ctx)
-	if ci.TPSLimit > 0 {
-		tpsBurst := ci.TPSLimitBurst
-		if tpsBurst < 1 {
-			tpsBurst = 1
-		}
-		tpsBucket = rate.NewLimiter(rate.Limit(ci.TPSLimit), tpsBurst)
-		fs.Infof(nil, "Starting HTTP transaction limiter: max %g transactions/s with burst %d", ci.TPSLimit, tpsBurst)
+	tpsBucket = newTPSBucket(ci)
+	if tpsBucket != nil {
+		fs.Infof(nil, "Starting HTTP transaction limiter: max %g transactions/s with burst %d", ci.TPSLimit, tpsBucket.Burst())
 	}
 }
 
@@ -107,6 +129,10 @@ func dialContextTimeout(ctx context.Context, network, address string, ci *fs.Con
 // Should only be used for testing.
 func ResetTransport() {
 	noTransport = new(sync.Once)
+	remoteTransportsMu.Lock()
+	remoteTransports = map[string]http.RoundTripper{}
+	remoteTPSBuckets = map[string]*rate.Limiter{}
+	remoteTransportsMu.Unlock()
 }
 
 // NewTransportCustom returns an http.RoundTripper with the correct timeouts.
@@ -176,11 +202,54 @@ func NewTransportCustom(ctx context.Context, customize func(*http.Transport)) ht
 	}
 
 	// Wrap that http.Transport in our own transport
-	return newTransport(ci, t)
+	newT := newTransport(ci, t)
+	if fs.IsOverridden(ctx, "tpslimit") || fs.IsOverridden(ctx, "tpslimit_burst") {
+		newT.tpsBucket = remoteTPSBucket(ctx, ci)
+		newT.ownTPSBucket = true
+	}
+	return newT
+}
+
+// remoteTPSBucket returns the limiter for the transactions per second
+// set in ci for the remote in ctx, making it if necessary
+func remoteTPSBucket(ctx context.Context, ci *fs.ConfigInfo) *rate.Limiter {
+	remote := fs.OverridingRemote(ctx)
+	remoteTransportsMu.Lock()
+	defer remoteTransportsMu.Unlock()
+	bucket, ok := remoteTPSBuckets[remote]
+	if !ok {
+		bucket = newTPSBucket(ci)
+		remoteTPSBuckets[remote] = bucket
+	}
+	return bucket
 }
 
 // NewTransport returns an http.RoundTripper with the correct timeouts
+//
+// The transport is shared unless the config of the remote in ctx
+// overrides the timeouts, headers or transactions per second limit,
+// in which case it is shared by the users of that remote.
 func NewTransport(ctx context.Context) http.RoundTripper {
+	for _, name := range remoteTransportOptions {
+		if fs.IsOverridden(ctx, name) {
+			remote := fs.OverridingRemote(ctx)
+			remoteTransportsMu.Lock()
+			t, ok := remoteTransports[remote]
+			remoteTransportsMu.Unlock()
+			if ok {
+				return t
+			}
+			t = NewTransportCustom(ctx, nil)
+			remoteTransportsMu.Lock()
+			defer remoteTransportsMu.Unlock()
+			// Use the one made by someone else in the meantime
+			if oldT, ok := remoteTransports[remote]; ok {
+				return oldT
+			}
+			remoteTransports[remote] = t
+			return t
+		}
+	}
 	(*noTransport).Do(func() {
 		transport = NewTransportCustom(ctx, nil)
 	})
@@ -208,6 +277,8 @@ type Transport struct {
 	filterRequest func(req *http.Request)
 	userAgent     string
 	headers       []*fs.HTTPOption
+	tpsBucket     *rate.Limiter // limiter for this transport if ownTPSBucket is set
+	ownTPSBucket  bool          // set to use tpsBucket rather than the global one
 }
 
 // newTransport wraps the http.Transport passed in and logs all
@@ -309,8 +380,12 @@ func cleanAuths(buf []byte) []byte {
 // RoundTrip implements the RoundTripper interface.
 func (t *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
 	// Get transactions per second token first if limiting
-	if tpsBucket != nil {
-		tbErr := tpsBucket.Wait(req.Context())
+	bucket := tpsBucket
+	if t.ownTPSBucket {
+		bucket = t.tpsBucket
+	}
+	if bucket != nil {
+		tbErr := bucket.Wait(req.Context())
 		if tbErr != nil && tbErr != context.Canceled {
 			fs.Errorf(nil, "HTTP token bucket error: %v", tbErr)
 		}
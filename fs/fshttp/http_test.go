@@ -1,9 +1,15 @@
 package fshttp
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
 )
 
 func TestCleanAuth(t *testing.T) {
@@ -45,3 +51,46 @@ func TestCleanAuths(t *testing.T) {
 		assert.Equal(t, test.want, got, test.in)
 	}
 }
+
+func TestNewTransportRemoteConfig(t *testing.T) {
+	ctx := context.Background()
+	shared := NewTransport(ctx)
+	defer ResetTransport()
+
+	// Overriding options which don't affect the transport shares it
+	remoteCtx, err := fs.AddRemoteConfig(ctx, "transfers", nil, configmap.Simple{"transfers": "2"})
+	require.NoError(t, err)
+	assert.Equal(t, shared, NewTransport(remoteCtx))
+
+	// Overriding the timeout makes a transport of its own
+	remoteCtx, err = fs.AddRemoteConfig(ctx, "timeout", nil, configmap.Simple{"timeout": "7s"})
+	require.NoError(t, err)
+	tr := NewTransport(remoteCtx).(*Transport)
+	assert.NotEqual(t, shared, tr)
+	assert.Equal(t, 7*time.Second, tr.ResponseHeaderTimeout)
+	assert.False(t, tr.ownTPSBucket)
+
+	// Which is shared by all the users of the remote
+	assert.True(t, tr == NewTransport(remoteCtx))
+
+	// Overriding the tpslimit gives it its own limiter
+	remoteCtx, err = fs.AddRemoteConfig(ctx, "tpslimit", nil, configmap.Simple{"tpslimit": "5", "tpslimit_burst": "2"})
+	require.NoError(t, err)
+	tr = NewTransport(remoteCtx).(*Transport)
+	assert.True(t, tr.ownTPSBucket)
+	require.NotNil(t, tr.tpsBucket)
+	assert.Equal(t, rate.Limit(5), tr.tpsBucket.Limit())
+	assert.Equal(t, 2, tr.tpsBucket.Burst())
+
+	// Which is shared with custom transports of the remote
+	custom := NewTransportCustom(remoteCtx, nil).(*Transport)
+	assert.True(t, custom != tr)
+	assert.True(t, custom.tpsBucket == tr.tpsBucket)
+
+	// Unless it turns it off
+	remoteCtx, err = fs.AddRemoteConfig(ctx, "notpslimit", nil, configmap.Simple{"tpslimit": "0"})
+	require.NoError(t, err)
+	tr = NewTransport(remoteCtx).(*Transport)
+	assert.True(t, tr.ownTPSBucket)
+	assert.Nil(t, tr.tpsBucket)
+}
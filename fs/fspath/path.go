@@ -6,8 +6,10 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
+	"github.com/rclone/rclone/fs/config/configmap"
 	"github.com/rclone/rclone/fs/driveletter"
 )
 
@@ -20,6 +22,9 @@ var (
 	errInvalidCharacters = errors.New("config name contains invalid characters - may only contain 0-9, A-Z ,a-z ,_ , - and space")
 	errCantBeEmpty       = errors.New("can't use empty string as a path")
 	errCantStartWithDash = errors.New("config name starts with -")
+	errEmptyParameter    = errors.New("config parameter has no name")
+	errUnterminatedQuote = errors.New("config parameter value has an unterminated quote")
+	errBadQuotedValue    = errors.New("config parameter value must be followed by , or : after the closing quote")
 
 	// urlMatcher is a pattern to match an rclone URL
 	// note that this matches invalid remoteNames
@@ -30,6 +35,10 @@ var (
 
 	// remoteNameMatcher is a pattern to match an rclone remote name
 	remoteNameMatcher = regexp.MustCompile(remoteNameRe + `$`)
+
+	// paramsMatcher is a pattern to match the start of a remote name
+	// which has parameters
+	paramsMatcher = regexp.MustCompile(`^:?[^\\/:,]*,`)
 )
 
 // CheckConfigName returns an error if configName is invalid
@@ -59,6 +68,13 @@ func CheckRemoteName(remoteName string) error {
 // So "remote:path/to/dir" will return "remote", "path/to/dir"
 // and "/path/to/local" will return ("", "/path/to/local")
 //
+// The remote name may be followed by parameters which override its
+// config, which are returned as part of configName - use
+// SplitConfigName to separate them.
+//
+// So "remote,chunk_size=64M:path" will return
+// "remote,chunk_size=64M", "path"
+//
 // Note that this will turn \ into / in the fsPath on Windows
 //
 // An error may be returned if the remote name has invalid characters
@@ -67,9 +83,20 @@ func Parse(path string) (configName, fsPath string, err error) {
 	if path == "" {
 		return "", "", errCantBeEmpty
 	}
-	parts := urlMatcher.FindStringSubmatch(path)
 	configName, fsPath = "", path
-	if parts != nil && !driveletter.IsDriveLetter(parts[1]) {
+	if paramsMatcher.MatchString(path) {
+		name, _, end, err := parseConfigName(path)
+		if err != nil {
+			return "", path, err
+		}
+		if end >= 0 && end < len(path) {
+			configName, fsPath = path[:end], path[end+1:]
+			err = CheckRemoteName(name + ":")
+			if err != nil {
+				return configName, fsPath, errInvalidCharacters
+			}
+		}
+	} else if parts := urlMatcher.FindStringSubmatch(path); parts != nil && !driveletter.IsDriveLetter(parts[1]) {
 		configName, fsPath = parts[1], parts[2]
 		err = CheckRemoteName(configName + ":")
 		if err != nil {
@@ -81,6 +108,143 @@ func Parse(path string) (configName, fsPath string, err error) {
 	return configName, fsPath, nil
 }
 
+// SplitConfigName splits configName, as returned by Parse, into the
+// name of the remote and the parameters overriding its config, which
+// will be empty if there aren't any.
+//
+// So "remote,chunk_size=64M" will return "remote",
+// {"chunk_size": "64M"}
+//
+// Parameters are separated by "," and a parameter without a value is
+// set to "true". Values may be quoted with " or ' and must be if they
+// contain "," or ":" (apart from "://" in a URL). The quote is
+// doubled to include it in a quoted value.
+func SplitConfigName(configName string) (name string, params configmap.Simple, err error) {
+	name, params, end, err := parseConfigName(configName)
+	if err != nil {
+		return "", nil, err
+	}
+	if end != len(configName) {
+		return "", nil, errInvalidCharacters
+	}
+	return name, params, nil
+}
+
+// JoinConfigName makes a config name out of name and params which is
+// the reverse of SplitConfigName.
+//
+// The parameters are sorted and only quoted if necessary so the same
+// name and params always make the same config name.
+func JoinConfigName(name string, params configmap.Simple) string {
+	if len(params) == 0 {
+		return name
+	}
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var out strings.Builder
+	out.WriteString(name)
+	for _, key := range keys {
+		value := params[key]
+		out.WriteString(",")
+		out.WriteString(key)
+		if value == "true" {
+			continue
+		}
+		out.WriteString("=")
+		if value == "" || strings.ContainsAny(value, `,:"'`) {
+			value = `"` + strings.Replace(value, `"`, `""`, -1) + `"`
+		}
+		out.WriteString(value)
+	}
+	return out.String()
+}
+
+// parseConfigName parses the remote name and parameters at the start
+// of s up to the first ":" not in a parameter value.
+//
+// It returns the index of the ":" or len(s) if there isn't one, or -1
+// if s doesn't start with a remote name as a parameter name contains
+// a path separator.
+func parseConfigName(s string) (name string, params configmap.Simple, end int, err error) {
+	start := 0
+	if strings.HasPrefix(s, ":") {
+		// the leading ":" of an on the fly backend is part of the name
+		start = 1
+	}
+	end = start + strings.IndexAny(s[start:], ",:")
+	if end < start {
+		end = len(s)
+	}
+	name = s[:end]
+	params = configmap.Simple{}
+	for end < len(s) && s[end] == ',' {
+		i := end + 1
+		end = i + strings.IndexAny(s[i:], "=,:/\\")
+		if end < i {
+			end = len(s)
+		} else if s[end] == '/' || s[end] == '\\' {
+			return "", nil, -1, nil
+		}
+		key := s[i:end]
+		if key == "" {
+			return "", nil, 0, errEmptyParameter
+		}
+		value := "true"
+		if end < len(s) && s[end] == '=' {
+			value, end, err = parseValue(s, end+1)
+			if err != nil {
+				return "", nil, 0, err
+			}
+		}
+		params[key] = value
+	}
+	return name, params, end, nil
+}
+
+// parseValue parses the parameter value in s starting at i returning
+// it and the index of the first character after it.
+func parseValue(s string, i int) (value string, end int, err error) {
+	if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+		quote := s[i]
+		var out strings.Builder
+		i++
+		for {
+			j := strings.IndexByte(s[i:], quote)
+			if j < 0 {
+				return "", 0, errUnterminatedQuote
+			}
+			out.WriteString(s[i : i+j])
+			i += j + 1
+			if i < len(s) && s[i] == quote {
+				// doubled quote
+				out.WriteByte(quote)
+				i++
+				continue
+			}
+			if i < len(s) && s[i] != ',' && s[i] != ':' {
+				return "", 0, errBadQuotedValue
+			}
+			return out.String(), i, nil
+		}
+	}
+	end = i
+	for end < len(s) && s[end] != ',' {
+		if s[end] == ':' {
+			if !strings.HasPrefix(s[end:], "://") {
+				break
+			}
+			// allow URLs without quoting
+			end += len("://")
+			continue
+		}
+		end++
+	}
+	return s[i:end], end, nil
+}
+
 // Split splits a remote into a parent and a leaf
 //
 // if it returns leaf as an empty string then remote is a directory
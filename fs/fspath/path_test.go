@@ -7,7 +7,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/rclone/rclone/fs/config/configmap"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCheckConfigName(t *testing.T) {
@@ -79,6 +81,17 @@ func TestParse(t *testing.T) {
 		{"rem.ote:/path/to/file", "rem.ote", "/path/to/file", errInvalidCharacters},
 		{":backend:/path/to/file", ":backend", "/path/to/file", nil},
 		{":bac*kend:/path/to/file", ":bac*kend", "/path/to/file", errInvalidCharacters},
+		{"remote,chunk_size=64M:bucket", "remote,chunk_size=64M", "bucket", nil},
+		{"remote,flag:bucket", "remote,flag", "bucket", nil},
+		{":s3,provider=Minio,endpoint=http://x:bucket", ":s3,provider=Minio,endpoint=http://x", "bucket", nil},
+		{`:s3,endpoint="http://x:9000":bucket:colon`, `:s3,endpoint="http://x:9000"`, "bucket:colon", nil},
+		{`remote,a='it''s':`, `remote,a='it''s'`, "", nil},
+		{"rem*ote,a=b:path", "rem*ote,a=b", "path", errInvalidCharacters},
+		{"remote,=b:path", "", "remote,=b:path", errEmptyParameter},
+		{`remote,a="b:path`, "", `remote,a="b:path`, errUnterminatedQuote},
+		{`remote,a="b"c:path`, "", `remote,a="b"c:path`, errBadQuotedValue},
+		{"path,with,commas", "", "path,with,commas", nil},
+		{"path,with/slash:colon", "", "path,with/slash:colon", nil},
 	} {
 		gotConfigName, gotFsPath, gotErr := Parse(test.in)
 		if runtime.GOOS == "windows" {
@@ -90,6 +103,55 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestSplitConfigName(t *testing.T) {
+	for _, test := range []struct {
+		in         string
+		wantName   string
+		wantParams configmap.Simple
+		wantErr    error
+	}{
+		{"", "", configmap.Simple{}, nil},
+		{"remote", "remote", configmap.Simple{}, nil},
+		{":s3", ":s3", configmap.Simple{}, nil},
+		{"remote,chunk_size=64M", "remote", configmap.Simple{"chunk_size": "64M"}, nil},
+		{":s3,provider=Minio,endpoint=http://x", ":s3", configmap.Simple{"provider": "Minio", "endpoint": "http://x"}, nil},
+		{`remote,a="x,y:z",b='it''s',c=""`, "remote", configmap.Simple{"a": "x,y:z", "b": "it's", "c": ""}, nil},
+		{"remote,flag,empty=", "remote", configmap.Simple{"flag": "true", "empty": ""}, nil},
+		{"remote,a=b:path", "", nil, errInvalidCharacters},
+		{"remote,", "", nil, errEmptyParameter},
+		{`remote,a="b`, "", nil, errUnterminatedQuote},
+	} {
+		gotName, gotParams, gotErr := SplitConfigName(test.in)
+		assert.Equal(t, test.wantErr, gotErr, test.in)
+		assert.Equal(t, test.wantName, gotName, test.in)
+		assert.Equal(t, test.wantParams, gotParams, test.in)
+	}
+}
+
+func TestJoinConfigName(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		params configmap.Simple
+		want   string
+	}{
+		{"remote", nil, "remote"},
+		{"remote", configmap.Simple{}, "remote"},
+		{"remote", configmap.Simple{"b": "2", "a": "1"}, "remote,a=1,b=2"},
+		{":s3", configmap.Simple{"endpoint": "http://x"}, `:s3,endpoint="http://x"`},
+		{"remote", configmap.Simple{"flag": "true", "empty": "", "quote": `"x"`}, `remote,empty="",flag,quote="""x"""`},
+	} {
+		got := JoinConfigName(test.name, test.params)
+		assert.Equal(t, test.want, got)
+		// Check it round trips
+		gotName, gotParams, err := SplitConfigName(got)
+		require.NoError(t, err)
+		assert.Equal(t, test.name, gotName)
+		if len(test.params) > 0 {
+			assert.Equal(t, test.params, gotParams)
+		}
+	}
+}
+
 func TestSplit(t *testing.T) {
 	for _, test := range []struct {
 		remote, wantParent, wantLeaf string
@@ -113,6 +175,9 @@ func TestSplit(t *testing.T) {
 		{":remote:potato/sausage", ":remote:potato/", "sausage", nil},
 		{":rem[ote:potato/sausage", "", "", errInvalidCharacters},
 
+		{"remote,a=b:potato/sausage", "remote,a=b:potato/", "sausage", nil},
+		{`:remote,a="http://x:80":potato/sausage`, `:remote,a="http://x:80":potato/`, "sausage", nil},
+
 		{"/", "/", "", nil},
 		{"/root", "/", "root", nil},
 		{"/a/b", "/a/", "b", nil},
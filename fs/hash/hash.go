@@ -3,6 +3,7 @@ package hash
 import (
 	"crypto/md5"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"hash"
@@ -10,8 +11,10 @@ import (
 	"io"
 	"strings"
 
+	"github.com/cespare/xxhash/v2"
 	"github.com/jzelinskie/whirlpool"
 	"github.com/pkg/errors"
+	"github.com/zeebo/blake3"
 )
 
 // Type indicates a standard hashing algorithm
@@ -60,13 +63,32 @@ var (
 
 	// CRC32 indicates CRC-32 support
 	CRC32 Type
+
+	// SHA256 indicates SHA-256 support
+	SHA256 Type
+
+	// BLAKE3 indicates BLAKE3 support
+	BLAKE3 Type
+
+	// XXH64 indicates xxHash64 support
+	XXH64 Type
+
+	// CRC32C indicates CRC-32C (Castagnoli) support
+	CRC32C Type
 )
 
+// crc32cTable is the Castagnoli polynomial table used by CRC-32C
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
 func init() {
 	MD5 = RegisterHash("MD5", 32, md5.New)
 	SHA1 = RegisterHash("SHA-1", 40, sha1.New)
 	Whirlpool = RegisterHash("Whirlpool", 128, whirlpool.New)
 	CRC32 = RegisterHash("CRC-32", 8, func() hash.Hash { return crc32.NewIEEE() })
+	SHA256 = RegisterHash("SHA-256", 64, sha256.New)
+	BLAKE3 = RegisterHash("BLAKE3", 64, func() hash.Hash { return blake3.New() })
+	XXH64 = RegisterHash("XXH64", 16, func() hash.Hash { return xxhash.New() })
+	CRC32C = RegisterHash("CRC-32C", 8, func() hash.Hash { return crc32.New(crc32cTable) })
 }
 
 // Supported returns a set of all the supported hashes by
@@ -138,7 +160,7 @@ func (h *Type) Set(s string) error {
 	}
 
 	for _, v := range hashes {
-		if v.name == s {
+		if strings.EqualFold(v.name, s) {
 			*h = v.hashType
 			return nil
 		}
@@ -75,6 +75,10 @@ var hashTestSet = []hashTest{
 			hash.SHA1:      "3ab6543c08a75f292a5ecedac87ec41642d12166",
 			hash.Whirlpool: "eddf52133d4566d763f716e853d6e4efbabd29e2c2e63f56747b1596172851d34c2df9944beb6640dbdbe3d9b4eb61180720a79e3d15baff31c91e43d63869a4",
 			hash.CRC32:     "a6041d7e",
+			hash.SHA256:    "c839e57675862af5c21bd0a15413c3ec579e0d5522dab600bc6c3489b05b8f54",
+			hash.BLAKE3:    "0a7276a407a3be1b4d31488318ee05a335aad5a3b82c4420e592a8178c9e86bb",
+			hash.XXH64:     "13cd7ced0c4af679",
+			hash.CRC32C:    "4d8ae017",
 		},
 	},
 	// Empty data set
@@ -85,6 +89,10 @@ var hashTestSet = []hashTest{
 			hash.SHA1:      "da39a3ee5e6b4b0d3255bfef95601890afd80709",
 			hash.Whirlpool: "19fa61d75522a4669b44e39c1d2e1726c530232130d407f89afee0964997f7a73e83be698b288febcf88e3e03c4f0757ea8964e59b63d93708b138cc42a66eb3",
 			hash.CRC32:     "00000000",
+			hash.SHA256:    "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			hash.BLAKE3:    "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262",
+			hash.XXH64:     "ef46db3751d8e999",
+			hash.CRC32C:    "00000000",
 		},
 	},
 }
@@ -169,3 +177,22 @@ func TestHashStringer(t *testing.T) {
 	h = hash.None
 	assert.Equal(t, h.String(), "None")
 }
+
+func TestHashSetter(t *testing.T) {
+	var ht hash.Type
+	for _, test := range []struct {
+		in   string
+		want hash.Type
+	}{
+		{"MD5", hash.MD5},
+		{"SHA-256", hash.SHA256},
+		{"sha-256", hash.SHA256},
+		{"blake3", hash.BLAKE3},
+		{"XXH64", hash.XXH64},
+		{"CRC-32C", hash.CRC32C},
+	} {
+		require.NoError(t, ht.Set(test.in), test.in)
+		assert.Equal(t, test.want, ht, test.in)
+	}
+	assert.Error(t, ht.Set("potato"))
+}
@@ -32,6 +32,7 @@ type March struct {
 	Callback               Marcher         // object to call with results
 	NoCheckDest            bool            // transfer all objects regardless without checking dst
 	NoUnicodeNormalization bool            // don't normalize unicode characters in filenames
+	Paths                  []string        // if set only march these paths and the directories under them
 	// internal state
 	srcListDir listDirFn // function to call to list a directory in the src
 	dstListDir listDirFn // function to call to list a directory in the dst
@@ -48,6 +49,15 @@ type Marcher interface {
 	Match(ctx context.Context, dst, src fs.DirEntry) (recurse bool)
 }
 
+// DstDirRenamer is an optional interface for a Marcher which may
+// rename destination directories in Match
+type DstDirRenamer interface {
+	// DstDirRemote is called after Match returns recurse for the
+	// directories dst and src and returns the path to list the
+	// destination directory from
+	DstDirRemote(dst, src fs.DirEntry) string
+}
+
 // init sets up a march over opt.Fsrc, and opt.Fdst calling back callback for each match
 func (m *March) init(ctx context.Context) {
 	ci := fs.GetConfig(ctx)
@@ -80,14 +90,15 @@ func (m *March) makeListDir(ctx context.Context, f fs.Fs, includeAll bool) listD
 	ci := fs.GetConfig(ctx)
 	fi := filter.GetConfig(ctx)
 	if !(ci.UseListR && f.Features().ListR != nil) && // !--fast-list active and
-		!(ci.NoTraverse && fi.HaveFilesFrom()) { // !(--files-from and --no-traverse)
+		!(ci.NoTraverse && fi.HaveFilesFrom()) && // !(--files-from and --no-traverse) and
+		m.Paths == nil { // !Paths
 		return func(dir string) (entries fs.DirEntries, err error) {
 			return list.DirSorted(m.Ctx, f, includeAll, dir)
 		}
 	}
 
 	// This returns a closure for use when --fast-list is active or for when
-	// --files-from and --no-traverse is set
+	// --files-from and --no-traverse is set or when Paths is set
 	var (
 		mu      sync.Mutex
 		started bool
@@ -98,7 +109,11 @@ func (m *March) makeListDir(ctx context.Context, f fs.Fs, includeAll bool) listD
 		mu.Lock()
 		defer mu.Unlock()
 		if !started {
-			dirs, dirsErr = walk.NewDirTree(m.Ctx, f, m.Dir, includeAll, ci.MaxDepth)
+			if m.Paths != nil {
+				dirs, dirsErr = walk.NewDirTreePaths(m.Ctx, f, m.Dir, includeAll, ci.MaxDepth, m.Paths)
+			} else {
+				dirs, dirsErr = walk.NewDirTree(m.Ctx, f, m.Dir, includeAll, ci.MaxDepth)
+			}
 			started = true
 		}
 		if dirsErr != nil {
@@ -478,9 +493,13 @@ func (m *March) processJob(job listDirJob) ([]listDirJob, error) {
 		}
 		recurse := m.Callback.Match(m.Ctx, match.dst, match.src)
 		if recurse && job.srcDepth > 0 && job.dstDepth > 0 {
+			dstRemote := match.dst.Remote()
+			if renamer, ok := m.Callback.(DstDirRenamer); ok {
+				dstRemote = renamer.DstDirRemote(match.dst, match.src)
+			}
 			jobs = append(jobs, listDirJob{
 				srcRemote: match.src.Remote(),
-				dstRemote: match.dst.Remote(),
+				dstRemote: dstRemote,
 				srcDepth:  job.srcDepth - 1,
 				dstDepth:  job.dstDepth - 1,
 			})
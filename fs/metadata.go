@@ -0,0 +1,149 @@
+package fs
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Metadata represents Object metadata in a standardised form
+//
+// Keys are lower case.  System metadata keys are described in the
+// MetadataInfo for each backend, any other keys are user metadata.
+type Metadata map[string]string
+
+// MetadataHelp represents help for a bit of system metadata
+type MetadataHelp struct {
+	Help     string
+	Type     string
+	Example  string
+	ReadOnly bool
+}
+
+// MetadataInfo is help for the whole metadata for this backend.
+type MetadataInfo struct {
+	System map[string]MetadataHelp
+	Help   string
+}
+
+// Metadataer is an optional interface for Object
+type Metadataer interface {
+	// Metadata returns metadata for an object
+	//
+	// It should return nil if there is no Metadata
+	Metadata(ctx context.Context) (Metadata, error)
+}
+
+// SetMetadataer is an optional interface for Object
+type SetMetadataer interface {
+	// SetMetadata sets metadata for an Object
+	//
+	// It should return fs.ErrorNotImplemented if it can't set metadata
+	SetMetadata(ctx context.Context, metadata Metadata) error
+}
+
+// Set k to v on m
+//
+// If m is nil, then it will get made
+func (m *Metadata) Set(k, v string) {
+	if *m == nil {
+		*m = make(Metadata, 1)
+	}
+	(*m)[k] = v
+}
+
+// Merge other into m
+//
+// If m is nil, then it will get made
+func (m *Metadata) Merge(other Metadata) {
+	for k, v := range other {
+		if *m == nil {
+			*m = make(Metadata, len(other))
+		}
+		(*m)[k] = v
+	}
+}
+
+// MergeOptions gets any Metadata from the options passed in and
+// stores it in m (which may be nil).
+//
+// If there is no m then metadata will be nil
+func (m *Metadata) MergeOptions(options []OpenOption) {
+	for _, opt := range options {
+		if metadataOption, ok := opt.(MetadataOption); ok {
+			m.Merge(Metadata(metadataOption))
+		}
+	}
+}
+
+// Map renames the keys of m according to mapping
+//
+// A key mapped to "" is removed.
+func (m Metadata) Map(mapping map[string]string) Metadata {
+	if len(mapping) == 0 || m == nil {
+		return m
+	}
+	out := make(Metadata, len(m))
+	for k, v := range m {
+		if newK, found := mapping[k]; found {
+			if newK == "" {
+				continue
+			}
+			k = newK
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// GetMetadata from an ObjectInfo
+//
+// If the object has no metadata then metadata will be nil
+func GetMetadata(ctx context.Context, o ObjectInfo) (metadata Metadata, err error) {
+	do, ok := o.(Metadataer)
+	if !ok {
+		return nil, nil
+	}
+	return do.Metadata(ctx)
+}
+
+// GetMetadataOptions from an ObjectInfo and merge it with any in options
+//
+// The source metadata has the --metadata-map key mapping applied
+// then --metadata-set and any MetadataOption in options merged in.
+//
+// If --metadata isn't in use it will return nil
+//
+// If the object has no metadata then metadata will be nil
+func GetMetadataOptions(ctx context.Context, o ObjectInfo, options []OpenOption) (metadata Metadata, err error) {
+	ci := GetConfig(ctx)
+	if !ci.Metadata {
+		return nil, nil
+	}
+	metadata, err = GetMetadata(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+	metadata = metadata.Map(ci.MetadataMap)
+	metadata.Merge(ci.MetadataSet)
+	metadata.MergeOptions(options)
+	return metadata, nil
+}
+
+// ParseMetadataPairs parses strings of the form key=value into
+// Metadata, lower casing the keys
+//
+// This is used for parsing the --metadata-set and --metadata-map
+// flags.
+func ParseMetadataPairs(pairs []string) (Metadata, error) {
+	var metadata Metadata
+	for _, pair := range pairs {
+		equal := strings.IndexRune(pair, '=')
+		if equal < 0 {
+			return nil, errors.Errorf("failed to parse %q as metadata - expecting key=value", pair)
+		}
+		metadata.Set(strings.ToLower(strings.TrimSpace(pair[:equal])), pair[equal+1:])
+	}
+	return metadata, nil
+}
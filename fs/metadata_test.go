@@ -0,0 +1,82 @@
+package fs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadataSet(t *testing.T) {
+	var m Metadata
+	assert.Nil(t, m)
+	m.Set("key", "value")
+	assert.NotNil(t, m)
+	assert.Equal(t, "value", m["key"])
+	m.Set("key", "value2")
+	assert.Equal(t, "value2", m["key"])
+}
+
+func TestMetadataMerge(t *testing.T) {
+	for _, test := range []struct {
+		in    Metadata
+		merge Metadata
+		want  Metadata
+	}{
+		{
+			in:    Metadata{},
+			merge: Metadata{},
+			want:  Metadata{},
+		}, {
+			in:    nil,
+			merge: nil,
+			want:  nil,
+		}, {
+			in:    nil,
+			merge: Metadata{},
+			want:  nil,
+		}, {
+			in:    nil,
+			merge: Metadata{"a": "1", "b": "2"},
+			want:  Metadata{"a": "1", "b": "2"},
+		}, {
+			in:    Metadata{"a": "1", "b": "2"},
+			merge: Metadata{"b": "B", "c": "3"},
+			want:  Metadata{"a": "1", "b": "B", "c": "3"},
+		},
+	} {
+		what := test.in
+		test.in.Merge(test.merge)
+		assert.Equal(t, test.want, test.in, what)
+	}
+}
+
+func TestMetadataMergeOptions(t *testing.T) {
+	var m Metadata
+	m.MergeOptions([]OpenOption{
+		&RangeOption{Start: 1, End: 2},
+		MetadataOption{"a": "1"},
+		MetadataOption{"b": "2", "a": "A"},
+	})
+	assert.Equal(t, Metadata{"a": "A", "b": "2"}, m)
+}
+
+func TestMetadataMap(t *testing.T) {
+	m := Metadata{"a": "1", "b": "2", "c": "3"}
+	assert.Equal(t, m, m.Map(nil))
+	assert.Equal(t, Metadata{"A": "1", "c": "3"}, m.Map(map[string]string{"a": "A", "b": "", "d": "D"}))
+	assert.Nil(t, Metadata(nil).Map(map[string]string{"a": "A"}))
+}
+
+func TestParseMetadataPairs(t *testing.T) {
+	m, err := ParseMetadataPairs(nil)
+	require.NoError(t, err)
+	assert.Nil(t, m)
+
+	m, err = ParseMetadataPairs([]string{"Key=Value", "empty=", "a=b=c"})
+	require.NoError(t, err)
+	assert.Equal(t, Metadata{"key": "Value", "empty": "", "a": "b=c"}, m)
+
+	_, err = ParseMetadataPairs([]string{"potato"})
+	assert.Error(t, err)
+}
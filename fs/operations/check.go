@@ -1,16 +1,23 @@
 package operations
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"path"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 
 	"github.com/pkg/errors"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/filter"
 	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/march"
@@ -56,14 +63,20 @@ type checkMarch struct {
 
 // report outputs the fileName to out if required and to the combined log
 func (c *checkMarch) report(o fs.DirEntry, out io.Writer, sigil rune) {
+	c.reportFilename(o.String(), out, sigil)
+}
+
+// reportFilename outputs filename to out if required and to the
+// combined log
+func (c *checkMarch) reportFilename(filename string, out io.Writer, sigil rune) {
 	if out != nil {
 		c.ioMu.Lock()
-		_, _ = fmt.Fprintf(out, "%v\n", o)
+		_, _ = fmt.Fprintf(out, "%s\n", filename)
 		c.ioMu.Unlock()
 	}
 	if c.opt.Combined != nil {
 		c.ioMu.Lock()
-		_, _ = fmt.Fprintf(c.opt.Combined, "%c %v\n", sigil, o)
+		_, _ = fmt.Fprintf(c.opt.Combined, "%c %s\n", sigil, filename)
 		c.ioMu.Unlock()
 	}
 }
@@ -224,6 +237,12 @@ func CheckFn(ctx context.Context, opt *CheckOpt) error {
 	err := m.Run(ctx)
 	c.wg.Wait() // wait for background go-routines
 
+	return c.reportResults(ctx, err)
+}
+
+// reportResults logs the totals of the check and returns an error if
+// there were differences or err is set
+func (c *checkMarch) reportResults(ctx context.Context, err error) error {
 	if c.dstFilesMissing > 0 {
 		fs.Logf(c.opt.Fdst, "%d files missing", c.dstFilesMissing)
 	}
@@ -358,3 +377,201 @@ func CheckDownload(ctx context.Context, opt *CheckOpt) error {
 	}
 	return CheckFn(ctx, &optCopy)
 }
+
+// HashSums maps the file names in a SUM file to their hashes
+type HashSums map[string]string
+
+// ParseSumFile parses a SUM file containing hashes of type hashType
+// in the format produced by md5sum, sha1sum and rclone hashsum.
+//
+// Each line is a hash followed by two spaces (or a space and a "*")
+// and the file name. The hashes may be hex or, as produced by
+// hashsum --base64, base64 encoded and are returned as lower case
+// hex. Blank lines are ignored.
+func ParseSumFile(ctx context.Context, sumFile fs.Object, hashType hash.Type) (HashSums, error) {
+	rd, err := sumFile.Open(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open sum file")
+	}
+	defer fs.CheckClose(rd, &err)
+
+	width := hash.Width(hashType)
+	hashes := HashSums{}
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		sum, filename, ok := splitSumLine(line)
+		if ok {
+			sum, ok = normaliseSum(sum, width)
+		}
+		if !ok {
+			err = errors.Errorf("%s:%d: malformed %v sum line %q", sumFile, lineNo, hashType, line)
+			fs.Errorf(nil, "%v", err)
+			_ = fs.CountError(err)
+			continue
+		}
+		if _, found := hashes[filename]; found {
+			err = errors.Errorf("%s:%d: duplicate file %q", sumFile, lineNo, filename)
+			fs.Errorf(nil, "%v", err)
+			_ = fs.CountError(err)
+		}
+		hashes[filename] = sum
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read sum file")
+	}
+	return hashes, nil
+}
+
+// splitSumLine splits a SUM file line into the hash and file name
+func splitSumLine(line string) (sum, filename string, ok bool) {
+	line = strings.TrimLeft(line, " ")
+	i := strings.IndexByte(line, ' ')
+	if i <= 0 || i+2 > len(line) {
+		return "", "", false
+	}
+	sum, filename = line[:i], line[i+1:]
+	// Text mode has a space before the file name and binary mode a "*"
+	if filename[0] == ' ' || filename[0] == '*' {
+		filename = filename[1:]
+	}
+	return sum, filename, filename != ""
+}
+
+// normaliseSum checks sum is a hex or base64 encoded hash of width
+// hex digits, returning it as lower case hex
+func normaliseSum(sum string, width int) (string, bool) {
+	if len(sum) == width {
+		if _, err := hex.DecodeString(sum); err == nil {
+			return strings.ToLower(sum), true
+		}
+	}
+	if len(sum) == base64.URLEncoding.EncodedLen(width/2) {
+		if raw, err := base64.URLEncoding.DecodeString(sum); err == nil {
+			return hex.EncodeToString(raw), true
+		}
+	}
+	return "", false
+}
+
+// CheckSum checks the files in fsrc against the hashes of type
+// hashType in the SUM file sumFile in fsum.
+//
+// The SUM file plays the part of the destination, so files only in
+// the SUM file are reported as missing on the source and files only
+// in fsrc as missing on the destination.
+//
+// If download is set then the hashes of the files are calculated by
+// reading them rather than asking the backend for them.
+func CheckSum(ctx context.Context, fsrc, fsum fs.Fs, sumFile string, hashType hash.Type, opt *CheckOpt, download bool) error {
+	ci := fs.GetConfig(ctx)
+	fi := filter.GetConfig(ctx)
+	if hashType == hash.None {
+		return errors.New("a hash type is needed to check against a sum file")
+	}
+	if !download && !fsrc.Hashes().Contains(hashType) {
+		return errors.Errorf("%v: hash type %v is not supported by the remote - use --download", fsrc, hashType)
+	}
+	sumObj, err := fsum.NewObject(ctx, sumFile)
+	if err != nil {
+		return errors.Wrap(err, "cannot open sum file")
+	}
+	hashes, err := ParseSumFile(ctx, sumObj, hashType)
+	if err != nil {
+		return err
+	}
+
+	optCopy := *opt
+	optCopy.Fsrc = fsrc
+	optCopy.Fdst = fsum
+	c := &checkMarch{
+		tokens: make(chan struct{}, ci.Checkers),
+		opt:    optCopy,
+	}
+	// The sum file may be in the tree being checked
+	sumPath := path.Join(fsum.Root(), sumFile)
+	isSumFile := func(o fs.Object) bool {
+		return SameConfig(fsrc, fsum) && path.Join(fsrc.Root(), o.Remote()) == sumPath
+	}
+	var hashesMu sync.Mutex
+	err = ListFn(ctx, fsrc, func(o fs.Object) {
+		if isSumFile(o) {
+			return
+		}
+		hashesMu.Lock()
+		sum, found := hashes[o.Remote()]
+		delete(hashes, o.Remote())
+		hashesMu.Unlock()
+		if !found {
+			err := errors.Errorf("File not in sum file %q", sumFile)
+			fs.Errorf(o, "%v", err)
+			_ = fs.CountError(err)
+			atomic.AddInt32(&c.differences, 1)
+			atomic.AddInt32(&c.dstFilesMissing, 1)
+			c.report(o, c.opt.MissingOnDst, '+')
+			return
+		}
+		c.wg.Add(1)
+		c.tokens <- struct{}{} // put a token to limit concurrency
+		go func() {
+			defer func() {
+				<-c.tokens // get the token back to free up a slot
+				c.wg.Done()
+			}()
+			c.checkSum(ctx, o, sum, hashType, download)
+		}()
+	})
+	c.wg.Wait() // wait for background go-routines
+
+	// Anything left in the sum file wasn't found in fsrc
+	if !opt.OneWay {
+		var missing []string
+		for filename := range hashes {
+			if fi.IncludeRemote(filename) {
+				missing = append(missing, filename)
+			}
+		}
+		sort.Strings(missing)
+		for _, filename := range missing {
+			err := errors.Errorf("File not in %v", fsrc)
+			fs.Errorf(filename, "%v", err)
+			_ = fs.CountError(err)
+			atomic.AddInt32(&c.differences, 1)
+			atomic.AddInt32(&c.srcFilesMissing, 1)
+			c.reportFilename(filename, c.opt.MissingOnSrc, '-')
+		}
+	}
+
+	return c.reportResults(ctx, err)
+}
+
+// checkSum checks the hash of o against sum from the sum file
+func (c *checkMarch) checkSum(ctx context.Context, o fs.Object, sum string, hashType hash.Type, download bool) {
+	objSum, err := hashSum(ctx, hashType, download, o)
+	if err == hash.ErrUnsupported {
+		err = errors.Errorf("hash %v unsupported - use --download", hashType)
+	}
+	if err == nil && objSum == "" {
+		err = errors.Errorf("hash %v not available - use --download", hashType)
+	}
+	switch {
+	case err != nil:
+		fs.Errorf(o, "%v", err)
+		_ = fs.CountError(err)
+		c.report(o, c.opt.Error, '!')
+	case !strings.EqualFold(objSum, sum):
+		atomic.AddInt32(&c.differences, 1)
+		err = errors.Errorf("%v differ", hashType)
+		fs.Errorf(o, "%v", err)
+		_ = fs.CountError(err)
+		c.report(o, c.opt.Differ, '*')
+	default:
+		atomic.AddInt32(&c.matches, 1)
+		c.report(o, c.opt.Match, '=')
+		fs.Debugf(o, "OK")
+	}
+}
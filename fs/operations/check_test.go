@@ -14,6 +14,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/lib/readers"
@@ -275,3 +276,150 @@ func TestCheckEqualReaders(t *testing.T) {
 	assert.Equal(t, myErr, err)
 	assert.Equal(t, differ, true)
 }
+
+func TestCheckSum(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	r.WriteFile("rutabaga", "is tasty", t3)
+	r.WriteFile("empty space", "-", t2)
+	r.WriteFile("potato2", "------------------------------------------------------------", t1)
+	r.WriteFile("local only", "potato", t1)
+	r.WriteObject(ctx, "MD5SUMS", `a49405dcd1c6a545eace9d6825af944b  rutabaga
+M21evFQ2U05h0W5j3fyjJw== *empty space
+
+00000000000000000000000000000000  potato2
+8ee2027983915ec78acc45027d874316  sum only
+not a hash line
+`, t1)
+
+	check := func(name string, download, oneway bool, wantErrors int64, want map[string]string) {
+		t.Run(name, func(t *testing.T) {
+			accounting.GlobalStats().ResetCounters()
+			opt := operations.CheckOpt{
+				OneWay:       oneway,
+				Combined:     new(bytes.Buffer),
+				MissingOnSrc: new(bytes.Buffer),
+				MissingOnDst: new(bytes.Buffer),
+				Match:        new(bytes.Buffer),
+				Differ:       new(bytes.Buffer),
+				Error:        new(bytes.Buffer),
+			}
+			err := operations.CheckSum(ctx, r.Flocal, r.Fremote, "MD5SUMS", hash.MD5, &opt, download)
+			assert.Error(t, err)
+			assert.Equal(t, wantErrors, accounting.GlobalStats().GetErrors())
+			for name, out := range map[string]io.Writer{
+				"combined":     opt.Combined,
+				"missingonsrc": opt.MissingOnSrc,
+				"missingondst": opt.MissingOnDst,
+				"match":        opt.Match,
+				"differ":       opt.Differ,
+				"error":        opt.Error,
+			} {
+				lines := strings.Split(strings.TrimSuffix(out.(*bytes.Buffer).String(), "\n"), "\n")
+				sort.Strings(lines)
+				assert.Equal(t, want[name], strings.Join(lines, "\n"), name)
+			}
+		})
+	}
+
+	want := map[string]string{
+		"combined":     "* potato2\n+ local only\n- sum only\n= empty space\n= rutabaga",
+		"missingonsrc": "sum only",
+		"missingondst": "local only",
+		"match":        "empty space\nrutabaga",
+		"differ":       "potato2",
+		"error":        "",
+	}
+	check("hash", false, false, 4, want)
+	check("download", true, false, 4, want)
+
+	want["combined"] = "* potato2\n+ local only\n= empty space\n= rutabaga"
+	want["missingonsrc"] = ""
+	check("oneway", false, true, 3, want)
+
+	err := operations.CheckSum(ctx, r.Flocal, r.Fremote, "MD5SUMS", hash.None, &operations.CheckOpt{}, false)
+	assert.Error(t, err)
+	err = operations.CheckSum(ctx, r.Flocal, r.Fremote, "notfound", hash.MD5, &operations.CheckOpt{}, false)
+	assert.Error(t, err)
+}
+
+func TestCheckSumRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	r.WriteFile("rutabaga", "is tasty", t3)
+	r.WriteFile("dir/potato2", "------------------------------------------------------------", t1)
+	r.WriteFile("empty space", "-", t2)
+
+	for _, outputBase64 := range []bool{false, true} {
+		var buf bytes.Buffer
+		require.NoError(t, operations.HashLister(ctx, hash.MD5, outputBase64, false, r.Flocal, &buf))
+		r.WriteObject(ctx, "MD5SUMS", buf.String(), t1)
+
+		accounting.GlobalStats().ResetCounters()
+		opt := operations.CheckOpt{Combined: new(bytes.Buffer)}
+		err := operations.CheckSum(ctx, r.Flocal, r.Fremote, "MD5SUMS", hash.MD5, &opt, false)
+		require.NoError(t, err, buf.String())
+		assert.Equal(t, int64(3), accounting.GlobalStats().GetChecks())
+		lines := strings.Split(strings.TrimSuffix(opt.Combined.(*bytes.Buffer).String(), "\n"), "\n")
+		sort.Strings(lines)
+		assert.Equal(t, []string{"= dir/potato2", "= empty space", "= rutabaga"}, lines)
+	}
+}
+
+// Test the sum file isn't checked if it is in the tree being checked
+// and that checking happens with --dry-run
+func TestCheckSumInTree(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	ci.DryRun = true
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	r.WriteObject(ctx, "rutabaga", "is tasty", t3)
+	r.WriteObject(ctx, "dir/potato2", "------------------------------------------------------------", t1)
+	var buf bytes.Buffer
+	require.NoError(t, operations.HashLister(ctx, hash.MD5, false, false, r.Fremote, &buf))
+	r.WriteObject(ctx, "MD5SUMS", buf.String(), t1)
+
+	accounting.GlobalStats().ResetCounters()
+	opt := operations.CheckOpt{Combined: new(bytes.Buffer)}
+	err := operations.CheckSum(ctx, r.Fremote, r.Fremote, "MD5SUMS", hash.MD5, &opt, false)
+	require.NoError(t, err, buf.String())
+	assert.Equal(t, int64(2), accounting.GlobalStats().GetChecks())
+	lines := strings.Split(strings.TrimSuffix(opt.Combined.(*bytes.Buffer).String(), "\n"), "\n")
+	sort.Strings(lines)
+	assert.Equal(t, []string{"= dir/potato2", "= rutabaga"}, lines)
+}
+
+func TestParseSumFile(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	r.WriteObject(ctx, "SHA1SUMS", "3BC15C8AAE3E4124DD409035F32EA2FD6835EFC9  upper\r\n"+
+		"  3bc15c8aae3e4124dd409035f32ea2fd6835efc9  leading space\n"+
+		"O8Fciq4-QSTdQJA18y6i_Wg178k= *base64\n"+
+		"3bc15c8aae3e4124dd409035f32ea2fd6835efc9   three spaces\n"+
+		"3bc15c8aae3e4124dd409035f32ea2fd6835efc9  upper\n"+
+		"3bc15c8a  short\n"+
+		"3bc15c8aae3e4124dd409035f32ea2fd6835efc9\n", t1)
+	o, err := r.Fremote.NewObject(ctx, "SHA1SUMS")
+	require.NoError(t, err)
+
+	accounting.GlobalStats().ResetCounters()
+	hashes, err := operations.ParseSumFile(ctx, o, hash.SHA1)
+	require.NoError(t, err)
+	sum := "3bc15c8aae3e4124dd409035f32ea2fd6835efc9"
+	assert.Equal(t, operations.HashSums{
+		"upper":         sum,
+		"leading space": sum,
+		"base64":        sum,
+		" three spaces": sum,
+	}, hashes)
+	// duplicate, short and missing file name
+	assert.Equal(t, int64(3), accounting.GlobalStats().GetErrors())
+}
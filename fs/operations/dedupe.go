@@ -316,10 +316,17 @@ func sortSmallestFirst(objs []fs.Object) {
 // Deduplicate interactively finds duplicate files and offers to
 // delete all but one or rename them to be different. Only useful with
 // Google Drive which can have duplicate file names.
-func Deduplicate(ctx context.Context, f fs.Fs, mode DeduplicateMode, byHash bool) error {
+//
+// ht is the hash to compare files with, or hash.None to use the first
+// hash f supports.
+func Deduplicate(ctx context.Context, f fs.Fs, mode DeduplicateMode, byHash bool, ht hash.Type) error {
 	ci := fs.GetConfig(ctx)
 	// find a hash to use
-	ht := f.Hashes().GetOne()
+	if ht == hash.None {
+		ht = f.Hashes().GetOne()
+	} else if !f.Hashes().Contains(ht) {
+		return errors.Errorf("%v doesn't support hash type %v", f, ht)
+	}
 	what := "names"
 	if byHash {
 		if ht == hash.None {
@@ -54,7 +54,7 @@ func TestDeduplicateInteractive(t *testing.T) {
 	file3 := r.WriteUncheckedObject(context.Background(), "one", "This is one", t1)
 	r.CheckWithDuplicates(t, file1, file2, file3)
 
-	err := operations.Deduplicate(context.Background(), r.Fremote, operations.DeduplicateInteractive, false)
+	err := operations.Deduplicate(context.Background(), r.Fremote, operations.DeduplicateInteractive, false, hash.None)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.Fremote, file1)
@@ -76,7 +76,7 @@ func TestDeduplicateSkip(t *testing.T) {
 	files = append(files, file3)
 	r.CheckWithDuplicates(t, files...)
 
-	err := operations.Deduplicate(context.Background(), r.Fremote, operations.DeduplicateSkip, false)
+	err := operations.Deduplicate(context.Background(), r.Fremote, operations.DeduplicateSkip, false, hash.None)
 	require.NoError(t, err)
 
 	r.CheckWithDuplicates(t, file1, file3)
@@ -99,7 +99,7 @@ func TestDeduplicateSizeOnly(t *testing.T) {
 		ci.SizeOnly = false
 	}()
 
-	err := operations.Deduplicate(context.Background(), r.Fremote, operations.DeduplicateSkip, false)
+	err := operations.Deduplicate(context.Background(), r.Fremote, operations.DeduplicateSkip, false, hash.None)
 	require.NoError(t, err)
 
 	r.CheckWithDuplicates(t, file1, file3)
@@ -115,7 +115,7 @@ func TestDeduplicateFirst(t *testing.T) {
 	file3 := r.WriteUncheckedObject(context.Background(), "one", "This is one BB", t1)
 	r.CheckWithDuplicates(t, file1, file2, file3)
 
-	err := operations.Deduplicate(context.Background(), r.Fremote, operations.DeduplicateFirst, false)
+	err := operations.Deduplicate(context.Background(), r.Fremote, operations.DeduplicateFirst, false, hash.None)
 	require.NoError(t, err)
 
 	// list until we get one object
@@ -145,7 +145,7 @@ func TestDeduplicateNewest(t *testing.T) {
 	file3 := r.WriteUncheckedObject(context.Background(), "one", "This is another one", t3)
 	r.CheckWithDuplicates(t, file1, file2, file3)
 
-	err := operations.Deduplicate(context.Background(), r.Fremote, operations.DeduplicateNewest, false)
+	err := operations.Deduplicate(context.Background(), r.Fremote, operations.DeduplicateNewest, false, hash.None)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.Fremote, file3)
@@ -164,12 +164,51 @@ func TestDeduplicateNewestByHash(t *testing.T) {
 	file4 := r.WriteObject(context.Background(), "not-one", "stuff", t3)
 	fstest.CheckItems(t, r.Fremote, file1, file2, file3, file4)
 
-	err := operations.Deduplicate(context.Background(), r.Fremote, operations.DeduplicateNewest, true)
+	err := operations.Deduplicate(context.Background(), r.Fremote, operations.DeduplicateNewest, true, hash.None)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.Fremote, file3, file4)
 }
 
+func TestDeduplicateNewestByHashType(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	if !r.Fremote.Hashes().Contains(hash.XXH64) {
+		t.Skip("Can't run this test without XXH64")
+	}
+	skipIfNoModTime(t, r.Fremote)
+	contents := random.String(100)
+
+	file1 := r.WriteObject(context.Background(), "one", contents, t1)
+	file2 := r.WriteObject(context.Background(), "also/one", contents, t2)
+	file3 := r.WriteObject(context.Background(), "another", contents, t3)
+	file4 := r.WriteObject(context.Background(), "not-one", "stuff", t3)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3, file4)
+
+	err := operations.Deduplicate(context.Background(), r.Fremote, operations.DeduplicateNewest, true, hash.XXH64)
+	require.NoError(t, err)
+
+	fstest.CheckItems(t, r.Fremote, file3, file4)
+}
+
+func TestDeduplicateUnsupportedHash(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	var unsupported hash.Type
+	for _, ht := range hash.Supported().Array() {
+		if !r.Fremote.Hashes().Contains(ht) {
+			unsupported = ht
+			break
+		}
+	}
+	if unsupported == hash.None {
+		t.Skip("Remote supports all hashes")
+	}
+
+	err := operations.Deduplicate(context.Background(), r.Fremote, operations.DeduplicateNewest, true, unsupported)
+	assert.Error(t, err)
+}
+
 func TestDeduplicateOldest(t *testing.T) {
 	r := fstest.NewRun(t)
 	defer r.Finalise()
@@ -180,7 +219,7 @@ func TestDeduplicateOldest(t *testing.T) {
 	file3 := r.WriteUncheckedObject(context.Background(), "one", "This is another one", t3)
 	r.CheckWithDuplicates(t, file1, file2, file3)
 
-	err := operations.Deduplicate(context.Background(), r.Fremote, operations.DeduplicateOldest, false)
+	err := operations.Deduplicate(context.Background(), r.Fremote, operations.DeduplicateOldest, false, hash.None)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.Fremote, file1)
@@ -196,7 +235,7 @@ func TestDeduplicateLargest(t *testing.T) {
 	file3 := r.WriteUncheckedObject(context.Background(), "one", "This is another one", t3)
 	r.CheckWithDuplicates(t, file1, file2, file3)
 
-	err := operations.Deduplicate(context.Background(), r.Fremote, operations.DeduplicateLargest, false)
+	err := operations.Deduplicate(context.Background(), r.Fremote, operations.DeduplicateLargest, false, hash.None)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.Fremote, file3)
@@ -212,7 +251,7 @@ func TestDeduplicateSmallest(t *testing.T) {
 	file3 := r.WriteUncheckedObject(context.Background(), "one", "This is another one", t3)
 	r.CheckWithDuplicates(t, file1, file2, file3)
 
-	err := operations.Deduplicate(context.Background(), r.Fremote, operations.DeduplicateSmallest, false)
+	err := operations.Deduplicate(context.Background(), r.Fremote, operations.DeduplicateSmallest, false, hash.None)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.Fremote, file1)
@@ -229,7 +268,7 @@ func TestDeduplicateRename(t *testing.T) {
 	file4 := r.WriteUncheckedObject(context.Background(), "one-1.txt", "This is not a duplicate", t1)
 	r.CheckWithDuplicates(t, file1, file2, file3, file4)
 
-	err := operations.Deduplicate(context.Background(), r.Fremote, operations.DeduplicateRename, false)
+	err := operations.Deduplicate(context.Background(), r.Fremote, operations.DeduplicateRename, false, hash.None)
 	require.NoError(t, err)
 
 	require.NoError(t, walk.ListR(context.Background(), r.Fremote, "", true, -1, walk.ListObjects, func(entries fs.DirEntries) error {
@@ -161,7 +161,7 @@ func multiThreadCopy(ctx context.Context, f fs.Fs, remote string, src fs.Object,
 	mc.calculateChunks()
 
 	// Make accounting
-	mc.acc = tr.Account(ctx, nil)
+	mc.acc = tr.Account(ctx, nil).WithRemoteBwLimit(ctx, src.Fs(), f)
 
 	// create write file handle
 	mc.wc, err = openWriterAt(gCtx, remote, mc.size)
@@ -197,6 +197,22 @@ func multiThreadCopy(ctx context.Context, f fs.Fs, remote string, src fs.Object,
 		return nil, errors.Wrap(err, "multi-thread copy: failed to set modification time")
 	}
 
+	// Copy the metadata across if --metadata is in use
+	metadata, err := fs.GetMetadataOptions(ctx, src, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "multi-thread copy: failed to read metadata from source object")
+	}
+	if metadata != nil {
+		if do, ok := obj.(fs.SetMetadataer); ok {
+			err = do.SetMetadata(ctx, metadata)
+			if err != nil {
+				return nil, errors.Wrap(err, "multi-thread copy: failed to set metadata")
+			}
+		} else {
+			fs.Debugf(obj, "multi-thread copy: can't set metadata on this backend")
+		}
+	}
+
 	fs.Debugf(src, "Finished multi-thread copy with %d parts of size %v", mc.streams, fs.SizeSuffix(mc.partSize))
 	return obj, nil
 }
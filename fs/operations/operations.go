@@ -34,6 +34,7 @@ import (
 	"github.com/rclone/rclone/lib/atexit"
 	"github.com/rclone/rclone/lib/random"
 	"github.com/rclone/rclone/lib/readers"
+	"github.com/rclone/rclone/lib/resume"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -335,6 +336,16 @@ func (o *OverrideRemote) GetTier() string {
 	return ""
 }
 
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *OverrideRemote) Metadata(ctx context.Context) (fs.Metadata, error) {
+	if do, ok := o.ObjectInfo.(fs.Metadataer); ok {
+		return do.Metadata(ctx)
+	}
+	return nil, nil
+}
+
 // Check all optional interfaces satisfied
 var _ fs.FullObjectInfo = (*OverrideRemote)(nil)
 
@@ -432,7 +443,8 @@ func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Obj
 			} else {
 				var in0 io.ReadCloser
 				options := []fs.OpenOption{hashOption}
-				for _, option := range ci.DownloadHeaders {
+				srcCi, _ := fs.RemoteConfig(ctx, src.Fs())
+				for _, option := range srcCi.DownloadHeaders {
 					options = append(options, option)
 				}
 				in0, err = NewReOpen(ctx, src, ci.LowLevelRetries, options...)
@@ -450,22 +462,27 @@ func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Obj
 						dst, err = Rcat(ctx, f, remote, in0, src.ModTime(ctx))
 						newDst = dst
 					} else {
-						in := tr.Account(ctx, in0).WithBuffer() // account and buffer the transfer
+						in := tr.Account(ctx, in0).WithBuffer().WithRemoteBwLimit(ctx, src.Fs(), f) // account and buffer the transfer
 						var wrappedSrc fs.ObjectInfo = src
 						// We try to pass the original object if possible
 						if src.Remote() != remote {
 							wrappedSrc = NewOverrideRemote(src, remote)
 						}
 						options := []fs.OpenOption{hashOption}
-						for _, option := range ci.UploadHeaders {
+						dstCi, _ := fs.RemoteConfig(ctx, f)
+						for _, option := range dstCi.UploadHeaders {
 							options = append(options, option)
 						}
+						uploadCtx := ctx
+						if ci.ResumeUploads {
+							uploadCtx = resume.WithUpload(ctx, f, remote, fs.Fingerprint(ctx, src, true))
+						}
 						if doUpdate {
 							actionTaken = "Copied (replaced existing)"
-							err = dst.Update(ctx, in, wrappedSrc, options...)
+							err = dst.Update(uploadCtx, in, wrappedSrc, options...)
 						} else {
 							actionTaken = "Copied (new)"
-							dst, err = f.Put(ctx, in, wrappedSrc, options...)
+							dst, err = f.Put(uploadCtx, in, wrappedSrc, options...)
 						}
 						closeErr := in.Close()
 						if err == nil {
@@ -675,6 +692,12 @@ func DeleteFile(ctx context.Context, dst fs.Object) (err error) {
 // If backupDir is set the files will be placed into that directory
 // instead of being deleted.
 func DeleteFilesWithBackupDir(ctx context.Context, toBeDeleted fs.ObjectsChan, backupDir fs.Fs) error {
+	return DeleteFilesWithBackupDirFn(ctx, toBeDeleted, backupDir, nil)
+}
+
+// DeleteFilesWithBackupDirFn is like DeleteFilesWithBackupDir but
+// calls fn, if set, with each file and the result of deleting it
+func DeleteFilesWithBackupDirFn(ctx context.Context, toBeDeleted fs.ObjectsChan, backupDir fs.Fs, fn func(dst fs.Object, err error)) error {
 	var wg sync.WaitGroup
 	ci := fs.GetConfig(ctx)
 	wg.Add(ci.Transfers)
@@ -686,6 +709,9 @@ func DeleteFilesWithBackupDir(ctx context.Context, toBeDeleted fs.ObjectsChan, b
 			defer wg.Done()
 			for dst := range toBeDeleted {
 				err := DeleteFileWithBackupDir(ctx, dst, backupDir)
+				if fn != nil {
+					fn(dst, err)
+				}
 				if err != nil {
 					atomic.AddInt32(&errorCount, 1)
 					if fserrors.IsFatalError(err) {
@@ -840,7 +866,7 @@ func ListLong(ctx context.Context, f fs.Fs, w io.Writer) error {
 //
 // Lists in parallel which may get them out of order
 func Md5sum(ctx context.Context, f fs.Fs, w io.Writer) error {
-	return HashLister(ctx, hash.MD5, f, w)
+	return HashLister(ctx, hash.MD5, false, false, f, w)
 }
 
 // Sha1sum list the Fs to the supplied writer
@@ -849,19 +875,25 @@ func Md5sum(ctx context.Context, f fs.Fs, w io.Writer) error {
 //
 // Lists in parallel which may get them out of order
 func Sha1sum(ctx context.Context, f fs.Fs, w io.Writer) error {
-	return HashLister(ctx, hash.SHA1, f, w)
+	return HashLister(ctx, hash.SHA1, false, false, f, w)
 }
 
 // hashSum returns the human readable hash for ht passed in.  This may
 // be UNSUPPORTED or ERROR. If it isn't returning a valid hash it will
 // return an error.
-func hashSum(ctx context.Context, ht hash.Type, o fs.Object) (string, error) {
+//
+// If download is set then the hash is calculated by reading the
+// object rather than asking the backend for it.
+func hashSum(ctx context.Context, ht hash.Type, download bool, o fs.Object) (string, error) {
+	var sum string
 	var err error
-	tr := accounting.Stats(ctx).NewCheckingTransfer(o)
-	defer func() {
+	if download {
+		sum, err = downloadHash(ctx, ht, o)
+	} else {
+		tr := accounting.Stats(ctx).NewCheckingTransfer(o)
+		sum, err = o.Hash(ctx, ht)
 		tr.Done(ctx, err)
-	}()
-	sum, err := o.Hash(ctx, ht)
+	}
 	if err == hash.ErrUnsupported {
 		sum = "UNSUPPORTED"
 	} else if err != nil {
@@ -871,23 +903,49 @@ func hashSum(ctx context.Context, ht hash.Type, o fs.Object) (string, error) {
 	return sum, err
 }
 
-// HashLister does an md5sum equivalent for the hash type passed in
-func HashLister(ctx context.Context, ht hash.Type, f fs.Fs, w io.Writer) error {
-	return ListFn(ctx, f, func(o fs.Object) {
-		sum, _ := hashSum(ctx, ht, o)
-		syncFprintf(w, "%*s  %s\n", hash.Width(ht), sum, o.Remote())
-	})
+// downloadHash calculates the hash of type ht of o by reading it
+func downloadHash(ctx context.Context, ht hash.Type, o fs.Object) (sum string, err error) {
+	ci := fs.GetConfig(ctx)
+	hasher, err := hash.NewMultiHasherTypes(hash.NewHashSet(ht))
+	if err != nil {
+		return "", err
+	}
+	in, err := NewReOpen(ctx, o, ci.LowLevelRetries)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to open file")
+	}
+	tr := accounting.Stats(ctx).NewTransfer(o)
+	defer func() {
+		tr.Done(ctx, err)
+	}()
+	acc := tr.Account(ctx, in).WithBuffer() // account and buffer the transfer
+	_, err = io.Copy(hasher, acc)
+	closeErr := acc.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read file")
+	}
+	return hasher.Sums()[ht], nil
 }
 
-// HashListerBase64 does an md5sum equivalent for the hash type passed in with base64 encoded
-func HashListerBase64(ctx context.Context, ht hash.Type, f fs.Fs, w io.Writer) error {
+// HashLister does an md5sum equivalent for the hash type passed in.
+//
+// If outputBase64 is set the hashes are written base64 encoded
+// instead of hex. If download is set the hashes are calculated by
+// reading the files rather than asking the backend for them.
+func HashLister(ctx context.Context, ht hash.Type, outputBase64 bool, download bool, f fs.Fs, w io.Writer) error {
+	width := hash.Width(ht)
+	if outputBase64 {
+		width = base64.URLEncoding.EncodedLen(width / 2)
+	}
 	return ListFn(ctx, f, func(o fs.Object) {
-		sum, err := hashSum(ctx, ht, o)
-		if err == nil {
+		sum, err := hashSum(ctx, ht, download, o)
+		if outputBase64 && err == nil {
 			hexBytes, _ := hex.DecodeString(sum)
 			sum = base64.URLEncoding.EncodeToString(hexBytes)
 		}
-		width := base64.URLEncoding.EncodedLen(hash.Width(ht) / 2)
 		syncFprintf(w, "%*s  %s\n", width, sum, o.Remote())
 	})
 }
@@ -1047,7 +1105,12 @@ func CleanUp(ctx context.Context, f fs.Fs) error {
 	if SkipDestructive(ctx, f, "clean up old files") {
 		return nil
 	}
-	return doCleanUp(ctx)
+	err := doCleanUp(ctx)
+	if err != nil {
+		return err
+	}
+	// The upload sessions which could be resumed have gone now
+	return resume.Cleanup(f)
 }
 
 // wrap a Reader and a Closer together into a ReadCloser
@@ -1134,7 +1197,8 @@ func Rcat(ctx context.Context, fdst fs.Fs, dstFileName string, in io.ReadCloser,
 	} else {
 		trackingIn = readCounter
 	}
-	for _, option := range ci.UploadHeaders {
+	dstCi, _ := fs.RemoteConfig(ctx, fdst)
+	for _, option := range dstCi.UploadHeaders {
 		options = append(options, option)
 	}
 
@@ -1277,11 +1341,14 @@ func Rmdirs(ctx context.Context, f fs.Fs, dir string, leaveRoot bool) error {
 }
 
 // GetCompareDest sets up --compare-dest
-func GetCompareDest(ctx context.Context) (CompareDest fs.Fs, err error) {
+func GetCompareDest(ctx context.Context) (CompareDest []fs.Fs, err error) {
 	ci := fs.GetConfig(ctx)
-	CompareDest, err = cache.Get(ctx, ci.CompareDest)
-	if err != nil {
-		return nil, fserrors.FatalError(errors.Errorf("Failed to make fs for --compare-dest %q: %v", ci.CompareDest, err))
+	CompareDest = make([]fs.Fs, len(ci.CompareDest))
+	for i, path := range ci.CompareDest {
+		CompareDest[i], err = cache.Get(ctx, path)
+		if err != nil {
+			return nil, fserrors.FatalError(errors.Errorf("Failed to make fs for --compare-dest %q: %v", path, err))
+		}
 	}
 	return CompareDest, nil
 }
@@ -1289,42 +1356,49 @@ func GetCompareDest(ctx context.Context) (CompareDest fs.Fs, err error) {
 // compareDest checks --compare-dest to see if src needs to
 // be copied
 //
-// Returns True if src is in --compare-dest
-func compareDest(ctx context.Context, dst, src fs.Object, CompareDest fs.Fs) (NoNeedTransfer bool, err error) {
+// Returns True if src is in any of the --compare-dest
+func compareDest(ctx context.Context, dst, src fs.Object, CompareDest []fs.Fs) (NoNeedTransfer bool, err error) {
 	var remote string
 	if dst == nil {
 		remote = src.Remote()
 	} else {
 		remote = dst.Remote()
 	}
-	CompareDestFile, err := CompareDest.NewObject(ctx, remote)
-	switch err {
-	case fs.ErrorObjectNotFound:
-		return false, nil
-	case nil:
-		break
-	default:
-		return false, err
-	}
-	if Equal(ctx, src, CompareDestFile) {
-		fs.Debugf(src, "Destination found in --compare-dest, skipping")
-		return true, nil
+	for _, compareDest := range CompareDest {
+		CompareDestFile, err := compareDest.NewObject(ctx, remote)
+		switch err {
+		case fs.ErrorObjectNotFound:
+			continue
+		case nil:
+			break
+		default:
+			return false, err
+		}
+		if Equal(ctx, src, CompareDestFile) {
+			location := fs.ConfigString(compareDest)
+			fs.Debugf(src, "Destination found in --compare-dest %q, skipping", location)
+			accounting.Stats(ctx).DestMatch(location)
+			return true, nil
+		}
 	}
 	return false, nil
 }
 
 // GetCopyDest sets up --copy-dest
-func GetCopyDest(ctx context.Context, fdst fs.Fs) (CopyDest fs.Fs, err error) {
+func GetCopyDest(ctx context.Context, fdst fs.Fs) (CopyDest []fs.Fs, err error) {
 	ci := fs.GetConfig(ctx)
-	CopyDest, err = cache.Get(ctx, ci.CopyDest)
-	if err != nil {
-		return nil, fserrors.FatalError(errors.Errorf("Failed to make fs for --copy-dest %q: %v", ci.CopyDest, err))
-	}
-	if !SameConfig(fdst, CopyDest) {
-		return nil, fserrors.FatalError(errors.New("parameter to --copy-dest has to be on the same remote as destination"))
-	}
-	if CopyDest.Features().Copy == nil {
-		return nil, fserrors.FatalError(errors.New("can't use --copy-dest on a remote which doesn't support server-side copy"))
+	CopyDest = make([]fs.Fs, len(ci.CopyDest))
+	for i, path := range ci.CopyDest {
+		CopyDest[i], err = cache.Get(ctx, path)
+		if err != nil {
+			return nil, fserrors.FatalError(errors.Errorf("Failed to make fs for --copy-dest %q: %v", path, err))
+		}
+		if !SameConfig(fdst, CopyDest[i]) {
+			return nil, fserrors.FatalError(errors.New("parameter to --copy-dest has to be on the same remote as destination"))
+		}
+		if CopyDest[i].Features().Copy == nil {
+			return nil, fserrors.FatalError(errors.New("can't use --copy-dest on a remote which doesn't support server-side copy"))
+		}
 	}
 	return CopyDest, nil
 }
@@ -1332,26 +1406,33 @@ func GetCopyDest(ctx context.Context, fdst fs.Fs) (CopyDest fs.Fs, err error) {
 // copyDest checks --copy-dest to see if src needs to
 // be copied
 //
+// The --copy-dest are checked in order and src is copied from the
+// first which has an identical file, which is why they must be given
+// newest first.
+//
 // Returns True if src was copied from --copy-dest
-func copyDest(ctx context.Context, fdst fs.Fs, dst, src fs.Object, CopyDest, backupDir fs.Fs) (NoNeedTransfer bool, err error) {
+func copyDest(ctx context.Context, fdst fs.Fs, dst, src fs.Object, CopyDest []fs.Fs, backupDir fs.Fs) (NoNeedTransfer bool, err error) {
 	var remote string
 	if dst == nil {
 		remote = src.Remote()
 	} else {
 		remote = dst.Remote()
 	}
-	CopyDestFile, err := CopyDest.NewObject(ctx, remote)
-	switch err {
-	case fs.ErrorObjectNotFound:
-		return false, nil
-	case nil:
-		break
-	default:
-		return false, err
-	}
 	opt := defaultEqualOpt(ctx)
 	opt.updateModTime = false
-	if equal(ctx, src, CopyDestFile, opt) {
+	for _, copyDest := range CopyDest {
+		CopyDestFile, err := copyDest.NewObject(ctx, remote)
+		switch err {
+		case fs.ErrorObjectNotFound:
+			continue
+		case nil:
+			break
+		default:
+			return false, err
+		}
+		if !equal(ctx, src, CopyDestFile, opt) {
+			continue
+		}
 		if dst == nil || !Equal(ctx, src, dst) {
 			if dst != nil && backupDir != nil {
 				err = MoveBackupDir(ctx, backupDir, dst)
@@ -1361,12 +1442,14 @@ func copyDest(ctx context.Context, fdst fs.Fs, dst, src fs.Object, CopyDest, bac
 				// If successful zero out the dstObj as it is no longer there
 				dst = nil
 			}
+			location := fs.ConfigString(copyDest)
 			_, err := Copy(ctx, fdst, dst, remote, CopyDestFile)
 			if err != nil {
-				fs.Errorf(src, "Destination found in --copy-dest, error copying")
+				fs.Errorf(src, "Destination found in --copy-dest %q, error copying", location)
 				return false, nil
 			}
-			fs.Debugf(src, "Destination found in --copy-dest, using server-side copy")
+			fs.Debugf(src, "Destination found in --copy-dest %q, using server-side copy", location)
+			accounting.Stats(ctx).DestMatch(location)
 			return true, nil
 		}
 		fs.Debugf(src, "Unchanged skipping")
@@ -1380,11 +1463,11 @@ func copyDest(ctx context.Context, fdst fs.Fs, dst, src fs.Object, CopyDest, bac
 // does not need to be copied
 //
 // Returns True if src does not need to be copied
-func CompareOrCopyDest(ctx context.Context, fdst fs.Fs, dst, src fs.Object, CompareOrCopyDest, backupDir fs.Fs) (NoNeedTransfer bool, err error) {
+func CompareOrCopyDest(ctx context.Context, fdst fs.Fs, dst, src fs.Object, CompareOrCopyDest []fs.Fs, backupDir fs.Fs) (NoNeedTransfer bool, err error) {
 	ci := fs.GetConfig(ctx)
-	if ci.CompareDest != "" {
+	if len(ci.CompareDest) > 0 {
 		return compareDest(ctx, dst, src, CompareOrCopyDest)
-	} else if ci.CopyDest != "" {
+	} else if len(ci.CopyDest) > 0 {
 		return copyDest(ctx, fdst, dst, src, CompareOrCopyDest, backupDir)
 	}
 	return false, nil
@@ -1596,6 +1679,60 @@ func MoveBackupDir(ctx context.Context, backupDir fs.Fs, dst fs.Object) (err err
 	return err
 }
 
+// MoveCaseInsensitive renames srcObj on fdst to dstFileName which
+// differs from its name only in case or unicode normalization.
+//
+// If fdst is case insensitive this will move the file to a temporary
+// name then move it back to the intended destination. This is
+// required to avoid issues with certain remotes and avoid file
+// deletion.
+func MoveCaseInsensitive(ctx context.Context, fdst fs.Fs, dstFileName string, srcObj fs.Object) (newDst fs.Object, err error) {
+	if SkipDestructive(ctx, srcObj, "rename to "+dstFileName) {
+		return srcObj, nil
+	}
+	if !fdst.Features().CaseInsensitive {
+		return Move(ctx, fdst, nil, dstFileName, srcObj)
+	}
+	// Create random name to temporarily move file to
+	tmpObjName := dstFileName + "-rclone-move-" + random.String(8)
+	_, err = fdst.NewObject(ctx, tmpObjName)
+	if err != fs.ErrorObjectNotFound {
+		if err == nil {
+			return nil, errors.New("found an already existing file with a randomly generated name. Try the operation again")
+		}
+		return nil, errors.Wrap(err, "error while attempting to move file to a temporary location")
+	}
+	tmpObj, err := Move(ctx, fdst, nil, tmpObjName, srcObj)
+	if err != nil {
+		return nil, errors.Wrap(err, "error while moving file to temporary location")
+	}
+	return Move(ctx, fdst, nil, dstFileName, tmpObj)
+}
+
+// DirMoveCaseInsensitive renames the directory srcRemote on f to
+// dstRemote which differs from it only in case or unicode
+// normalization.
+//
+// If f is case insensitive this is done in two steps via a temporary
+// name as the remote may not be able to do it in one.
+//
+// It returns renamed false if the rename was skipped because of
+// --dry-run or --interactive.
+func DirMoveCaseInsensitive(ctx context.Context, f fs.Fs, srcRemote, dstRemote string) (renamed bool, err error) {
+	if SkipDestructive(ctx, fs.LogDirName(f, srcRemote), "rename directory to "+dstRemote) {
+		return false, nil
+	}
+	if !f.Features().CaseInsensitive {
+		return true, DirMove(ctx, f, srcRemote, dstRemote)
+	}
+	tmpRemote := dstRemote + "-rclone-move-" + random.String(8)
+	err = DirMove(ctx, f, srcRemote, tmpRemote)
+	if err != nil {
+		return true, errors.Wrap(err, "error while moving directory to temporary location")
+	}
+	return true, DirMove(ctx, f, tmpRemote, dstRemote)
+}
+
 // moveOrCopyFile moves or copies a single file possibly to a new name
 func moveOrCopyFile(ctx context.Context, fdst fs.Fs, fsrc fs.Fs, dstFileName string, srcFileName string, cp bool) (err error) {
 	ci := fs.GetConfig(ctx)
@@ -1634,40 +1771,28 @@ func moveOrCopyFile(ctx context.Context, fdst fs.Fs, fsrc fs.Fs, dstFileName str
 	// move it back to the intended destination. This is required
 	// to avoid issues with certain remotes and avoid file deletion.
 	if !cp && fdst.Name() == fsrc.Name() && fdst.Features().CaseInsensitive && dstFileName != srcFileName && strings.ToLower(dstFilePath) == strings.ToLower(srcFilePath) {
-		// Create random name to temporarily move file to
-		tmpObjName := dstFileName + "-rclone-move-" + random.String(8)
-		_, err := fdst.NewObject(ctx, tmpObjName)
-		if err != fs.ErrorObjectNotFound {
-			if err == nil {
-				return errors.New("found an already existing file with a randomly generated name. Try the operation again")
-			}
-			return errors.Wrap(err, "error while attempting to move file to a temporary location")
-		}
 		tr := accounting.Stats(ctx).NewTransfer(srcObj)
 		defer func() {
 			tr.Done(ctx, err)
 		}()
-		tmpObj, err := Op(ctx, fdst, nil, tmpObjName, srcObj)
-		if err != nil {
-			return errors.Wrap(err, "error while moving file to temporary location")
-		}
-		_, err = Op(ctx, fdst, nil, dstFileName, tmpObj)
+		_, err = MoveCaseInsensitive(ctx, fdst, dstFileName, srcObj)
 		return err
 	}
 
-	var backupDir, copyDestDir fs.Fs
+	var backupDir fs.Fs
+	var copyDestDir []fs.Fs
 	if ci.BackupDir != "" || ci.Suffix != "" {
 		backupDir, err = BackupDir(ctx, fdst, fsrc, srcFileName)
 		if err != nil {
 			return errors.Wrap(err, "creating Fs for --backup-dir failed")
 		}
 	}
-	if ci.CompareDest != "" {
+	if len(ci.CompareDest) > 0 {
 		copyDestDir, err = GetCompareDest(ctx)
 		if err != nil {
 			return err
 		}
-	} else if ci.CopyDest != "" {
+	} else if len(ci.CopyDest) > 0 {
 		copyDestDir, err = GetCopyDest(ctx, fdst)
 		if err != nil {
 			return err
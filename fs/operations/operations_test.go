@@ -233,7 +233,7 @@ func TestHashSums(t *testing.T) {
 	var ht hash.Type
 	err = ht.Set("QuickXorHash")
 	require.NoError(t, err)
-	err = operations.HashLister(ctx, ht, r.Fremote, &buf)
+	err = operations.HashLister(ctx, ht, false, false, r.Fremote, &buf)
 	require.NoError(t, err)
 	res = buf.String()
 	if !strings.Contains(res, "2d00000000000000000000000100000000000000  empty space\n") &&
@@ -250,7 +250,7 @@ func TestHashSums(t *testing.T) {
 	// QuickXorHash Sum with Base64 Encoded
 
 	buf.Reset()
-	err = operations.HashListerBase64(ctx, ht, r.Fremote, &buf)
+	err = operations.HashLister(ctx, ht, true, false, r.Fremote, &buf)
 	require.NoError(t, err)
 	res = buf.String()
 	if !strings.Contains(res, "LQAAAAAAAAAAAAAAAQAAAAAAAAA=  empty space\n") &&
@@ -263,6 +263,15 @@ func TestHashSums(t *testing.T) {
 		!strings.Contains(res, "                              potato2\n") {
 		t.Errorf("potato2 missing: %q", res)
 	}
+
+	// QuickXorHash Sum by downloading the files
+
+	buf.Reset()
+	err = operations.HashLister(ctx, ht, false, true, r.Fremote, &buf)
+	require.NoError(t, err)
+	res = buf.String()
+	assert.Contains(t, res, "2d00000000000000000000000100000000000000  empty space\n")
+	assert.Contains(t, res, "4001dad296b6b4a52d6d694b67dad296b6b4a52d  potato2\n")
 }
 
 func TestSuffixName(t *testing.T) {
@@ -805,6 +814,34 @@ func TestCopyFile(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file2)
 }
 
+// Test that metadata is copied with --metadata
+func TestCopyFileMetadata(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	if !r.Flocal.Features().ReadMetadata || !r.Fremote.Features().WriteMetadata {
+		t.Skip("Metadata not supported")
+	}
+	ci.Metadata = true
+	ci.MetadataSet = fs.Metadata{"mtime": t2.Format(time.RFC3339Nano)}
+
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	fstest.CheckItems(t, r.Flocal, file1)
+
+	err := operations.CopyFile(ctx, r.Fremote, r.Flocal, file1.Path, file1.Path)
+	require.NoError(t, err)
+	file2 := file1
+	file2.ModTime = t2
+	fstest.CheckItems(t, r.Fremote, file2)
+
+	o, err := r.Fremote.NewObject(ctx, file1.Path)
+	require.NoError(t, err)
+	metadata, err := fs.GetMetadata(ctx, o)
+	require.NoError(t, err)
+	assert.Equal(t, t2.Format(time.RFC3339Nano), metadata["mtime"])
+}
+
 func TestCopyFileBackupDir(t *testing.T) {
 	ctx := context.Background()
 	ci := fs.GetConfig(ctx)
@@ -840,9 +877,9 @@ func TestCopyFileCompareDest(t *testing.T) {
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 
-	ci.CompareDest = r.FremoteName + "/CompareDest"
+	ci.CompareDest = []string{r.FremoteName + "/CompareDest"}
 	defer func() {
-		ci.CompareDest = ""
+		ci.CompareDest = nil
 	}()
 	fdst, err := fs.NewFs(ctx, r.FremoteName+"/dst")
 	require.NoError(t, err)
@@ -926,9 +963,9 @@ func TestCopyFileCopyDest(t *testing.T) {
 		t.Skip("Skipping test as remote does not support server-side copy")
 	}
 
-	ci.CopyDest = r.FremoteName + "/CopyDest"
+	ci.CopyDest = []string{r.FremoteName + "/CopyDest"}
 	defer func() {
-		ci.CopyDest = ""
+		ci.CopyDest = nil
 	}()
 
 	fdst, err := fs.NewFs(ctx, r.FremoteName+"/dst")
@@ -31,6 +31,7 @@ func init() {
     - showEncrypted -  If set show decrypted names
     - showOrigIDs - If set show the IDs for each item if known
     - showHash - If set return a dictionary of hashes
+    - metadata - If set return a dictionary of metadata
 
 The result is
 
@@ -0,0 +1,85 @@
+package fs
+
+import (
+	"fmt"
+	"time"
+)
+
+// Time is a time.Time with some more parsing options
+type Time time.Time
+
+// For overriding in unittests.
+var (
+	timeNowFunc = time.Now
+)
+
+// Turn Time into a string
+func (t Time) String() string {
+	if !t.IsSet() {
+		return "off"
+	}
+	return time.Time(t).Format(time.RFC3339Nano)
+}
+
+// IsSet returns if the time is not zero
+func (t Time) IsSet() bool {
+	return !time.Time(t).IsZero()
+}
+
+// ParseTime parses a time or duration string as a Time.
+//
+// A time is parsed in one of the formats accepted by ParseDuration
+// and a duration, such as 1d or 2h30m, means that long before now.
+// "off" returns the zero time.
+func ParseTime(date string) (t time.Time, err error) {
+	if date == "off" {
+		return time.Time{}, nil
+	}
+
+	now := timeNowFunc()
+
+	// Attempt to parse as a text time
+	for _, timeFormat := range timeFormats {
+		t, err = time.Parse(timeFormat, date)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	// Attempt to parse as a time.Duration
+	d, err := time.ParseDuration(date)
+	if err == nil {
+		return now.Add(-d), nil
+	}
+
+	d, err = parseDurationSuffixes(date)
+	if err == nil {
+		return now.Add(-d), nil
+	}
+
+	return t, err
+}
+
+// Set a Time
+func (t *Time) Set(s string) error {
+	parsedTime, err := ParseTime(s)
+	if err != nil {
+		return err
+	}
+	*t = Time(parsedTime)
+	return nil
+}
+
+// Type of the value
+func (t Time) Type() string {
+	return "Time"
+}
+
+// Scan implements the fmt.Scanner interface
+func (t *Time) Scan(s fmt.ScanState, ch rune) error {
+	token, err := s.Token(true, func(rune) bool { return true })
+	if err != nil {
+		return err
+	}
+	return t.Set(string(token))
+}
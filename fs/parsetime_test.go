@@ -0,0 +1,82 @@
+package fs
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Check it satisfies the interface
+var _ pflag.Value = (*Time)(nil)
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2020, 9, 5, 8, 15, 5, 250, time.UTC)
+	oldTimeNowFunc := timeNowFunc
+	timeNowFunc = func() time.Time { return now }
+	defer func() { timeNowFunc = oldTimeNowFunc }()
+
+	for _, test := range []struct {
+		in   string
+		want time.Time
+		err  bool
+	}{
+		{"", time.Time{}, true},
+		{"1ms", now.Add(-time.Millisecond), false},
+		{"1s", now.Add(-time.Second), false},
+		{"1m", now.Add(-time.Minute), false},
+		{"1.5m", now.Add(-(3 * time.Minute) / 2), false},
+		{"1h", now.Add(-time.Hour), false},
+		{"1d", now.Add(-time.Hour * 24), false},
+		{"1w", now.Add(-time.Hour * 24 * 7), false},
+		{"1M", now.Add(-time.Hour * 24 * 30), false},
+		{"1y", now.Add(-time.Hour * 24 * 365), false},
+		{"-1s", now.Add(time.Second), false},
+		{"1x", time.Time{}, true},
+		{"off", time.Time{}, false},
+		{"2022-03-26T17:48:19Z", time.Date(2022, 03, 26, 17, 48, 19, 0, time.UTC), false},
+		{"2022-03-26 17:48:19", time.Date(2022, 03, 26, 17, 48, 19, 0, time.UTC), false},
+		{"2022-03-26", time.Date(2022, 03, 26, 0, 0, 0, 0, time.UTC), false},
+	} {
+		parsedTime, err := ParseTime(test.in)
+		if test.err {
+			require.Error(t, err, test.in)
+		} else {
+			require.NoError(t, err, test.in)
+		}
+		assert.True(t, test.want.Equal(parsedTime), "%v should be parsed as %v instead of %v", test.in, test.want, parsedTime)
+	}
+}
+
+func TestTimeString(t *testing.T) {
+	for _, test := range []struct {
+		in   time.Time
+		want string
+	}{
+		{time.Time{}, "off"},
+		{time.Date(2022, 03, 26, 17, 48, 19, 0, time.UTC), "2022-03-26T17:48:19Z"},
+		{time.Date(2022, 03, 26, 17, 48, 19, 123000000, time.UTC), "2022-03-26T17:48:19.123Z"},
+	} {
+		got := Time(test.in).String()
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
+
+func TestTimeScan(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want Time
+	}{
+		{"off", Time{}},
+		{"2022-03-26 17:48:19", Time(time.Date(2022, 03, 26, 17, 48, 19, 0, time.UTC))},
+	} {
+		var got Time
+		n, err := fmt.Sscanln(test.in, &got)
+		require.NoError(t, err, test.in)
+		assert.Equal(t, 1, n, test.in)
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
@@ -0,0 +1,52 @@
+package fs
+
+import (
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/rclone/rclone/lib/random"
+)
+
+// PartialPrefix starts the name of a file while it is being uploaded.
+//
+// Unless --inplace is set the local, sftp and ftp backends upload to
+// a partial file next to the destination and rename it into place
+// when it is complete, so a failed or interrupted upload never leaves
+// a truncated file in place of the old one.
+//
+// The partial name made by PartialName has a random part so uploads
+// of the same file can't collide, and the prefix makes it a hidden
+// file which won't clash with the name of a real one. Partial files
+// are skipped in listings, and those left by a crash are removed
+// when the directory they are in is removed.
+const PartialPrefix = ".rclone-partial-"
+
+// partialRandomLen is the length of the random part of a partial name
+const partialRandomLen = 8
+
+// maxLeafLen is the longest leaf name most file systems allow in bytes
+const maxLeafLen = 255
+
+// partialRe matches the leaf names made by PartialName
+var partialRe = regexp.MustCompile(`^` + regexp.QuoteMeta(PartialPrefix) + `[a-z0-9]{8}-`)
+
+// PartialName returns a unique name to upload leaf to before renaming
+// it into place, of the form ".rclone-partial-<random>-leaf".
+//
+// leaf is shortened if need be so the result fits in 255 bytes.
+func PartialName(leaf string) string {
+	prefix := PartialPrefix + random.String(partialRandomLen) + "-"
+	if max := maxLeafLen - len(prefix); len(leaf) > max {
+		leaf = leaf[:max]
+		// don't leave part of a UTF-8 character at the end
+		for len(leaf) > 0 && !utf8.ValidString(leaf) {
+			leaf = leaf[:len(leaf)-1]
+		}
+	}
+	return prefix + leaf
+}
+
+// IsPartialName returns true if leaf is a name made by PartialName
+func IsPartialName(leaf string) bool {
+	return partialRe.MatchString(leaf)
+}
@@ -0,0 +1,45 @@
+package fs
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartialName(t *testing.T) {
+	name := PartialName("file.txt")
+	assert.True(t, strings.HasPrefix(name, PartialPrefix), name)
+	assert.True(t, strings.HasSuffix(name, "-file.txt"), name)
+	assert.Equal(t, len(PartialPrefix)+partialRandomLen+1+len("file.txt"), len(name))
+	assert.True(t, IsPartialName(name))
+	assert.NotEqual(t, name, PartialName("file.txt"))
+
+	// Long names are shortened on a character boundary
+	long := strings.Repeat("é", 200)
+	name = PartialName(long)
+	assert.True(t, len(name) <= maxLeafLen, len(name))
+	assert.True(t, utf8.ValidString(name))
+	assert.True(t, IsPartialName(name))
+}
+
+func TestIsPartialName(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want bool
+	}{
+		{"file.txt", false},
+		{"file.txt.partial", false},
+		{"log.20210101.partial", false},
+		{"file.txt.abcd1234.partial", false},
+		{".rclone-partial-abcd1234-file.txt", true},
+		{".rclone-partial-abcd1234-", true},
+		{".rclone-partial-ABCD1234-file.txt", false},
+		{".rclone-partial-abcd123-file.txt", false},
+		{".rclone-partial-file.txt", false},
+		{"x.rclone-partial-abcd1234-file.txt", false},
+	} {
+		assert.Equal(t, test.want, IsPartialName(test.in), test.in)
+	}
+}
@@ -0,0 +1,275 @@
+package fs
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/fspath"
+)
+
+// remoteOverrides are the global options which can be set in the
+// config of a remote, and how to parse them into a function which
+// sets them in a ConfigInfo.
+//
+// The names are the flag names with "-" replaced by "_" as used in
+// the config file.
+var remoteOverrides = map[string]func(value string) (func(ci *ConfigInfo), error){
+	"transfers": func(value string) (func(ci *ConfigInfo), error) {
+		transfers, err := parsePositiveInt(value)
+		return func(ci *ConfigInfo) { ci.Transfers = transfers }, err
+	},
+	"checkers": func(value string) (func(ci *ConfigInfo), error) {
+		checkers, err := parsePositiveInt(value)
+		return func(ci *ConfigInfo) { ci.Checkers = checkers }, err
+	},
+	"tpslimit": func(value string) (func(ci *ConfigInfo), error) {
+		tpsLimit, err := strconv.ParseFloat(value, 64)
+		return func(ci *ConfigInfo) { ci.TPSLimit = tpsLimit }, err
+	},
+	"tpslimit_burst": func(value string) (func(ci *ConfigInfo), error) {
+		tpsLimitBurst, err := strconv.Atoi(value)
+		return func(ci *ConfigInfo) { ci.TPSLimitBurst = tpsLimitBurst }, err
+	},
+	"bwlimit": func(value string) (func(ci *ConfigInfo), error) {
+		var bwLimit BwTimetable
+		err := bwLimit.Set(value)
+		return func(ci *ConfigInfo) { ci.BwLimit = bwLimit }, err
+	},
+	"low_level_retries": func(value string) (func(ci *ConfigInfo), error) {
+		lowLevelRetries, err := strconv.Atoi(value)
+		return func(ci *ConfigInfo) { ci.LowLevelRetries = lowLevelRetries }, err
+	},
+	"timeout": func(value string) (func(ci *ConfigInfo), error) {
+		timeout, err := ParseDuration(value)
+		return func(ci *ConfigInfo) { ci.Timeout = timeout }, err
+	},
+	"contimeout": func(value string) (func(ci *ConfigInfo), error) {
+		connectTimeout, err := ParseDuration(value)
+		return func(ci *ConfigInfo) { ci.ConnectTimeout = connectTimeout }, err
+	},
+	"header": func(value string) (func(ci *ConfigInfo), error) {
+		headers, err := parseHeaderList(value)
+		return func(ci *ConfigInfo) { ci.Headers = headers }, err
+	},
+	"header_upload": func(value string) (func(ci *ConfigInfo), error) {
+		headers, err := parseHeaderList(value)
+		return func(ci *ConfigInfo) { ci.UploadHeaders = headers }, err
+	},
+	"header_download": func(value string) (func(ci *ConfigInfo), error) {
+		headers, err := parseHeaderList(value)
+		return func(ci *ConfigInfo) { ci.DownloadHeaders = headers }, err
+	},
+}
+
+// parsePositiveInt parses value as an int which must be at least 1
+func parsePositiveInt(value string) (int, error) {
+	i, err := strconv.Atoi(value)
+	if err == nil && i < 1 {
+		err = errors.New("must be at least 1")
+	}
+	return i, err
+}
+
+// parseHeaderList parses a comma separated list of headers, each
+// looking like "Key: Value"
+func parseHeaderList(value string) (headers []*HTTPOption, err error) {
+	var list CommaSepList
+	err = list.Set(value)
+	if err != nil {
+		return nil, err
+	}
+	for _, header := range list {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) == 1 {
+			return nil, errors.Errorf("can't parse %q as an HTTP header - expecting a string like \"Content-Encoding: gzip\"", header)
+		}
+		headers = append(headers, &HTTPOption{
+			Key:   strings.TrimSpace(parts[0]),
+			Value: strings.TrimSpace(parts[1]),
+		})
+	}
+	return headers, nil
+}
+
+// remoteConfigContextKeyType is the type of the context key for the
+// overridden options
+type remoteConfigContextKeyType struct{}
+
+// Context key for the overridden options
+var remoteConfigContextKey = remoteConfigContextKeyType{}
+
+// overriddenConfig records which options were overridden in a context
+type overriddenConfig struct {
+	remote string              // name of the remote which overrode them last
+	names  map[string]struct{} // names of all the options overridden
+}
+
+// remoteConfig is the parsed global options set in the config of a
+// remote
+type remoteConfig struct {
+	names []string               // sorted names of the options set
+	sets  []func(ci *ConfigInfo) // functions to set them
+}
+
+// parseRemoteConfig parses the global options found in m.
+//
+// Options which are also options of the backend in fsInfo are left
+// to the backend.
+func parseRemoteConfig(fsInfo *RegInfo, m configmap.Getter) (*remoteConfig, error) {
+	var names []string
+	for name := range remoteOverrides {
+		if fsInfo != nil && fsInfo.Options.Get(name) != nil {
+			continue
+		}
+		if _, ok := m.Get(name); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	rc := &remoteConfig{names: names}
+	for _, name := range names {
+		value, _ := m.Get(name)
+		set, err := remoteOverrides[name](strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.Wrapf(err, "bad value for %q in remote config", name)
+		}
+		rc.sets = append(rc.sets, set)
+	}
+	return rc, nil
+}
+
+// apply returns ci with the options in rc set. If there are any ci
+// is copied first, otherwise it is returned unchanged.
+func (rc *remoteConfig) apply(ci *ConfigInfo) *ConfigInfo {
+	if len(rc.sets) == 0 {
+		return ci
+	}
+	ciCopy := new(ConfigInfo)
+	*ciCopy = *ci
+	for _, set := range rc.sets {
+		set(ciCopy)
+	}
+	return ciCopy
+}
+
+// Parsed remote configs by the name of the remote as returned by
+// Fs.Name() so they are only parsed once
+var (
+	remoteConfigsMu sync.Mutex
+	remoteConfigs   = map[string]*remoteConfig{}
+)
+
+// AddRemoteConfig returns a context with the config in ctx
+// overridden by any global options, such as transfers or bwlimit, set
+// in the config of the remote called name read from m.
+//
+// If no options are overridden ctx is returned unchanged.
+func AddRemoteConfig(ctx context.Context, name string, fsInfo *RegInfo, m configmap.Getter) (context.Context, error) {
+	rc, err := parseRemoteConfig(fsInfo, m)
+	if err != nil {
+		return ctx, err
+	}
+	return addRemoteConfig(ctx, name, rc), nil
+}
+
+// addRemoteConfig returns a context with the config in ctx
+// overridden by the options in rc for the remote called name, or ctx
+// if there are none.
+func addRemoteConfig(ctx context.Context, name string, rc *remoteConfig) context.Context {
+	if len(rc.names) == 0 {
+		return ctx
+	}
+	Debugf(nil, "Using config overridden by the remote for %s", strings.Join(rc.names, ", "))
+	// Keep the names overridden by any outer remote
+	overridden := overriddenConfig{
+		remote: name,
+		names:  map[string]struct{}{},
+	}
+	if old, ok := ctx.Value(remoteConfigContextKey).(overriddenConfig); ok {
+		for name := range old.names {
+			overridden.names[name] = struct{}{}
+		}
+	}
+	for _, name := range rc.names {
+		overridden.names[name] = struct{}{}
+	}
+	ctx = context.WithValue(ctx, configContextKey, rc.apply(GetConfig(ctx)))
+	ctx = context.WithValue(ctx, remoteConfigContextKey, overridden)
+	return ctx
+}
+
+// IsOverridden returns whether the global option name, e.g.
+// "tpslimit", was overridden by the config of a remote in ctx as set
+// by AddRemoteConfig.
+func IsOverridden(ctx context.Context, name string) bool {
+	overridden, _ := ctx.Value(remoteConfigContextKey).(overriddenConfig)
+	_, ok := overridden.names[name]
+	return ok
+}
+
+// OverridingRemote returns the name of the remote whose config last
+// overrode the global options in ctx, or "" if none did.
+//
+// Use this to share things made from the overridden options between
+// all the users of a remote.
+func OverridingRemote(ctx context.Context) string {
+	overridden, _ := ctx.Value(remoteConfigContextKey).(overriddenConfig)
+	return overridden.remote
+}
+
+// RemoteConfig returns the config which f uses - the config in ctx
+// with any overrides from the config of f applied - and the names of
+// the options which were overridden.
+//
+// The overrides are the ones parsed when f was made by NewFs, or are
+// parsed on first use if f wasn't.
+//
+// Use this where the global options need to be tuned for the remotes
+// in use, for example when choosing how many transfers to run.
+func RemoteConfig(ctx context.Context, f Info) (ci *ConfigInfo, overridden []string) {
+	ci = GetConfig(ctx)
+	if f == nil {
+		return ci, nil
+	}
+	remoteConfigsMu.Lock()
+	rc, ok := remoteConfigs[f.Name()]
+	remoteConfigsMu.Unlock()
+	if !ok {
+		rc = lookupRemoteConfig(f.Name())
+		setRemoteConfig(f.Name(), rc)
+	}
+	return rc.apply(ci), rc.names
+}
+
+// lookupRemoteConfig reads and parses the overrides in the config of
+// the remote called name
+func lookupRemoteConfig(name string) *remoteConfig {
+	configName, _, err := fspath.SplitConfigName(name)
+	if err != nil {
+		return &remoteConfig{}
+	}
+	var fsInfo *RegInfo
+	if strings.HasPrefix(configName, ":") {
+		fsInfo, _ = Find(configName[1:])
+	} else if fsName, ok := ConfigMap(nil, configName).Get("type"); ok {
+		fsInfo, _ = Find(fsName)
+	}
+	rc, err := parseRemoteConfig(fsInfo, ConfigMap(nil, name))
+	if err != nil {
+		// NewFs will have reported this already
+		return &remoteConfig{}
+	}
+	return rc
+}
+
+// setRemoteConfig remembers the overrides for the remote called name
+func setRemoteConfig(name string, rc *remoteConfig) {
+	remoteConfigsMu.Lock()
+	remoteConfigs[name] = rc
+	remoteConfigsMu.Unlock()
+}
@@ -0,0 +1,149 @@
+package fs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddRemoteConfig(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := AddConfig(ctx)
+	ci.Transfers = 4
+	ci.Checkers = 8
+
+	// Nothing set leaves the context alone
+	newCtx, err := AddRemoteConfig(ctx, "remote", nil, configmap.Simple{"key": "value"})
+	require.NoError(t, err)
+	assert.Equal(t, ctx, newCtx)
+	assert.False(t, IsOverridden(newCtx, "transfers"))
+	assert.Equal(t, "", OverridingRemote(newCtx))
+
+	newCtx, err = AddRemoteConfig(ctx, "remote", nil, configmap.Simple{
+		"transfers":         "2",
+		"tpslimit":          "10.5",
+		"bwlimit":           "1M",
+		"low_level_retries": "3",
+		"timeout":           "1m",
+		"contimeout":        "10s",
+		"header":            `X-A: 1,"X-B: 2,3"`,
+		"header_upload":     "Content-Encoding: gzip",
+	})
+	require.NoError(t, err)
+	newCi := GetConfig(newCtx)
+	assert.Equal(t, 2, newCi.Transfers)
+	assert.Equal(t, 8, newCi.Checkers)
+	assert.Equal(t, 10.5, newCi.TPSLimit)
+	assert.Equal(t, SizeSuffix(1024*1024), newCi.BwLimit.LimitAt(time.Now()).Bandwidth)
+	assert.Equal(t, 3, newCi.LowLevelRetries)
+	assert.Equal(t, time.Minute, newCi.Timeout)
+	assert.Equal(t, 10*time.Second, newCi.ConnectTimeout)
+	assert.Equal(t, []*HTTPOption{{Key: "X-A", Value: "1"}, {Key: "X-B", Value: "2,3"}}, newCi.Headers)
+	assert.Equal(t, []*HTTPOption{{Key: "Content-Encoding", Value: "gzip"}}, newCi.UploadHeaders)
+	assert.True(t, IsOverridden(newCtx, "transfers"))
+	assert.True(t, IsOverridden(newCtx, "header"))
+	assert.False(t, IsOverridden(newCtx, "checkers"))
+	assert.Equal(t, "remote", OverridingRemote(newCtx))
+
+	// The original config is unchanged
+	assert.Equal(t, 4, ci.Transfers)
+	assert.Equal(t, 0.0, ci.TPSLimit)
+
+	// Overrides of an outer remote are kept
+	newCtx, err = AddRemoteConfig(newCtx, "inner", nil, configmap.Simple{"checkers": "1"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, GetConfig(newCtx).Transfers)
+	assert.Equal(t, 1, GetConfig(newCtx).Checkers)
+	assert.True(t, IsOverridden(newCtx, "transfers"))
+	assert.True(t, IsOverridden(newCtx, "checkers"))
+	assert.Equal(t, "inner", OverridingRemote(newCtx))
+
+	// Options of the backend are left alone
+	fsInfo := &RegInfo{
+		Name:    "local",
+		Options: Options{{Name: "timeout"}},
+	}
+	newCtx, err = AddRemoteConfig(ctx, "remote", fsInfo, configmap.Simple{"timeout": "potato", "checkers": "3"})
+	require.NoError(t, err)
+	assert.Equal(t, ci.Timeout, GetConfig(newCtx).Timeout)
+	assert.Equal(t, 3, GetConfig(newCtx).Checkers)
+	assert.False(t, IsOverridden(newCtx, "timeout"))
+
+	// Bad values
+	for _, m := range []configmap.Simple{
+		{"transfers": "0"},
+		{"checkers": "potato"},
+		{"tpslimit": "fast"},
+		{"timeout": "soon"},
+		{"bwlimit": "1Q"},
+		{"header": "no colon"},
+	} {
+		_, err = AddRemoteConfig(ctx, "remote", nil, m)
+		assert.Error(t, err, m)
+	}
+}
+
+// remoteConfigInfo is a minimal Info for testing RemoteConfig
+type remoteConfigInfo string
+
+func (i remoteConfigInfo) Name() string             { return string(i) }
+func (i remoteConfigInfo) Root() string             { return "" }
+func (i remoteConfigInfo) String() string           { return string(i) }
+func (i remoteConfigInfo) Precision() time.Duration { return time.Second }
+func (i remoteConfigInfo) Hashes() hash.Set         { return hash.Set(hash.None) }
+func (i remoteConfigInfo) Features() *Features      { return &Features{} }
+
+func TestRemoteConfig(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := AddConfig(ctx)
+	ci.Transfers = 4
+
+	oldConfigFileGet := ConfigFileGet
+	ConfigFileGet = func(section, key string) (string, bool) {
+		if section == "slow" && key == "transfers" {
+			return "1", true
+		}
+		return "", false
+	}
+	defer func() {
+		ConfigFileGet = oldConfigFileGet
+	}()
+
+	for _, test := range []struct {
+		name          string
+		wantTransfers int
+		wantOverride  []string
+	}{
+		{"fast", 4, nil},
+		{"slow", 1, []string{"transfers"}},
+		{"slow,transfers=2", 2, []string{"transfers"}},
+		{"fast,transfers=3,checkers=5", 3, []string{"checkers", "transfers"}},
+		{"slow,transfers=0", 4, nil}, // bad values are ignored
+	} {
+		gotCi, gotOverride := RemoteConfig(ctx, remoteConfigInfo(test.name))
+		assert.Equal(t, test.wantTransfers, gotCi.Transfers, test.name)
+		assert.Equal(t, test.wantOverride, gotOverride, test.name)
+	}
+	assert.Equal(t, 4, ci.Transfers)
+
+	// The parsed config is remembered by the name of the remote
+	ConfigFileGet = oldConfigFileGet
+	gotCi, gotOverride := RemoteConfig(ctx, remoteConfigInfo("slow"))
+	assert.Equal(t, 1, gotCi.Transfers)
+	assert.Equal(t, []string{"transfers"}, gotOverride)
+
+	// NewFs replaces it so changes to the config are picked up
+	setRemoteConfig("slow", &remoteConfig{})
+	gotCi, gotOverride = RemoteConfig(ctx, remoteConfigInfo("slow"))
+	assert.Equal(t, 4, gotCi.Transfers)
+	assert.Nil(t, gotOverride)
+
+	gotCi, gotOverride = RemoteConfig(ctx, nil)
+	assert.Equal(t, ci, gotCi)
+	assert.Nil(t, gotOverride)
+}
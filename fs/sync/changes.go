@@ -0,0 +1,66 @@
+package sync
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/changes"
+	"github.com/rclone/rclone/fs/fserrors"
+)
+
+// runChanges syncs, copies or moves just the paths in the
+// --files-from-changes journal, or everything if a full sync is due,
+// removing the paths from the journal if it succeeds.
+func runChanges(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool, copyEmptySrcDirs bool) error {
+	ci := fs.GetConfig(ctx)
+	journal := changes.NewJournal(ci.FilesFromChanges)
+	set, err := journal.Take()
+	if err != nil {
+		return fserrors.FatalError(errors.Wrap(err, "--files-from-changes"))
+	}
+
+	full := set.HasRoot()
+	if full {
+		fs.Infof(fsrc, "Doing a full sync as everything may have changed")
+	} else if ci.FullSyncInterval > 0 && ci.FilesFromChanges != "-" {
+		last := journal.LastFullSync()
+		if last.IsZero() {
+			fs.Infof(fsrc, "Doing a full sync as there hasn't been one with %q", journal)
+			full = true
+		} else if since := time.Since(last); since >= ci.FullSyncInterval {
+			fs.Infof(fsrc, "Doing a full sync as the last was %v ago", since.Truncate(time.Second))
+			full = true
+		}
+	}
+
+	var paths []string
+	if !full {
+		paths = set.Paths()
+		if len(paths) == 0 {
+			fs.Infof(fsrc, "No changes in %q to sync", journal)
+			if ci.DryRun {
+				return nil
+			}
+			return journal.Done()
+		}
+		fs.Infof(fsrc, "Syncing %d changed paths from %q", len(paths), journal)
+	}
+
+	err = runSyncCopyMovePaths(ctx, fdst, fsrc, deleteMode, DoMove, deleteEmptySrcDirs, copyEmptySrcDirs, paths)
+	if err != nil {
+		// Leave the paths in the journal to be tried again
+		return err
+	}
+	if ci.DryRun {
+		return nil
+	}
+	if full {
+		err = journal.FullSyncDone()
+		if err != nil {
+			return err
+		}
+	}
+	return journal.Done()
+}
@@ -0,0 +1,540 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/operations"
+)
+
+// planVersion is the version of the plan format written
+const planVersion = 1
+
+// Actions in a plan
+const (
+	PlanCopy   = "copy"   // copy a file which isn't in the destination
+	PlanUpdate = "update" // copy a file over a different one in the destination
+	PlanMove   = "move"   // move a file to the destination
+	PlanDelete = "delete" // delete a file
+	PlanRename = "rename" // rename a file in the destination
+	PlanMkdir  = "mkdir"  // make a directory in the destination
+	PlanRmdir  = "rmdir"  // remove a directory if it is empty
+)
+
+// The phases a plan is applied in, in order
+const (
+	phaseRename = iota
+	phaseTransfer
+	phaseDelete
+	phaseMkdir
+	phaseRmdir
+	numPhases
+)
+
+// planPhases maps each action to the phase it is applied in
+var planPhases = map[string]int{
+	PlanRename: phaseRename,
+	PlanCopy:   phaseTransfer,
+	PlanUpdate: phaseTransfer,
+	PlanMove:   phaseTransfer,
+	PlanDelete: phaseDelete,
+	PlanMkdir:  phaseMkdir,
+	PlanRmdir:  phaseRmdir,
+}
+
+// Plan is what sync, copy or move decided to do. It is made by
+// running them with a context from WithPlan and can be written out,
+// reviewed or edited, read back and applied with ApplyPlan.
+type Plan struct {
+	Version   int        // version of the plan format
+	Operation string     // sync, copy or move
+	Src       string     // the source the plan was made for
+	Dst       string     // the destination the plan was made for
+	Items     []PlanItem // what to do
+
+	mu sync.Mutex
+}
+
+// PlanItem is a single action in a Plan
+//
+// The fingerprints are of the files when the plan was made and the
+// action is only applied if they still match. An empty fingerprint
+// means the file didn't exist.
+type PlanItem struct {
+	Action         string
+	Path           string // path of the file or directory
+	From           string `json:",omitempty"` // the old path of renamed files
+	Source         bool   `json:",omitempty"` // set if the action is on the source, eg deleting moved files
+	SrcFingerprint string `json:",omitempty"` // of the file at Path in the source
+	DstFingerprint string `json:",omitempty"` // of the file at Path, or From if renamed, in the destination
+}
+
+// NewPlan makes a new empty Plan
+func NewPlan() *Plan {
+	return &Plan{Version: planVersion}
+}
+
+// ReadPlan reads a Plan written by Plan.Write from in
+func ReadPlan(in io.Reader) (*Plan, error) {
+	plan := new(Plan)
+	err := json.NewDecoder(in).Decode(plan)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read plan")
+	}
+	if plan.Version != planVersion {
+		return nil, errors.Errorf("can't read plan version %d, expecting %d", plan.Version, planVersion)
+	}
+	for i := range plan.Items {
+		err = plan.Items[i].check()
+		if err != nil {
+			return nil, errors.Wrapf(err, "bad plan item %d", i+1)
+		}
+	}
+	return plan, nil
+}
+
+// check the item is one which can be applied
+func (item *PlanItem) check() error {
+	if _, ok := planPhases[item.Action]; !ok {
+		return errors.Errorf("unknown action %q", item.Action)
+	}
+	if item.Path == "" {
+		return errors.Errorf("%s needs a Path", item.Action)
+	}
+	if (item.Action == PlanRename) != (item.From != "") {
+		return errors.New("From must be set for a rename and only for a rename")
+	}
+	if item.Source && item.Action != PlanDelete && item.Action != PlanRmdir {
+		return errors.Errorf("can't %s in the source", item.Action)
+	}
+	return nil
+}
+
+// Write the plan to out as JSON, sorted into the order it is applied
+func (p *Plan) Write(out io.Writer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sortPlanItems(p.Items)
+	buf, err := json.MarshalIndent(p, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode plan")
+	}
+	buf = append(buf, '\n')
+	_, err = out.Write(buf)
+	return err
+}
+
+// sortPlanItems sorts items into the order they are applied in
+//
+// Directories are removed starting from the longest path so the
+// parents are empty when they are removed.
+func sortPlanItems(items []PlanItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := &items[i], &items[j]
+		phaseA, phaseB := planPhases[a.Action], planPhases[b.Action]
+		switch {
+		case phaseA != phaseB:
+			return phaseA < phaseB
+		case phaseA == phaseRmdir:
+			return a.Path > b.Path
+		}
+		return a.Path < b.Path
+	})
+}
+
+type planKey struct{}
+
+// WithPlan returns a new context which makes sync, copy and move
+// record what they would do in plan instead of doing it, as if
+// --dry-run was set.
+func WithPlan(ctx context.Context, plan *Plan) context.Context {
+	ctx, ci := fs.AddConfig(ctx)
+	ci.DryRun = true
+	return context.WithValue(ctx, planKey{}, plan)
+}
+
+// getPlan returns the Plan in ctx or nil if there isn't one
+func getPlan(ctx context.Context) *Plan {
+	plan, _ := ctx.Value(planKey{}).(*Plan)
+	return plan
+}
+
+// begin records which operation the plan is for
+func (p *Plan) begin(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case DoMove:
+		p.Operation = "move"
+	case deleteMode != fs.DeleteModeOff:
+		p.Operation = "sync"
+	default:
+		p.Operation = "copy"
+	}
+	p.Src = fs.ConfigString(fsrc)
+	p.Dst = fs.ConfigString(fdst)
+}
+
+// add item to the plan
+func (p *Plan) add(item PlanItem) {
+	p.mu.Lock()
+	p.Items = append(p.Items, item)
+	p.mu.Unlock()
+}
+
+// fingerprint returns the fingerprint of o to record in the plan or
+// "" if o is nil
+func fingerprint(ctx context.Context, o fs.Object) string {
+	if o == nil {
+		return ""
+	}
+	return fs.Fingerprint(ctx, o, false)
+}
+
+// addTransfer plans copying or moving src over dst, which may be nil
+func (p *Plan) addTransfer(ctx context.Context, DoMove bool, dst, src fs.Object) {
+	if p == nil {
+		return
+	}
+	action := PlanUpdate
+	switch {
+	case DoMove:
+		action = PlanMove
+	case dst == nil:
+		action = PlanCopy
+	}
+	p.add(PlanItem{
+		Action:         action,
+		Path:           src.Remote(),
+		SrcFingerprint: fingerprint(ctx, src),
+		DstFingerprint: fingerprint(ctx, dst),
+	})
+}
+
+// addDelete plans deleting o from the destination, or the source if
+// source is set
+func (p *Plan) addDelete(ctx context.Context, o fs.Object, source bool) {
+	if p == nil {
+		return
+	}
+	item := PlanItem{
+		Action: PlanDelete,
+		Path:   o.Remote(),
+		Source: source,
+	}
+	if source {
+		item.SrcFingerprint = fingerprint(ctx, o)
+	} else {
+		item.DstFingerprint = fingerprint(ctx, o)
+	}
+	p.add(item)
+}
+
+// addRename plans renaming dst in the destination to the path of src
+func (p *Plan) addRename(ctx context.Context, dst, src fs.Object) {
+	if p == nil {
+		return
+	}
+	p.add(PlanItem{
+		Action:         PlanRename,
+		Path:           src.Remote(),
+		From:           dst.Remote(),
+		SrcFingerprint: fingerprint(ctx, src),
+		DstFingerprint: fingerprint(ctx, dst),
+	})
+}
+
+// addDirs plans action on the directories in entries
+func (p *Plan) addDirs(action string, entries map[string]fs.DirEntry, source bool) {
+	if p == nil {
+		return
+	}
+	for _, entry := range entries {
+		if _, ok := entry.(fs.Directory); ok {
+			p.add(PlanItem{
+				Action: action,
+				Path:   entry.Remote(),
+				Source: source,
+			})
+		}
+	}
+}
+
+// planApplier applies a plan
+type planApplier struct {
+	ctx       context.Context
+	ci        *fs.ConfigInfo
+	fdst      fs.Fs
+	fsrc      fs.Fs
+	backupDir fs.Fs
+
+	mu  sync.Mutex
+	err error // last error applying the plan
+}
+
+// ApplyPlan does what plan says to fdst and fsrc, which must be the
+// remotes it was made for.
+//
+// Each file is checked against the fingerprints in the plan first and
+// is left alone if it has changed since the plan was made. Files the
+// plan has already been applied to are skipped, so a plan which
+// failed part way through can be applied again.
+//
+// Renames are done first, then copies and moves, then deletions and
+// finally directories are made and removed. Nothing is deleted if
+// there were errors before, unless --ignore-errors is set.
+func ApplyPlan(ctx context.Context, fdst, fsrc fs.Fs, plan *Plan) error {
+	if src, dst := fs.ConfigString(fsrc), fs.ConfigString(fdst); plan.Src != src || plan.Dst != dst {
+		return fserrors.FatalError(errors.Errorf("plan is for %q to %q not %q to %q", plan.Src, plan.Dst, src, dst))
+	}
+	ctx = withRemoteConcurrency(ctx, fdst, fsrc)
+	a := &planApplier{
+		ctx:  ctx,
+		ci:   fs.GetConfig(ctx),
+		fdst: fdst,
+		fsrc: fsrc,
+	}
+	if a.ci.BackupDir != "" || a.ci.Suffix != "" {
+		var err error
+		a.backupDir, err = operations.BackupDir(ctx, fdst, fsrc, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	var phases [numPhases][]PlanItem
+	for _, item := range plan.Items {
+		if err := item.check(); err != nil {
+			return fserrors.FatalError(errors.Wrap(err, "bad plan item"))
+		}
+		phase := planPhases[item.Action]
+		phases[phase] = append(phases[phase], item)
+	}
+	for phase, items := range phases {
+		if len(items) == 0 {
+			continue
+		}
+		sortPlanItems(items)
+		switch phase {
+		case phaseDelete:
+			if accounting.Stats(ctx).Errored() && !a.ci.IgnoreErrors {
+				fs.Errorf(fdst, "%v", fs.ErrorNotDeleting)
+				a.processError(fs.ErrorNotDeleting)
+				continue
+			}
+		case phaseRmdir:
+			if accounting.Stats(ctx).Errored() && !a.ci.IgnoreErrors {
+				fs.Errorf(fdst, "%v", fs.ErrorNotDeletingDirs)
+				a.processError(fs.ErrorNotDeletingDirs)
+				continue
+			}
+		}
+		if phase == phaseMkdir || phase == phaseRmdir {
+			// Directories are done in order
+			for _, item := range items {
+				a.apply(item)
+			}
+		} else {
+			a.applyParallel(items)
+		}
+	}
+	a.processError(ctx.Err())
+	return a.err
+}
+
+// processError records err if it is set
+func (a *planApplier) processError(err error) {
+	if err == nil {
+		return
+	}
+	a.mu.Lock()
+	a.err = err
+	a.mu.Unlock()
+}
+
+// applyParallel applies items using --transfers at once
+func (a *planApplier) applyParallel(items []PlanItem) {
+	in := make(chan PlanItem)
+	var wg sync.WaitGroup
+	wg.Add(a.ci.Transfers)
+	for i := 0; i < a.ci.Transfers; i++ {
+		go func() {
+			defer wg.Done()
+			for item := range in {
+				a.apply(item)
+			}
+		}()
+	}
+	for _, item := range items {
+		if a.ctx.Err() != nil {
+			break
+		}
+		in <- item
+	}
+	close(in)
+	wg.Wait()
+}
+
+// apply a single item of the plan
+func (a *planApplier) apply(item PlanItem) {
+	var err error
+	switch item.Action {
+	case PlanCopy, PlanUpdate, PlanMove:
+		err = a.transfer(item)
+	case PlanDelete:
+		err = a.delete(item)
+	case PlanRename:
+		err = a.rename(item)
+	case PlanMkdir:
+		err = operations.Mkdir(a.ctx, a.fdst, item.Path)
+		if err != nil {
+			fs.Errorf(fs.LogDirName(a.fdst, item.Path), "Failed to Mkdir: %v", err)
+		}
+	case PlanRmdir:
+		f := a.fdst
+		if item.Source {
+			f = a.fsrc
+		}
+		// TryRmdir only deletes empty directories
+		err = operations.TryRmdir(a.ctx, f, item.Path)
+		if err != nil {
+			fs.Debugf(fs.LogDirName(f, item.Path), "Failed to Rmdir: %v", err)
+			err = nil
+		}
+	}
+	a.processError(err)
+}
+
+// newObject finds remote in f returning a nil Object if it doesn't
+// exist
+func newObject(ctx context.Context, f fs.Fs, remote string) (fs.Object, error) {
+	o, err := f.NewObject(ctx, remote)
+	if err == fs.ErrorObjectNotFound {
+		return nil, nil
+	}
+	return o, err
+}
+
+// matches returns whether o, which may be nil, still has the
+// fingerprint recorded in the plan
+func matches(ctx context.Context, o fs.Object, fingerprint string) bool {
+	if o == nil {
+		return fingerprint == ""
+	}
+	return fingerprint != "" && fs.Fingerprint(ctx, o, false) == fingerprint
+}
+
+// changed returns the error for a file at remote which has changed
+// since the plan was made so item can't be applied
+func changed(item PlanItem, remote string, source bool) error {
+	where := "destination"
+	if source {
+		where = "source"
+	}
+	err := fs.CountError(errors.Errorf("not doing %s as %s file has changed since the plan was made", item.Action, where))
+	fs.Errorf(remote, "%v", err)
+	return err
+}
+
+// transfer copies or moves the file in item
+func (a *planApplier) transfer(item PlanItem) error {
+	src, err := newObject(a.ctx, a.fsrc, item.Path)
+	if err != nil {
+		return err
+	}
+	dst, err := newObject(a.ctx, a.fdst, item.Path)
+	if err != nil {
+		return err
+	}
+	if src == nil && dst != nil && item.Action == PlanMove {
+		fs.Debugf(dst, "Already moved")
+		return nil
+	}
+	if !matches(a.ctx, src, item.SrcFingerprint) || src == nil {
+		return changed(item, item.Path, true)
+	}
+	if !matches(a.ctx, dst, item.DstFingerprint) {
+		if dst != nil && !operations.NeedTransfer(a.ctx, dst, src) {
+			fs.Debugf(dst, "Already up to date")
+			if item.Action == PlanMove {
+				return operations.DeleteFile(a.ctx, src)
+			}
+			return nil
+		}
+		return changed(item, item.Path, false)
+	}
+	if dst != nil && a.backupDir != nil {
+		err = operations.MoveBackupDir(a.ctx, a.backupDir, dst)
+		if err != nil {
+			return err
+		}
+		dst = nil
+	}
+	if item.Action == PlanMove {
+		_, err = operations.Move(a.ctx, a.fdst, dst, item.Path, src)
+	} else {
+		_, err = operations.Copy(a.ctx, a.fdst, dst, item.Path, src)
+	}
+	return err
+}
+
+// delete deletes the file in item
+func (a *planApplier) delete(item PlanItem) error {
+	f, fingerprint := a.fdst, item.DstFingerprint
+	if item.Source {
+		f, fingerprint = a.fsrc, item.SrcFingerprint
+	}
+	o, err := newObject(a.ctx, f, item.Path)
+	if err != nil {
+		return err
+	}
+	if o == nil {
+		fs.Debugf(fs.LogDirName(f, item.Path), "Already deleted")
+		return nil
+	}
+	if !matches(a.ctx, o, fingerprint) {
+		return changed(item, item.Path, item.Source)
+	}
+	if item.Source {
+		return operations.DeleteFile(a.ctx, o)
+	}
+	return operations.DeleteFileWithBackupDir(a.ctx, o, a.backupDir)
+}
+
+// rename renames the file in item in the destination
+func (a *planApplier) rename(item PlanItem) error {
+	src, err := newObject(a.ctx, a.fsrc, item.Path)
+	if err != nil {
+		return err
+	}
+	if !matches(a.ctx, src, item.SrcFingerprint) {
+		return changed(item, item.Path, true)
+	}
+	dst, err := newObject(a.ctx, a.fdst, item.From)
+	if err != nil {
+		return err
+	}
+	existing, err := newObject(a.ctx, a.fdst, item.Path)
+	if err != nil {
+		return err
+	}
+	if dst == nil && existing != nil && matches(a.ctx, existing, item.DstFingerprint) {
+		fs.Debugf(existing, "Already renamed from %q", item.From)
+		return nil
+	}
+	if dst == nil || existing != nil || !matches(a.ctx, dst, item.DstFingerprint) {
+		return changed(item, item.From, false)
+	}
+	_, err = operations.Move(a.ctx, a.fdst, nil, item.Path, dst)
+	if err == nil {
+		fs.Infof(item.Path, "Renamed from %q", item.From)
+	}
+	return err
+}
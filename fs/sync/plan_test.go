@@ -0,0 +1,230 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// planActions returns the action and path of each item in plan
+func planActions(plan *Plan) (actions []string) {
+	for _, item := range plan.Items {
+		action := item.Action + " " + item.Path
+		if item.Source {
+			action += " (source)"
+		}
+		actions = append(actions, action)
+	}
+	return actions
+}
+
+// roundTrip writes plan out and reads it back
+func roundTrip(t *testing.T, plan *Plan) *Plan {
+	var buf bytes.Buffer
+	require.NoError(t, plan.Write(&buf))
+	newPlan, err := ReadPlan(&buf)
+	require.NoError(t, err)
+	return newPlan
+}
+
+func TestPlanSync(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	file1 := r.WriteFile("same", "same file", t1)
+	r.WriteObject(ctx, "same", "same file", t1)
+	file2 := r.WriteFile("new", "new file", t1)
+	file3 := r.WriteFile("dir/changed", "changed file", t2)
+	r.WriteObject(ctx, "dir/changed", "old", t1)
+	file4 := r.WriteObject(ctx, "gone/deleted", "deleted file", t1)
+	require.NoError(t, operations.Mkdir(ctx, r.Flocal, "empty"))
+
+	plan := NewPlan()
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, Sync(WithPlan(ctx, plan), r.Fremote, r.Flocal, true))
+
+	// Nothing has been done yet
+	fstest.CheckItems(t, r.Fremote, file1, fstest.NewItem("dir/changed", "old", t1), file4)
+
+	plan = roundTrip(t, plan)
+	assert.Equal(t, "sync", plan.Operation)
+	assert.Equal(t, fs.ConfigString(r.Flocal), plan.Src)
+	assert.Equal(t, fs.ConfigString(r.Fremote), plan.Dst)
+	assert.Equal(t, []string{
+		"update dir/changed",
+		"copy new",
+		"delete gone/deleted",
+		"mkdir empty",
+		"rmdir gone",
+	}, planActions(plan))
+	for _, item := range plan.Items[:3] {
+		if item.Action == PlanCopy {
+			assert.Equal(t, "", item.DstFingerprint)
+		} else {
+			assert.NotEqual(t, "", item.DstFingerprint, item.Path)
+		}
+	}
+
+	// Apply the plan
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, ApplyPlan(ctx, r.Fremote, r.Flocal, plan))
+	fstest.CheckListingWithPrecision(t, r.Fremote,
+		[]fstest.Item{file1, file2, file3},
+		[]string{"dir", "empty"},
+		fs.GetModifyWindow(ctx, r.Fremote))
+
+	// Applying it again does nothing
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, ApplyPlan(ctx, r.Fremote, r.Flocal, plan))
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+}
+
+func TestPlanChanged(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	r.WriteFile("new", "new file", t1)
+	file2 := r.WriteFile("other", "other file", t1)
+	file3 := r.WriteObject(ctx, "gone", "deleted file", t1)
+
+	plan := NewPlan()
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, Sync(WithPlan(ctx, plan), r.Fremote, r.Flocal, false))
+	assert.Equal(t, []string{"copy new", "copy other", "delete gone"}, planActions(plan))
+
+	// Change the source after the plan was made
+	r.WriteFile("new", "new file changed", t2)
+
+	accounting.GlobalStats().ResetCounters()
+	err := ApplyPlan(ctx, r.Fremote, r.Flocal, plan)
+	require.Error(t, err)
+	assert.Equal(t, fs.ErrorNotDeleting, err)
+	fstest.CheckItems(t, r.Fremote, file2, file3)
+
+	// Nothing changes when applied to the wrong remotes
+	err = ApplyPlan(ctx, r.Flocal, r.Fremote, plan)
+	require.Error(t, err)
+	assert.True(t, fserrors.IsFatalError(err))
+	assert.True(t, strings.Contains(err.Error(), "plan is for"))
+}
+
+func TestPlanCopyDest(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	// Use the memory backend as --copy-dest needs server-side copy
+	r.WriteFile("same", "same file", t1)
+	r.WriteFile("copied", "copied file", t1)
+	fdst, err := fs.NewFs(ctx, ":memory:plancopydest/dst")
+	require.NoError(t, err)
+	fcopyDest, err := fs.NewFs(ctx, ":memory:plancopydest/copydest")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, operations.Purge(ctx, fdst, ""))
+		require.NoError(t, operations.Purge(ctx, fcopyDest, ""))
+	}()
+	for _, item := range []struct {
+		f        fs.Fs
+		remote   string
+		contents string
+	}{
+		{fdst, "same", "same file"},
+		{fcopyDest, "same", "same file"},
+		{fcopyDest, "copied", "copied file"},
+	} {
+		_, err := operations.Rcat(ctx, item.f, item.remote, ioutil.NopCloser(strings.NewReader(item.contents)), t1)
+		require.NoError(t, err)
+	}
+
+	ctx, ci := fs.AddConfig(ctx)
+	ci.CopyDest = []string{fs.ConfigString(fcopyDest)}
+	plan := NewPlan()
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, CopyDir(WithPlan(ctx, plan), fdst, r.Flocal, false))
+
+	// Files already in the destination aren't copied again
+	assert.Equal(t, []string{"copy copied"}, planActions(plan))
+}
+
+func TestPlanMove(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	file1 := r.WriteFile("sub/moved", "moved file", t1)
+	r.WriteFile("same", "same file", t1)
+	file2 := r.WriteObject(ctx, "same", "same file", t1)
+
+	plan := NewPlan()
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, MoveDir(WithPlan(ctx, plan), r.Fremote, r.Flocal, true, false))
+	plan = roundTrip(t, plan)
+	assert.Equal(t, "move", plan.Operation)
+	assert.Equal(t, []string{"move sub/moved", "delete same (source)", "rmdir sub (source)"}, planActions(plan))
+
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, ApplyPlan(ctx, r.Fremote, r.Flocal, plan))
+	fstest.CheckListingWithPrecision(t, r.Flocal, nil, []string{}, fs.GetModifyWindow(ctx, r.Flocal))
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+}
+
+func TestPlanRename(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	if !operations.CanServerSideMove(r.Fremote) {
+		t.Skip("Skipping test as remote does not support server-side move")
+	}
+	ci.TrackRenames = true
+
+	file1 := r.WriteFile("renamed", "renamed file", t1)
+	r.WriteObject(ctx, "original", "renamed file", t1)
+
+	plan := NewPlan()
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, Sync(WithPlan(ctx, plan), r.Fremote, r.Flocal, false))
+	require.Equal(t, []string{"rename renamed"}, planActions(plan))
+	assert.Equal(t, "original", plan.Items[0].From)
+
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, ApplyPlan(ctx, r.Fremote, r.Flocal, plan))
+	fstest.CheckItems(t, r.Fremote, file1)
+	assert.Equal(t, int64(0), accounting.GlobalStats().GetTransfers())
+}
+
+func TestReadPlan(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want string
+	}{
+		{`{"Version":1,"Items":[{"Action":"copy","Path":"a"}]}`, ""},
+		{`{"Version":2}`, "can't read plan version 2"},
+		{`{"Version":1,"Items":[{"Action":"zap","Path":"a"}]}`, `bad plan item 1: unknown action "zap"`},
+		{`{"Version":1,"Items":[{"Action":"delete"}]}`, "bad plan item 1: delete needs a Path"},
+		{`{"Version":1,"Items":[{"Action":"rename","Path":"a"}]}`, "bad plan item 1: From must be set"},
+		{`{"Version":1,"Items":[{"Action":"copy","Path":"a","Source":true}]}`, "bad plan item 1: can't copy in the source"},
+		{`potato`, "failed to read plan"},
+	} {
+		_, err := ReadPlan(strings.NewReader(test.in))
+		if test.want == "" {
+			assert.NoError(t, err, test.in)
+		} else {
+			require.Error(t, err, test.in)
+			assert.True(t, strings.Contains(err.Error(), test.want), err.Error())
+		}
+	}
+}
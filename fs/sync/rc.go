@@ -29,6 +29,37 @@ func init() {
 See the [` + name + ` command](/commands/rclone_` + name + `/) command for more information on the above.`,
 		})
 	}
+	rc.Add(rc.Call{
+		Path:         "sync/watchstatus",
+		AuthRequired: true,
+		Fn:           rcWatchStatus,
+		Title:        "Show the state of the running sync --watch mirrors",
+		Help: `This takes no parameters and returns
+
+- watchers - a list of the running ` + "`rclone sync --watch`" + ` mirrors, each with
+    - id - a number identifying it
+    - srcFs - the source remote
+    - dstFs - the destination remote
+    - queued - the paths changed which are waiting to be synced
+    - inFlight - the paths being synced
+    - syncs - the number of successful syncs done
+    - lastSync - when the last successful sync finished
+    - lastFullSync - when the last successful full sync finished
+    - lastError - the error from the last failed sync
+    - lastErrorTime - when the last sync failed
+
+The paths are relative to the source and "/" means everything.
+The times and the error are left out until they have happened.
+
+See the [sync command](/commands/rclone_sync/) for more information on --watch.`,
+	})
+}
+
+// Show the state of the running watchers
+func rcWatchStatus(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	return rc.Params{
+		"watchers": watchStatus(),
+	}, nil
 }
 
 // Sync/Copy/Move a file
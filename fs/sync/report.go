@@ -0,0 +1,170 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+)
+
+// Action is what sync, copy or move did to a file
+type Action byte
+
+// Actions reported
+const (
+	ActionNew       Action = iota // copied to the destination where it didn't exist
+	ActionUpdated                 // replaced a different file in the destination
+	ActionDeleted                 // deleted from the destination
+	ActionIdentical               // skipped as already in the destination
+	ActionRenamed                 // renamed in the destination
+	ActionError                   // there was an error
+)
+
+var actionNames = []string{
+	ActionNew:       "new",
+	ActionUpdated:   "updated",
+	ActionDeleted:   "deleted",
+	ActionIdentical: "identical",
+	ActionRenamed:   "renamed",
+	ActionError:     "error",
+}
+
+// sigils used for the actions in the combined report
+var actionSigils = []rune{
+	ActionNew:       '+',
+	ActionUpdated:   '*',
+	ActionDeleted:   '-',
+	ActionIdentical: '=',
+	ActionRenamed:   '>',
+	ActionError:     '!',
+}
+
+// String turns an Action into a string
+func (a Action) String() string {
+	if int(a) >= len(actionNames) {
+		return fmt.Sprintf("Action(%d)", a)
+	}
+	return actionNames[a]
+}
+
+// ReportOpt contains the outputs for the report of what sync, copy or
+// move did to each file. Any of them may be nil.
+type ReportOpt struct {
+	Combined  io.Writer // all file paths with a leading sigil
+	New       io.Writer // files copied which weren't in the destination
+	Updated   io.Writer // files which replaced a different file in the destination
+	Deleted   io.Writer // files deleted from the destination
+	Identical io.Writer // files skipped as they were already in the destination
+	Renamed   io.Writer // files renamed in the destination
+	Error     io.Writer // files with errors of some kind
+	JSON      io.Writer // all files as JSON lines with details
+}
+
+// ReportItem is a line of the JSON report
+type ReportItem struct {
+	Action string
+	Path   string
+	From   string            `json:",omitempty"` // the old path of renamed files
+	Size   int64             // size of the file, -1 if unknown
+	Hashes map[string]string `json:",omitempty"`
+	Reason string            `json:",omitempty"`
+	Error  string            `json:",omitempty"`
+}
+
+// Report writes what sync, copy or move did to each file to the
+// outputs in its ReportOpt
+type Report struct {
+	mu  sync.Mutex
+	opt ReportOpt
+}
+
+// NewReport makes a new Report writing to the outputs in opt
+func NewReport(opt ReportOpt) *Report {
+	return &Report{opt: opt}
+}
+
+// writer returns the output for action
+func (r *Report) writer(action Action) io.Writer {
+	switch action {
+	case ActionNew:
+		return r.opt.New
+	case ActionUpdated:
+		return r.opt.Updated
+	case ActionDeleted:
+		return r.opt.Deleted
+	case ActionIdentical:
+		return r.opt.Identical
+	case ActionRenamed:
+		return r.opt.Renamed
+	case ActionError:
+		return r.opt.Error
+	}
+	return nil
+}
+
+// add reports action on the file at remote.
+//
+// o is the object the details in the JSON report are read from, and
+// may be nil. Its hash is only read if hashType is set.
+func (r *Report) add(ctx context.Context, action Action, remote, from string, o fs.Object, hashType hash.Type, reason string, err error) {
+	if r == nil {
+		return
+	}
+	var line []byte
+	if r.opt.JSON != nil {
+		item := ReportItem{
+			Action: action.String(),
+			Path:   remote,
+			From:   from,
+			Size:   -1,
+			Reason: reason,
+		}
+		if err != nil {
+			item.Error = err.Error()
+		}
+		if o != nil {
+			item.Size = o.Size()
+			if hashType != hash.None {
+				sum, hashErr := o.Hash(ctx, hashType)
+				if hashErr != nil {
+					fs.Debugf(o, "Failed to read hash for report: %v", hashErr)
+				} else if sum != "" {
+					item.Hashes = map[string]string{hashType.String(): sum}
+				}
+			}
+		}
+		line, err = json.Marshal(&item)
+		if err != nil {
+			fs.Errorf(remote, "Failed to encode report: %v", err)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if out := r.writer(action); out != nil {
+		_, _ = fmt.Fprintf(out, "%s\n", remote)
+	}
+	if r.opt.Combined != nil {
+		_, _ = fmt.Fprintf(r.opt.Combined, "%c %s\n", actionSigils[action], remote)
+	}
+	if line != nil {
+		_, _ = fmt.Fprintf(r.opt.JSON, "%s\n", line)
+	}
+}
+
+type reportKey struct{}
+
+// WithReport returns a new context which makes sync, copy and move
+// write what they do to each file to report
+func WithReport(ctx context.Context, report *Report) context.Context {
+	return context.WithValue(ctx, reportKey{}, report)
+}
+
+// getReport returns the Report in ctx or nil if there isn't one
+func getReport(ctx context.Context) *Report {
+	report, _ := ctx.Value(reportKey{}).(*Report)
+	return report
+}
@@ -0,0 +1,109 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActionString(t *testing.T) {
+	assert.Equal(t, "new", ActionNew.String())
+	assert.Equal(t, "error", ActionError.String())
+	assert.Equal(t, "Action(99)", Action(99).String())
+}
+
+// sortedLines returns the lines in buf sorted
+func sortedLines(buf *bytes.Buffer) []string {
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	sort.Strings(lines)
+	return lines
+}
+
+func TestSyncReport(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	r.WriteFile("new", "new file", t1)
+	same := r.WriteFile("same", "same file", t1)
+	r.WriteObject(ctx, "same", "same file", t1)
+	r.WriteFile("changed", "changed file", t2)
+	r.WriteObject(ctx, "changed", "old", t1)
+	r.WriteObject(ctx, "gone", "deleted file", t1)
+
+	var combined, newFiles, deleted, jsonReport bytes.Buffer
+	report := NewReport(ReportOpt{
+		Combined: &combined,
+		New:      &newFiles,
+		Deleted:  &deleted,
+		JSON:     &jsonReport,
+	})
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, Sync(WithReport(ctx, report), r.Fremote, r.Flocal, false))
+
+	assert.Equal(t, []string{"* changed", "+ new", "- gone", "= same"}, sortedLines(&combined))
+	assert.Equal(t, "new\n", newFiles.String())
+	assert.Equal(t, "gone\n", deleted.String())
+
+	items := map[string]ReportItem{}
+	for _, line := range sortedLines(&jsonReport) {
+		var item ReportItem
+		require.NoError(t, json.Unmarshal([]byte(line), &item))
+		items[item.Path] = item
+	}
+	require.Equal(t, 4, len(items))
+	assert.Equal(t, "new", items["new"].Action)
+	assert.Equal(t, "not in destination", items["new"].Reason)
+	assert.Equal(t, int64(8), items["new"].Size)
+	assert.Equal(t, "updated", items["changed"].Action)
+	assert.Equal(t, "size differs", items["changed"].Reason)
+	assert.Equal(t, int64(12), items["changed"].Size)
+	assert.Equal(t, "deleted", items["gone"].Action)
+	assert.Equal(t, "identical", items["same"].Action)
+	assert.Equal(t, same.Size, items["same"].Size)
+	haveHash := r.Fremote.Hashes().Overlap(r.Flocal.Hashes()).Count() > 0
+	for _, item := range items {
+		assert.Equal(t, "", item.Error)
+		// Only the files transferred are hashed without --checksum
+		if haveHash && (item.Action == "new" || item.Action == "updated") {
+			assert.Equal(t, 1, len(item.Hashes), item.Path)
+		} else {
+			assert.Equal(t, 0, len(item.Hashes), item.Path)
+		}
+	}
+}
+
+func TestSyncReportChecksum(t *testing.T) {
+	ctx := context.Background()
+	ci := fs.GetConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	ci.CheckSum = true
+	defer func() { ci.CheckSum = false }()
+	if r.Fremote.Hashes().Overlap(r.Flocal.Hashes()).Count() == 0 {
+		t.Skip("Can't run this test without a common hash")
+	}
+
+	r.WriteFile("same", "same file", t1)
+	r.WriteObject(ctx, "same", "same file", t2)
+
+	var jsonReport bytes.Buffer
+	report := NewReport(ReportOpt{JSON: &jsonReport})
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, Sync(WithReport(ctx, report), r.Fremote, r.Flocal, false))
+
+	var item ReportItem
+	require.NoError(t, json.Unmarshal(jsonReport.Bytes(), &item))
+	assert.Equal(t, "identical", item.Action)
+	// The hash read to compare the files is reported
+	assert.Equal(t, 1, len(item.Hashes))
+}
@@ -62,6 +62,7 @@ type syncCopyMove struct {
 	noRetryErr             error                  // error with NoRetry set
 	fatalErr               error                  // fatal error
 	commonHash             hash.Type              // common hash type between src and dst
+	reportHash             hash.Type              // hash type already read when comparing files, put in the report
 	modifyWindow           time.Duration          // modify window between fsrc, fdst
 	renameMapMu            sync.Mutex             // mutex to protect the below
 	renameMap              map[string][]fs.Object // dst files by hash - only used by trackRenames
@@ -70,9 +71,14 @@ type syncCopyMove struct {
 	trackRenamesWg         sync.WaitGroup         // wg for background track renames
 	trackRenamesCh         chan fs.Object         // objects are pumped in here
 	renameCheck            []fs.Object            // accumulate files to check for rename here
-	compareCopyDest        fs.Fs                  // place to check for files to server-side copy
+	compareCopyDest        []fs.Fs                // places to check for files to server-side copy
 	backupDir              fs.Fs                  // place to store overwrites/deletes
 	checkFirst             bool                   // if set run all the checkers before starting transfers
+	report                 *Report                // if set report what happened to each file here
+	plan                   *Plan                  // if set record what would be done to each file here
+	fixCaseMu              sync.Mutex             // protect fixedCaseDirs
+	fixedCaseDirs          map[string]bool        // src directories which now match the dst with --fix-case
+	paths                  []string               // if set only sync these paths and the directories under them
 }
 
 type trackRenamesStrategy byte
@@ -95,10 +101,36 @@ func (strategy trackRenamesStrategy) leaf() bool {
 	return (strategy & trackRenamesStrategyLeaf) != 0
 }
 
+// withRemoteConcurrency returns a context with --checkers and
+// --transfers set to the lowest of those set for fdst and fsrc. These
+// are the global values unless overridden in the config of the
+// remotes.
+func withRemoteConcurrency(ctx context.Context, fdst, fsrc fs.Fs) context.Context {
+	dstCi, _ := fs.RemoteConfig(ctx, fdst)
+	srcCi, _ := fs.RemoteConfig(ctx, fsrc)
+	checkers, transfers := dstCi.Checkers, dstCi.Transfers
+	if srcCi.Checkers < checkers {
+		checkers = srcCi.Checkers
+	}
+	if srcCi.Transfers < transfers {
+		transfers = srcCi.Transfers
+	}
+	ci := fs.GetConfig(ctx)
+	if ci.Checkers == checkers && ci.Transfers == transfers {
+		return ctx
+	}
+	fs.Debugf(fdst, "Using %d checkers and %d transfers as set in the config of the remotes", checkers, transfers)
+	ctx, ci = fs.AddConfig(ctx)
+	ci.Checkers = checkers
+	ci.Transfers = transfers
+	return ctx
+}
+
 func newSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool, copyEmptySrcDirs bool) (*syncCopyMove, error) {
 	if (deleteMode != fs.DeleteModeOff || DoMove) && operations.Overlapping(fdst, fsrc) {
 		return nil, fserrors.FatalError(fs.ErrorOverlapping)
 	}
+	ctx = withRemoteConcurrency(ctx, fdst, fsrc)
 	ci := fs.GetConfig(ctx)
 	fi := filter.GetConfig(ctx)
 	s := &syncCopyMove{
@@ -125,6 +157,9 @@ func newSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.Delete
 		modifyWindow:           fs.GetModifyWindow(ctx, fsrc, fdst),
 		trackRenamesCh:         make(chan fs.Object, ci.Checkers),
 		checkFirst:             ci.CheckFirst,
+		report:                 getReport(ctx),
+		plan:                   getPlan(ctx),
+		fixedCaseDirs:          make(map[string]bool),
 	}
 	backlog := ci.MaxBacklog
 	if s.checkFirst {
@@ -173,6 +208,9 @@ func newSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.Delete
 			return nil, errors.New("can't use --no-check-dest with --backup-dir")
 		}
 	}
+	if s.plan != nil && ci.FixCase {
+		return nil, errors.New("can't make a plan with --fix-case")
+	}
 	if s.trackRenames {
 		// Don't track renames for remotes without server-side move support.
 		if !operations.CanServerSideMove(fdst) {
@@ -194,6 +232,11 @@ func newSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.Delete
 			s.trackRenames = false
 		}
 	}
+	// Only report hashes which are read anyway so an unchanged file
+	// isn't read just to write the report
+	if ci.CheckSum || (s.trackRenames && s.trackRenamesStrategy.hash()) {
+		s.reportHash = s.commonHash
+	}
 	if s.trackRenames {
 		// track renames needs delete after
 		if s.deleteMode != fs.DeleteModeOff {
@@ -212,13 +255,13 @@ func newSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.Delete
 			return nil, err
 		}
 	}
-	if ci.CompareDest != "" {
+	if len(ci.CompareDest) > 0 {
 		var err error
 		s.compareCopyDest, err = operations.GetCompareDest(ctx)
 		if err != nil {
 			return nil, err
 		}
-	} else if ci.CopyDest != "" {
+	} else if len(ci.CopyDest) > 0 {
 		var err error
 		s.compareCopyDest, err = operations.GetCopyDest(ctx, fdst)
 		if err != nil {
@@ -312,21 +355,34 @@ func (s *syncCopyMove) pairChecker(in *pipe, out *pipe, fraction int, wg *sync.W
 		tr := accounting.Stats(s.ctx).NewCheckingTransfer(src)
 		// Check to see if can store this
 		if src.Storable() {
-			NoNeedTransfer, err := operations.CompareOrCopyDest(s.ctx, s.fdst, pair.Dst, pair.Src, s.compareCopyDest, s.backupDir)
-			if err != nil {
-				s.processError(err)
+			if s.ci.FixCase && !s.ci.Immutable && pair.Dst != nil {
+				pair.Dst = s.fixCaseFile(pair.Dst, src)
+			}
+			// Check the destination first so a file which is
+			// already there isn't counted as found in --copy-dest
+			needTransfer := operations.NeedTransfer(s.ctx, pair.Dst, pair.Src)
+			NoNeedTransfer := false
+			if needTransfer {
+				var cdErr error
+				NoNeedTransfer, cdErr = operations.CompareOrCopyDest(s.ctx, s.fdst, pair.Dst, pair.Src, s.compareCopyDest, s.backupDir)
+				if cdErr != nil {
+					s.processError(cdErr)
+				}
 			}
-			if !NoNeedTransfer && operations.NeedTransfer(s.ctx, pair.Dst, pair.Src) {
+			if !NoNeedTransfer && needTransfer {
 				// If files are treated as immutable, fail if destination exists and does not match
 				if s.ci.Immutable && pair.Dst != nil {
 					fs.Errorf(pair.Dst, "Source and destination exist but do not match: immutable file modified")
 					s.processError(fs.ErrorImmutableModified)
+					s.report.add(s.ctx, ActionError, src.Remote(), "", src, s.reportHash, "immutable file modified", fs.ErrorImmutableModified)
 				} else {
 					// If destination already exists, then we must move it into --backup-dir if required
-					if pair.Dst != nil && s.backupDir != nil {
+					// which is left until the plan is applied if making one
+					if pair.Dst != nil && s.backupDir != nil && s.plan == nil {
 						err := operations.MoveBackupDir(s.ctx, s.backupDir, pair.Dst)
 						if err != nil {
 							s.processError(err)
+							s.report.add(s.ctx, ActionError, src.Remote(), "", src, s.reportHash, "failed to move into --backup-dir", err)
 						} else {
 							// If successful zero out the dst as it is no longer there and copy the file
 							pair.Dst = nil
@@ -343,10 +399,12 @@ func (s *syncCopyMove) pairChecker(in *pipe, out *pipe, fraction int, wg *sync.W
 					}
 				}
 			} else {
+				s.reportNoTransfer(NoNeedTransfer, pair.Dst, src)
 				// If moving need to delete the files we don't need to copy
 				if s.DoMove {
 					// Delete src if no error on copy
 					s.processError(operations.DeleteFile(s.ctx, src))
+					s.plan.addDelete(s.ctx, src, true)
 				}
 			}
 		}
@@ -384,13 +442,114 @@ func (s *syncCopyMove) pairCopyOrMove(ctx context.Context, in *pipe, fdst fs.Fs,
 			return
 		}
 		src := pair.Src
+		var reason string
+		if s.report != nil {
+			// Work out why before the transfer changes dst
+			reason = s.transferReason(pair.Dst, src)
+		}
+		var newDst fs.Object
 		if s.DoMove {
-			_, err = operations.Move(ctx, fdst, pair.Dst, src.Remote(), src)
+			newDst, err = operations.Move(ctx, fdst, pair.Dst, src.Remote(), src)
 		} else {
-			_, err = operations.Copy(ctx, fdst, pair.Dst, src.Remote(), src)
+			newDst, err = operations.Copy(ctx, fdst, pair.Dst, src.Remote(), src)
 		}
 		s.processError(err)
+		s.reportTransfer(pair.Dst, src, newDst, reason, err)
+		if err == nil {
+			s.plan.addTransfer(ctx, s.DoMove, pair.Dst, src)
+		}
+	}
+}
+
+// reportNoTransfer reports src which didn't need transferring to dst.
+//
+// noNeedTransfer should be set if this was because of --compare-dest
+// or --copy-dest.
+func (s *syncCopyMove) reportNoTransfer(noNeedTransfer bool, dst, src fs.Object) {
+	if noNeedTransfer && len(s.ci.CopyDest) > 0 {
+		// The server-side copy from --copy-dest is done as a
+		// normal copy when the plan is applied
+		s.plan.addTransfer(s.ctx, false, dst, src)
+	}
+	if s.report == nil {
+		return
 	}
+	action, reason := ActionIdentical, "unchanged"
+	switch {
+	case noNeedTransfer && len(s.ci.CopyDest) > 0:
+		action, reason = ActionNew, "server-side copied from --copy-dest"
+	case noNeedTransfer:
+		reason = "found in --compare-dest"
+	case s.ci.IgnoreExisting:
+		reason = "destination exists"
+	case s.ci.UpdateOlder && dst.ModTime(s.ctx).After(src.ModTime(s.ctx)):
+		reason = "destination is newer"
+	}
+	o := dst
+	if o == nil {
+		o = src
+	}
+	s.report.add(s.ctx, action, src.Remote(), "", o, s.reportHash, reason, nil)
+}
+
+// transferReason returns why src is being transferred over dst,
+// which may be nil
+func (s *syncCopyMove) transferReason(dst, src fs.Object) string {
+	switch {
+	case dst == nil:
+		return "not in destination"
+	case s.ci.IgnoreTimes:
+		return "--ignore-times"
+	case src.Size() != dst.Size():
+		return "size differs"
+	}
+	return "modification time or hash differs"
+}
+
+// reportTransfer reports the result of transferring src over dst,
+// which may be nil, to make newDst
+func (s *syncCopyMove) reportTransfer(dst, src, newDst fs.Object, reason string, err error) {
+	if s.report == nil {
+		return
+	}
+	o := newDst
+	if o == nil {
+		o = src
+	}
+	// The hash of a file which was transferred is usually known
+	// from checking the transfer
+	hashType := s.commonHash
+	action := ActionUpdated
+	switch {
+	case err != nil:
+		hashType = s.reportHash
+		action, reason = ActionError, "failed to copy"
+		if s.DoMove {
+			reason = "failed to move"
+		}
+	case dst == nil:
+		action = ActionNew
+	}
+	s.report.add(s.ctx, action, src.Remote(), "", o, hashType, reason, err)
+}
+
+// reportDelete reports the result of deleting dst
+func (s *syncCopyMove) reportDelete(dst fs.Object, err error) {
+	if err == nil {
+		s.plan.addDelete(s.ctx, dst, false)
+	}
+	if s.report == nil {
+		return
+	}
+	if err != nil {
+		s.report.add(s.ctx, ActionError, dst.Remote(), "", dst, s.reportHash, "failed to delete", err)
+		return
+	}
+	reason := "not in source"
+	if s.backupDir != nil {
+		reason = "not in source, moved into --backup-dir"
+	}
+	s.report.add(s.ctx, ActionDeleted, dst.Remote(), "", dst, s.reportHash, reason, nil)
 }
 
 // This starts the background checkers.
@@ -478,7 +637,7 @@ func (s *syncCopyMove) startDeleters() {
 	s.deletersWg.Add(1)
 	go func() {
 		defer s.deletersWg.Done()
-		err := operations.DeleteFilesWithBackupDir(s.ctx, s.deleteFilesCh, s.backupDir)
+		err := operations.DeleteFilesWithBackupDirFn(s.ctx, s.deleteFilesCh, s.backupDir, s.reportDelete)
 		s.processError(err)
 	}()
 }
@@ -525,7 +684,7 @@ func (s *syncCopyMove) deleteFiles(checkSrcMap bool) error {
 		}
 		close(toDelete)
 	}()
-	return operations.DeleteFilesWithBackupDir(s.ctx, toDelete, s.backupDir)
+	return operations.DeleteFilesWithBackupDirFn(s.ctx, toDelete, s.backupDir, s.reportDelete)
 }
 
 // This deletes the empty directories in the slice passed in.  It
@@ -539,6 +698,8 @@ func (s *syncCopyMove) deleteEmptyDirectories(ctx context.Context, f fs.Fs, entr
 		return fs.ErrorNotDeletingDirs
 	}
 
+	s.plan.addDirs(PlanRmdir, entriesMap, f == s.fsrc)
+
 	var entries fs.DirEntries
 	for _, entry := range entriesMap {
 		entries = append(entries, entry)
@@ -796,6 +957,8 @@ func (s *syncCopyMove) tryRename(src fs.Object) bool {
 	s.dstFilesMu.Unlock()
 
 	fs.Infof(src, "Renamed from %q", dst.Remote())
+	s.plan.addRename(s.ctx, dst, src)
+	s.report.add(s.ctx, ActionRenamed, src.Remote(), dst.Remote(), src, s.reportHash, "", nil)
 	return true
 }
 
@@ -834,6 +997,7 @@ func (s *syncCopyMove) run() error {
 		DstIncludeAll:          s.fi.Opt.DeleteExcluded,
 		NoCheckDest:            s.noCheckDest,
 		NoUnicodeNormalization: s.noUnicodeNormalization,
+		Paths:                  s.paths,
 	}
 	s.processError(m.Run(s.ctx))
 
@@ -861,6 +1025,7 @@ func (s *syncCopyMove) run() error {
 	s.stopDeleters()
 
 	if s.copyEmptySrcDirs {
+		s.plan.addDirs(PlanMkdir, s.srcEmptyDirs, false)
 		s.processError(copyEmptyDirectories(s.ctx, s.fdst, s.srcEmptyDirs))
 	}
 
@@ -966,7 +1131,9 @@ func (s *syncCopyMove) SrcOnly(src fs.DirEntry) (recurse bool) {
 			if err != nil {
 				s.processError(err)
 			}
-			if !NoNeedTransfer {
+			if NoNeedTransfer {
+				s.reportNoTransfer(NoNeedTransfer, nil, x)
+			} else {
 				// No need to check since doesn't exist
 				ok := s.toBeUploaded.Put(s.ctx, fs.ObjectPair{Src: x, Dst: nil})
 				if !ok {
@@ -1010,11 +1177,15 @@ func (s *syncCopyMove) Match(ctx context.Context, dst, src fs.DirEntry) (recurse
 			err := errors.New("can't overwrite directory with file")
 			fs.Errorf(dst, "%v", err)
 			s.processError(err)
+			s.report.add(s.ctx, ActionError, src.Remote(), "", srcX, s.reportHash, "", err)
 		}
 	case fs.Directory:
 		// Do the same thing to the entire contents of the directory
-		_, ok := dst.(fs.Directory)
+		dstX, ok := dst.(fs.Directory)
 		if ok {
+			if s.ci.FixCase && !s.ci.Immutable {
+				s.fixCaseDir(dstX, srcX)
+			}
 			// Only record matched (src & dst) empty dirs when performing move
 			if s.DoMove {
 				// Record the src directory for deletion
@@ -1030,12 +1201,101 @@ func (s *syncCopyMove) Match(ctx context.Context, dst, src fs.DirEntry) (recurse
 		err := errors.New("can't overwrite file with directory")
 		fs.Errorf(dst, "%v", err)
 		s.processError(err)
+		s.report.add(s.ctx, ActionError, src.Remote(), "", nil, hash.None, "", err)
 	default:
 		panic("Bad object in DirEntries")
 	}
 	return false
 }
 
+// fixCasePath returns the current path on the destination of dst
+// which was matched with src, allowing for the parent directories
+// renamed by --fix-case
+func (s *syncCopyMove) fixCasePath(dst, src fs.DirEntry) string {
+	srcDir := path.Dir(src.Remote())
+	s.fixCaseMu.Lock()
+	fixed := srcDir == "." || s.fixedCaseDirs[srcDir]
+	s.fixCaseMu.Unlock()
+	if fixed {
+		return path.Join(srcDir, path.Base(dst.Remote()))
+	}
+	return dst.Remote()
+}
+
+// DstDirRemote returns the path the directory dst matched with src
+// should be listed from as it may have been renamed by --fix-case
+func (s *syncCopyMove) DstDirRemote(dst, src fs.DirEntry) string {
+	if !s.ci.FixCase {
+		return dst.Remote()
+	}
+	s.fixCaseMu.Lock()
+	fixed := s.fixedCaseDirs[src.Remote()]
+	s.fixCaseMu.Unlock()
+	if fixed {
+		return src.Remote()
+	}
+	return s.fixCasePath(dst, src)
+}
+
+// fixCaseDir renames the directory dst to the name of src if they
+// differ, which they can only do in case or unicode normalization.
+//
+// The directory is left alone if the destination can't rename
+// directories as the files in it will be renamed instead.
+func (s *syncCopyMove) fixCaseDir(dst, src fs.Directory) {
+	current := s.fixCasePath(dst, src)
+	fixed := current == src.Remote()
+	if !fixed && s.fdst.Features().DirMove != nil {
+		renamed, err := operations.DirMoveCaseInsensitive(s.ctx, s.fdst, current, src.Remote())
+		if err != nil {
+			err = fs.CountError(err)
+			fs.Errorf(src, "Failed to fix case of directory %q: %v", current, err)
+			s.processError(err)
+		} else if renamed {
+			fs.Infof(src, "Fixed case of directory %q", current)
+			fixed = true
+		}
+	}
+	if fixed {
+		s.fixCaseMu.Lock()
+		s.fixedCaseDirs[src.Remote()] = true
+		s.fixCaseMu.Unlock()
+	}
+}
+
+// fixCaseFile renames the file dst to the name of src if they
+// differ, which they can only do in case or unicode normalization.
+//
+// It returns the object to use for dst from now on.
+func (s *syncCopyMove) fixCaseFile(dst, src fs.Object) fs.Object {
+	current := s.fixCasePath(dst, src)
+	o := dst
+	if current != dst.Remote() {
+		// The parent directory has been renamed so find the file again
+		var err error
+		o, err = s.fdst.NewObject(s.ctx, current)
+		if err != nil {
+			fs.Errorf(src, "Failed to find %q to fix case: %v", current, err)
+			s.processError(err)
+			return dst
+		}
+	}
+	if current == src.Remote() {
+		return o
+	}
+	newDst, err := operations.MoveCaseInsensitive(s.ctx, s.fdst, src.Remote(), o)
+	if err != nil {
+		err = fs.CountError(err)
+		fs.Errorf(src, "Failed to fix case of %q: %v", current, err)
+		s.processError(err)
+		s.report.add(s.ctx, ActionError, src.Remote(), current, src, s.reportHash, "failed to fix case", err)
+		return o
+	}
+	fs.Infof(src, "Fixed case of %q", current)
+	s.report.add(s.ctx, ActionRenamed, src.Remote(), current, newDst, s.reportHash, "fixed case", nil)
+	return newDst
+}
+
 // Syncs fsrc into fdst
 //
 // If Delete is true then it deletes any files in fdst that aren't in fsrc
@@ -1044,10 +1304,23 @@ func (s *syncCopyMove) Match(ctx context.Context, dst, src fs.DirEntry) (recurse
 //
 // dir is the start directory, "" for root
 func runSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool, copyEmptySrcDirs bool) error {
+	ci := fs.GetConfig(ctx)
+	if ci.FilesFromChanges != "" {
+		return runChanges(ctx, fdst, fsrc, deleteMode, DoMove, deleteEmptySrcDirs, copyEmptySrcDirs)
+	}
+	return runSyncCopyMovePaths(ctx, fdst, fsrc, deleteMode, DoMove, deleteEmptySrcDirs, copyEmptySrcDirs, nil)
+}
+
+// runSyncCopyMovePaths syncs, copies or moves only paths and the
+// directories under them if paths is set, otherwise everything
+func runSyncCopyMovePaths(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool, copyEmptySrcDirs bool, paths []string) error {
 	ci := fs.GetConfig(ctx)
 	if deleteMode != fs.DeleteModeOff && DoMove {
 		return fserrors.FatalError(errors.New("can't delete and move at the same time"))
 	}
+	if plan := getPlan(ctx); plan != nil {
+		plan.begin(fdst, fsrc, deleteMode, DoMove)
+	}
 	// Run an extra pass to delete only
 	if deleteMode == fs.DeleteModeBefore {
 		if ci.TrackRenames {
@@ -1058,6 +1331,7 @@ func runSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.Delete
 		if err != nil {
 			return err
 		}
+		do.paths = paths
 		err = do.run()
 		if err != nil {
 			return err
@@ -1069,6 +1343,7 @@ func runSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.Delete
 	if err != nil {
 		return err
 	}
+	do.paths = paths
 	return do.run()
 }
 
@@ -1090,14 +1365,17 @@ func moveDir(ctx context.Context, fdst, fsrc fs.Fs, deleteEmptySrcDirs bool, cop
 
 // MoveDir moves fsrc into fdst
 func MoveDir(ctx context.Context, fdst, fsrc fs.Fs, deleteEmptySrcDirs bool, copyEmptySrcDirs bool) error {
+	ci := fs.GetConfig(ctx)
 	fi := filter.GetConfig(ctx)
 	if operations.Same(fdst, fsrc) {
 		fs.Errorf(fdst, "Nothing to do as source and destination are the same")
 		return nil
 	}
 
-	// First attempt to use DirMover if exists, same Fs and no filters are active
-	if fdstDirMove := fdst.Features().DirMove; fdstDirMove != nil && operations.SameConfig(fsrc, fdst) && fi.InActive() {
+	// First attempt to use DirMover if exists, same Fs and no filters or
+	// --files-from-changes are active.
+	// This can't report or plan what happens to each file so isn't used with a report or plan.
+	if fdstDirMove := fdst.Features().DirMove; fdstDirMove != nil && operations.SameConfig(fsrc, fdst) && fi.InActive() && getReport(ctx) == nil && getPlan(ctx) == nil && ci.FilesFromChanges == "" {
 		if operations.SkipDestructive(ctx, fdst, "server-side directory move") {
 			return nil
 		}
@@ -3,8 +3,12 @@
 package sync
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
@@ -14,6 +18,7 @@ import (
 	_ "github.com/rclone/rclone/backend/all" // import all backends
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/changes"
 	"github.com/rclone/rclone/fs/filter"
 	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fs/hash"
@@ -1480,9 +1485,9 @@ func TestSyncCompareDest(t *testing.T) {
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 
-	ci.CompareDest = r.FremoteName + "/CompareDest"
+	ci.CompareDest = []string{r.FremoteName + "/CompareDest"}
 	defer func() {
-		ci.CompareDest = ""
+		ci.CompareDest = nil
 	}()
 
 	fdst, err := fs.NewFs(ctx, r.FremoteName+"/dst")
@@ -1573,9 +1578,9 @@ func TestSyncCopyDest(t *testing.T) {
 		t.Skip("Skipping test as remote does not support server-side copy")
 	}
 
-	ci.CopyDest = r.FremoteName + "/CopyDest"
+	ci.CopyDest = []string{r.FremoteName + "/CopyDest"}
 	defer func() {
-		ci.CopyDest = ""
+		ci.CopyDest = nil
 	}()
 
 	fdst, err := fs.NewFs(ctx, r.FremoteName+"/dst")
@@ -1667,6 +1672,69 @@ func TestSyncCopyDest(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file2, file2dst, file3, file4, file4dst, file6, file7dst)
 }
 
+// Test with multiple CompareDest and CopyDest set
+func TestSyncMultipleCompareCopyDest(t *testing.T) {
+	ctx := context.Background()
+	ci := fs.GetConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	if r.Fremote.Features().Copy == nil {
+		t.Skip("Skipping test as remote does not support server-side copy")
+	}
+
+	// snap2 is newer than snap1 so is listed first
+	snap1 := r.WriteObject(ctx, "snap1/a", "a", t1)
+	snap1b := r.WriteObject(ctx, "snap1/b", "b", t1)
+	snap1c := r.WriteObject(ctx, "snap1/c", "c", t1)
+	snap2 := r.WriteObject(ctx, "snap2/a", "a", t1)
+	snap2c := r.WriteObject(ctx, "snap2/c", "c changed", t2)
+	fileA := r.WriteFile("a", "a", t1)
+	fileB := r.WriteFile("b", "b", t1)
+	fileC := r.WriteFile("c", "c", t1)
+	fileD := r.WriteFile("d", "d", t1)
+	fstest.CheckItems(t, r.Flocal, fileA, fileB, fileC, fileD)
+	snaps := []string{r.FremoteName + "/snap2", r.FremoteName + "/snap1"}
+	locations := []string{fs.ConfigString(r.Fremote) + "/snap2", fs.ConfigString(r.Fremote) + "/snap1"}
+
+	destMatches := func() interface{} {
+		out, err := accounting.GlobalStats().RemoteStats()
+		require.NoError(t, err)
+		return out["destMatches"]
+	}
+
+	// All but d is in one of the --compare-dest
+	ci.CompareDest = snaps
+	fdst, err := fs.NewFs(ctx, r.FremoteName+"/compare")
+	require.NoError(t, err)
+	accounting.GlobalStats().ResetCounters()
+	err = Sync(ctx, fdst, r.Flocal, false)
+	ci.CompareDest = nil
+	require.NoError(t, err)
+
+	fileDcompare := fileD
+	fileDcompare.Path = "compare/d"
+	fstest.CheckItems(t, r.Fremote, snap1, snap1b, snap1c, snap2, snap2c, fileDcompare)
+	assert.Equal(t, map[string]int64{locations[0]: 1, locations[1]: 2}, destMatches())
+
+	// All but d is copied from the newest --copy-dest which has it
+	ci.CopyDest = snaps
+	fdst, err = fs.NewFs(ctx, r.FremoteName+"/copy")
+	require.NoError(t, err)
+	accounting.GlobalStats().ResetCounters()
+	err = Sync(ctx, fdst, r.Flocal, false)
+	ci.CopyDest = nil
+	require.NoError(t, err)
+
+	var copied []fstest.Item
+	for _, item := range []fstest.Item{fileA, fileB, fileC, fileD} {
+		item.Path = "copy/" + item.Path
+		copied = append(copied, item)
+	}
+	fstest.CheckItems(t, r.Fremote, append(copied, snap1, snap1b, snap1c, snap2, snap2c, fileDcompare)...)
+	assert.Equal(t, map[string]int64{locations[0]: 1, locations[1]: 2}, destMatches())
+}
+
 // Test with BackupDir set
 func testSyncBackupDir(t *testing.T, backupDir string, suffix string, suffixKeepExtension bool) {
 	ctx := context.Background()
@@ -1958,6 +2026,136 @@ func TestSyncIgnoreCase(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file2)
 }
 
+// Test that --fix-case renames files and directories to match the source
+func TestSyncFixCase(t *testing.T) {
+	ctx := context.Background()
+	ci := fs.GetConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	// Only test if filesystems are case sensitive
+	if r.Fremote.Features().CaseInsensitive || r.Flocal.Features().CaseInsensitive {
+		t.Skip("Skipping test as local or remote are case-insensitive")
+	}
+	if !operations.CanServerSideMove(r.Fremote) {
+		t.Skip("Skipping test as remote does not support server-side move")
+	}
+
+	ci.IgnoreCaseSync = true
+	ci.FixCase = true
+	defer func() {
+		ci.IgnoreCaseSync = false
+		ci.FixCase = false
+	}()
+
+	// Create files and directories with different casing
+	file1 := r.WriteFile("report.pdf", "report", t1)
+	file2 := r.WriteFile("dir/sub/file.txt", "changed", t2)
+	file3 := r.WriteFile("same", "same", t1)
+	r.WriteObject(ctx, "Report.PDF", "report", t1)
+	r.WriteObject(ctx, "DIR/Sub/FILE.txt", "old", t1)
+	r.WriteObject(ctx, "same", "same", t1)
+
+	var combined bytes.Buffer
+	report := NewReport(ReportOpt{Combined: &combined})
+	accounting.GlobalStats().ResetCounters()
+	err := Sync(WithReport(ctx, report), r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1, file2, file3}, []string{"dir", "dir/sub"}, fs.GetModifyWindow(ctx, r.Fremote))
+	assert.Contains(t, combined.String(), "> report.pdf\n")
+	assert.Contains(t, combined.String(), "> dir/sub/file.txt\n")
+	assert.Contains(t, combined.String(), "* dir/sub/file.txt\n")
+}
+
+// Test --fix-case with --dry-run changes nothing and doesn't report
+// the files in directories which would be renamed as new
+func TestSyncFixCaseDryRun(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	// Only test if filesystems are case sensitive
+	if r.Fremote.Features().CaseInsensitive || r.Flocal.Features().CaseInsensitive {
+		t.Skip("Skipping test as local or remote are case-insensitive")
+	}
+	if !operations.CanServerSideMove(r.Fremote) {
+		t.Skip("Skipping test as remote does not support server-side move")
+	}
+
+	ci.IgnoreCaseSync = true
+	ci.FixCase = true
+	ci.DryRun = true
+
+	r.WriteFile("dir/sub/file.txt", "changed", t2)
+	file1 := r.WriteObject(ctx, "DIR/Sub/FILE.txt", "old", t1)
+
+	var combined bytes.Buffer
+	report := NewReport(ReportOpt{Combined: &combined})
+	accounting.GlobalStats().ResetCounters()
+	err := Sync(WithReport(ctx, report), r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1}, []string{"DIR", "DIR/Sub"}, fs.GetModifyWindow(ctx, r.Fremote))
+	assert.Contains(t, combined.String(), "* dir/sub/file.txt\n")
+	assert.NotContains(t, combined.String(), "+ dir/sub/file.txt\n")
+}
+
+// Test syncing just the paths in a --files-from-changes journal
+func TestSyncFilesFromChanges(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	dir, err := ioutil.TempDir("", "rclone-sync-changes")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	journalPath := filepath.Join(dir, "journal")
+	ci.FilesFromChanges = journalPath
+	ci.FullSyncInterval = 0
+	journal := changes.NewJournal(journalPath)
+
+	file1 := r.WriteFile("a/new.txt", "new", t1)
+	file2 := r.WriteFile("changed.txt", "changed", t2)
+	file3 := r.WriteFile("unchanged.txt", "different", t2)
+	r.WriteObject(ctx, "changed.txt", "old", t1)
+	file3dst := r.WriteObject(ctx, "unchanged.txt", "same", t1)
+	r.WriteObject(ctx, "deleted.txt", "deleted", t1)
+	r.WriteObject(ctx, "deleteddir/file.txt", "deleted", t1)
+	file4 := r.WriteObject(ctx, "other/file.txt", "other", t1)
+
+	// Only the changed paths are synced
+	set := make(changes.Set)
+	for _, remote := range []string{"a/new.txt", "changed.txt", "deleted.txt", "deleteddir", "missing"} {
+		set.Add(remote)
+	}
+	require.NoError(t, journal.Append(set))
+	accounting.GlobalStats().ResetCounters()
+	err = Sync(ctx, r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3dst, file4)
+	_, err = os.Stat(journalPath + ".syncing")
+	assert.True(t, os.IsNotExist(err), "journal not removed")
+
+	// Nothing to do if no changes
+	accounting.GlobalStats().ResetCounters()
+	err = Sync(ctx, r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), accounting.GlobalStats().GetChecks())
+
+	// A change to the root syncs everything
+	set = make(changes.Set)
+	set.Add(changes.Root)
+	require.NoError(t, journal.Append(set))
+	accounting.GlobalStats().ResetCounters()
+	err = Sync(ctx, r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+	assert.False(t, journal.LastFullSync().IsZero())
+}
+
 // Test that aborting on --max-transfer works
 func TestMaxTransfer(t *testing.T) {
 	ctx := context.Background()
@@ -2008,3 +2206,43 @@ func TestMaxTransfer(t *testing.T) {
 	t.Run("Soft", func(t *testing.T) { test(t, fs.CutoffModeSoft) })
 	t.Run("Cautious", func(t *testing.T) { test(t, fs.CutoffModeCautious) })
 }
+
+// Test the checkers and transfers are limited by the config of the remotes
+func TestWithRemoteConcurrency(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	ci.Checkers = 8
+	ci.Transfers = 4
+	dir, err := ioutil.TempDir("", "rclone-sync-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	newFs := func(params string) fs.Fs {
+		f, err := fs.NewFs(ctx, ":local"+params+":"+dir)
+		require.NoError(t, err)
+		return f
+	}
+	plain := newFs("")
+	fast := newFs(",transfers=16,checkers=32")
+	slow := newFs(",transfers=2")
+
+	for _, test := range []struct {
+		fdst, fsrc    fs.Fs
+		wantCheckers  int
+		wantTransfers int
+	}{
+		{plain, plain, 8, 4},
+		{fast, plain, 8, 4},
+		{fast, fast, 32, 16},
+		{slow, fast, 8, 2},
+		{plain, slow, 8, 2},
+	} {
+		what := fmt.Sprintf("%v <- %v", test.fdst.Name(), test.fsrc.Name())
+		gotCi := fs.GetConfig(withRemoteConcurrency(ctx, test.fdst, test.fsrc))
+		assert.Equal(t, test.wantCheckers, gotCi.Checkers, what)
+		assert.Equal(t, test.wantTransfers, gotCi.Transfers, what)
+	}
+	assert.Equal(t, 8, ci.Checkers)
+	assert.Equal(t, 4, ci.Transfers)
+}
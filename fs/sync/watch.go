@@ -0,0 +1,294 @@
+package sync
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/changes"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/rc"
+)
+
+// WatchOpt is the options for Watch
+type WatchOpt struct {
+	Delay            time.Duration // sync when there have been no changes for this long
+	PollInterval     time.Duration // passed on to remotes which poll for changes
+	CopyEmptySrcDirs bool          // create empty source dirs on the destination
+}
+
+// DefaultWatchOpt is the default options for Watch
+var DefaultWatchOpt = WatchOpt{
+	Delay:        5 * time.Second,
+	PollInterval: time.Minute,
+}
+
+// maxDelayFactor limits how long a sync is put off by changes which
+// keep coming to this many times WatchOpt.Delay
+const maxDelayFactor = 10
+
+// maxRetryDelay is the longest time to wait before retrying a failed
+// sync
+const maxRetryDelay = 5 * time.Minute
+
+// watcher keeps fdst in sync with fsrc by syncing the paths which
+// change
+type watcher struct {
+	id         int
+	fdst, fsrc fs.Fs
+	opt        WatchOpt
+	changed    chan struct{} // a change was queued
+
+	mu           sync.Mutex
+	queued       changes.Set   // changes waiting to be synced
+	firstChange  time.Time     // when the first queued change was made
+	lastChange   time.Time     // when the last queued change was made
+	inFlight     []string      // paths being synced, "/" for everything
+	retryDelay   time.Duration // how long to wait after the last error
+	notBefore    time.Time     // don't sync before this after an error
+	lastError    error         // the error from the last failed sync
+	lastErrorAt  time.Time     // when the last sync failed
+	lastSync     time.Time     // when the last successful sync finished
+	lastFullSync time.Time     // when the last successful full sync finished
+	syncs        int           // number of successful syncs
+}
+
+// Running watchers for the rc
+var (
+	watchersMu sync.Mutex
+	watchers   = map[int]*watcher{}
+	watcherID  = 0
+)
+
+// Watch syncs fsrc to fdst then keeps fdst in sync by listening for
+// changes in fsrc and syncing just the paths which changed.
+//
+// Changes are synced once there have been none for opt.Delay. A full
+// sync is done if fsrc reports that anything may have changed and
+// every --full-sync-interval.
+//
+// It returns when ctx is cancelled or if there is a fatal error.
+// Other errors are logged and the sync retried.
+func Watch(ctx context.Context, fdst, fsrc fs.Fs, opt WatchOpt) error {
+	doChangeNotify := fsrc.Features().ChangeNotify
+	if doChangeNotify == nil {
+		if fsrc.Features().IsLocal && runtime.GOOS == "linux" {
+			return fserrors.FatalError(errors.Errorf("%v doesn't support change notifications unless --local-change-notify is set so can't be watched", fsrc))
+		}
+		return fserrors.FatalError(errors.Errorf("%v doesn't support change notifications so can't be watched", fsrc))
+	}
+	w := &watcher{
+		fdst:    fdst,
+		fsrc:    fsrc,
+		opt:     opt,
+		changed: make(chan struct{}, 1),
+		queued:  make(changes.Set),
+	}
+	watchersMu.Lock()
+	watcherID++
+	w.id = watcherID
+	watchers[w.id] = w
+	watchersMu.Unlock()
+	defer func() {
+		watchersMu.Lock()
+		delete(watchers, w.id)
+		watchersMu.Unlock()
+	}()
+
+	// Listen for changes before the first sync so none are missed
+	pollInterval := make(chan time.Duration)
+	doChangeNotify(ctx, func(remote string, entryType fs.EntryType) {
+		fs.Debugf(fsrc, "Change to %q", remote)
+		w.add(remote)
+	}, pollInterval)
+	pollInterval <- opt.PollInterval
+	defer close(pollInterval)
+
+	// Start with a full sync
+	w.add(changes.Root)
+	return w.run(ctx)
+}
+
+// add queues remote to be synced
+func (w *watcher) add(remote string) {
+	w.mu.Lock()
+	now := time.Now()
+	if len(w.queued) == 0 {
+		w.firstChange = now
+	}
+	w.lastChange = now
+	w.queued.Add(remote)
+	w.mu.Unlock()
+	select {
+	case w.changed <- struct{}{}:
+	default:
+	}
+}
+
+// due returns how long until the queued changes should be synced or
+// false if there are none
+func (w *watcher) due() (time.Duration, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.queued) == 0 {
+		return 0, false
+	}
+	at := w.lastChange.Add(w.opt.Delay)
+	if latest := w.firstChange.Add(maxDelayFactor * w.opt.Delay); latest.Before(at) {
+		at = latest
+	}
+	if w.notBefore.After(at) {
+		at = w.notBefore
+	}
+	return time.Until(at), true
+}
+
+// run syncs the changes as they are due until ctx is cancelled
+func (w *watcher) run(ctx context.Context) error {
+	ci := fs.GetConfig(ctx)
+	var fullSyncC <-chan time.Time
+	if ci.FullSyncInterval > 0 {
+		ticker := time.NewTicker(ci.FullSyncInterval)
+		defer ticker.Stop()
+		fullSyncC = ticker.C
+	}
+	for {
+		var timer *time.Timer
+		var syncC <-chan time.Time
+		if d, ok := w.due(); ok {
+			timer = time.NewTimer(d)
+			syncC = timer.C
+		}
+		var err error
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-w.changed:
+		case <-fullSyncC:
+			w.add(changes.Root)
+		case <-syncC:
+			err = w.sync(ctx)
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if fserrors.IsFatalError(err) {
+			return err
+		}
+	}
+}
+
+// sync the queued changes
+func (w *watcher) sync(ctx context.Context) error {
+	ci := fs.GetConfig(ctx)
+	w.mu.Lock()
+	set := w.queued
+	w.queued = make(changes.Set)
+	full := set.HasRoot()
+	var paths []string
+	if full {
+		w.inFlight = []string{changes.Root}
+	} else {
+		paths = set.Paths()
+		w.inFlight = paths
+	}
+	w.mu.Unlock()
+
+	if full {
+		fs.Infof(w.fsrc, "Syncing everything")
+	} else {
+		fs.Infof(w.fsrc, "Syncing %d changed paths", len(paths))
+	}
+	// Errors are counted per sync so earlier ones don't stop deletions
+	accounting.Stats(ctx).ResetErrors()
+	err := runSyncCopyMovePaths(ctx, w.fdst, w.fsrc, ci.DeleteMode, false, false, w.opt.CopyEmptySrcDirs, paths)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.inFlight = nil
+	if err != nil {
+		fs.Errorf(w.fsrc, "Sync failed - will retry: %v", err)
+		w.lastError = err
+		w.lastErrorAt = time.Now()
+		// Put the paths back to be tried again
+		if len(w.queued) == 0 {
+			w.firstChange = time.Now()
+			w.lastChange = w.firstChange
+		}
+		for remote := range set {
+			w.queued.Add(remote)
+		}
+		w.retryDelay *= 2
+		if w.retryDelay < w.opt.Delay {
+			w.retryDelay = w.opt.Delay
+		}
+		if w.retryDelay > maxRetryDelay {
+			w.retryDelay = maxRetryDelay
+		}
+		w.notBefore = time.Now().Add(w.retryDelay)
+		return err
+	}
+	w.retryDelay = 0
+	w.notBefore = time.Time{}
+	w.lastSync = time.Now()
+	if full {
+		w.lastFullSync = w.lastSync
+	}
+	w.syncs++
+	return nil
+}
+
+// status returns the state of the watcher for the rc
+func (w *watcher) status() rc.Params {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	queued := []string{}
+	if w.queued.HasRoot() {
+		queued = append(queued, changes.Root)
+	} else {
+		queued = append(queued, w.queued.Paths()...)
+	}
+	inFlight := append([]string{}, w.inFlight...)
+	out := rc.Params{
+		"id":       w.id,
+		"srcFs":    fs.ConfigString(w.fsrc),
+		"dstFs":    fs.ConfigString(w.fdst),
+		"queued":   queued,
+		"inFlight": inFlight,
+		"syncs":    w.syncs,
+	}
+	if w.lastError != nil {
+		out["lastError"] = w.lastError.Error()
+		out["lastErrorTime"] = w.lastErrorAt
+	}
+	if !w.lastSync.IsZero() {
+		out["lastSync"] = w.lastSync
+	}
+	if !w.lastFullSync.IsZero() {
+		out["lastFullSync"] = w.lastFullSync
+	}
+	return out
+}
+
+// watchStatus returns the state of all the running watchers
+func watchStatus() []rc.Params {
+	watchersMu.Lock()
+	ws := make([]*watcher, 0, len(watchers))
+	for _, w := range watchers {
+		ws = append(ws, w)
+	}
+	watchersMu.Unlock()
+	sort.Slice(ws, func(i, j int) bool {
+		return ws[i].id < ws[j].id
+	})
+	out := make([]rc.Params, len(ws))
+	for i, w := range ws {
+		out[i] = w.status()
+	}
+	return out
+}
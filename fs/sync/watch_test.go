@@ -0,0 +1,95 @@
+package sync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatch(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	ci.FullSyncInterval = 0
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	flocal, err := fs.NewFs(ctx, ":local,change_notify:"+r.LocalName)
+	require.NoError(t, err)
+	if flocal.Features().ChangeNotify == nil {
+		t.Skip("local backend can't notify changes on this OS")
+	}
+
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	r.WriteObject(ctx, "deleted", "deleted contents", t1)
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() {
+		done <- Watch(ctx, r.Fremote, flocal, WatchOpt{
+			Delay:        10 * time.Millisecond,
+			PollInterval: time.Second,
+		})
+	}()
+
+	// Wait for remote to exist on the destination or not
+	waitFor := func(remote string, exists bool) {
+		for tries := 0; tries < 100; tries++ {
+			_, err := r.Fremote.NewObject(ctx, remote)
+			if (err == nil) == exists {
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		t.Fatalf("timed out waiting for %q exists=%v", remote, exists)
+	}
+
+	// The first sync is a full sync
+	waitFor("file1", true)
+	waitFor("deleted", false)
+
+	// Then changes are synced
+	file2 := r.WriteFile("dir/file2", "file2 contents", t2)
+	waitFor("dir/file2", true)
+	r.WriteFile("dir/file3", "file3 contents", t3)
+	waitFor("dir/file3", true)
+	require.NoError(t, flocal.Features().Purge(ctx, "dir"))
+	waitFor("dir/file3", false)
+	waitFor("dir/file2", false)
+	file2 = r.WriteFile("dir/file2", "file2 contents again", t2)
+	waitFor("dir/file2", true)
+
+	// The state is shown by the rc
+	call := rc.Calls.Get("sync/watchstatus")
+	require.NotNil(t, call)
+	var status rc.Params
+	for tries := 0; tries < 100; tries++ {
+		out, err := call.Fn(ctx, nil)
+		require.NoError(t, err)
+		watchers := out["watchers"].([]rc.Params)
+		require.Equal(t, 1, len(watchers))
+		status = watchers[0]
+		if len(status["queued"].([]string)) == 0 && len(status["inFlight"].([]string)) == 0 {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	assert.Equal(t, fs.ConfigString(flocal), status["srcFs"])
+	assert.Equal(t, fs.ConfigString(r.Fremote), status["dstFs"])
+	assert.Equal(t, []string{}, status["queued"])
+	assert.Equal(t, []string{}, status["inFlight"])
+	assert.True(t, status["syncs"].(int) > 1)
+	assert.NotNil(t, status["lastFullSync"])
+	assert.Nil(t, status["lastError"])
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+
+	cancel()
+	require.NoError(t, <-done)
+	out, err := call.Fn(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(out["watchers"].([]rc.Params)))
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/rclone/rclone/fs/dirtree"
 	"github.com/rclone/rclone/fs/filter"
 	"github.com/rclone/rclone/fs/list"
+	"golang.org/x/sync/errgroup"
 )
 
 // ErrorSkipDir is used as a return value from Walk to indicate that the
@@ -573,6 +574,88 @@ func NewDirTree(ctx context.Context, f fs.Fs, path string, includeAll bool, maxL
 	return walkNDirTree(ctx, f, path, includeAll, maxLevel, list.DirSorted)
 }
 
+// NewDirTreePaths returns a DirTree filled with just the objects at
+// paths and the contents of any directories at paths. The other
+// directories aren't listed.
+//
+// Paths which don't exist are ignored.
+//
+// If includeAll is not set it will use the filters defined.
+//
+// If maxLevel is < 0 then it will recurse indefinitely, else it will
+// only do maxLevel levels.
+func NewDirTreePaths(ctx context.Context, f fs.Fs, path string, includeAll bool, maxLevel int, paths []string) (dirtree.DirTree, error) {
+	return walkRDirTree(ctx, f, path, includeAll, maxLevel, makePathsListR(f, paths))
+}
+
+// makePathsListR makes a function to return the objects at paths and
+// everything in the directories at paths
+//
+// None of paths should be inside another one
+func makePathsListR(f fs.Fs, paths []string) fs.ListRFn {
+	return func(ctx context.Context, dir string, callback fs.ListRCallback) error {
+		ci := fs.GetConfig(ctx)
+		var (
+			remotes = make(chan string, ci.Checkers)
+			g       errgroup.Group
+		)
+		for i := 0; i < ci.Checkers; i++ {
+			g.Go(func() (err error) {
+				for remote := range remotes {
+					err = listPath(ctx, f, remote, callback)
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+		for _, remote := range paths {
+			remotes <- remote
+		}
+		close(remotes)
+		return g.Wait()
+	}
+}
+
+// listPath calls callback with the object at remote or the directory
+// at remote and everything in it
+func listPath(ctx context.Context, f fs.Fs, remote string, callback fs.ListRCallback) error {
+	if remote != "" {
+		o, err := f.NewObject(ctx, remote)
+		switch errors.Cause(err) {
+		case nil:
+			return callback(fs.DirEntries{o})
+		case fs.ErrorObjectNotFound, fs.ErrorNotAFile:
+		default:
+			return err
+		}
+	}
+	entries, err := list.DirSorted(ctx, f, true, remote)
+	if err == fs.ErrorDirNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	// Buckets can't tell an empty directory from a missing one
+	if remote != "" && (len(entries) > 0 || f.Features().CanHaveEmptyDirectories) {
+		entries = append(entries, fs.NewDir(remote, time.Now()))
+	}
+	err = callback(entries)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if dir, ok := entry.(fs.Directory); ok && dir.Remote() != remote {
+			err = ListR(ctx, f, dir.Remote(), true, -1, ListAll, callback)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func walkR(ctx context.Context, f fs.Fs, path string, includeAll bool, maxLevel int, fn Func, listR fs.ListRFn) error {
 	dirs, err := walkRDirTree(ctx, f, path, includeAll, maxLevel, listR)
 	if err != nil {
@@ -20,6 +20,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -757,6 +758,7 @@ func Run(t *testing.T, opt *Opt) {
 			require.NoError(t, err)
 
 			pollInterval := make(chan time.Duration)
+			var changesMu sync.Mutex
 			dirChanges := map[string]struct{}{}
 			objChanges := map[string]struct{}{}
 			doChangeNotify(ctx, func(x string, e fs.EntryType) {
@@ -765,6 +767,8 @@ func Run(t *testing.T, opt *Opt) {
 					fs.Debugf(nil, "Ignoring notify for file1 or file2: %q, %v", x, e)
 					return
 				}
+				changesMu.Lock()
+				defer changesMu.Unlock()
 				if e == fs.EntryDirectory {
 					dirChanges[x] = struct{}{}
 				} else if e == fs.EntryObject {
@@ -809,7 +813,9 @@ func Run(t *testing.T, opt *Opt) {
 			wantObjChanges := []string{"dir/file2", "dir/file4", "dir/file3"}
 			ok := false
 			for tries := 1; tries < 10; tries++ {
+				changesMu.Lock()
 				ok = contains(dirChanges, wantDirChanges) && contains(objChanges, wantObjChanges)
+				changesMu.Unlock()
 				if ok {
 					break
 				}
@@ -817,7 +823,9 @@ func Run(t *testing.T, opt *Opt) {
 				time.Sleep(3 * time.Second)
 			}
 			if !ok {
+				changesMu.Lock()
 				t.Errorf("%+v does not contain %+v or \n%+v does not contain %+v", dirChanges, wantDirChanges, objChanges, wantObjChanges)
+				changesMu.Unlock()
 			}
 
 			// tidy up afterwards
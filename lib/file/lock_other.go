@@ -0,0 +1,21 @@
+//+build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package file
+
+import "os"
+
+// LockImplemented is a constant indicating whether the
+// implementation of Lock actually does anything.
+const LockImplemented = false
+
+// Lock takes an exclusive advisory lock on the file, waiting until
+// it is available. Other processes which Lock the same file will
+// wait until Unlock is called or the file is closed.
+func Lock(f *os.File) error {
+	return nil
+}
+
+// Unlock releases the lock taken by Lock
+func Unlock(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,46 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLock(t *testing.T) {
+	if !LockImplemented {
+		t.Skip("Lock not implemented")
+	}
+	dir, tidy := testDir(t)
+	defer tidy()
+	path := filepath.Join(dir, "lock")
+
+	f1, err := os.Create(path)
+	require.NoError(t, err)
+	defer func() { _ = f1.Close() }()
+	f2, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() { _ = f2.Close() }()
+
+	require.NoError(t, Lock(f1))
+	locked := make(chan struct{})
+	go func() {
+		assert.NoError(t, Lock(f2))
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("second lock didn't wait")
+	case <-time.After(100 * time.Millisecond):
+	}
+	require.NoError(t, Unlock(f1))
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("second lock wasn't granted")
+	}
+	require.NoError(t, Unlock(f2))
+}
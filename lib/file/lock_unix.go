@@ -0,0 +1,30 @@
+//+build darwin dragonfly freebsd linux netbsd openbsd
+
+package file
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// LockImplemented is a constant indicating whether the
+// implementation of Lock actually does anything.
+const LockImplemented = true
+
+// Lock takes an exclusive advisory lock on the file, waiting until
+// it is available. Other processes which Lock the same file will
+// wait until Unlock is called or the file is closed.
+func Lock(f *os.File) error {
+	for {
+		err := unix.Flock(int(f.Fd()), unix.LOCK_EX)
+		if err != unix.EINTR {
+			return err
+		}
+	}
+}
+
+// Unlock releases the lock taken by Lock
+func Unlock(f *os.File) error {
+	return unix.Flock(int(f.Fd()), unix.LOCK_UN)
+}
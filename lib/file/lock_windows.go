@@ -0,0 +1,25 @@
+//+build windows
+
+package file
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// LockImplemented is a constant indicating whether the
+// implementation of Lock actually does anything.
+const LockImplemented = true
+
+// Lock takes an exclusive advisory lock on the file, waiting until
+// it is available. Other processes which Lock the same file will
+// wait until Unlock is called or the file is closed.
+func Lock(f *os.File) error {
+	return windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, &windows.Overlapped{})
+}
+
+// Unlock releases the lock taken by Lock
+func Unlock(f *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, &windows.Overlapped{})
+}
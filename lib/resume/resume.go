@@ -0,0 +1,274 @@
+// Package resume persists the state of multipart and session uploads
+// so they can be resumed by a later run of rclone.
+//
+// operations.Copy attaches an Upload to the context passed to Put and
+// Update when --resume-uploads is in effect. Backends which support
+// resuming fetch it with Get, Load the state saved by the last attempt
+// to upload the same source to the same destination, Save their state
+// once the upload session has been created and Remove it when the
+// upload has finished.
+package resume
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/config"
+)
+
+// Dir is the directory the upload state is kept in. If it is empty
+// the "resume" directory in the rclone cache directory is used.
+var Dir = ""
+
+// dir returns the directory to keep the upload state in
+func dir() string {
+	if Dir != "" {
+		return Dir
+	}
+	return filepath.Join(config.CacheDir, "resume")
+}
+
+// Upload is a resumable upload of a source to a file on a remote
+type Upload struct {
+	path        string // path of the state file
+	fsString    string // config string of the destination Fs
+	remote      string // path of the destination within the Fs
+	fingerprint string // fingerprint of the source
+}
+
+// stateFile is the format of the files in Dir
+type stateFile struct {
+	Fs          string          // config string of the destination Fs
+	Remote      string          // path of the destination within the Fs
+	Fingerprint string          // fingerprint of the source
+	Saved       time.Time       // when the state was saved
+	State       json.RawMessage // state of the upload saved by the backend
+}
+
+type uploadKey struct{}
+
+// WithUpload returns a new context which tells the backend that
+// uploading to remote on f can be resumed. The saved state is only
+// used for a source with the same fingerprint, as returned by
+// fs.Fingerprint.
+func WithUpload(ctx context.Context, f fs.Fs, remote, fingerprint string) context.Context {
+	fsString := fs.ConfigString(f)
+	sum := md5.Sum([]byte(fsString + "\x00" + remote))
+	u := &Upload{
+		path:        filepath.Join(dir(), hex.EncodeToString(sum[:])+".json"),
+		fsString:    fsString,
+		remote:      remote,
+		fingerprint: fingerprint,
+	}
+	return context.WithValue(ctx, uploadKey{}, u)
+}
+
+// Get returns the Upload in ctx or nil if the upload shouldn't be
+// resumed
+func Get(ctx context.Context) *Upload {
+	u, _ := ctx.Value(uploadKey{}).(*Upload)
+	return u
+}
+
+// Without returns a new context in which the upload can't be resumed.
+//
+// Backends which wrap other backends and change the data or the path
+// it is stored at (for example by encrypting or splitting it) must
+// pass this to the wrapped backend, as the state it saves would
+// otherwise be used to resume an upload of different data.
+func Without(ctx context.Context) context.Context {
+	if Get(ctx) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, uploadKey{}, (*Upload)(nil))
+}
+
+// String returns a description of the upload for logging
+func (u *Upload) String() string {
+	return u.remote
+}
+
+// readStateFile reads the state file at path
+func readStateFile(path string) (*stateFile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sf stateFile
+	err = json.Unmarshal(data, &sf)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode resume state %q", path)
+	}
+	return &sf, nil
+}
+
+// Load reads the state saved by the last attempt at the upload into
+// state, returning false if there isn't any.
+//
+// State saved for a source with a different fingerprint is stale and
+// is discarded. The upload session it refers to will be removed by
+// the cleanup command of the backend.
+func (u *Upload) Load(state interface{}) (found bool, err error) {
+	sf, err := readStateFile(u.path)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if sf.Fs != u.fsString || sf.Remote != u.remote || sf.Fingerprint != u.fingerprint {
+		fs.Debugf(u, "Discarding stale resume state saved %v", sf.Saved)
+		u.Remove()
+		return false, nil
+	}
+	err = json.Unmarshal(sf.State, state)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to decode resume state")
+	}
+	return true, nil
+}
+
+// Save writes state so a later attempt at the upload can resume it
+func (u *Upload) Save(state interface{}) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode resume state")
+	}
+	data, err = json.MarshalIndent(&stateFile{
+		Fs:          u.fsString,
+		Remote:      u.remote,
+		Fingerprint: u.fingerprint,
+		Saved:       time.Now(),
+		State:       data,
+	}, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode resume state")
+	}
+	err = os.MkdirAll(filepath.Dir(u.path), 0700)
+	if err != nil {
+		return errors.Wrap(err, "failed to make resume directory")
+	}
+	// The state may contain upload URLs which grant access so keep
+	// it private
+	tmpPath := u.path + ".tmp"
+	err = ioutil.WriteFile(tmpPath, data, 0600)
+	if err == nil {
+		err = os.Rename(tmpPath, u.path)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to write resume state")
+	}
+	return nil
+}
+
+// Remove removes the saved state, for example when the upload is
+// complete or can't be resumed
+func (u *Upload) Remove() {
+	err := os.Remove(u.path)
+	if err != nil && !os.IsNotExist(err) {
+		fs.Errorf(u, "Failed to remove resume state: %v", err)
+	}
+}
+
+// Cleanup removes the saved state of all the uploads to f and to the
+// directories below it.
+//
+// This should be called when the upload sessions on f have been
+// removed, for example by the cleanup command.
+func Cleanup(f fs.Fs) error {
+	entries, err := ioutil.ReadDir(dir())
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "failed to read resume directory")
+	}
+	prefix := fs.ConfigString(f)
+	if !strings.HasSuffix(prefix, ":") && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir(), entry.Name())
+		sf, err := readStateFile(path)
+		if err != nil {
+			fs.Debugf(nil, "Ignoring resume state: %v", err)
+			continue
+		}
+		target := sf.Fs
+		if !strings.HasSuffix(target, ":") && !strings.HasSuffix(target, "/") {
+			target += "/"
+		}
+		if !strings.HasPrefix(target+sf.Remote, prefix) {
+			continue
+		}
+		fs.Debugf(f, "Removing resume state for %q", sf.Remote)
+		err = os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "failed to remove resume state")
+		}
+	}
+	return nil
+}
+
+// skipAccounter is implemented by the accounting readers to mark
+// bytes as done without reading them
+type skipAccounter interface {
+	AccountSkip(n int64)
+}
+
+// Skip skips n bytes of in which have been uploaded already by a
+// previous attempt at the upload.
+//
+// The bytes are skipped in the reader under any accounting, seeking
+// it if possible, so they aren't counted as transferred or limited by
+// --bwlimit. They are marked as done in the stats of the transfer
+// instead.
+func Skip(in io.Reader, n int64) (err error) {
+	unwrapped, _ := accounting.UnWrap(in)
+	seeker, ok := unwrapped.(io.Seeker)
+	if ok {
+		ok, err = seek(seeker, n)
+	}
+	if !ok {
+		_, err = io.CopyN(ioutil.Discard, unwrapped, n)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to skip uploaded data")
+	}
+	if acc, ok := in.(skipAccounter); ok {
+		acc.AccountSkip(n)
+	}
+	return nil
+}
+
+// seek skips n bytes of seeker. It returns ok false if seeker can't
+// seek, e.g. if it is a pipe, and io.ErrUnexpectedEOF if n is past the
+// end as seeking there isn't an error.
+func seek(seeker io.Seeker, n int64) (ok bool, err error) {
+	pos, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false, nil
+	}
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return false, nil
+	}
+	if pos+n > end {
+		_, _ = seeker.Seek(pos, io.SeekStart)
+		return true, io.ErrUnexpectedEOF
+	}
+	_, err = seeker.Seek(pos+n, io.SeekStart)
+	return true, err
+}
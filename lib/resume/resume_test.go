@@ -0,0 +1,142 @@
+package resume
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testState struct {
+	ID   string
+	Part int
+}
+
+// setDir sets Dir to a temporary directory for the test
+func setDir(t *testing.T) func() {
+	tmp, err := ioutil.TempDir("", "rclone-resume-test")
+	require.NoError(t, err)
+	oldDir := Dir
+	Dir = tmp
+	return func() {
+		Dir = oldDir
+		require.NoError(t, os.RemoveAll(tmp))
+	}
+}
+
+// states returns the number of state files in Dir
+func states(t *testing.T) int {
+	matches, err := filepath.Glob(filepath.Join(Dir, "*.json"))
+	require.NoError(t, err)
+	return len(matches)
+}
+
+func TestUpload(t *testing.T) {
+	defer setDir(t)()
+	ctx := context.Background()
+	f := mockfs.NewFs(ctx, "remote", "bucket")
+
+	assert.Nil(t, Get(ctx))
+	up := Get(WithUpload(ctx, f, "file", "fingerprint"))
+	require.NotNil(t, up)
+	assert.Equal(t, "file", up.String())
+
+	// Nothing saved yet
+	var state testState
+	found, err := up.Load(&state)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// Save and load
+	require.NoError(t, up.Save(&testState{ID: "id", Part: 3}))
+	assert.Equal(t, 1, states(t))
+	found, err = up.Load(&state)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, testState{ID: "id", Part: 3}, state)
+
+	// The state of a different source is discarded
+	stale := Get(WithUpload(ctx, f, "file", "changed"))
+	found, err = stale.Load(&state)
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.Equal(t, 0, states(t))
+
+	// Remove
+	require.NoError(t, up.Save(&testState{ID: "id"}))
+	up.Remove()
+	assert.Equal(t, 0, states(t))
+	up.Remove()
+}
+
+func TestCleanup(t *testing.T) {
+	defer setDir(t)()
+	ctx := context.Background()
+	f := mockfs.NewFs(ctx, "remote", "bucket")
+	other := mockfs.NewFs(ctx, "remote", "bucket2")
+
+	require.NoError(t, Get(WithUpload(ctx, f, "file", "fp")).Save(&testState{}))
+	require.NoError(t, Get(WithUpload(ctx, f, "dir/file", "fp")).Save(&testState{}))
+	keep := Get(WithUpload(ctx, other, "file", "fp"))
+	require.NoError(t, keep.Save(&testState{}))
+	assert.Equal(t, 3, states(t))
+
+	require.NoError(t, Cleanup(f))
+	assert.Equal(t, 1, states(t))
+	found, err := keep.Load(&testState{})
+	require.NoError(t, err)
+	assert.True(t, found)
+}
+
+func TestSkip(t *testing.T) {
+	in := strings.NewReader("0123456789")
+	require.NoError(t, Skip(in, 4))
+	rest, err := ioutil.ReadAll(in)
+	require.NoError(t, err)
+	assert.Equal(t, "456789", string(rest))
+	assert.Error(t, Skip(in, 1))
+
+	// Readers which can't seek are read instead
+	in2 := ioutil.NopCloser(strings.NewReader("0123456789"))
+	require.NoError(t, Skip(in2, 4))
+	rest, err = ioutil.ReadAll(in2)
+	require.NoError(t, err)
+	assert.Equal(t, "456789", string(rest))
+	assert.Error(t, Skip(in2, 1))
+}
+
+func TestSkipAccounted(t *testing.T) {
+	ctx := context.Background()
+	stats := accounting.NewStats(ctx)
+	tr := stats.NewTransferRemoteSize("file", 10)
+	defer tr.Done(ctx, nil)
+	in := tr.Account(ctx, ioutil.NopCloser(strings.NewReader("0123456789")))
+
+	// The skipped bytes are done but not transferred
+	require.NoError(t, Skip(in, 4))
+	assert.Equal(t, int64(0), stats.GetBytes())
+	assert.Equal(t, int64(4), tr.Snapshot().Bytes)
+
+	rest, err := ioutil.ReadAll(in)
+	require.NoError(t, err)
+	assert.Equal(t, "456789", string(rest))
+	assert.Equal(t, int64(6), stats.GetBytes())
+	assert.Equal(t, int64(10), tr.Snapshot().Bytes)
+}
+
+func TestWithout(t *testing.T) {
+	ctx := context.Background()
+	f := mockfs.NewFs(ctx, "remote", "bucket")
+
+	assert.Equal(t, ctx, Without(ctx))
+	ctx = WithUpload(ctx, f, "file", "fingerprint")
+	require.NotNil(t, Get(ctx))
+	assert.Nil(t, Get(Without(ctx)))
+}
@@ -83,6 +83,7 @@ find that you need one or the other or both.
     --vfs-cache-max-size SizeSuffix      Max total size of objects in the cache. (default off)
     --vfs-cache-poll-interval duration   Interval to poll the cache for stale objects. (default 1m0s)
     --vfs-write-back duration            Time to writeback files after last use when using cache. (default 5s)
+    --vfs-stats duration                 Interval to log the stats of the vfs cache at (eg 1m). 0 for off.
 
 If run with ` + "`-vv`" + ` rclone will print the location of the file cache.  The
 files are stored in the user cache file area which is OS dependent but
@@ -104,6 +105,15 @@ for two reasons.  Firstly because it is only checked every
 --vfs-cache-poll-interval.  Secondly because open files cannot be
 evicted from the cache.
 
+Use --vfs-stats to log how full the cache is and how many files are
+waiting to be uploaded at regular intervals. The remote control can
+show this too with ` + "`rclone rc vfs/stats`" + `, list the files in the
+cache and their state with ` + "`rclone rc vfs/cache-items`" + ` and show
+the upload queue with ` + "`rclone rc vfs/queue`" + `. An upload can be
+started straight away, or retried if it failed, with
+
+    rclone rc vfs/queue-set-expiry id=123 expiry=0
+
 #### --vfs-cache-mode off
 
 In this mode (the default) the cache will read directly from the remote and write
@@ -5,12 +5,16 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
 	"github.com/rclone/rclone/fs/cache"
 	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/vfs/vfscache"
+	"github.com/rclone/rclone/vfs/vfscache/writeback"
 )
 
 const getVFSHelp = ` 
@@ -389,3 +393,294 @@ func rcList(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 	out["vfses"] = names
 	return out, nil
 }
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/stats",
+		Title: "Stats for a VFS.",
+		Help: `
+This returns stats for the selected VFS.
+
+    {
+        // Status of the Disk Cache - only present if --vfs-cache-mode > off
+        "diskCache": {
+            "bytesUsed": 0,
+            "dirty": 0,
+            "erroredFiles": 0,
+            "files": 0,
+            "inUse": 0,
+            "maxSize": -1,
+            "outOfSpace": false,
+            "path": "/home/user/.cache/rclone/vfs/local/mnt/a",
+            "pathMeta": "/home/user/.cache/rclone/vfsMeta/local/mnt/a",
+            "uploadsInProgress": 0,
+            "uploadsQueued": 0
+        },
+        "fs": "/mnt/a",
+        "inUse": 1,
+        "opt": {
+            // All the VFS options
+        }
+    }
+
+"maxSize" is the --vfs-cache-max-size in bytes or -1 if there isn't a
+limit. "dirty" is the number of files waiting to be uploaded.
+` + getVFSHelp,
+		Fn: rcStats,
+	})
+}
+
+func rcStats(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	out = rc.Params{
+		"fs":    fs.ConfigString(vfs.f),
+		"opt":   vfs.Opt,
+		"inUse": atomic.LoadInt32(&vfs.inUse),
+	}
+	if vfs.cache != nil {
+		out["diskCache"] = vfs.cache.Stats()
+	}
+	return out, nil
+}
+
+// getCache returns the cache of the VFS or an error if it doesn't
+// have one.
+func getCache(vfs *VFS) (*vfscache.Cache, error) {
+	if vfs.cache == nil {
+		return nil, errors.New("VFS has no cache - need --vfs-cache-mode > off")
+	}
+	return vfs.cache, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/cache-items",
+		Title: "List the files in the VFS cache.",
+		Help: `
+This returns the files in the cache of the selected VFS under the key
+"items", sorted by name. Each has the state of the file which is one of
+
+- clean - the cache file is the same as the remote
+- dirty - the cache file is waiting to be uploaded
+- uploading - the cache file is being uploaded
+- failed - the last upload failed and will be retried
+
+If the file failed to upload the error is in "error". The "id" of a
+file waiting to be uploaded can be passed to vfs/queue-set-expiry.
+
+    {
+        "items": [
+            {
+                "atime": "2021-03-12T16:58:41.123456789Z",
+                "diskSize": 1048576,
+                "error": "permission denied",
+                "id": 3,
+                "name": "dir/file.txt",
+                "opens": 0,
+                "size": 1048576,
+                "state": "failed"
+            }
+        ]
+    }
+
+Pass state=dirty (for example) to only list the files in that state.
+` + getVFSHelp,
+		Fn: rcCacheItems,
+	})
+}
+
+func rcCacheItems(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	state, err := in.GetString("state")
+	if rc.IsErrParamNotFound(err) {
+		state = ""
+	} else if err != nil {
+		return nil, err
+	}
+	c, err := getCache(vfs)
+	if err != nil {
+		return nil, err
+	}
+	items := []vfscache.ItemInfo{}
+	for _, item := range c.Items() {
+		if state == "" || item.State == state {
+			items = append(items, item)
+		}
+	}
+	return rc.Params{"items": items}, nil
+}
+
+// queueItem is an item in the writeback queue as returned by vfs/queue
+type queueItem struct {
+	writeback.QueueInfo
+	Expiry     float64     `json:"expiry"`               // seconds until the upload starts
+	ETA        interface{} `json:"eta,omitempty"`        // seconds until the upload finishes if uploading
+	Percentage interface{} `json:"percentage,omitempty"` // percentage uploaded if uploading
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/queue",
+		Title: "Queue info for a VFS.",
+		Help: `
+This returns info about the upload queue for the selected VFS.
+
+This is only useful if --vfs-cache-mode > off. If you call it when
+the --vfs-cache-mode is off, it will return an empty result.
+
+    {
+        "queued": // an array of files queued for upload
+        [
+            {
+                "name":      "file",   // string: name (full path) of the file,
+                "id":        123,      // integer: id of this item in the queue,
+                "expiry":    0.5,      // float: time until file is eligible for transfer, lowest goes first
+                "tries":     1,        // integer: number of times we have tried to upload
+                "delay":     5.0,      // float: seconds between upload attempts
+                "uploading": false,    // boolean: true if item is being uploaded
+                "error":     "...",    // string: error from the last failed upload if any
+                "eta":       12.5,     // float: seconds until the upload finishes if uploading
+                "percentage": 50       // integer: percentage uploaded if uploading
+            },
+        ],
+    }
+
+The queue is in the order the files will be uploaded, starting with
+the files being uploaded. The "expiry" time is the time until the
+upload of the file starts in seconds. It may go negative if the file
+is waiting for a free --transfers slot. The "eta" of a file being
+uploaded comes from the transfer stats and may be missing if not
+known yet.
+
+The "id" can be passed to vfs/queue-set-expiry to change the order
+of the queue or retry a failed upload straight away.
+` + getVFSHelp,
+		Fn: rcQueue,
+	})
+}
+
+// uploadStats returns the transfer stats of the files being uploaded
+// indexed by name
+func uploadStats() map[string]rc.Params {
+	stats := map[string]rc.Params{}
+	out, err := accounting.GlobalStats().RemoteStats()
+	if err != nil {
+		return stats
+	}
+	transferring, _ := out["transferring"].([]rc.Params)
+	for _, tr := range transferring {
+		if name, ok := tr["name"].(string); ok {
+			stats[name] = tr
+		}
+	}
+	return stats
+}
+
+func rcQueue(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	queued := []queueItem{}
+	if vfs.cache != nil {
+		now := time.Now()
+		var stats map[string]rc.Params
+		for _, info := range vfs.cache.Queue() {
+			item := queueItem{
+				QueueInfo: info,
+				Expiry:    info.Expiry.Sub(now).Seconds(),
+			}
+			if info.Uploading {
+				if stats == nil {
+					stats = uploadStats()
+				}
+				if tr, ok := stats[info.Name]; ok {
+					item.ETA = tr["eta"]
+					item.Percentage = tr["percentage"]
+				}
+			}
+			queued = append(queued, item)
+		}
+	}
+	return rc.Params{"queued": queued}, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/queue-set-expiry",
+		Title: "Set the expiry time for an item queued for upload.",
+		Help: `
+Use this to adjust the "expiry" time for an item in the upload queue.
+You will need to read the "id" of the item using vfs/queue before
+using this call.
+
+You can then set "expiry" to a floating point number of seconds from
+now when the item is eligible for upload. If you want the item to be
+uploaded as soon as possible then set it to a large negative number (eg
+-1000000000). If you want the upload of the item to be delayed
+for a long time then set it to a large positive number.
+
+Setting the "expiry" of an item which has already started
+uploading will return an error - the item will have been removed from
+the queue.
+
+If "relative" is true then the expiry time is relative to the current
+expiry of the item, otherwise it is relative to now.
+
+Setting the expiry of a file whose upload failed to now retries the
+upload straight away.
+
+    rclone rc vfs/queue-set-expiry id=123 expiry=0
+` + getVFSHelp,
+		Fn: rcQueueSetExpiry,
+	})
+}
+
+func rcQueueSetExpiry(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	c, err := getCache(vfs)
+	if err != nil {
+		return nil, err
+	}
+	id, err := in.GetInt64("id")
+	if err != nil {
+		return nil, err
+	}
+	expiry, err := in.GetFloat64("expiry")
+	if err != nil {
+		return nil, err
+	}
+	relative, err := in.GetBool("relative")
+	if err != nil && !rc.IsErrParamNotFound(err) {
+		return nil, err
+	}
+	base := time.Now()
+	if relative {
+		info, found := findQueueItem(c, writeback.Handle(id))
+		if !found {
+			return nil, writeback.ErrorIDNotFound
+		}
+		base = info.Expiry
+	}
+	err = c.QueueSetExpiry(writeback.Handle(id), base.Add(time.Duration(expiry*float64(time.Second))))
+	return nil, err
+}
+
+// findQueueItem finds the item with the id in the writeback queue
+func findQueueItem(c *vfscache.Cache, id writeback.Handle) (info writeback.QueueInfo, found bool) {
+	for _, info = range c.Queue() {
+		if info.ID == id {
+			return info, true
+		}
+	}
+	return info, false
+}
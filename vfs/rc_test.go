@@ -3,10 +3,13 @@ package vfs
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/rc"
 	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/vfs/vfscache"
+	"github.com/rclone/rclone/vfs/vfscache/writeback"
 	"github.com/rclone/rclone/vfs/vfscommon"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -119,3 +122,100 @@ func TestRcList(t *testing.T) {
 		},
 	}, out)
 }
+
+func TestRcStats(t *testing.T) {
+	r, vfs, cleanup, call := rcNewRun(t, "vfs/stats")
+	defer cleanup()
+	out, err := call.Fn(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, fs.ConfigString(r.Fremote), out["fs"])
+	assert.Equal(t, int32(1), out["inUse"])
+	assert.Equal(t, vfs.Opt, out["opt"])
+	assert.Nil(t, out["diskCache"])
+
+	// No cache
+	for _, method := range []string{"vfs/cache-items", "vfs/queue-set-expiry"} {
+		_, err = rc.Calls.Get(method).Fn(context.Background(), rc.Params{"id": 1, "expiry": 0})
+		require.Error(t, err, method)
+		assert.Contains(t, err.Error(), "VFS has no cache", method)
+	}
+	out, err = rc.Calls.Get("vfs/queue").Fn(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"queued": []queueItem{}}, out)
+}
+
+func TestRcQueue(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping test on non local remote")
+	}
+	opt := vfscommon.DefaultOpt
+	opt.CacheMode = vfscommon.CacheModeWrites
+	opt.CachePollInterval = 0
+	opt.WriteBack = time.Hour
+	r, vfs, cleanup := newTestVFSOpt(t, &opt)
+	defer cleanup()
+	ctx := context.Background()
+	in := func(params rc.Params) rc.Params {
+		params["fs"] = fs.ConfigString(r.Fremote)
+		return params
+	}
+
+	fd, err := vfs.Create("file.txt")
+	require.NoError(t, err)
+	_, err = fd.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, fd.Close())
+
+	out, err := rc.Calls.Get("vfs/stats").Fn(ctx, in(rc.Params{}))
+	require.NoError(t, err)
+	stats := out["diskCache"].(vfscache.Stats)
+	assert.Equal(t, 1, stats.Files)
+	assert.Equal(t, 1, stats.Dirty)
+	assert.Equal(t, 1, stats.UploadsQueued)
+	assert.Equal(t, int64(5), stats.BytesUsed)
+
+	out, err = rc.Calls.Get("vfs/cache-items").Fn(ctx, in(rc.Params{"state": "dirty"}))
+	require.NoError(t, err)
+	items := out["items"].([]vfscache.ItemInfo)
+	require.Equal(t, 1, len(items))
+	assert.Equal(t, "file.txt", items[0].Name)
+	assert.Equal(t, vfscache.ItemDirty, items[0].State)
+	id := items[0].ID
+
+	out, err = rc.Calls.Get("vfs/cache-items").Fn(ctx, in(rc.Params{"state": "clean"}))
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(out["items"].([]vfscache.ItemInfo)))
+
+	out, err = rc.Calls.Get("vfs/queue").Fn(ctx, in(rc.Params{}))
+	require.NoError(t, err)
+	queued := out["queued"].([]queueItem)
+	require.Equal(t, 1, len(queued))
+	assert.Equal(t, "file.txt", queued[0].Name)
+	assert.Equal(t, id, queued[0].ID)
+	assert.False(t, queued[0].Uploading)
+	assert.True(t, queued[0].Expiry > 3500, queued[0].Expiry)
+
+	// Move the upload later relative to its expiry
+	_, err = rc.Calls.Get("vfs/queue-set-expiry").Fn(ctx, in(rc.Params{"id": int64(id), "expiry": 3600, "relative": true}))
+	require.NoError(t, err)
+	out, err = rc.Calls.Get("vfs/queue").Fn(ctx, in(rc.Params{}))
+	require.NoError(t, err)
+	assert.True(t, out["queued"].([]queueItem)[0].Expiry > 7100)
+
+	_, err = rc.Calls.Get("vfs/queue-set-expiry").Fn(ctx, in(rc.Params{"id": int64(id) + 1, "expiry": 0}))
+	assert.Equal(t, writeback.ErrorIDNotFound, err)
+
+	// Upload it now
+	_, err = rc.Calls.Get("vfs/queue-set-expiry").Fn(ctx, in(rc.Params{"id": int64(id), "expiry": 0}))
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		out, err = rc.Calls.Get("vfs/queue").Fn(ctx, in(rc.Params{}))
+		require.NoError(t, err)
+		if len(out["queued"].([]queueItem)) == 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, 0, len(out["queued"].([]queueItem)))
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{fstest.NewItem("file.txt", "hello", time.Now())}, nil, fs.ModTimeNotSupported)
+}
@@ -137,6 +137,7 @@ func New(ctx context.Context, fremote fs.Fs, opt *vfscommon.Options, avFn AddVir
 	c.cond = sync.NewCond(&c.mu)
 
 	go c.cleaner(ctx)
+	go c.logStats(ctx)
 
 	return c, nil
 }
@@ -698,6 +699,25 @@ func (c *Cache) cleaner(ctx context.Context) {
 	}
 }
 
+// logStats logs the stats of the cache every --vfs-stats
+//
+// doesn't return until context is cancelled
+func (c *Cache) logStats(ctx context.Context) {
+	if c.opt.Stats <= 0 {
+		return
+	}
+	ticker := time.NewTicker(c.opt.Stats)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			fs.Logf(c.fremote, "vfs cache: stats: %v", c.Stats())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 // TotalInUse returns the number of items in the cache which are InUse
 func (c *Cache) TotalInUse() (n int) {
 	c.mu.Lock()
@@ -710,6 +730,89 @@ func (c *Cache) TotalInUse() (n int) {
 	return n
 }
 
+// Stats is information about the cache, returned by Cache.Stats
+type Stats struct {
+	Path              string `json:"path"`              // root of the cache directory
+	PathMeta          string `json:"pathMeta"`          // root of the cache metadata directory
+	Files             int    `json:"files"`             // number of items in the cache
+	InUse             int    `json:"inUse"`             // number of items open or dirty
+	Dirty             int    `json:"dirty"`             // number of items waiting to be uploaded
+	BytesUsed         int64  `json:"bytesUsed"`         // total size of files in the cache
+	MaxSize           int64  `json:"maxSize"`           // --vfs-cache-max-size or -1 if unlimited
+	UploadsInProgress int    `json:"uploadsInProgress"` // number of uploads in progress
+	UploadsQueued     int    `json:"uploadsQueued"`     // number of uploads waiting to start
+	ErroredFiles      int    `json:"erroredFiles"`      // number of items which failed to be reset
+	OutOfSpace        bool   `json:"outOfSpace"`        // set if the disk the cache is on is full
+}
+
+// String returns the stats as a line for the log
+func (s Stats) String() string {
+	maxSize := "off"
+	if s.MaxSize >= 0 {
+		maxSize = fs.SizeSuffix(s.MaxSize).String()
+	}
+	return fmt.Sprintf("objects %d, in use %d, dirty %d, to upload %d, uploading %d, total size %v of max %s, errored %d",
+		s.Files, s.InUse, s.Dirty, s.UploadsQueued, s.UploadsInProgress, fs.SizeSuffix(s.BytesUsed), maxSize, s.ErroredFiles)
+}
+
+// Stats returns information about the cache
+func (c *Cache) Stats() (s Stats) {
+	c.updateUsed()
+	c.mu.Lock()
+	s = Stats{
+		Path:         c.root,
+		PathMeta:     c.metaRoot,
+		Files:        len(c.item),
+		BytesUsed:    c.used,
+		MaxSize:      int64(c.opt.CacheMaxSize),
+		ErroredFiles: len(c.errItems),
+		OutOfSpace:   c.outOfSpace,
+	}
+	for _, item := range c.item {
+		if item.inUse() {
+			s.InUse++
+		}
+		if item.IsDirty() {
+			s.Dirty++
+		}
+	}
+	c.mu.Unlock()
+	s.UploadsInProgress, s.UploadsQueued = c.writeback.Stats()
+	return s
+}
+
+// Items returns information about the items in the cache sorted by
+// name.
+func (c *Cache) Items() []ItemInfo {
+	c.mu.Lock()
+	items := make(Items, 0, len(c.item))
+	for _, item := range c.item {
+		items = append(items, item)
+	}
+	c.mu.Unlock()
+	infos := make([]ItemInfo, 0, len(items))
+	for _, item := range items {
+		infos = append(infos, item.getInfo())
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// Queue returns information about the items waiting to be uploaded
+// or being uploaded, in the order they will be uploaded.
+func (c *Cache) Queue() []writeback.QueueInfo {
+	return c.writeback.Queue()
+}
+
+// QueueSetExpiry sets the time the upload of the item with the id
+// passed in will start. Set it to now to upload it or retry a failed
+// upload straight away.
+func (c *Cache) QueueSetExpiry(id writeback.Handle, expiry time.Time) error {
+	return c.writeback.SetExpiry(id, expiry)
+}
+
 // Dump the cache into a string for debugging purposes
 func (c *Cache) Dump() string {
 	if c == nil {
@@ -11,6 +11,7 @@ import (
 
 	_ "github.com/rclone/rclone/backend/local" // import the local backend
 	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/vfs/vfscache/writeback"
 	"github.com/rclone/rclone/vfs/vfscommon"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -680,6 +681,46 @@ func TestCacheTotaInUse(t *testing.T) {
 	assert.Equal(t, int(0), c.TotalInUse())
 }
 
+func TestCacheStatsAndItems(t *testing.T) {
+	_, c, cleanup := newTestCache(t)
+	defer cleanup()
+
+	stats := c.Stats()
+	assert.Equal(t, c.root, stats.Path)
+	assert.Equal(t, 0, stats.Files)
+	assert.Equal(t, int64(-1), stats.MaxSize)
+	assert.Equal(t, []ItemInfo{}, c.Items())
+
+	potato := c.Item("potato")
+	itemWrite(t, potato, "hello")
+	c.Item("apple")
+
+	stats = c.Stats()
+	assert.Equal(t, 2, stats.Files)
+	assert.Equal(t, 1, stats.InUse)
+	assert.Equal(t, 1, stats.Dirty)
+	assert.Equal(t, int64(5), stats.BytesUsed)
+	assert.Contains(t, stats.String(), "objects 2, in use 1, dirty 1, to upload 0, uploading 0, total size 5 of max off")
+
+	items := c.Items()
+	require.Equal(t, 2, len(items))
+	assert.Equal(t, "apple", items[0].Name)
+	assert.Equal(t, ItemClean, items[0].State)
+	assert.Equal(t, "potato", items[1].Name)
+	assert.Equal(t, ItemDirty, items[1].State)
+	assert.Equal(t, int64(5), items[1].Size)
+	assert.Equal(t, int64(5), items[1].DiskSize)
+	assert.Equal(t, 1, items[1].Opens)
+
+	require.NoError(t, potato.Close(nil))
+
+	items = c.Items()
+	assert.Equal(t, ItemClean, items[1].State)
+	assert.Equal(t, 0, items[1].Opens)
+	assert.Equal(t, 0, c.Stats().Dirty)
+	assert.Equal(t, []writeback.QueueInfo{}, c.Queue())
+}
+
 func TestCacheDump(t *testing.T) {
 	_, c, cleanup := newTestCache(t)
 	defer cleanup()
@@ -459,6 +459,68 @@ func (item *Item) IsDataDirty() bool {
 	return item.info.Dirty
 }
 
+// States of an item in the cache as returned in ItemInfo
+const (
+	ItemClean     = "clean"     // the cache file is the same as the remote
+	ItemDirty     = "dirty"     // the cache file is waiting to be uploaded
+	ItemUploading = "uploading" // the cache file is being uploaded
+	ItemFailed    = "failed"    // the last upload failed and will be retried
+)
+
+// ItemInfo is information about an item in the cache, returned by
+// Cache.Items
+type ItemInfo struct {
+	Name     string           `json:"name"`            // name in the VFS
+	Size     int64            `json:"size"`            // size of the file
+	DiskSize int64            `json:"diskSize"`        // bytes of the file in the cache
+	ATime    time.Time        `json:"atime"`           // last time file was accessed
+	Opens    int              `json:"opens"`           // number of times file is open
+	State    string           `json:"state"`           // one of ItemClean, ItemDirty, ItemUploading or ItemFailed
+	Error    string           `json:"error,omitempty"` // error from the last failed upload
+	ID       writeback.Handle `json:"id,omitempty"`    // id in the writeback queue if queued
+}
+
+// getInfo returns information about the item
+//
+// This must be called without item.mu held as it calls into the
+// writeback.
+func (item *Item) getInfo() (info ItemInfo) {
+	item.mu.Lock()
+	info = ItemInfo{
+		Name:     item.name,
+		Size:     item.info.Size,
+		DiskSize: item.info.Rs.Size(),
+		ATime:    item.info.ATime,
+		Opens:    item.opens,
+		State:    ItemClean,
+	}
+	dirty := item.metaDirty || item.info.Dirty
+	id := item.writeBackID
+	item.mu.Unlock()
+
+	if dirty {
+		info.State = ItemDirty
+	}
+	if id == 0 {
+		return info
+	}
+	wbInfo, found := item.c.writeback.Get(id)
+	if !found {
+		return info
+	}
+	info.ID = id
+	switch {
+	case wbInfo.Uploading:
+		info.State = ItemUploading
+	case wbInfo.Error != "":
+		info.State = ItemFailed
+	default:
+		info.State = ItemDirty
+	}
+	info.Error = wbInfo.Error
+	return info
+}
+
 // Create the cache file and store the metadata on disk
 // Called with item.mu locked
 func (item *Item) _createFile(osPath string) (err error) {
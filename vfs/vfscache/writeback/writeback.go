@@ -5,10 +5,12 @@ package writeback
 import (
 	"container/heap"
 	"context"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/vfs/vfscommon"
@@ -72,6 +74,7 @@ type writeBackItem struct {
 	putFn     PutFn              // To write the object data
 	tries     int                // number of times we have tried to upload
 	delay     time.Duration      // delay between upload attempts
+	err       error              // error from the last upload attempt if it failed
 }
 
 // A writeBackItems implements a priority queue by implementing
@@ -352,6 +355,7 @@ func (wb *WriteBack) upload(ctx context.Context, wbItem *writeBackItem) {
 			// Upload was cancelled so reset timer
 			wbItem.delay = wb.opt.WriteBack
 		} else {
+			wbItem.err = err
 			fs.Errorf(wbItem.name, "vfs cache: failed to upload try #%d, will retry in %v: %v", wbItem.tries, wbItem.delay, err)
 		}
 		// push the item back on the queue for retry
@@ -445,3 +449,96 @@ func (wb *WriteBack) Stats() (uploadsInProgress, uploadsQueued int) {
 	defer wb.mu.Unlock()
 	return wb.uploads, len(wb.items)
 }
+
+// QueueInfo is information about an item in the writeback queue,
+// returned by Queue and Get
+type QueueInfo struct {
+	Name      string    `json:"name"`            // name (full path) of the file
+	ID        Handle    `json:"id"`              // id of the queue item
+	Expiry    time.Time `json:"-"`               // time the upload will start
+	Tries     int       `json:"tries"`           // number of times we have tried to upload
+	Delay     float64   `json:"delay"`           // delay between upload attempts (s)
+	Uploading bool      `json:"uploading"`       // true if item is being uploaded
+	Error     string    `json:"error,omitempty"` // error from the last upload attempt if it failed
+}
+
+// info returns the QueueInfo for the item
+//
+// call with the lock held
+func (wbItem *writeBackItem) info() (info QueueInfo) {
+	info = QueueInfo{
+		Name:      wbItem.name,
+		ID:        wbItem.id,
+		Expiry:    wbItem.expiry,
+		Tries:     wbItem.tries,
+		Delay:     wbItem.delay.Seconds(),
+		Uploading: wbItem.uploading,
+	}
+	if wbItem.err != nil {
+		info.Error = wbItem.err.Error()
+	}
+	return info
+}
+
+// Queue returns information about the items in the writeback queue,
+// including those being uploaded, in the order they will be uploaded.
+func (wb *WriteBack) Queue() []QueueInfo {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	items := make([]QueueInfo, 0, len(wb.lookup))
+	for _, wbItem := range wb.lookup {
+		items = append(items, wbItem.info())
+	}
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		// Uploads in progress first
+		if a.Uploading != b.Uploading {
+			return a.Uploading
+		}
+		if a.Expiry.Equal(b.Expiry) {
+			return a.ID < b.ID
+		}
+		return a.Expiry.Before(b.Expiry)
+	})
+	return items
+}
+
+// Get returns information about the item with the id passed in and
+// whether it was found.
+func (wb *WriteBack) Get(id Handle) (info QueueInfo, found bool) {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	wbItem, found := wb.lookup[id]
+	if !found {
+		return info, false
+	}
+	return wbItem.info(), true
+}
+
+// ErrorIDNotFound is returned from SetExpiry when the item is not in
+// the queue
+var ErrorIDNotFound = errors.New("id not found in queue")
+
+// SetExpiry sets the time the item with the id passed in will be
+// uploaded, which can be used to reprioritise it or retry a failed
+// upload straight away.
+//
+// This returns an error if the item is being uploaded.
+func (wb *WriteBack) SetExpiry(id Handle, expiry time.Time) error {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	wbItem, found := wb.lookup[id]
+	if !found {
+		return ErrorIDNotFound
+	}
+	if !wbItem.onHeap {
+		return errors.New("can't change the expiry of an item being uploaded")
+	}
+	// Start the backoff again if retrying a failed upload early
+	if wbItem.err != nil && expiry.Before(wbItem.expiry) {
+		wbItem.delay = wb.opt.WriteBack
+	}
+	wb.items._update(wbItem, expiry)
+	wb._resetTimer()
+	return nil
+}
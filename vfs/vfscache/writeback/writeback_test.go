@@ -615,3 +615,60 @@ func TestWriteBackCancelUpload(t *testing.T) {
 	checkInLookup(t, wb, wbItem)
 	assert.True(t, pi.cancelled)
 }
+
+func TestWriteBackQueue(t *testing.T) {
+	wb, cancel := newTestWriteBack(t)
+	defer cancel()
+
+	pi1 := newPutItem(t)
+	pi2 := newPutItem(t)
+
+	id1 := wb.Add(0, "one", true, pi1.put)
+	id2 := wb.Add(0, "two", true, pi2.put)
+	now := time.Now()
+	assert.NoError(t, wb.SetExpiry(id1, now.Add(2*time.Hour)))
+	assert.NoError(t, wb.SetExpiry(id2, now.Add(time.Hour)))
+
+	queue := wb.Queue()
+	assert.Equal(t, 2, len(queue))
+	assert.Equal(t, QueueInfo{
+		Name:   "two",
+		ID:     id2,
+		Expiry: now.Add(time.Hour),
+		Delay:  0.1,
+	}, queue[0])
+	assert.Equal(t, "one", queue[1].Name)
+
+	// Start the upload of one now
+	assert.NoError(t, wb.SetExpiry(id1, time.Now()))
+	<-pi1.started
+	queue = wb.Queue()
+	assert.Equal(t, "one", queue[0].Name)
+	assert.True(t, queue[0].Uploading)
+	assert.Equal(t, 1, queue[0].Tries)
+	assert.Error(t, wb.SetExpiry(id1, time.Now()))
+
+	// Fail the upload
+	pi1.finish(errors.New("transfer failed BOOM"))
+	waitUntilNoTransfers(t, wb)
+	info, found := wb.Get(id1)
+	assert.True(t, found)
+	assert.False(t, info.Uploading)
+	assert.Equal(t, "transfer failed BOOM", info.Error)
+	assert.Equal(t, 0.2, info.Delay)
+
+	// Retry it straight away which starts the backoff again
+	assert.NoError(t, wb.SetExpiry(id1, time.Now()))
+	<-pi1.started
+	info, _ = wb.Get(id1)
+	assert.Equal(t, 2, info.Tries)
+	assert.Equal(t, 0.1, info.Delay)
+	pi1.finish(nil)
+	waitUntilNoTransfers(t, wb)
+	_, found = wb.Get(id1)
+	assert.False(t, found)
+
+	assert.Equal(t, ErrorIDNotFound, wb.SetExpiry(id1, time.Now()))
+	assert.True(t, wb.Remove(id2))
+	assert.Equal(t, []QueueInfo{}, wb.Queue())
+}
@@ -32,6 +32,7 @@ type Options struct {
 	ReadWait          time.Duration // time to wait for in-sequence read
 	WriteBack         time.Duration // time to wait before writing back dirty files
 	ReadAhead         fs.SizeSuffix // bytes to read ahead in cache mode "full"
+	Stats             time.Duration // interval to log the stats of the cache at if > 0
 }
 
 // DefaultOpt is the default values uses for Opt